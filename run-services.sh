@@ -102,6 +102,7 @@ start_service() {
 # Start services with verbose logging
 start_service "user-service" "8086" "${BLUE}"
 start_service "collection-service" "8082" "${CYAN}"
+start_service "circulation-service" "8089" "${CYAN}"
 start_service "document-service" "8081" "${GREEN}"
 start_service "search-service" "8084" "${YELLOW}"
 start_service "api-gateway" "8088" "${RED}"
@@ -145,6 +146,7 @@ echo ""
 echo -e "${CYAN}Service URLs:${NC}"
 echo "  User Service:       http://localhost:8086"
 echo "  Collection Service: http://localhost:8082"
+echo "  Circulation:        http://localhost:8089"
 echo "  Document Service:   http://localhost:8081"
 echo "  Search Service:     http://localhost:8084"
 echo "  API Gateway:        http://localhost:8088"
@@ -43,6 +43,13 @@ func (c *AnalyticsConsumer) Start(ctx context.Context, brokers []string) error {
 	go c.consumeTopic(ctx, brokers, "document.viewed")
 	go c.consumeTopic(ctx, brokers, "document.downloaded")
 
+	// Loans, returns and holds of physical copies
+	go c.consumeTopic(ctx, brokers, "circulation.checked_out")
+	go c.consumeTopic(ctx, brokers, "circulation.checked_in")
+	go c.consumeTopic(ctx, brokers, "circulation.renewed")
+	go c.consumeTopic(ctx, brokers, "circulation.overdue")
+	go c.consumeTopic(ctx, brokers, "circulation.hold_placed")
+
 	return nil
 }
 
@@ -65,12 +72,21 @@ func (c *AnalyticsConsumer) consumeTopic(ctx context.Context, brokers []string,
 			CreatedAt: time.Now(),
 		}
 
-		// Parse fields
-		if idStr, ok := payload["id"].(string); ok {
+		// Parse fields. Circulation events carry the document in document_id; their id is
+		// the loan or hold.
+		idKey := "id"
+		if _, ok := payload["document_id"]; ok {
+			idKey = "document_id"
+		}
+		if idStr, ok := payload[idKey].(string); ok {
 			if id, err := uuid.Parse(idStr); err == nil {
 				event.DocumentID = id
 			}
 		}
+		if event.DocumentID == uuid.Nil {
+			// Items not yet attached to a catalogue record are not reported on
+			return nil
+		}
 
 		if uidStr, ok := payload["user_id"].(string); ok {
 			if uid, err := uuid.Parse(uidStr); err == nil {
@@ -86,6 +86,37 @@ func (h *AnalyticsHandler) GetActivity(c *gin.Context) {
 	response.Success(c, activity, "Daily activity")
 }
 
+// GetCirculation returns circulation stats
+// @Summary      Get circulation stats
+// @Description  Get checkouts, returns, renewals, overdue loans and holds of physical copies, the most borrowed documents and daily loans
+// @Tags         analytics
+// @Produce      json
+// @Param        days       query     int     false  "Number of days" default(30)
+// @Param        limit      query     int     false  "Limit most borrowed" default(10)
+// @Success      200  {object}  repository.CirculationStats "Circulation stats"
+// @Failure      400  {object}  response.Response "Invalid days or limit"
+// @Failure      500  {object}  response.Response "Internal server error"
+// @Router       /circulation [get]
+func (h *AnalyticsHandler) GetCirculation(c *gin.Context) {
+	days, err := strconv.Atoi(c.DefaultQuery("days", "30"))
+	if err != nil {
+		response.BadRequest(c, "Invalid days")
+		return
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	if err != nil {
+		response.BadRequest(c, "Invalid limit")
+		return
+	}
+
+	stats, err := h.repo.GetCirculationStats(days, limit)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+	response.Success(c, stats, "Circulation stats")
+}
+
 func handleError(c *gin.Context, err error) {
 	c.JSON(http.StatusInternalServerError, gin.H{
 		"success": false,
@@ -100,6 +100,7 @@ func main() {
 		api.GET("/overview", handler.GetOverview)
 		api.GET("/documents/popular", handler.GetTopDocuments)
 		api.GET("/activity", handler.GetActivity)
+		api.GET("/circulation", handler.GetCirculation)
 	}
 
 	// Swagger configuration
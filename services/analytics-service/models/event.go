@@ -12,6 +12,13 @@ type EventType string
 const (
 	EventTypeView     EventType = "document.viewed"
 	EventTypeDownload EventType = "document.downloaded"
+
+	// Circulation of physical copies
+	EventTypeCheckout   EventType = "circulation.checked_out"
+	EventTypeCheckin    EventType = "circulation.checked_in"
+	EventTypeRenewal    EventType = "circulation.renewed"
+	EventTypeOverdue    EventType = "circulation.overdue"
+	EventTypeHoldPlaced EventType = "circulation.hold_placed"
 )
 
 // AnalyticsEvent represents a tracked user action
@@ -13,6 +13,7 @@ type AnalyticsRepository interface {
 	GetTotalStats() (map[string]int64, error)
 	GetTopDocuments(limit int) ([]DocumentStats, error)
 	GetDailyActivity(days int) ([]DailyActivity, error)
+	GetCirculationStats(days, limit int) (*CirculationStats, error)
 }
 
 type analyticsRepository struct {
@@ -32,6 +33,29 @@ type DailyActivity struct {
 	Downloads int64  `json:"downloads"`
 }
 
+// CirculationStats summarizes the lending of physical copies over a period
+type CirculationStats struct {
+	Checkouts    int64           `json:"checkouts"`
+	Returns      int64           `json:"returns"`
+	Renewals     int64           `json:"renewals"`
+	Overdue      int64           `json:"overdue"` // Loans that went overdue
+	HoldsPlaced  int64           `json:"holds_placed"`
+	MostBorrowed []DocumentLoans `json:"most_borrowed"`
+	Daily        []DailyLoans    `json:"daily"`
+}
+
+type DocumentLoans struct {
+	DocumentID uuid.UUID `json:"document_id"`
+	Checkouts  int64     `json:"checkouts"`
+	Holds      int64     `json:"holds"`
+}
+
+type DailyLoans struct {
+	Date      string `json:"date"`
+	Checkouts int64  `json:"checkouts"`
+	Returns   int64  `json:"returns"`
+}
+
 func NewAnalyticsRepository(db *gorm.DB) AnalyticsRepository {
 	return &analyticsRepository{db: db}
 }
@@ -99,3 +123,52 @@ func (r *analyticsRepository) GetDailyActivity(days int) ([]DailyActivity, error
 	}
 	return results, nil
 }
+
+func (r *analyticsRepository) GetCirculationStats(days, limit int) (*CirculationStats, error) {
+	startDate := time.Now().AddDate(0, 0, -days)
+	stats := &CirculationStats{}
+
+	totals := `
+		SELECT
+			COUNT(*) FILTER (WHERE event_type = 'circulation.checked_out') as checkouts,
+			COUNT(*) FILTER (WHERE event_type = 'circulation.checked_in') as returns,
+			COUNT(*) FILTER (WHERE event_type = 'circulation.renewed') as renewals,
+			COUNT(*) FILTER (WHERE event_type = 'circulation.overdue') as overdue,
+			COUNT(*) FILTER (WHERE event_type = 'circulation.hold_placed') as holds_placed
+		FROM analytics_events
+		WHERE event_type LIKE 'circulation.%' AND occurred_at >= ?
+	`
+	if err := r.db.Raw(totals, startDate).Scan(stats).Error; err != nil {
+		return nil, err
+	}
+
+	mostBorrowed := `
+		SELECT
+			document_id,
+			COUNT(*) FILTER (WHERE event_type = 'circulation.checked_out') as checkouts,
+			COUNT(*) FILTER (WHERE event_type = 'circulation.hold_placed') as holds
+		FROM analytics_events
+		WHERE event_type IN ('circulation.checked_out', 'circulation.hold_placed') AND occurred_at >= ?
+		GROUP BY document_id
+		ORDER BY checkouts DESC, holds DESC
+		LIMIT ?
+	`
+	if err := r.db.Raw(mostBorrowed, startDate, limit).Scan(&stats.MostBorrowed).Error; err != nil {
+		return nil, err
+	}
+
+	daily := `
+		SELECT
+			TO_CHAR(occurred_at, 'YYYY-MM-DD') as date,
+			COUNT(*) FILTER (WHERE event_type = 'circulation.checked_out') as checkouts,
+			COUNT(*) FILTER (WHERE event_type = 'circulation.checked_in') as returns
+		FROM analytics_events
+		WHERE event_type IN ('circulation.checked_out', 'circulation.checked_in') AND occurred_at >= ?
+		GROUP BY 1
+		ORDER BY 1 ASC
+	`
+	if err := r.db.Raw(daily, startDate).Scan(&stats.Daily).Error; err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
@@ -153,6 +153,91 @@ func (s *Server) setupRoutes() {
 			documents.GET("/:id/download", s.downloadDocument)
 			documents.GET("/:id/view", s.viewDocument)
 			documents.GET("/:id/thumbnail", s.getThumbnail)
+
+			// Version history
+			documents.GET("/:id/versions", s.listVersions)
+			documents.POST("/:id/versions", s.createVersion)
+			documents.GET("/:id/versions/compare", s.compareVersions)
+			documents.GET("/:id/versions/:versionId", s.getVersion)
+			documents.GET("/:id/versions/:versionId/download", s.downloadVersion)
+			documents.POST("/:id/versions/:versionId/restore", s.restoreVersion)
+			documents.DELETE("/:id/versions/:versionId", s.deleteVersion)
+
+			// Relationships between documents
+			documents.GET("/:id/relations", s.listRelations)
+			documents.POST("/:id/relations", s.createRelation)
+			documents.PUT("/:id/relations/:relationId", s.updateRelation)
+			documents.DELETE("/:id/relations/:relationId", s.deleteRelation)
+
+			// Annotations and highlights
+			documents.GET("/:id/annotations", s.listDocumentAnnotations)
+			documents.POST("/:id/annotations", s.createAnnotation)
+			documents.PUT("/:id/annotations/:annotationId", s.updateAnnotation)
+			documents.DELETE("/:id/annotations/:annotationId", s.deleteAnnotation)
+
+			// Favorites and reading progress
+			documents.PUT("/:id/favorite", s.favoriteDocument)
+			documents.DELETE("/:id/favorite", s.unfavoriteDocument)
+			documents.GET("/:id/progress", s.getReadingProgress)
+			documents.PUT("/:id/progress", s.saveReadingProgress)
+			documents.DELETE("/:id/progress", s.deleteReadingProgress)
+
+			// Controlled digital lending
+			documents.GET("/:id/lending", s.getLending)
+			documents.PUT("/:id/lending", s.configureLending)
+			documents.DELETE("/:id/lending", s.endLending)
+			documents.POST("/:id/borrow", s.borrowDocument)
+			documents.POST("/:id/return", s.returnDocument)
+
+			// Bulk import from manifests
+			documents.POST("/batch/import", s.importDocuments)
+
+			// Trash
+			documents.GET("/trash", s.listDocumentTrash)
+			documents.POST("/trash/:id/restore", s.restoreDocument)
+			documents.DELETE("/trash/:id", s.purgeDocument)
+
+			// Review queue
+			documents.GET("/submissions", s.listSubmissions)
+			documents.GET("/submissions/:id", s.getSubmission)
+			documents.GET("/submissions/:id/download", s.downloadSubmission)
+			documents.POST("/submissions/:id/approve", s.approveSubmission)
+			documents.POST("/submissions/:id/reject", s.rejectSubmission)
+		}
+
+		// The user's own annotations across documents
+		v1.GET("/annotations", s.listOwnAnnotations)
+
+		// Personal shelves and reading history
+		shelves := v1.Group("/shelves")
+		{
+			shelves.GET("", s.listShelves)
+			shelves.POST("", s.createShelf)
+			shelves.GET("/:shelfId", s.getShelf)
+			shelves.PUT("/:shelfId", s.updateShelf)
+			shelves.DELETE("/:shelfId", s.deleteShelf)
+			shelves.POST("/:shelfId/items", s.addShelfItem)
+			shelves.PUT("/:shelfId/items/:documentId", s.updateShelfItem)
+			shelves.DELETE("/:shelfId/items/:documentId", s.removeShelfItem)
+		}
+		v1.GET("/reading/recent", s.listRecentlyRead)
+		v1.GET("/lending/loans", s.listDigitalLoans)
+
+		// Resumable (tus) upload routes
+		uploads := v1.Group("/uploads")
+		{
+			uploads.POST("", s.createUpload)
+			uploads.HEAD("/:id", s.getUploadOffset)
+			uploads.PATCH("/:id", s.uploadChunk)
+			uploads.DELETE("/:id", s.terminateUpload)
+		}
+
+		// Direct-to-storage (presigned) upload routes
+		uploadIntents := v1.Group("/upload-intents")
+		{
+			uploadIntents.POST("", s.createUploadIntent)
+			uploadIntents.GET("/:id", s.getUploadIntent)
+			uploadIntents.POST("/:id/finalize", s.finalizeUploadIntent)
 		}
 
 		// Search routes
@@ -171,6 +256,41 @@ func (s *Server) setupRoutes() {
 			collections.DELETE("/:id", s.deleteCollection)
 			collections.GET("", s.listCollections)
 			collections.GET("/:id/documents", s.getCollectionDocuments)
+			collections.GET("/trash", s.listCollectionTrash)
+			collections.POST("/:id/restore", s.restoreCollection)
+			collections.POST("/:id/export", s.exportCollection)
+		}
+
+		// Background jobs (batch operations and exports)
+		jobs := v1.Group("/jobs")
+		{
+			jobs.GET("", s.listJobs)
+			jobs.GET("/:jobID", s.getJob)
+			jobs.GET("/:jobID/items", s.listJobItems)
+			jobs.GET("/:jobID/events", s.streamJob)
+			jobs.GET("/:jobID/ws", s.watchJob)
+			jobs.POST("/:jobID/cancel", s.cancelJob)
+			jobs.POST("/:jobID/retry", s.retryJob)
+		}
+
+		// Circulation routes: physical items, loans and holds
+		circulation := v1.Group("/circulation")
+		{
+			circulation.GET("/items", s.listItems)
+			circulation.POST("/items", s.createItem)
+			circulation.GET("/items/:itemId", s.getItem)
+			circulation.PUT("/items/:itemId", s.updateItem)
+			circulation.GET("/documents/:documentId/availability", s.getAvailability)
+			circulation.POST("/checkout", s.checkOut)
+			circulation.POST("/checkin", s.checkIn)
+			circulation.GET("/loans", s.listLoans)
+			circulation.GET("/loans/:loanId", s.getLoan)
+			circulation.POST("/loans/:loanId/renew", s.renewLoan)
+			circulation.POST("/holds", s.placeHold)
+			circulation.GET("/holds", s.listHolds)
+			circulation.DELETE("/holds/:holdId", s.cancelHold)
+			circulation.GET("/policies", s.listLoanPolicies)
+			circulation.PUT("/policies/:role", s.updateLoanPolicy)
 		}
 
 		// User routes
@@ -197,7 +317,7 @@ func (s *Server) loggingMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		start := time.Now()
 		path := c.Request.URL.Path
-		query := c.Request.URL.RawQuery
+		query := redactQuery(c.Request.URL)
 
 		c.Next()
 
@@ -217,6 +337,16 @@ func (s *Server) loggingMiddleware() gin.HandlerFunc {
 	}
 }
 
+// redactQuery encodes a query for the log without the tokens streaming clients pass in it
+func redactQuery(u *url.URL) string {
+	query := u.Query()
+	if !query.Has("access_token") {
+		return u.RawQuery
+	}
+	query.Set("access_token", "REDACTED")
+	return query.Encode()
+}
+
 func (s *Server) requestIDMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		requestID := c.GetHeader("X-Request-ID")
@@ -232,8 +362,9 @@ func (s *Server) requestIDMiddleware() gin.HandlerFunc {
 func (s *Server) corsMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		c.Header("Access-Control-Allow-Origin", "*")
-		c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		c.Header("Access-Control-Allow-Headers", "Origin, Content-Type, Authorization, X-Request-ID")
+		c.Header("Access-Control-Allow-Methods", "GET, HEAD, POST, PUT, PATCH, DELETE, OPTIONS")
+		c.Header("Access-Control-Allow-Headers", "Origin, Content-Type, Authorization, X-Request-ID, Tus-Resumable, Upload-Length, Upload-Metadata, Upload-Offset, Range, If-Range, If-None-Match, If-Modified-Since")
+		c.Header("Access-Control-Expose-Headers", "Location, Tus-Resumable, Tus-Version, Tus-Extension, Tus-Max-Size, Upload-Offset, Upload-Length, Upload-Expires, Upload-Document-Id, Accept-Ranges, Content-Range, Content-Length, Content-Disposition, ETag, Last-Modified")
 		c.Header("Access-Control-Max-Age", "86400")
 
 		if c.Request.Method == "OPTIONS" {
@@ -370,24 +501,55 @@ func (s *Server) readinessCheck(c *gin.Context) {
 
 // Service URLs (Hardcoded for local dev or env vars)
 var (
-	UserServiceUrl       = getEnv("USER_SERVICE_URL", "http://localhost:8086")
-	DocumentServiceUrl   = getEnv("DOCUMENT_SERVICE_URL", "http://localhost:8081")
-	CollectionServiceUrl = getEnv("COLLECTION_SERVICE_URL", "http://localhost:8082")
-	SearchServiceUrl     = getEnv("SEARCH_SERVICE_URL", "http://localhost:8084")
-	AnalyticsServiceUrl  = getEnv("ANALYTICS_SERVICE_URL", "http://localhost:8087")
+	UserServiceUrl        = getEnv("USER_SERVICE_URL", "http://localhost:8086")
+	DocumentServiceUrl    = getEnv("DOCUMENT_SERVICE_URL", "http://localhost:8081")
+	CollectionServiceUrl  = getEnv("COLLECTION_SERVICE_URL", "http://localhost:8082")
+	SearchServiceUrl      = getEnv("SEARCH_SERVICE_URL", "http://localhost:8084")
+	AnalyticsServiceUrl   = getEnv("ANALYTICS_SERVICE_URL", "http://localhost:8087")
+	CirculationServiceUrl = getEnv("CIRCULATION_SERVICE_URL", "http://localhost:8089")
 )
 
 // Helper for reverse proxy
 func (s *Server) proxyRequest(c *gin.Context, target string, pathRewrite func(string) string) {
+	proxy, ok := reverseProxy(c, target, pathRewrite)
+	if !ok {
+		return
+	}
+	proxy.ServeHTTP(c.Writer, c.Request)
+}
+
+// proxyStream proxies a long-lived response, such as server-sent events or a WebSocket,
+// passing each write on as soon as the service makes it
+func (s *Server) proxyStream(c *gin.Context, target string, pathRewrite func(string) string) {
+	proxy, ok := reverseProxy(c, target, pathRewrite)
+	if !ok {
+		return
+	}
+	proxy.FlushInterval = -1
+
+	// The stream may outlast the server's read and write timeouts
+	controller := http.NewResponseController(c.Writer)
+	controller.SetReadDeadline(time.Time{})
+	controller.SetWriteDeadline(time.Time{})
+
+	proxy.ServeHTTP(c.Writer, c.Request)
+}
+
+// reverseProxy builds the proxy of a request to a service
+func reverseProxy(c *gin.Context, target string, pathRewrite func(string) string) (*httputil.ReverseProxy, bool) {
 	remote, err := url.Parse(target)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid target URL"})
-		return
+		return nil, false
 	}
 
 	proxy := httputil.NewSingleHostReverseProxy(remote)
 	proxy.Director = func(req *http.Request) {
 		req.Header = c.Request.Header
+		// Services see the same request ID the client gets back
+		if requestID := c.GetString("request_id"); requestID != "" {
+			req.Header.Set("X-Request-ID", requestID)
+		}
 		req.Host = remote.Host
 		req.URL.Scheme = remote.Scheme
 		req.URL.Host = remote.Host
@@ -407,10 +569,11 @@ func (s *Server) proxyRequest(c *gin.Context, target string, pathRewrite func(st
 		resp.Header.Del("Access-Control-Allow-Methods")
 		resp.Header.Del("Access-Control-Allow-Headers")
 		resp.Header.Del("Access-Control-Allow-Credentials")
+		resp.Header.Del("Access-Control-Expose-Headers")
 		return nil
 	}
 
-	proxy.ServeHTTP(c.Writer, c.Request)
+	return proxy, true
 }
 
 // Rewrites
@@ -423,6 +586,9 @@ func rewriteDocuments(path string) string {
 func rewriteCollections(path string) string {
 	return path
 }
+func rewriteCirculation(path string) string {
+	return path
+}
 func rewriteSearch(path string) string {
 	// Assume Search Service uses compatible /api/v1/search path
 	return path
@@ -460,6 +626,170 @@ func (s *Server) getThumbnail(c *gin.Context) {
 	s.proxyRequest(c, DocumentServiceUrl, rewriteDocuments)
 }
 
+func (s *Server) listVersions(c *gin.Context) {
+	s.proxyRequest(c, DocumentServiceUrl, rewriteDocuments)
+}
+func (s *Server) createVersion(c *gin.Context) {
+	s.proxyRequest(c, DocumentServiceUrl, rewriteDocuments)
+}
+func (s *Server) compareVersions(c *gin.Context) {
+	s.proxyRequest(c, DocumentServiceUrl, rewriteDocuments)
+}
+func (s *Server) getVersion(c *gin.Context) {
+	s.proxyRequest(c, DocumentServiceUrl, rewriteDocuments)
+}
+func (s *Server) downloadVersion(c *gin.Context) {
+	s.proxyRequest(c, DocumentServiceUrl, rewriteDocuments)
+}
+func (s *Server) restoreVersion(c *gin.Context) {
+	s.proxyRequest(c, DocumentServiceUrl, rewriteDocuments)
+}
+func (s *Server) deleteVersion(c *gin.Context) {
+	s.proxyRequest(c, DocumentServiceUrl, rewriteDocuments)
+}
+
+func (s *Server) listRelations(c *gin.Context) {
+	s.proxyRequest(c, DocumentServiceUrl, rewriteDocuments)
+}
+func (s *Server) createRelation(c *gin.Context) {
+	s.proxyRequest(c, DocumentServiceUrl, rewriteDocuments)
+}
+func (s *Server) updateRelation(c *gin.Context) {
+	s.proxyRequest(c, DocumentServiceUrl, rewriteDocuments)
+}
+func (s *Server) deleteRelation(c *gin.Context) {
+	s.proxyRequest(c, DocumentServiceUrl, rewriteDocuments)
+}
+
+func (s *Server) listDocumentAnnotations(c *gin.Context) {
+	s.proxyRequest(c, DocumentServiceUrl, rewriteDocuments)
+}
+func (s *Server) createAnnotation(c *gin.Context) {
+	s.proxyRequest(c, DocumentServiceUrl, rewriteDocuments)
+}
+func (s *Server) updateAnnotation(c *gin.Context) {
+	s.proxyRequest(c, DocumentServiceUrl, rewriteDocuments)
+}
+func (s *Server) deleteAnnotation(c *gin.Context) {
+	s.proxyRequest(c, DocumentServiceUrl, rewriteDocuments)
+}
+func (s *Server) listOwnAnnotations(c *gin.Context) {
+	s.proxyRequest(c, DocumentServiceUrl, rewriteDocuments)
+}
+
+func (s *Server) favoriteDocument(c *gin.Context) {
+	s.proxyRequest(c, DocumentServiceUrl, rewriteDocuments)
+}
+func (s *Server) unfavoriteDocument(c *gin.Context) {
+	s.proxyRequest(c, DocumentServiceUrl, rewriteDocuments)
+}
+func (s *Server) getReadingProgress(c *gin.Context) {
+	s.proxyRequest(c, DocumentServiceUrl, rewriteDocuments)
+}
+func (s *Server) saveReadingProgress(c *gin.Context) {
+	s.proxyRequest(c, DocumentServiceUrl, rewriteDocuments)
+}
+func (s *Server) deleteReadingProgress(c *gin.Context) {
+	s.proxyRequest(c, DocumentServiceUrl, rewriteDocuments)
+}
+func (s *Server) listShelves(c *gin.Context) {
+	s.proxyRequest(c, DocumentServiceUrl, rewriteDocuments)
+}
+func (s *Server) createShelf(c *gin.Context) {
+	s.proxyRequest(c, DocumentServiceUrl, rewriteDocuments)
+}
+func (s *Server) getShelf(c *gin.Context) {
+	s.proxyRequest(c, DocumentServiceUrl, rewriteDocuments)
+}
+func (s *Server) updateShelf(c *gin.Context) {
+	s.proxyRequest(c, DocumentServiceUrl, rewriteDocuments)
+}
+func (s *Server) deleteShelf(c *gin.Context) {
+	s.proxyRequest(c, DocumentServiceUrl, rewriteDocuments)
+}
+func (s *Server) addShelfItem(c *gin.Context) {
+	s.proxyRequest(c, DocumentServiceUrl, rewriteDocuments)
+}
+func (s *Server) updateShelfItem(c *gin.Context) {
+	s.proxyRequest(c, DocumentServiceUrl, rewriteDocuments)
+}
+func (s *Server) removeShelfItem(c *gin.Context) {
+	s.proxyRequest(c, DocumentServiceUrl, rewriteDocuments)
+}
+func (s *Server) listRecentlyRead(c *gin.Context) {
+	s.proxyRequest(c, DocumentServiceUrl, rewriteDocuments)
+}
+func (s *Server) getLending(c *gin.Context) {
+	s.proxyRequest(c, DocumentServiceUrl, rewriteDocuments)
+}
+func (s *Server) configureLending(c *gin.Context) {
+	s.proxyRequest(c, DocumentServiceUrl, rewriteDocuments)
+}
+func (s *Server) endLending(c *gin.Context) {
+	s.proxyRequest(c, DocumentServiceUrl, rewriteDocuments)
+}
+func (s *Server) borrowDocument(c *gin.Context) {
+	s.proxyRequest(c, DocumentServiceUrl, rewriteDocuments)
+}
+func (s *Server) returnDocument(c *gin.Context) {
+	s.proxyRequest(c, DocumentServiceUrl, rewriteDocuments)
+}
+func (s *Server) listDigitalLoans(c *gin.Context) {
+	s.proxyRequest(c, DocumentServiceUrl, rewriteDocuments)
+}
+func (s *Server) importDocuments(c *gin.Context) {
+	s.proxyRequest(c, DocumentServiceUrl, rewriteDocuments)
+}
+
+func (s *Server) listDocumentTrash(c *gin.Context) {
+	s.proxyRequest(c, DocumentServiceUrl, rewriteDocuments)
+}
+func (s *Server) restoreDocument(c *gin.Context) {
+	s.proxyRequest(c, DocumentServiceUrl, rewriteDocuments)
+}
+func (s *Server) purgeDocument(c *gin.Context) {
+	s.proxyRequest(c, DocumentServiceUrl, rewriteDocuments)
+}
+
+func (s *Server) listSubmissions(c *gin.Context) {
+	s.proxyRequest(c, DocumentServiceUrl, rewriteDocuments)
+}
+func (s *Server) getSubmission(c *gin.Context) {
+	s.proxyRequest(c, DocumentServiceUrl, rewriteDocuments)
+}
+func (s *Server) downloadSubmission(c *gin.Context) {
+	s.proxyRequest(c, DocumentServiceUrl, rewriteDocuments)
+}
+func (s *Server) approveSubmission(c *gin.Context) {
+	s.proxyRequest(c, DocumentServiceUrl, rewriteDocuments)
+}
+func (s *Server) rejectSubmission(c *gin.Context) {
+	s.proxyRequest(c, DocumentServiceUrl, rewriteDocuments)
+}
+
+func (s *Server) createUpload(c *gin.Context) {
+	s.proxyRequest(c, DocumentServiceUrl, rewriteDocuments)
+}
+func (s *Server) getUploadOffset(c *gin.Context) {
+	s.proxyRequest(c, DocumentServiceUrl, rewriteDocuments)
+}
+func (s *Server) uploadChunk(c *gin.Context) {
+	s.proxyRequest(c, DocumentServiceUrl, rewriteDocuments)
+}
+func (s *Server) terminateUpload(c *gin.Context) {
+	s.proxyRequest(c, DocumentServiceUrl, rewriteDocuments)
+}
+
+func (s *Server) createUploadIntent(c *gin.Context) {
+	s.proxyRequest(c, DocumentServiceUrl, rewriteDocuments)
+}
+func (s *Server) getUploadIntent(c *gin.Context) {
+	s.proxyRequest(c, DocumentServiceUrl, rewriteDocuments)
+}
+func (s *Server) finalizeUploadIntent(c *gin.Context) {
+	s.proxyRequest(c, DocumentServiceUrl, rewriteDocuments)
+}
+
 func (s *Server) search(c *gin.Context)         { s.proxyRequest(c, SearchServiceUrl, rewriteSearch) }
 func (s *Server) advancedSearch(c *gin.Context) { s.proxyRequest(c, SearchServiceUrl, rewriteSearch) }
 
@@ -481,6 +811,84 @@ func (s *Server) listCollections(c *gin.Context) {
 func (s *Server) getCollectionDocuments(c *gin.Context) {
 	s.proxyRequest(c, CollectionServiceUrl, rewriteCollections)
 }
+func (s *Server) listCollectionTrash(c *gin.Context) {
+	s.proxyRequest(c, CollectionServiceUrl, rewriteCollections)
+}
+func (s *Server) restoreCollection(c *gin.Context) {
+	s.proxyRequest(c, CollectionServiceUrl, rewriteCollections)
+}
+
+// Exports package documents, so they are run by the document service
+func (s *Server) exportCollection(c *gin.Context) {
+	s.proxyRequest(c, DocumentServiceUrl, rewriteDocuments)
+}
+func (s *Server) listJobs(c *gin.Context) {
+	s.proxyRequest(c, DocumentServiceUrl, rewriteDocuments)
+}
+func (s *Server) getJob(c *gin.Context) {
+	s.proxyRequest(c, DocumentServiceUrl, rewriteDocuments)
+}
+func (s *Server) listJobItems(c *gin.Context) {
+	s.proxyRequest(c, DocumentServiceUrl, rewriteDocuments)
+}
+func (s *Server) streamJob(c *gin.Context) {
+	s.proxyStream(c, DocumentServiceUrl, rewriteDocuments)
+}
+func (s *Server) watchJob(c *gin.Context) {
+	s.proxyStream(c, DocumentServiceUrl, rewriteDocuments)
+}
+func (s *Server) cancelJob(c *gin.Context) {
+	s.proxyRequest(c, DocumentServiceUrl, rewriteDocuments)
+}
+func (s *Server) retryJob(c *gin.Context) {
+	s.proxyRequest(c, DocumentServiceUrl, rewriteDocuments)
+}
+
+func (s *Server) listItems(c *gin.Context) {
+	s.proxyRequest(c, CirculationServiceUrl, rewriteCirculation)
+}
+func (s *Server) createItem(c *gin.Context) {
+	s.proxyRequest(c, CirculationServiceUrl, rewriteCirculation)
+}
+func (s *Server) getItem(c *gin.Context) {
+	s.proxyRequest(c, CirculationServiceUrl, rewriteCirculation)
+}
+func (s *Server) updateItem(c *gin.Context) {
+	s.proxyRequest(c, CirculationServiceUrl, rewriteCirculation)
+}
+func (s *Server) getAvailability(c *gin.Context) {
+	s.proxyRequest(c, CirculationServiceUrl, rewriteCirculation)
+}
+func (s *Server) checkOut(c *gin.Context) {
+	s.proxyRequest(c, CirculationServiceUrl, rewriteCirculation)
+}
+func (s *Server) checkIn(c *gin.Context) {
+	s.proxyRequest(c, CirculationServiceUrl, rewriteCirculation)
+}
+func (s *Server) listLoans(c *gin.Context) {
+	s.proxyRequest(c, CirculationServiceUrl, rewriteCirculation)
+}
+func (s *Server) getLoan(c *gin.Context) {
+	s.proxyRequest(c, CirculationServiceUrl, rewriteCirculation)
+}
+func (s *Server) renewLoan(c *gin.Context) {
+	s.proxyRequest(c, CirculationServiceUrl, rewriteCirculation)
+}
+func (s *Server) placeHold(c *gin.Context) {
+	s.proxyRequest(c, CirculationServiceUrl, rewriteCirculation)
+}
+func (s *Server) listHolds(c *gin.Context) {
+	s.proxyRequest(c, CirculationServiceUrl, rewriteCirculation)
+}
+func (s *Server) cancelHold(c *gin.Context) {
+	s.proxyRequest(c, CirculationServiceUrl, rewriteCirculation)
+}
+func (s *Server) listLoanPolicies(c *gin.Context) {
+	s.proxyRequest(c, CirculationServiceUrl, rewriteCirculation)
+}
+func (s *Server) updateLoanPolicy(c *gin.Context) {
+	s.proxyRequest(c, CirculationServiceUrl, rewriteCirculation)
+}
 
 func (s *Server) registerUser(c *gin.Context)   { s.proxyRequest(c, UserServiceUrl, rewriteRegister) }
 func (s *Server) loginUser(c *gin.Context)      { s.proxyRequest(c, UserServiceUrl, rewriteLogin) }
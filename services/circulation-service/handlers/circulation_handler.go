@@ -0,0 +1,437 @@
+package handlers
+
+import (
+	"time"
+
+	"github.com/Kyei-Ernest/libsystem/services/circulation-service/repository"
+	"github.com/Kyei-Ernest/libsystem/services/circulation-service/service"
+	"github.com/Kyei-Ernest/libsystem/shared/models"
+	"github.com/Kyei-Ernest/libsystem/shared/response"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// CirculationHandler handles loan, hold and loan policy requests
+type CirculationHandler struct {
+	circulationService service.CirculationService
+}
+
+// NewCirculationHandler creates a new circulation handler
+func NewCirculationHandler(circulationService service.CirculationService) *CirculationHandler {
+	return &CirculationHandler{circulationService: circulationService}
+}
+
+// CheckOutRequest represents a checkout at the desk
+type CheckOutRequest struct {
+	Barcode string    `json:"barcode" binding:"required"`
+	UserID  uuid.UUID `json:"user_id" binding:"required"` // Borrower
+}
+
+// CheckInRequest represents a return at the desk
+type CheckInRequest struct {
+	Barcode string `json:"barcode" binding:"required"`
+}
+
+// PlaceHoldRequest represents a hold on a catalogue record
+type PlaceHoldRequest struct {
+	DocumentID uuid.UUID  `json:"document_id" binding:"required"`
+	UserID     *uuid.UUID `json:"user_id,omitempty"` // Staff only: place the hold for a patron
+}
+
+// CheckOut godoc
+// @Summary      Check out an item
+// @Description  Lend an item to a patron under the loan policy of their role. A patron with overdue loans, or at their loan limit, cannot borrow. Librarians only.
+// @Tags         circulation
+// @Security     BearerAuth
+// @Accept       json
+// @Produce      json
+// @Param        request body CheckOutRequest true "Checkout"
+// @Success      201  {object}  response.Response{data=models.Loan} "Loan"
+// @Failure      409  {object}  response.Response "Item unavailable or limit reached"
+// @Router       /circulation/checkout [post]
+func (h *CirculationHandler) CheckOut(c *gin.Context) {
+	var req CheckOutRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "Invalid request body: "+err.Error())
+		return
+	}
+
+	staffID, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	loan, err := h.circulationService.CheckOut(req.Barcode, req.UserID, staffID)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	response.Created(c, loan, "Item checked out successfully")
+}
+
+// CheckIn godoc
+// @Summary      Check in an item
+// @Description  Return an item. If a patron is waiting for the record, the response names the hold the item should be set aside for. Librarians only.
+// @Tags         circulation
+// @Security     BearerAuth
+// @Accept       json
+// @Produce      json
+// @Param        request body CheckInRequest true "Check-in"
+// @Success      200  {object}  response.Response{data=service.CheckInResult} "Returned loan"
+// @Failure      404  {object}  response.Response "No open loan"
+// @Router       /circulation/checkin [post]
+func (h *CirculationHandler) CheckIn(c *gin.Context) {
+	var req CheckInRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "Invalid request body: "+err.Error())
+		return
+	}
+
+	staffID, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	result, err := h.circulationService.CheckIn(req.Barcode, staffID)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	response.Success(c, result, "Item checked in successfully")
+}
+
+// ListLoans godoc
+// @Summary      List loans
+// @Description  The caller's loans. Librarians see everyone's, and may filter by borrower, item or record.
+// @Tags         circulation
+// @Security     BearerAuth
+// @Produce      json
+// @Param        status      query     string  false  "open (default), overdue or all"
+// @Param        user_id     query     string  false  "Borrower (staff only)"
+// @Param        item_id     query     string  false  "Item (staff only)"
+// @Param        document_id query     string  false  "Document ID"
+// @Param        page        query     int     false  "Page number" default(1)
+// @Param        page_size   query     int     false  "Page size" default(20)
+// @Success      200  {object}  response.Response{data=[]models.Loan} "Loans"
+// @Router       /circulation/loans [get]
+func (h *CirculationHandler) ListLoans(c *gin.Context) {
+	userID, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	filters := repository.LoanFilters{}
+	switch c.DefaultQuery("status", "open") {
+	case "open":
+		filters.Open = true
+	case "overdue":
+		now := time.Now()
+		filters.OverdueAt = &now
+	case "all":
+	default:
+		response.BadRequest(c, "status must be open, overdue or all")
+		return
+	}
+
+	var err error
+	if filters.DocumentID, err = optionalUUID(c, "document_id"); err != nil {
+		response.BadRequest(c, "Invalid document ID")
+		return
+	}
+	if isStaff(c) {
+		if filters.UserID, err = optionalUUID(c, "user_id"); err != nil {
+			response.BadRequest(c, "Invalid user ID")
+			return
+		}
+		if filters.ItemID, err = optionalUUID(c, "item_id"); err != nil {
+			response.BadRequest(c, "Invalid item ID")
+			return
+		}
+	} else {
+		filters.UserID = &userID
+	}
+
+	page, pageSize := pagination(c)
+	loans, total, err := h.circulationService.ListLoans(filters, page, pageSize)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	response.Paginated(c, loans, page, pageSize, total)
+}
+
+// GetLoan godoc
+// @Summary      Get a loan
+// @Tags         circulation
+// @Security     BearerAuth
+// @Produce      json
+// @Param        loanId path string true "Loan ID"
+// @Success      200  {object}  response.Response{data=models.Loan} "Loan"
+// @Failure      404  {object}  response.Response "Not found"
+// @Router       /circulation/loans/{loanId} [get]
+func (h *CirculationHandler) GetLoan(c *gin.Context) {
+	loanID, err := uuid.Parse(c.Param("loanId"))
+	if err != nil {
+		response.BadRequest(c, "Invalid loan ID")
+		return
+	}
+
+	userID, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	loan, err := h.circulationService.GetLoan(loanID, userID, isStaff(c))
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	response.Success(c, loan, "Loan retrieved successfully")
+}
+
+// RenewLoan godoc
+// @Summary      Renew a loan
+// @Description  Extend an open loan by the loan period of the borrower's policy, from the due date or from now if later. Refused at the renewal limit or when another patron is waiting for the record.
+// @Tags         circulation
+// @Security     BearerAuth
+// @Produce      json
+// @Param        loanId path string true "Loan ID"
+// @Success      200  {object}  response.Response{data=models.Loan} "Renewed loan"
+// @Failure      409  {object}  response.Response "Cannot be renewed"
+// @Router       /circulation/loans/{loanId}/renew [post]
+func (h *CirculationHandler) RenewLoan(c *gin.Context) {
+	loanID, err := uuid.Parse(c.Param("loanId"))
+	if err != nil {
+		response.BadRequest(c, "Invalid loan ID")
+		return
+	}
+
+	userID, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	loan, err := h.circulationService.Renew(loanID, userID, isStaff(c))
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	response.Success(c, loan, "Loan renewed successfully")
+}
+
+// PlaceHold godoc
+// @Summary      Place a hold
+// @Description  Join the queue for a catalogue record. If a copy is on the shelf it is set aside at once. Librarians may place holds for patrons.
+// @Tags         circulation
+// @Security     BearerAuth
+// @Accept       json
+// @Produce      json
+// @Param        request body PlaceHoldRequest true "Hold"
+// @Success      201  {object}  response.Response{data=models.Hold} "Hold placed"
+// @Failure      409  {object}  response.Response "Already held, borrowed or at the hold limit"
+// @Router       /circulation/holds [post]
+func (h *CirculationHandler) PlaceHold(c *gin.Context) {
+	var req PlaceHoldRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "Invalid request body: "+err.Error())
+		return
+	}
+
+	holderID, ok := currentUser(c)
+	if !ok {
+		return
+	}
+	if req.UserID != nil && *req.UserID != holderID {
+		if !isStaff(c) {
+			response.Forbidden(c, "Only librarians can place holds for other users")
+			return
+		}
+		holderID = *req.UserID
+	}
+
+	hold, err := h.circulationService.PlaceHold(req.DocumentID, holderID)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	response.Created(c, hold, "Hold placed successfully")
+}
+
+// ListHolds godoc
+// @Summary      List holds
+// @Description  The caller's holds with their place in the queue. Librarians see everyone's, and may filter by patron or record.
+// @Tags         circulation
+// @Security     BearerAuth
+// @Produce      json
+// @Param        status      query     string  false  "active (default) or all"
+// @Param        user_id     query     string  false  "Patron (staff only)"
+// @Param        document_id query     string  false  "Document ID"
+// @Param        page        query     int     false  "Page number" default(1)
+// @Param        page_size   query     int     false  "Page size" default(20)
+// @Success      200  {object}  response.Response{data=[]models.Hold} "Holds"
+// @Router       /circulation/holds [get]
+func (h *CirculationHandler) ListHolds(c *gin.Context) {
+	userID, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	filters := repository.HoldFilters{}
+	switch c.DefaultQuery("status", "active") {
+	case "active":
+		filters.Active = true
+	case "all":
+	default:
+		response.BadRequest(c, "status must be active or all")
+		return
+	}
+
+	var err error
+	if filters.DocumentID, err = optionalUUID(c, "document_id"); err != nil {
+		response.BadRequest(c, "Invalid document ID")
+		return
+	}
+	if isStaff(c) {
+		if filters.UserID, err = optionalUUID(c, "user_id"); err != nil {
+			response.BadRequest(c, "Invalid user ID")
+			return
+		}
+	} else {
+		filters.UserID = &userID
+	}
+
+	page, pageSize := pagination(c)
+	holds, total, err := h.circulationService.ListHolds(filters, page, pageSize)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	response.Paginated(c, holds, page, pageSize, total)
+}
+
+// CancelHold godoc
+// @Summary      Cancel a hold
+// @Description  Leave the queue. A copy already set aside goes to the next patron waiting.
+// @Tags         circulation
+// @Security     BearerAuth
+// @Produce      json
+// @Param        holdId path string true "Hold ID"
+// @Success      200  {object}  response.Response "Hold cancelled"
+// @Failure      404  {object}  response.Response "Not found"
+// @Router       /circulation/holds/{holdId} [delete]
+func (h *CirculationHandler) CancelHold(c *gin.Context) {
+	holdID, err := uuid.Parse(c.Param("holdId"))
+	if err != nil {
+		response.BadRequest(c, "Invalid hold ID")
+		return
+	}
+
+	userID, ok := currentUser(c)
+	if !ok {
+		return
+	}
+
+	if err := h.circulationService.CancelHold(holdID, userID, isStaff(c)); err != nil {
+		handleError(c, err)
+		return
+	}
+
+	response.Success(c, nil, "Hold cancelled successfully")
+}
+
+// ListPolicies godoc
+// @Summary      List loan policies
+// @Tags         circulation
+// @Security     BearerAuth
+// @Produce      json
+// @Success      200  {object}  response.Response{data=[]models.LoanPolicy} "Loan policies"
+// @Router       /circulation/policies [get]
+func (h *CirculationHandler) ListPolicies(c *gin.Context) {
+	policies, err := h.circulationService.ListPolicies()
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	response.Success(c, policies, "Loan policies retrieved successfully")
+}
+
+// UpdatePolicy godoc
+// @Summary      Update a loan policy
+// @Description  Change the loan period and limits of a role. Applies to new loans, renewals and holds. Administrators only.
+// @Tags         circulation
+// @Security     BearerAuth
+// @Accept       json
+// @Produce      json
+// @Param        role    path string              true "Role"
+// @Param        request body service.PolicyInput true "Policy"
+// @Success      200  {object}  response.Response{data=models.LoanPolicy} "Policy updated"
+// @Failure      404  {object}  response.Response "Unknown role"
+// @Router       /circulation/policies/{role} [put]
+func (h *CirculationHandler) UpdatePolicy(c *gin.Context) {
+	var req service.PolicyInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "Invalid request body: "+err.Error())
+		return
+	}
+
+	policy, err := h.circulationService.UpdatePolicy(models.UserRole(c.Param("role")), req)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	response.Success(c, policy, "Loan policy updated successfully")
+}
+
+// RegisterRoutes registers circulation routes
+func (h *CirculationHandler) RegisterRoutes(router *gin.RouterGroup, requiredAuth gin.HandlerFunc) {
+	staff := requireRole(models.RoleLibrarian, models.RoleAdmin)
+
+	circulation := router.Group("/circulation", requiredAuth)
+	{
+		circulation.POST("/checkout", staff, h.CheckOut)
+		circulation.POST("/checkin", staff, h.CheckIn)
+
+		circulation.GET("/loans", h.ListLoans)
+		circulation.GET("/loans/:loanId", h.GetLoan)
+		circulation.POST("/loans/:loanId/renew", h.RenewLoan)
+
+		circulation.POST("/holds", h.PlaceHold)
+		circulation.GET("/holds", h.ListHolds)
+		circulation.DELETE("/holds/:holdId", h.CancelHold)
+
+		circulation.GET("/policies", h.ListPolicies)
+		circulation.PUT("/policies/:role", requireRole(models.RoleAdmin), h.UpdatePolicy)
+	}
+}
+
+// currentUser returns the authenticated user, responding 401 when there is none
+func currentUser(c *gin.Context) (uuid.UUID, bool) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		response.Unauthorized(c, "User not authenticated")
+		return uuid.Nil, false
+	}
+	return userID.(uuid.UUID), true
+}
+
+// optionalUUID parses an optional UUID query parameter
+func optionalUUID(c *gin.Context, name string) (*uuid.UUID, error) {
+	value := c.Query(name)
+	if value == "" {
+		return nil, nil
+	}
+	id, err := uuid.Parse(value)
+	if err != nil {
+		return nil, err
+	}
+	return &id, nil
+}
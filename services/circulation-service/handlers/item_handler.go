@@ -0,0 +1,255 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/Kyei-Ernest/libsystem/services/circulation-service/repository"
+	"github.com/Kyei-Ernest/libsystem/services/circulation-service/service"
+	appErrors "github.com/Kyei-Ernest/libsystem/shared/errors"
+	"github.com/Kyei-Ernest/libsystem/shared/models"
+	"github.com/Kyei-Ernest/libsystem/shared/response"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// ItemHandler handles requests for physical copies
+type ItemHandler struct {
+	itemService service.ItemService
+}
+
+// NewItemHandler creates a new item handler
+func NewItemHandler(itemService service.ItemService) *ItemHandler {
+	return &ItemHandler{itemService: itemService}
+}
+
+// ListItems godoc
+// @Summary      List items
+// @Description  Physical copies, by call number. Librarians only.
+// @Tags         items
+// @Security     BearerAuth
+// @Produce      json
+// @Param        document_id query     string  false  "Document ID"
+// @Param        status      query     string  false  "Status"
+// @Param        location    query     string  false  "Location contains"
+// @Param        barcode     query     string  false  "Barcode"
+// @Param        page        query     int     false  "Page number" default(1)
+// @Param        page_size   query     int     false  "Page size" default(20)
+// @Success      200  {object}  response.Response{data=[]models.Item} "Items"
+// @Failure      403  {object}  response.Response "Forbidden"
+// @Router       /circulation/items [get]
+func (h *ItemHandler) ListItems(c *gin.Context) {
+	filters := repository.ItemFilters{
+		Status:   models.ItemStatus(c.Query("status")),
+		Location: c.Query("location"),
+		Barcode:  c.Query("barcode"),
+	}
+	if documentIDStr := c.Query("document_id"); documentIDStr != "" {
+		id, err := uuid.Parse(documentIDStr)
+		if err != nil {
+			response.BadRequest(c, "Invalid document ID")
+			return
+		}
+		filters.DocumentID = &id
+	}
+
+	page, pageSize := pagination(c)
+	items, total, err := h.itemService.ListItems(filters, page, pageSize)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	response.Paginated(c, items, page, pageSize, total)
+}
+
+// CreateItem godoc
+// @Summary      Add an item
+// @Description  Add a physical copy of a catalogue record. If patrons are waiting for the record, the copy goes to the hold shelf for the first of them. Librarians only.
+// @Tags         items
+// @Security     BearerAuth
+// @Accept       json
+// @Produce      json
+// @Param        request body service.ItemInput true "Item"
+// @Success      201  {object}  response.Response{data=models.Item} "Item created"
+// @Failure      409  {object}  response.Response "Barcode in use"
+// @Router       /circulation/items [post]
+func (h *ItemHandler) CreateItem(c *gin.Context) {
+	var req service.ItemInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "Invalid request body: "+err.Error())
+		return
+	}
+
+	item, err := h.itemService.CreateItem(req)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	response.Created(c, item, "Item created successfully")
+}
+
+// GetItem godoc
+// @Summary      Get an item
+// @Tags         items
+// @Security     BearerAuth
+// @Produce      json
+// @Param        itemId path string true "Item ID"
+// @Success      200  {object}  response.Response{data=models.Item} "Item"
+// @Failure      404  {object}  response.Response "Not found"
+// @Router       /circulation/items/{itemId} [get]
+func (h *ItemHandler) GetItem(c *gin.Context) {
+	itemID, err := uuid.Parse(c.Param("itemId"))
+	if err != nil {
+		response.BadRequest(c, "Invalid item ID")
+		return
+	}
+
+	item, err := h.itemService.GetItem(itemID)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	response.Success(c, item, "Item retrieved successfully")
+}
+
+// UpdateItem godoc
+// @Summary      Update an item
+// @Description  Change an item's record, call number, location, condition or notes, or set its status to available, in_repair, lost or withdrawn. Librarians only.
+// @Tags         items
+// @Security     BearerAuth
+// @Accept       json
+// @Produce      json
+// @Param        itemId  path string           true "Item ID"
+// @Param        request body service.ItemUpdate true "Changes"
+// @Success      200  {object}  response.Response{data=models.Item} "Item updated"
+// @Failure      400  {object}  response.Response "Invalid status change"
+// @Router       /circulation/items/{itemId} [put]
+func (h *ItemHandler) UpdateItem(c *gin.Context) {
+	itemID, err := uuid.Parse(c.Param("itemId"))
+	if err != nil {
+		response.BadRequest(c, "Invalid item ID")
+		return
+	}
+
+	var req service.ItemUpdate
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "Invalid request body: "+err.Error())
+		return
+	}
+
+	item, err := h.itemService.UpdateItem(itemID, req)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	response.Success(c, item, "Item updated successfully")
+}
+
+// GetAvailability godoc
+// @Summary      Get availability
+// @Description  The lendable copies of a catalogue record, how many are on the shelf and how many holds are waiting
+// @Tags         items
+// @Produce      json
+// @Param        documentId path string true "Document ID"
+// @Success      200  {object}  response.Response{data=service.Availability} "Availability"
+// @Router       /circulation/documents/{documentId}/availability [get]
+func (h *ItemHandler) GetAvailability(c *gin.Context) {
+	documentID, err := uuid.Parse(c.Param("documentId"))
+	if err != nil {
+		response.BadRequest(c, "Invalid document ID")
+		return
+	}
+
+	availability, err := h.itemService.GetAvailability(documentID)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	response.Success(c, availability, "Availability retrieved successfully")
+}
+
+// RegisterRoutes registers item routes
+func (h *ItemHandler) RegisterRoutes(router *gin.RouterGroup, requiredAuth gin.HandlerFunc) {
+	staff := requireRole(models.RoleLibrarian, models.RoleAdmin)
+
+	items := router.Group("/circulation/items")
+	{
+		items.GET("", requiredAuth, staff, h.ListItems)
+		items.POST("", requiredAuth, staff, h.CreateItem)
+		items.GET("/:itemId", requiredAuth, staff, h.GetItem)
+		items.PUT("/:itemId", requiredAuth, staff, h.UpdateItem)
+	}
+	router.GET("/circulation/documents/:documentId/availability", h.GetAvailability)
+}
+
+// requireRole allows only users with one of the roles
+func requireRole(roles ...models.UserRole) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role := userRole(c)
+		for _, allowed := range roles {
+			if role == allowed {
+				c.Next()
+				return
+			}
+		}
+		handleError(c, appErrors.NewForbiddenError("You do not have permission to perform this action", nil))
+		c.Abort()
+	}
+}
+
+// userRole returns the role of the authenticated user
+func userRole(c *gin.Context) models.UserRole {
+	role, _ := c.Get("role")
+	switch r := role.(type) {
+	case models.UserRole:
+		return r
+	case string:
+		return models.UserRole(r)
+	}
+	return ""
+}
+
+// isStaff reports whether the authenticated user is a librarian or administrator
+func isStaff(c *gin.Context) bool {
+	role := userRole(c)
+	return role == models.RoleLibrarian || role == models.RoleAdmin
+}
+
+// pagination parses the page and page size query parameters
+func pagination(c *gin.Context) (int, int) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+	return page, pageSize
+}
+
+// handleError handles errors and sends appropriate responses
+func handleError(c *gin.Context, err error) {
+	status := http.StatusInternalServerError
+	code := "INTERNAL_ERROR"
+	message := "Internal server error"
+
+	if appErr, ok := err.(*appErrors.AppError); ok {
+		status = appErr.HTTPStatus
+		code = appErr.Code
+		message = appErr.Message
+	}
+
+	c.JSON(status, gin.H{
+		"success": false,
+		"error": gin.H{
+			"code":    code,
+			"message": message,
+		},
+	})
+}
@@ -0,0 +1,282 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/Kyei-Ernest/libsystem/services/circulation-service/handlers"
+	"github.com/Kyei-Ernest/libsystem/services/circulation-service/repository"
+	"github.com/Kyei-Ernest/libsystem/services/circulation-service/service"
+	"github.com/Kyei-Ernest/libsystem/shared/database"
+	"github.com/Kyei-Ernest/libsystem/shared/kafka"
+	"github.com/Kyei-Ernest/libsystem/shared/metrics"
+	"github.com/Kyei-Ernest/libsystem/shared/security"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/joho/godotenv"
+	swaggerFiles "github.com/swaggo/files"
+	ginSwagger "github.com/swaggo/gin-swagger"
+
+	_ "github.com/Kyei-Ernest/libsystem/services/circulation-service/docs"
+)
+
+// @title           Circulation Service API
+// @version         1.0
+// @description     API for lending physical copies: items, loans, renewals, holds and loan policies
+// @termsOfService  http://swagger.io/terms/
+
+// @contact.name    API Support
+// @contact.url     http://www.swagger.io/support
+// @contact.email   support@swagger.io
+
+// @license.name    Apache 2.0
+// @license.url     http://www.apache.org/licenses/LICENSE-2.0.html
+
+// @host            localhost:8089
+// @BasePath        /api/v1
+
+func main() {
+	// Load .env file (optional - won't fail if missing)
+	_ = godotenv.Load("../../.env")
+
+	// Load configuration from environment
+	port := getEnv("PORT", "8089")
+	dbHost := getEnv("DB_HOST", "localhost")
+	dbPort := getEnv("DB_PORT", "5432")
+	dbUser := getEnv("DB_USER", "libsystem")
+	dbPassword := getEnv("DB_PASSWORD", "libsystem")
+	dbName := getEnv("DB_NAME", "libsystem")
+
+	// Initialize database connection
+	dbConfig := &database.Config{
+		Host:     dbHost,
+		Port:     dbPort,
+		User:     dbUser,
+		Password: dbPassword,
+		DBName:   dbName,
+		SSLMode:  "disable",
+		TimeZone: "UTC",
+	}
+
+	dbConn, err := database.NewConnection(dbConfig)
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer dbConn.Close()
+
+	// Note: AutoMigrate is disabled because all tables are created by SQL migrations
+	log.Println("Database connected successfully")
+
+	// Initialize repositories
+	itemRepo := repository.NewItemRepository(dbConn.DB)
+	policyRepo := repository.NewPolicyRepository(dbConn.DB)
+	circulationRepo := repository.NewCirculationRepository(dbConn.DB)
+
+	// Initialize Kafka producer for loan and hold events
+	kafkaBrokers := strings.Split(getEnv("KAFKA_BROKERS", "localhost:9093"), ",")
+	producer := kafka.NewProducer(kafka.ProducerConfig{
+		Brokers: kafkaBrokers,
+		Topic:   "", // No default topic, we specify per message
+	})
+	defer producer.Close()
+
+	// Overdue loans and uncollected holds are checked on this interval
+	sweepInterval, err := time.ParseDuration(getEnv("CIRCULATION_SWEEP_INTERVAL", "15m"))
+	if err != nil {
+		log.Fatalf("Invalid CIRCULATION_SWEEP_INTERVAL: %v", err)
+	}
+
+	// Initialize services
+	circulationService := service.NewCirculationService(circulationRepo, itemRepo, policyRepo, producer)
+	itemService := service.NewItemService(itemRepo, circulationRepo, circulationService)
+
+	// Periodically report overdue loans and expire holds that were not collected
+	go func() {
+		ticker := time.NewTicker(sweepInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			now := time.Now()
+			if reported, err := circulationService.ReportOverdue(now); err != nil {
+				log.Printf("Failed to report overdue loans: %v", err)
+			} else if reported > 0 {
+				log.Printf("Reported %d overdue loans", reported)
+			}
+			if expired, err := circulationService.ExpireHolds(now); err != nil {
+				log.Printf("Failed to expire holds: %v", err)
+			} else if expired > 0 {
+				log.Printf("Expired %d uncollected holds", expired)
+			}
+		}
+	}()
+
+	// Initialize handlers
+	itemHandler := handlers.NewItemHandler(itemService)
+	circulationHandler := handlers.NewCirculationHandler(circulationService)
+
+	// Initialize router
+	router := gin.Default()
+
+	// Add Prometheus metrics middleware
+	router.Use(metrics.PrometheusMiddleware())
+
+	// CORS middleware
+	router.Use(corsMiddleware())
+
+	// Health check endpoint
+	router.GET("/health", func(c *gin.Context) {
+		if err := dbConn.HealthCheck(); err != nil {
+			c.JSON(http.StatusServiceUnavailable, gin.H{
+				"status":   "unhealthy",
+				"database": "disconnected",
+			})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"status":   "healthy",
+			"service":  "circulation-service",
+			"database": "connected",
+		})
+	})
+
+	// API routes
+	v1 := router.Group("/api/v1")
+	{
+		// Required auth middleware for protected endpoints
+		requiredAuth := requiredAuthMiddleware()
+
+		itemHandler.RegisterRoutes(v1, requiredAuth)
+		circulationHandler.RegisterRoutes(v1, requiredAuth)
+	}
+
+	// Swagger configuration
+	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
+
+	// Start server
+	srv := &http.Server{
+		Addr:         ":" + port,
+		Handler:      router,
+		ReadTimeout:  15 * time.Second,
+		WriteTimeout: 15 * time.Second,
+		IdleTimeout:  60 * time.Second,
+	}
+
+	// Start server in a goroutine
+	go func() {
+		log.Printf("Circulation Service starting on port %s...\n", port)
+		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Fatalf("Failed to start server: %v", err)
+		}
+	}()
+
+	// Wait for interrupt signal to gracefully shutdown the server
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	<-quit
+	log.Println("Shutting down server...")
+
+	// Graceful shutdown
+	if err := srv.Close(); err != nil {
+		log.Printf("Server forced to shutdown: %v", err)
+	}
+
+	log.Println("Server exited")
+}
+
+// getEnv gets an environment variable or returns a default value
+func getEnv(key, defaultValue string) string {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	return value
+}
+
+// corsMiddleware adds CORS headers
+func corsMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
+		c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
+		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With")
+		c.Writer.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT, DELETE, PATCH")
+
+		if c.Request.Method == "OPTIONS" {
+			c.AbortWithStatus(204)
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// requiredAuthMiddleware requires authentication
+func requiredAuthMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tokenString := c.GetHeader("Authorization")
+		if tokenString == "" {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"success": false,
+				"error": gin.H{
+					"code":    "UNAUTHORIZED",
+					"message": "No token provided",
+				},
+			})
+			c.Abort()
+			return
+		}
+
+		if len(tokenString) > 7 && tokenString[:7] == "Bearer " {
+			tokenString = tokenString[7:]
+		}
+
+		// Validate JWT token and extract user ID and role
+		jwtSecret := getEnv("JWT_SECRET", "your-secret-key-change-in-production")
+		userID, role, err := validateTokenAndGetUserID(tokenString, jwtSecret)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"success": false,
+				"error": gin.H{
+					"code":    "UNAUTHORIZED",
+					"message": "Invalid token: " + err.Error(),
+				},
+			})
+			c.Abort()
+			return
+		}
+
+		// Set user ID and role in context
+		c.Set("user_id", userID)
+		c.Set("role", role)
+		c.Next()
+	}
+}
+
+// validateTokenAndGetUserID validates JWT and extracts user ID and role
+func validateTokenAndGetUserID(tokenString, jwtSecret string) (uuid.UUID, string, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &security.TokenClaims{}, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method")
+		}
+		return []byte(jwtSecret), nil
+	})
+
+	if err != nil {
+		return uuid.Nil, "", err
+	}
+
+	if claims, ok := token.Claims.(*security.TokenClaims); ok && token.Valid {
+		userID, err := uuid.Parse(claims.Subject)
+		if err != nil {
+			return uuid.Nil, "", err
+		}
+		return userID, string(claims.Role), nil
+	}
+
+	return uuid.Nil, "", fmt.Errorf("invalid token")
+}
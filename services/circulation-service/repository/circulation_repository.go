@@ -0,0 +1,562 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/Kyei-Ernest/libsystem/shared/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrItemUnavailable is returned when an item cannot be checked out in its status
+	ErrItemUnavailable = errors.New("item is not available")
+	// ErrItemHeld is returned when an item is on the hold shelf for another user
+	ErrItemHeld = errors.New("item is held for another user")
+	// ErrNoOpenLoan is returned when an item being checked in is not on loan
+	ErrNoOpenLoan = errors.New("item is not on loan")
+	// ErrLoanLimit is returned when the borrower has as many loans as their policy allows
+	ErrLoanLimit = errors.New("loan limit reached")
+	// ErrLoanClosed is returned when a returned loan is renewed
+	ErrLoanClosed = errors.New("loan has been returned")
+	// ErrRenewalLimit is returned when a loan has been renewed as often as allowed
+	ErrRenewalLimit = errors.New("renewal limit reached")
+	// ErrHoldsWaiting is returned when a loan is renewed while others wait for the record
+	ErrHoldsWaiting = errors.New("other users are waiting for this item")
+	// ErrHoldLimit is returned when the user has as many holds as their policy allows
+	ErrHoldLimit = errors.New("hold limit reached")
+	// ErrHoldExists is returned when the user already holds the record
+	ErrHoldExists = errors.New("record is already on hold for the user")
+	// ErrAlreadyBorrowed is returned when the user places a hold on a record they have on loan
+	ErrAlreadyBorrowed = errors.New("user already has a copy on loan")
+	// ErrNoItems is returned when a hold is placed on a record without lendable copies
+	ErrNoItems = errors.New("record has no copies to lend")
+	// ErrHoldClosed is returned when a hold that is no longer active is cancelled
+	ErrHoldClosed = errors.New("hold is no longer active")
+)
+
+// CirculationRepository defines the interface for loans and holds. Every operation that
+// changes an item's status locks the item, so a copy is never lent or set aside twice.
+type CirculationRepository interface {
+	CheckOut(loan *models.Loan, maxLoans int) (fulfilled, next *models.Hold, err error)
+	CheckIn(itemID, checkedInBy uuid.UUID, now time.Time) (*models.Loan, *models.Hold, error)
+	Renew(loanID uuid.UUID, dueAt time.Time, maxRenewals int) error
+	FindLoan(id uuid.UUID) (*models.Loan, error)
+	ListLoans(filters LoanFilters, offset, limit int) ([]models.Loan, int64, error)
+	ListOverdue(now time.Time, limit int) ([]models.Loan, error)
+	MarkOverdueNotified(id uuid.UUID, now time.Time) error
+
+	PlaceHold(hold *models.Hold, maxHolds int, now time.Time) error
+	CloseHold(id uuid.UUID, status models.HoldStatus, now time.Time) (*models.Hold, error)
+	FindHold(id uuid.UUID) (*models.Hold, error)
+	ListHolds(filters HoldFilters, offset, limit int) ([]models.Hold, int64, error)
+	ListExpiredHolds(now time.Time, limit int) ([]models.Hold, error)
+	QueuePosition(hold *models.Hold) (int, error)
+	CountWaiting(documentID uuid.UUID) (int64, error)
+	ShelveItem(itemID uuid.UUID, now time.Time) (*models.Hold, error)
+}
+
+// LoanFilters represents filters for listing loans
+type LoanFilters struct {
+	UserID     *uuid.UUID
+	ItemID     *uuid.UUID
+	Open       bool       // Only loans not yet returned
+	OverdueAt  *time.Time // Only loans open past their due date at this time
+	DocumentID *uuid.UUID
+}
+
+// HoldFilters represents filters for listing holds
+type HoldFilters struct {
+	UserID     *uuid.UUID
+	DocumentID *uuid.UUID
+	Active     bool // Only waiting and ready holds
+}
+
+type circulationRepository struct {
+	db *gorm.DB
+}
+
+// NewCirculationRepository creates a new circulation repository
+func NewCirculationRepository(db *gorm.DB) CirculationRepository {
+	return &circulationRepository{db: db}
+}
+
+// CheckOut lends an item that is on the shelf, or on the hold shelf for the borrower, unless
+// the borrower already has maxLoans open loans. A hold the borrower had on the record is
+// fulfilled and returned, with the hold that the copy set aside for it passed to, if any.
+func (r *circulationRepository) CheckOut(loan *models.Loan, maxLoans int) (*models.Hold, *models.Hold, error) {
+	var fulfilled, next *models.Hold
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		item, err := lockItem(tx, loan.ItemID)
+		if err != nil {
+			return err
+		}
+
+		switch item.Status {
+		case models.ItemAvailable:
+		case models.ItemOnHoldShelf:
+			var hold models.Hold
+			if err := tx.Where("item_id = ? AND status = ?", item.ID, models.HoldReady).First(&hold).Error; err != nil {
+				return err
+			}
+			if hold.UserID != loan.UserID {
+				return ErrItemHeld
+			}
+		default:
+			return ErrItemUnavailable
+		}
+
+		// Loans of one borrower are counted one checkout at a time
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(hashtext(?))", "loans:"+loan.UserID.String()).Error; err != nil {
+			return err
+		}
+		var open int64
+		if err := tx.Model(&models.Loan{}).
+			Where("user_id = ? AND returned_at IS NULL", loan.UserID).
+			Count(&open).Error; err != nil {
+			return err
+		}
+		if open >= int64(maxLoans) {
+			return ErrLoanLimit
+		}
+
+		if err := tx.Create(loan).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(item).Update("status", models.ItemOnLoan).Error; err != nil {
+			return err
+		}
+
+		// Borrowing any copy fulfils the borrower's hold on the record
+		if item.DocumentID != nil {
+			var hold models.Hold
+			err := tx.Where("document_id = ? AND user_id = ? AND status IN ?", *item.DocumentID, loan.UserID,
+				[]models.HoldStatus{models.HoldWaiting, models.HoldReady}).First(&hold).Error
+			if err == nil {
+				if hold.Status == models.HoldReady && hold.ItemID != nil && *hold.ItemID != item.ID {
+					// The copy set aside for the borrower goes to the next in the queue
+					if next, err = closeHold(tx, &hold, models.HoldFulfilled, loan.CheckedOutAt); err != nil {
+						return err
+					}
+				} else if err := tx.Model(&hold).Updates(map[string]interface{}{
+					"status":    models.HoldFulfilled,
+					"closed_at": loan.CheckedOutAt,
+				}).Error; err != nil {
+					return err
+				}
+				hold.Status = models.HoldFulfilled
+				fulfilled = &hold
+			} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+				return err
+			}
+		}
+		return nil
+	})
+	return fulfilled, next, err
+}
+
+// CheckIn closes the open loan of an item and passes the item to the first hold waiting for
+// its record, which is returned, or back to the shelf
+func (r *circulationRepository) CheckIn(itemID, checkedInBy uuid.UUID, now time.Time) (*models.Loan, *models.Hold, error) {
+	var loan models.Loan
+	var next *models.Hold
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		item, err := lockItem(tx, itemID)
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Where("item_id = ? AND returned_at IS NULL", itemID).First(&loan).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrNoOpenLoan
+			}
+			return err
+		}
+		loan.ReturnedAt = &now
+		loan.CheckedInBy = &checkedInBy
+		if err := tx.Model(&loan).Updates(map[string]interface{}{
+			"returned_at":   now,
+			"checked_in_by": checkedInBy,
+		}).Error; err != nil {
+			return err
+		}
+
+		// Copies reported lost, withdrawn or in repair keep that status when they come back
+		if item.Status != models.ItemOnLoan {
+			return nil
+		}
+		next, err = releaseItem(tx, item, now)
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return &loan, next, nil
+}
+
+// Renew extends an open loan to dueAt unless it was renewed maxRenewals times already or
+// another user is waiting for the record
+func (r *circulationRepository) Renew(loanID uuid.UUID, dueAt time.Time, maxRenewals int) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var loan models.Loan
+		if err := tx.Raw("SELECT * FROM loans WHERE id = ? FOR UPDATE", loanID).Scan(&loan).Error; err != nil {
+			return err
+		}
+		if loan.ID == uuid.Nil {
+			return errors.New("loan not found")
+		}
+		if loan.ReturnedAt != nil {
+			return ErrLoanClosed
+		}
+		if loan.Renewals >= maxRenewals {
+			return ErrRenewalLimit
+		}
+
+		var waiting int64
+		if err := tx.Model(&models.Hold{}).
+			Where("status = ? AND document_id = (SELECT document_id FROM items WHERE id = ?)", models.HoldWaiting, loan.ItemID).
+			Count(&waiting).Error; err != nil {
+			return err
+		}
+		if waiting > 0 {
+			return ErrHoldsWaiting
+		}
+
+		return tx.Model(&loan).Updates(map[string]interface{}{
+			"due_at":              dueAt,
+			"renewals":            gorm.Expr("renewals + 1"),
+			"overdue_notified_at": nil,
+		}).Error
+	})
+}
+
+// FindLoan retrieves a loan by ID with its item
+func (r *circulationRepository) FindLoan(id uuid.UUID) (*models.Loan, error) {
+	var loan models.Loan
+	if err := r.db.Preload("Item").Where("id = ?", id).First(&loan).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("loan not found")
+		}
+		return nil, err
+	}
+	return &loan, nil
+}
+
+// ListLoans lists loans with filters and pagination, newest first, with their items
+func (r *circulationRepository) ListLoans(filters LoanFilters, offset, limit int) ([]models.Loan, int64, error) {
+	var loans []models.Loan
+	var total int64
+
+	query := r.db.Model(&models.Loan{})
+
+	if filters.UserID != nil {
+		query = query.Where("user_id = ?", *filters.UserID)
+	}
+	if filters.ItemID != nil {
+		query = query.Where("item_id = ?", *filters.ItemID)
+	}
+	if filters.DocumentID != nil {
+		query = query.Where("item_id IN (SELECT id FROM items WHERE document_id = ?)", *filters.DocumentID)
+	}
+	if filters.Open {
+		query = query.Where("returned_at IS NULL")
+	}
+	if filters.OverdueAt != nil {
+		query = query.Where("returned_at IS NULL AND due_at < ?", *filters.OverdueAt)
+	}
+
+	// Get total count
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	if err := query.Preload("Item").Offset(offset).Limit(limit).Order("checked_out_at DESC").Find(&loans).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return loans, total, nil
+}
+
+// ListOverdue lists open loans past their due date that have not been reported overdue
+func (r *circulationRepository) ListOverdue(now time.Time, limit int) ([]models.Loan, error) {
+	var loans []models.Loan
+	err := r.db.Preload("Item").
+		Where("returned_at IS NULL AND due_at < ? AND overdue_notified_at IS NULL", now).
+		Order("due_at").
+		Limit(limit).
+		Find(&loans).Error
+	return loans, err
+}
+
+// MarkOverdueNotified records that a loan was reported overdue
+func (r *circulationRepository) MarkOverdueNotified(id uuid.UUID, now time.Time) error {
+	return r.db.Model(&models.Loan{}).Where("id = ?", id).Update("overdue_notified_at", now).Error
+}
+
+// PlaceHold queues a user for a record unless they already hold or borrow it, have
+// maxHolds active holds, or the record has no lendable copies. If a copy is on the shelf
+// it is set aside at once and the hold is ready.
+func (r *circulationRepository) PlaceHold(hold *models.Hold, maxHolds int, now time.Time) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(hashtext(?))", "holds:"+hold.UserID.String()).Error; err != nil {
+			return err
+		}
+
+		var active int64
+		if err := tx.Model(&models.Hold{}).
+			Where("user_id = ? AND status IN ?", hold.UserID, []models.HoldStatus{models.HoldWaiting, models.HoldReady}).
+			Count(&active).Error; err != nil {
+			return err
+		}
+		if active >= int64(maxHolds) {
+			return ErrHoldLimit
+		}
+
+		var existing int64
+		if err := tx.Model(&models.Hold{}).
+			Where("user_id = ? AND document_id = ? AND status IN ?", hold.UserID, hold.DocumentID,
+				[]models.HoldStatus{models.HoldWaiting, models.HoldReady}).
+			Count(&existing).Error; err != nil {
+			return err
+		}
+		if existing > 0 {
+			return ErrHoldExists
+		}
+
+		var borrowed int64
+		if err := tx.Model(&models.Loan{}).
+			Where("user_id = ? AND returned_at IS NULL AND item_id IN (SELECT id FROM items WHERE document_id = ?)",
+				hold.UserID, hold.DocumentID).
+			Count(&borrowed).Error; err != nil {
+			return err
+		}
+		if borrowed > 0 {
+			return ErrAlreadyBorrowed
+		}
+
+		var lendable int64
+		if err := tx.Model(&models.Item{}).
+			Where("document_id = ? AND status NOT IN ?", hold.DocumentID,
+				[]models.ItemStatus{models.ItemLost, models.ItemWithdrawn}).
+			Count(&lendable).Error; err != nil {
+			return err
+		}
+		if lendable == 0 {
+			return ErrNoItems
+		}
+
+		hold.Status = models.HoldWaiting
+		hold.PlacedAt = now
+		if err := tx.Create(hold).Error; err != nil {
+			return err
+		}
+
+		// A copy on the shelf is set aside straight away
+		var item models.Item
+		err := tx.Raw(`SELECT * FROM items WHERE document_id = ? AND status = ?
+			ORDER BY created_at LIMIT 1 FOR UPDATE SKIP LOCKED`, hold.DocumentID, models.ItemAvailable).
+			Scan(&item).Error
+		if err != nil {
+			return err
+		}
+		if item.ID == uuid.Nil {
+			return nil
+		}
+		ready, err := readyHold(tx, &item, hold, now)
+		if err != nil {
+			return err
+		}
+		*hold = *ready
+		return nil
+	})
+}
+
+// CloseHold cancels or expires an active hold. A copy set aside for it passes to the next
+// hold in the queue, which is returned, or back to the shelf.
+func (r *circulationRepository) CloseHold(id uuid.UUID, status models.HoldStatus, now time.Time) (*models.Hold, error) {
+	var next *models.Hold
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var hold models.Hold
+		if err := tx.Raw("SELECT * FROM holds WHERE id = ? FOR UPDATE", id).Scan(&hold).Error; err != nil {
+			return err
+		}
+		if hold.ID == uuid.Nil {
+			return errors.New("hold not found")
+		}
+		if !hold.Active() {
+			return ErrHoldClosed
+		}
+
+		var err error
+		next, err = closeHold(tx, &hold, status, now)
+		return err
+	})
+	return next, err
+}
+
+// FindHold retrieves a hold by ID with the copy set aside for it
+func (r *circulationRepository) FindHold(id uuid.UUID) (*models.Hold, error) {
+	var hold models.Hold
+	if err := r.db.Preload("Item").Where("id = ?", id).First(&hold).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("hold not found")
+		}
+		return nil, err
+	}
+	return &hold, nil
+}
+
+// ListHolds lists holds with filters and pagination, in queue order
+func (r *circulationRepository) ListHolds(filters HoldFilters, offset, limit int) ([]models.Hold, int64, error) {
+	var holds []models.Hold
+	var total int64
+
+	query := r.db.Model(&models.Hold{})
+
+	if filters.UserID != nil {
+		query = query.Where("user_id = ?", *filters.UserID)
+	}
+	if filters.DocumentID != nil {
+		query = query.Where("document_id = ?", *filters.DocumentID)
+	}
+	if filters.Active {
+		query = query.Where("status IN ?", []models.HoldStatus{models.HoldWaiting, models.HoldReady})
+	}
+
+	// Get total count
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	if err := query.Preload("Item").Offset(offset).Limit(limit).Order("placed_at").Find(&holds).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return holds, total, nil
+}
+
+// ListExpiredHolds lists ready holds whose pickup deadline has passed
+func (r *circulationRepository) ListExpiredHolds(now time.Time, limit int) ([]models.Hold, error) {
+	var holds []models.Hold
+	err := r.db.
+		Where("status = ? AND expires_at < ?", models.HoldReady, now).
+		Order("expires_at").
+		Limit(limit).
+		Find(&holds).Error
+	return holds, err
+}
+
+// QueuePosition returns the place of a waiting hold in the queue for its record, from 1
+func (r *circulationRepository) QueuePosition(hold *models.Hold) (int, error) {
+	var ahead int64
+	err := r.db.Model(&models.Hold{}).
+		Where("document_id = ? AND status = ? AND (placed_at < ? OR (placed_at = ? AND id < ?))",
+			hold.DocumentID, models.HoldWaiting, hold.PlacedAt, hold.PlacedAt, hold.ID).
+		Count(&ahead).Error
+	return int(ahead) + 1, err
+}
+
+// CountWaiting counts the holds waiting for a record
+func (r *circulationRepository) CountWaiting(documentID uuid.UUID) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.Hold{}).
+		Where("document_id = ? AND status = ?", documentID, models.HoldWaiting).
+		Count(&count).Error
+	return count, err
+}
+
+// ShelveItem passes an item that has come back on the shelf to the first hold waiting for
+// its record, which is returned. Items not on the shelf are left alone.
+func (r *circulationRepository) ShelveItem(itemID uuid.UUID, now time.Time) (*models.Hold, error) {
+	var next *models.Hold
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		item, err := lockItem(tx, itemID)
+		if err != nil {
+			return err
+		}
+		if item.Status != models.ItemAvailable {
+			return nil
+		}
+		next, err = releaseItem(tx, item, now)
+		return err
+	})
+	return next, err
+}
+
+// lockItem locks an item for the rest of the transaction
+func lockItem(tx *gorm.DB, id uuid.UUID) (*models.Item, error) {
+	var item models.Item
+	if err := tx.Raw("SELECT * FROM items WHERE id = ? FOR UPDATE", id).Scan(&item).Error; err != nil {
+		return nil, err
+	}
+	if item.ID == uuid.Nil {
+		return nil, errors.New("item not found")
+	}
+	return &item, nil
+}
+
+// closeHold closes a locked hold and releases the copy set aside for it
+func closeHold(tx *gorm.DB, hold *models.Hold, status models.HoldStatus, now time.Time) (*models.Hold, error) {
+	wasReady := hold.Status == models.HoldReady && hold.ItemID != nil
+	if err := tx.Model(hold).Updates(map[string]interface{}{
+		"status":    status,
+		"closed_at": now,
+	}).Error; err != nil {
+		return nil, err
+	}
+	if !wasReady {
+		return nil, nil
+	}
+
+	item, err := lockItem(tx, *hold.ItemID)
+	if err != nil {
+		return nil, err
+	}
+	if item.Status != models.ItemOnHoldShelf {
+		return nil, nil
+	}
+	return releaseItem(tx, item, now)
+}
+
+// releaseItem sets a locked item aside for the first hold waiting for its record, which is
+// returned, or puts it back on the shelf
+func releaseItem(tx *gorm.DB, item *models.Item, now time.Time) (*models.Hold, error) {
+	if item.DocumentID != nil {
+		var hold models.Hold
+		err := tx.Raw(`SELECT * FROM holds WHERE document_id = ? AND status = ?
+			ORDER BY placed_at, id LIMIT 1 FOR UPDATE SKIP LOCKED`, *item.DocumentID, models.HoldWaiting).
+			Scan(&hold).Error
+		if err != nil {
+			return nil, err
+		}
+		if hold.ID != uuid.Nil {
+			return readyHold(tx, item, &hold, now)
+		}
+	}
+
+	return nil, tx.Model(item).Update("status", models.ItemAvailable).Error
+}
+
+// readyHold sets a locked item aside for a hold until the pickup deadline of the holder's
+// role
+func readyHold(tx *gorm.DB, item *models.Item, hold *models.Hold, now time.Time) (*models.Hold, error) {
+	err := tx.Exec(`UPDATE holds SET status = ?, item_id = ?, ready_at = ?,
+			expires_at = CAST(? AS TIMESTAMP) + make_interval(days => COALESCE((
+				SELECT p.hold_pickup_days FROM loan_policies p JOIN users u ON u.role = p.role
+				WHERE u.id = holds.user_id), 7))
+		WHERE id = ?`,
+		models.HoldReady, item.ID, now, now, hold.ID).Error
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Model(item).Update("status", models.ItemOnHoldShelf).Error; err != nil {
+		return nil, err
+	}
+
+	var ready models.Hold
+	if err := tx.Where("id = ?", hold.ID).First(&ready).Error; err != nil {
+		return nil, err
+	}
+	return &ready, nil
+}
@@ -0,0 +1,123 @@
+package repository
+
+import (
+	"errors"
+
+	"github.com/Kyei-Ernest/libsystem/shared/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ErrBarcodeTaken is returned when another item has the barcode
+var ErrBarcodeTaken = errors.New("barcode already in use")
+
+// ItemRepository defines the interface for physical copies
+type ItemRepository interface {
+	Create(item *models.Item) error
+	FindByID(id uuid.UUID) (*models.Item, error)
+	FindByBarcode(barcode string) (*models.Item, error)
+	Update(item *models.Item) error
+	List(filters ItemFilters, offset, limit int) ([]models.Item, int64, error)
+	DocumentExists(documentID uuid.UUID) (bool, error)
+}
+
+// ItemFilters represents filters for listing items
+type ItemFilters struct {
+	DocumentID *uuid.UUID
+	Status     models.ItemStatus
+	Location   string
+	Barcode    string
+}
+
+type itemRepository struct {
+	db *gorm.DB
+}
+
+// NewItemRepository creates a new item repository
+func NewItemRepository(db *gorm.DB) ItemRepository {
+	return &itemRepository{db: db}
+}
+
+// Create creates a new item unless its barcode is in use
+func (r *itemRepository) Create(item *models.Item) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var taken int64
+		if err := tx.Model(&models.Item{}).Where("barcode = ?", item.Barcode).Count(&taken).Error; err != nil {
+			return err
+		}
+		if taken > 0 {
+			return ErrBarcodeTaken
+		}
+		return tx.Create(item).Error
+	})
+}
+
+// FindByID retrieves an item by ID
+func (r *itemRepository) FindByID(id uuid.UUID) (*models.Item, error) {
+	var item models.Item
+	if err := r.db.Where("id = ?", id).First(&item).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("item not found")
+		}
+		return nil, err
+	}
+	return &item, nil
+}
+
+// FindByBarcode retrieves an item by barcode
+func (r *itemRepository) FindByBarcode(barcode string) (*models.Item, error) {
+	var item models.Item
+	if err := r.db.Where("barcode = ?", barcode).First(&item).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("item not found")
+		}
+		return nil, err
+	}
+	return &item, nil
+}
+
+// Update saves an item's description and status
+func (r *itemRepository) Update(item *models.Item) error {
+	return r.db.Model(item).
+		Select("document_id", "call_number", "location", "condition", "status", "notes").
+		Updates(item).Error
+}
+
+// List lists items with filters and pagination, by call number
+func (r *itemRepository) List(filters ItemFilters, offset, limit int) ([]models.Item, int64, error) {
+	var items []models.Item
+	var total int64
+
+	query := r.db.Model(&models.Item{})
+
+	if filters.DocumentID != nil {
+		query = query.Where("document_id = ?", *filters.DocumentID)
+	}
+	if filters.Status != "" {
+		query = query.Where("status = ?", filters.Status)
+	}
+	if filters.Location != "" {
+		query = query.Where("location ILIKE ?", "%"+filters.Location+"%")
+	}
+	if filters.Barcode != "" {
+		query = query.Where("barcode = ?", filters.Barcode)
+	}
+
+	// Get total count
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	if err := query.Offset(offset).Limit(limit).Order("call_number, barcode").Find(&items).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return items, total, nil
+}
+
+// DocumentExists reports whether a catalogue record exists and is not in the trash
+func (r *itemRepository) DocumentExists(documentID uuid.UUID) (bool, error) {
+	var count int64
+	err := r.db.Table("documents").Where("id = ? AND deleted_at IS NULL", documentID).Count(&count).Error
+	return count > 0, err
+}
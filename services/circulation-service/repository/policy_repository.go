@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"errors"
+
+	"github.com/Kyei-Ernest/libsystem/shared/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// PolicyRepository defines the interface for loan policies and the borrowers they apply to
+type PolicyRepository interface {
+	List() ([]models.LoanPolicy, error)
+	FindByRole(role models.UserRole) (*models.LoanPolicy, error)
+	Update(policy *models.LoanPolicy) error
+	FindBorrower(userID uuid.UUID) (*models.User, error)
+}
+
+type policyRepository struct {
+	db *gorm.DB
+}
+
+// NewPolicyRepository creates a new policy repository
+func NewPolicyRepository(db *gorm.DB) PolicyRepository {
+	return &policyRepository{db: db}
+}
+
+// List lists the loan policies of all roles
+func (r *policyRepository) List() ([]models.LoanPolicy, error) {
+	var policies []models.LoanPolicy
+	err := r.db.Order("role").Find(&policies).Error
+	return policies, err
+}
+
+// FindByRole retrieves the loan policy of a role
+func (r *policyRepository) FindByRole(role models.UserRole) (*models.LoanPolicy, error) {
+	var policy models.LoanPolicy
+	if err := r.db.Where("role = ?", role).First(&policy).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("loan policy not found")
+		}
+		return nil, err
+	}
+	return &policy, nil
+}
+
+// Update saves a loan policy
+func (r *policyRepository) Update(policy *models.LoanPolicy) error {
+	return r.db.Model(policy).
+		Select("loan_period_days", "max_loans", "max_renewals", "max_holds", "hold_pickup_days").
+		Updates(policy).Error
+}
+
+// FindBorrower retrieves a user who may borrow, for their role
+func (r *policyRepository) FindBorrower(userID uuid.UUID) (*models.User, error) {
+	var user models.User
+	if err := r.db.Select("id", "role", "is_active").Where("id = ?", userID).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("user not found")
+		}
+		return nil, err
+	}
+	return &user, nil
+}
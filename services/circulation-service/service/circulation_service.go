@@ -0,0 +1,524 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Kyei-Ernest/libsystem/services/circulation-service/repository"
+	appErrors "github.com/Kyei-Ernest/libsystem/shared/errors"
+	"github.com/Kyei-Ernest/libsystem/shared/kafka"
+	"github.com/Kyei-Ernest/libsystem/shared/models"
+	"github.com/google/uuid"
+)
+
+// Circulation events, consumed by the analytics service
+const (
+	TopicCheckedOut = "circulation.checked_out"
+	TopicCheckedIn  = "circulation.checked_in"
+	TopicRenewed    = "circulation.renewed"
+	TopicOverdue    = "circulation.overdue"
+	TopicHoldPlaced = "circulation.hold_placed"
+	TopicHoldReady  = "circulation.hold_ready"
+	TopicHoldClosed = "circulation.hold_closed"
+)
+
+// sweepBatch is the number of overdue loans or expired holds handled per query
+const sweepBatch = 100
+
+// PolicyInput represents the rules of a loan policy
+type PolicyInput struct {
+	LoanPeriodDays int `json:"loan_period_days" binding:"required,min=1"`
+	MaxLoans       int `json:"max_loans" binding:"min=0"`
+	MaxRenewals    int `json:"max_renewals" binding:"min=0"`
+	MaxHolds       int `json:"max_holds" binding:"min=0"`
+	HoldPickupDays int `json:"hold_pickup_days" binding:"required,min=1"`
+}
+
+// CheckInResult is a returned loan and the hold its item was set aside for, if any
+type CheckInResult struct {
+	Loan *models.Loan `json:"loan"`
+	Hold *models.Hold `json:"hold,omitempty"` // Put the item on the hold shelf for this user
+}
+
+// CirculationService lends physical items: checkout and check-in at the desk, renewals,
+// the hold queue, and detection of overdue loans and uncollected holds. Loan periods and
+// limits come from the policy of the borrower's role.
+type CirculationService interface {
+	CheckOut(barcode string, borrowerID, staffID uuid.UUID) (*models.Loan, error)
+	CheckIn(barcode string, staffID uuid.UUID) (*CheckInResult, error)
+	Renew(loanID, userID uuid.UUID, isStaff bool) (*models.Loan, error)
+	GetLoan(loanID, userID uuid.UUID, isStaff bool) (*models.Loan, error)
+	ListLoans(filters repository.LoanFilters, page, pageSize int) ([]models.Loan, int64, error)
+
+	PlaceHold(documentID, holderID uuid.UUID) (*models.Hold, error)
+	CancelHold(holdID, userID uuid.UUID, isStaff bool) error
+	ListHolds(filters repository.HoldFilters, page, pageSize int) ([]models.Hold, int64, error)
+
+	ListPolicies() ([]models.LoanPolicy, error)
+	UpdatePolicy(role models.UserRole, input PolicyInput) (*models.LoanPolicy, error)
+
+	ShelveItem(itemID uuid.UUID)
+	ReportOverdue(now time.Time) (int, error)
+	ExpireHolds(now time.Time) (int, error)
+}
+
+// circulationService implements CirculationService
+type circulationService struct {
+	circulationRepo repository.CirculationRepository
+	itemRepo        repository.ItemRepository
+	policyRepo      repository.PolicyRepository
+	producer        *kafka.Producer
+}
+
+// NewCirculationService creates a new circulation service
+func NewCirculationService(circulationRepo repository.CirculationRepository, itemRepo repository.ItemRepository, policyRepo repository.PolicyRepository, producer *kafka.Producer) CirculationService {
+	return &circulationService{
+		circulationRepo: circulationRepo,
+		itemRepo:        itemRepo,
+		policyRepo:      policyRepo,
+		producer:        producer,
+	}
+}
+
+// CheckOut lends the item with a barcode to a borrower, due after their loan period
+func (s *circulationService) CheckOut(barcode string, borrowerID, staffID uuid.UUID) (*models.Loan, error) {
+	item, err := s.itemRepo.FindByBarcode(strings.TrimSpace(barcode))
+	if err != nil {
+		return nil, appErrors.NewNotFoundError("Item", err)
+	}
+	policy, err := s.borrowerPolicy(borrowerID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Borrowers with overdue items must return them first
+	now := time.Now()
+	_, overdue, err := s.circulationRepo.ListLoans(repository.LoanFilters{UserID: &borrowerID, OverdueAt: &now}, 0, 1)
+	if err != nil {
+		return nil, appErrors.NewInternalError("Failed to check loans", err)
+	}
+	if overdue > 0 {
+		return nil, appErrors.NewForbiddenError("Borrower has overdue items", nil)
+	}
+
+	loan := &models.Loan{
+		ItemID:       item.ID,
+		UserID:       borrowerID,
+		CheckedOutBy: staffID,
+		CheckedOutAt: now,
+		DueAt:        now.AddDate(0, 0, policy.LoanPeriodDays),
+	}
+	fulfilled, next, err := s.circulationRepo.CheckOut(loan, policy.MaxLoans)
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrItemUnavailable):
+			return nil, conflict(fmt.Sprintf("Item %s is %s", item.Barcode, item.Status), err)
+		case errors.Is(err, repository.ErrItemHeld):
+			return nil, conflict("Item is on the hold shelf for another patron", err)
+		case errors.Is(err, repository.ErrLoanLimit):
+			return nil, appErrors.NewValidationError(
+				fmt.Sprintf("Borrower already has %d items on loan", policy.MaxLoans), err)
+		}
+		return nil, appErrors.NewInternalError("Failed to check out item", err)
+	}
+
+	loan.Item = item
+	s.publish(TopicCheckedOut, loan.ID, loanEvent(loan, item, now))
+	// The borrower's hold is closed; a copy set aside for it passes to the next in the queue
+	if fulfilled != nil {
+		s.publish(TopicHoldClosed, fulfilled.ID, holdEvent(fulfilled, now))
+	}
+	s.publishHoldReady(next, nil)
+	return s.loadLoan(loan.ID)
+}
+
+// CheckIn returns the item with a barcode. If someone is waiting for its record the item
+// goes to the hold shelf for them.
+func (s *circulationService) CheckIn(barcode string, staffID uuid.UUID) (*CheckInResult, error) {
+	item, err := s.itemRepo.FindByBarcode(strings.TrimSpace(barcode))
+	if err != nil {
+		return nil, appErrors.NewNotFoundError("Item", err)
+	}
+
+	now := time.Now()
+	loan, hold, err := s.circulationRepo.CheckIn(item.ID, staffID, now)
+	if err != nil {
+		if errors.Is(err, repository.ErrNoOpenLoan) {
+			return nil, appErrors.NewValidationError(fmt.Sprintf("Item %s is not on loan", item.Barcode), err)
+		}
+		return nil, appErrors.NewInternalError("Failed to check in item", err)
+	}
+
+	event := loanEvent(loan, item, now)
+	event["overdue_days"] = overdueDays(loan.DueAt, now)
+	s.publish(TopicCheckedIn, loan.ID, event)
+	s.publishHoldReady(hold, item)
+
+	result := &CheckInResult{Hold: hold}
+	if result.Loan, err = s.loadLoan(loan.ID); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Renew extends a loan by the borrower's loan period, counted from now (or from the due
+// date, if later). Loans cannot be renewed while others wait for the record.
+func (s *circulationService) Renew(loanID, userID uuid.UUID, isStaff bool) (*models.Loan, error) {
+	loan, err := s.GetLoan(loanID, userID, isStaff)
+	if err != nil {
+		return nil, err
+	}
+	policy, err := s.borrowerPolicy(loan.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	from := now
+	if loan.DueAt.After(from) {
+		from = loan.DueAt
+	}
+	dueAt := from.AddDate(0, 0, policy.LoanPeriodDays)
+
+	if err := s.circulationRepo.Renew(loanID, dueAt, policy.MaxRenewals); err != nil {
+		switch {
+		case errors.Is(err, repository.ErrLoanClosed):
+			return nil, appErrors.NewValidationError("The item has been returned", err)
+		case errors.Is(err, repository.ErrRenewalLimit):
+			return nil, appErrors.NewValidationError(
+				fmt.Sprintf("Loans can be renewed at most %d times", policy.MaxRenewals), err)
+		case errors.Is(err, repository.ErrHoldsWaiting):
+			return nil, conflict("Other patrons are waiting for this record", err)
+		}
+		return nil, appErrors.NewInternalError("Failed to renew loan", err)
+	}
+
+	renewed, err := s.loadLoan(loanID)
+	if err != nil {
+		return nil, err
+	}
+	s.publish(TopicRenewed, loanID, loanEvent(renewed, renewed.Item, now))
+	return renewed, nil
+}
+
+// GetLoan retrieves a loan of the user; staff may see any loan
+func (s *circulationService) GetLoan(loanID, userID uuid.UUID, isStaff bool) (*models.Loan, error) {
+	loan, err := s.loadLoan(loanID)
+	if err != nil {
+		return nil, err
+	}
+	if !isStaff && loan.UserID != userID {
+		return nil, appErrors.NewNotFoundError("Loan", fmt.Errorf("loan %s belongs to another user", loanID))
+	}
+	return loan, nil
+}
+
+// ListLoans lists loans with filters and pagination
+func (s *circulationService) ListLoans(filters repository.LoanFilters, page, pageSize int) ([]models.Loan, int64, error) {
+	offset := (page - 1) * pageSize
+	loans, total, err := s.circulationRepo.ListLoans(filters, offset, pageSize)
+	if err != nil {
+		return nil, 0, appErrors.NewInternalError("Failed to list loans", err)
+	}
+
+	now := time.Now()
+	for i := range loans {
+		loans[i].Overdue = loans[i].IsOverdue(now)
+	}
+	return loans, total, nil
+}
+
+// PlaceHold queues a user for the next copy of a record. If a copy is on the shelf it is
+// set aside at once.
+func (s *circulationService) PlaceHold(documentID, holderID uuid.UUID) (*models.Hold, error) {
+	policy, err := s.borrowerPolicy(holderID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	hold := &models.Hold{DocumentID: documentID, UserID: holderID}
+	if err := s.circulationRepo.PlaceHold(hold, policy.MaxHolds, now); err != nil {
+		switch {
+		case errors.Is(err, repository.ErrHoldLimit):
+			return nil, appErrors.NewValidationError(fmt.Sprintf("At most %d holds may be active", policy.MaxHolds), err)
+		case errors.Is(err, repository.ErrHoldExists):
+			return nil, appErrors.NewConflictError("Hold", err)
+		case errors.Is(err, repository.ErrAlreadyBorrowed):
+			return nil, conflict("A copy of this record is already on loan to the patron", err)
+		case errors.Is(err, repository.ErrNoItems):
+			return nil, appErrors.NewValidationError("The record has no copies to lend", err)
+		}
+		return nil, appErrors.NewInternalError("Failed to place hold", err)
+	}
+
+	s.publish(TopicHoldPlaced, hold.ID, holdEvent(hold, now))
+	if hold.Status == models.HoldReady {
+		s.publishHoldReady(hold, nil)
+	}
+	return s.loadHold(hold.ID)
+}
+
+// CancelHold cancels a hold of the user; staff may cancel any hold. A copy set aside for
+// it passes to the next in the queue.
+func (s *circulationService) CancelHold(holdID, userID uuid.UUID, isStaff bool) error {
+	hold, err := s.circulationRepo.FindHold(holdID)
+	if err != nil {
+		return appErrors.NewNotFoundError("Hold", err)
+	}
+	if !isStaff && hold.UserID != userID {
+		return appErrors.NewNotFoundError("Hold", fmt.Errorf("hold %s belongs to another user", holdID))
+	}
+
+	return s.closeHold(hold, models.HoldCancelled, time.Now())
+}
+
+// ListHolds lists holds with filters and pagination, with the queue position of those
+// still waiting
+func (s *circulationService) ListHolds(filters repository.HoldFilters, page, pageSize int) ([]models.Hold, int64, error) {
+	offset := (page - 1) * pageSize
+	holds, total, err := s.circulationRepo.ListHolds(filters, offset, pageSize)
+	if err != nil {
+		return nil, 0, appErrors.NewInternalError("Failed to list holds", err)
+	}
+
+	for i := range holds {
+		if holds[i].Status != models.HoldWaiting {
+			continue
+		}
+		if holds[i].Position, err = s.circulationRepo.QueuePosition(&holds[i]); err != nil {
+			return nil, 0, appErrors.NewInternalError("Failed to list holds", err)
+		}
+	}
+	return holds, total, nil
+}
+
+// ListPolicies lists the loan policies of all roles
+func (s *circulationService) ListPolicies() ([]models.LoanPolicy, error) {
+	policies, err := s.policyRepo.List()
+	if err != nil {
+		return nil, appErrors.NewInternalError("Failed to list loan policies", err)
+	}
+	return policies, nil
+}
+
+// UpdatePolicy changes the loan policy of a role. Open loans keep their due dates.
+func (s *circulationService) UpdatePolicy(role models.UserRole, input PolicyInput) (*models.LoanPolicy, error) {
+	policy, err := s.policyRepo.FindByRole(role)
+	if err != nil {
+		return nil, appErrors.NewNotFoundError("Loan policy", err)
+	}
+
+	policy.LoanPeriodDays = input.LoanPeriodDays
+	policy.MaxLoans = input.MaxLoans
+	policy.MaxRenewals = input.MaxRenewals
+	policy.MaxHolds = input.MaxHolds
+	policy.HoldPickupDays = input.HoldPickupDays
+	if err := s.policyRepo.Update(policy); err != nil {
+		return nil, appErrors.NewInternalError("Failed to update loan policy", err)
+	}
+
+	return s.policyRepo.FindByRole(role)
+}
+
+// ShelveItem passes an item that has come onto the shelf (new, or back from repair) to
+// the hold queue of its record
+func (s *circulationService) ShelveItem(itemID uuid.UUID) {
+	hold, err := s.circulationRepo.ShelveItem(itemID, time.Now())
+	if err != nil {
+		fmt.Printf("DEBUG: Failed to pass item %s to the hold queue: %v\n", itemID, err)
+		return
+	}
+	s.publishHoldReady(hold, nil)
+}
+
+// ReportOverdue publishes an event for each loan that has become overdue since the last
+// run, once per loan (or per renewal)
+func (s *circulationService) ReportOverdue(now time.Time) (int, error) {
+	reported := 0
+	for {
+		loans, err := s.circulationRepo.ListOverdue(now, sweepBatch)
+		if err != nil {
+			return reported, err
+		}
+		for i := range loans {
+			loan := &loans[i]
+			event := loanEvent(loan, loan.Item, now)
+			event["overdue_days"] = overdueDays(loan.DueAt, now)
+			s.publish(TopicOverdue, loan.ID, event)
+			if err := s.circulationRepo.MarkOverdueNotified(loan.ID, now); err != nil {
+				return reported, err
+			}
+			reported++
+		}
+		if len(loans) < sweepBatch {
+			return reported, nil
+		}
+	}
+}
+
+// ExpireHolds closes ready holds that were not picked up in time and passes their copies on
+func (s *circulationService) ExpireHolds(now time.Time) (int, error) {
+	expired := 0
+	for {
+		holds, err := s.circulationRepo.ListExpiredHolds(now, sweepBatch)
+		if err != nil {
+			return expired, err
+		}
+		for i := range holds {
+			if err := s.closeHold(&holds[i], models.HoldExpired, now); err != nil {
+				var appErr *appErrors.AppError
+				if errors.As(err, &appErr) && appErr.Code != appErrors.ErrCodeInternal {
+					continue // Closed meanwhile
+				}
+				return expired, err
+			}
+			expired++
+		}
+		if len(holds) < sweepBatch {
+			return expired, nil
+		}
+	}
+}
+
+// closeHold cancels or expires a hold and announces the hold its copy passes to
+func (s *circulationService) closeHold(hold *models.Hold, status models.HoldStatus, now time.Time) error {
+	next, err := s.circulationRepo.CloseHold(hold.ID, status, now)
+	if err != nil {
+		if errors.Is(err, repository.ErrHoldClosed) {
+			return appErrors.NewValidationError("The hold is no longer active", err)
+		}
+		return appErrors.NewInternalError("Failed to close hold", err)
+	}
+
+	hold.Status = status
+	event := holdEvent(hold, now)
+	event["status"] = status
+	s.publish(TopicHoldClosed, hold.ID, event)
+	s.publishHoldReady(next, nil)
+	return nil
+}
+
+// borrowerPolicy retrieves the loan policy of an active user's role
+func (s *circulationService) borrowerPolicy(userID uuid.UUID) (*models.LoanPolicy, error) {
+	user, err := s.policyRepo.FindBorrower(userID)
+	if err != nil {
+		return nil, appErrors.NewNotFoundError("User", err)
+	}
+	if !user.IsActive {
+		return nil, appErrors.NewForbiddenError("The user's account is disabled", nil)
+	}
+
+	policy, err := s.policyRepo.FindByRole(user.Role)
+	if err != nil {
+		return nil, appErrors.NewInternalError(fmt.Sprintf("No loan policy for role %s", user.Role), err)
+	}
+	return policy, nil
+}
+
+// loadLoan retrieves a loan with its item and overdue flag
+func (s *circulationService) loadLoan(id uuid.UUID) (*models.Loan, error) {
+	loan, err := s.circulationRepo.FindLoan(id)
+	if err != nil {
+		return nil, appErrors.NewNotFoundError("Loan", err)
+	}
+	loan.Overdue = loan.IsOverdue(time.Now())
+	return loan, nil
+}
+
+// loadHold retrieves a hold with its copy and queue position
+func (s *circulationService) loadHold(id uuid.UUID) (*models.Hold, error) {
+	hold, err := s.circulationRepo.FindHold(id)
+	if err != nil {
+		return nil, appErrors.NewNotFoundError("Hold", err)
+	}
+	if hold.Status == models.HoldWaiting {
+		if hold.Position, err = s.circulationRepo.QueuePosition(hold); err != nil {
+			return nil, appErrors.NewInternalError("Failed to load hold", err)
+		}
+	}
+	return hold, nil
+}
+
+// publishHoldReady announces that a copy is on the hold shelf for a user
+func (s *circulationService) publishHoldReady(hold *models.Hold, item *models.Item) {
+	if hold == nil {
+		return
+	}
+	event := holdEvent(hold, time.Now())
+	if item != nil {
+		event["barcode"] = item.Barcode
+	}
+	s.publish(TopicHoldReady, hold.ID, event)
+}
+
+// publish sends a circulation event without blocking the request
+func (s *circulationService) publish(topic string, key uuid.UUID, event map[string]interface{}) {
+	if s.producer == nil {
+		return
+	}
+	go func() {
+		if err := s.producer.PublishToTopic(context.Background(), topic, key.String(), event); err != nil {
+			fmt.Printf("Failed to publish %s event: %v\n", topic, err)
+		}
+	}()
+}
+
+func loanEvent(loan *models.Loan, item *models.Item, now time.Time) map[string]interface{} {
+	event := map[string]interface{}{
+		"id":          loan.ID,
+		"loan_id":     loan.ID,
+		"item_id":     loan.ItemID,
+		"user_id":     loan.UserID,
+		"due_at":      loan.DueAt,
+		"renewals":    loan.Renewals,
+		"occurred_at": now,
+	}
+	if item != nil {
+		event["barcode"] = item.Barcode
+		if item.DocumentID != nil {
+			event["document_id"] = *item.DocumentID
+		}
+	}
+	return event
+}
+
+func holdEvent(hold *models.Hold, now time.Time) map[string]interface{} {
+	event := map[string]interface{}{
+		"id":          hold.ID,
+		"hold_id":     hold.ID,
+		"document_id": hold.DocumentID,
+		"user_id":     hold.UserID,
+		"status":      hold.Status,
+		"occurred_at": now,
+	}
+	if hold.ItemID != nil {
+		event["item_id"] = *hold.ItemID
+	}
+	if hold.ExpiresAt != nil {
+		event["expires_at"] = *hold.ExpiresAt
+	}
+	return event
+}
+
+// conflict reports a request that clashes with the state of an item, loan or hold
+func conflict(message string, err error) *appErrors.AppError {
+	return &appErrors.AppError{
+		Code:       appErrors.ErrCodeConflict,
+		Message:    message,
+		HTTPStatus: http.StatusConflict,
+		Err:        err,
+	}
+}
+
+// overdueDays counts the whole days a loan is past its due date
+func overdueDays(dueAt, at time.Time) int {
+	if !at.After(dueAt) {
+		return 0
+	}
+	return int(at.Sub(dueAt).Hours() / 24)
+}
@@ -0,0 +1,237 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/Kyei-Ernest/libsystem/services/circulation-service/repository"
+	appErrors "github.com/Kyei-Ernest/libsystem/shared/errors"
+	"github.com/Kyei-Ernest/libsystem/shared/models"
+	"github.com/google/uuid"
+)
+
+// ItemInput describes a new physical copy
+type ItemInput struct {
+	DocumentID *uuid.UUID           `json:"document_id,omitempty"` // Catalogue record
+	Barcode    string               `json:"barcode" binding:"required"`
+	CallNumber string               `json:"call_number,omitempty"`
+	Location   string               `json:"location,omitempty"`
+	Condition  models.ItemCondition `json:"condition,omitempty"` // Good when omitted
+	Notes      string               `json:"notes,omitempty"`
+}
+
+// ItemUpdate represents the fields of an item that can be changed. Status can only be set
+// to available, in_repair, lost or withdrawn, and not while the item is on loan or on the
+// hold shelf (except to report it lost).
+type ItemUpdate struct {
+	DocumentID *uuid.UUID            `json:"document_id,omitempty"`
+	CallNumber *string               `json:"call_number,omitempty"`
+	Location   *string               `json:"location,omitempty"`
+	Condition  *models.ItemCondition `json:"condition,omitempty"`
+	Status     *models.ItemStatus    `json:"status,omitempty"`
+	Notes      *string               `json:"notes,omitempty"`
+}
+
+// Availability summarizes the copies of a catalogue record
+type Availability struct {
+	DocumentID   uuid.UUID     `json:"document_id"`
+	Total        int           `json:"total"`     // Copies that can be lent
+	Available    int           `json:"available"` // Copies on the shelf
+	WaitingHolds int64         `json:"waiting_holds"`
+	Items        []models.Item `json:"items"`
+}
+
+// ItemService manages the physical copies of catalogue records
+type ItemService interface {
+	CreateItem(input ItemInput) (*models.Item, error)
+	GetItem(id uuid.UUID) (*models.Item, error)
+	UpdateItem(id uuid.UUID, update ItemUpdate) (*models.Item, error)
+	ListItems(filters repository.ItemFilters, page, pageSize int) ([]models.Item, int64, error)
+	GetAvailability(documentID uuid.UUID) (*Availability, error)
+}
+
+// itemService implements ItemService
+type itemService struct {
+	itemRepo           repository.ItemRepository
+	circulationRepo    repository.CirculationRepository
+	circulationService CirculationService
+}
+
+// NewItemService creates a new item service. Copies that come onto the shelf are offered
+// to the hold queue through circulationService.
+func NewItemService(itemRepo repository.ItemRepository, circulationRepo repository.CirculationRepository, circulationService CirculationService) ItemService {
+	return &itemService{
+		itemRepo:           itemRepo,
+		circulationRepo:    circulationRepo,
+		circulationService: circulationService,
+	}
+}
+
+// CreateItem adds a copy of a catalogue record to circulation
+func (s *itemService) CreateItem(input ItemInput) (*models.Item, error) {
+	barcode := strings.TrimSpace(input.Barcode)
+	if barcode == "" || len(barcode) > 64 {
+		return nil, appErrors.NewValidationError("Barcode must be 1 to 64 characters", nil)
+	}
+	if input.Condition == "" {
+		input.Condition = models.ConditionGood
+	}
+	if err := validateCondition(input.Condition); err != nil {
+		return nil, err
+	}
+	if input.DocumentID != nil {
+		if err := s.checkDocument(*input.DocumentID); err != nil {
+			return nil, err
+		}
+	}
+
+	item := &models.Item{
+		DocumentID: input.DocumentID,
+		Barcode:    barcode,
+		CallNumber: strings.TrimSpace(input.CallNumber),
+		Location:   strings.TrimSpace(input.Location),
+		Condition:  input.Condition,
+		Status:     models.ItemAvailable,
+		Notes:      strings.TrimSpace(input.Notes),
+	}
+	if err := s.itemRepo.Create(item); err != nil {
+		if errors.Is(err, repository.ErrBarcodeTaken) {
+			return nil, appErrors.NewConflictError("Item", err)
+		}
+		return nil, appErrors.NewInternalError("Failed to create item", err)
+	}
+
+	s.circulationService.ShelveItem(item.ID)
+	return s.GetItem(item.ID)
+}
+
+// GetItem retrieves an item by ID
+func (s *itemService) GetItem(id uuid.UUID) (*models.Item, error) {
+	item, err := s.itemRepo.FindByID(id)
+	if err != nil {
+		return nil, appErrors.NewNotFoundError("Item", err)
+	}
+	return item, nil
+}
+
+// UpdateItem changes an item's description or takes it out of (or back into) circulation
+func (s *itemService) UpdateItem(id uuid.UUID, update ItemUpdate) (*models.Item, error) {
+	item, err := s.GetItem(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if update.DocumentID != nil {
+		if err := s.checkDocument(*update.DocumentID); err != nil {
+			return nil, err
+		}
+		item.DocumentID = update.DocumentID
+	}
+	if update.CallNumber != nil {
+		item.CallNumber = strings.TrimSpace(*update.CallNumber)
+	}
+	if update.Location != nil {
+		item.Location = strings.TrimSpace(*update.Location)
+	}
+	if update.Condition != nil {
+		if err := validateCondition(*update.Condition); err != nil {
+			return nil, err
+		}
+		item.Condition = *update.Condition
+	}
+	if update.Notes != nil {
+		item.Notes = strings.TrimSpace(*update.Notes)
+	}
+	shelved := false
+	if update.Status != nil && *update.Status != item.Status {
+		if err := checkStatusChange(item.Status, *update.Status); err != nil {
+			return nil, err
+		}
+		item.Status = *update.Status
+		shelved = item.Status == models.ItemAvailable
+	}
+
+	if err := s.itemRepo.Update(item); err != nil {
+		return nil, appErrors.NewInternalError("Failed to update item", err)
+	}
+
+	// A copy back from repair, or found, goes to the first hold waiting for it
+	if shelved || update.DocumentID != nil {
+		s.circulationService.ShelveItem(id)
+	}
+	return s.GetItem(id)
+}
+
+// ListItems lists items with filters and pagination
+func (s *itemService) ListItems(filters repository.ItemFilters, page, pageSize int) ([]models.Item, int64, error) {
+	offset := (page - 1) * pageSize
+	items, total, err := s.itemRepo.List(filters, offset, pageSize)
+	if err != nil {
+		return nil, 0, appErrors.NewInternalError("Failed to list items", err)
+	}
+	return items, total, nil
+}
+
+// GetAvailability lists the lendable copies of a record and the length of its hold queue
+func (s *itemService) GetAvailability(documentID uuid.UUID) (*Availability, error) {
+	items, _, err := s.itemRepo.List(repository.ItemFilters{DocumentID: &documentID}, 0, 500)
+	if err != nil {
+		return nil, appErrors.NewInternalError("Failed to list items", err)
+	}
+	waiting, err := s.circulationRepo.CountWaiting(documentID)
+	if err != nil {
+		return nil, appErrors.NewInternalError("Failed to count holds", err)
+	}
+
+	availability := &Availability{DocumentID: documentID, WaitingHolds: waiting, Items: []models.Item{}}
+	for _, item := range items {
+		if item.Status == models.ItemLost || item.Status == models.ItemWithdrawn {
+			continue
+		}
+		availability.Total++
+		if item.Status == models.ItemAvailable {
+			availability.Available++
+		}
+		availability.Items = append(availability.Items, item)
+	}
+	return availability, nil
+}
+
+// checkDocument checks that a catalogue record exists
+func (s *itemService) checkDocument(documentID uuid.UUID) error {
+	exists, err := s.itemRepo.DocumentExists(documentID)
+	if err != nil {
+		return appErrors.NewInternalError("Failed to check document", err)
+	}
+	if !exists {
+		return appErrors.NewNotFoundError("Document", fmt.Errorf("document %s", documentID))
+	}
+	return nil
+}
+
+func validateCondition(condition models.ItemCondition) error {
+	switch condition {
+	case models.ConditionNew, models.ConditionGood, models.ConditionFair, models.ConditionPoor, models.ConditionDamaged:
+		return nil
+	}
+	return appErrors.NewValidationError(fmt.Sprintf("Unknown condition %q", condition), nil)
+}
+
+// checkStatusChange checks that staff may move an item between statuses by hand. Loans
+// and holds move items on and off loan and the hold shelf.
+func checkStatusChange(from, to models.ItemStatus) error {
+	switch to {
+	case models.ItemAvailable, models.ItemInRepair, models.ItemWithdrawn:
+		if from == models.ItemOnLoan || from == models.ItemOnHoldShelf {
+			return appErrors.NewValidationError(fmt.Sprintf("Item is %s; check it in or cancel the hold first", from), nil)
+		}
+		return nil
+	case models.ItemLost:
+		if from == models.ItemOnHoldShelf {
+			return appErrors.NewValidationError("Item is on the hold shelf; cancel the hold first", nil)
+		}
+		return nil
+	}
+	return appErrors.NewValidationError(fmt.Sprintf("Status cannot be set to %q", to), nil)
+}
@@ -218,9 +218,9 @@ func (h *CollectionHandler) UpdateCollection(c *gin.Context) {
 	response.Success(c, collection, "Collection updated successfully")
 }
 
-// DeleteCollection deletes a collection
+// DeleteCollection moves a collection to the trash
 // @Summary      Delete collection
-// @Description  Delete a collection (owner only)
+// @Description  Move a collection and its documents to the trash (owner only). It can be restored until the retention period ends.
 // @Tags         collections
 // @Security     BearerAuth
 // @Produce      json
@@ -254,6 +254,75 @@ func (h *CollectionHandler) DeleteCollection(c *gin.Context) {
 	response.Success(c, nil, "Collection deleted successfully")
 }
 
+// ListTrash lists deleted collections
+// @Summary      List trashed collections
+// @Description  Collections the user deleted and can restore (all of them for admins)
+// @Tags         collections
+// @Security     BearerAuth
+// @Produce      json
+// @Param        page      query     int     false  "Page number" default(1)
+// @Param        page_size query     int     false  "Page size" default(20)
+// @Success      200  {object}  response.Response{data=[]models.Collection} "Trashed collections"
+// @Failure      401  {object}  response.Response "Unauthorized"
+// @Router       /collections/trash [get]
+func (h *CollectionHandler) ListTrash(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	collections, total, err := h.collectionService.ListTrash(userID.(uuid.UUID), isAdmin(c), page, pageSize)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	response.Paginated(c, collections, page, pageSize, total)
+}
+
+// RestoreCollection restores a deleted collection
+// @Summary      Restore collection
+// @Description  Take a collection out of the trash together with the documents deleted with it
+// @Tags         collections
+// @Security     BearerAuth
+// @Produce      json
+// @Param        id   path      string  true  "Collection ID"
+// @Success      200  {object}  response.Response{data=models.Collection} "Collection restored"
+// @Failure      403  {object}  response.Response "Forbidden"
+// @Failure      404  {object}  response.Response "Not in trash"
+// @Router       /collections/{id}/restore [post]
+func (h *CollectionHandler) RestoreCollection(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.BadRequest(c, "Invalid collection ID")
+		return
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	collection, err := h.collectionService.RestoreCollection(id, userID.(uuid.UUID), isAdmin(c))
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	response.Success(c, collection, "Collection restored successfully")
+}
+
 // ListCollections lists all collections with filters
 // @Summary      List collections
 // @Description  List collections with pagination and filters
@@ -349,9 +418,19 @@ func (h *CollectionHandler) RegisterRoutes(router *gin.RouterGroup, optionalAuth
 		collections.POST("", requiredAuthMiddleware, h.CreateCollection)
 		collections.PUT("/:id", requiredAuthMiddleware, h.UpdateCollection)
 		collections.DELETE("/:id", requiredAuthMiddleware, h.DeleteCollection)
+
+		// Trash
+		collections.GET("/trash", requiredAuthMiddleware, h.ListTrash)
+		collections.POST("/:id/restore", requiredAuthMiddleware, h.RestoreCollection)
 	}
 }
 
+// isAdmin reports whether the authenticated user is an administrator
+func isAdmin(c *gin.Context) bool {
+	role, exists := c.Get("role")
+	return exists && role == string(models.RoleAdmin)
+}
+
 // handleError handles errors and sends appropriate responses
 func handleError(c *gin.Context, err error) {
 	c.JSON(500, gin.H{
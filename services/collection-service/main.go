@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
@@ -13,6 +14,7 @@ import (
 	"github.com/Kyei-Ernest/libsystem/services/collection-service/repository"
 	"github.com/Kyei-Ernest/libsystem/services/collection-service/service"
 	"github.com/Kyei-Ernest/libsystem/shared/database"
+	"github.com/Kyei-Ernest/libsystem/shared/kafka"
 	"github.com/Kyei-Ernest/libsystem/shared/metrics"
 	"github.com/Kyei-Ernest/libsystem/shared/security"
 	"github.com/gin-gonic/gin"
@@ -80,8 +82,35 @@ func main() {
 	// Initialize repositories
 	collectionRepo := repository.NewCollectionRepository(dbConn.DB)
 
+	// Initialize Kafka producer for collection trash events
+	kafkaBrokers := strings.Split(getEnv("KAFKA_BROKERS", "localhost:9093"), ",")
+	producer := kafka.NewProducer(kafka.ProducerConfig{
+		Brokers: kafkaBrokers,
+		Topic:   "", // No default topic, we specify per message
+	})
+	defer producer.Close()
+
+	// Deleted collections stay in the trash for the retention period
+	trashRetention, err := time.ParseDuration(getEnv("TRASH_RETENTION", "720h"))
+	if err != nil {
+		log.Fatalf("Invalid TRASH_RETENTION: %v", err)
+	}
+
 	// Initialize services
-	collectionService := service.NewCollectionService(collectionRepo)
+	collectionService := service.NewCollectionService(collectionRepo, producer, trashRetention)
+
+	// Periodically purge expired trash
+	go func() {
+		ticker := time.NewTicker(15 * time.Minute)
+		defer ticker.Stop()
+		for range ticker.C {
+			if purged, err := collectionService.PurgeExpired(); err != nil {
+				log.Printf("Failed to purge trash: %v", err)
+			} else if purged > 0 {
+				log.Printf("Purged %d collections from the trash", purged)
+			}
+		}
+	}()
 
 	// Initialize handlers
 	collectionHandler := handlers.NewCollectionHandler(collectionService)
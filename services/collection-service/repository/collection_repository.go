@@ -2,6 +2,7 @@ package repository
 
 import (
 	"errors"
+	"time"
 
 	"github.com/Kyei-Ernest/libsystem/shared/models"
 	"github.com/google/uuid"
@@ -13,8 +14,13 @@ type CollectionRepository interface {
 	Create(collection *models.Collection) error
 	FindByID(id uuid.UUID) (*models.Collection, error)
 	FindBySlug(slug string) (*models.Collection, error)
+	FindBySlugWithTrashed(slug string) (*models.Collection, error)
 	Update(collection *models.Collection) error
-	Delete(id uuid.UUID) error
+	Trash(id uuid.UUID, deletedBy *uuid.UUID, deletedAt time.Time) error
+	FindTrashedByID(id uuid.UUID) (*models.Collection, error)
+	ListTrash(ownerID *uuid.UUID, offset, limit int) ([]models.Collection, int64, error)
+	Restore(id uuid.UUID) error
+	PurgeExpired(before time.Time) (int64, error)
 	List(filters CollectionFilters, offset, limit int) ([]models.Collection, int64, error)
 	IncrementViewCount(id uuid.UUID) error
 	IncrementDocumentCount(id uuid.UUID, delta int) error
@@ -69,14 +75,88 @@ func (r *collectionRepository) FindBySlug(slug string) (*models.Collection, erro
 	return &collection, nil
 }
 
+// FindBySlugWithTrashed finds a collection by slug, including collections in the trash
+// (slugs stay taken until a collection is purged)
+func (r *collectionRepository) FindBySlugWithTrashed(slug string) (*models.Collection, error) {
+	var collection models.Collection
+	err := r.db.Unscoped().Where("slug = ?", slug).First(&collection).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("collection not found")
+		}
+		return nil, err
+	}
+	return &collection, nil
+}
+
 // Update updates a collection
 func (r *collectionRepository) Update(collection *models.Collection) error {
 	return r.db.Save(collection).Error
 }
 
-// Delete soft deletes a collection
-func (r *collectionRepository) Delete(id uuid.UUID) error {
-	return r.db.Delete(&models.Collection{}, id).Error
+// Trash soft deletes a collection, recording who deleted it
+func (r *collectionRepository) Trash(id uuid.UUID, deletedBy *uuid.UUID, deletedAt time.Time) error {
+	return r.db.Model(&models.Collection{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"deleted_at": deletedAt,
+			"deleted_by": deletedBy,
+		}).Error
+}
+
+// FindTrashedByID finds a collection in the trash by ID
+func (r *collectionRepository) FindTrashedByID(id uuid.UUID) (*models.Collection, error) {
+	var collection models.Collection
+	err := r.db.Unscoped().Where("id = ? AND deleted_at IS NOT NULL", id).First(&collection).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("collection not found in trash")
+		}
+		return nil, err
+	}
+	return &collection, nil
+}
+
+// ListTrash lists trashed collections, most recently deleted first
+func (r *collectionRepository) ListTrash(ownerID *uuid.UUID, offset, limit int) ([]models.Collection, int64, error) {
+	var collections []models.Collection
+	var total int64
+
+	query := r.db.Unscoped().Model(&models.Collection{}).Where("deleted_at IS NOT NULL")
+	if ownerID != nil {
+		query = query.Where("owner_id = ?", *ownerID)
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	if err := query.Offset(offset).Limit(limit).Order("deleted_at DESC").Find(&collections).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return collections, total, nil
+}
+
+// Restore takes a collection out of the trash
+func (r *collectionRepository) Restore(id uuid.UUID) error {
+	return r.db.Unscoped().Model(&models.Collection{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"deleted_at": nil,
+			"deleted_by": nil,
+		}).Error
+}
+
+// PurgeExpired permanently deletes collections trashed before the given time. A
+// collection is only purged once the document service has purged all of its documents,
+// so their files are never orphaned by the cascade.
+func (r *collectionRepository) PurgeExpired(before time.Time) (int64, error) {
+	result := r.db.Unscoped().
+		Where("deleted_at IS NOT NULL AND deleted_at < ?", before).
+		Where("NOT EXISTS (SELECT 1 FROM documents WHERE documents.collection_id = collections.id)").
+		Delete(&models.Collection{})
+	return result.RowsAffected, result.Error
 }
 
 // List lists collections with filters and pagination
@@ -1,11 +1,14 @@
 package service
 
 import (
+	"context"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/Kyei-Ernest/libsystem/services/collection-service/repository"
 	appErrors "github.com/Kyei-Ernest/libsystem/shared/errors"
+	"github.com/Kyei-Ernest/libsystem/shared/kafka"
 	"github.com/Kyei-Ernest/libsystem/shared/models"
 	"github.com/Kyei-Ernest/libsystem/shared/validator"
 	"github.com/google/uuid"
@@ -26,6 +29,9 @@ type CollectionService interface {
 	GetCollectionBySlug(slug string, userID *uuid.UUID) (*models.Collection, error)
 	UpdateCollection(id uuid.UUID, updates CollectionUpdate, userID uuid.UUID) (*models.Collection, error)
 	DeleteCollection(id uuid.UUID, userID uuid.UUID) error
+	ListTrash(userID uuid.UUID, isAdmin bool, page, pageSize int) ([]models.Collection, int64, error)
+	RestoreCollection(id uuid.UUID, userID uuid.UUID, isAdmin bool) (*models.Collection, error)
+	PurgeExpired() (int64, error)
 	ListCollections(filters repository.CollectionFilters, page, pageSize int) ([]models.Collection, int64, error)
 	CheckPermission(collectionID uuid.UUID, userID *uuid.UUID, action string) (bool, error)
 }
@@ -33,12 +39,17 @@ type CollectionService interface {
 // collectionService implements CollectionService
 type collectionService struct {
 	collectionRepo repository.CollectionRepository
+	producer       *kafka.Producer
+	trashRetention time.Duration
 }
 
-// NewCollectionService creates a new collection service
-func NewCollectionService(collectionRepo repository.CollectionRepository) CollectionService {
+// NewCollectionService creates a new collection service. Deleted collections stay in the
+// trash for trashRetention before they are purged.
+func NewCollectionService(collectionRepo repository.CollectionRepository, producer *kafka.Producer, trashRetention time.Duration) CollectionService {
 	return &collectionService{
 		collectionRepo: collectionRepo,
+		producer:       producer,
+		trashRetention: trashRetention,
 	}
 }
 
@@ -60,7 +71,7 @@ func (s *collectionService) CreateCollection(name, description string, ownerID u
 	}
 
 	// Check if slug already exists, make it unique if needed
-	existingCollection, _ := s.collectionRepo.FindBySlug(slug)
+	existingCollection, _ := s.collectionRepo.FindBySlugWithTrashed(slug)
 	if existingCollection != nil {
 		// Append timestamp to make it unique
 		slug = fmt.Sprintf("%s-%d", slug, time.Now().Unix())
@@ -79,6 +90,9 @@ func (s *collectionService) CreateCollection(name, description string, ownerID u
 			RequireApproval:        true,
 		}
 	}
+	if err := validateSettings(settings); err != nil {
+		return nil, err
+	}
 
 	// Create collection
 	collection := &models.Collection{
@@ -165,7 +179,7 @@ func (s *collectionService) UpdateCollection(id uuid.UUID, updates CollectionUpd
 		newSlug := validator.GenerateSlug(*updates.Name)
 		if newSlug != collection.Slug {
 			// Check if new slug exists
-			existingCollection, _ := s.collectionRepo.FindBySlug(newSlug)
+			existingCollection, _ := s.collectionRepo.FindBySlugWithTrashed(newSlug)
 			if existingCollection != nil && existingCollection.ID != id {
 				newSlug = fmt.Sprintf("%s-%d", newSlug, time.Now().Unix())
 			}
@@ -182,6 +196,9 @@ func (s *collectionService) UpdateCollection(id uuid.UUID, updates CollectionUpd
 	}
 
 	if updates.Settings != nil {
+		if err := validateSettings(updates.Settings); err != nil {
+			return nil, err
+		}
 		collection.Settings = *updates.Settings
 	}
 
@@ -193,7 +210,7 @@ func (s *collectionService) UpdateCollection(id uuid.UUID, updates CollectionUpd
 	return collection, nil
 }
 
-// DeleteCollection deletes a collection
+// DeleteCollection moves a collection and its documents to the trash
 func (s *collectionService) DeleteCollection(id uuid.UUID, userID uuid.UUID) error {
 	collection, err := s.collectionRepo.FindByID(id)
 	if err != nil {
@@ -205,14 +222,90 @@ func (s *collectionService) DeleteCollection(id uuid.UUID, userID uuid.UUID) err
 		return appErrors.NewForbiddenError("Only the owner can delete this collection", nil)
 	}
 
-	// Delete collection
-	if err := s.collectionRepo.Delete(id); err != nil {
+	deletedAt := time.Now()
+	if err := s.collectionRepo.Trash(id, &userID, deletedAt); err != nil {
+		return appErrors.NewInternalError("Failed to delete collection", err)
+	}
+
+	// The document service trashes the collection's documents; undo if it can't be told
+	if err := s.publishTrashEvent("collection.deleted", map[string]interface{}{
+		"id":         id,
+		"deleted_by": userID,
+		"deleted_at": deletedAt,
+	}); err != nil {
+		if restoreErr := s.collectionRepo.Restore(id); restoreErr != nil {
+			fmt.Printf("DEBUG: Failed to undo deletion of collection %s: %v\n", id, restoreErr)
+		}
 		return appErrors.NewInternalError("Failed to delete collection", err)
 	}
 
 	return nil
 }
 
+// ListTrash lists the user's trashed collections (all of them for admins)
+func (s *collectionService) ListTrash(userID uuid.UUID, isAdmin bool, page, pageSize int) ([]models.Collection, int64, error) {
+	var ownerID *uuid.UUID
+	if !isAdmin {
+		ownerID = &userID
+	}
+
+	offset := (page - 1) * pageSize
+	collections, total, err := s.collectionRepo.ListTrash(ownerID, offset, pageSize)
+	if err != nil {
+		return nil, 0, appErrors.NewInternalError("Failed to list trash", err)
+	}
+
+	return collections, total, nil
+}
+
+// RestoreCollection takes a collection out of the trash along with the documents that
+// were deleted with it
+func (s *collectionService) RestoreCollection(id uuid.UUID, userID uuid.UUID, isAdmin bool) (*models.Collection, error) {
+	collection, err := s.collectionRepo.FindTrashedByID(id)
+	if err != nil {
+		return nil, appErrors.NewNotFoundError("Collection", err)
+	}
+
+	if !isAdmin && collection.OwnerID != userID {
+		return nil, appErrors.NewForbiddenError("Only the owner can restore this collection", nil)
+	}
+
+	if err := s.collectionRepo.Restore(id); err != nil {
+		return nil, appErrors.NewInternalError("Failed to restore collection", err)
+	}
+
+	// The document service restores and reindexes the collection's documents
+	if err := s.publishTrashEvent("collection.restored", map[string]interface{}{
+		"id": id,
+	}); err != nil {
+		if trashErr := s.collectionRepo.Trash(id, collection.DeletedBy, collection.DeletedAt.Time); trashErr != nil {
+			fmt.Printf("DEBUG: Failed to undo restore of collection %s: %v\n", id, trashErr)
+		}
+		return nil, appErrors.NewInternalError("Failed to restore collection", err)
+	}
+
+	return s.collectionRepo.FindByID(id)
+}
+
+// PurgeExpired permanently deletes collections whose retention period in the trash has
+// passed and returns how many were purged
+func (s *collectionService) PurgeExpired() (int64, error) {
+	return s.collectionRepo.PurgeExpired(time.Now().Add(-s.trashRetention))
+}
+
+// publishTrashEvent publishes a collection trash event keyed by collection ID
+func (s *collectionService) publishTrashEvent(topic string, event map[string]interface{}) error {
+	if s.producer == nil {
+		return nil
+	}
+
+	key := fmt.Sprintf("%v", event["id"])
+	if err := s.producer.PublishToTopic(context.Background(), topic, key, event); err != nil {
+		return fmt.Errorf("failed to publish %s event: %w", topic, err)
+	}
+	return nil
+}
+
 // ListCollections lists collections with filters and pagination
 func (s *collectionService) ListCollections(filters repository.CollectionFilters, page, pageSize int) ([]models.Collection, int64, error) {
 	// Calculate offset
@@ -268,3 +361,26 @@ func (s *collectionService) CheckPermission(collectionID uuid.UUID, userID *uuid
 		return false, appErrors.NewBadRequestError(fmt.Sprintf("Unknown action: %s", action), nil)
 	}
 }
+
+// validateSettings checks a collection's upload policy and drops blank list entries
+func validateSettings(settings *models.CollectionSettings) error {
+	if settings.MaxFileSize < 0 {
+		return appErrors.NewValidationError("max_file_size must not be negative", nil)
+	}
+	if settings.MaxPageCount < 0 {
+		return appErrors.NewValidationError("max_page_count must not be negative", nil)
+	}
+	settings.AllowedFileTypes = compactStrings(settings.AllowedFileTypes)
+	settings.RequiredMetadata = compactStrings(settings.RequiredMetadata)
+	return nil
+}
+
+func compactStrings(values []string) []string {
+	var out []string
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
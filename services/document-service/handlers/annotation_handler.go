@@ -0,0 +1,278 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/Kyei-Ernest/libsystem/services/document-service/service"
+	"github.com/Kyei-Ernest/libsystem/shared/models"
+	"github.com/Kyei-Ernest/libsystem/shared/response"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// annotationExportLimit caps the annotations in a JSON-LD export
+const annotationExportLimit = 5000
+
+// jsonLDContentType is the media type of W3C Web Annotation exports
+const jsonLDContentType = `application/ld+json; profile="http://www.w3.org/ns/anno.jsonld"`
+
+// AnnotationHandler handles annotation requests
+type AnnotationHandler struct {
+	annotationService service.AnnotationService
+}
+
+// NewAnnotationHandler creates a new annotation handler
+func NewAnnotationHandler(annotationService service.AnnotationService) *AnnotationHandler {
+	return &AnnotationHandler{annotationService: annotationService}
+}
+
+// ListDocumentAnnotations godoc
+// @Summary List annotations on a document
+// @Description Annotations visible to the user on the document's current content, or on a version. format=jsonld exports them as a W3C Web Annotation collection.
+// @Tags annotations
+// @Produce json
+// @Param id path string true "Document ID"
+// @Param version_id query string false "Version ID"
+// @Param format query string false "jsonld for a W3C Web Annotation export"
+// @Param page query int false "Page number" default(1)
+// @Param page_size query int false "Page size" default(50)
+// @Success 200 {object} response.Response{data=[]models.Annotation}
+// @Failure 403 {object} response.Response "Content not available"
+// @Router /documents/{id}/annotations [get]
+func (h *AnnotationHandler) ListDocumentAnnotations(c *gin.Context) {
+	documentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.BadRequest(c, "Invalid document ID")
+		return
+	}
+
+	var versionID *uuid.UUID
+	if versionIDStr := c.Query("version_id"); versionIDStr != "" {
+		id, err := uuid.Parse(versionIDStr)
+		if err != nil {
+			response.BadRequest(c, "Invalid version ID")
+			return
+		}
+		versionID = &id
+	}
+
+	page, pageSize, export := annotationPage(c)
+	annotations, total, err := h.annotationService.ListDocumentAnnotations(documentID, versionID, optionalUserID(c), page, pageSize)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	if export {
+		writeJSONLD(c, annotations, "Annotations on document "+documentID.String())
+		return
+	}
+	response.Paginated(c, annotations, page, pageSize, total)
+}
+
+// ListOwnAnnotations godoc
+// @Summary List your annotations
+// @Description The user's own annotations on all documents and versions, newest first. q searches notes and quoted passages; format=jsonld exports them.
+// @Tags annotations
+// @Security BearerAuth
+// @Produce json
+// @Param q query string false "Search text"
+// @Param document_id query string false "Document ID"
+// @Param format query string false "jsonld for a W3C Web Annotation export"
+// @Param page query int false "Page number" default(1)
+// @Param page_size query int false "Page size" default(50)
+// @Success 200 {object} response.Response{data=[]models.Annotation}
+// @Router /annotations [get]
+func (h *AnnotationHandler) ListOwnAnnotations(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	var documentID *uuid.UUID
+	if documentIDStr := c.Query("document_id"); documentIDStr != "" {
+		id, err := uuid.Parse(documentIDStr)
+		if err != nil {
+			response.BadRequest(c, "Invalid document ID")
+			return
+		}
+		documentID = &id
+	}
+
+	page, pageSize, export := annotationPage(c)
+	annotations, total, err := h.annotationService.ListOwnAnnotations(userID.(uuid.UUID), documentID, c.Query("q"), page, pageSize)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	if export {
+		writeJSONLD(c, annotations, "Annotations by "+userID.(uuid.UUID).String())
+		return
+	}
+	response.Paginated(c, annotations, page, pageSize, total)
+}
+
+// CreateAnnotation godoc
+// @Summary Annotate a document
+// @Description Highlight a passage, optionally with a note. The target takes a page and a text_quote and/or text_position selector. Visibility is private (default), collection or public.
+// @Tags annotations
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Document ID"
+// @Param request body service.AnnotationInput true "Annotation"
+// @Success 201 {object} response.Response{data=models.Annotation}
+// @Failure 400 {object} response.Response
+// @Router /documents/{id}/annotations [post]
+func (h *AnnotationHandler) CreateAnnotation(c *gin.Context) {
+	documentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.BadRequest(c, "Invalid document ID")
+		return
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	var req service.AnnotationInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "Invalid request body: "+err.Error())
+		return
+	}
+
+	annotation, err := h.annotationService.CreateAnnotation(documentID, req, userID.(uuid.UUID))
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	response.Created(c, annotation, "Annotation created successfully")
+}
+
+// UpdateAnnotation godoc
+// @Summary Edit an annotation
+// @Description Change the note or visibility of your annotation; its anchors are fixed
+// @Tags annotations
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Document ID"
+// @Param annotationId path string true "Annotation ID"
+// @Param request body service.AnnotationUpdate true "Changes"
+// @Success 200 {object} response.Response{data=models.Annotation}
+// @Failure 403 {object} response.Response
+// @Router /documents/{id}/annotations/{annotationId} [put]
+func (h *AnnotationHandler) UpdateAnnotation(c *gin.Context) {
+	documentID, annotationID, ok := parseAnnotationParams(c)
+	if !ok {
+		return
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	var req service.AnnotationUpdate
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "Invalid request body: "+err.Error())
+		return
+	}
+
+	annotation, err := h.annotationService.UpdateAnnotation(documentID, annotationID, req, userID.(uuid.UUID))
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	response.Success(c, annotation, "Annotation updated successfully")
+}
+
+// DeleteAnnotation godoc
+// @Summary Delete an annotation
+// @Description Delete your annotation; the document's uploader and collection owner may also remove shared ones
+// @Tags annotations
+// @Security BearerAuth
+// @Param id path string true "Document ID"
+// @Param annotationId path string true "Annotation ID"
+// @Success 200 {object} response.Response
+// @Failure 403 {object} response.Response
+// @Router /documents/{id}/annotations/{annotationId} [delete]
+func (h *AnnotationHandler) DeleteAnnotation(c *gin.Context) {
+	documentID, annotationID, ok := parseAnnotationParams(c)
+	if !ok {
+		return
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	if err := h.annotationService.DeleteAnnotation(documentID, annotationID, userID.(uuid.UUID)); err != nil {
+		handleError(c, err)
+		return
+	}
+
+	response.Success(c, nil, "Annotation deleted successfully")
+}
+
+// RegisterRoutes registers annotation routes
+func (h *AnnotationHandler) RegisterRoutes(router *gin.RouterGroup, optionalAuth, requiredAuth gin.HandlerFunc) {
+	annotations := router.Group("/documents/:id/annotations")
+	{
+		annotations.GET("", optionalAuth, h.ListDocumentAnnotations)
+		annotations.POST("", requiredAuth, h.CreateAnnotation)
+		annotations.PUT("/:annotationId", requiredAuth, h.UpdateAnnotation)
+		annotations.DELETE("/:annotationId", requiredAuth, h.DeleteAnnotation)
+	}
+	router.GET("/annotations", requiredAuth, h.ListOwnAnnotations)
+}
+
+// annotationPage parses pagination, and whether a JSON-LD export was requested, which
+// returns all annotations up to annotationExportLimit
+func annotationPage(c *gin.Context) (int, int, bool) {
+	if c.Query("format") == "jsonld" {
+		return 1, annotationExportLimit, true
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "50"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 200 {
+		pageSize = 50
+	}
+	return page, pageSize, false
+}
+
+// writeJSONLD responds with annotations as a W3C Web Annotation collection
+func writeJSONLD(c *gin.Context, annotations []models.Annotation, label string) {
+	c.Header("Content-Type", jsonLDContentType)
+	c.JSON(http.StatusOK, service.AnnotationsJSONLD(annotations, label))
+}
+
+// parseAnnotationParams parses the document and annotation IDs from the path, responding
+// with 400 if either is invalid
+func parseAnnotationParams(c *gin.Context) (uuid.UUID, uuid.UUID, bool) {
+	documentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.BadRequest(c, "Invalid document ID")
+		return uuid.Nil, uuid.Nil, false
+	}
+	annotationID, err := uuid.Parse(c.Param("annotationId"))
+	if err != nil {
+		response.BadRequest(c, "Invalid annotation ID")
+		return uuid.Nil, uuid.Nil, false
+	}
+	return documentID, annotationID, true
+}
@@ -1,14 +1,15 @@
 package handlers
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"mime/multipart"
 	"net/http"
-	"sync"
 
 	"github.com/Kyei-Ernest/libsystem/services/document-service/service"
 	"github.com/Kyei-Ernest/libsystem/shared/jobs"
+	"github.com/Kyei-Ernest/libsystem/shared/models"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 )
@@ -18,12 +19,48 @@ type BatchHandler struct {
 	jobTracker      *jobs.JobTracker
 }
 
-// NewBatchHandler creates a new batch handler
+// bulkUploadConcurrency caps how many files of a bulk upload are ingested at once
+const bulkUploadConcurrency = 5
+
+// NewBatchHandler creates a new batch handler and registers its jobs with the tracker
 func NewBatchHandler(documentService service.DocumentService, jobTracker *jobs.JobTracker) *BatchHandler {
-	return &BatchHandler{
+	h := &BatchHandler{
 		documentService: documentService,
 		jobTracker:      jobTracker,
 	}
+	jobTracker.Register(jobs.JobTypeBulkUpload, jobs.Handler{Run: h.processBulkUpload, Cleanup: h.cleanupBulkUpload})
+	jobTracker.Register(jobs.JobTypeBulkMetadataUpdate, jobs.Handler{Run: h.processBulkMetadataUpdate})
+	jobTracker.Register(jobs.JobTypeBulkDelete, jobs.Handler{Run: h.processBulkDelete})
+	return h
+}
+
+// bulkUploadPayload is what a bulk upload job applies to every file
+type bulkUploadPayload struct {
+	CollectionID uuid.UUID                `json:"collection_id"`
+	Metadata     *models.DocumentMetadata `json:"metadata,omitempty"`
+	Availability service.Availability     `json:"availability"`
+}
+
+// bulkUploadItem is a file of a bulk upload, staged in storage until it is ingested
+type bulkUploadItem struct {
+	Filename    string `json:"filename"`
+	ContentType string `json:"content_type,omitempty"`
+	Size        int64  `json:"size"`
+	StagingPath string `json:"staging_path"`
+}
+
+// documentItem is a document a bulk job acts on
+type documentItem struct {
+	DocumentID uuid.UUID `json:"document_id"`
+}
+
+// documentItems makes an item per document
+func documentItems(documentIDs []uuid.UUID) []interface{} {
+	items := make([]interface{}, len(documentIDs))
+	for i, id := range documentIDs {
+		items[i] = documentItem{DocumentID: id}
+	}
+	return items
 }
 
 // BulkUpload handles bulk document uploads
@@ -35,6 +72,7 @@ func NewBatchHandler(documentService service.DocumentService, jobTracker *jobs.J
 // @Produce json
 // @Param files formData file true "Document files"
 // @Param collection_id formData string true "Collection ID"
+// @Param metadata formData string false "Document metadata as JSON, applied to every file"
 // @Success 202 {object} map[string]interface{} "Job created"
 // @Router /documents/batch/upload [post]
 func (h *BatchHandler) BulkUpload(c *gin.Context) {
@@ -45,7 +83,8 @@ func (h *BatchHandler) BulkUpload(c *gin.Context) {
 	}
 
 	// Parse multipart form
-	if err := c.Request.ParseMultipartForm(500 << 20); err != nil { // 500 MB max
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, 500<<20) // 500 MB max
+	if err := c.Request.ParseMultipartForm(multipartMemoryLimit); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to parse form: " + err.Error()})
 		return
 	}
@@ -58,6 +97,18 @@ func (h *BatchHandler) BulkUpload(c *gin.Context) {
 		return
 	}
 
+	docMetadata, err := parseMetadataField(c.PostForm("metadata"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid metadata: " + err.Error()})
+		return
+	}
+
+	availability, err := parseAvailability(c.PostForm)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
 	// Get files
 	files := c.Request.MultipartForm.File["files"]
 	if len(files) == 0 {
@@ -65,11 +116,53 @@ func (h *BatchHandler) BulkUpload(c *gin.Context) {
 		return
 	}
 
-	// Create job
-	job := h.jobTracker.CreateJob(jobs.JobTypeBulkUpload, len(files), userID.(uuid.UUID))
+	// Reject the batch up front if any file breaks the collection's upload policy
+	for _, fh := range files {
+		err := h.documentService.CheckUploadPolicy(service.UploadFile{
+			Filename:    fh.Filename,
+			ContentType: fh.Header.Get("Content-Type"),
+			Size:        fh.Size,
+		}, service.UploadMetadata{
+			CollectionID: collectionID,
+			UploaderID:   userID.(uuid.UUID),
+			Title:        fh.Filename,
+			Metadata:     docMetadata,
+			Availability: availability,
+		})
+		if err != nil {
+			c.JSON(errorStatus(err), gin.H{"error": fmt.Sprintf("File %s: %s", fh.Filename, errorMessage(err))})
+			return
+		}
+	}
 
-	// Start background processing
-	go h.processBulkUpload(job.ID, files, collectionID, userID.(uuid.UUID))
+	// Stage the files so that any replica can ingest them
+	items := make([]interface{}, 0, len(files))
+	discard := func() {
+		for _, item := range items {
+			h.documentService.DiscardStagedFile(item.(bulkUploadItem).StagingPath)
+		}
+	}
+	for _, fh := range files {
+		item, err := h.stageFile(fh)
+		if err != nil {
+			discard()
+			c.JSON(errorStatus(err), gin.H{"error": fmt.Sprintf("File %s: %s", fh.Filename, errorMessage(err))})
+			return
+		}
+		items = append(items, item)
+	}
+
+	job, err := h.jobTracker.Enqueue(jobs.JobSpec{
+		Type:      jobs.JobTypeBulkUpload,
+		CreatedBy: userID.(uuid.UUID),
+		Payload:   bulkUploadPayload{CollectionID: collectionID, Metadata: docMetadata, Availability: availability},
+		Items:     items,
+	})
+	if err != nil {
+		discard()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to queue bulk upload"})
+		return
+	}
 
 	c.JSON(http.StatusAccepted, gin.H{
 		"job_id":  job.ID,
@@ -78,70 +171,76 @@ func (h *BatchHandler) BulkUpload(c *gin.Context) {
 	})
 }
 
-// processBulkUpload processes bulk uploads in the background
-func (h *BatchHandler) processBulkUpload(jobID uuid.UUID, files []*multipart.FileHeader, collectionID, uploaderID uuid.UUID) {
-	h.jobTracker.StartJob(jobID)
-
-	completed := 0
-	failed := 0
-	var mu sync.Mutex
-
-	// Process files concurrently (with limit)
-	concurrency := 5
-	sem := make(chan struct{}, concurrency)
-	var wg sync.WaitGroup
-
-	for i, fileHeader := range files {
-		wg.Add(1)
-		go func(index int, fh *multipart.FileHeader) {
-			defer wg.Done()
-			sem <- struct{}{}        // Acquire semaphore
-			defer func() { <-sem }() // Release semaphore
-
-			// Open file
-			file, err := fh.Open()
-			if err != nil {
-				mu.Lock()
-				failed++
-				h.jobTracker.UpdateProgress(jobID, completed, failed, fmt.Sprintf("File %d: failed to open: %v", index, err))
-				mu.Unlock()
-				return
-			}
-			defer file.Close()
-
-			// Determine title from filename
-			title := fh.Filename
-			if len(title) > 100 {
-				title = title[:100]
-			}
-
-			metadata := service.UploadMetadata{
-				CollectionID: collectionID,
-				UploaderID:   uploaderID,
-				Title:        title,
-				Description:  fmt.Sprintf("Bulk uploaded (%d/%d)", index+1, len(files)),
-			}
-
-			// Upload document directly (file is already seekable)
-			_, err = h.documentService.UploadDocument(file, fh, metadata)
-			if err != nil {
-				mu.Lock()
-				failed++
-				h.jobTracker.UpdateProgress(jobID, completed, failed, fmt.Sprintf("File %s: %v", fh.Filename, err))
-				mu.Unlock()
-			} else {
-				mu.Lock()
-				completed++
-				h.jobTracker.UpdateProgress(jobID, completed, failed, "")
-				mu.Unlock()
-			}
-		}(i, fileHeader)
+// stageFile uploads a file of a bulk upload to storage
+func (h *BatchHandler) stageFile(fh *multipart.FileHeader) (bulkUploadItem, error) {
+	file, err := fh.Open()
+	if err != nil {
+		return bulkUploadItem{}, err
 	}
+	defer file.Close()
 
-	wg.Wait()
+	item := bulkUploadItem{
+		Filename:    fh.Filename,
+		ContentType: fh.Header.Get("Content-Type"),
+		Size:        fh.Size,
+	}
+	item.StagingPath, err = h.documentService.StageFile(file, service.UploadFile{
+		Filename:    item.Filename,
+		ContentType: item.ContentType,
+		Size:        item.Size,
+	})
+	return item, err
+}
 
-	// Mark job complete
-	h.jobTracker.CompleteJob(jobID)
+// processBulkUpload ingests the staged files of a bulk upload
+func (h *BatchHandler) processBulkUpload(ctx context.Context, run *jobs.Run) error {
+	var payload bulkUploadPayload
+	if err := run.Job.Payload.Decode(&payload); err != nil {
+		return fmt.Errorf("invalid bulk upload payload: %w", err)
+	}
+
+	return run.ForEach(ctx, bulkUploadConcurrency, func(ctx context.Context, item jobs.Item) (jobs.JobData, error) {
+		var file bulkUploadItem
+		if err := item.Payload.Decode(&file); err != nil {
+			return nil, fmt.Errorf("Item %d: %v", item.Seq, err)
+		}
+
+		// Determine title from filename
+		title := file.Filename
+		if len(title) > 100 {
+			title = title[:100]
+		}
+
+		document, err := h.documentService.IngestStagedDocument(file.StagingPath, service.UploadFile{
+			Filename:    file.Filename,
+			ContentType: file.ContentType,
+			Size:        file.Size,
+		}, service.UploadMetadata{
+			CollectionID: payload.CollectionID,
+			UploaderID:   run.Job.CreatedBy,
+			Title:        title,
+			Description:  fmt.Sprintf("Bulk uploaded (%d/%d)", item.Seq, run.Job.Total),
+			Metadata:     payload.Metadata,
+			Availability: payload.Availability,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("File %s: %v", file.Filename, err)
+		}
+		return jobs.JobData{"document_id": document.ID}, nil
+	})
+}
+
+// cleanupBulkUpload discards the staged files that were never ingested
+func (h *BatchHandler) cleanupBulkUpload(_ *jobs.Job, items []jobs.Item) {
+	for _, item := range items {
+		if item.Status == jobs.ItemStatusDone {
+			continue
+		}
+		var file bulkUploadItem
+		if err := item.Payload.Decode(&file); err == nil && file.StagingPath != "" {
+			h.documentService.DiscardStagedFile(file.StagingPath)
+		}
+	}
 }
 
 // seekableFile wraps multipart.File to be seekable
@@ -182,11 +281,16 @@ func (h *BatchHandler) BulkUpdateMetadata(c *gin.Context) {
 		return
 	}
 
-	// Create job
-	job := h.jobTracker.CreateJob(jobs.JobTypeBulkMetadataUpdate, len(req.DocumentIDs), userID.(uuid.UUID))
-
-	// Start background processing
-	go h.processBulkMetadataUpdate(job.ID, req.DocumentIDs, req.Updates, userID.(uuid.UUID))
+	job, err := h.jobTracker.Enqueue(jobs.JobSpec{
+		Type:      jobs.JobTypeBulkMetadataUpdate,
+		CreatedBy: userID.(uuid.UUID),
+		Payload:   jobs.JobData{"updates": req.Updates},
+		Items:     documentItems(req.DocumentIDs),
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to queue bulk metadata update"})
+		return
+	}
 
 	c.JSON(http.StatusAccepted, gin.H{
 		"job_id":  job.ID,
@@ -195,36 +299,28 @@ func (h *BatchHandler) BulkUpdateMetadata(c *gin.Context) {
 	})
 }
 
-// processBulkMetadataUpdate processes metadata updates in background
-func (h *BatchHandler) processBulkMetadataUpdate(jobID uuid.UUID, documentIDs []uuid.UUID, updates map[string]interface{}, userID uuid.UUID) {
-	h.jobTracker.StartJob(jobID)
-
-	completed := 0
-	failed := 0
-
-	for _, docID := range documentIDs {
-		// Build update struct
-		var docUpdates service.DocumentUpdate
+// processBulkMetadataUpdate applies a bulk metadata update to each document
+func (h *BatchHandler) processBulkMetadataUpdate(ctx context.Context, run *jobs.Run) error {
+	// Build update struct
+	var docUpdates service.DocumentUpdate
+	updates, _ := run.Job.Payload["updates"].(map[string]interface{})
+	if title, ok := updates["title"].(string); ok {
+		docUpdates.Title = &title
+	}
+	if desc, ok := updates["description"].(string); ok {
+		docUpdates.Description = &desc
+	}
 
-		if title, ok := updates["title"].(string); ok {
-			docUpdates.Title = &title
-		}
-		if desc, ok := updates["description"].(string); ok {
-			docUpdates.Description = &desc
+	return run.ForEach(ctx, 1, func(ctx context.Context, item jobs.Item) (jobs.JobData, error) {
+		var document documentItem
+		if err := item.Payload.Decode(&document); err != nil {
+			return nil, fmt.Errorf("Item %d: %v", item.Seq, err)
 		}
-
-		// Update document
-		_, err := h.documentService.UpdateDocument(docID, docUpdates, userID)
-		if err != nil {
-			failed++
-			h.jobTracker.UpdateProgress(jobID, completed, failed, fmt.Sprintf("Document %s: %v", docID, err))
-		} else {
-			completed++
-			h.jobTracker.UpdateProgress(jobID, completed, failed, "")
+		if _, err := h.documentService.UpdateDocument(document.DocumentID, docUpdates, run.Job.CreatedBy); err != nil {
+			return nil, fmt.Errorf("Document %s: %v", document.DocumentID, err)
 		}
-	}
-
-	h.jobTracker.CompleteJob(jobID)
+		return nil, nil
+	})
 }
 
 // BulkDelete deletes multiple documents
@@ -253,11 +349,15 @@ func (h *BatchHandler) BulkDelete(c *gin.Context) {
 		return
 	}
 
-	// Create job
-	job := h.jobTracker.CreateJob(jobs.JobTypeBulkDelete, len(req.DocumentIDs), userID.(uuid.UUID))
-
-	// Start background processing
-	go h.processBulkDelete(job.ID, req.DocumentIDs, userID.(uuid.UUID))
+	job, err := h.jobTracker.Enqueue(jobs.JobSpec{
+		Type:      jobs.JobTypeBulkDelete,
+		CreatedBy: userID.(uuid.UUID),
+		Items:     documentItems(req.DocumentIDs),
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to queue bulk delete"})
+		return
+	}
 
 	c.JSON(http.StatusAccepted, gin.H{
 		"job_id":  job.ID,
@@ -266,68 +366,16 @@ func (h *BatchHandler) BulkDelete(c *gin.Context) {
 	})
 }
 
-// processBulkDelete processes deletions in background
-func (h *BatchHandler) processBulkDelete(jobID uuid.UUID, documentIDs []uuid.UUID, userID uuid.UUID) {
-	h.jobTracker.StartJob(jobID)
-
-	completed := 0
-	failed := 0
-
-	for _, docID := range documentIDs {
-		err := h.documentService.DeleteDocument(docID, userID)
-		if err != nil {
-			failed++
-			h.jobTracker.UpdateProgress(jobID, completed, failed, fmt.Sprintf("Document %s: %v", docID, err))
-		} else {
-			completed++
-			h.jobTracker.UpdateProgress(jobID, completed, failed, "")
+// processBulkDelete deletes each document of a bulk delete
+func (h *BatchHandler) processBulkDelete(ctx context.Context, run *jobs.Run) error {
+	return run.ForEach(ctx, 1, func(ctx context.Context, item jobs.Item) (jobs.JobData, error) {
+		var document documentItem
+		if err := item.Payload.Decode(&document); err != nil {
+			return nil, fmt.Errorf("Item %d: %v", item.Seq, err)
 		}
-	}
-
-	h.jobTracker.CompleteJob(jobID)
-}
-
-// GetJobStatus retrieves job status
-// @Summary Get job status
-// @Description Get the current status of a background job
-// @Tags batch
-// @Security BearerAuth
-// @Produce json
-// @Param jobID path string true "Job ID"
-// @Success 200 {object} jobs.Job "Job status"
-// @Router /jobs/{jobID} [get]
-func (h *BatchHandler) GetJobStatus(c *gin.Context) {
-	jobIDStr := c.Param("jobID")
-	jobID, err := uuid.Parse(jobIDStr)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid job ID"})
-		return
-	}
-
-	job, err := h.jobTracker.GetJob(jobID)
-	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Job not found"})
-		return
-	}
-
-	c.JSON(http.StatusOK, job)
-}
-
-// ListJobs lists all jobs for the current user
-// @Summary List user jobs
-// @Description List all background jobs for the authenticated user
-// @Tags batch
-// @Security BearerAuth
-// @Produce json
-// @Success 200 {array} jobs.Job "List of jobs"
-// @Router /jobs [get]
-func (h *BatchHandler) ListJobs(c *gin.Context) {
-	userID, exists := c.Get("user_id")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
-		return
-	}
-
-	jobs := h.jobTracker.ListJobs(userID.(uuid.UUID))
-	c.JSON(http.StatusOK, jobs)
+		if err := h.documentService.DeleteDocument(document.DocumentID, run.Job.CreatedBy); err != nil {
+			return nil, fmt.Errorf("Document %s: %v", document.DocumentID, err)
+		}
+		return nil, nil
+	})
 }
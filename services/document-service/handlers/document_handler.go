@@ -1,9 +1,13 @@
 package handlers
 
 import (
+	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/Kyei-Ernest/libsystem/services/document-service/middleware"
 	"github.com/Kyei-Ernest/libsystem/services/document-service/repository"
@@ -15,15 +19,22 @@ import (
 	"github.com/google/uuid"
 )
 
+// multipartMemoryLimit caps how much of a multipart upload is buffered in memory
+const multipartMemoryLimit = 8 << 20 // 8 MB
+
 // DocumentHandler handles document-related HTTP requests
 type DocumentHandler struct {
-	documentService service.DocumentService
+	documentService  service.DocumentService
+	relationService  service.RelationService
+	watermarkService service.WatermarkService
 }
 
 // NewDocumentHandler creates a new document handler
-func NewDocumentHandler(documentService service.DocumentService) *DocumentHandler {
+func NewDocumentHandler(documentService service.DocumentService, relationService service.RelationService, watermarkService service.WatermarkService) *DocumentHandler {
 	return &DocumentHandler{
-		documentService: documentService,
+		documentService:  documentService,
+		relationService:  relationService,
+		watermarkService: watermarkService,
 	}
 }
 
@@ -38,6 +49,10 @@ func NewDocumentHandler(documentService service.DocumentService) *DocumentHandle
 // @Param        title          formData  string  true  "Document title"
 // @Param        description    formData  string  false "Document description"
 // @Param        collection_id  formData  string  true  "Collection ID"
+// @Param        metadata       formData  string  false "Document metadata as JSON (author, isbn, tags, custom_fields, ...)"
+// @Param        available_from         formData  string  false "Start of availability (RFC 3339); the document is embargoed until then"
+// @Param        available_until        formData  string  false "End of availability (RFC 3339); the document expires then"
+// @Param        embargo_show_metadata  formData  bool    false "List the document's metadata during its embargo"
 // @Success      201  {object}  response.Response{data=models.Document} "Document uploaded"
 // @Failure      400  {object}  response.Response "Invalid input"
 // @Failure      401  {object}  response.Response "Unauthorized"
@@ -51,30 +66,22 @@ func (h *DocumentHandler) UploadDocument(c *gin.Context) {
 	}
 
 	// Get user role for permission check
-	role, roleExists := c.Get("role")
-	if !roleExists {
+	if _, roleExists := c.Get("role"); !roleExists {
 		response.Unauthorized(c, "User role not found")
 		return
 	}
 
 	// PERMISSION CHECK: Only admin, librarian, archivist, and vendor can upload
 	// Patrons have read-only access
-	var userRole string
-	switch r := role.(type) {
-	case string:
-		userRole = r
-	case models.UserRole:
-		userRole = string(r)
-	default:
-		userRole = fmt.Sprintf("%v", role)
-	}
-	if userRole == "patron" {
+	if userRole(c) == "patron" {
 		response.Error(c, http.StatusForbidden, "FORBIDDEN", "Patrons do not have permission to upload documents. Please contact your librarian or administrator.")
 		return
 	}
 
-	// Parse multipart form
-	if err := c.Request.ParseMultipartForm(100 << 20); err != nil { // 100 MB max
+	// Parse multipart form. Only small parts are kept in memory; file parts beyond
+	// multipartMemoryLimit are spooled to disk by net/http and streamed from there.
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, 100<<20) // 100 MB max
+	if err := c.Request.ParseMultipartForm(multipartMemoryLimit); err != nil {
 		response.BadRequest(c, "Failed to parse form: "+err.Error())
 		return
 	}
@@ -103,11 +110,25 @@ func (h *DocumentHandler) UploadDocument(c *gin.Context) {
 		return
 	}
 
+	docMetadata, err := parseMetadataField(c.PostForm("metadata"))
+	if err != nil {
+		response.BadRequest(c, "Invalid metadata: "+err.Error())
+		return
+	}
+
+	availability, err := parseAvailability(c.PostForm)
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
 	metadata := service.UploadMetadata{
 		CollectionID: collectionID,
 		UploaderID:   userID.(uuid.UUID),
 		Title:        title,
 		Description:  description,
+		Metadata:     docMetadata,
+		Availability: availability,
 	}
 
 	document, err := h.documentService.UploadDocument(file, header, metadata)
@@ -116,12 +137,12 @@ func (h *DocumentHandler) UploadDocument(c *gin.Context) {
 		return
 	}
 
-	response.Created(c, document, "Document uploaded successfully")
+	response.Created(c, document, uploadedMessage(document))
 }
 
 // GetDocument retrieves a document by ID
 // @Summary      Get document by ID
-// @Description  Get document details
+// @Description  Get document details, with navigation among the works it belongs to
 // @Tags         documents
 // @Security     BearerAuth
 // @Produce      json
@@ -152,6 +173,14 @@ func (h *DocumentHandler) GetDocument(c *gin.Context) {
 		return
 	}
 
+	// Place the document among the works it belongs to (previous/next volume)
+	navigation, err := h.relationService.Navigation(document, userID)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+	document.Navigation = navigation
+
 	response.Success(c, document, "")
 }
 
@@ -188,6 +217,8 @@ func (h *DocumentHandler) UpdateDocument(c *gin.Context) {
 		Title       *string                  `json:"title,omitempty"`
 		Description *string                  `json:"description,omitempty"`
 		Metadata    *models.DocumentMetadata `json:"metadata,omitempty"`
+		// Replaces the whole availability window; omit to keep it
+		Availability *service.Availability `json:"availability,omitempty"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -196,9 +227,10 @@ func (h *DocumentHandler) UpdateDocument(c *gin.Context) {
 	}
 
 	updates := service.DocumentUpdate{
-		Title:       req.Title,
-		Description: req.Description,
-		Metadata:    req.Metadata,
+		Title:        req.Title,
+		Description:  req.Description,
+		Metadata:     req.Metadata,
+		Availability: req.Availability,
 	}
 
 	document, err := h.documentService.UpdateDocument(id, updates, userID.(uuid.UUID))
@@ -212,7 +244,7 @@ func (h *DocumentHandler) UpdateDocument(c *gin.Context) {
 
 // DeleteDocument deletes a document
 // @Summary      Delete document
-// @Description  Delete a document (uploader or admin only)
+// @Description  Move a document to the trash (uploader or admin only). It can be restored until the retention period ends.
 // @Tags         documents
 // @Security     BearerAuth
 // @Produce      json
@@ -300,6 +332,7 @@ func (h *DocumentHandler) ListDocuments(c *gin.Context) {
 		FileType:     fileType,
 		Search:       search,
 		IsIndexed:    isIndexed,
+		ViewerID:     optionalUserID(c),
 	}
 
 	documents, total, err := h.documentService.ListDocuments(filters, page, pageSize)
@@ -339,8 +372,10 @@ func (h *DocumentHandler) UpdateDocumentStatus(c *gin.Context) {
 	}
 
 	var req struct {
-		Status    *string `json:"status"`
-		IsIndexed *bool   `json:"is_indexed"`
+		Status        *string `json:"status"`
+		IsIndexed     *bool   `json:"is_indexed"`
+		ExtractedText *string `json:"extracted_text"` // Reported by the indexer
+		Hash          string  `json:"hash"`           // Content the extracted text belongs to
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -359,6 +394,18 @@ func (h *DocumentHandler) UpdateDocumentStatus(c *gin.Context) {
 		}
 	}
 
+	if req.ExtractedText != nil {
+		// Only internal services (service secret) report extraction results
+		if uid != uuid.Nil {
+			response.Forbidden(c, "Only internal services can set extracted text")
+			return
+		}
+		if err := h.documentService.SetExtractedText(id, req.Hash, *req.ExtractedText); err != nil {
+			handleError(c, err)
+			return
+		}
+	}
+
 	if req.IsIndexed != nil {
 		err = h.documentService.SetIndexed(id, *req.IsIndexed, uid)
 		if err != nil {
@@ -444,11 +491,16 @@ func (h *DocumentHandler) RecordDownload(c *gin.Context) {
 
 // DownloadDocument streams the document file for download
 // @Summary      Download document
-// @Description  Download document file (as attachment)
+// @Description  Download document file (as attachment). Supports Range/If-Range and conditional requests (ETag, Last-Modified).
 // @Tags         documents
 // @Security     BearerAuth
 // @Param        id   path      string  true  "Document ID"
+// @Param        Range          header    string  false  "Byte range(s), e.g. bytes=0-1023"
+// @Param        If-None-Match  header    string  false  "ETag from a previous response"
 // @Success      200  {file}    binary
+// @Success      206  {file}    binary  "Partial content"
+// @Success      304  "Not modified"
+// @Failure      416  "Range not satisfiable"
 // @Failure      400  {object}  response.Response "Invalid ID"
 // @Failure      404  {object}  response.Response "Document not found"
 // @Failure      500  {object}  response.Response "Internal server error"
@@ -459,11 +511,16 @@ func (h *DocumentHandler) DownloadDocument(c *gin.Context) {
 
 // ViewDocument streams the document file for inline viewing
 // @Summary      View document
-// @Description  View document file (inline)
+// @Description  View document file (inline). Supports Range/If-Range and conditional requests (ETag, Last-Modified).
 // @Tags         documents
 // @Security     BearerAuth
 // @Param        id   path      string  true  "Document ID"
+// @Param        Range          header    string  false  "Byte range(s), e.g. bytes=0-1023"
+// @Param        If-None-Match  header    string  false  "ETag from a previous response"
 // @Success      200  {file}    binary
+// @Success      206  {file}    binary  "Partial content"
+// @Success      304  "Not modified"
+// @Failure      416  "Range not satisfiable"
 // @Failure      400  {object}  response.Response "Invalid ID"
 // @Failure      404  {object}  response.Response "Document not found"
 // @Failure      500  {object}  response.Response "Internal server error"
@@ -474,11 +531,16 @@ func (h *DocumentHandler) ViewDocument(c *gin.Context) {
 
 // GetThumbnail streams the document thumbnail
 // @Summary      Get document thumbnail
-// @Description  Get document thumbnail image
+// @Description  Get document thumbnail image. Supports Range/If-Range and conditional requests (ETag, Last-Modified).
 // @Tags         documents
 // @Security     BearerAuth
 // @Param        id   path      string  true  "Document ID"
+// @Param        Range          header    string  false  "Byte range(s), e.g. bytes=0-1023"
+// @Param        If-None-Match  header    string  false  "ETag from a previous response"
 // @Success      200  {file}    binary
+// @Success      206  {file}    binary  "Partial content"
+// @Success      304  "Not modified"
+// @Failure      416  "Range not satisfiable"
 // @Failure      400  {object}  response.Response "Invalid ID"
 // @Failure      404  {object}  response.Response "Thumbnail not found"
 // @Failure      500  {object}  response.Response "Internal server error"
@@ -497,15 +559,13 @@ func (h *DocumentHandler) GetThumbnail(c *gin.Context) {
 		userID = &id
 	}
 
-	stream, _, err := h.documentService.GetThumbnailStream(id, userID)
+	stream, document, err := h.documentService.GetThumbnailStream(id, userID)
 	if err != nil {
 		handleError(c, err)
 		return
 	}
 	defer stream.Close()
 
-	// Set headers
-	c.Header("Content-Type", "image/png")
 	// Cache control for thumbnails
 	c.Header("Cache-Control", "public, max-age=86400") // 24 hours
 	// CORS headers for cross-origin image loading
@@ -513,9 +573,7 @@ func (h *DocumentHandler) GetThumbnail(c *gin.Context) {
 	c.Header("Access-Control-Allow-Methods", "GET, OPTIONS")
 	c.Header("Cross-Origin-Resource-Policy", "cross-origin")
 
-	// Stream content
-	// We don't know size easily unless we ask object info, but chunked is fine for images
-	c.DataFromReader(http.StatusOK, -1, "image/png", stream, map[string]string{})
+	serveContent(c, stream, "image/png", contentETag(document.Hash, "thumb"), document.UpdatedAt)
 }
 
 // streamDocument handles common streaming logic
@@ -538,29 +596,103 @@ func (h *DocumentHandler) streamDocument(c *gin.Context, attachment bool) {
 		handleError(c, err)
 		return
 	}
+
+	// Office documents arrive here as their PDF rendition, so they are stamped too
+	stream, document, stamped, err := watermark(c, h.watermarkService, stream, document, userID)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
 	defer stream.Close()
 
 	// Record action (fire and forget handled by service, but we call record explicity?)
 	// Actually current RecordDownload/View is synchronous db update + async kafka
 	// Ideally we should call it here.
-	if attachment {
-		go h.documentService.RecordDownload(id, userID)
-	} else {
-		go h.documentService.RecordView(id, userID)
+	// Viewers and resumed downloads fetch the rest of the file in ranges; only the
+	// request that starts reading from the beginning counts.
+	if rangeHeader := c.GetHeader("Range"); rangeHeader == "" || strings.HasPrefix(rangeHeader, "bytes=0-") {
+		if attachment {
+			go h.documentService.RecordDownload(id, userID)
+		} else {
+			go h.documentService.RecordView(id, userID)
+		}
 	}
 
-	// Set headers
-	c.Header("Content-Type", document.MimeType)
-	c.Header("Content-Length", strconv.FormatInt(document.FileSize, 10))
-
 	disposition := "inline"
 	if attachment {
 		disposition = "attachment"
 	}
 	c.Header("Content-Disposition", fmt.Sprintf("%s; filename=\"%s\"", disposition, document.OriginalFilename))
 
-	// Stream content
-	c.DataFromReader(http.StatusOK, document.FileSize, document.MimeType, stream, map[string]string{})
+	if stamped {
+		serveStamped(c, stream, document)
+		return
+	}
+	// Access can be revoked, so clients must revalidate (cheap thanks to the ETag)
+	c.Header("Cache-Control", "private, no-cache")
+	serveContent(c, stream, document.MimeType, contentETag(document.Hash, ""), document.UpdatedAt)
+}
+
+// watermark stamps content for the reader of the request if the document's collection
+// watermarks deliveries, reporting whether it did. A stamped copy replaces content.
+func watermark(c *gin.Context, watermarks service.WatermarkService, content io.ReadSeekCloser, document *models.Document, userID *uuid.UUID) (io.ReadSeekCloser, *models.Document, bool, error) {
+	if !watermarks.Applies(document, userID) {
+		return content, document, false, nil
+	}
+	stamped, stampedDoc, err := watermarks.Stamp(content, document, watermarkFor(c, userID))
+	if err != nil {
+		return nil, nil, false, err
+	}
+	return stamped, stampedDoc, true, nil
+}
+
+// watermarkFor describes the reader of the current request for the watermark
+func watermarkFor(c *gin.Context, userID *uuid.UUID) service.Watermark {
+	mark := service.Watermark{
+		UserName:  c.GetString("username"),
+		RequestID: c.GetHeader("X-Request-ID"),
+		IssuedAt:  time.Now(),
+	}
+	if userID != nil {
+		mark.UserID = *userID
+	}
+	if mark.RequestID == "" {
+		mark.RequestID = uuid.New().String()
+		c.Header("X-Request-ID", mark.RequestID)
+	}
+	return mark
+}
+
+// serveContent writes content honouring Range, If-Range, If-None-Match and
+// If-Modified-Since, answering with 206 (multipart/byteranges for several ranges),
+// 304 or 416 as appropriate
+func serveContent(c *gin.Context, content io.ReadSeeker, contentType, etag string, modTime time.Time) {
+	c.Header("Content-Type", contentType)
+	if etag != "" {
+		c.Header("ETag", etag)
+	}
+	http.ServeContent(c.Writer, c.Request, "", modTime, content)
+}
+
+// serveStamped writes a stamped copy whole. Every request is stamped afresh, so ranges
+// of different copies can't be pieced together and none are offered, and the copy is not
+// to be cached.
+func serveStamped(c *gin.Context, content io.Reader, document *models.Document) {
+	c.Header("Accept-Ranges", "none")
+	c.Header("Cache-Control", "private, no-store")
+	c.DataFromReader(http.StatusOK, document.FileSize, document.MimeType, content, nil)
+}
+
+// contentETag derives a strong entity tag from a content hash, optionally for a
+// rendition of it (e.g. a thumbnail). Returns "" when the hash is unknown.
+func contentETag(hash, variant string) string {
+	if hash == "" {
+		return ""
+	}
+	if variant != "" {
+		hash += "-" + variant
+	}
+	return `"` + hash + `"`
 }
 
 // RegisterRoutes registers document routes
@@ -589,6 +721,80 @@ func (h *DocumentHandler) RegisterRoutes(router *gin.RouterGroup, optionalAuth,
 	}
 }
 
+// uploadedMessage describes the outcome of an upload
+func uploadedMessage(document *models.Document) string {
+	if document.Status == models.StatusSubmitted {
+		return "Document submitted for review"
+	}
+	return "Document uploaded successfully"
+}
+
+// parseMetadataField decodes the optional JSON document metadata sent with an upload
+func parseMetadataField(raw string) (*models.DocumentMetadata, error) {
+	if strings.TrimSpace(raw) == "" {
+		return nil, nil
+	}
+	var metadata models.DocumentMetadata
+	if err := json.Unmarshal([]byte(raw), &metadata); err != nil {
+		return nil, err
+	}
+	return &metadata, nil
+}
+
+// parseAvailability reads the optional availability window sent with an upload, from the
+// fields available_from, available_until and embargo_show_metadata looked up with field
+func parseAvailability(field func(string) string) (service.Availability, error) {
+	var availability service.Availability
+	for name, bound := range map[string]**time.Time{
+		"available_from":  &availability.AvailableFrom,
+		"available_until": &availability.AvailableUntil,
+	} {
+		raw := strings.TrimSpace(field(name))
+		if raw == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			return availability, fmt.Errorf("invalid %s: expected an RFC 3339 timestamp", name)
+		}
+		*bound = &t
+	}
+	availability.EmbargoShowMetadata = field("embargo_show_metadata") == "true"
+	return availability, nil
+}
+
+// userRole returns the authenticated user's role from the context as a string
+func userRole(c *gin.Context) string {
+	role, exists := c.Get("role")
+	if !exists {
+		return ""
+	}
+	switch r := role.(type) {
+	case string:
+		return r
+	case models.UserRole:
+		return string(r)
+	default:
+		return fmt.Sprintf("%v", role)
+	}
+}
+
+// errorStatus returns the HTTP status for an error
+func errorStatus(err error) int {
+	if appErr, ok := err.(*appErrors.AppError); ok {
+		return appErr.HTTPStatus
+	}
+	return http.StatusInternalServerError
+}
+
+// errorMessage returns the client-facing message for an error
+func errorMessage(err error) string {
+	if appErr, ok := err.(*appErrors.AppError); ok {
+		return appErr.Message
+	}
+	return err.Error()
+}
+
 // handleError handles errors and sends appropriate responses
 func handleError(c *gin.Context, err error) {
 	status := http.StatusInternalServerError
@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/Kyei-Ernest/libsystem/services/document-service/service"
+	"github.com/Kyei-Ernest/libsystem/shared/models"
+	"github.com/Kyei-Ernest/libsystem/shared/response"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// ExportHandler handles collection export requests
+type ExportHandler struct {
+	exportService service.ExportService
+}
+
+// NewExportHandler creates a new export handler
+func NewExportHandler(exportService service.ExportService) *ExportHandler {
+	return &ExportHandler{exportService: exportService}
+}
+
+// ExportCollectionRequest represents a collection export request
+type ExportCollectionRequest struct {
+	Format            service.ExportFormat `json:"format"` // zip (default) or bagit
+	IncludeVersions   bool                 `json:"include_versions"`
+	IncludeThumbnails bool                 `json:"include_thumbnails"`
+}
+
+// ExportCollection godoc
+// @Summary Export a collection
+// @Description Package a collection's documents as a ZIP or a BagIt (RFC 8493) bag, with manifest.json and manifest.csv and SHA-256 checksums, optionally with every version and thumbnails. Runs as a background job; poll /jobs/{jobID} for progress and, once completed, the expiring download_url in its result. Librarians, admins and the collection owner only.
+// @Tags export
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Collection ID"
+// @Param request body ExportCollectionRequest false "Export options"
+// @Success 202 {object} response.Response{data=jobs.Job} "Export started"
+// @Failure 400 {object} response.Response "Invalid input"
+// @Failure 403 {object} response.Response "Not allowed to export the collection"
+// @Failure 404 {object} response.Response "Collection not found"
+// @Router /collections/{id}/export [post]
+func (h *ExportHandler) ExportCollection(c *gin.Context) {
+	collectionID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.BadRequest(c, "Invalid collection ID")
+		return
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	var req ExportCollectionRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.BadRequest(c, "Invalid request body: "+err.Error())
+			return
+		}
+	}
+
+	role := userRole(c)
+	isStaff := role == string(models.RoleAdmin) || role == string(models.RoleLibrarian)
+	job, err := h.exportService.StartExport(collectionID, userID.(uuid.UUID), isStaff, service.ExportOptions{
+		Format:            req.Format,
+		IncludeVersions:   req.IncludeVersions,
+		IncludeThumbnails: req.IncludeThumbnails,
+	})
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, response.Response{
+		Success: true,
+		Message: "Export started",
+		Data:    job,
+	})
+}
+
+// RegisterRoutes registers export routes
+func (h *ExportHandler) RegisterRoutes(router *gin.RouterGroup, requiredAuth gin.HandlerFunc) {
+	router.POST("/collections/:id/export", requiredAuth, h.ExportCollection)
+}
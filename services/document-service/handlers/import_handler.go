@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/Kyei-Ernest/libsystem/services/document-service/service"
+	"github.com/Kyei-Ernest/libsystem/shared/models"
+	"github.com/Kyei-Ernest/libsystem/shared/response"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// importUploadLimit caps a manifest and archive upload
+const importUploadLimit = 2 << 30 // 2 GB
+
+// ImportHandler handles bulk imports from manifests
+type ImportHandler struct {
+	importService service.ImportService
+}
+
+// NewImportHandler creates a new import handler
+func NewImportHandler(importService service.ImportService) *ImportHandler {
+	return &ImportHandler{importService: importService}
+}
+
+// StartImport godoc
+// @Summary Import documents from a manifest
+// @Description Import documents described by a CSV or JSON Lines manifest, with the files in an uploaded ZIP or (librarians and admins) a directory under the server's import source root. Every row is validated first; with dry_run, or if any row is invalid, nothing is imported and the per-row errors are reported. Otherwise the import runs as a background job with an item per row (202); if rows fail, retry the job with /jobs/{jobID}/retry.
+// @Tags batch
+// @Security BearerAuth
+// @Accept multipart/form-data
+// @Produce json
+// @Param manifest formData file true "CSV (with a header row) or JSON Lines manifest"
+// @Param archive formData file false "ZIP holding the files the manifest names"
+// @Param source_dir formData string false "Directory under the import source root holding the files"
+// @Param format formData string false "csv or jsonl; inferred from the manifest's extension"
+// @Param collection_id formData string false "Collection for rows that name none"
+// @Param dry_run formData bool false "Only validate"
+// @Success 200 {object} response.Response{data=service.ImportReport} "Dry run report"
+// @Success 202 {object} response.Response{data=service.ImportReport} "Import started"
+// @Failure 400 {object} response.Response "Invalid manifest or rows"
+// @Router /documents/batch/import [post]
+func (h *ImportHandler) StartImport(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, importUploadLimit)
+	if err := c.Request.ParseMultipartForm(multipartMemoryLimit); err != nil {
+		response.BadRequest(c, "Failed to parse form: "+err.Error())
+		return
+	}
+	defer c.Request.MultipartForm.RemoveAll()
+
+	manifest, err := c.FormFile("manifest")
+	if err != nil {
+		response.BadRequest(c, "A manifest file is required")
+		return
+	}
+	archive, err := c.FormFile("archive")
+	if err != nil && err != http.ErrMissingFile {
+		response.BadRequest(c, "Invalid archive: "+err.Error())
+		return
+	}
+
+	var collectionID *uuid.UUID
+	if collectionIDStr := c.PostForm("collection_id"); collectionIDStr != "" {
+		id, err := uuid.Parse(collectionIDStr)
+		if err != nil {
+			response.BadRequest(c, "Invalid collection ID")
+			return
+		}
+		collectionID = &id
+	}
+
+	dryRun := false
+	if dryRunStr := c.PostForm("dry_run"); dryRunStr != "" {
+		if dryRun, err = strconv.ParseBool(dryRunStr); err != nil {
+			response.BadRequest(c, "Invalid dry_run")
+			return
+		}
+	}
+
+	role := userRole(c)
+	report, err := h.importService.StartImport(service.ImportRequest{
+		UserID:       userID.(uuid.UUID),
+		IsStaff:      role == string(models.RoleAdmin) || role == string(models.RoleLibrarian),
+		Manifest:     manifest,
+		Format:       service.ManifestFormat(c.PostForm("format")),
+		Archive:      archive,
+		SourceDir:    c.PostForm("source_dir"),
+		CollectionID: collectionID,
+		DryRun:       dryRun,
+	})
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	switch {
+	case dryRun:
+		response.Success(c, report, fmt.Sprintf("%d of %d rows are valid", report.Valid, report.Rows))
+	case report.Job == nil:
+		c.JSON(http.StatusBadRequest, response.Response{
+			Success: false,
+			Message: fmt.Sprintf("%d of %d rows are invalid; nothing was imported", len(report.Errors), report.Rows),
+			Data:    report,
+			Error: &response.ErrorInfo{
+				Code:    "VALIDATION_ERROR",
+				Message: "Manifest has invalid rows",
+			},
+		})
+	default:
+		c.JSON(http.StatusAccepted, response.Response{
+			Success: true,
+			Message: fmt.Sprintf("Import started: %d rows queued", report.Rows),
+			Data:    report,
+		})
+	}
+}
+
+// RegisterRoutes registers import routes
+func (h *ImportHandler) RegisterRoutes(router *gin.RouterGroup, requiredAuth gin.HandlerFunc) {
+	router.POST("/documents/batch/import", requiredAuth, h.StartImport)
+}
@@ -0,0 +1,442 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	appErrors "github.com/Kyei-Ernest/libsystem/shared/errors"
+	"github.com/Kyei-Ernest/libsystem/shared/jobs"
+	"github.com/Kyei-Ernest/libsystem/shared/response"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"golang.org/x/net/websocket"
+)
+
+// jobStreamKeepAlive is how often a job stream is checked against the database and, if
+// nothing changed, pinged to keep proxies from closing it
+const jobStreamKeepAlive = 15 * time.Second
+
+// JobHandler handles background job requests
+type JobHandler struct {
+	jobTracker *jobs.JobTracker
+	broker     *jobs.Broker
+}
+
+// NewJobHandler creates a new job handler
+func NewJobHandler(jobTracker *jobs.JobTracker, broker *jobs.Broker) *JobHandler {
+	return &JobHandler{jobTracker: jobTracker, broker: broker}
+}
+
+// ListJobs godoc
+// @Summary List your jobs
+// @Description The background jobs the user started, newest first, optionally of one type and status
+// @Tags jobs
+// @Security BearerAuth
+// @Produce json
+// @Param type query string false "bulk_upload, bulk_metadata_update, bulk_delete, bulk_import or collection_export"
+// @Param status query string false "pending, running, completed, failed or cancelled"
+// @Param page query int false "Page number" default(1)
+// @Param page_size query int false "Page size" default(20)
+// @Success 200 {object} response.Response{data=[]jobs.Job}
+// @Failure 400 {object} response.Response "Invalid filter"
+// @Router /jobs [get]
+func (h *JobHandler) ListJobs(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	filter := jobs.JobFilter{
+		Type:   jobs.JobType(c.Query("type")),
+		Status: jobs.JobStatus(c.Query("status")),
+	}
+	switch filter.Type {
+	case "", jobs.JobTypeBulkUpload, jobs.JobTypeBulkMetadataUpdate, jobs.JobTypeBulkDelete, jobs.JobTypeBulkImport, jobs.JobTypeCollectionExport:
+	default:
+		response.BadRequest(c, "Invalid job type")
+		return
+	}
+	switch filter.Status {
+	case "", jobs.JobStatusPending, jobs.JobStatusRunning, jobs.JobStatusCompleted, jobs.JobStatusFailed, jobs.JobStatusCancelled:
+	default:
+		response.BadRequest(c, "Invalid job status")
+		return
+	}
+	// The service identity sees every job
+	if id := userID.(uuid.UUID); id != uuid.Nil {
+		filter.CreatedBy = &id
+	}
+
+	page, pageSize := jobPage(c)
+	list, total, err := h.jobTracker.ListJobs(filter, (page-1)*pageSize, pageSize)
+	if err != nil {
+		handleError(c, appErrors.NewInternalError("Failed to list jobs", err))
+		return
+	}
+
+	response.Paginated(c, list, page, pageSize, total)
+}
+
+// GetJob godoc
+// @Summary Get a job
+// @Description The status, progress, errors and result of a background job the user started
+// @Tags jobs
+// @Security BearerAuth
+// @Produce json
+// @Param jobID path string true "Job ID"
+// @Success 200 {object} response.Response{data=jobs.Job}
+// @Failure 404 {object} response.Response "Job not found"
+// @Router /jobs/{jobID} [get]
+func (h *JobHandler) GetJob(c *gin.Context) {
+	job, ok := h.ownJob(c)
+	if !ok {
+		return
+	}
+	response.Success(c, job, "")
+}
+
+// ListJobItems godoc
+// @Summary List a job's items
+// @Description The files, rows or documents a bulk job works through, with each one's status, result and error
+// @Tags jobs
+// @Security BearerAuth
+// @Produce json
+// @Param jobID path string true "Job ID"
+// @Param status query string false "pending, done or failed"
+// @Param page query int false "Page number" default(1)
+// @Param page_size query int false "Page size" default(20)
+// @Success 200 {object} response.Response{data=[]jobs.Item}
+// @Failure 404 {object} response.Response "Job not found"
+// @Router /jobs/{jobID}/items [get]
+func (h *JobHandler) ListJobItems(c *gin.Context) {
+	job, ok := h.ownJob(c)
+	if !ok {
+		return
+	}
+
+	status := jobs.ItemStatus(c.Query("status"))
+	switch status {
+	case "", jobs.ItemStatusPending, jobs.ItemStatusDone, jobs.ItemStatusFailed:
+	default:
+		response.BadRequest(c, "Invalid item status")
+		return
+	}
+
+	page, pageSize := jobPage(c)
+	items, total, err := h.jobTracker.ListItems(job.ID, status, (page-1)*pageSize, pageSize)
+	if err != nil {
+		handleError(c, appErrors.NewInternalError("Failed to list job items", err))
+		return
+	}
+
+	response.Paginated(c, items, page, pageSize, total)
+}
+
+// CancelJob godoc
+// @Summary Cancel a job
+// @Description Cancel a pending or running job. A running job stops within a heartbeat; the items it finished stay done.
+// @Tags jobs
+// @Security BearerAuth
+// @Produce json
+// @Param jobID path string true "Job ID"
+// @Success 200 {object} response.Response{data=jobs.Job}
+// @Failure 404 {object} response.Response "Job not found"
+// @Failure 409 {object} response.Response "Job has already finished"
+// @Router /jobs/{jobID}/cancel [post]
+func (h *JobHandler) CancelJob(c *gin.Context) {
+	job, ok := h.ownJob(c)
+	if !ok {
+		return
+	}
+
+	job, err := h.jobTracker.CancelJob(job.ID)
+	if err != nil {
+		handleError(c, jobError(err))
+		return
+	}
+
+	message := "Job cancelled"
+	if job.Status == jobs.JobStatusRunning {
+		message = "Job is being cancelled"
+	}
+	response.Success(c, job, message)
+}
+
+// RetryJob godoc
+// @Summary Retry a job
+// @Description Queue a failed or cancelled job again. Only its failed and unfinished items are redone; a job without items starts over.
+// @Tags jobs
+// @Security BearerAuth
+// @Produce json
+// @Param jobID path string true "Job ID"
+// @Success 202 {object} response.Response{data=jobs.Job}
+// @Failure 404 {object} response.Response "Job not found"
+// @Failure 409 {object} response.Response "Job can't be retried"
+// @Router /jobs/{jobID}/retry [post]
+func (h *JobHandler) RetryJob(c *gin.Context) {
+	job, ok := h.ownJob(c)
+	if !ok {
+		return
+	}
+
+	job, err := h.jobTracker.RetryJob(job.ID)
+	if err != nil {
+		handleError(c, jobError(err))
+		return
+	}
+
+	c.JSON(http.StatusAccepted, response.Response{
+		Success: true,
+		Message: "Job queued again",
+		Data:    job,
+	})
+}
+
+// StreamJob godoc
+// @Summary Stream a job's progress
+// @Description Server-sent events with the job's state: a progress event now and on every change (completed, failed, errors, result), then a done event once it finishes, after which the stream ends. EventSource clients can pass the token as access_token.
+// @Tags jobs
+// @Security BearerAuth
+// @Produce text/event-stream
+// @Param jobID path string true "Job ID"
+// @Param access_token query string false "Token, for clients that can't set headers"
+// @Success 200 {string} string "Event stream of jobs.Job"
+// @Failure 404 {object} response.Response "Job not found"
+// @Router /jobs/{jobID}/events [get]
+func (h *JobHandler) StreamJob(c *gin.Context) {
+	job, ok := h.ownJob(c)
+	if !ok {
+		return
+	}
+
+	// The stream lasts as long as the job, beyond the server's timeouts
+	controller := http.NewResponseController(c.Writer)
+	controller.SetReadDeadline(time.Time{})
+	controller.SetWriteDeadline(time.Time{})
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no") // Tell nginx not to buffer
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+
+	h.follow(c.Request.Context(), job.ID, &sseSink{w: c.Writer})
+}
+
+// WatchJob godoc
+// @Summary Watch a job's progress over a WebSocket
+// @Description The same updates as /jobs/{jobID}/events as JSON text messages, {"event": "progress" | "done" | "ping", "job": {...}}. The server closes the socket once the job finishes. Browsers pass the token as access_token.
+// @Tags jobs
+// @Security BearerAuth
+// @Param jobID path string true "Job ID"
+// @Param access_token query string false "Token, for clients that can't set headers"
+// @Success 101 {string} string "Switching protocols"
+// @Failure 404 {object} response.Response "Job not found"
+// @Router /jobs/{jobID}/ws [get]
+func (h *JobHandler) WatchJob(c *gin.Context) {
+	job, ok := h.ownJob(c)
+	if !ok {
+		return
+	}
+
+	// Clients connect from anywhere with a token, so the origin is not checked
+	server := websocket.Server{Handler: func(ws *websocket.Conn) {
+		defer ws.Close()
+		ws.SetDeadline(time.Time{})
+
+		// Messages from the client are ignored; reading notices when it goes away
+		ctx, cancel := context.WithCancel(c.Request.Context())
+		defer cancel()
+		go func() {
+			defer cancel()
+			var discard string
+			for websocket.Message.Receive(ws, &discard) == nil {
+			}
+		}()
+
+		h.follow(ctx, job.ID, &websocketSink{ws: ws})
+	}}
+	server.ServeHTTP(c.Writer, c.Request)
+}
+
+// jobSink is where a job stream writes to
+type jobSink interface {
+	// Send writes the job's state; done marks the last one
+	Send(job *jobs.Job, done bool) error
+	// Ping keeps an idle stream open
+	Ping() error
+}
+
+// follow streams a job's state to the sink until the job finishes or ctx is cancelled.
+// Updates come from the broker; the database is checked between them too, so a stream
+// still catches up if an update was lost on the way.
+func (h *JobHandler) follow(ctx context.Context, jobID uuid.UUID, sink jobSink) {
+	// Watch before reading the job, so no change falls in between
+	updates, stop := h.broker.Watch(jobID)
+	defer stop()
+
+	last, err := h.jobTracker.GetJob(jobID)
+	if err != nil {
+		return
+	}
+	if err := sink.Send(last, last.Finished()); err != nil || last.Finished() {
+		return
+	}
+
+	ticker := time.NewTicker(jobStreamKeepAlive)
+	defer ticker.Stop()
+	for {
+		var next *jobs.Job
+		select {
+		case <-ctx.Done():
+			return
+		case next = <-updates:
+		case <-ticker.C:
+			if next, err = h.jobTracker.GetJob(jobID); err != nil || !next.UpdatedAt.After(last.UpdatedAt) {
+				if sink.Ping() != nil {
+					return
+				}
+				continue
+			}
+		}
+
+		// Updates from different replicas can arrive out of order
+		if next.UpdatedAt.Before(last.UpdatedAt) {
+			continue
+		}
+		last = next
+		if err := sink.Send(last, last.Finished()); err != nil || last.Finished() {
+			return
+		}
+	}
+}
+
+// sseSink writes a job stream as server-sent events
+type sseSink struct {
+	w gin.ResponseWriter
+}
+
+func (s *sseSink) Send(job *jobs.Job, done bool) error {
+	data, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+	event := "progress"
+	if done {
+		event = "done"
+	}
+	if _, err := fmt.Fprintf(s.w, "event: %s\nid: %d\ndata: %s\n\n", event, job.UpdatedAt.UnixMilli(), data); err != nil {
+		return err
+	}
+	s.w.Flush()
+	return nil
+}
+
+func (s *sseSink) Ping() error {
+	if _, err := io.WriteString(s.w, ": ping\n\n"); err != nil {
+		return err
+	}
+	s.w.Flush()
+	return nil
+}
+
+// websocketSink writes a job stream as WebSocket messages
+type websocketSink struct {
+	ws *websocket.Conn
+}
+
+// jobMessage is a WebSocket message of a job stream
+type jobMessage struct {
+	Event string    `json:"event"`
+	Job   *jobs.Job `json:"job,omitempty"`
+}
+
+func (s *websocketSink) Send(job *jobs.Job, done bool) error {
+	event := "progress"
+	if done {
+		event = "done"
+	}
+	return websocket.JSON.Send(s.ws, jobMessage{Event: event, Job: job})
+}
+
+func (s *websocketSink) Ping() error {
+	return websocket.JSON.Send(s.ws, jobMessage{Event: "ping"})
+}
+
+// ownJob loads the job in the path if the user started it, writing the error otherwise.
+// Jobs belong to whoever started them; results can hold download links.
+func (h *JobHandler) ownJob(c *gin.Context) (*jobs.Job, bool) {
+	jobID, err := uuid.Parse(c.Param("jobID"))
+	if err != nil {
+		response.BadRequest(c, "Invalid job ID")
+		return nil, false
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		response.Unauthorized(c, "User not authenticated")
+		return nil, false
+	}
+
+	job, err := h.jobTracker.GetJob(jobID)
+	if err == nil && job.CreatedBy != userID.(uuid.UUID) && userID.(uuid.UUID) != uuid.Nil {
+		err = jobs.ErrJobNotFound
+	}
+	if err != nil {
+		handleError(c, jobError(err))
+		return nil, false
+	}
+	return job, true
+}
+
+// jobError maps job tracker errors to API errors
+func jobError(err error) error {
+	var jobErr *jobs.JobError
+	if !errors.As(err, &jobErr) {
+		return appErrors.NewInternalError("Job operation failed", err)
+	}
+	if jobErr == jobs.ErrJobNotFound {
+		return appErrors.NewNotFoundError("Job", err)
+	}
+	return &appErrors.AppError{
+		Code:       appErrors.ErrCodeConflict,
+		Message:    jobErr.Message,
+		HTTPStatus: http.StatusConflict,
+	}
+}
+
+// jobPage reads the page and page size of a job listing
+func jobPage(c *gin.Context) (int, int) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+	return page, pageSize
+}
+
+// RegisterRoutes registers job routes. The streams also take the token from the query,
+// through streamAuth, for browser clients.
+func (h *JobHandler) RegisterRoutes(router *gin.RouterGroup, requiredAuth, streamAuth gin.HandlerFunc) {
+	jobRoutes := router.Group("/jobs")
+	{
+		jobRoutes.GET("", requiredAuth, h.ListJobs)
+		jobRoutes.GET("/:jobID", requiredAuth, h.GetJob)
+		jobRoutes.GET("/:jobID/items", requiredAuth, h.ListJobItems)
+		jobRoutes.GET("/:jobID/events", streamAuth, requiredAuth, h.StreamJob)
+		jobRoutes.GET("/:jobID/ws", streamAuth, requiredAuth, h.WatchJob)
+		jobRoutes.POST("/:jobID/cancel", requiredAuth, h.CancelJob)
+		jobRoutes.POST("/:jobID/retry", requiredAuth, h.RetryJob)
+	}
+}
@@ -0,0 +1,236 @@
+package handlers
+
+import (
+	"strconv"
+
+	"github.com/Kyei-Ernest/libsystem/services/document-service/service"
+	"github.com/Kyei-Ernest/libsystem/shared/response"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// LendingHandler handles controlled digital lending requests
+type LendingHandler struct {
+	lendingService service.LendingService
+}
+
+// NewLendingHandler creates a new lending handler
+func NewLendingHandler(lendingService service.LendingService) *LendingHandler {
+	return &LendingHandler{lendingService: lendingService}
+}
+
+// GetLending godoc
+// @Summary Get lending status
+// @Description Whether a document is lent, how many licences are free, how many users are waiting, and your loan or waitlist place
+// @Tags lending
+// @Produce json
+// @Param id path string true "Document ID"
+// @Success 200 {object} response.Response{data=service.LendingStatus}
+// @Failure 404 {object} response.Response "Document not found"
+// @Router /documents/{id}/lending [get]
+func (h *LendingHandler) GetLending(c *gin.Context) {
+	documentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.BadRequest(c, "Invalid document ID")
+		return
+	}
+
+	status, err := h.lendingService.GetStatus(documentID, optionalUserID(c))
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	response.Success(c, status, "Lending status retrieved successfully")
+}
+
+// ConfigureLending godoc
+// @Summary Lend a document
+// @Description Put a document under controlled digital lending: viewing and downloading it then need a loan, and at most `licenses` users hold one at a time. Uploader or collection owner only.
+// @Tags lending
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Document ID"
+// @Param request body service.LendingSettings true "Lending terms"
+// @Success 200 {object} response.Response{data=service.LendingStatus}
+// @Failure 403 {object} response.Response "Not the uploader or collection owner"
+// @Router /documents/{id}/lending [put]
+func (h *LendingHandler) ConfigureLending(c *gin.Context) {
+	documentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.BadRequest(c, "Invalid document ID")
+		return
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	var req service.LendingSettings
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "Invalid request body: "+err.Error())
+		return
+	}
+
+	status, err := h.lendingService.Configure(documentID, userID.(uuid.UUID), &req)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	response.Success(c, status, "Lending updated successfully")
+}
+
+// EndLending godoc
+// @Summary Stop lending a document
+// @Description Make a lent document readable without a loan again. Open loans are closed and the waitlist cleared. Uploader or collection owner only.
+// @Tags lending
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Document ID"
+// @Success 200 {object} response.Response{data=service.LendingStatus}
+// @Failure 403 {object} response.Response "Not the uploader or collection owner"
+// @Router /documents/{id}/lending [delete]
+func (h *LendingHandler) EndLending(c *gin.Context) {
+	documentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.BadRequest(c, "Invalid document ID")
+		return
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	status, err := h.lendingService.Configure(documentID, userID.(uuid.UUID), nil)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	response.Success(c, status, "Lending ended successfully")
+}
+
+// Borrow godoc
+// @Summary Borrow a document
+// @Description Borrow a lent document for its loan period. When every licence is taken you join the waitlist (status "waiting", with your position) and are lent the document automatically when your turn comes. Borrowing again returns your current loan or place.
+// @Tags lending
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Document ID"
+// @Success 200 {object} response.Response{data=models.DigitalLoan}
+// @Failure 400 {object} response.Response "Document is not lent"
+// @Router /documents/{id}/borrow [post]
+func (h *LendingHandler) Borrow(c *gin.Context) {
+	documentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.BadRequest(c, "Invalid document ID")
+		return
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	loan, err := h.lendingService.Borrow(documentID, userID.(uuid.UUID))
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	response.Success(c, loan, "Document borrowed successfully")
+}
+
+// Return godoc
+// @Summary Return a document
+// @Description Return your loan of a document early, or leave its waitlist. The licence passes to the next user waiting.
+// @Tags lending
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Document ID"
+// @Success 200 {object} response.Response{data=models.DigitalLoan}
+// @Failure 404 {object} response.Response "No loan"
+// @Router /documents/{id}/return [post]
+func (h *LendingHandler) Return(c *gin.Context) {
+	documentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.BadRequest(c, "Invalid document ID")
+		return
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	loan, err := h.lendingService.Return(documentID, userID.(uuid.UUID))
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	response.Success(c, loan, "Document returned successfully")
+}
+
+// ListLoans godoc
+// @Summary My digital loans
+// @Description Your loans of lent documents and waitlist places, newest first
+// @Tags lending
+// @Security BearerAuth
+// @Produce json
+// @Param status query string false "open (default) or all"
+// @Param page query int false "Page number" default(1)
+// @Param page_size query int false "Page size" default(20)
+// @Success 200 {object} response.Response{data=[]models.DigitalLoan}
+// @Router /lending/loans [get]
+func (h *LendingHandler) ListLoans(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	status := c.DefaultQuery("status", "open")
+	if status != "open" && status != "all" {
+		response.BadRequest(c, "status must be open or all")
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	loans, total, err := h.lendingService.ListLoans(userID.(uuid.UUID), status == "open", page, pageSize)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	response.Paginated(c, loans, page, pageSize, total)
+}
+
+// RegisterRoutes registers lending routes
+func (h *LendingHandler) RegisterRoutes(router *gin.RouterGroup, optionalAuth, requiredAuth gin.HandlerFunc) {
+	documents := router.Group("/documents/:id")
+	{
+		documents.GET("/lending", optionalAuth, h.GetLending)
+		documents.PUT("/lending", requiredAuth, h.ConfigureLending)
+		documents.DELETE("/lending", requiredAuth, h.EndLending)
+		documents.POST("/borrow", requiredAuth, h.Borrow)
+		documents.POST("/return", requiredAuth, h.Return)
+	}
+	router.GET("/lending/loans", requiredAuth, h.ListLoans)
+}
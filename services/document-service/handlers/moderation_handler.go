@@ -0,0 +1,233 @@
+package handlers
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/Kyei-Ernest/libsystem/services/document-service/service"
+	"github.com/Kyei-Ernest/libsystem/shared/models"
+	"github.com/Kyei-Ernest/libsystem/shared/response"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// ModerationHandler handles the review queue for collections that require approval
+type ModerationHandler struct {
+	moderationService service.ModerationService
+}
+
+// NewModerationHandler creates a new moderation handler
+func NewModerationHandler(moderationService service.ModerationService) *ModerationHandler {
+	return &ModerationHandler{moderationService: moderationService}
+}
+
+// ListSubmissions lists the review queue
+// @Summary      List submissions
+// @Description  Submissions awaiting review (or rejected). Librarians and admins see all; others see their own submissions and those to collections they own.
+// @Tags         moderation
+// @Security     BearerAuth
+// @Produce      json
+// @Param        status        query     string  false  "submitted (default) or rejected"
+// @Param        collection_id query     string  false  "Collection ID"
+// @Param        page          query     int     false  "Page number" default(1)
+// @Param        page_size     query     int     false  "Page size" default(20)
+// @Success      200  {object}  response.Response{data=[]models.Document} "Submissions"
+// @Failure      400  {object}  response.Response "Invalid input"
+// @Router       /documents/submissions [get]
+func (h *ModerationHandler) ListSubmissions(c *gin.Context) {
+	reviewer, ok := currentReviewer(c)
+	if !ok {
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	var collectionID *uuid.UUID
+	if collectionIDStr := c.Query("collection_id"); collectionIDStr != "" {
+		id, err := uuid.Parse(collectionIDStr)
+		if err != nil {
+			response.BadRequest(c, "Invalid collection ID")
+			return
+		}
+		collectionID = &id
+	}
+
+	status := models.DocumentStatus(c.Query("status"))
+	documents, total, err := h.moderationService.ListSubmissions(reviewer, status, collectionID, page, pageSize)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	response.Paginated(c, documents, page, pageSize, total)
+}
+
+// GetSubmission retrieves a submission
+// @Summary      Get submission
+// @Description  Get a submission, including the review decision once made
+// @Tags         moderation
+// @Security     BearerAuth
+// @Produce      json
+// @Param        id   path      string  true  "Document ID"
+// @Success      200  {object}  response.Response{data=models.Document} "Submission"
+// @Failure      404  {object}  response.Response "Not found"
+// @Router       /documents/submissions/{id} [get]
+func (h *ModerationHandler) GetSubmission(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.BadRequest(c, "Invalid document ID")
+		return
+	}
+	reviewer, ok := currentReviewer(c)
+	if !ok {
+		return
+	}
+
+	document, err := h.moderationService.GetSubmission(id, reviewer)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	response.Success(c, document, "")
+}
+
+// DownloadSubmission downloads the file of a submission for review
+// @Summary      Download submission
+// @Description  Download the file of a submission. Supports Range and conditional requests.
+// @Tags         moderation
+// @Security     BearerAuth
+// @Param        id   path      string  true  "Document ID"
+// @Success      200  {file}    binary
+// @Failure      404  {object}  response.Response "Not found"
+// @Router       /documents/submissions/{id}/download [get]
+func (h *ModerationHandler) DownloadSubmission(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.BadRequest(c, "Invalid document ID")
+		return
+	}
+	reviewer, ok := currentReviewer(c)
+	if !ok {
+		return
+	}
+
+	stream, document, err := h.moderationService.GetSubmissionStream(id, reviewer)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+	defer stream.Close()
+
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", document.OriginalFilename))
+	c.Header("Cache-Control", "private, no-cache")
+
+	serveContent(c, stream, document.MimeType, contentETag(document.Hash, ""), document.UpdatedAt)
+}
+
+// ApproveSubmission approves a submission
+// @Summary      Approve submission
+// @Description  Accept a submission into its collection; it is then indexed. The submitter is notified.
+// @Tags         moderation
+// @Security     BearerAuth
+// @Produce      json
+// @Param        id   path      string  true  "Document ID"
+// @Success      200  {object}  response.Response{data=models.Document} "Submission approved"
+// @Failure      403  {object}  response.Response "Forbidden"
+// @Failure      409  {object}  response.Response "Already reviewed"
+// @Router       /documents/submissions/{id}/approve [post]
+func (h *ModerationHandler) ApproveSubmission(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.BadRequest(c, "Invalid document ID")
+		return
+	}
+	reviewer, ok := currentReviewer(c)
+	if !ok {
+		return
+	}
+
+	document, err := h.moderationService.Approve(id, reviewer)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	response.Success(c, document, "Submission approved")
+}
+
+// RejectSubmission rejects a submission
+// @Summary      Reject submission
+// @Description  Decline a submission with a reason. The submitter is notified.
+// @Tags         moderation
+// @Security     BearerAuth
+// @Accept       json
+// @Produce      json
+// @Param        id       path      string  true  "Document ID"
+// @Param        request  body      object  true  "reason"
+// @Success      200  {object}  response.Response{data=models.Document} "Submission rejected"
+// @Failure      400  {object}  response.Response "Reason missing"
+// @Failure      409  {object}  response.Response "Already reviewed"
+// @Router       /documents/submissions/{id}/reject [post]
+func (h *ModerationHandler) RejectSubmission(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.BadRequest(c, "Invalid document ID")
+		return
+	}
+	reviewer, ok := currentReviewer(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Reason string `json:"reason" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "Invalid request body: "+err.Error())
+		return
+	}
+
+	document, err := h.moderationService.Reject(id, reviewer, req.Reason)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	response.Success(c, document, "Submission rejected")
+}
+
+// RegisterRoutes registers moderation routes
+func (h *ModerationHandler) RegisterRoutes(router *gin.RouterGroup, requiredAuth gin.HandlerFunc) {
+	submissions := router.Group("/documents/submissions")
+	{
+		submissions.GET("", requiredAuth, h.ListSubmissions)
+		submissions.GET("/:id", requiredAuth, h.GetSubmission)
+		submissions.GET("/:id/download", requiredAuth, h.DownloadSubmission)
+		submissions.POST("/:id/approve", requiredAuth, h.ApproveSubmission)
+		submissions.POST("/:id/reject", requiredAuth, h.RejectSubmission)
+	}
+}
+
+// currentReviewer builds the reviewer from the authenticated user, responding with 401
+// if there is none
+func currentReviewer(c *gin.Context) (service.Reviewer, bool) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		response.Unauthorized(c, "User not authenticated")
+		return service.Reviewer{}, false
+	}
+
+	role := userRole(c)
+	return service.Reviewer{
+		ID:      userID.(uuid.UUID),
+		IsStaff: role == string(models.RoleAdmin) || role == string(models.RoleLibrarian),
+	}, true
+}
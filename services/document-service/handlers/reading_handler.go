@@ -0,0 +1,167 @@
+package handlers
+
+import (
+	"strconv"
+
+	"github.com/Kyei-Ernest/libsystem/services/document-service/service"
+	"github.com/Kyei-Ernest/libsystem/shared/response"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// ReadingHandler handles reading progress requests
+type ReadingHandler struct {
+	readingService service.ReadingService
+}
+
+// NewReadingHandler creates a new reading handler
+func NewReadingHandler(readingService service.ReadingService) *ReadingHandler {
+	return &ReadingHandler{readingService: readingService}
+}
+
+// GetProgress godoc
+// @Summary Get reading progress
+// @Description Your position in a document, as last synced by a viewer
+// @Tags reading
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Document ID"
+// @Success 200 {object} response.Response{data=models.ReadingProgress}
+// @Failure 404 {object} response.Response "Not read yet"
+// @Router /documents/{id}/progress [get]
+func (h *ReadingHandler) GetProgress(c *gin.Context) {
+	documentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.BadRequest(c, "Invalid document ID")
+		return
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	progress, err := h.readingService.GetProgress(documentID, userID.(uuid.UUID))
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	response.Success(c, progress, "Reading progress retrieved successfully")
+}
+
+// SaveProgress godoc
+// @Summary Sync reading progress
+// @Description Record your page and/or percentage in a document. A position with an earlier read_at than the stored one is ignored; the stored position is returned.
+// @Tags reading
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Document ID"
+// @Param request body service.ProgressInput true "Position"
+// @Success 200 {object} response.Response{data=models.ReadingProgress}
+// @Failure 400 {object} response.Response
+// @Router /documents/{id}/progress [put]
+func (h *ReadingHandler) SaveProgress(c *gin.Context) {
+	documentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.BadRequest(c, "Invalid document ID")
+		return
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	var req service.ProgressInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "Invalid request body: "+err.Error())
+		return
+	}
+
+	progress, err := h.readingService.SaveProgress(documentID, req, userID.(uuid.UUID))
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	response.Success(c, progress, "Reading progress saved")
+}
+
+// DeleteProgress godoc
+// @Summary Forget reading progress
+// @Description Remove a document from your reading history
+// @Tags reading
+// @Security BearerAuth
+// @Param id path string true "Document ID"
+// @Success 200 {object} response.Response
+// @Router /documents/{id}/progress [delete]
+func (h *ReadingHandler) DeleteProgress(c *gin.Context) {
+	documentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.BadRequest(c, "Invalid document ID")
+		return
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	if err := h.readingService.DeleteProgress(documentID, userID.(uuid.UUID)); err != nil {
+		handleError(c, err)
+		return
+	}
+
+	response.Success(c, nil, "Reading progress deleted")
+}
+
+// ListRecent godoc
+// @Summary Recently read
+// @Description Documents you viewed, downloaded or synced a position in, most recent first
+// @Tags reading
+// @Security BearerAuth
+// @Produce json
+// @Param page query int false "Page number" default(1)
+// @Param page_size query int false "Page size" default(20)
+// @Success 200 {object} response.Response{data=[]models.ReadingProgress}
+// @Router /reading/recent [get]
+func (h *ReadingHandler) ListRecent(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	progress, total, err := h.readingService.ListRecent(userID.(uuid.UUID), page, pageSize)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	response.Paginated(c, progress, page, pageSize, total)
+}
+
+// RegisterRoutes registers reading progress routes
+func (h *ReadingHandler) RegisterRoutes(router *gin.RouterGroup, requiredAuth gin.HandlerFunc) {
+	progress := router.Group("/documents/:id/progress")
+	{
+		progress.GET("", requiredAuth, h.GetProgress)
+		progress.PUT("", requiredAuth, h.SaveProgress)
+		progress.DELETE("", requiredAuth, h.DeleteProgress)
+	}
+	router.GET("/reading/recent", requiredAuth, h.ListRecent)
+}
@@ -0,0 +1,187 @@
+package handlers
+
+import (
+	"github.com/Kyei-Ernest/libsystem/services/document-service/middleware"
+	"github.com/Kyei-Ernest/libsystem/services/document-service/service"
+	"github.com/Kyei-Ernest/libsystem/shared/models"
+	"github.com/Kyei-Ernest/libsystem/shared/response"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// RelationHandler handles requests for relationships between documents
+type RelationHandler struct {
+	relationService service.RelationService
+}
+
+// NewRelationHandler creates a new relation handler
+func NewRelationHandler(relationService service.RelationService) *RelationHandler {
+	return &RelationHandler{relationService: relationService}
+}
+
+// ListRelations godoc
+// @Summary List document relations
+// @Description List a document's relations (part_of, has_part, series_member, has_member, supersedes, superseded_by, translation_of, has_translation, related), each as seen from the document
+// @Tags relations
+// @Produce json
+// @Param id path string true "Document ID"
+// @Success 200 {object} response.Response{data=[]service.RelatedDocument}
+// @Failure 404 {object} response.Response
+// @Router /documents/{id}/relations [get]
+func (h *RelationHandler) ListRelations(c *gin.Context) {
+	documentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.BadRequest(c, "Invalid document ID")
+		return
+	}
+
+	relations, err := h.relationService.ListRelations(documentID, optionalUserID(c))
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	response.Success(c, relations, "")
+}
+
+// CreateRelation godoc
+// @Summary Relate documents
+// @Description Relate the document to a target document. Relations that would make a document part of itself are rejected, and a document is part of at most one work. has_part and has_member also require edit permission on the target.
+// @Tags relations
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Document ID"
+// @Param request body service.RelationInput true "Relation"
+// @Success 201 {object} response.Response{data=service.RelatedDocument}
+// @Failure 400 {object} response.Response "Invalid type, position or cycle"
+// @Failure 409 {object} response.Response "Relation exists"
+// @Router /documents/{id}/relations [post]
+func (h *RelationHandler) CreateRelation(c *gin.Context) {
+	documentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.BadRequest(c, "Invalid document ID")
+		return
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	var req service.RelationInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "Invalid request body: "+err.Error())
+		return
+	}
+
+	relation, err := h.relationService.CreateRelation(documentID, req, userID.(uuid.UUID), isAdmin(c))
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	response.Created(c, relation, "Relation created successfully")
+}
+
+// UpdateRelation godoc
+// @Summary Reorder a part or volume
+// @Description Set the position of a part_of or series_member relation within its work
+// @Tags relations
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param id path string true "Document ID"
+// @Param relationId path string true "Relation ID"
+// @Param request body object true "New position (position field; null clears it)"
+// @Success 200 {object} response.Response{data=service.RelatedDocument}
+// @Failure 400 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /documents/{id}/relations/{relationId} [put]
+func (h *RelationHandler) UpdateRelation(c *gin.Context) {
+	documentID, relationID, ok := parseRelationParams(c)
+	if !ok {
+		return
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	var req struct {
+		Position *int `json:"position"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "Invalid request body: "+err.Error())
+		return
+	}
+
+	relation, err := h.relationService.UpdatePosition(documentID, relationID, req.Position, userID.(uuid.UUID), isAdmin(c))
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	response.Success(c, relation, "Relation updated successfully")
+}
+
+// DeleteRelation godoc
+// @Summary Remove a relation
+// @Description Remove a relation between the document and another
+// @Tags relations
+// @Security BearerAuth
+// @Param id path string true "Document ID"
+// @Param relationId path string true "Relation ID"
+// @Success 200 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /documents/{id}/relations/{relationId} [delete]
+func (h *RelationHandler) DeleteRelation(c *gin.Context) {
+	documentID, relationID, ok := parseRelationParams(c)
+	if !ok {
+		return
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	if err := h.relationService.DeleteRelation(documentID, relationID, userID.(uuid.UUID), isAdmin(c)); err != nil {
+		handleError(c, err)
+		return
+	}
+
+	response.Success(c, nil, "Relation deleted successfully")
+}
+
+// RegisterRoutes registers relation routes. Changing a relation requires edit permission
+// on the document it is changed from, and for parts and members on the part or member too.
+func (h *RelationHandler) RegisterRoutes(router *gin.RouterGroup, optionalAuth, requiredAuth gin.HandlerFunc, permChecker *middleware.PermissionChecker) {
+	relations := router.Group("/documents/:id/relations")
+	{
+		relations.GET("", optionalAuth, h.ListRelations)
+		relations.POST("", requiredAuth, permChecker.RequireDocumentPermission(models.PermissionEdit), h.CreateRelation)
+		relations.PUT("/:relationId", requiredAuth, permChecker.RequireDocumentPermission(models.PermissionEdit), h.UpdateRelation)
+		relations.DELETE("/:relationId", requiredAuth, permChecker.RequireDocumentPermission(models.PermissionEdit), h.DeleteRelation)
+	}
+}
+
+// parseRelationParams parses the document and relation IDs from the path, responding with
+// 400 if either is invalid
+func parseRelationParams(c *gin.Context) (uuid.UUID, uuid.UUID, bool) {
+	documentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.BadRequest(c, "Invalid document ID")
+		return uuid.Nil, uuid.Nil, false
+	}
+	relationID, err := uuid.Parse(c.Param("relationId"))
+	if err != nil {
+		response.BadRequest(c, "Invalid relation ID")
+		return uuid.Nil, uuid.Nil, false
+	}
+	return documentID, relationID, true
+}
@@ -0,0 +1,393 @@
+package handlers
+
+import (
+	"strconv"
+
+	"github.com/Kyei-Ernest/libsystem/services/document-service/service"
+	"github.com/Kyei-Ernest/libsystem/shared/response"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// ShelfHandler handles requests for favorites and reading lists
+type ShelfHandler struct {
+	shelfService service.ShelfService
+}
+
+// NewShelfHandler creates a new shelf handler
+func NewShelfHandler(shelfService service.ShelfService) *ShelfHandler {
+	return &ShelfHandler{shelfService: shelfService}
+}
+
+// ListShelves godoc
+// @Summary List shelves
+// @Description The user's own shelves, favorites first, or the public shelves of owner_id
+// @Tags shelves
+// @Produce json
+// @Param owner_id query string false "Owner ID; the current user when omitted"
+// @Param page query int false "Page number" default(1)
+// @Param page_size query int false "Page size" default(20)
+// @Success 200 {object} response.Response{data=[]models.Shelf}
+// @Failure 401 {object} response.Response
+// @Router /shelves [get]
+func (h *ShelfHandler) ListShelves(c *gin.Context) {
+	userID := optionalUserID(c)
+
+	var ownerID uuid.UUID
+	if ownerIDStr := c.Query("owner_id"); ownerIDStr != "" {
+		id, err := uuid.Parse(ownerIDStr)
+		if err != nil {
+			response.BadRequest(c, "Invalid owner ID")
+			return
+		}
+		ownerID = id
+	} else if userID != nil {
+		ownerID = *userID
+	} else {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	shelves, total, err := h.shelfService.ListShelves(ownerID, userID, page, pageSize)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	response.Paginated(c, shelves, page, pageSize, total)
+}
+
+// GetShelf godoc
+// @Summary Get a shelf
+// @Description A shelf with its documents in order. Public shelves can be viewed by anyone; documents the viewer may not see are left out.
+// @Tags shelves
+// @Produce json
+// @Param shelfId path string true "Shelf ID"
+// @Success 200 {object} response.Response{data=models.Shelf}
+// @Failure 404 {object} response.Response
+// @Router /shelves/{shelfId} [get]
+func (h *ShelfHandler) GetShelf(c *gin.Context) {
+	shelfID, err := uuid.Parse(c.Param("shelfId"))
+	if err != nil {
+		response.BadRequest(c, "Invalid shelf ID")
+		return
+	}
+
+	shelf, err := h.shelfService.GetShelf(shelfID, optionalUserID(c))
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	response.Success(c, shelf, "Shelf retrieved successfully")
+}
+
+// CreateShelf godoc
+// @Summary Create a reading list
+// @Tags shelves
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body service.ShelfInput true "Reading list"
+// @Success 201 {object} response.Response{data=models.Shelf}
+// @Failure 409 {object} response.Response "Name already in use"
+// @Router /shelves [post]
+func (h *ShelfHandler) CreateShelf(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	var req service.ShelfInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "Invalid request body: "+err.Error())
+		return
+	}
+
+	shelf, err := h.shelfService.CreateShelf(req, userID.(uuid.UUID))
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	response.Created(c, shelf, "Shelf created successfully")
+}
+
+// UpdateShelf godoc
+// @Summary Update a shelf
+// @Description Rename or describe a reading list, or make a shelf public or private. Favorites cannot be renamed.
+// @Tags shelves
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param shelfId path string true "Shelf ID"
+// @Param request body service.ShelfUpdate true "Changes"
+// @Success 200 {object} response.Response{data=models.Shelf}
+// @Failure 404 {object} response.Response
+// @Router /shelves/{shelfId} [put]
+func (h *ShelfHandler) UpdateShelf(c *gin.Context) {
+	shelfID, err := uuid.Parse(c.Param("shelfId"))
+	if err != nil {
+		response.BadRequest(c, "Invalid shelf ID")
+		return
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	var req service.ShelfUpdate
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "Invalid request body: "+err.Error())
+		return
+	}
+
+	shelf, err := h.shelfService.UpdateShelf(shelfID, req, userID.(uuid.UUID))
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	response.Success(c, shelf, "Shelf updated successfully")
+}
+
+// DeleteShelf godoc
+// @Summary Delete a reading list
+// @Tags shelves
+// @Security BearerAuth
+// @Param shelfId path string true "Shelf ID"
+// @Success 200 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /shelves/{shelfId} [delete]
+func (h *ShelfHandler) DeleteShelf(c *gin.Context) {
+	shelfID, err := uuid.Parse(c.Param("shelfId"))
+	if err != nil {
+		response.BadRequest(c, "Invalid shelf ID")
+		return
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	if err := h.shelfService.DeleteShelf(shelfID, userID.(uuid.UUID)); err != nil {
+		handleError(c, err)
+		return
+	}
+
+	response.Success(c, nil, "Shelf deleted successfully")
+}
+
+// AddItem godoc
+// @Summary Add a document to a shelf
+// @Description Put a document on one of your shelves, at position (moving later documents down) or at the end
+// @Tags shelves
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param shelfId path string true "Shelf ID"
+// @Param request body service.ShelfItemInput true "Document"
+// @Success 201 {object} response.Response{data=models.ShelfItem}
+// @Failure 409 {object} response.Response "Already on the shelf"
+// @Router /shelves/{shelfId}/items [post]
+func (h *ShelfHandler) AddItem(c *gin.Context) {
+	shelfID, err := uuid.Parse(c.Param("shelfId"))
+	if err != nil {
+		response.BadRequest(c, "Invalid shelf ID")
+		return
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	var req service.ShelfItemInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "Invalid request body: "+err.Error())
+		return
+	}
+
+	item, err := h.shelfService.AddItem(shelfID, req, userID.(uuid.UUID))
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	response.Created(c, item, "Document added to shelf")
+}
+
+// UpdateItem godoc
+// @Summary Move or annotate a shelf item
+// @Tags shelves
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param shelfId path string true "Shelf ID"
+// @Param documentId path string true "Document ID"
+// @Param request body service.ShelfItemUpdate true "Changes"
+// @Success 200 {object} response.Response{data=models.ShelfItem}
+// @Failure 404 {object} response.Response
+// @Router /shelves/{shelfId}/items/{documentId} [put]
+func (h *ShelfHandler) UpdateItem(c *gin.Context) {
+	shelfID, documentID, ok := parseShelfItemParams(c)
+	if !ok {
+		return
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	var req service.ShelfItemUpdate
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "Invalid request body: "+err.Error())
+		return
+	}
+
+	item, err := h.shelfService.UpdateItem(shelfID, documentID, req, userID.(uuid.UUID))
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	response.Success(c, item, "Shelf item updated successfully")
+}
+
+// RemoveItem godoc
+// @Summary Remove a document from a shelf
+// @Tags shelves
+// @Security BearerAuth
+// @Param shelfId path string true "Shelf ID"
+// @Param documentId path string true "Document ID"
+// @Success 200 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /shelves/{shelfId}/items/{documentId} [delete]
+func (h *ShelfHandler) RemoveItem(c *gin.Context) {
+	shelfID, documentID, ok := parseShelfItemParams(c)
+	if !ok {
+		return
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	if err := h.shelfService.RemoveItem(shelfID, documentID, userID.(uuid.UUID)); err != nil {
+		handleError(c, err)
+		return
+	}
+
+	response.Success(c, nil, "Document removed from shelf")
+}
+
+// Favorite godoc
+// @Summary Favorite a document
+// @Description Add a document to your favorites; favoriting it again changes nothing
+// @Tags shelves
+// @Security BearerAuth
+// @Produce json
+// @Param id path string true "Document ID"
+// @Success 200 {object} response.Response{data=models.ShelfItem}
+// @Router /documents/{id}/favorite [put]
+func (h *ShelfHandler) Favorite(c *gin.Context) {
+	documentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.BadRequest(c, "Invalid document ID")
+		return
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	item, err := h.shelfService.Favorite(documentID, userID.(uuid.UUID))
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	response.Success(c, item, "Document added to favorites")
+}
+
+// Unfavorite godoc
+// @Summary Unfavorite a document
+// @Tags shelves
+// @Security BearerAuth
+// @Param id path string true "Document ID"
+// @Success 200 {object} response.Response
+// @Router /documents/{id}/favorite [delete]
+func (h *ShelfHandler) Unfavorite(c *gin.Context) {
+	documentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.BadRequest(c, "Invalid document ID")
+		return
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	if err := h.shelfService.Unfavorite(documentID, userID.(uuid.UUID)); err != nil {
+		handleError(c, err)
+		return
+	}
+
+	response.Success(c, nil, "Document removed from favorites")
+}
+
+// RegisterRoutes registers shelf and favorite routes
+func (h *ShelfHandler) RegisterRoutes(router *gin.RouterGroup, optionalAuth, requiredAuth gin.HandlerFunc) {
+	shelves := router.Group("/shelves")
+	{
+		shelves.GET("", optionalAuth, h.ListShelves)
+		shelves.POST("", requiredAuth, h.CreateShelf)
+		shelves.GET("/:shelfId", optionalAuth, h.GetShelf)
+		shelves.PUT("/:shelfId", requiredAuth, h.UpdateShelf)
+		shelves.DELETE("/:shelfId", requiredAuth, h.DeleteShelf)
+		shelves.POST("/:shelfId/items", requiredAuth, h.AddItem)
+		shelves.PUT("/:shelfId/items/:documentId", requiredAuth, h.UpdateItem)
+		shelves.DELETE("/:shelfId/items/:documentId", requiredAuth, h.RemoveItem)
+	}
+	router.PUT("/documents/:id/favorite", requiredAuth, h.Favorite)
+	router.DELETE("/documents/:id/favorite", requiredAuth, h.Unfavorite)
+}
+
+// parseShelfItemParams parses the shelf and document IDs from the path, responding with
+// 400 if either is invalid
+func parseShelfItemParams(c *gin.Context) (uuid.UUID, uuid.UUID, bool) {
+	shelfID, err := uuid.Parse(c.Param("shelfId"))
+	if err != nil {
+		response.BadRequest(c, "Invalid shelf ID")
+		return uuid.Nil, uuid.Nil, false
+	}
+	documentID, err := uuid.Parse(c.Param("documentId"))
+	if err != nil {
+		response.BadRequest(c, "Invalid document ID")
+		return uuid.Nil, uuid.Nil, false
+	}
+	return shelfID, documentID, true
+}
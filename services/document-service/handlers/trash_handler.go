@@ -0,0 +1,147 @@
+package handlers
+
+import (
+	"strconv"
+
+	"github.com/Kyei-Ernest/libsystem/services/document-service/service"
+	"github.com/Kyei-Ernest/libsystem/shared/models"
+	"github.com/Kyei-Ernest/libsystem/shared/response"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// TrashHandler handles requests for deleted documents
+type TrashHandler struct {
+	trashService service.TrashService
+}
+
+// NewTrashHandler creates a new trash handler
+func NewTrashHandler(trashService service.TrashService) *TrashHandler {
+	return &TrashHandler{trashService: trashService}
+}
+
+// ListTrash lists deleted documents
+// @Summary      List trash
+// @Description  Documents the user uploaded or deleted, or the trash of a collection they own. Trashed documents are purged after the retention period.
+// @Tags         trash
+// @Security     BearerAuth
+// @Produce      json
+// @Param        collection_id query     string  false  "Collection ID"
+// @Param        page          query     int     false  "Page number" default(1)
+// @Param        page_size     query     int     false  "Page size" default(20)
+// @Success      200  {object}  response.Response{data=[]models.Document} "Trashed documents"
+// @Failure      403  {object}  response.Response "Forbidden"
+// @Router       /documents/trash [get]
+func (h *TrashHandler) ListTrash(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	var collectionID *uuid.UUID
+	if collectionIDStr := c.Query("collection_id"); collectionIDStr != "" {
+		id, err := uuid.Parse(collectionIDStr)
+		if err != nil {
+			response.BadRequest(c, "Invalid collection ID")
+			return
+		}
+		collectionID = &id
+	}
+
+	documents, total, err := h.trashService.ListTrash(userID.(uuid.UUID), isAdmin(c), collectionID, page, pageSize)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	response.Paginated(c, documents, page, pageSize, total)
+}
+
+// RestoreDocument restores a deleted document
+// @Summary      Restore document
+// @Description  Takes a document out of the trash and reindexes it
+// @Tags         trash
+// @Security     BearerAuth
+// @Produce      json
+// @Param        id   path      string  true  "Document ID"
+// @Success      200  {object}  response.Response{data=models.Document} "Document restored"
+// @Failure      404  {object}  response.Response "Not in trash"
+// @Failure      409  {object}  response.Response "Collection is in the trash"
+// @Router       /documents/trash/{id}/restore [post]
+func (h *TrashHandler) RestoreDocument(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.BadRequest(c, "Invalid document ID")
+		return
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	document, err := h.trashService.RestoreDocument(id, userID.(uuid.UUID), isAdmin(c))
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	response.Success(c, document, "Document restored successfully")
+}
+
+// PurgeDocument permanently deletes a document in the trash
+// @Summary      Purge document
+// @Description  Permanently deletes a trashed document, its versions and files
+// @Tags         trash
+// @Security     BearerAuth
+// @Produce      json
+// @Param        id   path      string  true  "Document ID"
+// @Success      200  {object}  response.Response "Document purged"
+// @Failure      404  {object}  response.Response "Not in trash"
+// @Router       /documents/trash/{id} [delete]
+func (h *TrashHandler) PurgeDocument(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.BadRequest(c, "Invalid document ID")
+		return
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	if err := h.trashService.PurgeDocument(id, userID.(uuid.UUID), isAdmin(c)); err != nil {
+		handleError(c, err)
+		return
+	}
+
+	response.Success(c, nil, "Document permanently deleted")
+}
+
+// RegisterRoutes registers trash routes
+func (h *TrashHandler) RegisterRoutes(router *gin.RouterGroup, requiredAuth gin.HandlerFunc) {
+	trash := router.Group("/documents/trash")
+	{
+		trash.GET("", requiredAuth, h.ListTrash)
+		trash.POST("/:id/restore", requiredAuth, h.RestoreDocument)
+		trash.DELETE("/:id", requiredAuth, h.PurgeDocument)
+	}
+}
+
+// isAdmin reports whether the authenticated user is an administrator
+func isAdmin(c *gin.Context) bool {
+	return userRole(c) == string(models.RoleAdmin)
+}
@@ -0,0 +1,295 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/Kyei-Ernest/libsystem/services/document-service/service"
+	"github.com/Kyei-Ernest/libsystem/shared/models"
+	"github.com/Kyei-Ernest/libsystem/shared/response"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// tusVersion is the tus protocol version implemented by UploadHandler
+const tusVersion = "1.0.0"
+
+// UploadHandler implements the tus 1.0 resumable upload protocol
+// (core, creation, expiration and termination extensions)
+type UploadHandler struct {
+	uploadService service.UploadService
+}
+
+// NewUploadHandler creates a new resumable upload handler
+func NewUploadHandler(uploadService service.UploadService) *UploadHandler {
+	return &UploadHandler{uploadService: uploadService}
+}
+
+// CreateUpload opens a resumable upload
+// @Summary      Create resumable upload
+// @Description  tus 1.0 creation. Upload-Metadata keys: filename, filetype, title, description, collection_id, metadata (JSON document metadata), available_from, available_until (RFC 3339), embargo_show_metadata ("true")
+// @Tags         uploads
+// @Security     BearerAuth
+// @Param        Tus-Resumable    header    string  true   "tus protocol version (1.0.0)"
+// @Param        Upload-Length    header    int     true   "Total upload size in bytes"
+// @Param        Upload-Metadata  header    string  false  "Comma separated key/base64-value pairs"
+// @Success      201  "Upload created; Location header points to the upload"
+// @Failure      400  {object}  response.Response "Invalid input"
+// @Failure      403  {object}  response.Response "Forbidden"
+// @Failure      413  {object}  response.Response "Upload too large"
+// @Router       /uploads [post]
+func (h *UploadHandler) CreateUpload(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	// Patrons have read-only access
+	if userRole(c) == string(models.RolePatron) {
+		response.Error(c, http.StatusForbidden, "FORBIDDEN", "Patrons do not have permission to upload documents. Please contact your librarian or administrator.")
+		return
+	}
+
+	length, err := strconv.ParseInt(c.GetHeader("Upload-Length"), 10, 64)
+	if err != nil || length < 0 {
+		response.BadRequest(c, "Upload-Length header is required (deferred length is not supported)")
+		return
+	}
+	if length > h.uploadService.MaxUploadSize() {
+		response.Error(c, http.StatusRequestEntityTooLarge, "PAYLOAD_TOO_LARGE", "Upload exceeds Tus-Max-Size")
+		return
+	}
+
+	meta, err := parseUploadMetadata(c.GetHeader("Upload-Metadata"))
+	if err != nil {
+		response.BadRequest(c, "Invalid Upload-Metadata: "+err.Error())
+		return
+	}
+
+	var collectionID uuid.UUID
+	if raw := meta["collection_id"]; raw != "" {
+		collectionID, err = uuid.Parse(raw)
+		if err != nil {
+			response.BadRequest(c, "Invalid collection ID")
+			return
+		}
+	}
+
+	title := meta["title"]
+	if title == "" {
+		title = meta["filename"]
+	}
+
+	docMetadata, err := parseMetadataField(meta["metadata"])
+	if err != nil {
+		response.BadRequest(c, "Invalid metadata: "+err.Error())
+		return
+	}
+
+	availability, err := parseAvailability(func(key string) string { return meta[key] })
+	if err != nil {
+		response.BadRequest(c, err.Error())
+		return
+	}
+
+	session, err := h.uploadService.CreateUpload(length, service.UploadFile{
+		Filename:    meta["filename"],
+		ContentType: meta["filetype"],
+		Size:        length,
+	}, service.UploadMetadata{
+		CollectionID: collectionID,
+		UploaderID:   userID.(uuid.UUID),
+		Title:        title,
+		Description:  meta["description"],
+		Metadata:     docMetadata,
+		Availability: availability,
+	})
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	c.Header("Location", strings.TrimSuffix(c.Request.URL.Path, "/")+"/"+session.ID.String())
+	c.Header("Upload-Expires", session.ExpiresAt.UTC().Format(http.TimeFormat))
+	c.Status(http.StatusCreated)
+}
+
+// GetUploadOffset reports how many bytes of an upload have been received
+// @Summary      Get resumable upload offset
+// @Description  tus 1.0 HEAD request; returns Upload-Offset and Upload-Length headers
+// @Tags         uploads
+// @Security     BearerAuth
+// @Param        id   path      string  true  "Upload ID"
+// @Success      200  "Upload-Offset header set"
+// @Failure      404  "Upload not found or expired"
+// @Router       /uploads/{id} [head]
+func (h *UploadHandler) GetUploadOffset(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.Status(http.StatusNotFound)
+		return
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.Status(http.StatusUnauthorized)
+		return
+	}
+
+	session, err := h.uploadService.GetUpload(id, userID.(uuid.UUID))
+	if err != nil {
+		// HEAD responses carry no body
+		c.Status(errorStatus(err))
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	setUploadHeaders(c, session)
+	c.Header("Upload-Length", strconv.FormatInt(session.Length, 10))
+	c.Status(http.StatusOK)
+}
+
+// WriteChunk appends bytes to an upload
+// @Summary      Upload a chunk
+// @Description  tus 1.0 PATCH request with Content-Type application/offset+octet-stream. The final chunk creates the document.
+// @Tags         uploads
+// @Security     BearerAuth
+// @Accept       application/offset+octet-stream
+// @Param        id             path      string  true  "Upload ID"
+// @Param        Upload-Offset  header    int     true  "Offset the chunk starts at"
+// @Success      204  "Chunk stored; Upload-Offset header holds the new offset"
+// @Failure      409  {object}  response.Response "Offset mismatch"
+// @Failure      415  {object}  response.Response "Wrong content type"
+// @Router       /uploads/{id} [patch]
+func (h *UploadHandler) WriteChunk(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.NotFound(c, "Upload not found")
+		return
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	if c.ContentType() != "application/offset+octet-stream" {
+		response.Error(c, http.StatusUnsupportedMediaType, "UNSUPPORTED_MEDIA_TYPE", "Content-Type must be application/offset+octet-stream")
+		return
+	}
+
+	offset, err := strconv.ParseInt(c.GetHeader("Upload-Offset"), 10, 64)
+	if err != nil || offset < 0 {
+		response.BadRequest(c, "Invalid Upload-Offset header")
+		return
+	}
+
+	session, err := h.uploadService.WriteChunk(id, userID.(uuid.UUID), offset, c.Request.Body)
+	if session != nil {
+		setUploadHeaders(c, session)
+	}
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// TerminateUpload discards an unfinished upload
+// @Summary      Terminate resumable upload
+// @Description  tus 1.0 termination extension
+// @Tags         uploads
+// @Security     BearerAuth
+// @Param        id   path      string  true  "Upload ID"
+// @Success      204  "Upload terminated"
+// @Failure      404  {object}  response.Response "Upload not found"
+// @Router       /uploads/{id} [delete]
+func (h *UploadHandler) TerminateUpload(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.NotFound(c, "Upload not found")
+		return
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	if err := h.uploadService.TerminateUpload(id, userID.(uuid.UUID)); err != nil {
+		handleError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// RegisterRoutes registers resumable upload routes
+func (h *UploadHandler) RegisterRoutes(router *gin.RouterGroup, requiredAuth gin.HandlerFunc) {
+	uploads := router.Group("/uploads", h.tusHeaders())
+	{
+		uploads.POST("", requiredAuth, h.CreateUpload)
+		uploads.HEAD("/:id", requiredAuth, h.GetUploadOffset)
+		uploads.PATCH("/:id", requiredAuth, h.WriteChunk)
+		uploads.DELETE("/:id", requiredAuth, h.TerminateUpload)
+	}
+}
+
+// tusHeaders advertises the protocol on every response and rejects clients speaking
+// an unsupported version
+func (h *UploadHandler) tusHeaders() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("Tus-Resumable", tusVersion)
+		c.Header("Tus-Version", tusVersion)
+		c.Header("Tus-Extension", "creation,expiration,termination")
+		c.Header("Tus-Max-Size", strconv.FormatInt(h.uploadService.MaxUploadSize(), 10))
+
+		if c.GetHeader("Tus-Resumable") != tusVersion {
+			c.AbortWithStatus(http.StatusPreconditionFailed)
+			return
+		}
+		c.Next()
+	}
+}
+
+// setUploadHeaders sets the offset/expiry headers shared by HEAD and PATCH responses
+func setUploadHeaders(c *gin.Context, session *models.UploadSession) {
+	c.Header("Upload-Offset", strconv.FormatInt(session.Offset, 10))
+	if session.Status == models.UploadStatusInProgress {
+		c.Header("Upload-Expires", session.ExpiresAt.UTC().Format(http.TimeFormat))
+	}
+	if session.DocumentID != nil {
+		c.Header("Upload-Document-Id", session.DocumentID.String())
+	}
+}
+
+// parseUploadMetadata decodes a tus Upload-Metadata header ("key base64value,key2 ...")
+func parseUploadMetadata(header string) (map[string]string, error) {
+	meta := make(map[string]string)
+	if strings.TrimSpace(header) == "" {
+		return meta, nil
+	}
+
+	for _, pair := range strings.Split(header, ",") {
+		parts := strings.SplitN(strings.TrimSpace(pair), " ", 2)
+		if parts[0] == "" {
+			continue
+		}
+		if len(parts) == 1 {
+			meta[parts[0]] = ""
+			continue
+		}
+		value, err := base64.StdEncoding.DecodeString(parts[1])
+		if err != nil {
+			return nil, err
+		}
+		meta[parts[0]] = string(value)
+	}
+	return meta, nil
+}
@@ -0,0 +1,166 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/Kyei-Ernest/libsystem/services/document-service/service"
+	"github.com/Kyei-Ernest/libsystem/shared/models"
+	"github.com/Kyei-Ernest/libsystem/shared/response"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// UploadIntentHandler handles direct-to-storage (presigned) upload requests
+type UploadIntentHandler struct {
+	intentService service.UploadIntentService
+}
+
+// NewUploadIntentHandler creates a new direct upload handler
+func NewUploadIntentHandler(intentService service.UploadIntentService) *UploadIntentHandler {
+	return &UploadIntentHandler{intentService: intentService}
+}
+
+// CreateUploadIntent issues presigned URLs for uploading a file straight to storage
+// @Summary      Create direct upload
+// @Description  Returns a presigned PUT URL and POST policy for a staging key. Upload the file with either, then call finalize.
+// @Tags         uploads
+// @Security     BearerAuth
+// @Accept       json
+// @Produce      json
+// @Param        request  body      object  true  "filename, content_type, size, title, description, collection_id, metadata, available_from, available_until, embargo_show_metadata"
+// @Success      201      {object}  response.Response "Upload intent created"
+// @Failure      400      {object}  response.Response "Invalid input"
+// @Failure      401      {object}  response.Response "Unauthorized"
+// @Failure      403      {object}  response.Response "Forbidden"
+// @Router       /upload-intents [post]
+func (h *UploadIntentHandler) CreateUploadIntent(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	// Patrons have read-only access
+	if userRole(c) == string(models.RolePatron) {
+		response.Error(c, http.StatusForbidden, "FORBIDDEN", "Patrons do not have permission to upload documents. Please contact your librarian or administrator.")
+		return
+	}
+
+	var req struct {
+		Filename     string                   `json:"filename" binding:"required"`
+		ContentType  string                   `json:"content_type" binding:"required"`
+		Size         int64                    `json:"size" binding:"required"`
+		Title        string                   `json:"title"`
+		Description  string                   `json:"description"`
+		CollectionID uuid.UUID                `json:"collection_id"`
+		Metadata     *models.DocumentMetadata `json:"metadata"`
+		service.Availability
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		response.BadRequest(c, "Invalid request body: "+err.Error())
+		return
+	}
+
+	title := req.Title
+	if title == "" {
+		title = req.Filename
+	}
+
+	intent, upload, err := h.intentService.CreateIntent(service.UploadFile{
+		Filename:    req.Filename,
+		ContentType: req.ContentType,
+		Size:        req.Size,
+	}, service.UploadMetadata{
+		CollectionID: req.CollectionID,
+		UploaderID:   userID.(uuid.UUID),
+		Title:        title,
+		Description:  req.Description,
+		Metadata:     req.Metadata,
+		Availability: req.Availability,
+	})
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	response.Created(c, gin.H{
+		"intent": intent,
+		"upload": upload,
+	}, "Upload intent created")
+}
+
+// GetUploadIntent retrieves the state of a direct upload
+// @Summary      Get direct upload
+// @Description  Get the status of an upload intent
+// @Tags         uploads
+// @Security     BearerAuth
+// @Produce      json
+// @Param        id   path      string  true  "Upload intent ID"
+// @Success      200  {object}  response.Response "Upload intent"
+// @Failure      404  {object}  response.Response "Not found"
+// @Router       /upload-intents/{id} [get]
+func (h *UploadIntentHandler) GetUploadIntent(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.BadRequest(c, "Invalid upload intent ID")
+		return
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	intent, err := h.intentService.GetIntent(id, userID.(uuid.UUID))
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	response.Success(c, intent, "")
+}
+
+// FinalizeUploadIntent verifies a directly uploaded file and creates its document
+// @Summary      Finalize direct upload
+// @Description  Verifies, scans, hashes and deduplicates the uploaded object, then creates the document. Safe to retry.
+// @Tags         uploads
+// @Security     BearerAuth
+// @Produce      json
+// @Param        id   path      string  true  "Upload intent ID"
+// @Success      201  {object}  response.Response{data=models.Document} "Document created"
+// @Failure      400  {object}  response.Response "File not uploaded or rejected"
+// @Failure      409  {object}  response.Response "Duplicate or previously rejected"
+// @Router       /upload-intents/{id}/finalize [post]
+func (h *UploadIntentHandler) FinalizeUploadIntent(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.BadRequest(c, "Invalid upload intent ID")
+		return
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	document, err := h.intentService.FinalizeIntent(id, userID.(uuid.UUID))
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	response.Created(c, document, uploadedMessage(document))
+}
+
+// RegisterRoutes registers direct upload routes
+func (h *UploadIntentHandler) RegisterRoutes(router *gin.RouterGroup, requiredAuth gin.HandlerFunc) {
+	intents := router.Group("/upload-intents")
+	{
+		intents.POST("", requiredAuth, h.CreateUploadIntent)
+		intents.GET("/:id", requiredAuth, h.GetUploadIntent)
+		intents.POST("/:id/finalize", requiredAuth, h.FinalizeUploadIntent)
+	}
+}
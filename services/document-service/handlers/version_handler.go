@@ -1,39 +1,60 @@
 package handlers
 
 import (
+	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
 
+	"github.com/Kyei-Ernest/libsystem/services/document-service/middleware"
 	"github.com/Kyei-Ernest/libsystem/services/document-service/service"
+	"github.com/Kyei-Ernest/libsystem/shared/models"
+	"github.com/Kyei-Ernest/libsystem/shared/response"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 )
 
+// VersionHandler handles document version requests
 type VersionHandler struct {
-	versionService service.VersionService
+	versionService   service.VersionService
+	watermarkService service.WatermarkService
 }
 
 // NewVersionHandler creates a new version handler
-func NewVersionHandler(versionService service.VersionService) *VersionHandler {
-	return &VersionHandler{versionService: versionService}
+func NewVersionHandler(versionService service.VersionService, watermarkService service.WatermarkService) *VersionHandler {
+	return &VersionHandler{versionService: versionService, watermarkService: watermarkService}
 }
 
 // CreateVersion godoc
 // @Summary Create a new document version
-// @Description Creates a new version snapshot of a document
+// @Description Upload a revised file as a new version (multipart/form-data with "file"), or record the current file as a version (JSON). The new version becomes current and is reindexed.
 // @Tags versions
-// @Accept json
+// @Security BearerAuth
+// @Accept multipart/form-data,json
 // @Produce json
 // @Param id path string true "Document ID"
-// @Param body body object true "Version creation request"
-// @Success 201 {object} models.DocumentVersion
-// @Failure 400 {object} map[string]interface{}
-// @Failure 500 {object} map[string]interface{}
+// @Param file formData file false "Revised file"
+// @Param change_summary formData string false "What changed"
+// @Success 201 {object} response.Response{data=models.DocumentVersion}
+// @Failure 400 {object} response.Response
+// @Failure 409 {object} response.Response "Same content already exists"
 // @Router /documents/{id}/versions [post]
 func (h *VersionHandler) CreateVersion(c *gin.Context) {
-	documentIDStr := c.Param("id")
-	documentID, err := uuid.Parse(documentIDStr)
+	documentID, err := uuid.Parse(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid document ID"})
+		response.BadRequest(c, "Invalid document ID")
+		return
+	}
+
+	// Get user ID from context (set by auth middleware)
+	userID, exists := c.Get("user_id")
+	if !exists {
+		response.Unauthorized(c, "User not authenticated")
+		return
+	}
+
+	if strings.HasPrefix(c.ContentType(), "multipart/") {
+		h.uploadVersion(c, documentID, userID.(uuid.UUID))
 		return
 	}
 
@@ -42,51 +63,72 @@ func (h *VersionHandler) CreateVersion(c *gin.Context) {
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		response.BadRequest(c, "Invalid request body: "+err.Error())
 		return
 	}
 
-	// Get user ID from context (set by auth middleware)
-	userID, exists := c.Get("user_id")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+	version, err := h.versionService.CreateVersion(documentID, userID.(uuid.UUID), req.ChangeSummary)
+	if err != nil {
+		handleError(c, err)
 		return
 	}
 
-	version, err := h.versionService.CreateVersion(documentID, userID.(uuid.UUID), req.ChangeSummary)
+	response.Created(c, version, "Version created successfully")
+}
+
+// uploadVersion stores an uploaded file as the new current version
+func (h *VersionHandler) uploadVersion(c *gin.Context, documentID, userID uuid.UUID) {
+	// Same limits as document uploads; large files spool to disk
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, 100<<20) // 100 MB max
+	if err := c.Request.ParseMultipartForm(multipartMemoryLimit); err != nil {
+		response.BadRequest(c, "Failed to parse form: "+err.Error())
+		return
+	}
+
+	file, header, err := c.Request.FormFile("file")
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		response.BadRequest(c, "No file provided")
 		return
 	}
+	defer file.Close()
 
-	c.JSON(http.StatusCreated, version)
+	version, err := h.versionService.UploadVersion(documentID, userID, file, service.UploadFile{
+		Filename:    header.Filename,
+		ContentType: header.Header.Get("Content-Type"),
+		Size:        header.Size,
+	}, c.PostForm("change_summary"))
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	response.Created(c, version, "Version uploaded successfully")
 }
 
 // GetVersions godoc
 // @Summary Get all versions of a document
-// @Description Retrieves version history for a document
+// @Description Retrieves version history for a document, newest first
 // @Tags versions
 // @Produce json
 // @Param id path string true "Document ID"
-// @Success 200 {array} models.DocumentVersion
-// @Failure 400 {object} map[string]interface{}
-// @Failure 500 {object} map[string]interface{}
+// @Success 200 {object} response.Response{data=[]models.DocumentVersion}
+// @Failure 400 {object} response.Response
+// @Failure 404 {object} response.Response
 // @Router /documents/{id}/versions [get]
 func (h *VersionHandler) GetVersions(c *gin.Context) {
-	documentIDStr := c.Param("id")
-	documentID, err := uuid.Parse(documentIDStr)
+	documentID, err := uuid.Parse(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid document ID"})
+		response.BadRequest(c, "Invalid document ID")
 		return
 	}
 
-	versions, err := h.versionService.GetVersions(documentID)
+	versions, err := h.versionService.GetVersions(documentID, optionalUserID(c))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		handleError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, versions)
+	response.Success(c, versions, "")
 }
 
 // GetVersion godoc
@@ -96,85 +138,211 @@ func (h *VersionHandler) GetVersions(c *gin.Context) {
 // @Produce json
 // @Param id path string true "Document ID"
 // @Param versionId path string true "Version ID"
-// @Success 200 {object} models.DocumentVersion
-// @Failure 400 {object} map[string]interface{}
-// @Failure 404 {object} map[string]interface{}
-// @Failure 500 {object} map[string]interface{}
+// @Success 200 {object} response.Response{data=models.DocumentVersion}
+// @Failure 400 {object} response.Response
+// @Failure 404 {object} response.Response
 // @Router /documents/{id}/versions/{versionId} [get]
 func (h *VersionHandler) GetVersion(c *gin.Context) {
-	versionIDStr := c.Param("versionId")
-	versionID, err := uuid.Parse(versionIDStr)
+	documentID, versionID, ok := parseVersionParams(c)
+	if !ok {
+		return
+	}
+
+	version, err := h.versionService.GetVersion(documentID, versionID, optionalUserID(c))
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	response.Success(c, version, "")
+}
+
+// DownloadVersion godoc
+// @Summary Download a specific version
+// @Description Download the file of a document version. Supports Range and conditional requests, except for copies watermarked for the reader, which are sent whole.
+// @Tags versions
+// @Param id path string true "Document ID"
+// @Param versionId path string true "Version ID"
+// @Success 200 {file} binary
+// @Success 206 {file} binary "Partial content"
+// @Success 304 "Not modified"
+// @Failure 404 {object} response.Response
+// @Router /documents/{id}/versions/{versionId}/download [get]
+func (h *VersionHandler) DownloadVersion(c *gin.Context) {
+	documentID, versionID, ok := parseVersionParams(c)
+	if !ok {
+		return
+	}
+
+	userID := optionalUserID(c)
+	stream, document, err := h.versionService.GetVersionStream(documentID, versionID, userID)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid version ID"})
+		handleError(c, err)
 		return
 	}
 
-	version, err := h.versionService.GetVersion(versionID)
+	// Versions of watermarked collections are stamped like the current file
+	stream, document, stamped, err := watermark(c, h.watermarkService, stream, document, userID)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Version not found"})
+		handleError(c, err)
 		return
 	}
+	defer stream.Close()
 
-	c.JSON(http.StatusOK, version)
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", document.OriginalFilename))
+	if stamped {
+		serveStamped(c, stream, document)
+		return
+	}
+	c.Header("Cache-Control", "private, no-cache")
+	serveContent(c, stream, document.MimeType, contentETag(document.Hash, ""), document.UpdatedAt)
 }
 
 // RestoreVersion godoc
 // @Summary Restore a document to a previous version
-// @Description Restores a document to a specific version
+// @Description Adds a copy of the version as the newest version and makes it current
 // @Tags versions
-// @Accept json
+// @Security BearerAuth
 // @Produce json
 // @Param id path string true "Document ID"
 // @Param versionId path string true "Version ID"
-// @Success 200 {object} map[string]interface{}
-// @Failure 400 {object} map[string]interface{}
-// @Failure 500 {object} map[string]interface{}
+// @Success 200 {object} response.Response{data=models.DocumentVersion}
+// @Failure 400 {object} response.Response
+// @Failure 409 {object} response.Response "Version is already current"
 // @Router /documents/{id}/versions/{versionId}/restore [post]
 func (h *VersionHandler) RestoreVersion(c *gin.Context) {
-	versionIDStr := c.Param("versionId")
-	versionID, err := uuid.Parse(versionIDStr)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid version ID"})
+	documentID, versionID, ok := parseVersionParams(c)
+	if !ok {
 		return
 	}
 
 	// Get user ID from context
 	userID, exists := c.Get("user_id")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		response.Unauthorized(c, "User not authenticated")
 		return
 	}
 
-	if err := h.versionService.RestoreVersion(versionID, userID.(uuid.UUID)); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	version, err := h.versionService.RestoreVersion(documentID, versionID, userID.(uuid.UUID))
+	if err != nil {
+		handleError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Document restored successfully"})
+	response.Success(c, version, "Document restored successfully")
 }
 
 // DeleteVersion godoc
 // @Summary Delete a document version
-// @Description Deletes a specific version from history
+// @Description Permanently deletes a version that is not current, along with its file
 // @Tags versions
+// @Security BearerAuth
 // @Param id path string true "Document ID"
 // @Param versionId path string true "Version ID"
-// @Success 200 {object} map[string]interface{}
-// @Failure 400 {object} map[string]interface{}
-// @Failure 500 {object} map[string]interface{}
+// @Success 200 {object} response.Response
+// @Failure 400 {object} response.Response
+// @Failure 409 {object} response.Response "Version is current"
 // @Router /documents/{id}/versions/{versionId} [delete]
 func (h *VersionHandler) DeleteVersion(c *gin.Context) {
-	versionIDStr := c.Param("versionId")
-	versionID, err := uuid.Parse(versionIDStr)
+	documentID, versionID, ok := parseVersionParams(c)
+	if !ok {
+		return
+	}
+
+	if err := h.versionService.DeleteVersion(documentID, versionID); err != nil {
+		handleError(c, err)
+		return
+	}
+
+	response.Success(c, nil, "Version deleted successfully")
+}
+
+// CompareVersions godoc
+// @Summary Compare two versions
+// @Description Line- and word-level diff of the versions' extracted text plus a metadata diff. format=unified returns only the unified text diff.
+// @Tags versions
+// @Produce json,text/x-diff
+// @Param id path string true "Document ID"
+// @Param from query string true "Base version ID"
+// @Param to query string true "Target version ID"
+// @Param format query string false "json (default) or unified"
+// @Param context query int false "Unchanged lines around each hunk (default 3)"
+// @Success 200 {object} response.Response{data=service.VersionComparison}
+// @Failure 400 {object} response.Response
+// @Failure 404 {object} response.Response
+// @Router /documents/{id}/versions/compare [get]
+func (h *VersionHandler) CompareVersions(c *gin.Context) {
+	documentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.BadRequest(c, "Invalid document ID")
+		return
+	}
+	fromID, err := uuid.Parse(c.Query("from"))
+	if err != nil {
+		response.BadRequest(c, "Invalid from version ID")
+		return
+	}
+	toID, err := uuid.Parse(c.Query("to"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid version ID"})
+		response.BadRequest(c, "Invalid to version ID")
 		return
 	}
 
-	if err := h.versionService.DeleteVersion(versionID); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	context, err := strconv.Atoi(c.DefaultQuery("context", "3"))
+	if err != nil || context < 0 {
+		response.BadRequest(c, "Invalid context")
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Version deleted successfully"})
+	comparison, err := h.versionService.CompareVersions(documentID, fromID, toID, context, optionalUserID(c))
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+
+	if c.Query("format") == "unified" {
+		c.Data(http.StatusOK, "text/x-diff; charset=utf-8", []byte(comparison.Text.Unified))
+		return
+	}
+
+	response.Success(c, comparison, "")
+}
+
+// RegisterRoutes registers version routes
+func (h *VersionHandler) RegisterRoutes(router *gin.RouterGroup, optionalAuth, requiredAuth gin.HandlerFunc, permChecker *middleware.PermissionChecker) {
+	versions := router.Group("/documents/:id/versions")
+	{
+		versions.GET("", optionalAuth, h.GetVersions)
+		versions.GET("/compare", optionalAuth, h.CompareVersions)
+		versions.POST("", requiredAuth, permChecker.RequireDocumentPermission(models.PermissionEdit), h.CreateVersion)
+		versions.GET("/:versionId", optionalAuth, h.GetVersion)
+		versions.GET("/:versionId/download", optionalAuth, h.DownloadVersion)
+		versions.POST("/:versionId/restore", requiredAuth, permChecker.RequireDocumentPermission(models.PermissionEdit), h.RestoreVersion)
+		versions.DELETE("/:versionId", requiredAuth, permChecker.RequireDocumentPermission(models.PermissionDelete), h.DeleteVersion)
+	}
+}
+
+// parseVersionParams parses the document and version IDs from the path, responding with
+// 400 if either is invalid
+func parseVersionParams(c *gin.Context) (uuid.UUID, uuid.UUID, bool) {
+	documentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.BadRequest(c, "Invalid document ID")
+		return uuid.Nil, uuid.Nil, false
+	}
+	versionID, err := uuid.Parse(c.Param("versionId"))
+	if err != nil {
+		response.BadRequest(c, "Invalid version ID")
+		return uuid.Nil, uuid.Nil, false
+	}
+	return documentID, versionID, true
+}
+
+// optionalUserID returns the authenticated user's ID, or nil for anonymous requests
+func optionalUserID(c *gin.Context) *uuid.UUID {
+	if uid, exists := c.Get("user_id"); exists {
+		id := uid.(uuid.UUID)
+		return &id
+	}
+	return nil
 }
@@ -1,11 +1,16 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"strconv"
 	"strings"
 	"syscall"
 	"time"
@@ -17,7 +22,7 @@ import (
 	"github.com/Kyei-Ernest/libsystem/shared/database"
 	"github.com/Kyei-Ernest/libsystem/shared/jobs"
 	"github.com/Kyei-Ernest/libsystem/shared/kafka"
-	"github.com/Kyei-Ernest/libsystem/shared/models"
+	sharedRedis "github.com/Kyei-Ernest/libsystem/shared/redis"
 	"github.com/Kyei-Ernest/libsystem/shared/security"
 	"github.com/Kyei-Ernest/libsystem/shared/storage"
 	"github.com/gin-gonic/gin"
@@ -93,6 +98,7 @@ func main() {
 		UseSSL:          minioUseSSL,
 		BucketName:      minioBucket,
 		Region:          "us-east-1",
+		PublicEndpoint:  getEnv("MINIO_PUBLIC_ENDPOINT", minioEndpoint), // host clients upload to directly
 	}
 
 	var storageClient *storage.MinIOClient
@@ -130,17 +136,265 @@ func main() {
 	documentRepo := repository.NewDocumentRepository(dbConn.DB)
 	collectionRepo := repository.NewCollectionRepository(dbConn.DB)
 	permissionRepo := repository.NewPermissionRepository(dbConn.DB)
-	fileService := service.NewFileService()
-	documentService := service.NewDocumentService(documentRepo, collectionRepo, fileService, storageClient, producer, virusScanner)
+	uploadRepo := repository.NewUploadRepository(dbConn.DB)
+	uploadIntentRepo := repository.NewUploadIntentRepository(dbConn.DB)
+	versionRepo := repository.NewVersionRepository(dbConn.DB)
+	blobRepo := repository.NewBlobRepository(dbConn.DB)
+	relationRepo := repository.NewRelationRepository(dbConn.DB)
+	annotationRepo := repository.NewAnnotationRepository(dbConn.DB)
+	shelfRepo := repository.NewShelfRepository(dbConn.DB)
+	readingRepo := repository.NewReadingRepository(dbConn.DB)
+	lendingRepo := repository.NewLendingRepository(dbConn.DB)
+	maxFileSize, _ := strconv.ParseInt(getEnv("MAX_FILE_SIZE", "104857600"), 10, 64) // 100MB
+	fileService := service.NewFileService(maxFileSize)
+
+	// Office documents are previewed as PDF renditions, converted by a bounded pool of
+	// LibreOffice processes and cached in storage
+	previewWorkers, err := strconv.Atoi(getEnv("PREVIEW_WORKERS", "2"))
+	if err != nil || previewWorkers < 1 {
+		log.Fatalf("Invalid PREVIEW_WORKERS: %s", getEnv("PREVIEW_WORKERS", "2"))
+	}
+	previewTimeout, err := time.ParseDuration(getEnv("PREVIEW_TIMEOUT", "2m"))
+	if err != nil {
+		log.Fatalf("Invalid PREVIEW_TIMEOUT: %v", err)
+	}
+	previewService := service.NewPreviewService(storageClient, previewWorkers, previewTimeout)
+
+	// PDFs from watermarked collections are stamped for each delivery by a bounded pool
+	// of Ghostscript processes
+	watermarkWorkers, err := strconv.Atoi(getEnv("WATERMARK_WORKERS", "2"))
+	if err != nil || watermarkWorkers < 1 {
+		log.Fatalf("Invalid WATERMARK_WORKERS: %s", getEnv("WATERMARK_WORKERS", "2"))
+	}
+	watermarkTimeout, err := time.ParseDuration(getEnv("WATERMARK_TIMEOUT", "1m"))
+	if err != nil {
+		log.Fatalf("Invalid WATERMARK_TIMEOUT: %v", err)
+	}
+	watermarkService := service.NewWatermarkService(watermarkWorkers, watermarkTimeout)
+
+	documentService := service.NewDocumentService(documentRepo, collectionRepo, versionRepo, permissionRepo, blobRepo, relationRepo, lendingRepo, fileService, storageClient, producer, virusScanner, previewService)
 	permissionService := service.NewPermissionService(permissionRepo, documentRepo, collectionRepo)
+	versionService := service.NewVersionService(versionRepo, documentRepo, documentService, previewService, storageClient)
+	relationService := service.NewRelationService(relationRepo, documentService, permissionService)
+	annotationService := service.NewAnnotationService(annotationRepo, versionRepo, documentService)
+	shelfService := service.NewShelfService(shelfRepo, documentService)
+	readingService := service.NewReadingService(readingRepo, documentService)
+	lendingService := service.NewLendingService(lendingRepo, documentService, producer)
+
+	// Resumable uploads are staged on local disk until complete
+	uploadStagingDir := getEnv("UPLOAD_STAGING_DIR", filepath.Join(os.TempDir(), "libsystem-uploads"))
+	uploadExpiry, err := time.ParseDuration(getEnv("UPLOAD_EXPIRY", "24h"))
+	if err != nil {
+		log.Fatalf("Invalid UPLOAD_EXPIRY: %v", err)
+	}
+	uploadService := service.NewUploadService(uploadRepo, documentService, fileService, uploadStagingDir, uploadExpiry)
 
-	// Initialize job tracker
-	jobTracker := jobs.NewJobTracker()
+	// Direct uploads go straight to storage through presigned URLs
+	uploadURLExpiry, err := time.ParseDuration(getEnv("UPLOAD_URL_EXPIRY", "1h"))
+	if err != nil {
+		log.Fatalf("Invalid UPLOAD_URL_EXPIRY: %v", err)
+	}
+	uploadIntentService := service.NewUploadIntentService(uploadIntentRepo, documentService, fileService, storageClient, uploadURLExpiry, uploadExpiry)
+
+	// Deleted documents stay in the trash for the retention period
+	trashRetention, err := time.ParseDuration(getEnv("TRASH_RETENTION", "720h"))
+	if err != nil {
+		log.Fatalf("Invalid TRASH_RETENTION: %v", err)
+	}
+	moderationService := service.NewModerationService(documentRepo, documentService, storageClient, producer)
+	trashService := service.NewTrashService(documentRepo, collectionRepo, versionRepo, documentService, trashRetention)
+
+	// Documents follow their collection into and out of the trash
+	for topic, handle := range map[string]func([]byte) error{
+		"collection.deleted":  trashService.HandleCollectionDeleted,
+		"collection.restored": trashService.HandleCollectionRestored,
+	} {
+		consumer := kafka.NewConsumer(kafka.ConsumerConfig{
+			Brokers: kafkaBrokers,
+			Topic:   topic,
+			GroupID: "document-service-trash-group",
+		})
+		defer consumer.Close()
+		go consumeEvents(consumer, topic, handle)
+	}
+
+	// Views and downloads, also counted by the analytics service, keep recently read current
+	for _, topic := range []string{"document.viewed", "document.downloaded"} {
+		consumer := kafka.NewConsumer(kafka.ConsumerConfig{
+			Brokers: kafkaBrokers,
+			Topic:   topic,
+			GroupID: "document-service-reading-group",
+		})
+		defer consumer.Close()
+		go consumeEvents(consumer, topic, readingService.HandleRead)
+	}
+
+	// Thumbnails, page counts and embedded metadata are generated off the upload path
+	var ingestPreviews service.PreviewService
+	if getEnv("PREVIEW_ON_INGEST", "true") == "true" {
+		ingestPreviews = previewService
+	}
+	derivativeService := service.NewDerivativeService(documentRepo, collectionRepo, versionRepo, blobRepo, documentService, storageClient, ingestPreviews, producer)
+	derivativeWorkers, err := strconv.Atoi(getEnv("DERIVATIVE_WORKERS", "2"))
+	if err != nil || derivativeWorkers < 1 {
+		log.Fatalf("Invalid DERIVATIVE_WORKERS: %s", getEnv("DERIVATIVE_WORKERS", "2"))
+	}
+	for i := 0; i < derivativeWorkers; i++ {
+		consumer := kafka.NewConsumer(kafka.ConsumerConfig{
+			Brokers: kafkaBrokers,
+			Topic:   "document.uploaded",
+			GroupID: "document-service-derivatives-group",
+		})
+		defer consumer.Close()
+		go consumeEvents(consumer, "document.uploaded", derivativeService.HandleUploaded)
+	}
+
+	// Periodically purge abandoned resumable and direct uploads and expired trash, and
+	// retry documents whose processing stalled
+	go func() {
+		ticker := time.NewTicker(15 * time.Minute)
+		defer ticker.Stop()
+		for range ticker.C {
+			if purged, err := uploadService.PurgeExpired(); err != nil {
+				log.Printf("Failed to purge expired uploads: %v", err)
+			} else if purged > 0 {
+				log.Printf("Purged %d expired uploads", purged)
+			}
+			if purged, err := uploadIntentService.PurgeExpired(); err != nil {
+				log.Printf("Failed to purge expired upload intents: %v", err)
+			} else if purged > 0 {
+				log.Printf("Purged %d expired upload intents", purged)
+			}
+			if purged, err := trashService.PurgeExpired(); err != nil {
+				log.Printf("Failed to purge trash: %v", err)
+			} else if purged > 0 {
+				log.Printf("Purged %d documents from the trash", purged)
+			}
+			if processed, err := derivativeService.RetryUnprocessed(time.Now().Add(-15 * time.Minute)); err != nil {
+				log.Printf("Failed to retry document processing: %v", err)
+			} else if processed > 0 {
+				log.Printf("Processed %d stalled documents", processed)
+			}
+		}
+	}()
+
+	// Embargoes lift and documents expire at the boundaries of their availability windows
+	availabilityService := service.NewAvailabilityService(documentRepo, documentService)
+	availabilityInterval, err := time.ParseDuration(getEnv("AVAILABILITY_INTERVAL", "1m"))
+	if err != nil || availabilityInterval <= 0 {
+		log.Fatalf("Invalid AVAILABILITY_INTERVAL: %s", getEnv("AVAILABILITY_INTERVAL", "1m"))
+	}
+	go func() {
+		ticker := time.NewTicker(availabilityInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			if settled, err := availabilityService.ApplyDue(time.Now()); err != nil {
+				log.Printf("Failed to apply document availability: %v", err)
+			} else if settled > 0 {
+				log.Printf("Updated availability of %d documents", settled)
+			}
+		}
+	}()
+
+	// Digital loans end at their expiry and the licences go to the waitlist
+	lendingInterval, err := time.ParseDuration(getEnv("LENDING_INTERVAL", "1m"))
+	if err != nil || lendingInterval <= 0 {
+		log.Fatalf("Invalid LENDING_INTERVAL: %s", getEnv("LENDING_INTERVAL", "1m"))
+	}
+	go func() {
+		ticker := time.NewTicker(lendingInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			if settled, err := lendingService.ExpireDue(time.Now()); err != nil {
+				log.Printf("Failed to expire digital loans: %v", err)
+			} else if settled > 0 {
+				log.Printf("Returned expired loans of %d documents", settled)
+			}
+		}
+	}()
+
+	// Background jobs are kept in Postgres and run by whichever replica claims them; a
+	// replica renews the lease on the jobs it runs, and jobs whose lease lapses are taken
+	// over by another
+	jobWorkers, err := strconv.Atoi(getEnv("JOB_WORKERS", "2"))
+	if err != nil || jobWorkers < 1 {
+		log.Fatalf("Invalid JOB_WORKERS: %s", getEnv("JOB_WORKERS", "2"))
+	}
+	jobLease, err := time.ParseDuration(getEnv("JOB_LEASE", "1m"))
+	if err != nil || jobLease < 3*time.Second {
+		log.Fatalf("Invalid JOB_LEASE: %s", getEnv("JOB_LEASE", "1m"))
+	}
+	jobRetention, err := time.ParseDuration(getEnv("JOB_RETENTION", "168h"))
+	if err != nil || jobRetention <= 0 {
+		log.Fatalf("Invalid JOB_RETENTION: %s", getEnv("JOB_RETENTION", "168h"))
+	}
+	hostname, _ := os.Hostname()
+	workerID := fmt.Sprintf("%s-%d-%s", hostname, os.Getpid(), uuid.New().String()[:8])
+	jobTracker := jobs.NewJobTracker(dbConn.DB, workerID, jobLease)
+
+	// Initialize Redis client (optional - job progress streams reach watchers on other
+	// replicas through it; without it they only hear of jobs run on their own replica
+	// until they next check the database)
+	redisClient, err := sharedRedis.NewClient(&sharedRedis.Config{
+		Host:     getEnv("REDIS_HOST", "localhost"),
+		Port:     getEnv("REDIS_PORT", "6379"),
+		Password: getEnv("REDIS_PASSWORD", ""),
+		DB:       0,
+	})
+	if err != nil {
+		log.Printf("Warning: Failed to connect to Redis: %v (job updates stay on this replica)", err)
+	} else {
+		log.Println("Redis connected successfully")
+		defer redisClient.Close()
+	}
+	jobBroker := jobs.NewBroker(redisClient)
+	jobTracker.SetBroker(jobBroker)
+
+	// Collection exports are stored until their download link expires
+	exportLinkTTL, err := time.ParseDuration(getEnv("EXPORT_LINK_TTL", "24h"))
+	if err != nil || exportLinkTTL <= 0 || exportLinkTTL > 7*24*time.Hour {
+		log.Fatalf("Invalid EXPORT_LINK_TTL: %s", getEnv("EXPORT_LINK_TTL", "24h"))
+	}
+	exportService := service.NewExportService(documentRepo, collectionRepo, versionRepo, storageClient, jobTracker, exportLinkTTL)
+
+	// Bulk imports keep their archive in storage until the job is cleaned up, so failed
+	// rows can be retried; IMPORT_SOURCE_DIR enables importing files already on the server
+	importService := service.NewImportService(documentService, collectionRepo, storageClient, jobTracker, getEnv("IMPORT_SOURCE_DIR", ""))
+
+	go func() {
+		ticker := time.NewTicker(time.Hour)
+		defer ticker.Stop()
+		for range ticker.C {
+			if purged, err := exportService.PurgeExpired(); err != nil {
+				log.Printf("Failed to purge expired exports: %v", err)
+			} else if purged > 0 {
+				log.Printf("Purged %d expired exports", purged)
+			}
+			if removed, err := jobTracker.CleanupOldJobs(jobRetention); err != nil {
+				log.Printf("Failed to clean up old jobs: %v", err)
+			} else if removed > 0 {
+				log.Printf("Removed %d finished jobs", removed)
+			}
+		}
+	}()
 
 	// Initialize handlers
-	documentHandler := handlers.NewDocumentHandler(documentService)
+	documentHandler := handlers.NewDocumentHandler(documentService, relationService, watermarkService)
 	permissionHandler := handlers.NewPermissionHandler(permissionService)
 	batchHandler := handlers.NewBatchHandler(documentService, jobTracker)
+	uploadHandler := handlers.NewUploadHandler(uploadService)
+	uploadIntentHandler := handlers.NewUploadIntentHandler(uploadIntentService)
+	versionHandler := handlers.NewVersionHandler(versionService, watermarkService)
+	relationHandler := handlers.NewRelationHandler(relationService)
+	annotationHandler := handlers.NewAnnotationHandler(annotationService)
+	shelfHandler := handlers.NewShelfHandler(shelfService)
+	readingHandler := handlers.NewReadingHandler(readingService)
+	lendingHandler := handlers.NewLendingHandler(lendingService)
+	trashHandler := handlers.NewTrashHandler(trashService)
+	moderationHandler := handlers.NewModerationHandler(moderationService)
+	exportHandler := handlers.NewExportHandler(exportService)
+	importHandler := handlers.NewImportHandler(importService)
+	jobHandler := handlers.NewJobHandler(jobTracker, jobBroker)
 
 	// Initialize middleware
 	permissionChecker := middleware.NewPermissionChecker(permissionService)
@@ -173,6 +427,19 @@ func main() {
 		optionalAuth := optionalAuthMiddleware()
 		requiredAuth := requiredAuthMiddleware()
 		documentHandler.RegisterRoutes(v1, optionalAuth, requiredAuth, permissionHandler, permissionChecker)
+		uploadHandler.RegisterRoutes(v1, requiredAuth)
+		uploadIntentHandler.RegisterRoutes(v1, requiredAuth)
+		versionHandler.RegisterRoutes(v1, optionalAuth, requiredAuth, permissionChecker)
+		relationHandler.RegisterRoutes(v1, optionalAuth, requiredAuth, permissionChecker)
+		annotationHandler.RegisterRoutes(v1, optionalAuth, requiredAuth)
+		shelfHandler.RegisterRoutes(v1, optionalAuth, requiredAuth)
+		readingHandler.RegisterRoutes(v1, requiredAuth)
+		lendingHandler.RegisterRoutes(v1, optionalAuth, requiredAuth)
+		trashHandler.RegisterRoutes(v1, requiredAuth)
+		moderationHandler.RegisterRoutes(v1, requiredAuth)
+		exportHandler.RegisterRoutes(v1, requiredAuth)
+		importHandler.RegisterRoutes(v1, requiredAuth)
+		jobHandler.RegisterRoutes(v1, requiredAuth, queryTokenMiddleware())
 
 		// Batch operations routes
 		batch := v1.Group("/documents/batch")
@@ -181,13 +448,6 @@ func main() {
 			batch.PATCH("/metadata", requiredAuth, batchHandler.BulkUpdateMetadata)
 			batch.DELETE("/delete", requiredAuth, batchHandler.BulkDelete)
 		}
-
-		// Job tracking routes
-		jobs := v1.Group("/jobs")
-		{
-			jobs.GET("", requiredAuth, batchHandler.ListJobs)
-			jobs.GET("/:jobID", requiredAuth, batchHandler.GetJobStatus)
-		}
 	}
 
 	// Swagger configuration
@@ -202,6 +462,15 @@ func main() {
 		IdleTimeout:  60 * time.Second,
 	}
 
+	// Run background jobs until shutdown
+	jobCtx, stopJobs := context.WithCancel(context.Background())
+	jobsStopped := make(chan struct{})
+	go func() {
+		jobTracker.Run(jobCtx, jobWorkers)
+		close(jobsStopped)
+	}()
+	go jobBroker.Run(jobCtx)
+
 	// Start server in a goroutine
 	go func() {
 		log.Printf("Document Service starting on port %s...\n", port)
@@ -221,6 +490,15 @@ func main() {
 		log.Printf("Server forced to shutdown: %v", err)
 	}
 
+	// Running jobs go back to the queue; those that don't stop in time are taken over
+	// once their lease lapses
+	stopJobs()
+	select {
+	case <-jobsStopped:
+	case <-time.After(30 * time.Second):
+		log.Println("Background jobs did not stop in time")
+	}
+
 	log.Println("Server exited")
 }
 
@@ -233,13 +511,34 @@ func getEnv(key, defaultValue string) string {
 	return value
 }
 
+// consumeEvents reads events from a topic until the consumer is closed
+func consumeEvents(consumer *kafka.Consumer, topic string, handle func([]byte) error) {
+	log.Printf("Listening for events on topic %s...", topic)
+	for {
+		msg, err := consumer.ReadMessage(context.Background())
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return
+			}
+			log.Printf("Error reading %s message: %v", topic, err)
+			time.Sleep(time.Second)
+			continue
+		}
+
+		if err := handle(msg.Value); err != nil {
+			log.Printf("Failed to process %s event: %v", topic, err)
+		}
+	}
+}
+
 // corsMiddleware adds CORS headers
 func corsMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
 		c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With")
-		c.Writer.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT, DELETE, PATCH")
+		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With, Tus-Resumable, Upload-Length, Upload-Metadata, Upload-Offset, Range, If-Range, If-None-Match, If-Modified-Since")
+		c.Writer.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS, GET, HEAD, PUT, DELETE, PATCH")
+		c.Writer.Header().Set("Access-Control-Expose-Headers", "Location, Tus-Resumable, Tus-Version, Tus-Extension, Tus-Max-Size, Upload-Offset, Upload-Length, Upload-Expires, Upload-Document-Id, Accept-Ranges, Content-Range, Content-Length, Content-Disposition, ETag, Last-Modified")
 
 		if c.Request.Method == "OPTIONS" {
 			c.AbortWithStatus(204)
@@ -250,13 +549,27 @@ func corsMiddleware() gin.HandlerFunc {
 	}
 }
 
-// optionalAuthMiddleware extracts user ID if token is present
+// optionalAuthMiddleware extracts user ID if token is present; invalid tokens are
+// treated as anonymous
 func optionalAuthMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		tokenString := c.GetHeader("Authorization")
 		if tokenString != "" && len(tokenString) > 7 && tokenString[:7] == "Bearer " {
-			// Token validation would go here
-			// c.Set("user_id", userID)
+			jwtSecret := getEnv("JWT_SECRET", "your-super-secret-jwt-key-change-in-production-min-32-chars")
+			if claims, err := validateToken(tokenString[7:], jwtSecret); err == nil {
+				setUser(c, claims)
+			}
+		}
+		c.Next()
+	}
+}
+
+// queryTokenMiddleware takes the token from the access_token query parameter when there is
+// no Authorization header, for EventSource and WebSocket clients that can't set one
+func queryTokenMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if token := c.Query("access_token"); token != "" && c.GetHeader("Authorization") == "" {
+			c.Request.Header.Set("Authorization", "Bearer "+token)
 		}
 		c.Next()
 	}
@@ -301,7 +614,7 @@ func requiredAuthMiddleware() gin.HandlerFunc {
 
 		// Validate JWT token and extract user ID
 		jwtSecret := getEnv("JWT_SECRET", "your-super-secret-jwt-key-change-in-production-min-32-chars")
-		userID, role, err := validateTokenAndGetUser(tokenString, jwtSecret)
+		claims, err := validateToken(tokenString, jwtSecret)
 		if err != nil {
 			c.JSON(http.StatusUnauthorized, gin.H{
 				"success": false,
@@ -315,14 +628,25 @@ func requiredAuthMiddleware() gin.HandlerFunc {
 		}
 
 		// Set actual user ID and Role from token
-		c.Set("user_id", userID)
-		c.Set("role", role)
+		setUser(c, claims)
 		c.Next()
 	}
 }
 
-// validateTokenAndGetUserID validates JWT and extracts user ID and Role
-func validateTokenAndGetUser(tokenString, jwtSecret string) (uuid.UUID, models.UserRole, error) {
+// setUser puts the token's user on the context
+func setUser(c *gin.Context, claims *security.TokenClaims) {
+	c.Set("user_id", claims.UserID)
+	c.Set("role", claims.Role)
+	// Named on watermarks
+	username := claims.Username
+	if username == "" {
+		username = claims.Email
+	}
+	c.Set("username", username)
+}
+
+// validateToken validates JWT and returns its claims
+func validateToken(tokenString, jwtSecret string) (*security.TokenClaims, error) {
 	token, err := jwt.ParseWithClaims(tokenString, &security.TokenClaims{}, func(token *jwt.Token) (interface{}, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, fmt.Errorf("unexpected signing method")
@@ -331,12 +655,12 @@ func validateTokenAndGetUser(tokenString, jwtSecret string) (uuid.UUID, models.U
 	})
 
 	if err != nil {
-		return uuid.Nil, "", err
+		return nil, err
 	}
 
 	if claims, ok := token.Claims.(*security.TokenClaims); ok && token.Valid {
-		return claims.UserID, claims.Role, nil
+		return claims, nil
 	}
 
-	return uuid.Nil, "", fmt.Errorf("invalid token claims")
+	return nil, fmt.Errorf("invalid token claims")
 }
@@ -0,0 +1,113 @@
+package repository
+
+import (
+	"errors"
+
+	"github.com/Kyei-Ernest/libsystem/shared/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// AnnotationRepository defines the interface for annotation data operations
+type AnnotationRepository interface {
+	Create(annotation *models.Annotation) error
+	FindByID(id uuid.UUID) (*models.Annotation, error)
+	Update(annotation *models.Annotation) error
+	Delete(id uuid.UUID) error
+	List(filters AnnotationFilters, offset, limit int) ([]models.Annotation, int64, error)
+}
+
+// AnnotationFilters represents filters for listing annotations
+type AnnotationFilters struct {
+	DocumentID *uuid.UUID
+	Hash       string     // Content the annotations were made on
+	CreatorID  *uuid.UUID // Only annotations by this user
+	ViewerID   *uuid.UUID // Limits to annotations the viewer may see; nil sees public ones only
+	Search     string     // Search in notes and quoted passages
+}
+
+type annotationRepository struct {
+	db *gorm.DB
+}
+
+// NewAnnotationRepository creates a new annotation repository
+func NewAnnotationRepository(db *gorm.DB) AnnotationRepository {
+	return &annotationRepository{db: db}
+}
+
+// Create creates a new annotation
+func (r *annotationRepository) Create(annotation *models.Annotation) error {
+	return r.db.Create(annotation).Error
+}
+
+// FindByID retrieves an annotation by ID
+func (r *annotationRepository) FindByID(id uuid.UUID) (*models.Annotation, error) {
+	var annotation models.Annotation
+	if err := r.db.Where("id = ?", id).First(&annotation).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("annotation not found")
+		}
+		return nil, err
+	}
+	return &annotation, nil
+}
+
+// Update saves an annotation's note and visibility; its anchors are fixed
+func (r *annotationRepository) Update(annotation *models.Annotation) error {
+	return r.db.Model(annotation).Select("body", "visibility").Updates(annotation).Error
+}
+
+// Delete removes an annotation
+func (r *annotationRepository) Delete(id uuid.UUID) error {
+	return r.db.Where("id = ?", id).Delete(&models.Annotation{}).Error
+}
+
+// List lists annotations with filters and pagination, in document order for a single
+// document and newest first otherwise
+func (r *annotationRepository) List(filters AnnotationFilters, offset, limit int) ([]models.Annotation, int64, error) {
+	var annotations []models.Annotation
+	var total int64
+
+	query := r.db.Model(&models.Annotation{})
+
+	if filters.DocumentID != nil {
+		query = query.Where("document_id = ?", *filters.DocumentID)
+	}
+	if filters.Hash != "" {
+		query = query.Where("hash = ?", filters.Hash)
+	}
+	if filters.CreatorID != nil {
+		query = query.Where("creator_id = ?", *filters.CreatorID)
+	}
+
+	// Collection annotations are visible to the collection owner and users it is shared with
+	if filters.ViewerID != nil {
+		query = query.Where(`creator_id = ? OR visibility = ? OR (visibility = ? AND document_id IN (
+			SELECT d.id FROM documents d JOIN collections c ON c.id = d.collection_id
+			WHERE c.owner_id = ? OR EXISTS (
+				SELECT 1 FROM collection_shares s WHERE s.collection_id = c.id AND s.shared_with_user_id = ?)))`,
+			*filters.ViewerID, models.AnnotationPublic, models.AnnotationCollection, *filters.ViewerID, *filters.ViewerID)
+	} else {
+		query = query.Where("visibility = ?", models.AnnotationPublic)
+	}
+
+	if filters.Search != "" {
+		searchPattern := "%" + filters.Search + "%"
+		query = query.Where("body ILIKE ? OR target->'text_quote'->>'exact' ILIKE ?", searchPattern, searchPattern)
+	}
+
+	// Get total count
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	order := "created_at DESC"
+	if filters.DocumentID != nil {
+		order = "(target->>'page')::int NULLS FIRST, (target->'text_position'->>'start')::int NULLS LAST, created_at"
+	}
+	if err := query.Offset(offset).Limit(limit).Order(order).Find(&annotations).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return annotations, total, nil
+}
@@ -0,0 +1,121 @@
+package repository
+
+import (
+	"errors"
+
+	"github.com/Kyei-Ernest/libsystem/shared/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// BlobRepository defines the interface for reference-counted content storage records
+type BlobRepository interface {
+	FindByHash(hash string) (*models.Blob, error)
+	Acquire(blob *models.Blob) (*models.Blob, bool, error)
+	Retain(hash string) error
+	Release(hash string) (*models.Blob, error)
+	SetThumbnail(hash, thumbnailPath string) (string, error)
+}
+
+type blobRepository struct {
+	db *gorm.DB
+}
+
+// NewBlobRepository creates a new blob repository
+func NewBlobRepository(db *gorm.DB) BlobRepository {
+	return &blobRepository{db: db}
+}
+
+// FindByHash retrieves the blob holding the given content
+func (r *blobRepository) FindByHash(hash string) (*models.Blob, error) {
+	var blob models.Blob
+	if err := r.db.Where("hash = ?", hash).First(&blob).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("blob not found")
+		}
+		return nil, err
+	}
+	return &blob, nil
+}
+
+// Acquire adds a reference to the blob with blob.Hash, creating it from blob when there is
+// none. It returns the stored blob and whether it was created. The blob is committed
+// before its creator puts the content at its storage path, so callers sharing an existing
+// blob must check that the object is there.
+func (r *blobRepository) Acquire(blob *models.Blob) (*models.Blob, bool, error) {
+	var stored models.Blob
+	err := r.db.Raw(`
+		INSERT INTO blobs (hash, storage_path, size, mime_type, ref_count, created_at, updated_at)
+		VALUES (?, ?, ?, ?, 1, NOW(), NOW())
+		ON CONFLICT (hash) DO UPDATE SET ref_count = blobs.ref_count + 1
+		RETURNING hash, storage_path, COALESCE(thumbnail_path, '') AS thumbnail_path, size, mime_type, ref_count, created_at, updated_at`,
+		blob.Hash, blob.StoragePath, blob.Size, blob.MimeType,
+	).Scan(&stored).Error
+	if err != nil {
+		return nil, false, err
+	}
+	// Storage paths are never reused, so the proposed one comes back only on insert
+	return &stored, stored.StoragePath == blob.StoragePath, nil
+}
+
+// Retain adds a reference to an existing blob
+func (r *blobRepository) Retain(hash string) error {
+	result := r.db.Model(&models.Blob{}).
+		Where("hash = ?", hash).
+		Update("ref_count", gorm.Expr("ref_count + 1"))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("blob not found")
+	}
+	return nil
+}
+
+// Release drops a reference to a blob. When it was the last one the blob record is
+// deleted and returned so the caller can delete its objects; otherwise it returns nil.
+func (r *blobRepository) Release(hash string) (*models.Blob, error) {
+	var released *models.Blob
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var blob models.Blob
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("hash = ?", hash).
+			First(&blob).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil // Nothing to release
+			}
+			return err
+		}
+
+		if blob.RefCount > 1 {
+			return tx.Model(&blob).Update("ref_count", gorm.Expr("ref_count - 1")).Error
+		}
+
+		if err := tx.Delete(&blob).Error; err != nil {
+			return err
+		}
+		released = &blob
+		return nil
+	})
+	return released, err
+}
+
+// SetThumbnail records the thumbnail generated for a blob unless one was recorded first.
+// It returns the thumbnail the blob ends up with.
+func (r *blobRepository) SetThumbnail(hash, thumbnailPath string) (string, error) {
+	result := r.db.Model(&models.Blob{}).
+		Where("hash = ? AND COALESCE(thumbnail_path, '') = ''", hash).
+		Update("thumbnail_path", thumbnailPath)
+	if result.Error != nil {
+		return "", result.Error
+	}
+	if result.RowsAffected > 0 {
+		return thumbnailPath, nil
+	}
+
+	blob, err := r.FindByHash(hash)
+	if err != nil {
+		return "", err
+	}
+	return blob.ThumbnailPath, nil
+}
@@ -12,6 +12,7 @@ import (
 type CollectionRepository interface {
 	Create(collection *models.Collection) error
 	FindByID(id uuid.UUID) (*models.Collection, error)
+	FindByIDWithTrashed(id uuid.UUID) (*models.Collection, error)
 	FindBySlug(slug string) (*models.Collection, error)
 	Update(collection *models.Collection) error
 	Delete(id uuid.UUID) error
@@ -56,6 +57,19 @@ func (r *collectionRepository) FindByID(id uuid.UUID) (*models.Collection, error
 	return &collection, nil
 }
 
+// FindByIDWithTrashed finds a collection by ID, including collections in the trash
+func (r *collectionRepository) FindByIDWithTrashed(id uuid.UUID) (*models.Collection, error) {
+	var collection models.Collection
+	err := r.db.Unscoped().Where("id = ?", id).First(&collection).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("collection not found")
+		}
+		return nil, err
+	}
+	return &collection, nil
+}
+
 // FindBySlug finds a collection by slug
 func (r *collectionRepository) FindBySlug(slug string) (*models.Collection, error) {
 	var collection models.Collection
@@ -2,24 +2,41 @@ package repository
 
 import (
 	"errors"
+	"time"
 
 	"github.com/Kyei-Ernest/libsystem/shared/models"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // DocumentRepository defines the interface for document data access
 type DocumentRepository interface {
 	Create(document *models.Document) error
 	FindByID(id uuid.UUID) (*models.Document, error)
-	FindByHash(hash string) (*models.Document, error)
+	FindByHashInCollection(hash string, collectionID uuid.UUID) (*models.Document, error)
 	Update(document *models.Document) error
-	Delete(id uuid.UUID) error
+	Trash(id, deletedBy uuid.UUID) error
+	TrashByCollection(collectionID uuid.UUID, deletedBy *uuid.UUID, deletedAt time.Time) ([]uuid.UUID, error)
+	FindTrashedByID(id uuid.UUID) (*models.Document, error)
+	ListTrash(filters TrashFilters, offset, limit int) ([]models.Document, int64, error)
+	Restore(id uuid.UUID) error
+	RestoreByCollection(collectionID uuid.UUID) ([]uuid.UUID, error)
+	ListPurgeable(before time.Time, limit int) ([]models.Document, error)
+	Purge(id uuid.UUID) error
 	List(filters DocumentFilters, offset, limit int) ([]models.Document, int64, error)
+	ListSubmissions(filters SubmissionFilters, offset, limit int) ([]models.Document, int64, error)
+	Review(id uuid.UUID, status models.DocumentStatus, reviewedBy uuid.UUID, note string) (bool, error)
 	UpdateStatus(id uuid.UUID, status models.DocumentStatus) error
+	TransitionStatus(id uuid.UUID, from []models.DocumentStatus, to models.DocumentStatus) (bool, error)
+	SetDerivatives(id uuid.UUID, hash string, derivatives Derivatives) error
+	EditDetails(id uuid.UUID, edit func(document *models.Document) error) error
+	ListUnprocessed(before time.Time, limit int) ([]models.Document, error)
+	ListAvailabilityDue(now time.Time, limit int) ([]models.Document, error)
 	IncrementViewCount(id uuid.UUID) error
 	IncrementDownloadCount(id uuid.UUID) error
 	SetIndexed(id uuid.UUID, indexed bool) error
+	SetExtractedText(id uuid.UUID, hash, text string) error
 }
 
 // DocumentFilters represents filters for listing documents
@@ -30,6 +47,29 @@ type DocumentFilters struct {
 	FileType     string
 	Search       string // Search in title and description
 	IsIndexed    *bool
+	ViewerID     *uuid.UUID // Also lists the viewer's own documents outside their window
+}
+
+// TrashFilters represents filters for listing trashed documents. OwnerID matches
+// documents the user uploaded or deleted.
+type TrashFilters struct {
+	CollectionID *uuid.UUID
+	OwnerID      *uuid.UUID
+}
+
+// Derivatives are the results of processing a document's content
+type Derivatives struct {
+	ThumbnailPath   string
+	PageCount       int
+	ProcessingError string
+}
+
+// SubmissionFilters represents filters for the review queue. VisibleTo limits results to
+// submissions the user made or that target collections they own.
+type SubmissionFilters struct {
+	Status       models.DocumentStatus
+	CollectionID *uuid.UUID
+	VisibleTo    *uuid.UUID
 }
 
 // documentRepository implements DocumentRepository using GORM
@@ -60,10 +100,12 @@ func (r *documentRepository) FindByID(id uuid.UUID) (*models.Document, error) {
 	return &document, nil
 }
 
-// FindByHash finds a document by hash
-func (r *documentRepository) FindByHash(hash string) (*models.Document, error) {
+// FindByHashInCollection finds a document with the given content in a collection,
+// including documents in the trash (hashes stay unique per collection until a document
+// is purged)
+func (r *documentRepository) FindByHashInCollection(hash string, collectionID uuid.UUID) (*models.Document, error) {
 	var document models.Document
-	err := r.db.Where("hash = ?", hash).First(&document).Error
+	err := r.db.Unscoped().Where("hash = ? AND collection_id = ?", hash, collectionID).First(&document).Error
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, nil // Not an error, just no duplicate
@@ -78,9 +120,128 @@ func (r *documentRepository) Update(document *models.Document) error {
 	return r.db.Save(document).Error
 }
 
-// Delete soft deletes a document
-func (r *documentRepository) Delete(id uuid.UUID) error {
-	return r.db.Delete(&models.Document{}, id).Error
+// Trash soft deletes a document, recording who deleted it
+func (r *documentRepository) Trash(id, deletedBy uuid.UUID) error {
+	return r.db.Model(&models.Document{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"deleted_at": time.Now(),
+			"deleted_by": deletedBy,
+		}).Error
+}
+
+// TrashByCollection soft deletes the documents of a collection, marking them to be
+// restored with it, and returns their IDs
+func (r *documentRepository) TrashByCollection(collectionID uuid.UUID, deletedBy *uuid.UUID, deletedAt time.Time) ([]uuid.UUID, error) {
+	var ids []uuid.UUID
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Document{}).Where("collection_id = ?", collectionID).Pluck("id", &ids).Error; err != nil {
+			return err
+		}
+		if len(ids) == 0 {
+			return nil
+		}
+		return tx.Model(&models.Document{}).
+			Where("id IN ?", ids).
+			Updates(map[string]interface{}{
+				"deleted_at":              deletedAt,
+				"deleted_by":              deletedBy,
+				"trashed_with_collection": true,
+			}).Error
+	})
+	return ids, err
+}
+
+// FindTrashedByID finds a document in the trash by ID
+func (r *documentRepository) FindTrashedByID(id uuid.UUID) (*models.Document, error) {
+	var document models.Document
+	err := r.db.Unscoped().Preload("Collection", func(db *gorm.DB) *gorm.DB {
+		return db.Unscoped()
+	}).Where("id = ? AND deleted_at IS NOT NULL", id).First(&document).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("document not found in trash")
+		}
+		return nil, err
+	}
+	return &document, nil
+}
+
+// ListTrash lists trashed documents, most recently deleted first
+func (r *documentRepository) ListTrash(filters TrashFilters, offset, limit int) ([]models.Document, int64, error) {
+	var documents []models.Document
+	var total int64
+
+	query := r.db.Unscoped().Model(&models.Document{}).Where("deleted_at IS NOT NULL")
+
+	if filters.CollectionID != nil {
+		query = query.Where("collection_id = ?", *filters.CollectionID)
+	}
+
+	if filters.OwnerID != nil {
+		query = query.Where("uploader_id = ? OR deleted_by = ?", *filters.OwnerID, *filters.OwnerID)
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	if err := query.Offset(offset).Limit(limit).Order("deleted_at DESC").Find(&documents).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return documents, total, nil
+}
+
+// Restore takes a document out of the trash
+func (r *documentRepository) Restore(id uuid.UUID) error {
+	return r.db.Unscoped().Model(&models.Document{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"deleted_at":              nil,
+			"deleted_by":              nil,
+			"trashed_with_collection": false,
+		}).Error
+}
+
+// RestoreByCollection takes the documents trashed along with a collection out of the
+// trash and returns their IDs. Documents deleted individually stay in the trash.
+func (r *documentRepository) RestoreByCollection(collectionID uuid.UUID) ([]uuid.UUID, error) {
+	var ids []uuid.UUID
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Model(&models.Document{}).
+			Where("collection_id = ? AND deleted_at IS NOT NULL AND trashed_with_collection", collectionID).
+			Pluck("id", &ids).Error; err != nil {
+			return err
+		}
+		if len(ids) == 0 {
+			return nil
+		}
+		return tx.Unscoped().Model(&models.Document{}).
+			Where("id IN ?", ids).
+			Updates(map[string]interface{}{
+				"deleted_at":              nil,
+				"deleted_by":              nil,
+				"trashed_with_collection": false,
+			}).Error
+	})
+	return ids, err
+}
+
+// ListPurgeable lists documents that have been in the trash since before the given time
+func (r *documentRepository) ListPurgeable(before time.Time, limit int) ([]models.Document, error) {
+	var documents []models.Document
+	err := r.db.Unscoped().
+		Where("deleted_at IS NOT NULL AND deleted_at < ?", before).
+		Order("deleted_at").
+		Limit(limit).
+		Find(&documents).Error
+	return documents, err
+}
+
+// Purge permanently deletes a trashed document; its versions are removed by cascade
+func (r *documentRepository) Purge(id uuid.UUID) error {
+	return r.db.Unscoped().Where("id = ? AND deleted_at IS NOT NULL", id).Delete(&models.Document{}).Error
 }
 
 // List lists documents with filters and pagination
@@ -90,6 +251,20 @@ func (r *documentRepository) List(filters DocumentFilters, offset, limit int) ([
 
 	query := r.db.Model(&models.Document{}).Preload("Collection").Preload("Uploader")
 
+	// Submissions are only listed through the review queue
+	query = query.Where("status NOT IN ?", []models.DocumentStatus{models.StatusSubmitted, models.StatusRejected})
+
+	// Documents are listed within their availability window, or during an embargo that
+	// shows metadata; their uploader and collection owner see them throughout
+	now := time.Now()
+	available := r.db.Where("(available_until IS NULL OR available_until > ?) AND (available_from IS NULL OR available_from <= ? OR embargo_show_metadata)", now, now)
+	if filters.ViewerID != nil {
+		available = available.
+			Or("uploader_id = ?", *filters.ViewerID).
+			Or("collection_id IN (?)", r.db.Model(&models.Collection{}).Select("id").Where("owner_id = ?", *filters.ViewerID))
+	}
+	query = query.Where(available)
+
 	// Apply filters
 	if filters.CollectionID != nil {
 		query = query.Where("collection_id = ?", *filters.CollectionID)
@@ -129,11 +304,129 @@ func (r *documentRepository) List(filters DocumentFilters, offset, limit int) ([
 	return documents, total, nil
 }
 
+// ListSubmissions lists documents in the review queue, oldest first
+func (r *documentRepository) ListSubmissions(filters SubmissionFilters, offset, limit int) ([]models.Document, int64, error) {
+	var documents []models.Document
+	var total int64
+
+	query := r.db.Model(&models.Document{}).Preload("Collection").Preload("Uploader").
+		Where("status = ?", filters.Status)
+
+	// Documents rejected by processing rather than by a moderator are not submissions
+	if filters.Status == models.StatusRejected {
+		query = query.Where("reviewed_at IS NOT NULL")
+	}
+
+	if filters.CollectionID != nil {
+		query = query.Where("collection_id = ?", *filters.CollectionID)
+	}
+
+	if filters.VisibleTo != nil {
+		query = query.Where("uploader_id = ? OR collection_id IN (SELECT id FROM collections WHERE owner_id = ?)",
+			*filters.VisibleTo, *filters.VisibleTo)
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	if err := query.Offset(offset).Limit(limit).Order("created_at").Find(&documents).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return documents, total, nil
+}
+
+// Review records the decision on a submitted document. It reports false if the document
+// was no longer awaiting review (e.g. another moderator decided first).
+func (r *documentRepository) Review(id uuid.UUID, status models.DocumentStatus, reviewedBy uuid.UUID, note string) (bool, error) {
+	result := r.db.Model(&models.Document{}).
+		Where("id = ? AND status = ?", id, models.StatusSubmitted).
+		Updates(map[string]interface{}{
+			"status":      status,
+			"reviewed_by": reviewedBy,
+			"reviewed_at": time.Now(),
+			"review_note": note,
+		})
+	return result.RowsAffected > 0, result.Error
+}
+
 // UpdateStatus updates the status of a document
 func (r *documentRepository) UpdateStatus(id uuid.UUID, status models.DocumentStatus) error {
 	return r.db.Model(&models.Document{}).Where("id = ?", id).Update("status", status).Error
 }
 
+// TransitionStatus moves a document to a new status if it is in one of the from states.
+// It reports false if the document had moved on in the meantime.
+func (r *documentRepository) TransitionStatus(id uuid.UUID, from []models.DocumentStatus, to models.DocumentStatus) (bool, error) {
+	result := r.db.Model(&models.Document{}).
+		Where("id = ? AND status IN ?", id, from).
+		Update("status", to)
+	return result.RowsAffected > 0, result.Error
+}
+
+// SetDerivatives stores the results of processing a document's content, provided its
+// content still has the given hash (results for replaced content are dropped)
+func (r *documentRepository) SetDerivatives(id uuid.UUID, hash string, derivatives Derivatives) error {
+	return r.db.Model(&models.Document{}).
+		Where("id = ? AND hash = ?", id, hash).
+		Updates(map[string]interface{}{
+			"thumbnail_path":   derivatives.ThumbnailPath,
+			"page_count":       derivatives.PageCount,
+			"processing_error": derivatives.ProcessingError,
+		}).Error
+}
+
+// EditDetails applies edit to a document's title, description, metadata and availability
+// while holding its row lock, so that edits by users and by the derivative worker do not
+// overwrite each other
+func (r *documentRepository) EditDetails(id uuid.UUID, edit func(document *models.Document) error) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var document models.Document
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ?", id).
+			First(&document).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("document not found")
+			}
+			return err
+		}
+
+		if err := edit(&document); err != nil {
+			return err
+		}
+		return tx.Model(&document).
+			Select("title", "description", "metadata", "available_from", "available_until", "embargo_show_metadata").
+			Updates(&document).Error
+	})
+}
+
+// ListUnprocessed lists documents still waiting for processing that were last updated
+// before the given time
+func (r *documentRepository) ListUnprocessed(before time.Time, limit int) ([]models.Document, error) {
+	var documents []models.Document
+	err := r.db.
+		Where("status IN ? AND updated_at < ?", []models.DocumentStatus{models.StatusPending, models.StatusProcessing}, before).
+		Order("updated_at").
+		Limit(limit).
+		Find(&documents).Error
+	return documents, err
+}
+
+// ListAvailabilityDue lists documents whose status no longer matches their availability
+// window at now: embargoed ones past their start, and active or embargoed ones past their end
+func (r *documentRepository) ListAvailabilityDue(now time.Time, limit int) ([]models.Document, error) {
+	var documents []models.Document
+	err := r.db.
+		Where("(status = ? AND (available_from IS NULL OR available_from <= ?)) OR (status IN ? AND available_until <= ?)",
+			models.StatusEmbargoed, now,
+			[]models.DocumentStatus{models.StatusActive, models.StatusEmbargoed}, now).
+		Order("id").
+		Limit(limit).
+		Find(&documents).Error
+	return documents, err
+}
+
 // IncrementViewCount increments the view count for a document
 func (r *documentRepository) IncrementViewCount(id uuid.UUID) error {
 	return r.db.Model(&models.Document{}).
@@ -158,3 +451,11 @@ func (r *documentRepository) SetIndexed(id uuid.UUID, indexed bool) error {
 	}
 	return r.db.Model(&models.Document{}).Where("id = ?", id).Updates(updates).Error
 }
+
+// SetExtractedText stores the extracted text of a document, provided its content still
+// has the given hash (extraction of superseded content is ignored)
+func (r *documentRepository) SetExtractedText(id uuid.UUID, hash, text string) error {
+	return r.db.Model(&models.Document{}).
+		Where("id = ? AND hash = ?", id, hash).
+		Update("extracted_text", text).Error
+}
@@ -0,0 +1,369 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/Kyei-Ernest/libsystem/shared/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrNotLent is returned when a loan is requested of a document that is not lent
+	ErrNotLent = errors.New("document is not lent")
+	// ErrDigitalLoanNotFound is returned when the user has no loan or waitlist place
+	ErrDigitalLoanNotFound = errors.New("digital loan not found")
+)
+
+// LendingRepository defines the interface for controlled digital lending. Every change to
+// the loans of a document happens under a lock on the document, which also settles its
+// loans: expired loans are closed and free licences go to the waitlist in order.
+type LendingRepository interface {
+	Borrow(documentID, userID uuid.UUID, now time.Time) (*models.DigitalLoan, []models.DigitalLoan, error)
+	Return(documentID, userID uuid.UUID, now time.Time) (*models.DigitalLoan, []models.DigitalLoan, error)
+	SetLending(documentID uuid.UUID, licenses *int, periodHours int, now time.Time) ([]models.DigitalLoan, error)
+	Settle(documentID uuid.UUID, now time.Time) ([]models.DigitalLoan, []models.DigitalLoan, error)
+	FindOpen(documentID, userID uuid.UUID) (*models.DigitalLoan, error)
+	CountOpen(documentID uuid.UUID, now time.Time) (int64, int64, error)
+	WaitlistPosition(loan *models.DigitalLoan) (int, error)
+	ListByUser(userID uuid.UUID, open bool, offset, limit int) ([]models.DigitalLoan, int64, error)
+	ListExpiredDocuments(now time.Time, limit int) ([]uuid.UUID, error)
+}
+
+type lendingRepository struct {
+	db *gorm.DB
+}
+
+// lendingTerms are the lending settings of a document
+type lendingTerms struct {
+	LendingLicenses    *int
+	LendingPeriodHours int
+}
+
+// NewLendingRepository creates a new lending repository
+func NewLendingRepository(db *gorm.DB) LendingRepository {
+	return &lendingRepository{db: db}
+}
+
+// Borrow lends a document to a user, or puts them on the waitlist when every licence is
+// taken. A user who already has a loan or a place gets that back. Also returns the loans
+// started, which include the user's if it started now.
+func (r *lendingRepository) Borrow(documentID, userID uuid.UUID, now time.Time) (*models.DigitalLoan, []models.DigitalLoan, error) {
+	var loan *models.DigitalLoan
+	var started []models.DigitalLoan
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		terms, err := lockLending(tx, documentID)
+		if err != nil {
+			return err
+		}
+		if terms.LendingLicenses == nil {
+			return ErrNotLent
+		}
+		if _, started, err = settleLoans(tx, documentID, terms, now); err != nil {
+			return err
+		}
+
+		if loan, err = findOpenLoan(tx, documentID, userID); err == nil {
+			return nil
+		} else if !errors.Is(err, ErrDigitalLoanNotFound) {
+			return err
+		}
+
+		// Settling handed every free licence to the waitlist, so one is left only when
+		// nobody is waiting
+		active, err := countActive(tx, documentID)
+		if err != nil {
+			return err
+		}
+		loan = &models.DigitalLoan{
+			DocumentID:  documentID,
+			UserID:      userID,
+			Status:      models.DigitalLoanWaiting,
+			RequestedAt: now,
+		}
+		if active < int64(*terms.LendingLicenses) {
+			startLoan(loan, terms, now)
+		}
+		if err := tx.Create(loan).Error; err != nil {
+			return err
+		}
+		if loan.Status == models.DigitalLoanActive {
+			started = append(started, *loan)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return loan, started, nil
+}
+
+// Return ends a user's loan of a document, or takes them off its waitlist, and passes a
+// freed licence to the next user waiting. Returns the closed loan and the loans started.
+func (r *lendingRepository) Return(documentID, userID uuid.UUID, now time.Time) (*models.DigitalLoan, []models.DigitalLoan, error) {
+	var loan *models.DigitalLoan
+	var started []models.DigitalLoan
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		terms, err := lockLending(tx, documentID)
+		if err != nil {
+			return err
+		}
+		if loan, err = findOpenLoan(tx, documentID, userID); err != nil {
+			return err
+		}
+
+		status := models.DigitalLoanCancelled
+		if loan.Status == models.DigitalLoanActive {
+			status = models.DigitalLoanReturned
+			if !loan.IsActive(now) {
+				status = models.DigitalLoanExpired
+			}
+		}
+		if err := closeLoans(tx, []uuid.UUID{loan.ID}, status, now); err != nil {
+			return err
+		}
+		loan.Status = status
+		loan.ClosedAt = &now
+
+		if terms.LendingLicenses != nil {
+			_, started, err = settleLoans(tx, documentID, terms, now)
+		}
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return loan, started, nil
+}
+
+// SetLending changes the lending settings of a document. A nil licence count ends
+// lending: open loans are closed and the waitlist cleared. Added licences go to the
+// waitlist at once; loans beyond a reduced count run until they end. Returns the loans
+// started.
+func (r *lendingRepository) SetLending(documentID uuid.UUID, licenses *int, periodHours int, now time.Time) ([]models.DigitalLoan, error) {
+	var started []models.DigitalLoan
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if _, err := lockLending(tx, documentID); err != nil {
+			return err
+		}
+		if err := tx.Model(&models.Document{}).Where("id = ?", documentID).Updates(map[string]interface{}{
+			"lending_licenses":     licenses,
+			"lending_period_hours": periodHours,
+		}).Error; err != nil {
+			return err
+		}
+
+		if licenses == nil {
+			if err := tx.Model(&models.DigitalLoan{}).
+				Where("document_id = ? AND status = ?", documentID, models.DigitalLoanActive).
+				Updates(map[string]interface{}{"status": models.DigitalLoanReturned, "closed_at": now}).Error; err != nil {
+				return err
+			}
+			return tx.Model(&models.DigitalLoan{}).
+				Where("document_id = ? AND status = ?", documentID, models.DigitalLoanWaiting).
+				Updates(map[string]interface{}{"status": models.DigitalLoanCancelled, "closed_at": now}).Error
+		}
+
+		var err error
+		_, started, err = settleLoans(tx, documentID, &lendingTerms{LendingLicenses: licenses, LendingPeriodHours: periodHours}, now)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return started, nil
+}
+
+// Settle closes a document's loans that have expired at now and starts loans for the
+// users waiting. Returns the loans expired and the loans started.
+func (r *lendingRepository) Settle(documentID uuid.UUID, now time.Time) ([]models.DigitalLoan, []models.DigitalLoan, error) {
+	var expired, started []models.DigitalLoan
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		terms, err := lockLending(tx, documentID)
+		if err != nil {
+			return err
+		}
+		if terms.LendingLicenses == nil {
+			return nil
+		}
+		expired, started, err = settleLoans(tx, documentID, terms, now)
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return expired, started, nil
+}
+
+// FindOpen retrieves a user's active loan of, or waitlist place for, a document
+func (r *lendingRepository) FindOpen(documentID, userID uuid.UUID) (*models.DigitalLoan, error) {
+	return findOpenLoan(r.db, documentID, userID)
+}
+
+// CountOpen counts the loans of a document that are running at now and the users waiting
+func (r *lendingRepository) CountOpen(documentID uuid.UUID, now time.Time) (int64, int64, error) {
+	var counts struct {
+		Active  int64
+		Waiting int64
+	}
+	err := r.db.Raw(`
+		SELECT
+			COUNT(*) FILTER (WHERE status = 'active' AND expires_at > ?) AS active,
+			COUNT(*) FILTER (WHERE status = 'waiting') AS waiting
+		FROM digital_loans
+		WHERE document_id = ? AND status IN ('active', 'waiting')`,
+		now, documentID,
+	).Scan(&counts).Error
+	return counts.Active, counts.Waiting, err
+}
+
+// WaitlistPosition returns the place of a waiting loan on its waitlist, from 1
+func (r *lendingRepository) WaitlistPosition(loan *models.DigitalLoan) (int, error) {
+	var ahead int64
+	err := r.db.Model(&models.DigitalLoan{}).
+		Where("document_id = ? AND status = ? AND (requested_at, id) < (?, ?)",
+			loan.DocumentID, models.DigitalLoanWaiting, loan.RequestedAt, loan.ID).
+		Count(&ahead).Error
+	return int(ahead) + 1, err
+}
+
+// ListByUser lists a user's loans, newest first, with their documents loaded. Open limits
+// the list to active loans and waitlist places.
+func (r *lendingRepository) ListByUser(userID uuid.UUID, open bool, offset, limit int) ([]models.DigitalLoan, int64, error) {
+	var loans []models.DigitalLoan
+	var total int64
+
+	query := r.db.Model(&models.DigitalLoan{}).Where("user_id = ?", userID)
+	if open {
+		query = query.Where("status IN ?", []models.DigitalLoanStatus{models.DigitalLoanActive, models.DigitalLoanWaiting})
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	err := query.Preload("Document").
+		Order("requested_at DESC").
+		Offset(offset).
+		Limit(limit).
+		Find(&loans).Error
+	return loans, total, err
+}
+
+// ListExpiredDocuments lists documents with loans still active past their end at now
+func (r *lendingRepository) ListExpiredDocuments(now time.Time, limit int) ([]uuid.UUID, error) {
+	var ids []uuid.UUID
+	err := r.db.Model(&models.DigitalLoan{}).
+		Distinct("document_id").
+		Where("status = ? AND expires_at <= ?", models.DigitalLoanActive, now).
+		Limit(limit).
+		Pluck("document_id", &ids).Error
+	return ids, err
+}
+
+// lockLending locks a document against concurrent lending changes and returns its
+// lending settings
+func lockLending(tx *gorm.DB, documentID uuid.UUID) (*lendingTerms, error) {
+	var terms lendingTerms
+	if err := tx.Model(&models.Document{}).
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Select("lending_licenses", "lending_period_hours").
+		Where("id = ?", documentID).
+		Take(&terms).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("document not found")
+		}
+		return nil, err
+	}
+	return &terms, nil
+}
+
+// settleLoans expires a locked document's loans that ended by now and starts loans for
+// waiting users, oldest request first, while licences are free
+func settleLoans(tx *gorm.DB, documentID uuid.UUID, terms *lendingTerms, now time.Time) ([]models.DigitalLoan, []models.DigitalLoan, error) {
+	var expired []models.DigitalLoan
+	if err := tx.Where("document_id = ? AND status = ? AND expires_at <= ?", documentID, models.DigitalLoanActive, now).
+		Find(&expired).Error; err != nil {
+		return nil, nil, err
+	}
+	if len(expired) > 0 {
+		ids := make([]uuid.UUID, len(expired))
+		for i := range expired {
+			ids[i] = expired[i].ID
+			expired[i].Status = models.DigitalLoanExpired
+			expired[i].ClosedAt = &now
+		}
+		if err := closeLoans(tx, ids, models.DigitalLoanExpired, now); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	active, err := countActive(tx, documentID)
+	if err != nil {
+		return nil, nil, err
+	}
+	free := int64(*terms.LendingLicenses) - active
+	if free <= 0 {
+		return expired, nil, nil
+	}
+
+	var started []models.DigitalLoan
+	if err := tx.Where("document_id = ? AND status = ?", documentID, models.DigitalLoanWaiting).
+		Order("requested_at ASC, id ASC").
+		Limit(int(free)).
+		Find(&started).Error; err != nil {
+		return nil, nil, err
+	}
+	for i := range started {
+		startLoan(&started[i], terms, now)
+		if err := tx.Model(&started[i]).Updates(map[string]interface{}{
+			"status":     started[i].Status,
+			"started_at": started[i].StartedAt,
+			"expires_at": started[i].ExpiresAt,
+		}).Error; err != nil {
+			return nil, nil, err
+		}
+	}
+	return expired, started, nil
+}
+
+// startLoan makes a loan active from now for the lending period
+func startLoan(loan *models.DigitalLoan, terms *lendingTerms, now time.Time) {
+	expiresAt := now.Add(time.Duration(terms.LendingPeriodHours) * time.Hour)
+	loan.Status = models.DigitalLoanActive
+	loan.StartedAt = &now
+	loan.ExpiresAt = &expiresAt
+}
+
+func closeLoans(tx *gorm.DB, ids []uuid.UUID, status models.DigitalLoanStatus, now time.Time) error {
+	return tx.Model(&models.DigitalLoan{}).
+		Where("id IN ?", ids).
+		Updates(map[string]interface{}{"status": status, "closed_at": now}).Error
+}
+
+func countActive(tx *gorm.DB, documentID uuid.UUID) (int64, error) {
+	var active int64
+	err := tx.Model(&models.DigitalLoan{}).
+		Where("document_id = ? AND status = ?", documentID, models.DigitalLoanActive).
+		Count(&active).Error
+	return active, err
+}
+
+func findOpenLoan(db *gorm.DB, documentID, userID uuid.UUID) (*models.DigitalLoan, error) {
+	var loan models.DigitalLoan
+	if err := db.Where("document_id = ? AND user_id = ? AND status IN ?", documentID, userID,
+		[]models.DigitalLoanStatus{models.DigitalLoanActive, models.DigitalLoanWaiting}).
+		First(&loan).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrDigitalLoanNotFound
+		}
+		return nil, err
+	}
+	return &loan, nil
+}
@@ -0,0 +1,102 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/Kyei-Ernest/libsystem/shared/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ReadingRepository defines the interface for users' reading progress
+type ReadingRepository interface {
+	Find(userID, documentID uuid.UUID) (*models.ReadingProgress, error)
+	SavePosition(progress *models.ReadingProgress) error
+	TouchLastRead(userID, documentID uuid.UUID, readAt time.Time) error
+	Delete(userID, documentID uuid.UUID) error
+	ListRecent(userID uuid.UUID, offset, limit int) ([]models.ReadingProgress, int64, error)
+}
+
+type readingRepository struct {
+	db *gorm.DB
+}
+
+// NewReadingRepository creates a new reading progress repository
+func NewReadingRepository(db *gorm.DB) ReadingRepository {
+	return &readingRepository{db: db}
+}
+
+// Find retrieves a user's progress in a document
+func (r *readingRepository) Find(userID, documentID uuid.UUID) (*models.ReadingProgress, error) {
+	var progress models.ReadingProgress
+	if err := r.db.Where("user_id = ? AND document_id = ?", userID, documentID).First(&progress).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("reading progress not found")
+		}
+		return nil, err
+	}
+	return &progress, nil
+}
+
+// SavePosition records a reading position unless a later one (by PositionUpdatedAt) is
+// already stored, so that a device syncing late does not move the reader back
+func (r *readingRepository) SavePosition(progress *models.ReadingProgress) error {
+	return r.db.Exec(`
+		INSERT INTO reading_progress (user_id, document_id, page, percentage, position_updated_at, last_read_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT (user_id, document_id) DO UPDATE SET
+			page = CASE WHEN reading_progress.position_updated_at IS NULL
+				OR reading_progress.position_updated_at <= EXCLUDED.position_updated_at
+				THEN EXCLUDED.page ELSE reading_progress.page END,
+			percentage = CASE WHEN reading_progress.position_updated_at IS NULL
+				OR reading_progress.position_updated_at <= EXCLUDED.position_updated_at
+				THEN EXCLUDED.percentage ELSE reading_progress.percentage END,
+			position_updated_at = GREATEST(reading_progress.position_updated_at, EXCLUDED.position_updated_at),
+			last_read_at = GREATEST(reading_progress.last_read_at, EXCLUDED.last_read_at)`,
+		progress.UserID, progress.DocumentID, progress.Page, progress.Percentage,
+		progress.PositionUpdatedAt, progress.LastReadAt,
+	).Error
+}
+
+// TouchLastRead records that a user read a document, keeping any position. Documents that
+// no longer exist are ignored.
+func (r *readingRepository) TouchLastRead(userID, documentID uuid.UUID, readAt time.Time) error {
+	return r.db.Exec(`
+		INSERT INTO reading_progress (user_id, document_id, last_read_at)
+		SELECT ?, id, ? FROM documents WHERE id = ?
+		ON CONFLICT (user_id, document_id) DO UPDATE SET
+			last_read_at = GREATEST(reading_progress.last_read_at, EXCLUDED.last_read_at)`,
+		userID, readAt, documentID,
+	).Error
+}
+
+// Delete forgets a user's progress in a document
+func (r *readingRepository) Delete(userID, documentID uuid.UUID) error {
+	return r.db.Where("user_id = ? AND document_id = ?", userID, documentID).Delete(&models.ReadingProgress{}).Error
+}
+
+// ListRecent lists the documents a user read most recently, with the documents and their
+// collections loaded, leaving out documents in the trash
+func (r *readingRepository) ListRecent(userID uuid.UUID, offset, limit int) ([]models.ReadingProgress, int64, error) {
+	var progress []models.ReadingProgress
+	var total int64
+
+	query := r.db.Model(&models.ReadingProgress{}).
+		Where("user_id = ?", userID).
+		Where("document_id IN (SELECT id FROM documents WHERE deleted_at IS NULL)")
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	err := query.Preload("Document.Collection").
+		Order("last_read_at DESC").
+		Offset(offset).Limit(limit).
+		Find(&progress).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return progress, total, nil
+}
@@ -0,0 +1,155 @@
+package repository
+
+import (
+	"errors"
+
+	"github.com/Kyei-Ernest/libsystem/shared/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrRelationExists is returned when the documents are already related that way
+	ErrRelationExists = errors.New("relation already exists")
+	// ErrRelationCycle is returned when a relation would make a document its own ancestor
+	ErrRelationCycle = errors.New("relation would create a cycle")
+	// ErrRelationParent is returned when a document would become part of a second work
+	ErrRelationParent = errors.New("document is already part of another work")
+)
+
+// RelationRepository defines the interface for relationships between documents
+type RelationRepository interface {
+	Create(relation *models.DocumentRelation, acyclic []models.RelationType) error
+	FindByID(id uuid.UUID) (*models.DocumentRelation, error)
+	UpdatePosition(id uuid.UUID, position *int) error
+	Delete(id uuid.UUID) error
+	ListByDocument(documentID uuid.UUID) ([]models.DocumentRelation, error)
+	ListMembers(objectID uuid.UUID, relationType models.RelationType) ([]models.DocumentRelation, error)
+	ListWorks(documentID uuid.UUID) ([]models.DocumentRelation, error)
+}
+
+type relationRepository struct {
+	db *gorm.DB
+}
+
+// NewRelationRepository creates a new relation repository
+func NewRelationRepository(db *gorm.DB) RelationRepository {
+	return &relationRepository{db: db}
+}
+
+// Create stores a relation unless it duplicates an existing one, gives a document a second
+// parent work, or closes a cycle through relations of the acyclic types. Relation writes
+// are serialized so that concurrent requests cannot build a cycle between them.
+func (r *relationRepository) Create(relation *models.DocumentRelation, acyclic []models.RelationType) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(hashtext('document_relations'))").Error; err != nil {
+			return err
+		}
+
+		// Related is symmetric, so either direction counts as the same relation
+		var existing int64
+		query := tx.Model(&models.DocumentRelation{}).
+			Where("type = ? AND subject_id = ? AND object_id = ?", relation.Type, relation.SubjectID, relation.ObjectID)
+		if relation.Type == models.RelationRelated {
+			query = query.Or("type = ? AND subject_id = ? AND object_id = ?", relation.Type, relation.ObjectID, relation.SubjectID)
+		}
+		if err := query.Count(&existing).Error; err != nil {
+			return err
+		}
+		if existing > 0 {
+			return ErrRelationExists
+		}
+
+		if relation.Type == models.RelationPartOf {
+			var parents int64
+			if err := tx.Model(&models.DocumentRelation{}).
+				Where("type = ? AND subject_id = ?", models.RelationPartOf, relation.SubjectID).
+				Count(&parents).Error; err != nil {
+				return err
+			}
+			if parents > 0 {
+				return ErrRelationParent
+			}
+		}
+
+		if len(acyclic) > 0 {
+			// The relation closes a cycle if its subject is reachable from its object
+			var cycle bool
+			err := tx.Raw(`
+				WITH RECURSIVE reach(id) AS (
+					SELECT CAST(? AS uuid)
+					UNION
+					SELECT r.object_id FROM document_relations r JOIN reach ON r.subject_id = reach.id
+					WHERE r.type IN ?
+				)
+				SELECT EXISTS (SELECT 1 FROM reach WHERE id = ?)`,
+				relation.ObjectID, acyclic, relation.SubjectID,
+			).Scan(&cycle).Error
+			if err != nil {
+				return err
+			}
+			if cycle {
+				return ErrRelationCycle
+			}
+		}
+
+		return tx.Create(relation).Error
+	})
+}
+
+// FindByID retrieves a relation by ID
+func (r *relationRepository) FindByID(id uuid.UUID) (*models.DocumentRelation, error) {
+	var relation models.DocumentRelation
+	if err := r.db.Where("id = ?", id).First(&relation).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("relation not found")
+		}
+		return nil, err
+	}
+	return &relation, nil
+}
+
+// UpdatePosition sets the position of a relation among the object's parts or volumes
+func (r *relationRepository) UpdatePosition(id uuid.UUID, position *int) error {
+	return r.db.Model(&models.DocumentRelation{}).Where("id = ?", id).Update("position", position).Error
+}
+
+// Delete removes a relation
+func (r *relationRepository) Delete(id uuid.UUID) error {
+	return r.db.Where("id = ?", id).Delete(&models.DocumentRelation{}).Error
+}
+
+// ListByDocument lists the relations a document takes part in, as subject or object, with
+// both documents loaded. Documents in the trash are not loaded and have a zero ID.
+func (r *relationRepository) ListByDocument(documentID uuid.UUID) ([]models.DocumentRelation, error) {
+	var relations []models.DocumentRelation
+	err := r.db.
+		Preload("Subject.Collection").
+		Preload("Object.Collection").
+		Where("subject_id = ? OR object_id = ?", documentID, documentID).
+		Order("type, position NULLS LAST, created_at").
+		Find(&relations).Error
+	return relations, err
+}
+
+// ListMembers lists the parts or volumes of a work in order, with the members loaded
+func (r *relationRepository) ListMembers(objectID uuid.UUID, relationType models.RelationType) ([]models.DocumentRelation, error) {
+	var relations []models.DocumentRelation
+	err := r.db.
+		Preload("Subject.Collection").
+		Where("object_id = ? AND type = ?", objectID, relationType).
+		Order("position NULLS LAST, created_at").
+		Find(&relations).Error
+	return relations, err
+}
+
+// ListWorks lists the works and series a document is a part or volume of
+func (r *relationRepository) ListWorks(documentID uuid.UUID) ([]models.DocumentRelation, error) {
+	var relations []models.DocumentRelation
+	err := r.db.
+		Where("subject_id = ? AND type IN ?", documentID,
+			[]models.RelationType{models.RelationPartOf, models.RelationSeriesMember}).
+		Order("created_at").
+		Find(&relations).Error
+	return relations, err
+}
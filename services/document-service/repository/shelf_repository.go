@@ -0,0 +1,320 @@
+package repository
+
+import (
+	"errors"
+
+	"github.com/Kyei-Ernest/libsystem/shared/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrShelfNameTaken is returned when the owner already has a shelf with the name
+	ErrShelfNameTaken = errors.New("shelf name already in use")
+	// ErrShelfItemExists is returned when the document is already on the shelf
+	ErrShelfItemExists = errors.New("document is already on the shelf")
+	// ErrShelfItemNotFound is returned when the document is not on the shelf
+	ErrShelfItemNotFound = errors.New("shelf item not found")
+	// ErrShelfFull is returned when a shelf holds as many documents as it may
+	ErrShelfFull = errors.New("shelf is full")
+)
+
+// ShelfRepository defines the interface for personal shelves and their documents
+type ShelfRepository interface {
+	Create(shelf *models.Shelf) error
+	FindByID(id uuid.UUID) (*models.Shelf, error)
+	EnsureFavorites(ownerID uuid.UUID) (*models.Shelf, error)
+	Update(shelf *models.Shelf) error
+	Delete(id uuid.UUID) error
+	ListByOwner(ownerID uuid.UUID, publicOnly bool, offset, limit int) ([]models.Shelf, int64, error)
+	ListItems(shelfID uuid.UUID) ([]models.ShelfItem, error)
+	FindItem(shelfID, documentID uuid.UUID) (*models.ShelfItem, error)
+	AddItem(item *models.ShelfItem, maxItems int) error
+	UpdateItem(shelfID, documentID uuid.UUID, position *int, note *string) error
+	RemoveItem(shelfID, documentID uuid.UUID) error
+}
+
+type shelfRepository struct {
+	db *gorm.DB
+}
+
+// NewShelfRepository creates a new shelf repository
+func NewShelfRepository(db *gorm.DB) ShelfRepository {
+	return &shelfRepository{db: db}
+}
+
+// Create creates a new shelf unless the owner already has one with the name
+func (r *shelfRepository) Create(shelf *models.Shelf) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := checkShelfName(tx, shelf); err != nil {
+			return err
+		}
+		return tx.Create(shelf).Error
+	})
+}
+
+// FindByID retrieves a shelf by ID with its item count
+func (r *shelfRepository) FindByID(id uuid.UUID) (*models.Shelf, error) {
+	var shelf models.Shelf
+	if err := r.db.Where("id = ?", id).First(&shelf).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("shelf not found")
+		}
+		return nil, err
+	}
+	if err := r.db.Model(&models.ShelfItem{}).Where("shelf_id = ?", id).Count(&shelf.ItemCount).Error; err != nil {
+		return nil, err
+	}
+	return &shelf, nil
+}
+
+// EnsureFavorites retrieves the owner's favorites shelf, creating it on first use
+func (r *shelfRepository) EnsureFavorites(ownerID uuid.UUID) (*models.Shelf, error) {
+	err := r.db.Exec(`INSERT INTO shelves (owner_id, kind, name) VALUES (?, ?, ?)
+		ON CONFLICT (owner_id) WHERE kind = 'favorites' DO NOTHING`,
+		ownerID, models.ShelfFavorites, "Favorites").Error
+	if err != nil {
+		return nil, err
+	}
+
+	var shelf models.Shelf
+	if err := r.db.Where("owner_id = ? AND kind = ?", ownerID, models.ShelfFavorites).First(&shelf).Error; err != nil {
+		return nil, err
+	}
+	return r.FindByID(shelf.ID)
+}
+
+// Update saves a shelf's name, description and visibility
+func (r *shelfRepository) Update(shelf *models.Shelf) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := checkShelfName(tx, shelf); err != nil {
+			return err
+		}
+		return tx.Model(shelf).Select("name", "description", "is_public").Updates(shelf).Error
+	})
+}
+
+// Delete removes a shelf and its items
+func (r *shelfRepository) Delete(id uuid.UUID) error {
+	return r.db.Where("id = ?", id).Delete(&models.Shelf{}).Error
+}
+
+// ListByOwner lists a user's shelves, favorites first, with their item counts
+func (r *shelfRepository) ListByOwner(ownerID uuid.UUID, publicOnly bool, offset, limit int) ([]models.Shelf, int64, error) {
+	var shelves []models.Shelf
+	var total int64
+
+	query := r.db.Model(&models.Shelf{}).Where("owner_id = ?", ownerID)
+	if publicOnly {
+		query = query.Where("is_public = ?", true)
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	err := query.
+		Order("kind = 'favorites' DESC, LOWER(name)").
+		Offset(offset).Limit(limit).
+		Find(&shelves).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// Item counts are not stored on the shelf
+	counts := make(map[uuid.UUID]int64, len(shelves))
+	if len(shelves) > 0 {
+		ids := make([]uuid.UUID, len(shelves))
+		for i := range shelves {
+			ids[i] = shelves[i].ID
+		}
+		var rows []struct {
+			ShelfID uuid.UUID
+			Count   int64
+		}
+		if err := r.db.Model(&models.ShelfItem{}).
+			Select("shelf_id, COUNT(*) AS count").
+			Where("shelf_id IN ?", ids).
+			Group("shelf_id").
+			Scan(&rows).Error; err != nil {
+			return nil, 0, err
+		}
+		for _, row := range rows {
+			counts[row.ShelfID] = row.Count
+		}
+	}
+	for i := range shelves {
+		shelves[i].ItemCount = counts[shelves[i].ID]
+	}
+
+	return shelves, total, nil
+}
+
+// ListItems lists the documents on a shelf in order, with the documents and their
+// collections loaded. Documents in the trash are not loaded and have a nil Document.
+func (r *shelfRepository) ListItems(shelfID uuid.UUID) ([]models.ShelfItem, error) {
+	var items []models.ShelfItem
+	err := r.db.
+		Preload("Document.Collection").
+		Where("shelf_id = ?", shelfID).
+		Order("position, added_at").
+		Find(&items).Error
+	return items, err
+}
+
+// FindItem retrieves a document's entry on a shelf
+func (r *shelfRepository) FindItem(shelfID, documentID uuid.UUID) (*models.ShelfItem, error) {
+	var item models.ShelfItem
+	if err := r.db.Where("shelf_id = ? AND document_id = ?", shelfID, documentID).First(&item).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrShelfItemNotFound
+		}
+		return nil, err
+	}
+	return &item, nil
+}
+
+// AddItem puts a document on a shelf at item.Position, moving later documents down, or at
+// the end when the position is 0 or past it
+func (r *shelfRepository) AddItem(item *models.ShelfItem, maxItems int) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		count, err := lockShelf(tx, item.ShelfID)
+		if err != nil {
+			return err
+		}
+		if count >= int64(maxItems) {
+			return ErrShelfFull
+		}
+
+		var existing int64
+		if err := tx.Model(&models.ShelfItem{}).
+			Where("shelf_id = ? AND document_id = ?", item.ShelfID, item.DocumentID).
+			Count(&existing).Error; err != nil {
+			return err
+		}
+		if existing > 0 {
+			return ErrShelfItemExists
+		}
+
+		if item.Position < 1 || item.Position > int(count) {
+			item.Position = int(count) + 1
+		} else if err := tx.Model(&models.ShelfItem{}).
+			Where("shelf_id = ? AND position >= ?", item.ShelfID, item.Position).
+			Update("position", gorm.Expr("position + 1")).Error; err != nil {
+			return err
+		}
+
+		return tx.Create(item).Error
+	})
+}
+
+// UpdateItem changes a document's note and moves it to a position on its shelf, shifting
+// the documents in between. Positions past the end move it to the end.
+func (r *shelfRepository) UpdateItem(shelfID, documentID uuid.UUID, position *int, note *string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		count, err := lockShelf(tx, shelfID)
+		if err != nil {
+			return err
+		}
+
+		var item models.ShelfItem
+		if err := tx.Where("shelf_id = ? AND document_id = ?", shelfID, documentID).First(&item).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrShelfItemNotFound
+			}
+			return err
+		}
+
+		updates := map[string]interface{}{}
+		if note != nil {
+			updates["note"] = *note
+		}
+		if position != nil {
+			target := *position
+			if target < 1 {
+				target = 1
+			}
+			if target > int(count) {
+				target = int(count)
+			}
+
+			shift := tx.Model(&models.ShelfItem{}).Where("shelf_id = ?", shelfID)
+			switch {
+			case target > item.Position:
+				err = shift.Where("position > ? AND position <= ?", item.Position, target).
+					Update("position", gorm.Expr("position - 1")).Error
+			case target < item.Position:
+				err = shift.Where("position >= ? AND position < ?", target, item.Position).
+					Update("position", gorm.Expr("position + 1")).Error
+			}
+			if err != nil {
+				return err
+			}
+			updates["position"] = target
+		}
+		if len(updates) == 0 {
+			return nil
+		}
+
+		return tx.Model(&models.ShelfItem{}).
+			Where("shelf_id = ? AND document_id = ?", shelfID, documentID).
+			Updates(updates).Error
+	})
+}
+
+// RemoveItem takes a document off a shelf and closes the gap it leaves
+func (r *shelfRepository) RemoveItem(shelfID, documentID uuid.UUID) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if _, err := lockShelf(tx, shelfID); err != nil {
+			return err
+		}
+
+		var item models.ShelfItem
+		if err := tx.Where("shelf_id = ? AND document_id = ?", shelfID, documentID).First(&item).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrShelfItemNotFound
+			}
+			return err
+		}
+
+		if err := tx.Where("shelf_id = ? AND document_id = ?", shelfID, documentID).
+			Delete(&models.ShelfItem{}).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.ShelfItem{}).
+			Where("shelf_id = ? AND position > ?", shelfID, item.Position).
+			Update("position", gorm.Expr("position - 1")).Error
+	})
+}
+
+// lockShelf serializes changes to a shelf's items for the rest of the transaction and
+// returns how many items it holds
+func lockShelf(tx *gorm.DB, shelfID uuid.UUID) (int64, error) {
+	var locked []uuid.UUID
+	if err := tx.Raw("SELECT id FROM shelves WHERE id = ? FOR UPDATE", shelfID).Scan(&locked).Error; err != nil {
+		return 0, err
+	}
+	if len(locked) == 0 {
+		return 0, errors.New("shelf not found")
+	}
+
+	var count int64
+	err := tx.Model(&models.ShelfItem{}).Where("shelf_id = ?", shelfID).Count(&count).Error
+	return count, err
+}
+
+// checkShelfName checks that no other shelf of the owner has the shelf's name
+func checkShelfName(tx *gorm.DB, shelf *models.Shelf) error {
+	var taken int64
+	query := tx.Model(&models.Shelf{}).Where("owner_id = ? AND LOWER(name) = LOWER(?)", shelf.OwnerID, shelf.Name)
+	if shelf.ID != uuid.Nil {
+		query = query.Where("id <> ?", shelf.ID)
+	}
+	if err := query.Count(&taken).Error; err != nil {
+		return err
+	}
+	if taken > 0 {
+		return ErrShelfNameTaken
+	}
+	return nil
+}
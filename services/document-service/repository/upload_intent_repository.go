@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/Kyei-Ernest/libsystem/shared/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// UploadIntentRepository defines the interface for direct upload intent data access
+type UploadIntentRepository interface {
+	Create(intent *models.UploadIntent) error
+	FindByID(id uuid.UUID) (*models.UploadIntent, error)
+	Update(intent *models.UploadIntent) error
+	Delete(id uuid.UUID) error
+	ListExpired(before time.Time) ([]models.UploadIntent, error)
+}
+
+// uploadIntentRepository implements UploadIntentRepository using GORM
+type uploadIntentRepository struct {
+	db *gorm.DB
+}
+
+// NewUploadIntentRepository creates a new upload intent repository
+func NewUploadIntentRepository(db *gorm.DB) UploadIntentRepository {
+	return &uploadIntentRepository{db: db}
+}
+
+// Create creates a new upload intent
+func (r *uploadIntentRepository) Create(intent *models.UploadIntent) error {
+	return r.db.Create(intent).Error
+}
+
+// FindByID finds an upload intent by ID
+func (r *uploadIntentRepository) FindByID(id uuid.UUID) (*models.UploadIntent, error) {
+	var intent models.UploadIntent
+	err := r.db.Where("id = ?", id).First(&intent).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("upload intent not found")
+		}
+		return nil, err
+	}
+	return &intent, nil
+}
+
+// Update updates an upload intent
+func (r *uploadIntentRepository) Update(intent *models.UploadIntent) error {
+	return r.db.Save(intent).Error
+}
+
+// Delete permanently deletes an upload intent
+func (r *uploadIntentRepository) Delete(id uuid.UUID) error {
+	return r.db.Unscoped().Delete(&models.UploadIntent{}, id).Error
+}
+
+// ListExpired lists upload intents that expired before the given time
+func (r *uploadIntentRepository) ListExpired(before time.Time) ([]models.UploadIntent, error) {
+	var intents []models.UploadIntent
+	err := r.db.Where("expires_at < ?", before).Find(&intents).Error
+	return intents, err
+}
@@ -0,0 +1,119 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/Kyei-Ernest/libsystem/shared/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrUploadNotFound is returned for an upload session that does not exist
+var ErrUploadNotFound = errors.New("upload session not found")
+
+// UploadRepository defines the interface for resumable upload session data access
+type UploadRepository interface {
+	Create(session *models.UploadSession) error
+	FindByID(id uuid.UUID) (*models.UploadSession, error)
+	Update(session *models.UploadSession) error
+	Delete(id uuid.UUID) error
+	ListExpired(before time.Time) ([]models.UploadSession, error)
+	Lock(id uuid.UUID, fn func(session *models.UploadSession) error) error
+	DeleteLocked(id uuid.UUID, fn func(session *models.UploadSession) (bool, error)) (bool, error)
+}
+
+// uploadRepository implements UploadRepository using GORM
+type uploadRepository struct {
+	db *gorm.DB
+}
+
+// NewUploadRepository creates a new upload session repository
+func NewUploadRepository(db *gorm.DB) UploadRepository {
+	return &uploadRepository{db: db}
+}
+
+// Create creates a new upload session
+func (r *uploadRepository) Create(session *models.UploadSession) error {
+	return r.db.Create(session).Error
+}
+
+// FindByID finds an upload session by ID
+func (r *uploadRepository) FindByID(id uuid.UUID) (*models.UploadSession, error) {
+	var session models.UploadSession
+	err := r.db.Where("id = ?", id).First(&session).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUploadNotFound
+		}
+		return nil, err
+	}
+	return &session, nil
+}
+
+// Update updates an upload session
+func (r *uploadRepository) Update(session *models.UploadSession) error {
+	return r.db.Save(session).Error
+}
+
+// Delete permanently deletes an upload session
+func (r *uploadRepository) Delete(id uuid.UUID) error {
+	return r.db.Unscoped().Delete(&models.UploadSession{}, id).Error
+}
+
+// ListExpired lists upload sessions that expired before the given time
+func (r *uploadRepository) ListExpired(before time.Time) ([]models.UploadSession, error) {
+	var sessions []models.UploadSession
+	err := r.db.Where("expires_at < ?", before).Find(&sessions).Error
+	return sessions, err
+}
+
+// Lock runs fn on an upload session while holding its row lock and saves the session
+// unless fn fails. Requests for the same upload, on any replica, run one at a time.
+func (r *uploadRepository) Lock(id uuid.UUID, fn func(session *models.UploadSession) error) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		session, err := lockUpload(tx, id)
+		if err != nil {
+			return err
+		}
+		if err := fn(session); err != nil {
+			return err
+		}
+		return tx.Save(session).Error
+	})
+}
+
+// DeleteLocked permanently deletes an upload session while holding its row lock, provided
+// fn agrees, so that no request for it is in flight. Reports whether it was deleted.
+func (r *uploadRepository) DeleteLocked(id uuid.UUID, fn func(session *models.UploadSession) (bool, error)) (bool, error) {
+	deleted := false
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		session, err := lockUpload(tx, id)
+		if err != nil {
+			return err
+		}
+		if deleted, err = fn(session); err != nil || !deleted {
+			return err
+		}
+		return tx.Unscoped().Delete(&models.UploadSession{}, id).Error
+	})
+	if err != nil {
+		return false, err
+	}
+	return deleted, nil
+}
+
+// lockUpload loads an upload session with its row locked for update
+func lockUpload(tx *gorm.DB, id uuid.UUID) (*models.UploadSession, error) {
+	var session models.UploadSession
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id = ?", id).
+		First(&session).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUploadNotFound
+		}
+		return nil, err
+	}
+	return &session, nil
+}
@@ -1,6 +1,8 @@
 package repository
 
 import (
+	"errors"
+
 	"github.com/Kyei-Ernest/libsystem/shared/models"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
@@ -11,6 +13,9 @@ type VersionRepository interface {
 	Create(version *models.DocumentVersion) error
 	GetByID(id uuid.UUID) (*models.DocumentVersion, error)
 	GetByDocumentID(documentID uuid.UUID) ([]models.DocumentVersion, error)
+	NextVersionNumber(documentID uuid.UUID) (int, error)
+	SetExtractedText(documentID uuid.UUID, hash, text string) error
+	SetThumbnail(documentID uuid.UUID, hash, thumbnailPath string) error
 	Delete(id uuid.UUID) error
 }
 
@@ -32,6 +37,9 @@ func (r *versionRepository) Create(version *models.DocumentVersion) error {
 func (r *versionRepository) GetByID(id uuid.UUID) (*models.DocumentVersion, error) {
 	var version models.DocumentVersion
 	if err := r.db.Where("id = ?", id).First(&version).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("version not found")
+		}
 		return nil, err
 	}
 	return &version, nil
@@ -48,7 +56,33 @@ func (r *versionRepository) GetByDocumentID(documentID uuid.UUID) ([]models.Docu
 	return versions, nil
 }
 
-// Delete deletes a version
+// NextVersionNumber returns the number for a new version of a document
+// Numbers of deleted versions are never reused
+func (r *versionRepository) NextVersionNumber(documentID uuid.UUID) (int, error) {
+	var max int
+	err := r.db.Unscoped().Model(&models.DocumentVersion{}).
+		Where("document_id = ?", documentID).
+		Select("COALESCE(MAX(version_number), 0)").
+		Scan(&max).Error
+	return max + 1, err
+}
+
+// SetExtractedText stores the extracted text on the document's versions with the given content hash
+func (r *versionRepository) SetExtractedText(documentID uuid.UUID, hash, text string) error {
+	return r.db.Model(&models.DocumentVersion{}).
+		Where("document_id = ? AND hash = ?", documentID, hash).
+		Update("extracted_text", text).Error
+}
+
+// SetThumbnail records the thumbnail of the document's versions with the given content
+// hash that have none
+func (r *versionRepository) SetThumbnail(documentID uuid.UUID, hash, thumbnailPath string) error {
+	return r.db.Model(&models.DocumentVersion{}).
+		Where("document_id = ? AND hash = ? AND COALESCE(thumbnail_path, '') = ''", documentID, hash).
+		Update("thumbnail_path", thumbnailPath).Error
+}
+
+// Delete permanently deletes a version
 func (r *versionRepository) Delete(id uuid.UUID) error {
-	return r.db.Where("id = ?", id).Delete(&models.DocumentVersion{}).Error
+	return r.db.Unscoped().Where("id = ?", id).Delete(&models.DocumentVersion{}).Error
 }
@@ -0,0 +1,370 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/Kyei-Ernest/libsystem/services/document-service/repository"
+	appErrors "github.com/Kyei-Ernest/libsystem/shared/errors"
+	"github.com/Kyei-Ernest/libsystem/shared/models"
+	"github.com/google/uuid"
+)
+
+const (
+	maxAnnotationBody  = 10000 // Characters in a note
+	maxAnnotationQuote = 5000  // Characters in a quoted passage
+)
+
+// AnnotationInput describes a new annotation
+type AnnotationInput struct {
+	Target     models.AnnotationTarget     `json:"target"`
+	Body       string                      `json:"body,omitempty"`
+	Visibility models.AnnotationVisibility `json:"visibility,omitempty"` // Private when omitted
+	VersionID  *uuid.UUID                  `json:"version_id,omitempty"` // Version annotated; the current content when omitted
+}
+
+// AnnotationUpdate represents the fields of an annotation that can be changed
+type AnnotationUpdate struct {
+	Body       *string                      `json:"body,omitempty"`
+	Visibility *models.AnnotationVisibility `json:"visibility,omitempty"`
+}
+
+// AnnotationService manages highlights and notes on documents. Annotations are anchored to
+// the content they were made on, so a document lists the annotations of its current
+// content, and each version those of its own.
+type AnnotationService interface {
+	ListDocumentAnnotations(documentID uuid.UUID, versionID, userID *uuid.UUID, page, pageSize int) ([]models.Annotation, int64, error)
+	ListOwnAnnotations(userID uuid.UUID, documentID *uuid.UUID, search string, page, pageSize int) ([]models.Annotation, int64, error)
+	CreateAnnotation(documentID uuid.UUID, input AnnotationInput, userID uuid.UUID) (*models.Annotation, error)
+	UpdateAnnotation(documentID, annotationID uuid.UUID, update AnnotationUpdate, userID uuid.UUID) (*models.Annotation, error)
+	DeleteAnnotation(documentID, annotationID, userID uuid.UUID) error
+}
+
+// annotationService implements AnnotationService
+type annotationService struct {
+	annotationRepo  repository.AnnotationRepository
+	versionRepo     repository.VersionRepository
+	documentService DocumentService
+}
+
+// NewAnnotationService creates a new annotation service
+func NewAnnotationService(annotationRepo repository.AnnotationRepository, versionRepo repository.VersionRepository, documentService DocumentService) AnnotationService {
+	return &annotationService{
+		annotationRepo:  annotationRepo,
+		versionRepo:     versionRepo,
+		documentService: documentService,
+	}
+}
+
+// ListDocumentAnnotations lists the annotations the user may see on a version of a
+// document, or on its current content
+func (s *annotationService) ListDocumentAnnotations(documentID uuid.UUID, versionID, userID *uuid.UUID, page, pageSize int) ([]models.Annotation, int64, error) {
+	// Annotations quote the content, which is withheld during an embargo
+	document, err := s.documentService.GetContentDocument(documentID, userID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	hash := document.Hash
+	if versionID != nil {
+		version, err := s.findVersion(documentID, *versionID)
+		if err != nil {
+			return nil, 0, err
+		}
+		hash = version.Hash
+	}
+
+	offset := (page - 1) * pageSize
+	annotations, total, err := s.annotationRepo.List(repository.AnnotationFilters{
+		DocumentID: &documentID,
+		Hash:       hash,
+		ViewerID:   userID,
+	}, offset, pageSize)
+	if err != nil {
+		return nil, 0, appErrors.NewInternalError("Failed to list annotations", err)
+	}
+
+	s.resolveVersions(annotations)
+	return annotations, total, nil
+}
+
+// ListOwnAnnotations lists and searches the user's own annotations, on every version
+func (s *annotationService) ListOwnAnnotations(userID uuid.UUID, documentID *uuid.UUID, search string, page, pageSize int) ([]models.Annotation, int64, error) {
+	offset := (page - 1) * pageSize
+	annotations, total, err := s.annotationRepo.List(repository.AnnotationFilters{
+		DocumentID: documentID,
+		CreatorID:  &userID,
+		ViewerID:   &userID,
+		Search:     strings.TrimSpace(search),
+	}, offset, pageSize)
+	if err != nil {
+		return nil, 0, appErrors.NewInternalError("Failed to list annotations", err)
+	}
+
+	s.resolveVersions(annotations)
+	return annotations, total, nil
+}
+
+// CreateAnnotation anchors a new annotation to a document the user may read
+func (s *annotationService) CreateAnnotation(documentID uuid.UUID, input AnnotationInput, userID uuid.UUID) (*models.Annotation, error) {
+	if input.Visibility == "" {
+		input.Visibility = models.AnnotationPrivate
+	}
+	if err := validateAnnotation(input.Target, input.Body, input.Visibility); err != nil {
+		return nil, err
+	}
+
+	document, err := s.documentService.GetContentDocument(documentID, &userID)
+	if err != nil {
+		return nil, err
+	}
+
+	annotation := &models.Annotation{
+		DocumentID: documentID,
+		Hash:       document.Hash,
+		CreatorID:  userID,
+		Visibility: input.Visibility,
+		Target:     input.Target,
+		Body:       strings.TrimSpace(input.Body),
+	}
+	if input.VersionID != nil {
+		version, err := s.findVersion(documentID, *input.VersionID)
+		if err != nil {
+			return nil, err
+		}
+		annotation.Hash = version.Hash
+	}
+
+	if err := s.annotationRepo.Create(annotation); err != nil {
+		return nil, appErrors.NewInternalError("Failed to create annotation", err)
+	}
+
+	return s.get(annotation.ID)
+}
+
+// UpdateAnnotation changes the note or visibility of the user's own annotation
+func (s *annotationService) UpdateAnnotation(documentID, annotationID uuid.UUID, update AnnotationUpdate, userID uuid.UUID) (*models.Annotation, error) {
+	annotation, err := s.find(documentID, annotationID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if annotation.CreatorID != userID {
+		return nil, appErrors.NewForbiddenError("Only the creator can edit an annotation", nil)
+	}
+
+	if update.Body != nil {
+		annotation.Body = strings.TrimSpace(*update.Body)
+	}
+	if update.Visibility != nil {
+		annotation.Visibility = *update.Visibility
+	}
+	if err := validateAnnotation(annotation.Target, annotation.Body, annotation.Visibility); err != nil {
+		return nil, err
+	}
+
+	if err := s.annotationRepo.Update(annotation); err != nil {
+		return nil, appErrors.NewInternalError("Failed to update annotation", err)
+	}
+	return s.get(annotationID)
+}
+
+// DeleteAnnotation deletes an annotation. Besides its creator, the document's uploader and
+// collection owner may remove annotations others can see.
+func (s *annotationService) DeleteAnnotation(documentID, annotationID, userID uuid.UUID) error {
+	annotation, err := s.find(documentID, annotationID, userID)
+	if err != nil {
+		return err
+	}
+
+	if annotation.CreatorID != userID {
+		document, err := s.documentService.GetDocument(documentID, &userID)
+		if err != nil {
+			return err
+		}
+		if !managesDocument(document, &userID) {
+			return appErrors.NewForbiddenError("Only the creator can delete this annotation", nil)
+		}
+	}
+
+	if err := s.annotationRepo.Delete(annotationID); err != nil {
+		return appErrors.NewInternalError("Failed to delete annotation", err)
+	}
+	return nil
+}
+
+// find retrieves an annotation and checks it belongs to the document; others' private
+// annotations are not found
+func (s *annotationService) find(documentID, annotationID, userID uuid.UUID) (*models.Annotation, error) {
+	annotation, err := s.annotationRepo.FindByID(annotationID)
+	if err != nil {
+		return nil, appErrors.NewNotFoundError("Annotation", err)
+	}
+	if annotation.DocumentID != documentID {
+		return nil, appErrors.NewNotFoundError("Annotation",
+			fmt.Errorf("annotation %s does not belong to document %s", annotationID, documentID))
+	}
+	if annotation.Visibility == models.AnnotationPrivate && annotation.CreatorID != userID {
+		return nil, appErrors.NewNotFoundError("Annotation", fmt.Errorf("annotation %s is private", annotationID))
+	}
+	return annotation, nil
+}
+
+// get reloads an annotation with its version resolved
+func (s *annotationService) get(id uuid.UUID) (*models.Annotation, error) {
+	annotation, err := s.annotationRepo.FindByID(id)
+	if err != nil {
+		return nil, appErrors.NewNotFoundError("Annotation", err)
+	}
+	annotations := []models.Annotation{*annotation}
+	s.resolveVersions(annotations)
+	return &annotations[0], nil
+}
+
+// findVersion retrieves a version and checks it belongs to the document
+func (s *annotationService) findVersion(documentID, versionID uuid.UUID) (*models.DocumentVersion, error) {
+	version, err := s.versionRepo.GetByID(versionID)
+	if err != nil {
+		return nil, appErrors.NewNotFoundError("Version", err)
+	}
+	if version.DocumentID != documentID {
+		return nil, appErrors.NewNotFoundError("Version", fmt.Errorf("version %s does not belong to document %s", versionID, documentID))
+	}
+	return version, nil
+}
+
+// resolveVersions sets the version each annotation was made on: the newest version with
+// its content. Annotations on content that predates versioning have none.
+func (s *annotationService) resolveVersions(annotations []models.Annotation) {
+	versionsByDocument := make(map[uuid.UUID]map[string]uuid.UUID)
+	for i := range annotations {
+		annotation := &annotations[i]
+		byHash, loaded := versionsByDocument[annotation.DocumentID]
+		if !loaded {
+			byHash = make(map[string]uuid.UUID)
+			versions, err := s.versionRepo.GetByDocumentID(annotation.DocumentID)
+			if err != nil {
+				fmt.Printf("DEBUG: Failed to load versions of document %s: %v\n", annotation.DocumentID, err)
+			}
+			// Newest first; the first version with a hash wins
+			for _, version := range versions {
+				if _, seen := byHash[version.Hash]; !seen {
+					byHash[version.Hash] = version.ID
+				}
+			}
+			versionsByDocument[annotation.DocumentID] = byHash
+		}
+		if versionID, ok := byHash[annotation.Hash]; ok {
+			annotation.VersionID = &versionID
+		}
+	}
+}
+
+// validateAnnotation checks an annotation's anchors, note and visibility
+func validateAnnotation(target models.AnnotationTarget, body string, visibility models.AnnotationVisibility) error {
+	switch visibility {
+	case models.AnnotationPrivate, models.AnnotationCollection, models.AnnotationPublic:
+	default:
+		return appErrors.NewValidationError(fmt.Sprintf("Unknown visibility %q", visibility), nil)
+	}
+
+	if target.TextQuote == nil && target.TextPosition == nil {
+		return appErrors.NewValidationError("target needs a text_quote or text_position selector", nil)
+	}
+	if target.Page != nil && *target.Page < 1 {
+		return appErrors.NewValidationError("target page must be 1 or more", nil)
+	}
+	if quote := target.TextQuote; quote != nil {
+		if strings.TrimSpace(quote.Exact) == "" {
+			return appErrors.NewValidationError("text_quote needs the exact passage", nil)
+		}
+		if utf8.RuneCountInString(quote.Exact+quote.Prefix+quote.Suffix) > maxAnnotationQuote {
+			return appErrors.NewValidationError(fmt.Sprintf("text_quote is limited to %d characters", maxAnnotationQuote), nil)
+		}
+	}
+	if position := target.TextPosition; position != nil && (position.Start < 0 || position.End <= position.Start) {
+		return appErrors.NewValidationError("text_position needs 0 <= start < end", nil)
+	}
+
+	if utf8.RuneCountInString(body) > maxAnnotationBody {
+		return appErrors.NewValidationError(fmt.Sprintf("Notes are limited to %d characters", maxAnnotationBody), nil)
+	}
+	return nil
+}
+
+// AnnotationsJSONLD renders annotations as a W3C Web Annotation collection (JSON-LD).
+// Documents, versions and users are identified by URN, as the services have no stable
+// public IRIs.
+func AnnotationsJSONLD(annotations []models.Annotation, label string) map[string]interface{} {
+	items := make([]map[string]interface{}, 0, len(annotations))
+	for i := range annotations {
+		items = append(items, annotationJSONLD(&annotations[i]))
+	}
+	return map[string]interface{}{
+		"@context": "http://www.w3.org/ns/anno.jsonld",
+		"type":     "AnnotationCollection",
+		"label":    label,
+		"total":    len(items),
+		"first": map[string]interface{}{
+			"type":  "AnnotationPage",
+			"items": items,
+		},
+	}
+}
+
+func annotationJSONLD(annotation *models.Annotation) map[string]interface{} {
+	// Text selectors are alternatives; on paged content each refines the page
+	var selectors []map[string]interface{}
+	if quote := annotation.Target.TextQuote; quote != nil {
+		selector := map[string]interface{}{"type": "TextQuoteSelector", "exact": quote.Exact}
+		if quote.Prefix != "" {
+			selector["prefix"] = quote.Prefix
+		}
+		if quote.Suffix != "" {
+			selector["suffix"] = quote.Suffix
+		}
+		selectors = append(selectors, selector)
+	}
+	if position := annotation.Target.TextPosition; position != nil {
+		selectors = append(selectors, map[string]interface{}{
+			"type": "TextPositionSelector", "start": position.Start, "end": position.End,
+		})
+	}
+	if page := annotation.Target.Page; page != nil {
+		for i, selector := range selectors {
+			selectors[i] = map[string]interface{}{
+				"type":       "FragmentSelector",
+				"conformsTo": "http://tools.ietf.org/rfc/rfc3778",
+				"value":      fmt.Sprintf("page=%d", *page),
+				"refinedBy":  selector,
+			}
+		}
+	}
+
+	source := "urn:uuid:" + annotation.DocumentID.String()
+	if annotation.VersionID != nil {
+		source = "urn:uuid:" + annotation.VersionID.String()
+	}
+	item := map[string]interface{}{
+		"id":         "urn:uuid:" + annotation.ID.String(),
+		"type":       "Annotation",
+		"motivation": annotation.Motivation(),
+		"created":    annotation.CreatedAt.UTC().Format(time.RFC3339),
+		"modified":   annotation.UpdatedAt.UTC().Format(time.RFC3339),
+		"creator":    map[string]interface{}{"id": "urn:uuid:" + annotation.CreatorID.String(), "type": "Person"},
+		"target": map[string]interface{}{
+			"source":   source,
+			"selector": selectors,
+		},
+	}
+	if annotation.Body != "" {
+		item["body"] = map[string]interface{}{
+			"type":    "TextualBody",
+			"value":   annotation.Body,
+			"format":  "text/plain",
+			"purpose": "commenting",
+		}
+	}
+	return item
+}
@@ -0,0 +1,65 @@
+package service
+
+import (
+	"time"
+
+	"github.com/Kyei-Ernest/libsystem/services/document-service/repository"
+)
+
+// availabilityBatchSize is the number of due documents settled per query
+const availabilityBatchSize = 100
+
+// AvailabilityService moves documents across the boundaries of their availability windows:
+// embargoed documents are published once their embargo lifts and documents past their end
+// expire. Access checks compare against the clock themselves, so a late run only delays
+// status changes and events, never exposes content.
+type AvailabilityService interface {
+	ApplyDue(now time.Time) (int, error)
+}
+
+// availabilityService implements AvailabilityService
+type availabilityService struct {
+	documentRepo    repository.DocumentRepository
+	documentService DocumentService
+}
+
+// NewAvailabilityService creates a new availability service
+func NewAvailabilityService(documentRepo repository.DocumentRepository, documentService DocumentService) AvailabilityService {
+	return &availabilityService{
+		documentRepo:    documentRepo,
+		documentService: documentService,
+	}
+}
+
+// ApplyDue settles every document whose status no longer matches its availability window
+// at now, returning how many were considered
+func (s *availabilityService) ApplyDue(now time.Time) (int, error) {
+	settled := 0
+	seen := make(map[string]bool)
+
+	for {
+		documents, err := s.documentRepo.ListAvailabilityDue(now, availabilityBatchSize)
+		if err != nil {
+			return settled, err
+		}
+
+		progressed := false
+		for i := range documents {
+			id := documents[i].ID.String()
+			if seen[id] {
+				continue // Still due after settling; leave it for the next run
+			}
+			seen[id] = true
+			progressed = true
+
+			if err := s.documentService.SettleAvailability(documents[i].ID, now); err != nil {
+				return settled, err
+			}
+			settled++
+		}
+
+		if len(documents) < availabilityBatchSize || !progressed {
+			return settled, nil
+		}
+	}
+}
@@ -0,0 +1,327 @@
+package service
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"mime"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	appErrors "github.com/Kyei-Ernest/libsystem/shared/errors"
+)
+
+// sniffLen is how much of an upload is inspected to detect its content type. It covers
+// the first local file headers of OOXML and EPUB containers.
+const sniffLen = 8 << 10
+
+const (
+	mimePDF         = "application/pdf"
+	mimeDOC         = "application/msword"
+	mimeDOCX        = "application/vnd.openxmlformats-officedocument.wordprocessingml.document"
+	mimeEPUB        = "application/epub+zip"
+	mimeHTML        = "text/html"
+	mimeText        = "text/plain"
+	mimeZIP         = "application/zip"
+	mimeOLE         = "application/x-ole-storage"
+	mimeOctetStream = "application/octet-stream"
+)
+
+var (
+	oleSignature = []byte{0xD0, 0xCF, 0x11, 0xE0, 0xA1, 0xB1, 0x1A, 0xE1}
+	zipSignature = []byte("PK\x03\x04")
+	// Name of the stream holding a Word 97-2003 document, as stored (UTF-16LE) in the
+	// compound file directory
+	wordDocumentStream = []byte("W\x00o\x00r\x00d\x00D\x00o\x00c\x00u\x00m\x00e\x00n\x00t\x00")
+)
+
+// mimeAliases maps non-canonical MIME types clients send to the ones recorded
+var mimeAliases = map[string]string{
+	"application/x-pdf":            mimePDF,
+	"text/htm":                     mimeHTML,
+	"application/xhtml+xml":        mimeHTML,
+	"application/x-zip-compressed": mimeZIP,
+}
+
+// mimeExtensions gives the extension a detected type is stored under. The indexer picks
+// its extractor from the storage path.
+var mimeExtensions = map[string]string{
+	mimePDF:  ".pdf",
+	mimeDOC:  ".doc",
+	mimeDOCX: ".docx",
+	mimeEPUB: ".epub",
+	mimeHTML: ".html",
+	mimeText: ".txt",
+}
+
+// baseMediaType returns the lower-cased media type without parameters, resolving aliases
+func baseMediaType(contentType string) string {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType = strings.ToLower(strings.TrimSpace(strings.SplitN(contentType, ";", 2)[0]))
+	}
+	if canonical, ok := mimeAliases[mediaType]; ok {
+		return canonical
+	}
+	return mediaType
+}
+
+// undeclaredType reports whether a declared content type says nothing about the file, so
+// the type detected from its content is used instead
+func undeclaredType(contentType string) bool {
+	base := baseMediaType(contentType)
+	return base == "" || base == mimeOctetStream
+}
+
+// detectContentType identifies an upload from its leading bytes. Text types carry a
+// charset parameter. ZIP and OLE containers that cannot be told apart from the head alone
+// are reported as mimeZIP and mimeOLE; refineContainerType settles them from the full file.
+func detectContentType(head []byte) string {
+	// Readers tolerate junk before the header, so the signature need not be at offset 0
+	if bytes.Contains(head[:min(len(head), 1024)], []byte("%PDF-")) {
+		return mimePDF
+	}
+	if bytes.HasPrefix(head, oleSignature) {
+		if bytes.Contains(head, wordDocumentStream) {
+			return mimeDOC
+		}
+		return mimeOLE
+	}
+	if bytes.HasPrefix(head, zipSignature) {
+		return detectZipHead(head)
+	}
+
+	charset, ok := detectTextEncoding(head)
+	if !ok {
+		return mimeOctetStream
+	}
+	mediaType := mimeText
+	if charset != "utf-16le" && charset != "utf-16be" && looksLikeHTML(head) {
+		mediaType = mimeHTML
+	}
+	return mime.FormatMediaType(mediaType, map[string]string{"charset": charset})
+}
+
+// detectZipHead classifies a ZIP container from its first local file headers. EPUB must
+// store an uncompressed "mimetype" entry first; Word documents have parts under word/.
+func detectZipHead(head []byte) string {
+	const nameOffset = 30 // size of the fixed part of a local file header
+	if len(head) > nameOffset+8 && string(head[nameOffset:nameOffset+8]) == "mimetype" &&
+		bytes.Contains(head[nameOffset:min(len(head), 128)], []byte(mimeEPUB)) {
+		return mimeEPUB
+	}
+	if bytes.Contains(head, []byte("word/")) {
+		return mimeDOCX
+	}
+	return mimeZIP
+}
+
+// detectTextEncoding reports whether head is text and in which encoding. Text that is
+// neither UTF-8 nor UTF-16 is accepted as ISO-8859-1 when it has no control characters.
+func detectTextEncoding(head []byte) (string, bool) {
+	switch {
+	case bytes.HasPrefix(head, []byte{0xEF, 0xBB, 0xBF}):
+		return "utf-8", true
+	case bytes.HasPrefix(head, []byte{0xFF, 0xFE}):
+		return "utf-16le", true
+	case bytes.HasPrefix(head, []byte{0xFE, 0xFF}):
+		return "utf-16be", true
+	case len(head) == 0:
+		return "", false
+	}
+
+	for _, b := range head {
+		if b < 0x20 && b != '\t' && b != '\n' && b != '\r' && b != '\f' && b != 0x1B {
+			return "", false
+		}
+	}
+
+	// The head may end part-way through a multi-byte sequence
+	text := head
+	for i := len(text) - 1; i >= 0 && i >= len(text)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(text[i]) {
+			if !utf8.FullRune(text[i:]) {
+				text = text[:i]
+			}
+			break
+		}
+	}
+	if utf8.Valid(text) {
+		return "utf-8", true
+	}
+	return "iso-8859-1", true
+}
+
+// looksLikeHTML reports whether text starts with markup identifying an HTML document
+func looksLikeHTML(head []byte) bool {
+	text := strings.ToLower(strings.TrimLeft(string(bytes.TrimPrefix(head, []byte{0xEF, 0xBB, 0xBF})), " \t\r\n\f"))
+	for _, prefix := range []string{"<!doctype html", "<html", "<head", "<body", "<!--", "<?xml"} {
+		if strings.HasPrefix(text, prefix) {
+			if prefix == "<?xml" || prefix == "<!--" {
+				return strings.Contains(text, "<html")
+			}
+			return true
+		}
+	}
+	return false
+}
+
+// resolveContentType cross-checks the declared content type against the detected one and
+// returns the type to record. Undeclared and generic types take the detected type, and so
+// do close relatives (text subtypes, Word formats); any other disagreement is rejected.
+func resolveContentType(declared, detected string) (string, error) {
+	detectedBase := baseMediaType(detected)
+	declaredBase := baseMediaType(declared)
+
+	if detectedBase == mimeOctetStream {
+		return "", appErrors.NewValidationError("File content is not a recognised document type", nil)
+	}
+	undeclared := undeclaredType(declared)
+
+	// Containers the head could not classify are confirmed by refineContainerType once
+	// the whole file has been received
+	switch detectedBase {
+	case mimeOLE:
+		if undeclared || isWordType(declaredBase) {
+			return mimeDOC, nil
+		}
+	case mimeZIP:
+		if declaredBase == mimeDOCX || declaredBase == mimeEPUB {
+			return declaredBase, nil
+		}
+		if undeclared {
+			return mimeDOCX, nil
+		}
+	}
+
+	switch {
+	case undeclared || declaredBase == detectedBase:
+		return detected, nil
+	case strings.HasPrefix(declaredBase, "text/") && strings.HasPrefix(detectedBase, "text/"):
+		// Markup detection only looks at the start; keep the declared subtype
+		_, params, _ := mime.ParseMediaType(detected)
+		return mime.FormatMediaType(declaredBase, params), nil
+	case isWordType(declaredBase) && isWordType(detectedBase):
+		return detected, nil
+	}
+
+	return "", appErrors.NewValidationError(
+		fmt.Sprintf("File content does not match its declared type: declared %s, detected %s", declaredBase, detectedBase),
+		nil,
+	)
+}
+
+func isWordType(mediaType string) bool {
+	return mediaType == mimeDOC || mediaType == mimeDOCX
+}
+
+// needsContainerCheck reports whether a content type is a container format whose head
+// alone does not prove what it holds
+func needsContainerCheck(contentType string) bool {
+	switch baseMediaType(contentType) {
+	case mimeDOCX, mimeEPUB, mimeDOC:
+		return true
+	}
+	return false
+}
+
+// refineContainerType inspects a received ZIP or OLE container in full and returns the
+// document type it holds, or mimeOctetStream when it holds none that is accepted
+func refineContainerType(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	signature := make([]byte, len(oleSignature))
+	if _, err := io.ReadFull(f, signature); err != nil {
+		return mimeOctetStream, nil
+	}
+
+	if bytes.Equal(signature, oleSignature) {
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			return "", err
+		}
+		found, err := containsBytes(f, wordDocumentStream)
+		if err != nil {
+			return "", err
+		}
+		if found {
+			return mimeDOC, nil
+		}
+		return mimeOctetStream, nil
+	}
+
+	archive, err := zip.OpenReader(path)
+	if err != nil {
+		return mimeOctetStream, nil
+	}
+	defer archive.Close()
+
+	entries := make(map[string]*zip.File, len(archive.File))
+	for _, entry := range archive.File {
+		entries[entry.Name] = entry
+	}
+
+	if entry, ok := entries["mimetype"]; ok {
+		if _, hasContainer := entries["META-INF/container.xml"]; hasContainer && zipEntryEquals(entry, mimeEPUB) {
+			return mimeEPUB, nil
+		}
+	}
+	if _, ok := entries["word/document.xml"]; ok {
+		if _, hasTypes := entries["[Content_Types].xml"]; hasTypes {
+			return mimeDOCX, nil
+		}
+	}
+	return mimeOctetStream, nil
+}
+
+// zipEntryEquals reports whether a small archive entry holds exactly want
+func zipEntryEquals(entry *zip.File, want string) bool {
+	rc, err := entry.Open()
+	if err != nil {
+		return false
+	}
+	defer rc.Close()
+
+	data, err := io.ReadAll(io.LimitReader(rc, int64(len(want))+1))
+	return err == nil && strings.TrimSpace(string(data)) == want
+}
+
+// containsBytes scans r for needle without loading it whole
+func containsBytes(r io.Reader, needle []byte) (bool, error) {
+	br := bufio.NewReaderSize(r, 64<<10)
+	window := make([]byte, 0, 64<<10+len(needle))
+	chunk := make([]byte, 64<<10)
+	for {
+		n, err := br.Read(chunk)
+		window = append(window, chunk[:n]...)
+		if bytes.Contains(window, needle) {
+			return true, nil
+		}
+		// Keep enough of the tail to match a needle split across reads
+		if keep := len(needle) - 1; len(window) > keep {
+			window = append(window[:0], window[len(window)-keep:]...)
+		}
+		if err == io.EOF {
+			return false, nil
+		}
+		if err != nil {
+			return false, err
+		}
+	}
+}
+
+// extensionForType returns the file extension for a recorded content type, falling back
+// to the filename's own extension
+func extensionForType(contentType, filenameExt string) string {
+	ext, ok := mimeExtensions[baseMediaType(contentType)]
+	if !ok || filenameExt == ext || (ext == ".html" && filenameExt == ".htm") {
+		return filenameExt
+	}
+	return ext
+}
@@ -0,0 +1,278 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	appErrors "github.com/Kyei-Ernest/libsystem/shared/errors"
+)
+
+// Windows and Linux executables renamed to look like documents
+var (
+	peExecutable  = append([]byte("MZ\x90\x00\x03\x00\x00\x00\x04\x00\x00\x00\xff\xff\x00\x00"), make([]byte, 64)...)
+	elfExecutable = append([]byte("\x7fELF\x02\x01\x01\x00"), make([]byte, 64)...)
+)
+
+type zipEntry struct {
+	name    string
+	content string
+	store   bool // uncompressed, as EPUB requires for its mimetype entry
+}
+
+func buildZip(t *testing.T, entries ...zipEntry) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for _, entry := range entries {
+		method := zip.Deflate
+		if entry.store {
+			method = zip.Store
+		}
+		f, err := w.CreateHeader(&zip.FileHeader{Name: entry.name, Method: method})
+		if err != nil {
+			t.Fatalf("create zip entry %s: %v", entry.name, err)
+		}
+		if _, err := f.Write([]byte(entry.content)); err != nil {
+			t.Fatalf("write zip entry %s: %v", entry.name, err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("close zip: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func docxFile(t *testing.T) []byte {
+	return buildZip(t,
+		zipEntry{name: "[Content_Types].xml", content: "<Types/>"},
+		zipEntry{name: "_rels/.rels", content: "<Relationships/>"},
+		zipEntry{name: "word/document.xml", content: "<w:document/>"},
+	)
+}
+
+func epubFile(t *testing.T) []byte {
+	return buildZip(t,
+		zipEntry{name: "mimetype", content: mimeEPUB, store: true},
+		zipEntry{name: "META-INF/container.xml", content: "<container/>"},
+		zipEntry{name: "OEBPS/content.opf", content: "<package/>"},
+	)
+}
+
+func plainZipFile(t *testing.T) []byte {
+	return buildZip(t, zipEntry{name: "notes.txt", content: "just some notes"})
+}
+
+// oleFile builds a compound file whose directory holds stream at offset (nil for none)
+func oleFile(size, offset int, stream []byte) []byte {
+	data := make([]byte, size)
+	copy(data, oleSignature)
+	if stream != nil {
+		copy(data[offset:], stream)
+	}
+	return data
+}
+
+func head(data []byte) []byte {
+	return data[:min(len(data), sniffLen)]
+}
+
+func TestDetectContentType(t *testing.T) {
+	// An even sniffLen ends on the first byte of a two-byte "é" after the leading "a"
+	cutUTF8 := []byte("a" + strings.Repeat("é", sniffLen))
+	// sniffLen ends two bytes into a three-byte "€"
+	cutUTF8Euro := []byte(strings.Repeat("€", sniffLen))
+
+	tests := []struct {
+		name string
+		head []byte
+		want string
+	}{
+		{"pdf", []byte("%PDF-1.7\n%\xe2\xe3\xcf\xd3\n1 0 obj"), mimePDF},
+		{"pdf after junk", []byte("\r\n\r\n%PDF-1.4\n"), mimePDF},
+		{"windows executable", peExecutable, mimeOctetStream},
+		{"linux executable", elfExecutable, mimeOctetStream},
+		{"empty", []byte{}, mimeOctetStream},
+		{"docx", head(docxFile(t)), mimeDOCX},
+		{"epub", head(epubFile(t)), mimeEPUB},
+		{"plain zip", head(plainZipFile(t)), mimeZIP},
+		{"word 97 document", head(oleFile(4096, 1024, wordDocumentStream)), mimeDOC},
+		{"ole without WordDocument stream", head(oleFile(4096, 0, nil)), mimeOLE},
+		{"ole with WordDocument stream past the head", head(oleFile(sniffLen*2, sniffLen+512, wordDocumentStream)), mimeOLE},
+		{"utf-8 text", []byte("Chapter one\nIt was a dark night."), "text/plain; charset=utf-8"},
+		{"utf-8 with bom", []byte("\xef\xbb\xbfCaf\xc3\xa9"), "text/plain; charset=utf-8"},
+		{"utf-8 cut inside a two-byte character", head(cutUTF8), "text/plain; charset=utf-8"},
+		{"utf-8 cut inside a three-byte character", head(cutUTF8Euro), "text/plain; charset=utf-8"},
+		{"utf-16le", []byte("\xff\xfeH\x00i\x00"), "text/plain; charset=utf-16le"},
+		{"utf-16be", []byte("\xfe\xff\x00H\x00i"), "text/plain; charset=utf-16be"},
+		{"utf-16 markup is not sniffed as html", []byte("\xff\xfe<\x00h\x00t\x00m\x00l\x00>\x00"), "text/plain; charset=utf-16le"},
+		{"latin-1", []byte("Caf\xe9 cr\xe8me br\xfbl\xe9e"), "text/plain; charset=iso-8859-1"},
+		{"invalid utf-8 before the cut", append([]byte("ab\xc3"), []byte("cd")...), "text/plain; charset=iso-8859-1"},
+		{"html", []byte("<!DOCTYPE html>\n<html><body>Hi</body></html>"), "text/html; charset=utf-8"},
+		{"html after whitespace", []byte("\n\t  <HTML><head></head></HTML>"), "text/html; charset=utf-8"},
+		{"html behind xml declaration", []byte("<?xml version=\"1.0\"?>\n<html xmlns=\"http://www.w3.org/1999/xhtml\"></html>"), "text/html; charset=utf-8"},
+		{"xml that is not html", []byte("<?xml version=\"1.0\"?>\n<rss version=\"2.0\"></rss>"), "text/plain; charset=utf-8"},
+		{"html behind comment", []byte("<!-- generated -->\n<html><body></body></html>"), "text/html; charset=utf-8"},
+		{"comment without html", []byte("<!-- just a comment -->\nplain text"), "text/plain; charset=utf-8"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := detectContentType(tt.head); got != tt.want {
+				t.Errorf("detectContentType() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveContentType(t *testing.T) {
+	tests := []struct {
+		name     string
+		declared string
+		detected string
+		want     string
+		wantErr  bool
+	}{
+		{"matching pdf", mimePDF, mimePDF, mimePDF, false},
+		{"alias of the detected type", "application/x-pdf", mimePDF, mimePDF, false},
+		{"undeclared takes detected", "", "text/plain; charset=utf-8", "text/plain; charset=utf-8", false},
+		{"generic takes detected", mimeOctetStream, mimePDF, mimePDF, false},
+		{"declared with parameters", "application/pdf; name=a.pdf", mimePDF, mimePDF, false},
+		{"text subtype keeps declared", "text/html", "text/plain; charset=iso-8859-1", "text/html; charset=iso-8859-1", false},
+		{"doc declared as docx", mimeDOCX, mimeDOC, mimeDOC, false},
+		{"docx declared as doc", mimeDOC, mimeDOCX, mimeDOCX, false},
+		{"zip declared as docx", mimeDOCX, mimeZIP, mimeDOCX, false},
+		{"zip declared as epub", mimeEPUB, mimeZIP, mimeEPUB, false},
+		{"undeclared zip", "", mimeZIP, mimeDOCX, false},
+		{"ole declared as doc", mimeDOC, mimeOLE, mimeDOC, false},
+		{"undeclared ole", mimeOctetStream, mimeOLE, mimeDOC, false},
+
+		{"executable declared as pdf", mimePDF, mimeOctetStream, "", true},
+		{"executable declared as docx", mimeDOCX, mimeOctetStream, "", true},
+		{"undeclared executable", "", mimeOctetStream, "", true},
+		{"zip declared as pdf", mimePDF, mimeZIP, "", true},
+		{"ole declared as epub", mimeEPUB, mimeOLE, "", true},
+		{"html declared as pdf", mimePDF, "text/html; charset=utf-8", "", true},
+		{"pdf declared as text", mimeText, mimePDF, "", true},
+		{"epub declared as docx", mimeDOCX, mimeEPUB, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := resolveContentType(tt.declared, tt.detected)
+			if tt.wantErr {
+				var appErr *appErrors.AppError
+				if !errors.As(err, &appErr) || appErr.Code != appErrors.ErrCodeValidation {
+					t.Fatalf("resolveContentType() error = %v, want a validation error", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("resolveContentType() unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("resolveContentType() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRefineContainerType(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want string
+	}{
+		{"docx", docxFile(t), mimeDOCX},
+		{"docx without content types", buildZip(t, zipEntry{name: "word/document.xml", content: "<w:document/>"}), mimeOctetStream},
+		{"epub", epubFile(t), mimeEPUB},
+		{"epub with wrong mimetype", buildZip(t,
+			zipEntry{name: "mimetype", content: "application/zip", store: true},
+			zipEntry{name: "META-INF/container.xml", content: "<container/>"},
+		), mimeOctetStream},
+		{"epub without container", buildZip(t, zipEntry{name: "mimetype", content: mimeEPUB, store: true}), mimeOctetStream},
+		{"plain zip", plainZipFile(t), mimeOctetStream},
+		{"zip signature on a truncated archive", []byte("PK\x03\x04 and nothing else"), mimeOctetStream},
+		{"word 97 document", oleFile(4096, 1024, wordDocumentStream), mimeDOC},
+		{"WordDocument stream past the head", oleFile(256<<10, 200<<10, wordDocumentStream), mimeDOC},
+		{"ole without WordDocument stream", oleFile(256<<10, 0, nil), mimeOctetStream},
+		{"executable", peExecutable, mimeOctetStream},
+		{"shorter than a signature", []byte("PK"), mimeOctetStream},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "upload")
+			if err := os.WriteFile(path, tt.data, 0o600); err != nil {
+				t.Fatal(err)
+			}
+			got, err := refineContainerType(path)
+			if err != nil {
+				t.Fatalf("refineContainerType() unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("refineContainerType() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("missing file", func(t *testing.T) {
+		if _, err := refineContainerType(filepath.Join(t.TempDir(), "missing")); err == nil {
+			t.Error("refineContainerType() succeeded on a missing file")
+		}
+	})
+}
+
+func TestContainsBytes(t *testing.T) {
+	needle := wordDocumentStream
+	const chunk = 64 << 10
+	at := func(size, offset int) []byte {
+		data := make([]byte, size)
+		copy(data[offset:], needle)
+		return data
+	}
+
+	tests := []struct {
+		name    string
+		data    []byte
+		oneByte bool
+		want    bool
+	}{
+		{"at the start", at(1024, 0), false, true},
+		{"at the end", at(chunk*3, chunk*3-len(needle)), false, true},
+		{"split across reads", at(chunk*2, chunk-len(needle)/2), false, true},
+		{"single-byte reads", at(512, 100), true, true},
+		{"absent", make([]byte, chunk*2), false, false},
+		{"only a prefix at the end", at(chunk, chunk-len(needle)+2), false, false},
+		{"empty", nil, false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := bytes.NewReader(tt.data)
+			reader := iotest.HalfReader(r)
+			if tt.oneByte {
+				reader = iotest.OneByteReader(r)
+			}
+			got, err := containsBytes(reader, needle)
+			if err != nil {
+				t.Fatalf("containsBytes() unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("containsBytes() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("read error", func(t *testing.T) {
+		readErr := errors.New("disk gone")
+		if _, err := containsBytes(iotest.ErrReader(readErr), needle); !errors.Is(err, readErr) {
+			t.Errorf("containsBytes() error = %v, want %v", err, readErr)
+		}
+	})
+}
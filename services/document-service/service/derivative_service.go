@@ -0,0 +1,411 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Kyei-Ernest/libsystem/services/document-service/repository"
+	appErrors "github.com/Kyei-Ernest/libsystem/shared/errors"
+	"github.com/Kyei-Ernest/libsystem/shared/kafka"
+	"github.com/Kyei-Ernest/libsystem/shared/metadata"
+	"github.com/Kyei-Ernest/libsystem/shared/models"
+	"github.com/Kyei-Ernest/libsystem/shared/storage"
+	"github.com/google/uuid"
+)
+
+// DerivativeService produces what is derived from a document's content — thumbnail, page
+// count and embedded metadata, which pre-fills metadata fields the uploader left empty —
+// off the upload request path. New documents move from pending through processing to
+// active — or embargoed or expired, per their availability window — or to rejected when
+// their content is unusable.
+type DerivativeService interface {
+	HandleUploaded(payload []byte) error
+	Process(documentID uuid.UUID, hash string) error
+	RetryUnprocessed(before time.Time) (int, error)
+}
+
+type derivativeService struct {
+	documentRepo   repository.DocumentRepository
+	collectionRepo repository.CollectionRepository
+	versionRepo    repository.VersionRepository
+	blobRepo       repository.BlobRepository
+	storage        *storage.MinIOClient
+	thumbnailGen   *ThumbnailGenerator
+	extractors     *metadata.Registry
+	previews       PreviewService
+	producer       *kafka.Producer
+
+	documentService DocumentService
+}
+
+// NewDerivativeService creates a new derivative service. Office documents get their
+// preview rendition at ingest when previews is not nil.
+func NewDerivativeService(
+	documentRepo repository.DocumentRepository,
+	collectionRepo repository.CollectionRepository,
+	versionRepo repository.VersionRepository,
+	blobRepo repository.BlobRepository,
+	documentService DocumentService,
+	storage *storage.MinIOClient,
+	previews PreviewService,
+	producer *kafka.Producer,
+) DerivativeService {
+	return &derivativeService{
+		documentRepo:   documentRepo,
+		collectionRepo: collectionRepo,
+		versionRepo:    versionRepo,
+		blobRepo:       blobRepo,
+		storage:        storage,
+		thumbnailGen:   NewThumbnailGenerator(),
+		extractors:     metadata.DefaultRegistry(),
+		previews:       previews,
+		producer:       producer,
+
+		documentService: documentService,
+	}
+}
+
+// rejection is a processing failure caused by the content itself; retrying cannot help
+type rejection struct {
+	reason string
+}
+
+func (r *rejection) Error() string {
+	return r.reason
+}
+
+// HandleUploaded processes the content announced by a document.uploaded event
+func (s *derivativeService) HandleUploaded(payload []byte) error {
+	var event struct {
+		ID   uuid.UUID `json:"id"`
+		Hash string    `json:"hash"`
+	}
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return fmt.Errorf("invalid document.uploaded event: %w", err)
+	}
+	return s.Process(event.ID, event.Hash)
+}
+
+// Process generates the derivatives of a document's current content and settles the
+// status of a new document. Documents that are already active keep their status; a
+// failure is only recorded in their processing error.
+func (s *derivativeService) Process(documentID uuid.UUID, hash string) error {
+	document, err := s.documentRepo.FindByID(documentID)
+	if err != nil {
+		// Trashed or purged since the event was published
+		fmt.Printf("DEBUG: Skipping derivatives for document %s: %v\n", documentID, err)
+		return nil
+	}
+	// Replaced content is covered by the event for its replacement; submissions are
+	// processed once approved
+	if (hash != "" && document.Hash != hash) || underReview(document.Status) {
+		return nil
+	}
+
+	settling := document.Status == models.StatusPending || document.Status == models.StatusProcessing
+	if settling {
+		if _, err := s.documentRepo.TransitionStatus(document.ID,
+			[]models.DocumentStatus{models.StatusPending}, models.StatusProcessing); err != nil {
+			return fmt.Errorf("failed to mark document %s as processing: %w", document.ID, err)
+		}
+	}
+
+	derivatives, extracted, err := s.derive(document)
+	var rejected *rejection
+	if err != nil && !errors.As(err, &rejected) {
+		// Left in processing; RetryUnprocessed picks it up again
+		return fmt.Errorf("failed to process document %s: %w", document.ID, err)
+	}
+	if rejected != nil {
+		derivatives = repository.Derivatives{
+			ThumbnailPath:   document.ThumbnailPath,
+			PageCount:       derivatives.PageCount,
+			ProcessingError: rejected.reason,
+		}
+	}
+
+	if extracted != nil {
+		if err := s.applyMetadata(document, extracted); err != nil {
+			return fmt.Errorf("failed to store extracted metadata of document %s: %w", document.ID, err)
+		}
+	}
+
+	if err := s.documentRepo.SetDerivatives(document.ID, document.Hash, derivatives); err != nil {
+		return fmt.Errorf("failed to store derivatives of document %s: %w", document.ID, err)
+	}
+	if derivatives.ThumbnailPath != "" {
+		if err := s.versionRepo.SetThumbnail(document.ID, document.Hash, derivatives.ThumbnailPath); err != nil {
+			fmt.Printf("DEBUG: Failed to record thumbnail on versions of %s: %v\n", document.ID, err)
+		}
+	}
+
+	if !settling {
+		return nil
+	}
+	status := models.StatusRejected
+	listed := false
+	if rejected == nil {
+		// Documents under embargo or past their end wait in the matching status; reload in
+		// case the window was edited during processing
+		current, err := s.documentRepo.FindByID(document.ID)
+		if err != nil {
+			return nil // Trashed in the meantime
+		}
+		status = current.AvailabilityStatus(time.Now())
+		listed = status == models.StatusActive || (status == models.StatusEmbargoed && current.EmbargoShowMetadata)
+	}
+	moved, err := s.documentRepo.TransitionStatus(document.ID,
+		[]models.DocumentStatus{models.StatusProcessing}, status)
+	if err != nil {
+		return fmt.Errorf("failed to mark document %s as %s: %w", document.ID, status, err)
+	}
+	fmt.Printf("DEBUG: Document %s processed: %s\n", document.ID, status)
+	if !moved {
+		return nil
+	}
+	if rejected != nil {
+		s.publishRejected(document, rejected.reason)
+	}
+	// The upload was indexed as soon as it was accepted; take it out of search again
+	// unless its status lists it
+	if !listed {
+		s.documentService.RemoveFromSearch(document.ID)
+	}
+	return nil
+}
+
+// publishRejected announces that a document's content was rejected after its upload had
+// been accepted, so the uploader can be told why
+func (s *derivativeService) publishRejected(document *models.Document, reason string) {
+	if s.producer == nil {
+		return
+	}
+
+	event := map[string]interface{}{
+		"id":            document.ID,
+		"collection_id": document.CollectionID,
+		"uploader_id":   document.UploaderID,
+		"title":         document.Title,
+		"reason":        reason,
+		"occurred_at":   time.Now(),
+	}
+	if err := s.producer.PublishToTopic(context.Background(), "document.rejected", document.ID.String(), event); err != nil {
+		fmt.Printf("DEBUG: Failed to publish document.rejected event: %v\n", err)
+	}
+}
+
+// derive fetches a document's content and generates its derivatives. A failed thumbnail
+// is noted in the processing error without rejecting the document.
+func (s *derivativeService) derive(document *models.Document) (repository.Derivatives, *metadata.Result, error) {
+	var derivatives repository.Derivatives
+
+	localPath, err := s.fetch(document)
+	if err != nil {
+		return derivatives, nil, err
+	}
+	defer os.Remove(localPath)
+
+	extracted, err := s.extract(localPath, document.FileType)
+	if err != nil {
+		// Content the extractor cannot parse is still served as uploaded
+		fmt.Printf("DEBUG: Failed to extract metadata of %s: %v\n", document.ID, err)
+	}
+
+	if extracted != nil && extracted.PageCount > 0 {
+		derivatives.PageCount = extracted.PageCount
+
+		collection, err := s.collectionRepo.FindByID(document.CollectionID)
+		if err != nil {
+			return derivatives, nil, fmt.Errorf("failed to load collection: %w", err)
+		}
+		if err := checkPagePolicy(collection.Settings, extracted.PageCount); err != nil {
+			return derivatives, nil, &rejection{reason: errorReason(err)}
+		}
+	}
+
+	// Office documents are viewed as their PDF rendition. Rendering it now spares the first
+	// viewer the wait, and the thumbnail is taken from it.
+	thumbnailSource, thumbnailType := localPath, document.MimeType
+	var problems []string
+	if s.previews != nil && s.previews.NeedsPreview(document.MimeType) {
+		pdfPath, err := s.preview(document, localPath)
+		if err != nil {
+			fmt.Printf("DEBUG: Failed to generate preview for %s: %v\n", document.ID, err)
+			problems = append(problems, "Preview generation failed: "+errorReason(err))
+			thumbnailSource = ""
+		} else {
+			defer os.Remove(pdfPath)
+			thumbnailSource, thumbnailType = pdfPath, mimePDF
+		}
+	}
+
+	if thumbnailSource != "" {
+		thumbnailPath, err := s.thumbnail(document, thumbnailSource, thumbnailType)
+		if err != nil {
+			fmt.Printf("DEBUG: Failed to generate thumbnail for %s: %v\n", document.ID, err)
+			problems = append(problems, "Thumbnail generation failed: "+err.Error())
+		}
+		derivatives.ThumbnailPath = thumbnailPath
+	}
+	derivatives.ProcessingError = strings.Join(problems, "; ")
+
+	return derivatives, extracted, nil
+}
+
+// preview renders the PDF rendition of a document unless it is cached, and returns a
+// local copy of it
+func (s *derivativeService) preview(document *models.Document, localPath string) (string, error) {
+	key, err := s.previews.PreviewFromFile(document.Hash, localPath)
+	if err != nil {
+		return "", err
+	}
+	return s.download(key, ".pdf")
+}
+
+// extract reads the metadata embedded in a local copy of a document
+func (s *derivativeService) extract(path, fileType string) (*metadata.Result, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	return s.extractors.Extract(fileType, f, info.Size())
+}
+
+// applyMetadata pre-fills the document's metadata with extracted values. Fields users
+// supplied or edited are kept; see metadata.Merge.
+func (s *derivativeService) applyMetadata(document *models.Document, extracted *metadata.Result) error {
+	return s.documentRepo.EditDetails(document.ID, func(current *models.Document) error {
+		// The content was replaced while it was processed; its own event covers it
+		if current.Hash != document.Hash {
+			return nil
+		}
+		metadata.Merge(&current.Metadata, &extracted.Metadata, extracted.Source)
+		return nil
+	})
+}
+
+// fetch copies a document's stored content to a local temp file
+func (s *derivativeService) fetch(document *models.Document) (string, error) {
+	if s.storage == nil {
+		return "", errors.New("storage is not available")
+	}
+
+	exists, err := s.storage.FileExists(document.StoragePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to check stored file: %w", err)
+	}
+	if !exists {
+		return "", &rejection{reason: "The stored file is missing"}
+	}
+
+	return s.download(document.StoragePath, filepath.Ext(document.StoragePath))
+}
+
+// download copies a stored object to a local temp file
+func (s *derivativeService) download(objectName, ext string) (string, error) {
+	src, err := s.storage.DownloadFile(objectName)
+	if err != nil {
+		return "", fmt.Errorf("failed to download %s: %w", objectName, err)
+	}
+	defer src.Close()
+
+	dst, err := os.CreateTemp("", "derive-*"+ext)
+	if err != nil {
+		return "", fmt.Errorf("failed to create temp file: %w", err)
+	}
+	defer dst.Close()
+
+	if _, err := io.Copy(dst, src); err != nil {
+		os.Remove(dst.Name())
+		return "", fmt.Errorf("failed to download %s: %w", objectName, err)
+	}
+	return dst.Name(), nil
+}
+
+// thumbnail returns the thumbnail of a document's content, generating it from a local file
+// of the given type unless the blob already has one. Documents sharing the content share
+// the thumbnail.
+func (s *derivativeService) thumbnail(document *models.Document, localPath, mimeType string) (string, error) {
+	blob, err := s.blobRepo.FindByHash(document.Hash)
+	if err != nil {
+		return "", err
+	}
+	if blob.ThumbnailPath != "" {
+		return blob.ThumbnailPath, nil
+	}
+
+	generated, err := s.thumbnailGen.GenerateThumbnail(localPath, mimeType)
+	if errors.Is(err, ErrThumbnailUnsupported) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(generated)
+
+	f, err := os.Open(generated)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return "", err
+	}
+
+	key := blobKey(document.Hash, filepath.Ext(generated))
+	if err := s.storage.UploadFile(key, f, info.Size(), "image/png"); err != nil {
+		return "", err
+	}
+
+	// Another worker may have recorded a thumbnail for the same content first
+	recorded, err := s.blobRepo.SetThumbnail(document.Hash, key)
+	if err != nil || recorded != key {
+		if deleteErr := s.storage.DeleteFile(key); deleteErr != nil {
+			fmt.Printf("DEBUG: Failed to delete thumbnail %s: %v\n", key, deleteErr)
+		}
+	}
+	return recorded, err
+}
+
+// RetryUnprocessed processes documents whose processing has not finished by the given
+// time, e.g. because the event was lost or the worker stopped part-way
+func (s *derivativeService) RetryUnprocessed(before time.Time) (int, error) {
+	documents, err := s.documentRepo.ListUnprocessed(before, 100)
+	if err != nil {
+		return 0, err
+	}
+
+	processed := 0
+	for _, document := range documents {
+		if err := s.Process(document.ID, document.Hash); err != nil {
+			fmt.Printf("DEBUG: Retry failed: %v\n", err)
+			continue
+		}
+		processed++
+	}
+	return processed, nil
+}
+
+// errorReason returns the client-facing message of an application error
+func errorReason(err error) string {
+	var appErr *appErrors.AppError
+	if errors.As(err, &appErr) {
+		return appErr.Message
+	}
+	return err.Error()
+}
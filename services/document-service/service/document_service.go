@@ -3,18 +3,17 @@ package service
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"mime/multipart"
 	"os"
-	"os/exec"
-	"path/filepath"
-	"strings"
 	"time"
 
 	"github.com/Kyei-Ernest/libsystem/services/document-service/repository"
 	appErrors "github.com/Kyei-Ernest/libsystem/shared/errors"
 	"github.com/Kyei-Ernest/libsystem/shared/kafka"
+	docmeta "github.com/Kyei-Ernest/libsystem/shared/metadata"
 	"github.com/Kyei-Ernest/libsystem/shared/models"
 	"github.com/Kyei-Ernest/libsystem/shared/security"
 	"github.com/Kyei-Ernest/libsystem/shared/storage"
@@ -29,58 +28,168 @@ type UploadMetadata struct {
 	Title        string
 	Description  string
 	Metadata     *models.DocumentMetadata
+	Availability Availability
+}
+
+// Availability is the window in which a document is available to everyone; a nil bound
+// leaves that side open. EmbargoShowMetadata lists the document, without its content,
+// before AvailableFrom.
+type Availability struct {
+	AvailableFrom       *time.Time `json:"available_from"`
+	AvailableUntil      *time.Time `json:"available_until"`
+	EmbargoShowMetadata bool       `json:"embargo_show_metadata"`
+}
+
+// UploadFile describes a received file independently of the transport it arrived by
+// (multipart form, resumable upload, ...)
+type UploadFile struct {
+	Filename    string
+	ContentType string
+	Size        int64
+}
+
+// StoredFile describes a file accepted into permanent storage
+type StoredFile struct {
+	StoragePath   string
+	ThumbnailPath string
+	Hash          string
+	Size          int64
+	Filename      string
+	FileType      string
+	MimeType      string
 }
 
 // DocumentUpdate represents fields that can be updated
 type DocumentUpdate struct {
-	Title       *string
-	Description *string
-	Metadata    *models.DocumentMetadata
+	Title        *string
+	Description  *string
+	Metadata     *models.DocumentMetadata
+	Availability *Availability // Replaces the whole window
 }
 
 // DocumentService defines the interface for document management operations
 type DocumentService interface {
 	UploadDocument(file multipart.File, header *multipart.FileHeader, metadata UploadMetadata) (*models.Document, error)
+	IngestDocument(src io.Reader, file UploadFile, metadata UploadMetadata) (*models.Document, error)
+	IngestStagedDocument(stagingPath string, file UploadFile, metadata UploadMetadata) (*models.Document, error)
+	StageFile(src io.Reader, file UploadFile) (string, error)
+	DiscardStagedFile(stagingPath string)
+	StoreFile(src io.Reader, file UploadFile, collectionID uuid.UUID) (*StoredFile, error)
+	CheckUploadPolicy(file UploadFile, metadata UploadMetadata) error
+	DiscardStoredFile(stored *StoredFile)
+	RetainContent(hash string) error
+	ReleaseContent(hash string)
+	RequestReindex(id uuid.UUID) error
+	RemoveFromSearch(id uuid.UUID)
+	SetExtractedText(id uuid.UUID, hash, text string) error
 	GetDocument(id uuid.UUID, userID *uuid.UUID) (*models.Document, error)
+	GetContentDocument(id uuid.UUID, userID *uuid.UUID) (*models.Document, error)
+	SettleAvailability(id uuid.UUID, now time.Time) error
 	UpdateDocument(id uuid.UUID, updates DocumentUpdate, userID uuid.UUID) (*models.Document, error)
 	DeleteDocument(id uuid.UUID, userID uuid.UUID) error
 	ListDocuments(filters repository.DocumentFilters, page, pageSize int) ([]models.Document, int64, error)
-	CheckDuplicate(hash string) (*models.Document, error)
+	CheckDuplicate(hash string, collectionID uuid.UUID) (*models.Document, error)
 	UpdateDocumentStatus(id uuid.UUID, status models.DocumentStatus, userID uuid.UUID) error
 	SetIndexed(id uuid.UUID, indexed bool, userID uuid.UUID) error
 	RecordView(id uuid.UUID, userID *uuid.UUID) error
 	RecordDownload(id uuid.UUID, userID *uuid.UUID) error
-	GetFileStream(id uuid.UUID, userID *uuid.UUID) (io.ReadCloser, *models.Document, error)
-	GetThumbnailStream(id uuid.UUID, userID *uuid.UUID) (io.ReadCloser, *models.Document, error)
-	GetPreviewStream(id uuid.UUID, userID *uuid.UUID) (io.ReadCloser, *models.Document, error)
+	GetFileStream(id uuid.UUID, userID *uuid.UUID) (io.ReadSeekCloser, *models.Document, error)
+	GetThumbnailStream(id uuid.UUID, userID *uuid.UUID) (io.ReadSeekCloser, *models.Document, error)
+	GetPreviewStream(id uuid.UUID, userID *uuid.UUID) (io.ReadSeekCloser, *models.Document, error)
+	CheckLoan(document *models.Document, userID *uuid.UUID) error
 }
 
 // documentService implements DocumentService
 type documentService struct {
 	documentRepo   repository.DocumentRepository
 	collectionRepo repository.CollectionRepository // Injected for default collection handling
+	versionRepo    repository.VersionRepository
+	permissionRepo repository.PermissionRepository // Collection shares decide who may upload directly
+	blobRepo       repository.BlobRepository       // Reference counts of stored content
+	relationRepo   repository.RelationRepository   // Works a document belongs to, for indexing
+	lendingRepo    repository.LendingRepository    // Loans that open lent documents to their readers
 	fileService    FileService
 	storage        *storage.MinIOClient
 	producer       *kafka.Producer
 	virusScanner   *security.VirusScanner
-	thumbnailGen   *ThumbnailGenerator
+	previews       PreviewService
 }
 
 // NewDocumentService creates a new document service
-func NewDocumentService(documentRepo repository.DocumentRepository, collectionRepo repository.CollectionRepository, fileService FileService, storageClient *storage.MinIOClient, producer *kafka.Producer, virusScanner *security.VirusScanner) DocumentService {
+func NewDocumentService(documentRepo repository.DocumentRepository, collectionRepo repository.CollectionRepository, versionRepo repository.VersionRepository, permissionRepo repository.PermissionRepository, blobRepo repository.BlobRepository, relationRepo repository.RelationRepository, lendingRepo repository.LendingRepository, fileService FileService, storageClient *storage.MinIOClient, producer *kafka.Producer, virusScanner *security.VirusScanner, previews PreviewService) DocumentService {
 	return &documentService{
 		documentRepo:   documentRepo,
 		collectionRepo: collectionRepo,
+		versionRepo:    versionRepo,
+		permissionRepo: permissionRepo,
+		blobRepo:       blobRepo,
+		relationRepo:   relationRepo,
+		lendingRepo:    lendingRepo,
 		fileService:    fileService,
 		storage:        storageClient,
 		producer:       producer,
 		virusScanner:   virusScanner,
-		thumbnailGen:   NewThumbnailGenerator(),
+		previews:       previews,
 	}
 }
 
 // UploadDocument handles document upload with validation and deduplication
 func (s *documentService) UploadDocument(file multipart.File, header *multipart.FileHeader, metadata UploadMetadata) (*models.Document, error) {
+	return s.IngestDocument(file, UploadFile{
+		Filename:    header.Filename,
+		ContentType: header.Header.Get("Content-Type"),
+		Size:        header.Size,
+	}, metadata)
+}
+
+// IngestDocument validates, scans, deduplicates and stores a file read from src and creates
+// its document record. All upload paths funnel through here.
+func (s *documentService) IngestDocument(src io.Reader, file UploadFile, metadata UploadMetadata) (*models.Document, error) {
+	return s.ingest(src, file, metadata, "")
+}
+
+// IngestStagedDocument ingests an object that a client already uploaded to a staging key
+// (presigned upload). The object is read back for scanning and hashing but not re-uploaded;
+// it is promoted on success and removed if it is rejected after being read.
+func (s *documentService) IngestStagedDocument(stagingPath string, file UploadFile, metadata UploadMetadata) (*models.Document, error) {
+	if s.storage == nil {
+		return nil, appErrors.NewInternalError("File storage is not available", nil)
+	}
+
+	object, err := s.storage.DownloadFile(stagingPath)
+	if err != nil {
+		return nil, appErrors.NewInternalError("Failed to read staged file", err)
+	}
+	defer object.Close()
+
+	return s.ingest(object, file, metadata, stagingPath)
+}
+
+// StageFile uploads a file to a fresh staging key, for IngestStagedDocument to ingest later
+func (s *documentService) StageFile(src io.Reader, file UploadFile) (string, error) {
+	if s.storage == nil {
+		return "", appErrors.NewInternalError("File storage is not available", nil)
+	}
+
+	stagingPath := fmt.Sprintf("%s%s%s", stagingPrefix, uuid.New(), s.fileService.GetFileExtension(file.Filename))
+	contentType := file.ContentType
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	if _, err := s.storage.UploadStream(stagingPath, src, contentType); err != nil {
+		return "", appErrors.NewInternalError("Failed to stage file", err)
+	}
+	return stagingPath, nil
+}
+
+// DiscardStagedFile removes a staged file that will not be ingested (best effort)
+func (s *documentService) DiscardStagedFile(stagingPath string) {
+	s.discardStaged(stagingPath)
+}
+
+// ingest implements IngestDocument and IngestStagedDocument. When stagedPath is empty the
+// stream is also uploaded to a fresh staging key.
+func (s *documentService) ingest(src io.Reader, file UploadFile, metadata UploadMetadata, stagedPath string) (*models.Document, error) {
 	// Validate title
 	if err := validator.ValidateRequired(metadata.Title, "title"); err != nil {
 		return nil, appErrors.NewValidationError(err.Error(), err)
@@ -112,58 +221,34 @@ func (s *documentService) UploadDocument(file multipart.File, header *multipart.
 		}
 	}
 
-	// Validate file size
-	if err := s.fileService.ValidateFileSize(header.Size); err != nil {
-		return nil, err
-	}
-
-	// Validate file type
-	if err := s.fileService.ValidateFileType(header.Header.Get("Content-Type")); err != nil {
-		return nil, err
+	collection, err := s.collectionRepo.FindByID(metadata.CollectionID)
+	if err != nil {
+		return nil, appErrors.NewNotFoundError("Collection", err)
 	}
 
-	// Read file content for hashing and scanning
-	fileContent, err := io.ReadAll(file)
+	status, err := s.submissionStatus(collection, metadata.UploaderID)
 	if err != nil {
-		return nil, appErrors.NewInternalError("Failed to read file", err)
+		return nil, err
 	}
 
-	// Scan for viruses (CRITICAL SECURITY CHECK)
-	if s.virusScanner != nil {
-		if err := s.virusScanner.ScanFile(bytes.NewReader(fileContent), header.Filename); err != nil {
-			return nil, appErrors.NewValidationError("Virus scan failed: "+err.Error(), err)
-		}
+	if err := checkMetadataPolicy(collection.Settings, metadata); err != nil {
+		return nil, err
 	}
-
-	// Generate hash for deduplication
-	hash, err := s.fileService.GenerateHash(bytes.NewReader(fileContent))
-	if err != nil {
+	if err := validateAvailability(metadata.Availability); err != nil {
 		return nil, err
 	}
 
-	// Check for duplicate
-	existingDoc, err := s.documentRepo.FindByHash(hash)
+	stored, err := s.storeFile(src, file, collection, stagedPath)
 	if err != nil {
-		return nil, appErrors.NewInternalError("Failed to check for duplicates", err)
-	}
-	if existingDoc != nil {
-		return nil, appErrors.NewConflictError(
-			"Document",
-			fmt.Errorf("a document with the same content already exists (ID: %s)", existingDoc.ID),
-		)
+		return nil, err
 	}
 
-	// Get file extension
-	ext := s.fileService.GetFileExtension(header.Filename)
-	fileType := getFileType(ext)
-
-	// Create storage path (in production, this would upload to S3/MinIO)
-	storagePath := fmt.Sprintf("documents/%s/%s%s", metadata.CollectionID, uuid.New(), ext)
-
 	// Set default metadata if not provided
 	if metadata.Metadata == nil {
 		metadata.Metadata = &models.DocumentMetadata{}
 	}
+	// Provenance is only recorded by extraction
+	docmeta.ClearProvenance(metadata.Metadata)
 
 	// Create document record
 	document := &models.Document{
@@ -171,101 +256,407 @@ func (s *documentService) UploadDocument(file multipart.File, header *multipart.
 		Description:      metadata.Description,
 		CollectionID:     metadata.CollectionID,
 		UploaderID:       metadata.UploaderID,
-		Status:           models.StatusPending,
-		OriginalFilename: header.Filename,
-		FileType:         fileType,
-		MimeType:         header.Header.Get("Content-Type"),
-		FileSize:         header.Size,
-		StoragePath:      storagePath,
-		Hash:             hash,
+		Status:           status,
+		OriginalFilename: stored.Filename,
+		FileType:         stored.FileType,
+		MimeType:         stored.MimeType,
+		FileSize:         stored.Size,
+		StoragePath:      stored.StoragePath,
+		ThumbnailPath:    stored.ThumbnailPath,
+		Hash:             stored.Hash,
 		Metadata:         *metadata.Metadata,
 		IsIndexed:        false,
+
+		AvailableFrom:       metadata.Availability.AvailableFrom,
+		AvailableUntil:      metadata.Availability.AvailableUntil,
+		EmbargoShowMetadata: metadata.Availability.EmbargoShowMetadata,
+	}
+
+	if err := s.documentRepo.Create(document); err != nil {
+		// Rollback: remove stored objects if the record cannot be created
+		s.DiscardStoredFile(stored)
+		return nil, appErrors.NewInternalError("Failed to create document", err)
+	}
+
+	// Submissions are indexed once approved
+	if status == models.StatusSubmitted {
+		s.publishSubmitted(document)
+	} else {
+		s.publishUploaded(document)
+	}
+
+	// Fetch with relationships
+	return s.documentRepo.FindByID(document.ID)
+}
+
+// StoreFile validates, scans and deduplicates a file read from src and moves it into
+// permanent storage for the collection without creating a document record. The stored
+// file holds one reference to its content; the caller must DiscardStoredFile it if it
+// cannot record it.
+func (s *documentService) StoreFile(src io.Reader, file UploadFile, collectionID uuid.UUID) (*StoredFile, error) {
+	collection, err := s.collectionRepo.FindByID(collectionID)
+	if err != nil {
+		return nil, appErrors.NewNotFoundError("Collection", err)
+	}
+	return s.storeFile(src, file, collection, "")
+}
+
+// CheckUploadPolicy validates a declared file and its metadata against the target
+// collection's upload policy, and the requested availability window, so transports that
+// receive the file later can fail fast. Uploads without a collection go to the
+// uploader's default collection and are only checked against its policy on ingestion.
+func (s *documentService) CheckUploadPolicy(file UploadFile, metadata UploadMetadata) error {
+	if err := validateAvailability(metadata.Availability); err != nil {
+		return err
+	}
+	if metadata.CollectionID == uuid.Nil {
+		return nil
+	}
+
+	collection, err := s.collectionRepo.FindByID(metadata.CollectionID)
+	if err != nil {
+		return appErrors.NewNotFoundError("Collection", err)
 	}
 
-	// Generate Thumbnail (Best Effort)
-	// We do this BEFORE database creation so we can save the path, but if it fails we don't block upload?
-	// Or we can update it after. Let's do it before.
-	if s.thumbnailGen != nil {
-		// Save to temp file
+	if err := checkFilePolicy(collection.Settings, file, s.fileService.GetFileExtension(file.Filename)); err != nil {
+		return err
+	}
+	return checkMetadataPolicy(collection.Settings, metadata)
+}
+
+// DiscardStoredFile drops the reference a stored file that could not be recorded holds on
+// its content (best effort)
+func (s *documentService) DiscardStoredFile(stored *StoredFile) {
+	if stored == nil {
+		return
+	}
+	s.ReleaseContent(stored.Hash)
+}
+
+// storeFile implements StoreFile. When stagedPath is empty the stream is also uploaded to
+// a fresh staging key; otherwise the object already staged there is promoted.
+func (s *documentService) storeFile(src io.Reader, file UploadFile, collection *models.Collection, stagedPath string) (*StoredFile, error) {
+	// Validate file size
+	if err := s.fileService.ValidateFileSize(file.Size); err != nil {
+		return nil, err
+	}
+
+	// Detect the content type from the file's signature rather than trusting the client
+	head := make([]byte, sniffLen)
+	n, err := io.ReadFull(src, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return nil, appErrors.NewInternalError("Failed to read file", err)
+	}
+	head = head[:n]
+	src = io.MultiReader(bytes.NewReader(head), src)
+
+	declaredType := file.ContentType
+	contentType, err := resolveContentType(declaredType, detectContentType(head))
+	if err != nil {
+		return nil, err
+	}
+	if contentType != declaredType {
+		fmt.Printf("DEBUG: Declared content type %q of %s recorded as %q\n", declaredType, file.Filename, contentType)
+	}
+
+	// Validate file type
+	if err := s.fileService.ValidateFileType(contentType); err != nil {
+		return nil, err
+	}
+
+	// Store under the extension of the detected type
+	ext := extensionForType(contentType, s.fileService.GetFileExtension(file.Filename))
+	fileType := getFileType(ext)
+	file.ContentType = contentType
+
+	// Apply the collection's own upload policy
+	if err := checkFilePolicy(collection.Settings, file, ext); err != nil {
+		return nil, err
+	}
+
+	// Stream the upload once, aborting it as soon as it exceeds the size limits
+	pipeline := &uploadPipeline{hash: s.fileService.GenerateHash, limit: s.fileService.MaxFileSize()}
+	if capSize := collection.Settings.MaxFileSize; capSize > 0 && (pipeline.limit <= 0 || capSize < pipeline.limit) {
+		pipeline.limit = capSize
+	}
+	if s.virusScanner != nil {
+		pipeline.scanner = s.virusScanner
+	}
+	if s.storage != nil {
+		pipeline.store = s.storage
+	}
+
+	stagingPath := stagedPath
+	if stagingPath == "" && s.storage != nil {
+		stagingPath = fmt.Sprintf("%s%s%s", stagingPrefix, uuid.New(), ext)
+	}
+
+	// Containers are confirmed from a local copy of the whole file, and so are the pages of
+	// PDFs counted when the collection limits them. Thumbnails and other derivatives are
+	// produced later by the derivative worker.
+	countPages := contentType == mimePDF && collection.Settings.MaxPageCount > 0
+	var localPath string
+	var local io.WriteCloser
+	if needsContainerCheck(contentType) || countPages {
 		tempFile, err := os.CreateTemp("", "upload-*"+ext)
-		if err == nil {
-			defer os.Remove(tempFile.Name()) // Clean up
-			if _, err := io.Copy(tempFile, bytes.NewReader(fileContent)); err == nil {
-				tempFile.Close() // Ensure written
-
-				// Generate
-				thumbPath, err := s.thumbnailGen.GenerateThumbnail(tempFile.Name(), header.Header.Get("Content-Type"))
-				if err == nil {
-					defer os.Remove(thumbPath) // Cleanup generated file
-
-					// Upload to MinIO
-					thumbExt := filepath.Ext(thumbPath)
-					storageThumbPath := fmt.Sprintf("thumbnails/%s/%s%s", metadata.CollectionID, uuid.New(), thumbExt)
-
-					// Read thumbnail
-					thumbData, err := os.ReadFile(thumbPath)
-					if err == nil && s.storage != nil {
-						if err := s.storage.UploadFile(storageThumbPath, bytes.NewReader(thumbData), int64(len(thumbData)), "image/png"); err == nil {
-							document.ThumbnailPath = storageThumbPath
-							fmt.Printf("DEBUG: Thumbnail generated and uploaded to %s\n", storageThumbPath)
-						} else {
-							fmt.Printf("DEBUG: Thumbnail upload failed: %v\n", err)
-						}
-					}
-				} else {
-					fmt.Printf("DEBUG: Thumbnail generation failed: %v\n", err)
-				}
+		if err != nil {
+			return nil, appErrors.NewInternalError("Failed to buffer upload", err)
+		}
+		localPath = tempFile.Name()
+		defer os.Remove(localPath) // Clean up
+		local = tempFile
+	}
+
+	hash, written, err := pipeline.stage(src, file, stagingPath, stagedPath == "", local)
+	if err != nil {
+		return nil, err
+	}
+
+	// Office and EPUB containers are confirmed from the whole file
+	if needsContainerCheck(contentType) {
+		if err := s.confirmContainerType(localPath, declaredType, &file, &ext, collection); err != nil {
+			s.discardStaged(stagingPath)
+			return nil, err
+		}
+		contentType = file.ContentType
+		fileType = getFileType(ext)
+	}
+
+	// PDF pages are cheap to count, so an upload over the limit is refused here; other
+	// formats are counted by the derivative worker, which rejects the document
+	if countPages {
+		if pages, ok := pdfPageCount(localPath); ok {
+			if err := checkPagePolicy(collection.Settings, pages); err != nil {
+				s.discardStaged(stagingPath)
+				return nil, err
 			}
-		} else {
-			fmt.Printf("DEBUG: Failed to create temp file for thumbnail: %v\n", err)
 		}
 	}
 
-	if err := s.documentRepo.Create(document); err != nil {
-		return nil, appErrors.NewInternalError("Failed to create document", err)
+	// Each collection holds a given content once; other collections share its blob
+	existingDoc, err := s.documentRepo.FindByHashInCollection(hash, collection.ID)
+	if err != nil {
+		s.discardStaged(stagingPath)
+		return nil, appErrors.NewInternalError("Failed to check for duplicates", err)
+	}
+	if existingDoc != nil {
+		s.discardStaged(stagingPath)
+		return nil, duplicateError(existingDoc)
+	}
+
+	// Reference the blob for this content, storing it if it is new
+	blob, created, err := s.blobRepo.Acquire(&models.Blob{
+		Hash:        hash,
+		StoragePath: blobKey(hash, ext),
+		Size:        written,
+		MimeType:    contentType,
+	})
+	if err != nil {
+		s.discardStaged(stagingPath)
+		return nil, appErrors.NewInternalError("Failed to record file", err)
 	}
 
-	// Upload file to MinIO/S3
+	// The blob is shared as soon as it is recorded, before its creator has promoted the
+	// object. An upload that finds the object missing (still being promoted, or its
+	// promotion failed) promotes its own copy of the identical content instead.
 	if s.storage != nil {
-		fmt.Printf("DEBUG: Starting MinIO upload to %s (%d bytes)\n", storagePath, header.Size)
-		if err := s.storage.UploadFile(storagePath, bytes.NewReader(fileContent), header.Size, header.Header.Get("Content-Type")); err != nil {
-			fmt.Printf("DEBUG: MinIO upload failed: %v\n", err)
-			// Rollback: delete document record if upload fails
-			s.documentRepo.Delete(document.ID)
+		present := false
+		if !created {
+			if present, err = s.storage.FileExists(blob.StoragePath); err != nil {
+				s.discardStaged(stagingPath)
+				s.ReleaseContent(hash)
+				return nil, appErrors.NewInternalError("Failed to check stored file", err)
+			}
+		}
+
+		if present {
+			s.discardStaged(stagingPath)
+			fmt.Printf("DEBUG: Content %s is already stored at %s; sharing it\n", hash, blob.StoragePath)
+		} else if err := s.storage.MoveFile(stagingPath, blob.StoragePath); err != nil {
+			// Uploads sharing the blob meanwhile store the object themselves
+			s.discardStaged(stagingPath)
+			s.ReleaseContent(hash)
 			return nil, appErrors.NewInternalError("Failed to upload file to storage", err)
 		}
-		fmt.Println("DEBUG: MinIO upload successful")
 	} else {
 		fmt.Println("DEBUG: MinIO client is nil, skipping upload")
 	}
 
-	// Publish Kafka Event
-	if s.producer != nil {
-		event := map[string]interface{}{
-			"id":           document.ID,
-			"title":        document.Title,
-			"description":  document.Description,
-			"created_at":   document.CreatedAt,
-			"uploader_id":  document.UploaderID,
-			"file_type":    document.FileType,
-			"mime_type":    document.MimeType,
-			"storage_path": document.StoragePath,
+	return &StoredFile{
+		StoragePath:   blob.StoragePath,
+		ThumbnailPath: blob.ThumbnailPath,
+		Hash:          hash,
+		Size:          written,
+		Filename:      file.Filename,
+		FileType:      fileType,
+		MimeType:      contentType,
+	}, nil
+}
+
+// blobKey returns a fresh storage key for content with the given hash. Keys are never
+// reused, so a blob stored again after its last reference went cannot collide with the
+// objects of the old one while they are being deleted.
+func blobKey(hash, ext string) string {
+	return fmt.Sprintf("blobs/%s/%s/%s%s", hash[:2], hash, uuid.New(), ext)
+}
+
+// RetainContent adds a reference to stored content for a new document or version row
+func (s *documentService) RetainContent(hash string) error {
+	if err := s.blobRepo.Retain(hash); err != nil {
+		return appErrors.NewInternalError("Failed to reference file", err)
+	}
+	return nil
+}
+
+// ReleaseContent drops a reference to stored content and deletes its objects with the
+// last one (best effort)
+func (s *documentService) ReleaseContent(hash string) {
+	blob, err := s.blobRepo.Release(hash)
+	if err != nil {
+		fmt.Printf("DEBUG: Failed to release content %s: %v\n", hash, err)
+		return
+	}
+	if blob == nil || s.storage == nil {
+		return
+	}
+
+	for _, object := range []string{blob.StoragePath, blob.ThumbnailPath, previewKey(blob.Hash)} {
+		if object == "" {
+			continue
 		}
-		// Use background context for async publishing, or request context?
-		// Fire and forget for now, but log error
-		fmt.Println("DEBUG: Publishing Kafka event...")
-		if err := s.producer.PublishToTopic(context.Background(), "document.uploaded", document.ID.String(), event); err != nil {
-			fmt.Printf("DEBUG: Failed to publish document.uploaded event: %v\n", err)
-			// Don't fail the request, just log
-		} else {
-			fmt.Println("DEBUG: Kafka event published")
+		if err := s.storage.DeleteFile(object); err != nil {
+			// The record is gone; an orphaned object is harmless
+			fmt.Printf("DEBUG: Failed to delete %s: %v\n", object, err)
 		}
-	} else {
+	}
+}
+
+// submissionStatus decides the initial status of an upload into a collection. The owner
+// and users the collection is shared with for editing upload directly; anyone else needs
+// the collection to accept submissions, and waits for review if it requires approval.
+func (s *documentService) submissionStatus(collection *models.Collection, uploaderID uuid.UUID) (models.DocumentStatus, error) {
+	if collection.OwnerID == uploaderID {
+		return models.StatusPending, nil
+	}
+
+	for _, level := range []models.PermissionLevel{models.PermissionEdit, models.PermissionAdmin} {
+		shared, err := s.permissionRepo.HasCollectionShare(collection.ID, uploaderID, level)
+		if err != nil {
+			return "", appErrors.NewInternalError("Failed to check collection permissions", err)
+		}
+		if shared {
+			return models.StatusPending, nil
+		}
+	}
+
+	if !collection.Settings.AllowPublicSubmissions {
+		return "", appErrors.NewForbiddenError("This collection does not accept submissions", nil)
+	}
+	if collection.Settings.RequireApproval {
+		return models.StatusSubmitted, nil
+	}
+	return models.StatusPending, nil
+}
+
+// publishSubmitted announces a submission awaiting review
+func (s *documentService) publishSubmitted(document *models.Document) {
+	if s.producer == nil {
+		return
+	}
+
+	event := map[string]interface{}{
+		"id":            document.ID,
+		"title":         document.Title,
+		"collection_id": document.CollectionID,
+		"submitter_id":  document.UploaderID,
+		"submitted_at":  document.CreatedAt,
+	}
+	if err := s.producer.PublishToTopic(context.Background(), "document.submitted", document.ID.String(), event); err != nil {
+		fmt.Printf("DEBUG: Failed to publish document.submitted event: %v\n", err)
+	}
+}
+
+// confirmContainerType checks a received container against its provisional content type
+// and, when it holds a related format, updates file and ext to the format it holds
+func (s *documentService) confirmContainerType(localPath, declaredType string, file *UploadFile, ext *string, collection *models.Collection) error {
+	actual, err := refineContainerType(localPath)
+	if err != nil {
+		return appErrors.NewInternalError("Failed to inspect file", err)
+	}
+	if actual == baseMediaType(file.ContentType) {
+		return nil
+	}
+
+	contentType, err := resolveContentType(declaredType, actual)
+	if err != nil {
+		return err
+	}
+	if err := s.fileService.ValidateFileType(contentType); err != nil {
+		return err
+	}
+
+	file.ContentType = contentType
+	*ext = extensionForType(contentType, *ext)
+	return checkFilePolicy(collection.Settings, *file, *ext)
+}
+
+// publishUploaded announces new or changed document content so it is (re)indexed.
+// The hash lets consumers that report back tell which content they processed.
+func (s *documentService) publishUploaded(document *models.Document) {
+	if s.producer == nil {
 		fmt.Println("DEBUG: Kafka producer is nil")
+		return
 	}
 
-	// Fetch with relationships
-	return s.documentRepo.FindByID(document.ID)
+	event := map[string]interface{}{
+		"id":           document.ID,
+		"title":        document.Title,
+		"description":  document.Description,
+		"created_at":   document.CreatedAt,
+		"uploader_id":  document.UploaderID,
+		"file_type":    document.FileType,
+		"mime_type":    document.MimeType,
+		"storage_path": document.StoragePath,
+		"hash":         document.Hash,
+	}
+	// Search shows documents within their availability window only
+	if document.AvailableFrom != nil {
+		event["available_from"] = document.AvailableFrom
+		event["embargo_show_metadata"] = document.EmbargoShowMetadata
+	}
+	if document.AvailableUntil != nil {
+		event["available_until"] = document.AvailableUntil
+	}
+	// Parts and volumes record their work, so search can group them; group_id is the
+	// series (or else the work) a document belongs to, or the document itself
+	event["group_id"] = document.ID
+	if works, err := s.relationRepo.ListWorks(document.ID); err != nil {
+		fmt.Printf("DEBUG: Failed to load works of document %s: %v\n", document.ID, err)
+	} else {
+		for _, work := range works {
+			switch work.Type {
+			case models.RelationSeriesMember:
+				event["series_id"] = work.ObjectID
+				event["series_position"] = work.Position
+				event["group_id"] = work.ObjectID
+			case models.RelationPartOf:
+				event["part_of_id"] = work.ObjectID
+				event["part_position"] = work.Position
+				if _, inSeries := event["series_id"]; !inSeries {
+					event["group_id"] = work.ObjectID
+				}
+			}
+		}
+	}
+	// Use background context for async publishing, or request context?
+	// Fire and forget for now, but log error
+	fmt.Println("DEBUG: Publishing Kafka event...")
+	if err := s.producer.PublishToTopic(context.Background(), "document.uploaded", document.ID.String(), event); err != nil {
+		fmt.Printf("DEBUG: Failed to publish document.uploaded event: %v\n", err)
+		// Don't fail the request, just log
+	} else {
+		fmt.Println("DEBUG: Kafka event published")
+	}
 }
 
 // GetDocument retrieves a document by ID
@@ -277,18 +668,60 @@ func (s *documentService) GetDocument(id uuid.UUID, userID *uuid.UUID) (*models.
 		return nil, appErrors.NewNotFoundError("Document", err)
 	}
 
-	// In production, check collection permissions here
-	// For now, allow access to active documents
-	if document.Status != models.StatusActive && document.Status != models.StatusPending {
-		if userID == nil || *userID != document.UploaderID {
-			fmt.Println("DEBUG: GetDocument forbidden")
-			return nil, appErrors.NewForbiddenError("Document is not available", nil)
-		}
+	if err := checkVisible(document, userID); err != nil {
+		fmt.Println("DEBUG: GetDocument forbidden")
+		return nil, err
+	}
+
+	return document, nil
+}
+
+// checkVisible checks that the user may see a document, which must have its collection
+// loaded. In production, check collection permissions here. For now, active documents
+// within their availability window are visible to everyone; others only to the uploader
+// and the collection owner (e.g. submissions under review).
+func checkVisible(document *models.Document, userID *uuid.UUID) error {
+	if managesDocument(document, userID) {
+		return nil
+	}
+
+	// The window is checked against the clock; the scheduler may not have caught up
+	now := time.Now()
+	switch {
+	case document.Status != models.StatusActive && document.Status != models.StatusPending &&
+		document.Status != models.StatusProcessing && document.Status != models.StatusEmbargoed:
+		return appErrors.NewForbiddenError("Document is not available", nil)
+	case document.IsExpired(now):
+		return appErrors.NewForbiddenError("Document is no longer available", nil)
+	case document.IsEmbargoed(now) && !document.EmbargoShowMetadata:
+		return appErrors.NewForbiddenError("Document is not available yet", nil)
+	}
+	return nil
+}
+
+// GetContentDocument retrieves a document whose content (file, preview, versions) the
+// user may read. Documents listed during their embargo only expose their metadata.
+func (s *documentService) GetContentDocument(id uuid.UUID, userID *uuid.UUID) (*models.Document, error) {
+	document, err := s.GetDocument(id, userID)
+	if err != nil {
+		return nil, err
 	}
 
+	if !managesDocument(document, userID) && document.IsEmbargoed(time.Now()) {
+		return nil, appErrors.NewForbiddenError(
+			fmt.Sprintf("Document is under embargo until %s", document.AvailableFrom.UTC().Format(time.RFC3339)),
+			nil,
+		)
+	}
 	return document, nil
 }
 
+// managesDocument reports whether the user is the document's uploader or the owner of its
+// collection, who see it regardless of its status and availability
+func managesDocument(document *models.Document, userID *uuid.UUID) bool {
+	return userID != nil && (*userID == document.UploaderID || *userID == document.Collection.OwnerID)
+}
+
 // UpdateDocument updates document metadata
 func (s *documentService) UpdateDocument(id uuid.UUID, updates DocumentUpdate, userID uuid.UUID) (*models.Document, error) {
 	document, err := s.documentRepo.FindByID(id)
@@ -301,31 +734,62 @@ func (s *documentService) UpdateDocument(id uuid.UUID, updates DocumentUpdate, u
 		return nil, appErrors.NewForbiddenError("Only the uploader can update this document", nil)
 	}
 
-	// Update fields if provided
 	if updates.Title != nil {
 		if err := validator.ValidateRequired(*updates.Title, "title"); err != nil {
 			return nil, appErrors.NewValidationError(err.Error(), err)
 		}
-		document.Title = *updates.Title
 	}
-
-	if updates.Description != nil {
-		document.Description = *updates.Description
+	if updates.Availability != nil {
+		if err := validateAvailability(*updates.Availability); err != nil {
+			return nil, err
+		}
 	}
 
-	if updates.Metadata != nil {
-		document.Metadata = *updates.Metadata
+	// Update fields if provided, on the current row: the derivative worker may be filling
+	// in extracted metadata concurrently
+	windowChanged := false
+	err = s.documentRepo.EditDetails(id, func(current *models.Document) error {
+		if updates.Title != nil {
+			current.Title = *updates.Title
+		}
+		if updates.Description != nil {
+			current.Description = *updates.Description
+		}
+		if updates.Metadata != nil {
+			edited := *updates.Metadata
+			// Extracted values the user changed are theirs from now on
+			docmeta.ClaimEdits(current.Metadata, &edited)
+			current.Metadata = edited
+		}
+		if updates.Availability != nil {
+			windowChanged = !sameTime(current.AvailableFrom, updates.Availability.AvailableFrom) ||
+				!sameTime(current.AvailableUntil, updates.Availability.AvailableUntil) ||
+				current.EmbargoShowMetadata != updates.Availability.EmbargoShowMetadata
+			current.AvailableFrom = updates.Availability.AvailableFrom
+			current.AvailableUntil = updates.Availability.AvailableUntil
+			current.EmbargoShowMetadata = updates.Availability.EmbargoShowMetadata
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, appErrors.NewInternalError("Failed to update document", err)
 	}
 
-	// Save updates
-	if err := s.documentRepo.Update(document); err != nil {
-		return nil, appErrors.NewInternalError("Failed to update document", err)
+	// A changed window may open or close the document right away, and search filters on it
+	if windowChanged {
+		if err := s.settleAvailability(id, time.Now(), true); err != nil {
+			return nil, err
+		}
 	}
 
+	document, err = s.documentRepo.FindByID(id)
+	if err != nil {
+		return nil, appErrors.NewNotFoundError("Document", err)
+	}
 	return document, nil
 }
 
-// DeleteDocument deletes a document
+// DeleteDocument moves a document to the trash
 func (s *documentService) DeleteDocument(id uuid.UUID, userID uuid.UUID) error {
 	document, err := s.documentRepo.FindByID(id)
 	if err != nil {
@@ -337,36 +801,136 @@ func (s *documentService) DeleteDocument(id uuid.UUID, userID uuid.UUID) error {
 		return appErrors.NewForbiddenError("Only the uploader can delete this document", nil)
 	}
 
-	// Delete file from storage first
-	if s.storage != nil && document.StoragePath != "" {
-		if err := s.storage.DeleteFile(document.StoragePath); err != nil {
-			// Log error but don't fail - continue with database deletion
-			// In production, you might want to queue for retry
+	// Move to the trash; files are kept until the document is purged
+	if err := s.documentRepo.Trash(id, userID); err != nil {
+		return appErrors.NewInternalError("Failed to delete document", err)
+	}
+
+	s.RemoveFromSearch(id)
+	return nil
+}
+
+// RemoveFromSearch announces that a document is gone so the indexer drops it
+func (s *documentService) RemoveFromSearch(id uuid.UUID) {
+	if s.producer == nil {
+		return
+	}
+
+	event := map[string]interface{}{
+		"id":         id,
+		"deleted_at": time.Now(),
+	}
+	go func() {
+		fmt.Printf("DEBUG: Publishing document.deleted event for %s\n", id)
+		if err := s.producer.PublishToTopic(context.Background(), "document.deleted", id.String(), event); err != nil {
+			fmt.Printf("DEBUG: Failed to publish document.deleted event: %v\n", err)
 		}
+	}()
+}
+
+// underReview reports whether a document is a submission that is awaiting review or was
+// rejected
+func underReview(status models.DocumentStatus) bool {
+	return status == models.StatusSubmitted || status == models.StatusRejected
+}
+
+// SettleAvailability moves a processed document to the status its availability window
+// gives it at now, announcing when it is published or expires. Documents that are still
+// being processed or reviewed are settled when that finishes.
+func (s *documentService) SettleAvailability(id uuid.UUID, now time.Time) error {
+	return s.settleAvailability(id, now, false)
+}
+
+// settleAvailability settles a document as SettleAvailability does. After its window was
+// edited the document is reindexed even when its status stays, so search has the new window.
+func (s *documentService) settleAvailability(id uuid.UUID, now time.Time, windowChanged bool) error {
+	document, err := s.documentRepo.FindByID(id)
+	if err != nil {
+		return appErrors.NewNotFoundError("Document", err)
 	}
 
-	// Delete document record
-	if err := s.documentRepo.Delete(id); err != nil {
-		return appErrors.NewInternalError("Failed to delete document", err)
+	switch document.Status {
+	case models.StatusActive, models.StatusEmbargoed, models.StatusExpired:
+	default:
+		return nil
 	}
 
-	// Publish Kafka Event
-	if s.producer != nil {
-		event := map[string]interface{}{
-			"id":         id,
-			"deleted_at": time.Now(),
+	status := document.AvailabilityStatus(now)
+	if status == document.Status {
+		if windowChanged && status != models.StatusExpired {
+			return s.RequestReindex(id)
 		}
-		go func() {
-			fmt.Printf("DEBUG: Publishing document.deleted event for %s\n", id)
-			if err := s.producer.PublishToTopic(context.Background(), "document.deleted", id.String(), event); err != nil {
-				fmt.Printf("DEBUG: Failed to publish document.deleted event: %v\n", err)
-			}
-		}()
+		return nil
+	}
+	moved, err := s.documentRepo.TransitionStatus(id, []models.DocumentStatus{document.Status}, status)
+	if err != nil {
+		return appErrors.NewInternalError("Failed to update document status", err)
 	}
+	if !moved {
+		return nil // Settled concurrently
+	}
+	fmt.Printf("DEBUG: Document %s is now %s\n", id, status)
+
+	if status == models.StatusExpired {
+		s.publishAvailability("document.expired", document)
+		s.RemoveFromSearch(id)
+		return nil
+	}
+	if status == models.StatusActive {
+		s.publishAvailability("document.published", document)
+	}
+	// Refresh the search index, which an expired document had left
+	return s.RequestReindex(id)
+}
 
+// sameTime reports whether two optional times are both unset or equal
+func sameTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
+
+// publishAvailability announces that a document became available or expired
+func (s *documentService) publishAvailability(topic string, document *models.Document) {
+	if s.producer == nil {
+		return
+	}
+
+	event := map[string]interface{}{
+		"id":              document.ID,
+		"collection_id":   document.CollectionID,
+		"uploader_id":     document.UploaderID,
+		"available_from":  document.AvailableFrom,
+		"available_until": document.AvailableUntil,
+		"occurred_at":     time.Now(),
+	}
+	if err := s.producer.PublishToTopic(context.Background(), topic, document.ID.String(), event); err != nil {
+		fmt.Printf("DEBUG: Failed to publish %s event: %v\n", topic, err)
+	}
+}
+
+// validateAvailability checks that an availability window is not empty
+func validateAvailability(availability Availability) error {
+	if availability.AvailableFrom != nil && availability.AvailableUntil != nil &&
+		!availability.AvailableFrom.Before(*availability.AvailableUntil) {
+		return appErrors.NewValidationError("available_until must be after available_from", nil)
+	}
 	return nil
 }
 
+// duplicateError reports that content already belongs to another document in the same
+// collection, which may be in the trash. The document is not identified: the uploader
+// may not be allowed to see it.
+func duplicateError(existing *models.Document) error {
+	if existing.DeletedAt.Valid {
+		return appErrors.NewConflictError("Document",
+			fmt.Errorf("a document with the same content is in this collection's trash; restore it instead"))
+	}
+	return appErrors.NewConflictError("Document",
+		fmt.Errorf("a document with the same content already exists in this collection"))
+}
+
 // ListDocuments lists documents with filters and pagination
 func (s *documentService) ListDocuments(filters repository.DocumentFilters, page, pageSize int) ([]models.Document, int64, error) {
 	// Calculate offset
@@ -380,9 +944,9 @@ func (s *documentService) ListDocuments(filters repository.DocumentFilters, page
 	return documents, total, nil
 }
 
-// CheckDuplicate checks if a document with the same hash exists
-func (s *documentService) CheckDuplicate(hash string) (*models.Document, error) {
-	return s.documentRepo.FindByHash(hash)
+// CheckDuplicate checks if a document with the same hash exists in a collection
+func (s *documentService) CheckDuplicate(hash string, collectionID uuid.UUID) (*models.Document, error) {
+	return s.documentRepo.FindByHashInCollection(hash, collectionID)
 }
 
 // UpdateDocumentStatus updates the status of a document
@@ -397,6 +961,17 @@ func (s *documentService) UpdateDocumentStatus(id uuid.UUID, status models.Docum
 		return appErrors.NewForbiddenError("Only the uploader can update document status", nil)
 	}
 
+	// Review decisions are made through the moderation queue
+	if underReview(document.Status) || status == models.StatusSubmitted {
+		return appErrors.NewConflictError("Document",
+			fmt.Errorf("the status of submissions is set by review"))
+	}
+
+	// Embargo and expiry follow the availability window
+	if status == models.StatusEmbargoed || status == models.StatusExpired {
+		return appErrors.NewValidationError("Embargo and expiry are set through available_from and available_until", nil)
+	}
+
 	return s.documentRepo.UpdateStatus(id, status)
 }
 
@@ -412,14 +987,44 @@ func (s *documentService) SetIndexed(id uuid.UUID, indexed bool, userID uuid.UUI
 		return appErrors.NewForbiddenError("Only the uploader can update indexing status", nil)
 	}
 
-	if err := s.documentRepo.SetIndexed(id, indexed); err != nil {
-		return err
+	// The status is settled by the derivative worker, independently of indexing
+	return s.documentRepo.SetIndexed(id, indexed)
+}
+
+// SetExtractedText records the text extracted from a document's content by the indexer
+// The hash identifies the content that was processed; text for replaced content is dropped.
+func (s *documentService) SetExtractedText(id uuid.UUID, hash, text string) error {
+	if hash == "" {
+		return appErrors.NewValidationError("hash is required with extracted text", nil)
+	}
+	if err := s.documentRepo.SetExtractedText(id, hash, text); err != nil {
+		return appErrors.NewInternalError("Failed to store extracted text", err)
+	}
+	// Versions keep their own copy so they can still be compared once superseded
+	if err := s.versionRepo.SetExtractedText(id, hash, text); err != nil {
+		return appErrors.NewInternalError("Failed to store extracted text", err)
+	}
+	return nil
+}
+
+// RequestReindex marks a document as not indexed and re-announces its content so the
+// indexer processes it again (e.g. after its file changed)
+func (s *documentService) RequestReindex(id uuid.UUID) error {
+	document, err := s.documentRepo.FindByID(id)
+	if err != nil {
+		return appErrors.NewNotFoundError("Document", err)
+	}
+
+	// Submissions stay out of search until approved
+	if underReview(document.Status) {
+		return nil
 	}
 
-	// Also mark as active if indexed
-	if indexed {
-		return s.documentRepo.UpdateStatus(id, models.StatusActive)
+	if err := s.documentRepo.SetIndexed(id, false); err != nil {
+		return appErrors.NewInternalError("Failed to reset indexing status", err)
 	}
+
+	s.publishUploaded(document)
 	return nil
 }
 
@@ -466,12 +1071,16 @@ func (s *documentService) RecordDownload(id uuid.UUID, userID *uuid.UUID) error
 }
 
 // GetFileStream retrieves the file stream for a document
-func (s *documentService) GetFileStream(id uuid.UUID, userID *uuid.UUID) (io.ReadCloser, *models.Document, error) {
-	// reuse GetDocument for permission checks
-	document, err := s.GetDocument(id, userID)
+// The stream is seekable so that byte ranges can be served without reading the whole object
+func (s *documentService) GetFileStream(id uuid.UUID, userID *uuid.UUID) (io.ReadSeekCloser, *models.Document, error) {
+	// reuse GetContentDocument for permission checks
+	document, err := s.GetContentDocument(id, userID)
 	if err != nil {
 		return nil, nil, err
 	}
+	if err := s.CheckLoan(document, userID); err != nil {
+		return nil, nil, err
+	}
 
 	if s.storage == nil {
 		return nil, nil, appErrors.NewInternalError("Storage service not available", nil)
@@ -485,7 +1094,7 @@ func (s *documentService) GetFileStream(id uuid.UUID, userID *uuid.UUID) (io.Rea
 		return nil, nil, appErrors.NewNotFoundError("File in storage", fmt.Errorf("path: %s", document.StoragePath))
 	}
 
-	stream, err := s.storage.DownloadFile(document.StoragePath)
+	stream, err := s.storage.OpenFile(document.StoragePath)
 	if err != nil {
 		return nil, nil, appErrors.NewInternalError("Failed to get file stream", err)
 	}
@@ -493,9 +1102,34 @@ func (s *documentService) GetFileStream(id uuid.UUID, userID *uuid.UUID) (io.Rea
 	return stream, document, nil
 }
 
+// CheckLoan checks that the user may read the content of a document under controlled
+// digital lending: lent documents need a loan running now, except for the uploader and
+// the collection owner. The loan's end is checked against the clock, so content closes on
+// time even if the lending sweeper has not run.
+func (s *documentService) CheckLoan(document *models.Document, userID *uuid.UUID) error {
+	if document.LendingLicenses == nil || managesDocument(document, userID) {
+		return nil
+	}
+	if userID == nil {
+		return appErrors.NewUnauthorizedError("Sign in and borrow this document to read it", nil)
+	}
+	if s.lendingRepo == nil {
+		return appErrors.NewInternalError("Lending not available", nil)
+	}
+
+	loan, err := s.lendingRepo.FindOpen(document.ID, *userID)
+	if err != nil && !errors.Is(err, repository.ErrDigitalLoanNotFound) {
+		return appErrors.NewInternalError("Failed to check loan", err)
+	}
+	if loan == nil || !loan.IsActive(time.Now()) {
+		return appErrors.NewForbiddenError("Borrow this document to read it", nil)
+	}
+	return nil
+}
+
 // GetThumbnailStream gets a stream for the document thumbnail
-func (s *documentService) GetThumbnailStream(id uuid.UUID, userID *uuid.UUID) (io.ReadCloser, *models.Document, error) {
-	document, err := s.GetDocument(id, userID)
+func (s *documentService) GetThumbnailStream(id uuid.UUID, userID *uuid.UUID) (io.ReadSeekCloser, *models.Document, error) {
+	document, err := s.GetContentDocument(id, userID)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -518,7 +1152,7 @@ func (s *documentService) GetThumbnailStream(id uuid.UUID, userID *uuid.UUID) (i
 		return nil, document, appErrors.NewNotFoundError("Thumbnail file", fmt.Errorf("path: %s", document.ThumbnailPath))
 	}
 
-	stream, err := s.storage.DownloadFile(document.ThumbnailPath)
+	stream, err := s.storage.OpenFile(document.ThumbnailPath)
 	if err != nil {
 		return nil, nil, appErrors.NewInternalError("Failed to get thumbnail stream", err)
 	}
@@ -526,95 +1160,54 @@ func (s *documentService) GetThumbnailStream(id uuid.UUID, userID *uuid.UUID) (i
 	return stream, document, nil
 }
 
-// GetPreviewStream gets a stream for document preview (converting to PDF if necessary)
-func (s *documentService) GetPreviewStream(id uuid.UUID, userID *uuid.UUID) (io.ReadCloser, *models.Document, error) {
-	document, err := s.GetDocument(id, userID)
+// GetPreviewStream gets a stream for document preview. Office documents are served as
+// their cached PDF rendition, which is generated on first view if ingest has not already.
+func (s *documentService) GetPreviewStream(id uuid.UUID, userID *uuid.UUID) (io.ReadSeekCloser, *models.Document, error) {
+	document, err := s.GetContentDocument(id, userID)
 	if err != nil {
 		return nil, nil, err
 	}
-
-	// Check if we need conversion (Office docs)
-	needsConversion := false
-	mimeType := document.MimeType
-	if strings.Contains(mimeType, "msword") ||
-		strings.Contains(mimeType, "officedocument") ||
-		strings.Contains(mimeType, "vnd.oasis.opendocument") {
-		needsConversion = true
+	if err := s.CheckLoan(document, userID); err != nil {
+		return nil, nil, err
 	}
 
-	if !needsConversion {
+	if s.previews == nil || !s.previews.NeedsPreview(document.MimeType) {
 		// Just return original file
 		return s.GetFileStream(id, userID)
 	}
 
-	// CONVERSION LOGIC
-	if s.storage == nil {
-		return nil, nil, appErrors.NewInternalError("Storage unavailable", nil)
-	}
-
-	// 1. Download original file to temp
-	ext := filepath.Ext(document.OriginalFilename)
-	tempOriginal, err := os.CreateTemp("", "preview_orig_*"+ext)
-	if err != nil {
-		return nil, nil, appErrors.NewInternalError("Failed to create temp file", err)
-	}
-	defer os.Remove(tempOriginal.Name()) // Clean up original temp after we are done
-	defer tempOriginal.Close()
-
-	// Check file existence
-	exists, err := s.storage.FileExists(document.StoragePath)
-	if err != nil {
-		return nil, nil, appErrors.NewInternalError("Failed to check file existence", err)
-	}
-	if !exists {
-		return nil, nil, appErrors.NewNotFoundError("Original file for conversion", fmt.Errorf("path: %s", document.StoragePath))
-	}
-
-	originalStream, err := s.storage.DownloadFile(document.StoragePath)
+	key, err := s.previews.Preview(document.Hash, document.StoragePath)
 	if err != nil {
 		return nil, nil, err
 	}
-	defer originalStream.Close()
-
-	if _, err := io.Copy(tempOriginal, originalStream); err != nil {
-		return nil, nil, appErrors.NewInternalError("Failed to download for conversion", err)
-	}
 
-	// 2. Convert to PDF using soffice
-	tempDir, err := os.MkdirTemp("", "preview_out_*")
+	info, err := s.storage.GetFileInfo(key)
 	if err != nil {
-		return nil, nil, appErrors.NewInternalError("Failed to create temp dir", err)
-	}
-	// We can't easily defer RemoveAll(tempDir) here because we need to return the file stream from it.
-	// We will rely on the caller/OS cleanup or a smarter stream wrapper.
-	// actually for now, we can read the whole PDF into memory or use a specialized specific file that we schedule for deletion.
-	// Better approach: Read PDF to memory buffer, then cleanup.
-	// For large files this is bad, but for previews it's acceptable for MVP.
-	defer os.RemoveAll(tempDir)
-
-	cmd := exec.Command("soffice", "--headless", "--convert-to", "pdf", "--outdir", tempDir, tempOriginal.Name())
-	if output, err := cmd.CombinedOutput(); err != nil {
-		fmt.Printf("Conversion failed: %s\n", string(output))
-		return nil, nil, appErrors.NewInternalError("Document conversion failed", err)
+		return nil, nil, appErrors.NewInternalError("Failed to get preview", err)
 	}
-
-	// 3. Find generated PDF
-	baseName := filepath.Base(tempOriginal.Name())
-	pdfName := strings.TrimSuffix(baseName, filepath.Ext(baseName)) + ".pdf"
-	pdfPath := filepath.Join(tempDir, pdfName)
-
-	pdfContent, err := os.ReadFile(pdfPath)
+	stream, err := s.storage.OpenFile(key)
 	if err != nil {
-		return nil, nil, appErrors.NewInternalError("Failed to read converted PDF", err)
+		return nil, nil, appErrors.NewInternalError("Failed to get preview stream", err)
 	}
 
-	// Return memory stream
 	// Update document metadata for the response/viewer (it thinks it's getting a PDF now)
 	previewDoc := *document
-	previewDoc.MimeType = "application/pdf"
-	previewDoc.FileSize = int64(len(pdfContent))
+	previewDoc.MimeType = mimePDF
+	previewDoc.FileSize = info.Size
+	// Cache validators (ETag) must distinguish the rendition from the original file
+	previewDoc.Hash = document.Hash + "-pdf"
 
-	return io.NopCloser(bytes.NewReader(pdfContent)), &previewDoc, nil
+	return stream, &previewDoc, nil
+}
+
+// discardStaged removes a staged upload that was not accepted (best effort)
+func (s *documentService) discardStaged(stagingPath string) {
+	if s.storage == nil {
+		return
+	}
+	if err := s.storage.DeleteFile(stagingPath); err != nil {
+		fmt.Printf("DEBUG: Failed to discard staged upload %s: %v\n", stagingPath, err)
+	}
 }
 
 // getFileType returns a human-readable file type from extension
@@ -0,0 +1,695 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Kyei-Ernest/libsystem/services/document-service/repository"
+	appErrors "github.com/Kyei-Ernest/libsystem/shared/errors"
+	"github.com/Kyei-Ernest/libsystem/shared/jobs"
+	"github.com/Kyei-Ernest/libsystem/shared/models"
+	"github.com/Kyei-Ernest/libsystem/shared/storage"
+	"github.com/google/uuid"
+)
+
+// ExportFormat is the packaging of a collection export
+type ExportFormat string
+
+const (
+	ExportZIP   ExportFormat = "zip"   // Files with manifests and a checksum list
+	ExportBagIt ExportFormat = "bagit" // A zipped BagIt (RFC 8493) bag
+)
+
+// ExportOptions selects what a collection export contains besides the current files
+type ExportOptions struct {
+	Format            ExportFormat `json:"format"`
+	IncludeVersions   bool         `json:"include_versions"`
+	IncludeThumbnails bool         `json:"include_thumbnails"`
+}
+
+// exportPageSize is how many documents are read from the database at a time
+const exportPageSize = 100
+
+// ExportService packages collections for transfer or offline archiving. Exports run as
+// background jobs that stage each document from storage and write it into a ZIP stored
+// back in storage, reachable through a download link until it expires; PurgeExpired then
+// deletes the package.
+type ExportService interface {
+	StartExport(collectionID, userID uuid.UUID, isStaff bool, options ExportOptions) (*jobs.Job, error)
+	PurgeExpired() (int, error)
+}
+
+type exportService struct {
+	documentRepo   repository.DocumentRepository
+	collectionRepo repository.CollectionRepository
+	versionRepo    repository.VersionRepository
+	storage        *storage.MinIOClient
+	jobTracker     *jobs.JobTracker
+	linkTTL        time.Duration
+}
+
+// NewExportService creates a new export service whose packages are kept for linkTTL, and
+// registers its jobs with the tracker
+func NewExportService(
+	documentRepo repository.DocumentRepository,
+	collectionRepo repository.CollectionRepository,
+	versionRepo repository.VersionRepository,
+	storage *storage.MinIOClient,
+	jobTracker *jobs.JobTracker,
+	linkTTL time.Duration,
+) ExportService {
+	s := &exportService{
+		documentRepo:   documentRepo,
+		collectionRepo: collectionRepo,
+		versionRepo:    versionRepo,
+		storage:        storage,
+		jobTracker:     jobTracker,
+		linkTTL:        linkTTL,
+	}
+	jobTracker.Register(jobs.JobTypeCollectionExport, jobs.Handler{Run: s.run, Cleanup: s.cleanup})
+	return s
+}
+
+// exportPayload is what an export job needs
+type exportPayload struct {
+	CollectionID uuid.UUID     `json:"collection_id"`
+	Options      ExportOptions `json:"options"`
+}
+
+// StartExport queues an export of a collection. Staff export any collection; other users
+// export the collections they own.
+func (s *exportService) StartExport(collectionID, userID uuid.UUID, isStaff bool, options ExportOptions) (*jobs.Job, error) {
+	if options.Format == "" {
+		options.Format = ExportZIP
+	}
+	if options.Format != ExportZIP && options.Format != ExportBagIt {
+		return nil, appErrors.NewValidationError("format must be zip or bagit", nil)
+	}
+	if s.storage == nil {
+		return nil, appErrors.NewInternalError("Storage unavailable", nil)
+	}
+
+	collection, err := s.collectionRepo.FindByID(collectionID)
+	if err != nil {
+		return nil, appErrors.NewNotFoundError("Collection", err)
+	}
+	if !isStaff && collection.OwnerID != userID {
+		return nil, appErrors.NewForbiddenError("Only the collection owner or staff can export a collection", nil)
+	}
+
+	_, total, err := s.documentRepo.List(s.exportFilters(collection), 0, 1)
+	if err != nil {
+		return nil, appErrors.NewInternalError("Failed to count documents", err)
+	}
+
+	job, err := s.jobTracker.Enqueue(jobs.JobSpec{
+		Type:      jobs.JobTypeCollectionExport,
+		CreatedBy: userID,
+		Payload:   exportPayload{CollectionID: collection.ID, Options: options},
+		Total:     int(total),
+		Result: jobs.JobData{
+			"collection_id": collection.ID,
+			"format":        options.Format,
+		},
+	})
+	if err != nil {
+		return nil, appErrors.NewInternalError("Failed to queue export", err)
+	}
+	return job, nil
+}
+
+// exportFilters lists every document of the collection, including those outside their
+// availability window, which its owner sees
+func (s *exportService) exportFilters(collection *models.Collection) repository.DocumentFilters {
+	return repository.DocumentFilters{
+		CollectionID: &collection.ID,
+		ViewerID:     &collection.OwnerID,
+	}
+}
+
+// exportKey is where an export job's package is stored
+func exportKey(collectionID, jobID uuid.UUID) string {
+	return fmt.Sprintf("exports/%s/%s.zip", collectionID, jobID)
+}
+
+// run writes the package through a pipe into storage and publishes its link. A job that
+// is run again, after its worker stopped, starts over.
+func (s *exportService) run(ctx context.Context, run *jobs.Run) error {
+	var payload exportPayload
+	if err := run.Job.Payload.Decode(&payload); err != nil {
+		return fmt.Errorf("invalid export payload: %w", err)
+	}
+	collection, err := s.collectionRepo.FindByID(payload.CollectionID)
+	if err != nil {
+		return fmt.Errorf("collection not found: %w", err)
+	}
+	if err := run.Progress(0, 0, ""); err != nil {
+		return err
+	}
+
+	key := exportKey(collection.ID, run.Job.ID)
+	reader, writer := io.Pipe()
+	go func() {
+		writer.CloseWithError(s.writePackage(ctx, writer, run, collection, payload.Options))
+	}()
+
+	// A package that could not be written in full is never stored
+	size, err := s.storage.UploadStream(key, reader, "application/zip")
+	// Stops the writer if the upload gave up first
+	reader.CloseWithError(io.ErrClosedPipe)
+	if ctx.Err() != nil {
+		if err == nil {
+			s.storage.DeleteFile(key)
+		}
+		return ctx.Err()
+	}
+	if err != nil {
+		return fmt.Errorf("export failed: %w", err)
+	}
+
+	expiresAt := time.Now().Add(s.linkTTL)
+	url, err := s.storage.GetPresignedURL(key, s.linkTTL)
+	if err != nil {
+		s.storage.DeleteFile(key)
+		return fmt.Errorf("failed to create download link: %w", err)
+	}
+
+	return run.SetResult(jobs.JobData{
+		"file_size":    size,
+		"download_url": url,
+		"expires_at":   expiresAt,
+	})
+}
+
+// PurgeExpired deletes the packages of exports whose download links have expired
+func (s *exportService) PurgeExpired() (int, error) {
+	if s.storage == nil {
+		return 0, nil
+	}
+
+	cutoff := time.Now().Add(-s.linkTTL)
+	filter := jobs.JobFilter{Type: jobs.JobTypeCollectionExport, Status: jobs.JobStatusCompleted, FinishedBefore: &cutoff}
+	purged := 0
+	for offset := 0; ; offset += exportPageSize {
+		exports, _, err := s.jobTracker.ListJobs(filter, offset, exportPageSize)
+		if err != nil {
+			return purged, err
+		}
+
+		for i := range exports {
+			job := &exports[i]
+			if done, _ := job.Result["purged"].(bool); done {
+				continue
+			}
+			if !s.deletePackage(job) {
+				continue
+			}
+			if err := s.jobTracker.SetResult(job.ID, jobs.JobData{"purged": true, "download_url": nil}); err != nil {
+				return purged, err
+			}
+			purged++
+		}
+
+		if len(exports) < exportPageSize {
+			return purged, nil
+		}
+	}
+}
+
+// cleanup deletes an export's package if it is still there
+func (s *exportService) cleanup(job *jobs.Job, _ []jobs.Item) {
+	if purged, _ := job.Result["purged"].(bool); !purged && job.Status == jobs.JobStatusCompleted {
+		s.deletePackage(job)
+	}
+}
+
+// deletePackage deletes an export's package from storage, reporting whether it did
+func (s *exportService) deletePackage(job *jobs.Job) bool {
+	var payload exportPayload
+	if err := job.Payload.Decode(&payload); err != nil {
+		return false
+	}
+	key := exportKey(payload.CollectionID, job.ID)
+	if err := s.storage.DeleteFile(key); err != nil {
+		fmt.Printf("DEBUG: Failed to delete expired export %s: %v\n", key, err)
+		return false
+	}
+	return true
+}
+
+// exportedFile is a payload file and its SHA-256
+type exportedFile struct {
+	Path   string
+	SHA256 string
+	Size   int64
+}
+
+// exportedVersion describes a version in the manifest
+type exportedVersion struct {
+	VersionNumber    int       `json:"version_number"`
+	OriginalFilename string    `json:"original_filename"`
+	MimeType         string    `json:"mime_type"`
+	FileSize         int64     `json:"file_size"`
+	SHA256           string    `json:"sha256"`
+	Path             string    `json:"path"`
+	ChangeLog        string    `json:"change_log,omitempty"`
+	CreatedAt        time.Time `json:"created_at"`
+}
+
+// exportedDocument describes a document in the manifest
+type exportedDocument struct {
+	ID               uuid.UUID               `json:"id"`
+	Title            string                  `json:"title"`
+	Description      string                  `json:"description,omitempty"`
+	Status           models.DocumentStatus   `json:"status"`
+	Metadata         models.DocumentMetadata `json:"metadata"`
+	OriginalFilename string                  `json:"original_filename"`
+	MimeType         string                  `json:"mime_type"`
+	FileSize         int64                   `json:"file_size"`
+	PageCount        int                     `json:"page_count,omitempty"`
+	SHA256           string                  `json:"sha256"`
+	Path             string                  `json:"path"`
+	ThumbnailPath    string                  `json:"thumbnail_path,omitempty"`
+	Versions         []exportedVersion       `json:"versions,omitempty"`
+	CreatedAt        time.Time               `json:"created_at"`
+	UpdatedAt        time.Time               `json:"updated_at"`
+}
+
+// exportManifest is the package's manifest.json
+type exportManifest struct {
+	Collection struct {
+		ID          uuid.UUID `json:"id"`
+		Name        string    `json:"name"`
+		Slug        string    `json:"slug"`
+		Description string    `json:"description,omitempty"`
+	} `json:"collection"`
+	Format     ExportFormat       `json:"format"`
+	ExportedAt time.Time          `json:"exported_at"`
+	Documents  []exportedDocument `json:"documents"`
+}
+
+// packageWriter writes entries under a root directory of a ZIP, remembering the
+// checksums of payload files
+type packageWriter struct {
+	zip     *zip.Writer
+	root    string
+	payload string // Payload directory under root ("data/" in bags)
+	files   []exportedFile
+}
+
+// copy writes an entry to the payload from r, stored rather than compressed as most
+// documents already are
+func (p *packageWriter) copy(name string, r io.Reader) (exportedFile, error) {
+	entry, err := p.zip.CreateHeader(&zip.FileHeader{
+		Name:     p.root + p.payload + name,
+		Method:   zip.Store,
+		Modified: time.Now(),
+	})
+	if err != nil {
+		return exportedFile{}, err
+	}
+
+	hasher := sha256.New()
+	size, err := io.Copy(io.MultiWriter(entry, hasher), r)
+	if err != nil {
+		return exportedFile{}, err
+	}
+
+	file := exportedFile{Path: name, SHA256: hex.EncodeToString(hasher.Sum(nil)), Size: size}
+	p.files = append(p.files, file)
+	return file, nil
+}
+
+// write writes a small entry outside the payload and returns its checksum
+func (p *packageWriter) write(name string, content []byte) (string, error) {
+	entry, err := p.zip.CreateHeader(&zip.FileHeader{
+		Name:     p.root + name,
+		Method:   zip.Deflate,
+		Modified: time.Now(),
+	})
+	if err != nil {
+		return "", err
+	}
+	if _, err := entry.Write(content); err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// writePackage writes the export to w. Documents whose files can't be read or don't match
+// their checksums are left out entirely and reported on the job; anything else, including
+// cancellation, ends the export.
+func (s *exportService) writePackage(ctx context.Context, w io.Writer, run *jobs.Run, collection *models.Collection, options ExportOptions) error {
+	pkg := &packageWriter{
+		zip:  zip.NewWriter(w),
+		root: exportName(collection.Slug, collection.ID.String()) + "/",
+	}
+	if options.Format == ExportBagIt {
+		pkg.payload = "data/"
+	}
+
+	manifest := exportManifest{
+		Format:     options.Format,
+		ExportedAt: time.Now().UTC(),
+		Documents:  []exportedDocument{},
+	}
+	manifest.Collection.ID = collection.ID
+	manifest.Collection.Name = collection.Name
+	manifest.Collection.Slug = collection.Slug
+	manifest.Collection.Description = collection.Description
+
+	completed, failed := 0, 0
+	seen := make(map[uuid.UUID]bool)
+	for offset := 0; ; offset += exportPageSize {
+		documents, _, err := s.documentRepo.List(s.exportFilters(collection), offset, exportPageSize)
+		if err != nil {
+			return fmt.Errorf("failed to list documents: %w", err)
+		}
+
+		for i := range documents {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			document := &documents[i]
+			// Pages shift when documents are added during the export
+			if seen[document.ID] {
+				continue
+			}
+			seen[document.ID] = true
+
+			exported, err := s.exportDocument(pkg, document, options)
+			var writeErr *exportWriteError
+			if errors.As(err, &writeErr) {
+				return err
+			}
+			if err != nil {
+				failed++
+				if err := run.Progress(completed, failed, fmt.Sprintf("Document %s: %v", document.ID, err)); err != nil {
+					return err
+				}
+				continue
+			}
+			manifest.Documents = append(manifest.Documents, *exported)
+			completed++
+			if err := run.Progress(completed, failed, ""); err != nil {
+				return err
+			}
+		}
+
+		if len(documents) < exportPageSize {
+			break
+		}
+	}
+
+	manifestJSON, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode manifest: %w", err)
+	}
+	if _, err := pkg.copy("manifest.json", bytes.NewReader(manifestJSON)); err != nil {
+		return err
+	}
+	manifestCSV, err := manifestCSV(manifest.Documents)
+	if err != nil {
+		return fmt.Errorf("failed to encode manifest: %w", err)
+	}
+	if _, err := pkg.copy("manifest.csv", bytes.NewReader(manifestCSV)); err != nil {
+		return err
+	}
+
+	if options.Format == ExportBagIt {
+		if err := writeBagTags(pkg, collection); err != nil {
+			return err
+		}
+	} else if _, err := pkg.write("checksums.sha256", checksumList(pkg.files, "")); err != nil {
+		return err
+	}
+
+	return pkg.zip.Close()
+}
+
+// exportDocument adds a document's file, and optionally its versions and thumbnail, under
+// files/{id}/. Everything is downloaded and checked against the recorded hashes before
+// anything is written, so a document that can't be exported leaves nothing in the
+// package; an error writing it, after that, ends the export.
+func (s *exportService) exportDocument(pkg *packageWriter, document *models.Document, options ExportOptions) (*exportedDocument, error) {
+	stage := &exportStage{}
+	defer stage.discard()
+
+	exported, err := s.stageDocument(stage, document, options)
+	if err != nil {
+		return nil, err
+	}
+	if err := stage.writeTo(pkg); err != nil {
+		return nil, &exportWriteError{err: err}
+	}
+	return exported, nil
+}
+
+// stageDocument downloads a document's files and describes it for the manifest
+func (s *exportService) stageDocument(stage *exportStage, document *models.Document, options ExportOptions) (*exportedDocument, error) {
+	dir := "files/" + document.ID.String() + "/"
+	filename := exportName(document.OriginalFilename, "document")
+
+	file, err := s.stageObject(stage, document.StoragePath, dir+filename)
+	if err != nil {
+		return nil, err
+	}
+	if document.Hash != "" && !strings.EqualFold(file.SHA256, document.Hash) {
+		return nil, fmt.Errorf("content does not match its recorded checksum")
+	}
+
+	exported := &exportedDocument{
+		ID:               document.ID,
+		Title:            document.Title,
+		Description:      document.Description,
+		Status:           document.Status,
+		Metadata:         document.Metadata,
+		OriginalFilename: document.OriginalFilename,
+		MimeType:         document.MimeType,
+		FileSize:         file.Size,
+		PageCount:        document.PageCount,
+		SHA256:           document.Hash,
+		Path:             file.Path,
+		CreatedAt:        document.CreatedAt,
+		UpdatedAt:        document.UpdatedAt,
+	}
+
+	if options.IncludeThumbnails && document.ThumbnailPath != "" {
+		thumbnail, err := s.stageObject(stage, document.ThumbnailPath, dir+"thumbnail"+path.Ext(document.ThumbnailPath))
+		if err != nil {
+			return nil, fmt.Errorf("thumbnail: %w", err)
+		}
+		exported.ThumbnailPath = thumbnail.Path
+	}
+
+	if options.IncludeVersions {
+		versions, err := s.versionRepo.GetByDocumentID(document.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list versions: %w", err)
+		}
+		for _, version := range versions {
+			name := fmt.Sprintf("%sversions/v%d/%s", dir, version.VersionNumber, exportName(version.OriginalFilename, "document"))
+			file, err := s.stageObject(stage, version.StoragePath, name)
+			if err != nil {
+				return nil, fmt.Errorf("version %d: %w", version.VersionNumber, err)
+			}
+			if version.Hash != "" && !strings.EqualFold(file.SHA256, version.Hash) {
+				return nil, fmt.Errorf("version %d does not match its recorded checksum", version.VersionNumber)
+			}
+			exported.Versions = append(exported.Versions, exportedVersion{
+				VersionNumber:    version.VersionNumber,
+				OriginalFilename: version.OriginalFilename,
+				MimeType:         version.MimeType,
+				FileSize:         file.Size,
+				SHA256:           version.Hash,
+				Path:             file.Path,
+				ChangeLog:        version.ChangeLog,
+				CreatedAt:        version.CreatedAt,
+			})
+		}
+	}
+
+	return exported, nil
+}
+
+// stageObject downloads an object from storage into the stage
+func (s *exportService) stageObject(stage *exportStage, objectName, name string) (exportedFile, error) {
+	object, err := s.storage.DownloadFile(objectName)
+	if err != nil {
+		return exportedFile{}, err
+	}
+	defer object.Close()
+	return stage.add(name, object)
+}
+
+// exportWriteError is a failure to write a staged document into the package, which
+// leaves the package incomplete
+type exportWriteError struct {
+	err error
+}
+
+func (e *exportWriteError) Error() string {
+	return "failed to write package: " + e.err.Error()
+}
+
+func (e *exportWriteError) Unwrap() error {
+	return e.err
+}
+
+// stagedExportFile is a payload file downloaded to a temporary file
+type stagedExportFile struct {
+	exportedFile
+	temp *os.File
+}
+
+// exportStage holds a document's files until all of them have been read
+type exportStage struct {
+	files []stagedExportFile
+}
+
+// add copies r to a temporary file, hashing it, as the payload file name
+func (e *exportStage) add(name string, r io.Reader) (exportedFile, error) {
+	temp, err := os.CreateTemp("", "export-*")
+	if err != nil {
+		return exportedFile{}, err
+	}
+	staged := stagedExportFile{exportedFile: exportedFile{Path: name}, temp: temp}
+	e.files = append(e.files, staged)
+
+	hasher := sha256.New()
+	size, err := io.Copy(io.MultiWriter(temp, hasher), r)
+	if err != nil {
+		return exportedFile{}, err
+	}
+	staged.SHA256 = hex.EncodeToString(hasher.Sum(nil))
+	staged.Size = size
+	e.files[len(e.files)-1] = staged
+	return staged.exportedFile, nil
+}
+
+// writeTo copies the staged files into the package's payload
+func (e *exportStage) writeTo(pkg *packageWriter) error {
+	for _, staged := range e.files {
+		if _, err := staged.temp.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		file, err := pkg.copy(staged.Path, staged.temp)
+		if err != nil {
+			return err
+		}
+		if file.SHA256 != staged.SHA256 {
+			return fmt.Errorf("%s changed while it was staged", staged.Path)
+		}
+	}
+	return nil
+}
+
+// discard removes the temporary files
+func (e *exportStage) discard() {
+	for _, staged := range e.files {
+		staged.temp.Close()
+		os.Remove(staged.temp.Name())
+	}
+}
+
+// writeBagTags writes the bag declaration, bag info and the payload and tag manifests
+func writeBagTags(pkg *packageWriter, collection *models.Collection) error {
+	var octets int64
+	for _, file := range pkg.files {
+		octets += file.Size
+	}
+
+	tags := []struct {
+		name    string
+		content []byte
+	}{
+		{"bagit.txt", []byte("BagIt-Version: 1.0\nTag-File-Character-Encoding: UTF-8\n")},
+		{"bag-info.txt", []byte(fmt.Sprintf(
+			"Source-Organization: libsystem\nExternal-Identifier: %s\nExternal-Description: %s\nBagging-Date: %s\nPayload-Oxum: %d.%d\n",
+			collection.ID, bagInfoValue(collection.Name), time.Now().UTC().Format("2006-01-02"), octets, len(pkg.files),
+		))},
+		{"manifest-sha256.txt", checksumList(pkg.files, pkg.payload)},
+	}
+
+	var tagFiles []exportedFile
+	for _, tag := range tags {
+		sum, err := pkg.write(tag.name, tag.content)
+		if err != nil {
+			return err
+		}
+		tagFiles = append(tagFiles, exportedFile{Path: tag.name, SHA256: sum})
+	}
+
+	_, err := pkg.write("tagmanifest-sha256.txt", checksumList(tagFiles, ""))
+	return err
+}
+
+// checksumList formats files as "checksum  path" lines, the format of sha256sum and of
+// BagIt manifests, which percent-encode line breaks and "%" in paths
+func checksumList(files []exportedFile, prefix string) []byte {
+	encoder := strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A")
+	var buf bytes.Buffer
+	for _, file := range files {
+		fmt.Fprintf(&buf, "%s  %s\n", file.SHA256, encoder.Replace(prefix+file.Path))
+	}
+	return buf.Bytes()
+}
+
+// manifestCSV is a row per document file, with the fields most often needed on import
+func manifestCSV(documents []exportedDocument) ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Write([]string{"id", "title", "author", "publisher", "publish_date", "isbn", "tags", "original_filename", "mime_type", "file_size", "sha256", "path", "created_at"})
+	for _, document := range documents {
+		w.Write([]string{
+			document.ID.String(),
+			document.Title,
+			document.Metadata.Author,
+			document.Metadata.Publisher,
+			document.Metadata.PublishDate,
+			document.Metadata.ISBN,
+			strings.Join(document.Metadata.Tags, ";"),
+			document.OriginalFilename,
+			document.MimeType,
+			strconv.FormatInt(document.FileSize, 10),
+			document.SHA256,
+			document.Path,
+			document.CreatedAt.UTC().Format(time.RFC3339),
+		})
+	}
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
+
+// exportName makes a name safe as a single path segment, falling back when nothing is left
+func exportName(name, fallback string) string {
+	name = strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f || r == '/' || r == '\\' || r == ':' {
+			return '_'
+		}
+		return r
+	}, strings.TrimSpace(name))
+	if name == "" || name == "." || name == ".." {
+		return fallback
+	}
+	return name
+}
+
+// bagInfoValue keeps a bag-info value on one line
+func bagInfoValue(value string) string {
+	return strings.Join(strings.Fields(value), " ")
+}
@@ -16,6 +16,7 @@ type FileService interface {
 	GenerateHash(file io.Reader) (string, error)
 	ValidateFileType(mimeType string) error
 	ValidateFileSize(size int64) error
+	MaxFileSize() int64
 	GetFileExtension(filename string) string
 }
 
@@ -25,10 +26,17 @@ type fileService struct {
 	allowedTypes map[string]bool
 }
 
+// DefaultMaxFileSize is the upload size limit used when none is configured
+const DefaultMaxFileSize int64 = 100 * 1024 * 1024 // 100MB
+
 // NewFileService creates a new file service
-func NewFileService() FileService {
+// maxFileSize <= 0 falls back to DefaultMaxFileSize
+func NewFileService(maxFileSize int64) FileService {
+	if maxFileSize <= 0 {
+		maxFileSize = DefaultMaxFileSize
+	}
 	return &fileService{
-		maxFileSize: 100 * 1024 * 1024, // 100MB
+		maxFileSize: maxFileSize,
 		allowedTypes: map[string]bool{
 			"application/pdf":    true,
 			"application/msword": true,
@@ -50,9 +58,9 @@ func (s *fileService) GenerateHash(file io.Reader) (string, error) {
 	return hex.EncodeToString(hasher.Sum(nil)), nil
 }
 
-// ValidateFileType validates if the file type is allowed
+// ValidateFileType validates if the file type is allowed. Parameters such as charset are ignored.
 func (s *fileService) ValidateFileType(mimeType string) error {
-	if !s.allowedTypes[mimeType] {
+	if !s.allowedTypes[baseMediaType(mimeType)] {
 		return appErrors.NewValidationError(
 			fmt.Sprintf("File type '%s' is not allowed. Allowed types: PDF, DOCX, TXT, HTML, EPUB", mimeType),
 			nil,
@@ -75,6 +83,11 @@ func (s *fileService) ValidateFileSize(size int64) error {
 	return nil
 }
 
+// MaxFileSize returns the configured maximum upload size in bytes
+func (s *fileService) MaxFileSize() int64 {
+	return s.maxFileSize
+}
+
 // GetFileExtension extracts the file extension from filename
 func (s *fileService) GetFileExtension(filename string) string {
 	ext := filepath.Ext(filename)
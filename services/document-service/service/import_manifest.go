@@ -0,0 +1,263 @@
+package service
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/Kyei-Ernest/libsystem/shared/models"
+)
+
+// ManifestFormat is the format of a bulk import manifest
+type ManifestFormat string
+
+const (
+	ManifestCSV   ManifestFormat = "csv"   // A header row, then a row per file
+	ManifestJSONL ManifestFormat = "jsonl" // A JSON object per line
+)
+
+// importMaxRows caps the rows of one manifest
+const importMaxRows = 10000
+
+// importMaxLine caps a JSON Lines manifest line
+const importMaxLine = 1 << 20 // 1 MB
+
+// ImportRow is a file to import and the document to create for it. Row is its row number
+// in the manifest, counting data rows from 1.
+type ImportRow struct {
+	Row         int                     `json:"row"`
+	File        string                  `json:"file"`                 // Path in the archive or under the source directory
+	Collection  string                  `json:"collection,omitempty"` // Collection ID or slug; the import's default when empty
+	Title       string                  `json:"title,omitempty"`      // The file's name without extension when empty
+	Description string                  `json:"description,omitempty"`
+	Metadata    models.DocumentMetadata `json:"metadata"`
+}
+
+// ImportRowError reports why a manifest row can't be imported
+type ImportRowError struct {
+	Row   int    `json:"row"`
+	File  string `json:"file,omitempty"`
+	Error string `json:"error"`
+}
+
+// manifestFormatFor infers a manifest's format from its file name
+func manifestFormatFor(filename string) (ManifestFormat, bool) {
+	switch strings.ToLower(path.Ext(filename)) {
+	case ".csv":
+		return ManifestCSV, true
+	case ".jsonl", ".ndjson":
+		return ManifestJSONL, true
+	}
+	return "", false
+}
+
+// parseManifest reads the rows of a manifest. Rows that can't be read are reported
+// rather than failing the manifest; so are columns (or JSON fields) it ignores.
+func parseManifest(r io.Reader, format ManifestFormat) ([]ImportRow, []ImportRowError, []string, error) {
+	switch format {
+	case ManifestCSV:
+		return parseCSVManifest(r)
+	case ManifestJSONL:
+		return parseJSONLManifest(r)
+	}
+	return nil, nil, nil, fmt.Errorf("unsupported manifest format %q", format)
+}
+
+// parseCSVManifest maps the header's columns onto rows. Tags are separated by ";", and
+// "custom.<name>" columns fill custom fields. "path" is accepted for the file, so the
+// manifest.csv of a collection export can be imported again.
+func parseCSVManifest(r io.Reader) ([]ImportRow, []ImportRowError, []string, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, nil, nil, fmt.Errorf("manifest is empty")
+	}
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to read manifest header: %w", err)
+	}
+
+	columns := make([]string, len(header))
+	var ignored []string
+	hasFile := false
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff"))) // Byte order mark
+		switch {
+		case name == "file" || name == "path" || name == "filename":
+			columns[i] = "file"
+			hasFile = true
+		case name == "collection" || name == "collection_id":
+			columns[i] = "collection"
+		case name == "title" || name == "description" || name == "author" || name == "publisher" ||
+			name == "publish_date" || name == "isbn" || name == "tags":
+			columns[i] = name
+		case strings.HasPrefix(name, "custom.") && len(name) > len("custom."):
+			// Custom field names keep their case
+			columns[i] = "custom." + strings.TrimSpace(header[i])[len("custom."):]
+		default:
+			ignored = append(ignored, strings.TrimSpace(header[i]))
+		}
+	}
+	if !hasFile {
+		return nil, nil, nil, fmt.Errorf("manifest has no file column")
+	}
+
+	var rows []ImportRow
+	var rowErrors []ImportRowError
+	for number := 1; ; number++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if number > importMaxRows {
+			return nil, nil, nil, fmt.Errorf("manifest has more than %d rows", importMaxRows)
+		}
+		if err != nil {
+			if _, ok := err.(*csv.ParseError); !ok {
+				return nil, nil, nil, fmt.Errorf("failed to read manifest: %w", err)
+			}
+			rowErrors = append(rowErrors, ImportRowError{Row: number, Error: err.Error()})
+			continue
+		}
+
+		row := ImportRow{Row: number}
+		for i, value := range record {
+			if i >= len(columns) {
+				break
+			}
+			value = strings.TrimSpace(value)
+			switch column := columns[i]; column {
+			case "file":
+				row.File = value
+			case "collection":
+				row.Collection = value
+			case "title":
+				row.Title = value
+			case "description":
+				row.Description = value
+			case "author":
+				row.Metadata.Author = value
+			case "publisher":
+				row.Metadata.Publisher = value
+			case "publish_date":
+				row.Metadata.PublishDate = value
+			case "isbn":
+				row.Metadata.ISBN = value
+			case "tags":
+				for _, tag := range strings.Split(value, ";") {
+					if tag = strings.TrimSpace(tag); tag != "" {
+						row.Metadata.Tags = append(row.Metadata.Tags, tag)
+					}
+				}
+			default:
+				if strings.HasPrefix(column, "custom.") && value != "" {
+					if row.Metadata.CustomFields == nil {
+						row.Metadata.CustomFields = make(map[string]interface{})
+					}
+					row.Metadata.CustomFields[column[len("custom."):]] = value
+				}
+			}
+		}
+		rows = append(rows, row)
+	}
+
+	return rows, rowErrors, ignored, nil
+}
+
+// jsonlManifestRow is a line of a JSON Lines manifest
+type jsonlManifestRow struct {
+	File         string                 `json:"file"`
+	Path         string                 `json:"path"`
+	Collection   string                 `json:"collection"`
+	CollectionID string                 `json:"collection_id"`
+	Title        string                 `json:"title"`
+	Description  string                 `json:"description"`
+	Author       string                 `json:"author"`
+	Publisher    string                 `json:"publisher"`
+	PublishDate  string                 `json:"publish_date"`
+	ISBN         string                 `json:"isbn"`
+	Tags         []string               `json:"tags"`
+	CustomFields map[string]interface{} `json:"custom_fields"`
+}
+
+// jsonlManifestFields are the fields of jsonlManifestRow, to report the others
+var jsonlManifestFields = map[string]bool{
+	"file": true, "path": true, "collection": true, "collection_id": true, "title": true,
+	"description": true, "author": true, "publisher": true, "publish_date": true,
+	"isbn": true, "tags": true, "custom_fields": true,
+}
+
+// parseJSONLManifest reads a JSON object per line; blank lines are skipped but counted
+func parseJSONLManifest(r io.Reader) ([]ImportRow, []ImportRowError, []string, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), importMaxLine)
+
+	var rows []ImportRow
+	var rowErrors []ImportRowError
+	ignored := make(map[string]bool)
+	for number := 1; scanner.Scan(); number++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		if number > importMaxRows {
+			return nil, nil, nil, fmt.Errorf("manifest has more than %d rows", importMaxRows)
+		}
+
+		var fields map[string]json.RawMessage
+		var entry jsonlManifestRow
+		if err := json.Unmarshal([]byte(line), &fields); err != nil {
+			rowErrors = append(rowErrors, ImportRowError{Row: number, Error: "invalid JSON: " + err.Error()})
+			continue
+		}
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			rowErrors = append(rowErrors, ImportRowError{Row: number, Error: "invalid JSON: " + err.Error()})
+			continue
+		}
+		for name := range fields {
+			if !jsonlManifestFields[name] {
+				ignored[name] = true
+			}
+		}
+
+		row := ImportRow{
+			Row:         number,
+			File:        strings.TrimSpace(entry.File),
+			Collection:  strings.TrimSpace(entry.Collection),
+			Title:       strings.TrimSpace(entry.Title),
+			Description: entry.Description,
+			Metadata: models.DocumentMetadata{
+				Author:       entry.Author,
+				Publisher:    entry.Publisher,
+				PublishDate:  entry.PublishDate,
+				ISBN:         entry.ISBN,
+				Tags:         entry.Tags,
+				CustomFields: entry.CustomFields,
+			},
+		}
+		if row.File == "" {
+			row.File = strings.TrimSpace(entry.Path)
+		}
+		if row.Collection == "" {
+			row.Collection = strings.TrimSpace(entry.CollectionID)
+		}
+		rows = append(rows, row)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+
+	names := make([]string, 0, len(ignored))
+	for name := range ignored {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return rows, rowErrors, names, nil
+}
@@ -0,0 +1,216 @@
+package service
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Kyei-Ernest/libsystem/shared/models"
+)
+
+func TestManifestFormatFor(t *testing.T) {
+	tests := []struct {
+		filename string
+		want     ManifestFormat
+		ok       bool
+	}{
+		{"manifest.csv", ManifestCSV, true},
+		{"MANIFEST.CSV", ManifestCSV, true},
+		{"rows.jsonl", ManifestJSONL, true},
+		{"rows.ndjson", ManifestJSONL, true},
+		{"rows.json", "", false},
+		{"manifest", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.filename, func(t *testing.T) {
+			got, ok := manifestFormatFor(tt.filename)
+			if got != tt.want || ok != tt.ok {
+				t.Errorf("manifestFormatFor() = %q, %v, want %q, %v", got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestParseCSVManifest(t *testing.T) {
+	tests := []struct {
+		name        string
+		manifest    string
+		wantRows    []ImportRow
+		wantErrors  []int // rows reported as unreadable
+		wantIgnored []string
+		wantErr     string
+	}{
+		{
+			name: "all columns",
+			manifest: "file,collection,title,description,author,publisher,publish_date,isbn,tags,custom.Shelf\n" +
+				"books/a.pdf,rare-books,A,About A,Ann,Pub,2001,978-0,history; maps ;,B12\n",
+			wantRows: []ImportRow{{
+				Row: 1, File: "books/a.pdf", Collection: "rare-books", Title: "A", Description: "About A",
+				Metadata: models.DocumentMetadata{
+					Author: "Ann", Publisher: "Pub", PublishDate: "2001", ISBN: "978-0",
+					Tags:         []string{"history", "maps"},
+					CustomFields: map[string]interface{}{"Shelf": "B12"},
+				},
+			}},
+		},
+		{
+			name:     "export manifest with path and collection_id",
+			manifest: "\ufeffPath, Collection_ID ,Title\ndata/a.pdf,3f2504e0-4f89-11d3-9a0c-0305e82c3301,A\n",
+			wantRows: []ImportRow{{Row: 1, File: "data/a.pdf", Collection: "3f2504e0-4f89-11d3-9a0c-0305e82c3301", Title: "A"}},
+		},
+		{
+			name:        "unknown columns are ignored and reported",
+			manifest:    "file,Shelf Mark,title,custom.\na.pdf,B12,A,x\n",
+			wantRows:    []ImportRow{{Row: 1, File: "a.pdf", Title: "A"}},
+			wantIgnored: []string{"Shelf Mark", "custom."},
+		},
+		{
+			name:     "short and long rows",
+			manifest: "file,title\na.pdf\nb.pdf,B,extra\n",
+			wantRows: []ImportRow{{Row: 1, File: "a.pdf"}, {Row: 2, File: "b.pdf", Title: "B"}},
+		},
+		{
+			name:       "malformed row is reported and the rest are read",
+			manifest:   "file,title\na.pdf,A\nb.pdf,\"unterminated\nc.pdf,C\n",
+			wantRows:   []ImportRow{{Row: 1, File: "a.pdf", Title: "A"}},
+			wantErrors: []int{2},
+		},
+		{
+			name:       "bare quote",
+			manifest:   "file,title\na\"b.pdf,A\nc.pdf,C\n",
+			wantRows:   []ImportRow{{Row: 2, File: "c.pdf", Title: "C"}},
+			wantErrors: []int{1},
+		},
+		{
+			name:     "missing file column",
+			manifest: "title,author\nA,Ann\n",
+			wantErr:  "no file column",
+		},
+		{
+			name:     "empty manifest",
+			manifest: "",
+			wantErr:  "empty",
+		},
+		{
+			name:     "too many rows",
+			manifest: "file\n" + strings.Repeat("a.pdf\n", importMaxRows+1),
+			wantErr:  "more than",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows, rowErrors, ignored, err := parseManifest(strings.NewReader(tt.manifest), ManifestCSV)
+			checkParsedManifest(t, rows, rowErrors, ignored, err, tt.wantRows, tt.wantErrors, tt.wantIgnored, tt.wantErr)
+		})
+	}
+}
+
+func TestParseJSONLManifest(t *testing.T) {
+	tests := []struct {
+		name        string
+		manifest    string
+		wantRows    []ImportRow
+		wantErrors  []int
+		wantIgnored []string
+		wantErr     string
+	}{
+		{
+			name: "all fields",
+			manifest: `{"file":" books/a.pdf ","collection":"rare-books","title":"A","description":"About A",` +
+				`"author":"Ann","publisher":"Pub","publish_date":"2001","isbn":"978-0","tags":["maps"],"custom_fields":{"shelf":"B12"}}` + "\n",
+			wantRows: []ImportRow{{
+				Row: 1, File: "books/a.pdf", Collection: "rare-books", Title: "A", Description: "About A",
+				Metadata: models.DocumentMetadata{
+					Author: "Ann", Publisher: "Pub", PublishDate: "2001", ISBN: "978-0",
+					Tags:         []string{"maps"},
+					CustomFields: map[string]interface{}{"shelf": "B12"},
+				},
+			}},
+		},
+		{
+			name:     "path and collection_id",
+			manifest: `{"path":"data/a.pdf","collection_id":"rare-books"}` + "\n",
+			wantRows: []ImportRow{{Row: 1, File: "data/a.pdf", Collection: "rare-books"}},
+		},
+		{
+			name:        "unknown fields are ignored and reported once",
+			manifest:    `{"file":"a.pdf","shelf":"B12"}` + "\n" + `{"file":"b.pdf","shelf":"B13","Copies":2}` + "\n",
+			wantRows:    []ImportRow{{Row: 1, File: "a.pdf"}, {Row: 2, File: "b.pdf"}},
+			wantIgnored: []string{"Copies", "shelf"},
+		},
+		{
+			name:     "blank lines are counted",
+			manifest: `{"file":"a.pdf"}` + "\n\n   \n" + `{"file":"b.pdf"}`,
+			wantRows: []ImportRow{{Row: 1, File: "a.pdf"}, {Row: 4, File: "b.pdf"}},
+		},
+		{
+			name: "malformed lines are reported and the rest are read",
+			manifest: `{"file":"a.pdf"}` + "\n" +
+				`{"file":"b.pdf"` + "\n" + // truncated object
+				`["c.pdf"]` + "\n" + // not an object
+				`{"file":"d.pdf","tags":"maps"}` + "\n" + // wrong type
+				`{"file":"e.pdf"} trailing` + "\n" +
+				`{"file":"f.pdf"}` + "\n",
+			wantRows:   []ImportRow{{Row: 1, File: "a.pdf"}, {Row: 6, File: "f.pdf"}},
+			wantErrors: []int{2, 3, 4, 5},
+		},
+		{
+			name:     "too many rows",
+			manifest: strings.Repeat(`{"file":"a.pdf"}`+"\n", importMaxRows+1),
+			wantErr:  "more than",
+		},
+		{
+			name:     "line too long",
+			manifest: `{"file":"a.pdf","description":"` + strings.Repeat("x", importMaxLine) + `"}` + "\n",
+			wantErr:  "failed to read manifest",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows, rowErrors, ignored, err := parseManifest(strings.NewReader(tt.manifest), ManifestJSONL)
+			checkParsedManifest(t, rows, rowErrors, ignored, err, tt.wantRows, tt.wantErrors, tt.wantIgnored, tt.wantErr)
+		})
+	}
+}
+
+func TestParseManifestUnsupportedFormat(t *testing.T) {
+	if _, _, _, err := parseManifest(strings.NewReader("file\na.pdf\n"), "xlsx"); err == nil {
+		t.Error("parseManifest() accepted an unsupported format")
+	}
+}
+
+func checkParsedManifest(t *testing.T, rows []ImportRow, rowErrors []ImportRowError, ignored []string, err error,
+	wantRows []ImportRow, wantErrors []int, wantIgnored []string, wantErr string) {
+	t.Helper()
+	if wantErr != "" {
+		if err == nil || !strings.Contains(err.Error(), wantErr) {
+			t.Fatalf("parseManifest() error = %v, want one containing %q", err, wantErr)
+		}
+		return
+	}
+	if err != nil {
+		t.Fatalf("parseManifest() unexpected error: %v", err)
+	}
+
+	if !reflect.DeepEqual(rows, wantRows) {
+		t.Errorf("rows = %+v, want %+v", rows, wantRows)
+	}
+	var errorRows []int
+	for _, rowError := range rowErrors {
+		if rowError.Error == "" {
+			t.Errorf("row %d reported without a message", rowError.Row)
+		}
+		errorRows = append(errorRows, rowError.Row)
+	}
+	if !reflect.DeepEqual(errorRows, wantErrors) {
+		t.Errorf("rows with errors = %v, want %v", errorRows, wantErrors)
+	}
+	if len(ignored) != 0 || len(wantIgnored) != 0 {
+		if !reflect.DeepEqual(ignored, wantIgnored) {
+			t.Errorf("ignored columns = %v, want %v", ignored, wantIgnored)
+		}
+	}
+}
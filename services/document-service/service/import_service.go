@@ -0,0 +1,567 @@
+package service
+
+import (
+	"archive/zip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/Kyei-Ernest/libsystem/services/document-service/repository"
+	appErrors "github.com/Kyei-Ernest/libsystem/shared/errors"
+	"github.com/Kyei-Ernest/libsystem/shared/jobs"
+	"github.com/Kyei-Ernest/libsystem/shared/storage"
+	"github.com/google/uuid"
+)
+
+// ImportRequest describes a bulk import: a manifest, and the files it names either in an
+// uploaded ZIP or in a directory under the server's import source root
+type ImportRequest struct {
+	UserID       uuid.UUID
+	IsStaff      bool
+	Manifest     *multipart.FileHeader
+	Format       ManifestFormat // Inferred from the manifest's name when empty
+	Archive      *multipart.FileHeader
+	SourceDir    string     // Relative to the import source root; staff only
+	CollectionID *uuid.UUID // For rows that name no collection
+	DryRun       bool
+}
+
+// ImportReport is the outcome of validating a manifest and, unless it was a dry run
+// or rows are invalid, the job importing it
+type ImportReport struct {
+	Rows           int              `json:"rows"`
+	Valid          int              `json:"valid"`
+	Errors         []ImportRowError `json:"errors"`
+	IgnoredColumns []string         `json:"ignored_columns,omitempty"`
+	Job            *jobs.Job        `json:"job,omitempty"`
+}
+
+// ImportService imports documents in bulk from a manifest with a row per file. Manifests
+// are validated in full before anything is imported. The import is a job with an item
+// per row, so retrying a job that partly failed imports only the rows that failed;
+// content already in its collection counts as imported.
+type ImportService interface {
+	StartImport(request ImportRequest) (*ImportReport, error)
+}
+
+type importService struct {
+	documentService DocumentService
+	collectionRepo  repository.CollectionRepository
+	storage         *storage.MinIOClient
+	jobTracker      *jobs.JobTracker
+	sourceRoot      string // Server-side source directories live under it; empty disables them
+}
+
+// NewImportService creates a new import service and registers its jobs with the tracker
+func NewImportService(
+	documentService DocumentService,
+	collectionRepo repository.CollectionRepository,
+	storage *storage.MinIOClient,
+	jobTracker *jobs.JobTracker,
+	sourceRoot string,
+) ImportService {
+	s := &importService{
+		documentService: documentService,
+		collectionRepo:  collectionRepo,
+		storage:         storage,
+		jobTracker:      jobTracker,
+		sourceRoot:      sourceRoot,
+	}
+	jobTracker.Register(jobs.JobTypeBulkImport, jobs.Handler{Run: s.run, Cleanup: s.cleanup})
+	return s
+}
+
+// importPayload is what an import job needs besides its rows
+type importPayload struct {
+	Archive      string     `json:"archive,omitempty"` // Storage key of the uploaded ZIP
+	SourceDir    string     `json:"source_dir,omitempty"`
+	CollectionID *uuid.UUID `json:"collection_id,omitempty"`
+}
+
+// importSource opens the files a manifest names
+type importSource interface {
+	// Stat returns the size of a file, or an error if it can't be imported
+	Stat(name string) (int64, error)
+	Open(name string) (io.ReadCloser, error)
+	Close() error
+}
+
+// StartImport validates an import, then queues it unless it is a dry run or any row is
+// invalid. A queued import's archive is kept in storage until the job is cleaned up.
+func (s *importService) StartImport(request ImportRequest) (*ImportReport, error) {
+	if request.Manifest == nil {
+		return nil, appErrors.NewValidationError("A manifest is required", nil)
+	}
+	if request.Format == "" {
+		format, ok := manifestFormatFor(request.Manifest.Filename)
+		if !ok {
+			return nil, appErrors.NewValidationError("Manifest must be .csv or .jsonl, or give format", nil)
+		}
+		request.Format = format
+	}
+	if request.Format != ManifestCSV && request.Format != ManifestJSONL {
+		return nil, appErrors.NewValidationError("format must be csv or jsonl", nil)
+	}
+	if (request.Archive == nil) == (request.SourceDir == "") {
+		return nil, appErrors.NewValidationError("Provide either an archive or a source_dir", nil)
+	}
+	if request.SourceDir != "" {
+		if !request.IsStaff {
+			return nil, appErrors.NewForbiddenError("Only librarians and admins can import from the server", nil)
+		}
+		if s.sourceRoot == "" {
+			return nil, appErrors.NewValidationError("Importing from server directories is not enabled", nil)
+		}
+	}
+	if request.Archive != nil && s.storage == nil {
+		return nil, appErrors.NewInternalError("Storage unavailable", nil)
+	}
+
+	payload := importPayload{SourceDir: request.SourceDir, CollectionID: request.CollectionID}
+
+	var source importSource
+	if request.Archive != nil {
+		archivePath, err := saveTemp(request.Archive, "import-*.zip")
+		if err != nil {
+			return nil, appErrors.NewInternalError("Failed to stage archive", err)
+		}
+		if source, err = openArchive(archivePath); err != nil {
+			return nil, err
+		}
+	} else {
+		var err error
+		if source, err = s.openDir(request.SourceDir); err != nil {
+			return nil, err
+		}
+	}
+	defer source.Close()
+
+	rows, report, err := s.validate(request, source)
+	if err != nil {
+		return nil, err
+	}
+	if request.DryRun || len(report.Errors) > 0 {
+		return report, nil
+	}
+
+	if request.Archive != nil {
+		payload.Archive = fmt.Sprintf("imports/%s.zip", uuid.New())
+		if err := s.storeArchive(request.Archive, payload.Archive); err != nil {
+			return nil, appErrors.NewInternalError("Failed to stage archive", err)
+		}
+	}
+
+	items := make([]interface{}, len(rows))
+	for i, row := range rows {
+		items[i] = row
+	}
+	job, err := s.jobTracker.Enqueue(jobs.JobSpec{
+		Type:      jobs.JobTypeBulkImport,
+		CreatedBy: request.UserID,
+		Payload:   payload,
+		Items:     items,
+	})
+	if err != nil {
+		if payload.Archive != "" {
+			s.storage.DeleteFile(payload.Archive)
+		}
+		return nil, appErrors.NewInternalError("Failed to queue import", err)
+	}
+
+	report.Job = job
+	return report, nil
+}
+
+// run imports the job's pending rows one at a time. Rows are validated again as they are
+// imported, so a fixed source directory is picked up when the job is retried.
+func (s *importService) run(ctx context.Context, run *jobs.Run) error {
+	var payload importPayload
+	if err := run.Job.Payload.Decode(&payload); err != nil {
+		return fmt.Errorf("invalid import payload: %w", err)
+	}
+
+	source, err := s.openSource(payload)
+	if err != nil {
+		return errors.New(errorMessageOf(err))
+	}
+	defer source.Close()
+
+	return run.ForEach(ctx, 1, func(ctx context.Context, item jobs.Item) (jobs.JobData, error) {
+		var row ImportRow
+		if err := item.Payload.Decode(&row); err != nil {
+			return nil, fmt.Errorf("invalid row: %v", err)
+		}
+
+		documentID, existed, err := s.importRow(run.Job.CreatedBy, payload.CollectionID, source, row)
+		if err != nil {
+			return nil, fmt.Errorf("Row %d (%s): %s", row.Row, row.File, errorMessageOf(err))
+		}
+		return jobs.JobData{"document_id": documentID, "existing": existed}, nil
+	})
+}
+
+// cleanup removes an import's archive from storage
+func (s *importService) cleanup(job *jobs.Job, _ []jobs.Item) {
+	var payload importPayload
+	if err := job.Payload.Decode(&payload); err != nil || payload.Archive == "" || s.storage == nil {
+		return
+	}
+	if err := s.storage.DeleteFile(payload.Archive); err != nil {
+		fmt.Printf("DEBUG: Failed to delete import archive %s: %v\n", payload.Archive, err)
+	}
+}
+
+// importRow creates the document for a row. Content that is already in the collection
+// was imported before (by an earlier attempt or otherwise); its document is returned.
+func (s *importService) importRow(userID uuid.UUID, defaultID *uuid.UUID, source importSource, row ImportRow) (uuid.UUID, bool, error) {
+	collectionID, err := s.resolveCollection(row, defaultID)
+	if err != nil {
+		return uuid.Nil, false, err
+	}
+	size, err := source.Stat(row.File)
+	if err != nil {
+		return uuid.Nil, false, appErrors.NewValidationError(err.Error(), err)
+	}
+	file, err := source.Open(row.File)
+	if err != nil {
+		return uuid.Nil, false, appErrors.NewInternalError("Failed to open file", err)
+	}
+	defer file.Close()
+
+	hasher := sha256.New()
+	upload, metadata := s.uploadFor(row, userID, collectionID, size)
+	document, err := s.documentService.IngestDocument(io.TeeReader(file, hasher), upload, metadata)
+	if err == nil {
+		return document.ID, false, nil
+	}
+
+	if appErr, ok := err.(*appErrors.AppError); ok && appErr.Code == appErrors.ErrCodeConflict {
+		// Ingest read the whole file before finding the duplicate
+		existing, findErr := s.documentService.CheckDuplicate(hex.EncodeToString(hasher.Sum(nil)), collectionID)
+		if findErr == nil && existing != nil && !existing.DeletedAt.Valid {
+			return existing.ID, true, nil
+		}
+	}
+	return uuid.Nil, false, err
+}
+
+// validate checks every row: that its file is there, that its collection exists and
+// that the file and metadata meet the collection's upload policy
+func (s *importService) validate(request ImportRequest, source importSource) ([]ImportRow, *ImportReport, error) {
+	manifest, err := request.Manifest.Open()
+	if err != nil {
+		return nil, nil, appErrors.NewInternalError("Failed to read manifest", err)
+	}
+	defer manifest.Close()
+
+	rows, rowErrors, ignored, err := parseManifest(manifest, request.Format)
+	if err != nil {
+		return nil, nil, appErrors.NewValidationError("Invalid manifest: "+err.Error(), err)
+	}
+
+	report := &ImportReport{
+		Rows:           len(rows) + len(rowErrors),
+		Errors:         rowErrors,
+		IgnoredColumns: ignored,
+	}
+	for _, row := range rows {
+		if err := s.validateRow(request, source, row); err != nil {
+			report.Errors = append(report.Errors, ImportRowError{Row: row.Row, File: row.File, Error: errorMessageOf(err)})
+			continue
+		}
+		report.Valid++
+	}
+	if report.Errors == nil {
+		report.Errors = []ImportRowError{}
+	}
+	return rows, report, nil
+}
+
+// validateRow checks a row as StartImport does
+func (s *importService) validateRow(request ImportRequest, source importSource, row ImportRow) error {
+	if row.File == "" {
+		return appErrors.NewValidationError("file is required", nil)
+	}
+	size, err := source.Stat(row.File)
+	if err != nil {
+		return appErrors.NewValidationError(err.Error(), err)
+	}
+	collectionID, err := s.resolveCollection(row, request.CollectionID)
+	if err != nil {
+		return err
+	}
+	upload, metadata := s.uploadFor(row, request.UserID, collectionID, size)
+	return s.documentService.CheckUploadPolicy(upload, metadata)
+}
+
+// uploadFor describes a row's file and document as an upload
+func (s *importService) uploadFor(row ImportRow, userID, collectionID uuid.UUID, size int64) (UploadFile, UploadMetadata) {
+	filename := path.Base(filepath.ToSlash(row.File))
+	title := row.Title
+	if title == "" {
+		title = strings.TrimSuffix(filename, path.Ext(filename))
+	}
+	metadata := row.Metadata
+
+	return UploadFile{
+		Filename:    filename,
+		ContentType: mime.TypeByExtension(strings.ToLower(path.Ext(filename))),
+		Size:        size,
+	}, UploadMetadata{
+		CollectionID: collectionID,
+		UploaderID:   userID,
+		Title:        title,
+		Description:  row.Description,
+		Metadata:     &metadata,
+	}
+}
+
+// resolveCollection finds a row's collection by ID or slug, or takes the default
+func (s *importService) resolveCollection(row ImportRow, defaultID *uuid.UUID) (uuid.UUID, error) {
+	if row.Collection == "" {
+		if defaultID == nil {
+			return uuid.Nil, appErrors.NewValidationError("No collection given for the row and no default collection_id", nil)
+		}
+		return *defaultID, nil
+	}
+
+	if id, err := uuid.Parse(row.Collection); err == nil {
+		if _, err := s.collectionRepo.FindByID(id); err != nil {
+			return uuid.Nil, appErrors.NewNotFoundError("Collection", err)
+		}
+		return id, nil
+	}
+	collection, err := s.collectionRepo.FindBySlug(row.Collection)
+	if err != nil {
+		return uuid.Nil, appErrors.NewNotFoundError("Collection", err)
+	}
+	return collection.ID, nil
+}
+
+// openSource opens where an import job's files are; an archive is fetched from storage
+func (s *importService) openSource(payload importPayload) (importSource, error) {
+	if payload.Archive == "" {
+		return s.openDir(payload.SourceDir)
+	}
+	if s.storage == nil {
+		return nil, appErrors.NewInternalError("Storage unavailable", nil)
+	}
+
+	object, err := s.storage.DownloadFile(payload.Archive)
+	if err != nil {
+		return nil, appErrors.NewInternalError("Failed to read archive", err)
+	}
+	defer object.Close()
+
+	// ZIPs are read at random, so the archive is copied to a local file first
+	archivePath, err := copyTemp(object, "import-*.zip")
+	if err != nil {
+		return nil, appErrors.NewInternalError("Failed to read archive", err)
+	}
+	return openArchive(archivePath)
+}
+
+// openDir opens a source directory under the import source root
+func (s *importService) openDir(sourceDir string) (importSource, error) {
+	root, err := filepath.EvalSymlinks(s.sourceRoot)
+	if err != nil {
+		return nil, appErrors.NewInternalError("Import source root is unavailable", err)
+	}
+	dir, err := insideDir(root, sourceDir)
+	if err != nil {
+		return nil, appErrors.NewValidationError("Invalid source_dir: "+err.Error(), err)
+	}
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		return nil, appErrors.NewValidationError("source_dir is not a directory under the import source root", err)
+	}
+	return &dirSource{root: dir}, nil
+}
+
+// storeArchive uploads an import's archive to storage
+func (s *importService) storeArchive(header *multipart.FileHeader, key string) error {
+	src, err := header.Open()
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	return s.storage.UploadFile(key, src, header.Size, "application/zip")
+}
+
+// openArchive opens a local ZIP, which is removed when the source is closed
+func openArchive(archivePath string) (importSource, error) {
+	reader, err := zip.OpenReader(archivePath)
+	if err != nil {
+		os.Remove(archivePath)
+		return nil, appErrors.NewValidationError("Archive is not a valid ZIP file", err)
+	}
+	source := newArchiveSource(reader)
+	source.path = archivePath
+	return source, nil
+}
+
+// saveTemp copies an uploaded file to a new temporary file
+func saveTemp(header *multipart.FileHeader, pattern string) (string, error) {
+	src, err := header.Open()
+	if err != nil {
+		return "", err
+	}
+	defer src.Close()
+
+	return copyTemp(src, pattern)
+}
+
+// copyTemp copies src to a new temporary file and returns its path
+func copyTemp(src io.Reader, pattern string) (string, error) {
+	out, err := os.CreateTemp("", pattern)
+	if err != nil {
+		return "", err
+	}
+	if _, err := io.Copy(out, src); err != nil {
+		out.Close()
+		os.Remove(out.Name())
+		return "", err
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(out.Name())
+		return "", err
+	}
+	return out.Name(), nil
+}
+
+// errorMessageOf is the client-facing message of an error
+func errorMessageOf(err error) string {
+	if appErr, ok := err.(*appErrors.AppError); ok {
+		return appErr.Message
+	}
+	return err.Error()
+}
+
+// archiveSource reads files from a ZIP. When every entry is in one top-level directory
+// (as in collection exports) names are also found without it, and in bags without data/.
+type archiveSource struct {
+	reader *zip.ReadCloser
+	files  map[string]*zip.File
+	prefix string
+	path   string // Local copy, removed on Close
+}
+
+func newArchiveSource(reader *zip.ReadCloser) *archiveSource {
+	source := &archiveSource{reader: reader, files: make(map[string]*zip.File)}
+
+	common := ""
+	for i, f := range reader.File {
+		name := path.Clean(strings.TrimPrefix(f.Name, "/"))
+		if !f.FileInfo().IsDir() {
+			source.files[name] = f
+		}
+		top, _, found := strings.Cut(name, "/")
+		if !found {
+			top = ""
+		}
+		if i == 0 {
+			common = top
+		} else if top != common {
+			common = ""
+		}
+	}
+	if common != "" {
+		source.prefix = common + "/"
+	}
+	return source
+}
+
+// find looks a manifest path up in the archive
+func (a *archiveSource) find(name string) (*zip.File, bool) {
+	name = path.Clean("/" + filepath.ToSlash(name))[1:]
+	for _, candidate := range []string{name, a.prefix + name, a.prefix + "data/" + name} {
+		if f, ok := a.files[candidate]; ok {
+			return f, true
+		}
+	}
+	return nil, false
+}
+
+func (a *archiveSource) Stat(name string) (int64, error) {
+	f, ok := a.find(name)
+	if !ok {
+		return 0, fmt.Errorf("file %s is not in the archive", name)
+	}
+	return int64(f.UncompressedSize64), nil
+}
+
+func (a *archiveSource) Open(name string) (io.ReadCloser, error) {
+	f, ok := a.find(name)
+	if !ok {
+		return nil, fmt.Errorf("file %s is not in the archive", name)
+	}
+	return f.Open()
+}
+
+func (a *archiveSource) Close() error {
+	err := a.reader.Close()
+	if a.path != "" {
+		os.Remove(a.path)
+	}
+	return err
+}
+
+// dirSource reads files from a directory on the server, never outside it
+type dirSource struct {
+	root string
+}
+
+func (d *dirSource) resolve(name string) (string, error) {
+	return insideDir(d.root, name)
+}
+
+func (d *dirSource) Stat(name string) (int64, error) {
+	filePath, err := d.resolve(name)
+	if err != nil {
+		return 0, err
+	}
+	info, err := os.Stat(filePath)
+	if err != nil || !info.Mode().IsRegular() {
+		return 0, fmt.Errorf("file %s is not in the source directory", name)
+	}
+	return info.Size(), nil
+}
+
+func (d *dirSource) Open(name string) (io.ReadCloser, error) {
+	filePath, err := d.resolve(name)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(filePath)
+}
+
+func (d *dirSource) Close() error {
+	return nil
+}
+
+// insideDir resolves name under root, following symlinks, and refuses anything that
+// ends up outside it. root must have its symlinks resolved already.
+func insideDir(root, name string) (string, error) {
+	joined := filepath.Join(root, filepath.Clean(string(filepath.Separator)+name))
+	resolved, err := filepath.EvalSymlinks(joined)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", fmt.Errorf("%s does not exist", name)
+		}
+		return "", err
+	}
+	rel, err := filepath.Rel(root, resolved)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("%s is outside the source directory", name)
+	}
+	return resolved, nil
+}
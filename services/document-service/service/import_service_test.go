@@ -0,0 +1,289 @@
+package service
+
+import (
+	"archive/zip"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	appErrors "github.com/Kyei-Ernest/libsystem/shared/errors"
+	"github.com/Kyei-Ernest/libsystem/shared/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// fakeImportDocuments ingests into memory and, like documentService, rejects content
+// already in the collection as a conflict. Deleted documents still conflict, as they do
+// while a deletion is being processed.
+type fakeImportDocuments struct {
+	DocumentService
+	documents map[string]*models.Document // by collection and hash
+	ingests   int
+}
+
+func newFakeImportDocuments() *fakeImportDocuments {
+	return &fakeImportDocuments{documents: make(map[string]*models.Document)}
+}
+
+func (f *fakeImportDocuments) IngestDocument(src io.Reader, file UploadFile, metadata UploadMetadata) (*models.Document, error) {
+	f.ingests++
+	data, err := io.ReadAll(src)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(data)
+	key := metadata.CollectionID.String() + "/" + hex.EncodeToString(sum[:])
+	if _, ok := f.documents[key]; ok {
+		return nil, appErrors.NewConflictError("Document", nil)
+	}
+
+	document := &models.Document{Title: metadata.Title, CollectionID: metadata.CollectionID}
+	document.ID = uuid.New()
+	f.documents[key] = document
+	return document, nil
+}
+
+func (f *fakeImportDocuments) CheckDuplicate(hash string, collectionID uuid.UUID) (*models.Document, error) {
+	document, ok := f.documents[collectionID.String()+"/"+hash]
+	if !ok {
+		return nil, nil
+	}
+	return document, nil
+}
+
+func writeImportArchive(t *testing.T, files map[string]string) importSource {
+	t.Helper()
+	archivePath := filepath.Join(t.TempDir(), "import.zip")
+	out, err := os.Create(archivePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := zip.NewWriter(out)
+	for name, content := range files {
+		f, err := w.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.Write([]byte(content))
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	out.Close()
+
+	source, err := openArchive(archivePath)
+	if err != nil {
+		t.Fatalf("openArchive() error: %v", err)
+	}
+	t.Cleanup(func() { source.Close() })
+	return source
+}
+
+func TestImportRowResume(t *testing.T) {
+	source := writeImportArchive(t, map[string]string{
+		"a.pdf":      "%PDF-1.7 a",
+		"b.pdf":      "%PDF-1.7 b",
+		"copy/a.pdf": "%PDF-1.7 a",
+	})
+	documents := newFakeImportDocuments()
+	s := &importService{documentService: documents}
+	userID := uuid.New()
+	collectionID := uuid.New()
+
+	first, existed, err := s.importRow(userID, &collectionID, source, ImportRow{Row: 1, File: "a.pdf"})
+	if err != nil || existed {
+		t.Fatalf("first import = %v, %v, %v; want a new document", first, existed, err)
+	}
+
+	// A retried job runs the row again after it had been imported
+	again, existed, err := s.importRow(userID, &collectionID, source, ImportRow{Row: 1, File: "a.pdf"})
+	if err != nil {
+		t.Fatalf("resumed import error: %v", err)
+	}
+	if !existed || again != first {
+		t.Errorf("resumed import = %v, existing %v; want %v, existing true", again, existed, first)
+	}
+
+	// A second row with the same content resolves to the same document
+	duplicate, existed, err := s.importRow(userID, &collectionID, source, ImportRow{Row: 2, File: "copy/a.pdf"})
+	if err != nil || !existed || duplicate != first {
+		t.Errorf("duplicate row = %v, %v, %v; want %v, true, nil", duplicate, existed, err, first)
+	}
+
+	other, existed, err := s.importRow(userID, &collectionID, source, ImportRow{Row: 3, File: "b.pdf"})
+	if err != nil || existed || other == first {
+		t.Errorf("other row = %v, %v, %v; want a new document", other, existed, err)
+	}
+
+	// The same content goes into another collection as a new document
+	otherCollection := uuid.New()
+	elsewhere, existed, err := s.importRow(userID, &otherCollection, source, ImportRow{Row: 4, File: "a.pdf"})
+	if err != nil || existed || elsewhere == first {
+		t.Errorf("row for another collection = %v, %v, %v; want a new document", elsewhere, existed, err)
+	}
+
+	if documents.ingests != 5 {
+		t.Errorf("ingested %d times, want 5", documents.ingests)
+	}
+}
+
+func TestImportRowDuplicateOfDeletedDocument(t *testing.T) {
+	source := writeImportArchive(t, map[string]string{"a.pdf": "%PDF-1.7 a"})
+	documents := newFakeImportDocuments()
+	s := &importService{documentService: documents}
+	collectionID := uuid.New()
+
+	if _, _, err := s.importRow(uuid.New(), &collectionID, source, ImportRow{Row: 1, File: "a.pdf"}); err != nil {
+		t.Fatal(err)
+	}
+	// A conflict with a deleted document is not taken as already imported
+	for _, document := range documents.documents {
+		document.DeletedAt = gorm.DeletedAt{Time: time.Now(), Valid: true}
+	}
+	_, existed, err := s.importRow(uuid.New(), &collectionID, source, ImportRow{Row: 1, File: "a.pdf"})
+	var appErr *appErrors.AppError
+	if existed || !errors.As(err, &appErr) || appErr.Code != appErrors.ErrCodeConflict {
+		t.Errorf("import over a deleted document = existing %v, %v; want the conflict", existed, err)
+	}
+}
+
+func TestArchiveSourceEntryPaths(t *testing.T) {
+	source := writeImportArchive(t, map[string]string{
+		"export/data/books/a.pdf": "a",
+		"export/manifest.csv":     "file\n",
+	})
+
+	tests := []struct {
+		name  string
+		entry string
+		found bool
+	}{
+		{"relative", "books/a.pdf", true},
+		{"with the export directory", "export/data/books/a.pdf", true},
+		{"dot segments inside the archive", "books/../books/./a.pdf", true},
+		{"parent segments stay inside the archive", "../../books/a.pdf", true},
+		{"absolute path is relative to the archive", "/books/a.pdf", true},
+		{"escape to a file outside", "../../../etc/passwd", false},
+		{"absolute path outside", "/etc/passwd", false},
+		{"missing", "books/b.pdf", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			size, err := source.Stat(tt.entry)
+			if tt.found {
+				if err != nil || size != 1 {
+					t.Errorf("Stat(%q) = %d, %v; want the archive entry", tt.entry, size, err)
+				}
+				return
+			}
+			if err == nil {
+				t.Errorf("Stat(%q) found a file", tt.entry)
+			}
+			if _, err := source.Open(tt.entry); err == nil {
+				t.Errorf("Open(%q) opened a file", tt.entry)
+			}
+		})
+	}
+}
+
+func TestDirSourceEntryPaths(t *testing.T) {
+	base, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := filepath.Join(base, "imports", "batch")
+	if err := os.MkdirAll(filepath.Join(root, "books"), 0o750); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "books", "a.pdf"), []byte("a"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	secret := filepath.Join(base, "secret.pdf")
+	if err := os.WriteFile(secret, []byte("secret"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(secret, filepath.Join(root, "link.pdf")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(base, filepath.Join(root, "up")); err != nil {
+		t.Fatal(err)
+	}
+	source := &dirSource{root: root}
+
+	tests := []struct {
+		name  string
+		entry string
+		found bool
+	}{
+		{"relative", "books/a.pdf", true},
+		{"dot segments inside the directory", "books/../books/a.pdf", true},
+		{"parent segments", "../../secret.pdf", false},
+		{"absolute path", secret, false},
+		{"symlink to a file outside", "link.pdf", false},
+		{"through a symlinked directory", "up/secret.pdf", false},
+		{"directory", "books", false},
+		{"missing", "books/b.pdf", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			size, err := source.Stat(tt.entry)
+			if tt.found {
+				if err != nil || size != 1 {
+					t.Errorf("Stat(%q) = %d, %v; want the file in the directory", tt.entry, size, err)
+				}
+				return
+			}
+			if err == nil {
+				t.Errorf("Stat(%q) = %d; want an error", tt.entry, size)
+			}
+		})
+	}
+
+	t.Run("open", func(t *testing.T) {
+		for _, entry := range []string{"../../secret.pdf", secret, "link.pdf", "up/secret.pdf"} {
+			if f, err := source.Open(entry); err == nil {
+				data, _ := io.ReadAll(f)
+				f.Close()
+				if string(data) == "secret" {
+					t.Errorf("Open(%q) read a file outside the directory", entry)
+				}
+			}
+		}
+	})
+}
+
+func TestOpenDirStaysUnderSourceRoot(t *testing.T) {
+	base, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := filepath.Join(base, "imports")
+	if err := os.MkdirAll(filepath.Join(root, "batch"), 0o750); err != nil {
+		t.Fatal(err)
+	}
+	s := &importService{sourceRoot: root}
+
+	if _, err := s.openDir("batch"); err != nil {
+		t.Errorf("openDir(batch) error: %v", err)
+	}
+	for _, dir := range []string{"..", "../..", base, "batch/../.."} {
+		source, err := s.openDir(dir)
+		if err != nil {
+			continue
+		}
+		if d, ok := source.(*dirSource); !ok || d.root != root {
+			t.Errorf("openDir(%q) opened %v outside the source root", dir, source)
+		}
+	}
+	var appErr *appErrors.AppError
+	if _, err := s.openDir("missing"); !errors.As(err, &appErr) || appErr.Code != appErrors.ErrCodeValidation {
+		t.Errorf("openDir(missing) error = %v, want a validation error", err)
+	}
+}
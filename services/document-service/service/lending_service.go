@@ -0,0 +1,302 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Kyei-Ernest/libsystem/services/document-service/repository"
+	appErrors "github.com/Kyei-Ernest/libsystem/shared/errors"
+	"github.com/Kyei-Ernest/libsystem/shared/kafka"
+	"github.com/Kyei-Ernest/libsystem/shared/models"
+	"github.com/google/uuid"
+)
+
+// maxLendingPeriodHours caps the loan period of a lent document (one year)
+const maxLendingPeriodHours = 24 * 365
+
+// lendingBatchSize is the number of documents with expired loans settled per query
+const lendingBatchSize = 100
+
+// LendingSettings are the terms on which a document is lent
+type LendingSettings struct {
+	Licenses    int `json:"licenses" binding:"required,min=1"`     // Patrons who may read it at once
+	PeriodHours int `json:"period_hours" binding:"required,min=1"` // Length of a loan
+}
+
+// LendingStatus describes the lending of a document, and the caller's loan of it
+type LendingStatus struct {
+	DocumentID  uuid.UUID           `json:"document_id"`
+	Lent        bool                `json:"lent"`
+	Licenses    int                 `json:"licenses,omitempty"`
+	PeriodHours int                 `json:"period_hours,omitempty"`
+	Available   int64               `json:"available"` // Licences free now
+	Waiting     int64               `json:"waiting"`
+	Loan        *models.DigitalLoan `json:"loan,omitempty"` // The caller's loan or waitlist place
+}
+
+// LendingService runs controlled digital lending: the content of a lent document can only
+// be read under a time-limited loan, at most one per licence at a time. Users who find
+// every licence taken join a waitlist and are lent the document, in order, as loans are
+// returned or expire.
+type LendingService interface {
+	GetStatus(documentID uuid.UUID, userID *uuid.UUID) (*LendingStatus, error)
+	Configure(documentID, userID uuid.UUID, settings *LendingSettings) (*LendingStatus, error)
+	Borrow(documentID, userID uuid.UUID) (*models.DigitalLoan, error)
+	Return(documentID, userID uuid.UUID) (*models.DigitalLoan, error)
+	ListLoans(userID uuid.UUID, open bool, page, pageSize int) ([]models.DigitalLoan, int64, error)
+	ExpireDue(now time.Time) (int, error)
+}
+
+// lendingService implements LendingService
+type lendingService struct {
+	lendingRepo     repository.LendingRepository
+	documentService DocumentService
+	producer        *kafka.Producer
+}
+
+// NewLendingService creates a new lending service
+func NewLendingService(lendingRepo repository.LendingRepository, documentService DocumentService, producer *kafka.Producer) LendingService {
+	return &lendingService{
+		lendingRepo:     lendingRepo,
+		documentService: documentService,
+		producer:        producer,
+	}
+}
+
+// GetStatus describes the lending of a document the user may see
+func (s *lendingService) GetStatus(documentID uuid.UUID, userID *uuid.UUID) (*LendingStatus, error) {
+	document, err := s.documentService.GetDocument(documentID, userID)
+	if err != nil {
+		return nil, err
+	}
+	return s.status(document, userID)
+}
+
+// Configure lends a document on the given terms, or ends lending when settings is nil.
+// Only the uploader and the collection owner may change it.
+func (s *lendingService) Configure(documentID, userID uuid.UUID, settings *LendingSettings) (*LendingStatus, error) {
+	document, err := s.documentService.GetDocument(documentID, &userID)
+	if err != nil {
+		return nil, err
+	}
+	if !managesDocument(document, &userID) {
+		return nil, appErrors.NewForbiddenError("Only the uploader or the collection owner can change lending", nil)
+	}
+
+	var licenses *int
+	periodHours := 0
+	if settings != nil {
+		if settings.Licenses < 1 {
+			return nil, appErrors.NewValidationError("At least one licence is required", nil)
+		}
+		if settings.PeriodHours < 1 || settings.PeriodHours > maxLendingPeriodHours {
+			return nil, appErrors.NewValidationError(
+				fmt.Sprintf("Loan period must be between 1 and %d hours", maxLendingPeriodHours), nil)
+		}
+		licenses = &settings.Licenses
+		periodHours = settings.PeriodHours
+	}
+
+	now := time.Now()
+	started, err := s.lendingRepo.SetLending(documentID, licenses, periodHours, now)
+	if err != nil {
+		return nil, appErrors.NewInternalError("Failed to update lending", err)
+	}
+	s.publishStarted(started, true)
+
+	document.LendingLicenses = licenses
+	document.LendingPeriodHours = periodHours
+	return s.status(document, &userID)
+}
+
+// Borrow lends a document to the user, or puts them on its waitlist when every licence is
+// taken. Asking again returns the loan or place the user already has.
+func (s *lendingService) Borrow(documentID, userID uuid.UUID) (*models.DigitalLoan, error) {
+	document, err := s.documentService.GetContentDocument(documentID, &userID)
+	if err != nil {
+		return nil, err
+	}
+	if document.LendingLicenses == nil {
+		return nil, appErrors.NewValidationError("Document is not lent; it can be read without a loan", nil)
+	}
+	if managesDocument(document, &userID) {
+		return nil, appErrors.NewValidationError("You manage this document and can read it without a loan", nil)
+	}
+
+	loan, started, err := s.lendingRepo.Borrow(documentID, userID, time.Now())
+	if err != nil {
+		if errors.Is(err, repository.ErrNotLent) {
+			return nil, appErrors.NewValidationError("Document is not lent; it can be read without a loan", err)
+		}
+		return nil, appErrors.NewInternalError("Failed to borrow document", err)
+	}
+
+	// Only loans handed to other users from the waitlist are handoffs
+	handoffs := started[:0]
+	for _, l := range started {
+		if l.ID != loan.ID {
+			handoffs = append(handoffs, l)
+		}
+	}
+	s.publishStarted(handoffs, true)
+	if len(handoffs) < len(started) {
+		s.publishStarted([]models.DigitalLoan{*loan}, false)
+	}
+
+	return s.withPosition(loan)
+}
+
+// Return ends the user's loan of a document, or takes them off its waitlist. A freed
+// licence goes to the next user waiting.
+func (s *lendingService) Return(documentID, userID uuid.UUID) (*models.DigitalLoan, error) {
+	loan, started, err := s.lendingRepo.Return(documentID, userID, time.Now())
+	if err != nil {
+		if errors.Is(err, repository.ErrDigitalLoanNotFound) {
+			return nil, appErrors.NewNotFoundError("Loan", err)
+		}
+		return nil, appErrors.NewInternalError("Failed to return document", err)
+	}
+
+	if loan.StartedAt != nil {
+		s.publish("document.loan_ended", loan, nil)
+	}
+	s.publishStarted(started, true)
+	return loan, nil
+}
+
+// ListLoans lists the user's loans, newest first; open limits them to current loans and
+// waitlist places
+func (s *lendingService) ListLoans(userID uuid.UUID, open bool, page, pageSize int) ([]models.DigitalLoan, int64, error) {
+	offset := (page - 1) * pageSize
+	loans, total, err := s.lendingRepo.ListByUser(userID, open, offset, pageSize)
+	if err != nil {
+		return nil, 0, appErrors.NewInternalError("Failed to list loans", err)
+	}
+
+	for i := range loans {
+		if loans[i].Status == models.DigitalLoanWaiting {
+			if loans[i].Position, err = s.lendingRepo.WaitlistPosition(&loans[i]); err != nil {
+				return nil, 0, appErrors.NewInternalError("Failed to find waitlist position", err)
+			}
+		}
+	}
+	return loans, total, nil
+}
+
+// ExpireDue returns loans that ended by now and lends the freed licences to the users
+// waiting, returning how many documents were settled. Content access compares loans
+// against the clock itself, so a late run only delays handoffs, never extends a loan.
+func (s *lendingService) ExpireDue(now time.Time) (int, error) {
+	settled := 0
+	seen := make(map[uuid.UUID]bool)
+
+	for {
+		ids, err := s.lendingRepo.ListExpiredDocuments(now, lendingBatchSize)
+		if err != nil {
+			return settled, err
+		}
+
+		progressed := false
+		for _, id := range ids {
+			if seen[id] {
+				continue // Still due after settling; leave it for the next run
+			}
+			seen[id] = true
+			progressed = true
+
+			expired, started, err := s.lendingRepo.Settle(id, now)
+			if err != nil {
+				return settled, err
+			}
+			for i := range expired {
+				s.publish("document.loan_ended", &expired[i], nil)
+			}
+			s.publishStarted(started, true)
+			settled++
+		}
+
+		if len(ids) < lendingBatchSize || !progressed {
+			return settled, nil
+		}
+	}
+}
+
+// status describes the lending of a loaded document and the user's loan of it
+func (s *lendingService) status(document *models.Document, userID *uuid.UUID) (*LendingStatus, error) {
+	status := &LendingStatus{DocumentID: document.ID}
+	if document.LendingLicenses == nil {
+		return status, nil
+	}
+	status.Lent = true
+	status.Licenses = *document.LendingLicenses
+	status.PeriodHours = document.LendingPeriodHours
+
+	active, waiting, err := s.lendingRepo.CountOpen(document.ID, time.Now())
+	if err != nil {
+		return nil, appErrors.NewInternalError("Failed to count loans", err)
+	}
+	status.Waiting = waiting
+	if free := int64(status.Licenses) - active; free > 0 && waiting == 0 {
+		status.Available = free
+	}
+
+	if userID != nil {
+		loan, err := s.lendingRepo.FindOpen(document.ID, *userID)
+		if err != nil && !errors.Is(err, repository.ErrDigitalLoanNotFound) {
+			return nil, appErrors.NewInternalError("Failed to find loan", err)
+		}
+		if loan != nil {
+			if status.Loan, err = s.withPosition(loan); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return status, nil
+}
+
+// withPosition fills in the waitlist position of a waiting loan
+func (s *lendingService) withPosition(loan *models.DigitalLoan) (*models.DigitalLoan, error) {
+	if loan.Status != models.DigitalLoanWaiting {
+		return loan, nil
+	}
+	position, err := s.lendingRepo.WaitlistPosition(loan)
+	if err != nil {
+		return nil, appErrors.NewInternalError("Failed to find waitlist position", err)
+	}
+	loan.Position = position
+	return loan, nil
+}
+
+// publishStarted announces loans that started, so that users handed a loan from the
+// waitlist can be told
+func (s *lendingService) publishStarted(loans []models.DigitalLoan, handoff bool) {
+	for i := range loans {
+		s.publish("document.loan_started", &loans[i], map[string]interface{}{"handoff": handoff})
+	}
+}
+
+func (s *lendingService) publish(topic string, loan *models.DigitalLoan, extra map[string]interface{}) {
+	if s.producer == nil {
+		return
+	}
+
+	event := map[string]interface{}{
+		"id":          loan.DocumentID,
+		"loan_id":     loan.ID,
+		"user_id":     loan.UserID,
+		"status":      loan.Status,
+		"expires_at":  loan.ExpiresAt,
+		"occurred_at": time.Now(),
+	}
+	for k, v := range extra {
+		event[k] = v
+	}
+
+	go func() {
+		if err := s.producer.PublishToTopic(context.Background(), topic, loan.DocumentID.String(), event); err != nil {
+			fmt.Printf("DEBUG: Failed to publish %s event: %v\n", topic, err)
+		}
+	}()
+}
@@ -0,0 +1,202 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/Kyei-Ernest/libsystem/services/document-service/repository"
+	appErrors "github.com/Kyei-Ernest/libsystem/shared/errors"
+	"github.com/Kyei-Ernest/libsystem/shared/kafka"
+	"github.com/Kyei-Ernest/libsystem/shared/models"
+	"github.com/Kyei-Ernest/libsystem/shared/storage"
+	"github.com/google/uuid"
+)
+
+// Reviewer identifies who is acting on the review queue. Staff (librarians and admins)
+// moderate every collection; other users moderate the collections they own.
+type Reviewer struct {
+	ID      uuid.UUID
+	IsStaff bool
+}
+
+// ModerationService defines the interface for reviewing submissions to collections that
+// require approval
+type ModerationService interface {
+	ListSubmissions(reviewer Reviewer, status models.DocumentStatus, collectionID *uuid.UUID, page, pageSize int) ([]models.Document, int64, error)
+	GetSubmission(id uuid.UUID, reviewer Reviewer) (*models.Document, error)
+	GetSubmissionStream(id uuid.UUID, reviewer Reviewer) (io.ReadSeekCloser, *models.Document, error)
+	Approve(id uuid.UUID, reviewer Reviewer) (*models.Document, error)
+	Reject(id uuid.UUID, reviewer Reviewer, reason string) (*models.Document, error)
+}
+
+// moderationService implements ModerationService
+type moderationService struct {
+	documentRepo    repository.DocumentRepository
+	documentService DocumentService
+	storage         *storage.MinIOClient
+	producer        *kafka.Producer
+}
+
+// NewModerationService creates a new moderation service
+func NewModerationService(
+	documentRepo repository.DocumentRepository,
+	documentService DocumentService,
+	storage *storage.MinIOClient,
+	producer *kafka.Producer,
+) ModerationService {
+	return &moderationService{
+		documentRepo:    documentRepo,
+		documentService: documentService,
+		storage:         storage,
+		producer:        producer,
+	}
+}
+
+// ListSubmissions lists submissions with the given status (submitted or rejected). Staff
+// see all of them; other users see those they made or that target their collections.
+func (s *moderationService) ListSubmissions(reviewer Reviewer, status models.DocumentStatus, collectionID *uuid.UUID, page, pageSize int) ([]models.Document, int64, error) {
+	if status == "" {
+		status = models.StatusSubmitted
+	}
+	if !underReview(status) {
+		return nil, 0, appErrors.NewValidationError("status must be submitted or rejected", nil)
+	}
+
+	filters := repository.SubmissionFilters{
+		Status:       status,
+		CollectionID: collectionID,
+	}
+	if !reviewer.IsStaff {
+		filters.VisibleTo = &reviewer.ID
+	}
+
+	offset := (page - 1) * pageSize
+	documents, total, err := s.documentRepo.ListSubmissions(filters, offset, pageSize)
+	if err != nil {
+		return nil, 0, appErrors.NewInternalError("Failed to list submissions", err)
+	}
+
+	return documents, total, nil
+}
+
+// GetSubmission retrieves a submission visible to the user: its submitter or a moderator
+func (s *moderationService) GetSubmission(id uuid.UUID, reviewer Reviewer) (*models.Document, error) {
+	document, err := s.documentRepo.FindByID(id)
+	if err != nil {
+		return nil, appErrors.NewNotFoundError("Submission", err)
+	}
+
+	if !underReview(document.Status) || (document.UploaderID != reviewer.ID && !canModerate(document, reviewer)) {
+		return nil, appErrors.NewNotFoundError("Submission", fmt.Errorf("document %s is not under review", id))
+	}
+
+	return document, nil
+}
+
+// GetSubmissionStream opens the file of a submission so it can be reviewed
+func (s *moderationService) GetSubmissionStream(id uuid.UUID, reviewer Reviewer) (io.ReadSeekCloser, *models.Document, error) {
+	document, err := s.GetSubmission(id, reviewer)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if s.storage == nil {
+		return nil, nil, appErrors.NewInternalError("Storage service not available", nil)
+	}
+
+	stream, err := s.storage.OpenFile(document.StoragePath)
+	if err != nil {
+		return nil, nil, appErrors.NewInternalError("Failed to get file stream", err)
+	}
+
+	return stream, document, nil
+}
+
+// Approve accepts a submission into its collection and sends it for indexing
+func (s *moderationService) Approve(id uuid.UUID, reviewer Reviewer) (*models.Document, error) {
+	document, err := s.review(id, reviewer, models.StatusPending, "")
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.documentService.RequestReindex(id); err != nil {
+		return nil, err
+	}
+
+	return document, nil
+}
+
+// Reject declines a submission with a reason for the submitter
+func (s *moderationService) Reject(id uuid.UUID, reviewer Reviewer, reason string) (*models.Document, error) {
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return nil, appErrors.NewValidationError("A reason is required to reject a submission", nil)
+	}
+
+	return s.review(id, reviewer, models.StatusRejected, reason)
+}
+
+// review records a decision on a pending submission and notifies the submitter
+func (s *moderationService) review(id uuid.UUID, reviewer Reviewer, status models.DocumentStatus, note string) (*models.Document, error) {
+	document, err := s.documentRepo.FindByID(id)
+	if err != nil {
+		return nil, appErrors.NewNotFoundError("Submission", err)
+	}
+
+	if !canModerate(document, reviewer) {
+		return nil, appErrors.NewForbiddenError("Only the collection owner or a librarian can review this submission", nil)
+	}
+	if document.UploaderID == reviewer.ID {
+		return nil, appErrors.NewForbiddenError("You cannot review your own submission", nil)
+	}
+
+	reviewed, err := s.documentRepo.Review(id, status, reviewer.ID, note)
+	if err != nil {
+		return nil, appErrors.NewInternalError("Failed to record review", err)
+	}
+	if !reviewed {
+		return nil, appErrors.NewConflictError("Submission",
+			fmt.Errorf("document %s is not awaiting review", id))
+	}
+
+	document, err = s.documentRepo.FindByID(id)
+	if err != nil {
+		return nil, appErrors.NewInternalError("Failed to load reviewed document", err)
+	}
+
+	s.publishReviewed(document)
+	return document, nil
+}
+
+// publishReviewed notifies the submitter of the decision on their submission
+func (s *moderationService) publishReviewed(document *models.Document) {
+	if s.producer == nil {
+		return
+	}
+
+	decision := "approved"
+	if document.Status == models.StatusRejected {
+		decision = "rejected"
+	}
+
+	event := map[string]interface{}{
+		"id":            document.ID,
+		"title":         document.Title,
+		"collection_id": document.CollectionID,
+		"submitter_id":  document.UploaderID,
+		"decision":      decision,
+		"reason":        document.ReviewNote,
+		"reviewed_by":   document.ReviewedBy,
+		"reviewed_at":   document.ReviewedAt,
+	}
+	if err := s.producer.PublishToTopic(context.Background(), "document.reviewed", document.ID.String(), event); err != nil {
+		fmt.Printf("DEBUG: Failed to publish document.reviewed event: %v\n", err)
+	}
+}
+
+// canModerate reports whether the reviewer moderates the document's collection
+func canModerate(document *models.Document, reviewer Reviewer) bool {
+	return reviewer.IsStaff || document.Collection.OwnerID == reviewer.ID
+}
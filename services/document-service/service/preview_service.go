@@ -0,0 +1,207 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	appErrors "github.com/Kyei-Ernest/libsystem/shared/errors"
+	"github.com/Kyei-Ernest/libsystem/shared/storage"
+	"golang.org/x/sync/singleflight"
+)
+
+// PreviewService renders Office documents to PDF for in-browser viewing. Renditions are
+// cached in storage by content hash, so each content is converted once however many
+// documents share it. Conversions run in a bounded pool of LibreOffice processes.
+type PreviewService interface {
+	NeedsPreview(mimeType string) bool
+	Preview(hash, storagePath string) (string, error)
+	PreviewFromFile(hash, localPath string) (string, error)
+}
+
+type previewService struct {
+	storage *storage.MinIOClient
+	slots   chan int // Free converter slots; each has its own LibreOffice profile
+	timeout time.Duration
+	group   singleflight.Group
+}
+
+// NewPreviewService creates a preview service running up to workers conversions at once,
+// each killed after timeout
+func NewPreviewService(storage *storage.MinIOClient, workers int, timeout time.Duration) PreviewService {
+	slots := make(chan int, workers)
+	for i := 0; i < workers; i++ {
+		slots <- i
+	}
+	return &previewService{
+		storage: storage,
+		slots:   slots,
+		timeout: timeout,
+	}
+}
+
+// previewKey is where the PDF rendition of content is stored. Equal content has an equal
+// rendition, so the key needs no uniqueness beyond the hash.
+func previewKey(hash string) string {
+	return fmt.Sprintf("previews/%s/%s.pdf", hash[:2], hash)
+}
+
+// NeedsPreview reports whether content of a type is converted to PDF for viewing
+func (s *previewService) NeedsPreview(mimeType string) bool {
+	return strings.Contains(mimeType, "msword") ||
+		strings.Contains(mimeType, "officedocument") ||
+		strings.Contains(mimeType, "vnd.oasis.opendocument")
+}
+
+// Preview returns the storage key of the PDF rendition of the content stored at
+// storagePath, converting it first unless it is cached
+func (s *previewService) Preview(hash, storagePath string) (string, error) {
+	return s.ensure(hash, func() (string, func(), error) {
+		return s.download(storagePath)
+	})
+}
+
+// PreviewFromFile is Preview for content the caller already has on local disk
+func (s *previewService) PreviewFromFile(hash, localPath string) (string, error) {
+	return s.ensure(hash, func() (string, func(), error) {
+		return localPath, func() {}, nil
+	})
+}
+
+// ensure converts content unless its rendition is cached. Concurrent requests for the same
+// content wait for a single conversion.
+func (s *previewService) ensure(hash string, source func() (string, func(), error)) (string, error) {
+	if s.storage == nil {
+		return "", appErrors.NewInternalError("Storage unavailable", nil)
+	}
+
+	key := previewKey(hash)
+	_, err, _ := s.group.Do(hash, func() (interface{}, error) {
+		exists, err := s.storage.FileExists(key)
+		if err != nil {
+			return nil, appErrors.NewInternalError("Failed to check preview cache", err)
+		}
+		if exists {
+			return nil, nil
+		}
+
+		sourcePath, cleanup, err := source()
+		if err != nil {
+			return nil, err
+		}
+		defer cleanup()
+
+		return nil, s.convertAndStore(sourcePath, key)
+	})
+	if err != nil {
+		return "", err
+	}
+	return key, nil
+}
+
+// download copies stored content to a local temp file for conversion
+func (s *previewService) download(storagePath string) (string, func(), error) {
+	exists, err := s.storage.FileExists(storagePath)
+	if err != nil {
+		return "", nil, appErrors.NewInternalError("Failed to check file existence", err)
+	}
+	if !exists {
+		return "", nil, appErrors.NewNotFoundError("Original file for conversion", fmt.Errorf("path: %s", storagePath))
+	}
+
+	src, err := s.storage.DownloadFile(storagePath)
+	if err != nil {
+		return "", nil, appErrors.NewInternalError("Failed to download for conversion", err)
+	}
+	defer src.Close()
+
+	tempOriginal, err := os.CreateTemp("", "preview_orig_*"+filepath.Ext(storagePath))
+	if err != nil {
+		return "", nil, appErrors.NewInternalError("Failed to create temp file", err)
+	}
+	defer tempOriginal.Close()
+	cleanup := func() { os.Remove(tempOriginal.Name()) }
+
+	if _, err := io.Copy(tempOriginal, src); err != nil {
+		cleanup()
+		return "", nil, appErrors.NewInternalError("Failed to download for conversion", err)
+	}
+	return tempOriginal.Name(), cleanup, nil
+}
+
+// convertAndStore converts a local file to PDF and uploads the result to key
+func (s *previewService) convertAndStore(sourcePath, key string) error {
+	outDir, err := os.MkdirTemp("", "preview_out_*")
+	if err != nil {
+		return appErrors.NewInternalError("Failed to create temp dir", err)
+	}
+	defer os.RemoveAll(outDir)
+
+	pdfPath, err := s.convert(sourcePath, outDir)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Open(pdfPath)
+	if err != nil {
+		return appErrors.NewInternalError("Failed to read converted PDF", err)
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return appErrors.NewInternalError("Failed to read converted PDF", err)
+	}
+	if err := s.storage.UploadFile(key, f, info.Size(), mimePDF); err != nil {
+		return appErrors.NewInternalError("Failed to store preview", err)
+	}
+	return nil
+}
+
+// convert runs LibreOffice in a free slot, waiting for one for at most the timeout
+func (s *previewService) convert(sourcePath, outDir string) (string, error) {
+	wait := time.NewTimer(s.timeout)
+	defer wait.Stop()
+
+	var slot int
+	select {
+	case slot = <-s.slots:
+	case <-wait.C:
+		return "", appErrors.NewInternalError("Document conversion is busy; try again later", nil)
+	}
+	defer func() { s.slots <- slot }()
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+
+	// Concurrent LibreOffice processes must not share a user profile
+	profileDir := filepath.Join(os.TempDir(), fmt.Sprintf("libsystem-soffice-%d", slot))
+	cmd := exec.CommandContext(ctx, "soffice",
+		"-env:UserInstallation=file://"+filepath.ToSlash(profileDir),
+		"--headless", "--convert-to", "pdf", "--outdir", outDir, sourcePath)
+	killProcessGroupOnCancel(cmd)
+
+	output, err := cmd.CombinedOutput()
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		// A killed instance may leave its profile locked
+		os.RemoveAll(profileDir)
+		return "", appErrors.NewInternalError("Document conversion timed out", ctx.Err())
+	}
+	if err != nil {
+		fmt.Printf("DEBUG: Conversion failed: %s\n", string(output))
+		return "", appErrors.NewInternalError("Document conversion failed", err)
+	}
+
+	baseName := filepath.Base(sourcePath)
+	pdfPath := filepath.Join(outDir, strings.TrimSuffix(baseName, filepath.Ext(baseName))+".pdf")
+	if _, err := os.Stat(pdfPath); err != nil {
+		return "", appErrors.NewInternalError("Failed to read converted PDF", err)
+	}
+	return pdfPath, nil
+}
@@ -0,0 +1,14 @@
+//go:build !unix
+
+package service
+
+import (
+	"os/exec"
+	"time"
+)
+
+// killProcessGroupOnCancel bounds the wait for cmd after its context is cancelled; only
+// the process itself is killed on this platform
+func killProcessGroupOnCancel(cmd *exec.Cmd) {
+	cmd.WaitDelay = 5 * time.Second
+}
@@ -0,0 +1,160 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/Kyei-Ernest/libsystem/services/document-service/repository"
+	appErrors "github.com/Kyei-Ernest/libsystem/shared/errors"
+	"github.com/Kyei-Ernest/libsystem/shared/models"
+	"github.com/google/uuid"
+)
+
+// ProgressInput is a reading position reported by a viewer
+type ProgressInput struct {
+	Page       *int       `json:"page,omitempty"`
+	Percentage *float64   `json:"percentage,omitempty"`
+	ReadAt     *time.Time `json:"read_at,omitempty"` // When the reader was there; now when omitted
+}
+
+// ReadEvent is published by the document service when a document is viewed or downloaded
+type ReadEvent struct {
+	ID         uuid.UUID  `json:"id"`
+	UserID     *uuid.UUID `json:"user_id,omitempty"`
+	OccurredAt time.Time  `json:"occurred_at"`
+}
+
+// ReadingService tracks where users are in the documents they read. Viewers sync the
+// position; views and downloads keep the recently read list current.
+type ReadingService interface {
+	GetProgress(documentID, userID uuid.UUID) (*models.ReadingProgress, error)
+	SaveProgress(documentID uuid.UUID, input ProgressInput, userID uuid.UUID) (*models.ReadingProgress, error)
+	DeleteProgress(documentID, userID uuid.UUID) error
+	ListRecent(userID uuid.UUID, page, pageSize int) ([]models.ReadingProgress, int64, error)
+	HandleRead(payload []byte) error
+}
+
+// readingService implements ReadingService
+type readingService struct {
+	readingRepo     repository.ReadingRepository
+	documentService DocumentService
+}
+
+// NewReadingService creates a new reading service
+func NewReadingService(readingRepo repository.ReadingRepository, documentService DocumentService) ReadingService {
+	return &readingService{
+		readingRepo:     readingRepo,
+		documentService: documentService,
+	}
+}
+
+// GetProgress retrieves the user's position in a document
+func (s *readingService) GetProgress(documentID, userID uuid.UUID) (*models.ReadingProgress, error) {
+	if _, err := s.documentService.GetDocument(documentID, &userID); err != nil {
+		return nil, err
+	}
+
+	progress, err := s.readingRepo.Find(userID, documentID)
+	if err != nil {
+		return nil, appErrors.NewNotFoundError("Reading progress", err)
+	}
+	return progress, nil
+}
+
+// SaveProgress records the user's position in a document they may read. A position
+// reported with an earlier read_at than the stored one does not replace it, so the
+// latest position wins whichever device syncs last; the stored position is returned.
+func (s *readingService) SaveProgress(documentID uuid.UUID, input ProgressInput, userID uuid.UUID) (*models.ReadingProgress, error) {
+	document, err := s.documentService.GetContentDocument(documentID, &userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if input.Page == nil && input.Percentage == nil {
+		return nil, appErrors.NewValidationError("A page or percentage is required", nil)
+	}
+	if input.Page != nil {
+		if *input.Page < 1 {
+			return nil, appErrors.NewValidationError("Page must be at least 1", nil)
+		}
+		if document.PageCount > 0 && *input.Page > document.PageCount {
+			return nil, appErrors.NewValidationError(
+				fmt.Sprintf("Page must be at most %d", document.PageCount), nil)
+		}
+	}
+	if input.Percentage != nil && (*input.Percentage < 0 || *input.Percentage > 100) {
+		return nil, appErrors.NewValidationError("Percentage must be between 0 and 100", nil)
+	}
+
+	// A device clock running ahead must not pin the position
+	now := time.Now()
+	readAt := now
+	if input.ReadAt != nil && input.ReadAt.Before(now) {
+		readAt = *input.ReadAt
+	}
+
+	progress := &models.ReadingProgress{
+		UserID:            userID,
+		DocumentID:        documentID,
+		Page:              input.Page,
+		Percentage:        input.Percentage,
+		PositionUpdatedAt: &readAt,
+		LastReadAt:        readAt,
+	}
+	if err := s.readingRepo.SavePosition(progress); err != nil {
+		return nil, appErrors.NewInternalError("Failed to save reading progress", err)
+	}
+
+	stored, err := s.readingRepo.Find(userID, documentID)
+	if err != nil {
+		return nil, appErrors.NewInternalError("Failed to load reading progress", err)
+	}
+	return stored, nil
+}
+
+// DeleteProgress removes a document from the user's reading history
+func (s *readingService) DeleteProgress(documentID, userID uuid.UUID) error {
+	if err := s.readingRepo.Delete(userID, documentID); err != nil {
+		return appErrors.NewInternalError("Failed to delete reading progress", err)
+	}
+	return nil
+}
+
+// ListRecent lists the documents the user read most recently that they may still see
+func (s *readingService) ListRecent(userID uuid.UUID, page, pageSize int) ([]models.ReadingProgress, int64, error) {
+	offset := (page - 1) * pageSize
+	progress, total, err := s.readingRepo.ListRecent(userID, offset, pageSize)
+	if err != nil {
+		return nil, 0, appErrors.NewInternalError("Failed to list recently read documents", err)
+	}
+
+	visible := make([]models.ReadingProgress, 0, len(progress))
+	for _, p := range progress {
+		if p.Document == nil || checkVisible(p.Document, &userID) != nil {
+			continue
+		}
+		visible = append(visible, p)
+	}
+	return visible, total, nil
+}
+
+// HandleRead records a view or download by a signed-in user as a read. Anonymous reads
+// are ignored.
+func (s *readingService) HandleRead(payload []byte) error {
+	var event ReadEvent
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return fmt.Errorf("invalid read event: %w", err)
+	}
+	if event.UserID == nil {
+		return nil
+	}
+	if event.OccurredAt.IsZero() {
+		event.OccurredAt = time.Now()
+	}
+
+	if err := s.readingRepo.TouchLastRead(*event.UserID, event.ID, event.OccurredAt); err != nil {
+		return fmt.Errorf("failed to record read of document %s: %w", event.ID, err)
+	}
+	return nil
+}
@@ -0,0 +1,325 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/Kyei-Ernest/libsystem/services/document-service/repository"
+	appErrors "github.com/Kyei-Ernest/libsystem/shared/errors"
+	"github.com/Kyei-Ernest/libsystem/shared/models"
+	"github.com/google/uuid"
+)
+
+// RelationInput describes a relation from a document to a target document
+type RelationInput struct {
+	Type     models.RelationType `json:"type" binding:"required"`
+	TargetID uuid.UUID           `json:"target_id" binding:"required"`
+	Position *int                `json:"position,omitempty"` // For part_of, has_part, series_member and has_member
+}
+
+// RelatedDocument is a relation seen from one of its documents: Type reads from that
+// document to Document (a relation stored as part_of shows as has_part on the work)
+type RelatedDocument struct {
+	ID       uuid.UUID           `json:"id"` // Relation ID
+	Type     models.RelationType `json:"type"`
+	Document models.DocumentLink `json:"document"`
+}
+
+// RelationService manages typed relationships between documents: parts of works, series
+// volumes, editions, translations and related works
+type RelationService interface {
+	ListRelations(documentID uuid.UUID, userID *uuid.UUID) ([]RelatedDocument, error)
+	CreateRelation(documentID uuid.UUID, input RelationInput, userID uuid.UUID, isAdmin bool) (*RelatedDocument, error)
+	UpdatePosition(documentID, relationID uuid.UUID, position *int, userID uuid.UUID, isAdmin bool) (*RelatedDocument, error)
+	DeleteRelation(documentID, relationID uuid.UUID, userID uuid.UUID, isAdmin bool) error
+	Navigation(document *models.Document, userID *uuid.UUID) (*models.DocumentNavigation, error)
+}
+
+// relationService implements RelationService
+type relationService struct {
+	relationRepo      repository.RelationRepository
+	documentService   DocumentService
+	permissionService PermissionService
+}
+
+// NewRelationService creates a new relation service
+func NewRelationService(relationRepo repository.RelationRepository, documentService DocumentService, permissionService PermissionService) RelationService {
+	return &relationService{
+		relationRepo:      relationRepo,
+		documentService:   documentService,
+		permissionService: permissionService,
+	}
+}
+
+// acyclicTypes lists the stored types whose relations, followed from subject to object,
+// must never lead back to where they started. Parts and series memberships form one
+// hierarchy; related is symmetric and unchecked.
+var acyclicTypes = map[models.RelationType][]models.RelationType{
+	models.RelationPartOf:        {models.RelationPartOf, models.RelationSeriesMember},
+	models.RelationSeriesMember:  {models.RelationPartOf, models.RelationSeriesMember},
+	models.RelationSupersedes:    {models.RelationSupersedes},
+	models.RelationTranslationOf: {models.RelationTranslationOf},
+}
+
+// ListRelations lists a document's relations to the documents the user may see
+func (s *relationService) ListRelations(documentID uuid.UUID, userID *uuid.UUID) ([]RelatedDocument, error) {
+	if _, err := s.documentService.GetDocument(documentID, userID); err != nil {
+		return nil, err
+	}
+
+	relations, err := s.relationRepo.ListByDocument(documentID)
+	if err != nil {
+		return nil, appErrors.NewInternalError("Failed to list relations", err)
+	}
+
+	related := make([]RelatedDocument, 0, len(relations))
+	for i := range relations {
+		view, other := relatedFrom(&relations[i], documentID)
+		if other.ID == uuid.Nil || checkVisible(other, userID) != nil {
+			continue // In the trash, or hidden from the user
+		}
+		related = append(related, view)
+	}
+	return related, nil
+}
+
+// CreateRelation relates a document to a target the user may see. Inverse types are stored
+// as their forward type, so has_part on a work creates part_of on the part.
+func (s *relationService) CreateRelation(documentID uuid.UUID, input RelationInput, userID uuid.UUID, isAdmin bool) (*RelatedDocument, error) {
+	if !input.Type.Valid() {
+		return nil, appErrors.NewValidationError(fmt.Sprintf("Unknown relation type %q", input.Type), nil)
+	}
+	if input.TargetID == documentID {
+		return nil, appErrors.NewValidationError("A document cannot be related to itself", nil)
+	}
+
+	if _, err := s.documentService.GetDocument(documentID, &userID); err != nil {
+		return nil, err
+	}
+	if _, err := s.documentService.GetDocument(input.TargetID, &userID); err != nil {
+		return nil, err
+	}
+
+	relation := &models.DocumentRelation{
+		SubjectID: documentID,
+		ObjectID:  input.TargetID,
+		Type:      input.Type,
+		Position:  input.Position,
+		CreatedBy: userID,
+	}
+	if !input.Type.Stored() {
+		relation.SubjectID, relation.ObjectID = input.TargetID, documentID
+		relation.Type = input.Type.Inverse()
+	}
+	if relation.Position != nil && !relation.Type.Ordered() {
+		return nil, appErrors.NewValidationError(fmt.Sprintf("Relations of type %s have no position", input.Type), nil)
+	}
+	if err := s.requireMemberEdit(relation, documentID, userID, isAdmin); err != nil {
+		return nil, err
+	}
+
+	if err := s.relationRepo.Create(relation, acyclicTypes[relation.Type]); err != nil {
+		switch {
+		case errors.Is(err, repository.ErrRelationExists), errors.Is(err, repository.ErrRelationParent):
+			return nil, appErrors.NewConflictError("Relation", err)
+		case errors.Is(err, repository.ErrRelationCycle):
+			return nil, appErrors.NewValidationError("The relation would make a document part of itself", err)
+		}
+		return nil, appErrors.NewInternalError("Failed to create relation", err)
+	}
+
+	s.reindexMember(relation)
+	return s.view(relation.ID, documentID)
+}
+
+// UpdatePosition moves a part or volume within its work
+func (s *relationService) UpdatePosition(documentID, relationID uuid.UUID, position *int, userID uuid.UUID, isAdmin bool) (*RelatedDocument, error) {
+	relation, err := s.find(documentID, relationID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !relation.Type.Ordered() {
+		return nil, appErrors.NewValidationError(fmt.Sprintf("Relations of type %s have no position", relation.Type), nil)
+	}
+	if err := s.requireMemberEdit(relation, documentID, userID, isAdmin); err != nil {
+		return nil, err
+	}
+
+	if err := s.relationRepo.UpdatePosition(relationID, position); err != nil {
+		return nil, appErrors.NewInternalError("Failed to update relation", err)
+	}
+
+	s.reindexMember(relation)
+	return s.view(relationID, documentID)
+}
+
+// DeleteRelation removes a relation of a document
+func (s *relationService) DeleteRelation(documentID, relationID uuid.UUID, userID uuid.UUID, isAdmin bool) error {
+	relation, err := s.find(documentID, relationID, userID)
+	if err != nil {
+		return err
+	}
+	if err := s.requireMemberEdit(relation, documentID, userID, isAdmin); err != nil {
+		return err
+	}
+
+	if err := s.relationRepo.Delete(relationID); err != nil {
+		return appErrors.NewInternalError("Failed to delete relation", err)
+	}
+
+	s.reindexMember(relation)
+	return nil
+}
+
+// Navigation places a document among the works it belongs to, showing only documents the
+// user may see. Previous and next step through the volumes of its series, or else through
+// the parts of the work it belongs to. It returns nil for unrelated documents.
+func (s *relationService) Navigation(document *models.Document, userID *uuid.UUID) (*models.DocumentNavigation, error) {
+	relations, err := s.relationRepo.ListByDocument(document.ID)
+	if err != nil {
+		return nil, appErrors.NewInternalError("Failed to load relations", err)
+	}
+
+	navigation := &models.DocumentNavigation{}
+	var siblingsOf *models.DocumentRelation
+	for i := range relations {
+		relation := &relations[i]
+		if !relation.Type.Ordered() {
+			continue
+		}
+
+		if relation.ObjectID == document.ID {
+			if relation.Subject.ID != uuid.Nil && checkVisible(&relation.Subject, userID) == nil {
+				navigation.Parts = append(navigation.Parts, documentLink(&relation.Subject, relation.Position))
+			}
+			continue
+		}
+
+		if relation.Object.ID == uuid.Nil || checkVisible(&relation.Object, userID) != nil {
+			continue
+		}
+		link := documentLink(&relation.Object, nil)
+		switch {
+		case relation.Type == models.RelationPartOf:
+			navigation.PartOf = &link
+			if siblingsOf == nil {
+				siblingsOf = relation
+			}
+		case navigation.Series == nil:
+			navigation.Series = &link
+			siblingsOf = relation
+		}
+	}
+
+	if siblingsOf != nil {
+		members, err := s.relationRepo.ListMembers(siblingsOf.ObjectID, siblingsOf.Type)
+		if err != nil {
+			return nil, appErrors.NewInternalError("Failed to load relations", err)
+		}
+		navigation.Previous, navigation.Next = neighbours(members, document.ID, userID)
+	}
+
+	if navigation.PartOf == nil && navigation.Series == nil && len(navigation.Parts) == 0 {
+		return nil, nil
+	}
+	return navigation, nil
+}
+
+// find retrieves a relation of a document whose other document the user may see
+func (s *relationService) find(documentID, relationID uuid.UUID, userID uuid.UUID) (*models.DocumentRelation, error) {
+	relation, err := s.relationRepo.FindByID(relationID)
+	if err != nil {
+		return nil, appErrors.NewNotFoundError("Relation", err)
+	}
+	if relation.SubjectID != documentID && relation.ObjectID != documentID {
+		return nil, appErrors.NewNotFoundError("Relation",
+			fmt.Errorf("relation %s does not belong to document %s", relationID, documentID))
+	}
+	if _, err := s.documentService.GetDocument(documentID, &userID); err != nil {
+		return nil, err
+	}
+	return relation, nil
+}
+
+// requireMemberEdit checks that the user may edit the part or member of an ordered relation
+// changed from its work. Edit permission on the work alone (checked by the routes) would
+// let a user claim any document they can see as part of their own.
+func (s *relationService) requireMemberEdit(relation *models.DocumentRelation, documentID, userID uuid.UUID, isAdmin bool) error {
+	if !relation.Type.Ordered() || relation.SubjectID == documentID || isAdmin {
+		return nil
+	}
+	allowed, err := s.permissionService.HasDocumentPermission(userID, relation.SubjectID, models.PermissionEdit)
+	if err != nil {
+		return appErrors.NewInternalError("Failed to check permissions", err)
+	}
+	if !allowed {
+		return appErrors.NewForbiddenError("Changing a part or member of a work requires edit permission on that document", nil)
+	}
+	return nil
+}
+
+// view returns a stored relation as seen from a document
+func (s *relationService) view(relationID, documentID uuid.UUID) (*RelatedDocument, error) {
+	relations, err := s.relationRepo.ListByDocument(documentID)
+	if err != nil {
+		return nil, appErrors.NewInternalError("Failed to load relation", err)
+	}
+	for i := range relations {
+		if relations[i].ID == relationID {
+			view, _ := relatedFrom(&relations[i], documentID)
+			return &view, nil
+		}
+	}
+	return nil, appErrors.NewNotFoundError("Relation", fmt.Errorf("relation %s", relationID))
+}
+
+// reindexMember refreshes the search entry of a part or volume, which records the work it
+// belongs to
+func (s *relationService) reindexMember(relation *models.DocumentRelation) {
+	if !relation.Type.Ordered() {
+		return
+	}
+	if err := s.documentService.RequestReindex(relation.SubjectID); err != nil {
+		fmt.Printf("DEBUG: Failed to reindex document %s: %v\n", relation.SubjectID, err)
+	}
+}
+
+// relatedFrom returns a relation as seen from one of its documents, and the other document
+func relatedFrom(relation *models.DocumentRelation, documentID uuid.UUID) (RelatedDocument, *models.Document) {
+	view := RelatedDocument{ID: relation.ID, Type: relation.Type}
+	other := &relation.Object
+	if relation.SubjectID != documentID {
+		view.Type = relation.Type.Inverse()
+		other = &relation.Subject
+	}
+	view.Document = documentLink(other, relation.Position)
+	return view, other
+}
+
+// neighbours returns the visible members of a work before and after a document
+func neighbours(members []models.DocumentRelation, documentID uuid.UUID, userID *uuid.UUID) (*models.DocumentLink, *models.DocumentLink) {
+	var previous, next *models.DocumentLink
+	found := false
+	for i := range members {
+		member := &members[i]
+		if member.SubjectID == documentID {
+			found = true
+			continue
+		}
+		if member.Subject.ID == uuid.Nil || checkVisible(&member.Subject, userID) != nil {
+			continue
+		}
+		link := documentLink(&member.Subject, member.Position)
+		if !found {
+			previous = &link
+		} else {
+			next = &link
+			break
+		}
+	}
+	return previous, next
+}
+
+func documentLink(document *models.Document, position *int) models.DocumentLink {
+	return models.DocumentLink{ID: document.ID, Title: document.Title, Position: position}
+}
@@ -0,0 +1,345 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/Kyei-Ernest/libsystem/services/document-service/repository"
+	appErrors "github.com/Kyei-Ernest/libsystem/shared/errors"
+	"github.com/Kyei-Ernest/libsystem/shared/models"
+	"github.com/google/uuid"
+)
+
+const (
+	maxShelfItems    = 1000 // Documents on one shelf
+	maxShelfItemNote = 2000 // Characters in a note on a shelf item
+)
+
+// ShelfInput describes a new reading list
+type ShelfInput struct {
+	Name        string `json:"name" binding:"required"`
+	Description string `json:"description,omitempty"`
+	IsPublic    bool   `json:"is_public"`
+}
+
+// ShelfUpdate represents the fields of a shelf that can be changed
+type ShelfUpdate struct {
+	Name        *string `json:"name,omitempty"`
+	Description *string `json:"description,omitempty"`
+	IsPublic    *bool   `json:"is_public,omitempty"`
+}
+
+// ShelfItemInput describes a document to put on a shelf
+type ShelfItemInput struct {
+	DocumentID uuid.UUID `json:"document_id" binding:"required"`
+	Position   int       `json:"position,omitempty"` // At the end when omitted
+	Note       string    `json:"note,omitempty"`
+}
+
+// ShelfItemUpdate represents the fields of a shelf item that can be changed
+type ShelfItemUpdate struct {
+	Position *int    `json:"position,omitempty"`
+	Note     *string `json:"note,omitempty"`
+}
+
+// ShelfService manages users' favorites and reading lists. Shelves are private to their
+// owner unless made public, and list only the documents the viewer may see.
+type ShelfService interface {
+	ListShelves(ownerID uuid.UUID, userID *uuid.UUID, page, pageSize int) ([]models.Shelf, int64, error)
+	GetShelf(shelfID uuid.UUID, userID *uuid.UUID) (*models.Shelf, error)
+	CreateShelf(input ShelfInput, userID uuid.UUID) (*models.Shelf, error)
+	UpdateShelf(shelfID uuid.UUID, update ShelfUpdate, userID uuid.UUID) (*models.Shelf, error)
+	DeleteShelf(shelfID, userID uuid.UUID) error
+	AddItem(shelfID uuid.UUID, input ShelfItemInput, userID uuid.UUID) (*models.ShelfItem, error)
+	UpdateItem(shelfID, documentID uuid.UUID, update ShelfItemUpdate, userID uuid.UUID) (*models.ShelfItem, error)
+	RemoveItem(shelfID, documentID, userID uuid.UUID) error
+	Favorite(documentID, userID uuid.UUID) (*models.ShelfItem, error)
+	Unfavorite(documentID, userID uuid.UUID) error
+}
+
+// shelfService implements ShelfService
+type shelfService struct {
+	shelfRepo       repository.ShelfRepository
+	documentService DocumentService
+}
+
+// NewShelfService creates a new shelf service
+func NewShelfService(shelfRepo repository.ShelfRepository, documentService DocumentService) ShelfService {
+	return &shelfService{
+		shelfRepo:       shelfRepo,
+		documentService: documentService,
+	}
+}
+
+// ListShelves lists a user's shelves: all of them to the user, the public ones to others
+func (s *shelfService) ListShelves(ownerID uuid.UUID, userID *uuid.UUID, page, pageSize int) ([]models.Shelf, int64, error) {
+	own := userID != nil && *userID == ownerID
+	if own {
+		if _, err := s.shelfRepo.EnsureFavorites(ownerID); err != nil {
+			return nil, 0, appErrors.NewInternalError("Failed to load favorites", err)
+		}
+	}
+
+	offset := (page - 1) * pageSize
+	shelves, total, err := s.shelfRepo.ListByOwner(ownerID, !own, offset, pageSize)
+	if err != nil {
+		return nil, 0, appErrors.NewInternalError("Failed to list shelves", err)
+	}
+	return shelves, total, nil
+}
+
+// GetShelf retrieves a shelf with the documents on it that the user may see
+func (s *shelfService) GetShelf(shelfID uuid.UUID, userID *uuid.UUID) (*models.Shelf, error) {
+	shelf, err := s.shelfRepo.FindByID(shelfID)
+	if err != nil {
+		return nil, appErrors.NewNotFoundError("Shelf", err)
+	}
+	own := userID != nil && *userID == shelf.OwnerID
+	if !own && !shelf.IsPublic {
+		// Private shelves are not revealed to others
+		return nil, appErrors.NewNotFoundError("Shelf", fmt.Errorf("shelf %s is private", shelfID))
+	}
+
+	items, err := s.shelfRepo.ListItems(shelfID)
+	if err != nil {
+		return nil, appErrors.NewInternalError("Failed to load shelf", err)
+	}
+
+	shelf.Items = make([]models.ShelfItem, 0, len(items))
+	for _, item := range items {
+		if item.Document == nil || checkVisible(item.Document, userID) != nil {
+			continue // In the trash, or hidden from the user
+		}
+		shelf.Items = append(shelf.Items, item)
+	}
+	if !own {
+		shelf.ItemCount = int64(len(shelf.Items))
+	}
+	return shelf, nil
+}
+
+// CreateShelf creates a reading list
+func (s *shelfService) CreateShelf(input ShelfInput, userID uuid.UUID) (*models.Shelf, error) {
+	name, err := validateShelfName(input.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	shelf := &models.Shelf{
+		OwnerID:     userID,
+		Kind:        models.ShelfList,
+		Name:        name,
+		Description: strings.TrimSpace(input.Description),
+		IsPublic:    input.IsPublic,
+	}
+	if err := s.shelfRepo.Create(shelf); err != nil {
+		return nil, shelfError("Failed to create shelf", err)
+	}
+
+	return s.shelfRepo.FindByID(shelf.ID)
+}
+
+// UpdateShelf renames, describes or publishes one of the user's shelves. Favorites keep
+// their name.
+func (s *shelfService) UpdateShelf(shelfID uuid.UUID, update ShelfUpdate, userID uuid.UUID) (*models.Shelf, error) {
+	shelf, err := s.ownShelf(shelfID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if update.Name != nil {
+		if shelf.Kind == models.ShelfFavorites {
+			return nil, appErrors.NewValidationError("Favorites cannot be renamed", nil)
+		}
+		name, err := validateShelfName(*update.Name)
+		if err != nil {
+			return nil, err
+		}
+		shelf.Name = name
+	}
+	if update.Description != nil {
+		shelf.Description = strings.TrimSpace(*update.Description)
+	}
+	if update.IsPublic != nil {
+		shelf.IsPublic = *update.IsPublic
+	}
+
+	if err := s.shelfRepo.Update(shelf); err != nil {
+		return nil, shelfError("Failed to update shelf", err)
+	}
+
+	return s.shelfRepo.FindByID(shelfID)
+}
+
+// DeleteShelf deletes one of the user's reading lists
+func (s *shelfService) DeleteShelf(shelfID, userID uuid.UUID) error {
+	shelf, err := s.ownShelf(shelfID, userID)
+	if err != nil {
+		return err
+	}
+	if shelf.Kind == models.ShelfFavorites {
+		return appErrors.NewValidationError("Favorites cannot be deleted", nil)
+	}
+
+	if err := s.shelfRepo.Delete(shelfID); err != nil {
+		return appErrors.NewInternalError("Failed to delete shelf", err)
+	}
+	return nil
+}
+
+// AddItem puts a document the user may see on one of their shelves
+func (s *shelfService) AddItem(shelfID uuid.UUID, input ShelfItemInput, userID uuid.UUID) (*models.ShelfItem, error) {
+	if _, err := s.ownShelf(shelfID, userID); err != nil {
+		return nil, err
+	}
+	return s.addItem(shelfID, input, userID)
+}
+
+// UpdateItem moves a document on one of the user's shelves or changes its note
+func (s *shelfService) UpdateItem(shelfID, documentID uuid.UUID, update ShelfItemUpdate, userID uuid.UUID) (*models.ShelfItem, error) {
+	if _, err := s.ownShelf(shelfID, userID); err != nil {
+		return nil, err
+	}
+
+	var note *string
+	if update.Note != nil {
+		trimmed := strings.TrimSpace(*update.Note)
+		if err := validateShelfNote(trimmed); err != nil {
+			return nil, err
+		}
+		note = &trimmed
+	}
+
+	if err := s.shelfRepo.UpdateItem(shelfID, documentID, update.Position, note); err != nil {
+		return nil, shelfError("Failed to update shelf item", err)
+	}
+
+	return s.findItem(shelfID, documentID)
+}
+
+// RemoveItem takes a document off one of the user's shelves
+func (s *shelfService) RemoveItem(shelfID, documentID, userID uuid.UUID) error {
+	if _, err := s.ownShelf(shelfID, userID); err != nil {
+		return err
+	}
+
+	if err := s.shelfRepo.RemoveItem(shelfID, documentID); err != nil {
+		return shelfError("Failed to remove shelf item", err)
+	}
+	return nil
+}
+
+// Favorite adds a document to the user's favorites; favoriting it again changes nothing
+func (s *shelfService) Favorite(documentID, userID uuid.UUID) (*models.ShelfItem, error) {
+	favorites, err := s.shelfRepo.EnsureFavorites(userID)
+	if err != nil {
+		return nil, appErrors.NewInternalError("Failed to load favorites", err)
+	}
+
+	item, err := s.addItem(favorites.ID, ShelfItemInput{DocumentID: documentID}, userID)
+	var appErr *appErrors.AppError
+	if errors.As(err, &appErr) && appErr.Code == appErrors.ErrCodeConflict {
+		return s.findItem(favorites.ID, documentID)
+	}
+	return item, err
+}
+
+// Unfavorite removes a document from the user's favorites, if it is there
+func (s *shelfService) Unfavorite(documentID, userID uuid.UUID) error {
+	favorites, err := s.shelfRepo.EnsureFavorites(userID)
+	if err != nil {
+		return appErrors.NewInternalError("Failed to load favorites", err)
+	}
+
+	if _, err := s.shelfRepo.FindItem(favorites.ID, documentID); err != nil {
+		return nil
+	}
+	if err := s.shelfRepo.RemoveItem(favorites.ID, documentID); err != nil {
+		return shelfError("Failed to remove favorite", err)
+	}
+	return nil
+}
+
+// addItem puts a document the user may see on a shelf
+func (s *shelfService) addItem(shelfID uuid.UUID, input ShelfItemInput, userID uuid.UUID) (*models.ShelfItem, error) {
+	note := strings.TrimSpace(input.Note)
+	if err := validateShelfNote(note); err != nil {
+		return nil, err
+	}
+	if _, err := s.documentService.GetDocument(input.DocumentID, &userID); err != nil {
+		return nil, err
+	}
+
+	item := &models.ShelfItem{
+		ShelfID:    shelfID,
+		DocumentID: input.DocumentID,
+		Position:   input.Position,
+		Note:       note,
+	}
+	if err := s.shelfRepo.AddItem(item, maxShelfItems); err != nil {
+		return nil, shelfError("Failed to add document to shelf", err)
+	}
+
+	return s.findItem(shelfID, input.DocumentID)
+}
+
+// ownShelf retrieves one of the user's shelves; others' shelves are not found
+func (s *shelfService) ownShelf(shelfID, userID uuid.UUID) (*models.Shelf, error) {
+	shelf, err := s.shelfRepo.FindByID(shelfID)
+	if err != nil {
+		return nil, appErrors.NewNotFoundError("Shelf", err)
+	}
+	if shelf.OwnerID != userID {
+		if shelf.IsPublic {
+			return nil, appErrors.NewForbiddenError("Only the owner can change this shelf", nil)
+		}
+		return nil, appErrors.NewNotFoundError("Shelf", fmt.Errorf("shelf %s is private", shelfID))
+	}
+	return shelf, nil
+}
+
+// findItem retrieves a document's entry on a shelf
+func (s *shelfService) findItem(shelfID, documentID uuid.UUID) (*models.ShelfItem, error) {
+	item, err := s.shelfRepo.FindItem(shelfID, documentID)
+	if err != nil {
+		return nil, appErrors.NewNotFoundError("Shelf item", err)
+	}
+	return item, nil
+}
+
+// shelfError maps shelf repository errors to application errors
+func shelfError(message string, err error) error {
+	switch {
+	case errors.Is(err, repository.ErrShelfNameTaken):
+		return appErrors.NewConflictError("Shelf", err)
+	case errors.Is(err, repository.ErrShelfItemExists):
+		return appErrors.NewConflictError("Shelf item", err)
+	case errors.Is(err, repository.ErrShelfFull):
+		return appErrors.NewValidationError(fmt.Sprintf("A shelf holds at most %d documents", maxShelfItems), err)
+	case errors.Is(err, repository.ErrShelfItemNotFound):
+		return appErrors.NewNotFoundError("Shelf item", err)
+	}
+	return appErrors.NewInternalError(message, err)
+}
+
+// validateShelfName trims a reading list's name and checks it
+func validateShelfName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	switch {
+	case name == "":
+		return "", appErrors.NewValidationError("Shelf name is required", nil)
+	case len(name) > 255:
+		return "", appErrors.NewValidationError("Shelf name must be at most 255 characters", nil)
+	case strings.EqualFold(name, "Favorites"):
+		return "", appErrors.NewValidationError("Favorites is reserved", nil)
+	}
+	return name, nil
+}
+
+func validateShelfNote(note string) error {
+	if len([]rune(note)) > maxShelfItemNote {
+		return appErrors.NewValidationError(fmt.Sprintf("Notes must be at most %d characters", maxShelfItemNote), nil)
+	}
+	return nil
+}
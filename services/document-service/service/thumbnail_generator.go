@@ -2,6 +2,7 @@ package service
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
@@ -23,6 +24,9 @@ func NewThumbnailGenerator() *ThumbnailGenerator {
 	}
 }
 
+// ErrThumbnailUnsupported is returned for content types no thumbnail can be made from
+var ErrThumbnailUnsupported = errors.New("unsupported mime type for thumbnail")
+
 // GenerateThumbnail generates a thumbnail for the given file
 // Returns the path to the generated thumbnail file
 func (g *ThumbnailGenerator) GenerateThumbnail(filePath string, mimeType string) (string, error) {
@@ -52,7 +56,7 @@ func (g *ThumbnailGenerator) GenerateThumbnail(filePath string, mimeType string)
 		return g.generateFromText(filePath, outputPrefix)
 	}
 
-	return "", fmt.Errorf("unsupported mime type for thumbnail: %s", mimeType)
+	return "", fmt.Errorf("%w: %s", ErrThumbnailUnsupported, mimeType)
 }
 
 func (g *ThumbnailGenerator) generateFromPDF(inputPath string, outputPrefix string) (string, error) {
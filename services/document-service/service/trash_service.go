@@ -0,0 +1,231 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/Kyei-Ernest/libsystem/services/document-service/repository"
+	appErrors "github.com/Kyei-Ernest/libsystem/shared/errors"
+	"github.com/Kyei-Ernest/libsystem/shared/models"
+	"github.com/google/uuid"
+)
+
+// purgeBatchSize is the number of expired documents purged per query
+const purgeBatchSize = 100
+
+// CollectionTrashEvent is published by the collection service when a collection is moved
+// to or restored from the trash
+type CollectionTrashEvent struct {
+	ID        uuid.UUID  `json:"id"`
+	DeletedBy *uuid.UUID `json:"deleted_by,omitempty"`
+	DeletedAt time.Time  `json:"deleted_at"`
+}
+
+// TrashService defines the interface for the document trash
+type TrashService interface {
+	ListTrash(userID uuid.UUID, isAdmin bool, collectionID *uuid.UUID, page, pageSize int) ([]models.Document, int64, error)
+	RestoreDocument(id, userID uuid.UUID, isAdmin bool) (*models.Document, error)
+	PurgeDocument(id, userID uuid.UUID, isAdmin bool) error
+	HandleCollectionDeleted(payload []byte) error
+	HandleCollectionRestored(payload []byte) error
+	PurgeExpired() (int, error)
+}
+
+// trashService implements TrashService
+type trashService struct {
+	documentRepo    repository.DocumentRepository
+	collectionRepo  repository.CollectionRepository
+	versionRepo     repository.VersionRepository
+	documentService DocumentService
+	retention       time.Duration
+}
+
+// NewTrashService creates a new trash service. Documents are purged once they have been
+// in the trash for longer than retention.
+func NewTrashService(
+	documentRepo repository.DocumentRepository,
+	collectionRepo repository.CollectionRepository,
+	versionRepo repository.VersionRepository,
+	documentService DocumentService,
+	retention time.Duration,
+) TrashService {
+	return &trashService{
+		documentRepo:    documentRepo,
+		collectionRepo:  collectionRepo,
+		versionRepo:     versionRepo,
+		documentService: documentService,
+		retention:       retention,
+	}
+}
+
+// ListTrash lists trashed documents. Without a collection, these are the documents the
+// user uploaded or deleted; a collection's trash is visible to its owner.
+func (s *trashService) ListTrash(userID uuid.UUID, isAdmin bool, collectionID *uuid.UUID, page, pageSize int) ([]models.Document, int64, error) {
+	filters := repository.TrashFilters{CollectionID: collectionID}
+
+	if collectionID != nil {
+		collection, err := s.collectionRepo.FindByIDWithTrashed(*collectionID)
+		if err != nil {
+			return nil, 0, appErrors.NewNotFoundError("Collection", err)
+		}
+		if !isAdmin && collection.OwnerID != userID {
+			return nil, 0, appErrors.NewForbiddenError("Only the collection owner can view its trash", nil)
+		}
+	} else if !isAdmin {
+		filters.OwnerID = &userID
+	}
+
+	offset := (page - 1) * pageSize
+	documents, total, err := s.documentRepo.ListTrash(filters, offset, pageSize)
+	if err != nil {
+		return nil, 0, appErrors.NewInternalError("Failed to list trash", err)
+	}
+
+	return documents, total, nil
+}
+
+// RestoreDocument takes a document out of the trash and reindexes it
+func (s *trashService) RestoreDocument(id, userID uuid.UUID, isAdmin bool) (*models.Document, error) {
+	document, err := s.findTrashed(id, userID, isAdmin)
+	if err != nil {
+		return nil, err
+	}
+
+	if document.Collection.DeletedAt.Valid {
+		return nil, appErrors.NewConflictError("Document",
+			fmt.Errorf("its collection is in the trash; restore the collection instead"))
+	}
+
+	if err := s.documentRepo.Restore(id); err != nil {
+		return nil, appErrors.NewInternalError("Failed to restore document", err)
+	}
+
+	if err := s.documentService.RequestReindex(id); err != nil {
+		return nil, err
+	}
+
+	restored, err := s.documentRepo.FindByID(id)
+	if err != nil {
+		return nil, appErrors.NewInternalError("Failed to load restored document", err)
+	}
+	return restored, nil
+}
+
+// PurgeDocument permanently deletes a trashed document and its files
+func (s *trashService) PurgeDocument(id, userID uuid.UUID, isAdmin bool) error {
+	document, err := s.findTrashed(id, userID, isAdmin)
+	if err != nil {
+		return err
+	}
+
+	if err := s.purge(document); err != nil {
+		return appErrors.NewInternalError("Failed to purge document", err)
+	}
+	return nil
+}
+
+// HandleCollectionDeleted moves the documents of a trashed collection to the trash
+func (s *trashService) HandleCollectionDeleted(payload []byte) error {
+	var event CollectionTrashEvent
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return fmt.Errorf("invalid collection.deleted event: %w", err)
+	}
+	if event.DeletedAt.IsZero() {
+		event.DeletedAt = time.Now()
+	}
+
+	ids, err := s.documentRepo.TrashByCollection(event.ID, event.DeletedBy, event.DeletedAt)
+	if err != nil {
+		return fmt.Errorf("failed to trash documents of collection %s: %w", event.ID, err)
+	}
+
+	for _, id := range ids {
+		s.documentService.RemoveFromSearch(id)
+	}
+	return nil
+}
+
+// HandleCollectionRestored restores the documents trashed along with a collection
+func (s *trashService) HandleCollectionRestored(payload []byte) error {
+	var event CollectionTrashEvent
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return fmt.Errorf("invalid collection.restored event: %w", err)
+	}
+
+	ids, err := s.documentRepo.RestoreByCollection(event.ID)
+	if err != nil {
+		return fmt.Errorf("failed to restore documents of collection %s: %w", event.ID, err)
+	}
+
+	for _, id := range ids {
+		if err := s.documentService.RequestReindex(id); err != nil {
+			fmt.Printf("DEBUG: Failed to reindex restored document %s: %v\n", id, err)
+		}
+	}
+	return nil
+}
+
+// PurgeExpired permanently deletes documents whose retention period in the trash has
+// passed and returns how many were purged
+func (s *trashService) PurgeExpired() (int, error) {
+	cutoff := time.Now().Add(-s.retention)
+	purged := 0
+
+	for {
+		documents, err := s.documentRepo.ListPurgeable(cutoff, purgeBatchSize)
+		if err != nil {
+			return purged, err
+		}
+
+		for i := range documents {
+			if err := s.purge(&documents[i]); err != nil {
+				return purged, err
+			}
+			purged++
+		}
+
+		if len(documents) < purgeBatchSize {
+			return purged, nil
+		}
+	}
+}
+
+// findTrashed retrieves a trashed document the user may restore or purge: its uploader,
+// whoever deleted it, the collection owner or an admin
+func (s *trashService) findTrashed(id, userID uuid.UUID, isAdmin bool) (*models.Document, error) {
+	document, err := s.documentRepo.FindTrashedByID(id)
+	if err != nil {
+		return nil, appErrors.NewNotFoundError("Document", err)
+	}
+
+	allowed := isAdmin ||
+		document.UploaderID == userID ||
+		(document.DeletedBy != nil && *document.DeletedBy == userID) ||
+		document.Collection.OwnerID == userID
+	if !allowed {
+		return nil, appErrors.NewForbiddenError("You do not have permission to manage this document", nil)
+	}
+
+	return document, nil
+}
+
+// purge hard-deletes a document row (and by cascade its versions), then releases the
+// content they referenced; files no other document uses are deleted
+func (s *trashService) purge(document *models.Document) error {
+	versions, err := s.versionRepo.GetByDocumentID(document.ID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.documentRepo.Purge(document.ID); err != nil {
+		return err
+	}
+
+	// The document row and each of its version rows held a reference to their content
+	s.documentService.ReleaseContent(document.Hash)
+	for _, v := range versions {
+		s.documentService.ReleaseContent(v.Hash)
+	}
+	return nil
+}
@@ -0,0 +1,262 @@
+package service
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/Kyei-Ernest/libsystem/services/document-service/repository"
+	appErrors "github.com/Kyei-Ernest/libsystem/shared/errors"
+	"github.com/Kyei-Ernest/libsystem/shared/models"
+	"github.com/Kyei-Ernest/libsystem/shared/storage"
+	"github.com/Kyei-Ernest/libsystem/shared/validator"
+	"github.com/google/uuid"
+)
+
+// PresignedUpload describes how a client uploads a file straight to storage: either a
+// single PUT to PutURL, or a multipart form POST of PostFields plus the file to PostURL.
+// Both enforce the declared content type at upload time; the PUT must be of exactly the
+// declared size, the POST of at most it.
+type PresignedUpload struct {
+	PutURL     string            `json:"put_url"`
+	PostURL    string            `json:"post_url"`
+	PostFields map[string]string `json:"post_fields"`
+	ExpiresAt  time.Time         `json:"expires_at"`
+}
+
+// UploadIntentService manages direct-to-storage uploads. The client uploads to a presigned
+// staging key, then finalizes the intent, which verifies the object and hands it off to
+// DocumentService.IngestStagedDocument.
+type UploadIntentService interface {
+	CreateIntent(file UploadFile, metadata UploadMetadata) (*models.UploadIntent, *PresignedUpload, error)
+	GetIntent(id, userID uuid.UUID) (*models.UploadIntent, error)
+	FinalizeIntent(id, userID uuid.UUID) (*models.Document, error)
+	PurgeExpired() (int, error)
+}
+
+// uploadIntentService implements UploadIntentService
+type uploadIntentService struct {
+	intentRepo      repository.UploadIntentRepository
+	documentService DocumentService
+	fileService     FileService
+	storage         *storage.MinIOClient
+	urlExpiry       time.Duration // validity of the presigned URLs
+	expiry          time.Duration // time allowed to upload and finalize
+	locks           sync.Map      // intent ID -> *sync.Mutex, serialises finalization
+}
+
+// NewUploadIntentService creates a new direct upload service
+func NewUploadIntentService(intentRepo repository.UploadIntentRepository, documentService DocumentService, fileService FileService, storageClient *storage.MinIOClient, urlExpiry, expiry time.Duration) UploadIntentService {
+	return &uploadIntentService{
+		intentRepo:      intentRepo,
+		documentService: documentService,
+		fileService:     fileService,
+		storage:         storageClient,
+		urlExpiry:       urlExpiry,
+		expiry:          expiry,
+	}
+}
+
+// CreateIntent validates the declared file and issues presigned upload URLs for a fresh
+// staging key
+func (s *uploadIntentService) CreateIntent(file UploadFile, metadata UploadMetadata) (*models.UploadIntent, *PresignedUpload, error) {
+	if s.storage == nil {
+		return nil, nil, appErrors.NewInternalError("File storage is not available", nil)
+	}
+
+	if err := validator.ValidateRequired(metadata.Title, "title"); err != nil {
+		return nil, nil, appErrors.NewValidationError(err.Error(), err)
+	}
+	if err := validator.ValidateRequired(file.Filename, "filename"); err != nil {
+		return nil, nil, appErrors.NewValidationError(err.Error(), err)
+	}
+	if file.Size <= 0 {
+		return nil, nil, appErrors.NewValidationError("size must be greater than zero", nil)
+	}
+
+	// Fail fast on files that ingestion would reject anyway
+	if err := s.fileService.ValidateFileSize(file.Size); err != nil {
+		return nil, nil, err
+	}
+	if err := s.fileService.ValidateFileType(file.ContentType); err != nil {
+		return nil, nil, err
+	}
+	if err := s.documentService.CheckUploadPolicy(file, metadata); err != nil {
+		return nil, nil, err
+	}
+
+	// Keep the extension: the indexer picks its extractor from the storage path
+	id := uuid.New()
+	objectKey := fmt.Sprintf("%s%s%s", stagingPrefix, id, s.fileService.GetFileExtension(file.Filename))
+
+	putURL, err := s.storage.GetPresignedPutURL(objectKey, file.ContentType, file.Size, s.urlExpiry)
+	if err != nil {
+		return nil, nil, appErrors.NewInternalError("Failed to generate upload URL", err)
+	}
+	postURL, postFields, err := s.storage.GetPresignedPostPolicy(objectKey, file.ContentType, file.Size, s.urlExpiry)
+	if err != nil {
+		return nil, nil, appErrors.NewInternalError("Failed to generate upload policy", err)
+	}
+
+	now := time.Now()
+	intent := &models.UploadIntent{
+		BaseModel:    models.BaseModel{ID: id},
+		UploaderID:   metadata.UploaderID,
+		CollectionID: metadata.CollectionID,
+		Title:        metadata.Title,
+		Description:  metadata.Description,
+		Metadata:     uploadDocumentMetadata(metadata),
+		Filename:     file.Filename,
+		ContentType:  file.ContentType,
+		Size:         file.Size,
+		ObjectKey:    objectKey,
+		Status:       models.UploadStatusInProgress,
+		ExpiresAt:    now.Add(s.expiry),
+
+		AvailableFrom:       metadata.Availability.AvailableFrom,
+		AvailableUntil:      metadata.Availability.AvailableUntil,
+		EmbargoShowMetadata: metadata.Availability.EmbargoShowMetadata,
+	}
+
+	if err := s.intentRepo.Create(intent); err != nil {
+		return nil, nil, appErrors.NewInternalError("Failed to create upload intent", err)
+	}
+
+	return intent, &PresignedUpload{
+		PutURL:     putURL,
+		PostURL:    postURL,
+		PostFields: postFields,
+		ExpiresAt:  now.Add(s.urlExpiry),
+	}, nil
+}
+
+// GetIntent retrieves an upload intent owned by the user
+func (s *uploadIntentService) GetIntent(id, userID uuid.UUID) (*models.UploadIntent, error) {
+	intent, err := s.intentRepo.FindByID(id)
+	if err != nil {
+		return nil, appErrors.NewNotFoundError("Upload intent", err)
+	}
+	if intent.UploaderID != userID {
+		return nil, appErrors.NewForbiddenError("Only the uploader can access this upload", nil)
+	}
+	if intent.Status == models.UploadStatusInProgress && time.Now().After(intent.ExpiresAt) {
+		return nil, appErrors.NewNotFoundError("Upload intent", fmt.Errorf("upload intent expired at %s", intent.ExpiresAt))
+	}
+	return intent, nil
+}
+
+// FinalizeIntent verifies the staged object and ingests it as a document. Finalizing an
+// already completed intent returns its document, so clients can safely retry.
+func (s *uploadIntentService) FinalizeIntent(id, userID uuid.UUID) (*models.Document, error) {
+	lock, _ := s.locks.LoadOrStore(id, &sync.Mutex{})
+	lock.(*sync.Mutex).Lock()
+	defer lock.(*sync.Mutex).Unlock()
+
+	intent, err := s.GetIntent(id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	switch intent.Status {
+	case models.UploadStatusCompleted:
+		return s.documentService.GetDocument(*intent.DocumentID, &userID)
+	case models.UploadStatusFailed:
+		return nil, &appErrors.AppError{
+			Code:       appErrors.ErrCodeConflict,
+			Message:    "Upload was rejected: " + intent.Error,
+			HTTPStatus: http.StatusConflict,
+		}
+	}
+
+	if s.storage == nil {
+		return nil, appErrors.NewInternalError("File storage is not available", nil)
+	}
+
+	exists, err := s.storage.FileExists(intent.ObjectKey)
+	if err != nil {
+		return nil, appErrors.NewInternalError("Failed to check uploaded file", err)
+	}
+	if !exists {
+		return nil, appErrors.NewBadRequestError("File has not been uploaded yet", nil)
+	}
+
+	info, err := s.storage.GetFileInfo(intent.ObjectKey)
+	if err != nil {
+		return nil, appErrors.NewInternalError("Failed to check uploaded file", err)
+	}
+
+	// The presigned PUT signs the declared size, but the POST policy only caps it, so a
+	// form upload may be short; discard the object so the client can upload again while
+	// the URL is still valid
+	if info.Size != intent.Size {
+		s.discardObject(intent.ObjectKey)
+		return nil, appErrors.NewValidationError(
+			fmt.Sprintf("Uploaded file is %d bytes but %d were declared", info.Size, intent.Size), nil)
+	}
+
+	// The declared content type is recorded; it was validated when the intent was created
+	document, ingestErr := s.documentService.IngestStagedDocument(intent.ObjectKey, UploadFile{
+		Filename:    intent.Filename,
+		ContentType: intent.ContentType,
+		Size:        info.Size,
+	}, UploadMetadata{
+		CollectionID: intent.CollectionID,
+		UploaderID:   intent.UploaderID,
+		Title:        intent.Title,
+		Description:  intent.Description,
+		Metadata:     &intent.Metadata,
+		Availability: Availability{
+			AvailableFrom:       intent.AvailableFrom,
+			AvailableUntil:      intent.AvailableUntil,
+			EmbargoShowMetadata: intent.EmbargoShowMetadata,
+		},
+	})
+
+	if ingestErr != nil {
+		// Ingestion removes the object once it has read it; make sure it is gone
+		// when it was rejected earlier
+		s.discardObject(intent.ObjectKey)
+		intent.Status = models.UploadStatusFailed
+		intent.Error = ingestErr.Error()
+	} else {
+		intent.Status = models.UploadStatusCompleted
+		intent.DocumentID = &document.ID
+	}
+
+	if err := s.intentRepo.Update(intent); err != nil {
+		fmt.Printf("DEBUG: Failed to update upload intent %s: %v\n", intent.ID, err)
+	}
+
+	return document, ingestErr
+}
+
+// PurgeExpired removes expired upload intents and any objects uploaded but never finalized
+// Returns the number of intents removed
+func (s *uploadIntentService) PurgeExpired() (int, error) {
+	intents, err := s.intentRepo.ListExpired(time.Now())
+	if err != nil {
+		return 0, err
+	}
+
+	for _, intent := range intents {
+		if intent.Status == models.UploadStatusInProgress {
+			s.discardObject(intent.ObjectKey)
+		}
+		if err := s.intentRepo.Delete(intent.ID); err != nil {
+			fmt.Printf("DEBUG: Failed to delete upload intent %s: %v\n", intent.ID, err)
+		}
+		s.locks.Delete(intent.ID)
+	}
+	return len(intents), nil
+}
+
+// discardObject removes a staged object (best effort)
+func (s *uploadIntentService) discardObject(objectKey string) {
+	if s.storage == nil {
+		return
+	}
+	if err := s.storage.DeleteFile(objectKey); err != nil {
+		fmt.Printf("DEBUG: Failed to remove staged object %s: %v\n", objectKey, err)
+	}
+}
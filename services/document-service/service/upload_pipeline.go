@@ -0,0 +1,207 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	appErrors "github.com/Kyei-Ernest/libsystem/shared/errors"
+)
+
+// stagingPrefix is the storage prefix for objects that have been uploaded but not yet
+// accepted (deduplicated and recorded). Objects are promoted out of it on success.
+const stagingPrefix = "staging/"
+
+// streamConsumer reads an upload stream. It must either consume the stream or return an error.
+type streamConsumer func(r io.Reader) error
+
+// teeStream fans src out to every consumer concurrently through io.Pipe, so the upload is
+// read exactly once and memory stays bounded by the copy buffer (plus whatever a consumer
+// buffers itself) regardless of file size.
+// Returns the number of bytes read from src. If any consumer fails, the remaining consumers
+// are aborted and the error that aborted them is returned: src's read error if reading
+// failed, otherwise the error of the consumer that failed first.
+func teeStream(src io.Reader, consumers ...streamConsumer) (int64, error) {
+	pipeWriters := make([]*io.PipeWriter, len(consumers))
+	writers := make([]io.Writer, len(consumers))
+
+	// The other consumers fail in turn once their pipes are closed; keep the cause
+	var mu sync.Mutex
+	var firstErr error
+
+	var wg sync.WaitGroup
+	for i, consume := range consumers {
+		pr, pw := io.Pipe()
+		pipeWriters[i] = pw
+		writers[i] = pw
+
+		wg.Add(1)
+		go func(consume streamConsumer, pr *io.PipeReader) {
+			defer wg.Done()
+			err := consume(pr)
+			if err == nil {
+				// Drain anything left unread so the writer side never blocks
+				_, err = io.Copy(io.Discard, pr)
+			}
+			if err != nil {
+				mu.Lock()
+				if firstErr == nil {
+					firstErr = err
+				}
+				mu.Unlock()
+				pr.CloseWithError(err)
+			} else {
+				pr.Close()
+			}
+		}(consume, pr)
+	}
+
+	source := &readErrReader{r: src}
+	written, copyErr := io.Copy(io.MultiWriter(writers...), source)
+	for _, pw := range pipeWriters {
+		pw.CloseWithError(copyErr) // nil signals EOF to the consumers
+	}
+	wg.Wait()
+
+	switch {
+	case source.err != nil:
+		return written, source.err
+	case firstErr != nil:
+		// Prefer the consumer's own error over the pipe error it caused on the writer side
+		return written, firstErr
+	}
+	return written, copyErr
+}
+
+// readErrReader records the error reading r failed with
+type readErrReader struct {
+	r   io.Reader
+	err error
+}
+
+func (e *readErrReader) Read(p []byte) (int, error) {
+	n, err := e.r.Read(p)
+	if err != nil && err != io.EOF {
+		e.err = err
+	}
+	return n, err
+}
+
+// stagingStore is the storage an upload is staged in
+type stagingStore interface {
+	UploadStream(objectName string, reader io.Reader, contentType string) (int64, error)
+	DeleteFile(objectName string) error
+}
+
+// streamScanner scans an upload for viruses
+type streamScanner interface {
+	ScanFile(reader io.Reader, filename string) error
+}
+
+// uploadPipeline streams an upload once: hashing, virus scanning, staging in storage and
+// the local copy all consume it concurrently. scanner and store are optional.
+type uploadPipeline struct {
+	hash    func(io.Reader) (string, error)
+	scanner streamScanner
+	store   stagingStore
+	limit   int64 // the stream is aborted once it exceeds this many bytes; 0 for no limit
+}
+
+// errUploadTooLarge aborts a stream that exceeds the pipeline's limit
+var errUploadTooLarge = errors.New("upload exceeds the size limit")
+
+// stage streams src through the pipeline. The object is uploaded to stagingPath when
+// upload is set (otherwise it was staged there already), and local, if given, receives a
+// copy. If the stream fails the staged object is removed.
+// Returns the content hash and the number of bytes read.
+func (p *uploadPipeline) stage(src io.Reader, file UploadFile, stagingPath string, upload bool, local io.WriteCloser) (string, int64, error) {
+	var hash string
+	consumers := []streamConsumer{
+		func(r io.Reader) error {
+			h, err := p.hash(r)
+			hash = h
+			return err
+		},
+	}
+
+	// Scan for viruses (CRITICAL SECURITY CHECK)
+	if p.scanner != nil {
+		consumers = append(consumers, func(r io.Reader) error {
+			if err := p.scanner.ScanFile(r, file.Filename); err != nil {
+				return appErrors.NewValidationError("Virus scan failed: "+err.Error(), err)
+			}
+			return nil
+		})
+	}
+
+	// Stage the object under a temporary key until it passes the duplicate check
+	if upload && p.store != nil {
+		consumers = append(consumers, func(r io.Reader) error {
+			if _, err := p.store.UploadStream(stagingPath, r, file.ContentType); err != nil {
+				return appErrors.NewInternalError("Failed to upload file to storage", err)
+			}
+			return nil
+		})
+	}
+
+	if local != nil {
+		consumers = append(consumers, func(r io.Reader) error {
+			defer local.Close()
+			if _, err := io.Copy(local, r); err != nil {
+				return appErrors.NewInternalError("Failed to buffer upload", err)
+			}
+			return nil
+		})
+	}
+
+	// The declared size may be absent or wrong; the cap applies to what is received
+	if p.limit > 0 {
+		src = &limitedReader{r: src, remaining: p.limit}
+	}
+
+	fmt.Printf("DEBUG: Streaming upload to %s (%d bytes)\n", stagingPath, file.Size)
+	written, err := teeStream(src, consumers...)
+	if errors.Is(err, errUploadTooLarge) {
+		err = appErrors.NewValidationError(
+			fmt.Sprintf("File exceeds the upload limit of %s", formatSize(p.limit)), errUploadTooLarge)
+	}
+	if err != nil {
+		p.discard(stagingPath)
+		if appErr, ok := err.(*appErrors.AppError); ok {
+			return "", written, appErr
+		}
+		return "", written, appErrors.NewInternalError("Failed to read file", err)
+	}
+	return hash, written, nil
+}
+
+// discard removes a staged object (best effort)
+func (p *uploadPipeline) discard(stagingPath string) {
+	if p.store == nil || stagingPath == "" {
+		return
+	}
+	if err := p.store.DeleteFile(stagingPath); err != nil {
+		fmt.Printf("DEBUG: Failed to discard staged upload %s: %v\n", stagingPath, err)
+	}
+}
+
+// limitedReader reads up to remaining bytes from r and fails once r holds more
+type limitedReader struct {
+	r         io.Reader
+	remaining int64
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	if int64(len(p)) > l.remaining+1 {
+		p = p[:l.remaining+1]
+	}
+	n, err := l.r.Read(p)
+	if int64(n) > l.remaining {
+		n = int(l.remaining)
+		l.remaining = 0
+		return n, errUploadTooLarge
+	}
+	l.remaining -= int64(n)
+	return n, err
+}
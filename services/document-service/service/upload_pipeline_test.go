@@ -0,0 +1,331 @@
+package service
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+
+	appErrors "github.com/Kyei-Ernest/libsystem/shared/errors"
+)
+
+// eicar marks the test files fakeScanner reports as infected
+const eicar = "EICAR-STANDARD-ANTIVIRUS-TEST-FILE"
+
+// fakeScanner reads the whole stream, like clamd, and reports it infected if it holds eicar
+type fakeScanner struct {
+	err error // returned instead of scanning, as when clamd is unreachable
+}
+
+func (f *fakeScanner) ScanFile(reader io.Reader, filename string) error {
+	if f.err != nil {
+		return f.err
+	}
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return fmt.Errorf("virus scan failed for %s: %w", filename, err)
+	}
+	if bytes.Contains(data, []byte(eicar)) {
+		return fmt.Errorf("file %s is infected with: Eicar-Test-Signature", filename)
+	}
+	return nil
+}
+
+// fakeStore keeps staged objects in memory. failAfter > 0 makes uploads fail once that
+// many bytes have been written, leaving the part written behind.
+type fakeStore struct {
+	mu        sync.Mutex
+	objects   map[string][]byte
+	deleted   []string
+	uploads   int
+	failAfter int
+}
+
+func newFakeStore(staged ...string) *fakeStore {
+	store := &fakeStore{objects: make(map[string][]byte)}
+	for _, name := range staged {
+		store.objects[name] = []byte("staged")
+	}
+	return store
+}
+
+func (f *fakeStore) UploadStream(objectName string, reader io.Reader, contentType string) (int64, error) {
+	f.mu.Lock()
+	f.uploads++
+	f.mu.Unlock()
+
+	var data []byte
+	var err error
+	if f.failAfter > 0 {
+		data, err = io.ReadAll(io.LimitReader(reader, int64(f.failAfter)))
+		if err == nil {
+			err = errors.New("connection reset by peer")
+		}
+	} else {
+		data, err = io.ReadAll(reader)
+	}
+
+	f.mu.Lock()
+	f.objects[objectName] = data
+	f.mu.Unlock()
+	return int64(len(data)), err
+}
+
+func (f *fakeStore) DeleteFile(objectName string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.deleted = append(f.deleted, objectName)
+	delete(f.objects, objectName)
+	return nil
+}
+
+// countingReader counts the bytes read from it
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// failingReader yields data, then fails
+type failingReader struct {
+	data []byte
+	err  error
+}
+
+func (f *failingReader) Read(p []byte) (int, error) {
+	if len(f.data) == 0 {
+		return 0, f.err
+	}
+	n := copy(p, f.data)
+	f.data = f.data[n:]
+	return n, nil
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func TestUploadPipelineStage(t *testing.T) {
+	const stagingPath = "staging/upload.pdf"
+	clean := bytes.Repeat([]byte("%PDF-1.7 clean page "), 20000) // ~400 KB
+	infected := append(bytes.Repeat([]byte("%PDF-1.7 page "), 20000), eicar...)
+	readErr := errors.New("unexpected EOF from client")
+
+	tests := []struct {
+		name          string
+		src           []byte
+		srcErr        error // the source fails after src
+		limit         int64
+		scanner       *fakeScanner
+		failAfter     int
+		alreadyStaged bool
+
+		wantCode    string // AppError code; empty for success
+		wantMessage string
+		wantCause   error
+		readsAll    bool // the source was read to the end
+	}{
+		{
+			name:     "clean",
+			src:      clean,
+			limit:    int64(len(clean)),
+			scanner:  &fakeScanner{},
+			readsAll: true,
+		},
+		{
+			name:        "size limit breached mid-stream",
+			src:         clean,
+			limit:       64 << 10,
+			scanner:     &fakeScanner{},
+			wantCode:    appErrors.ErrCodeValidation,
+			wantMessage: "File exceeds the upload limit of 64.0 KB",
+			wantCause:   errUploadTooLarge,
+		},
+		{
+			name:        "size limit breached by one byte",
+			src:         clean,
+			limit:       int64(len(clean)) - 1,
+			scanner:     &fakeScanner{},
+			wantCode:    appErrors.ErrCodeValidation,
+			wantMessage: "File exceeds the upload limit",
+			wantCause:   errUploadTooLarge,
+			readsAll:    true,
+		},
+		{
+			name:        "infected",
+			src:         infected,
+			scanner:     &fakeScanner{},
+			wantCode:    appErrors.ErrCodeValidation,
+			wantMessage: "Virus scan failed: file report.pdf is infected with: Eicar-Test-Signature",
+			readsAll:    true,
+		},
+		{
+			name:          "infected object staged beforehand",
+			src:           infected,
+			scanner:       &fakeScanner{},
+			alreadyStaged: true,
+			wantCode:      appErrors.ErrCodeValidation,
+			wantMessage:   "Virus scan failed",
+			readsAll:      true,
+		},
+		{
+			name:        "scanner unavailable",
+			src:         clean,
+			scanner:     &fakeScanner{err: errors.New("virus scan failed for report.pdf: dial tcp: connection refused")},
+			wantCode:    appErrors.ErrCodeValidation,
+			wantMessage: "Virus scan failed: virus scan failed for report.pdf: dial tcp: connection refused",
+		},
+		{
+			name:        "storage write failure",
+			src:         clean,
+			scanner:     &fakeScanner{},
+			failAfter:   100 << 10,
+			wantCode:    appErrors.ErrCodeInternal,
+			wantMessage: "Failed to upload file to storage",
+		},
+		{
+			name:        "source read failure",
+			src:         clean[:1000],
+			srcErr:      readErr,
+			scanner:     &fakeScanner{},
+			wantCode:    appErrors.ErrCodeInternal,
+			wantMessage: "Failed to read file",
+			wantCause:   readErr,
+			readsAll:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var staged []string
+			if tt.alreadyStaged {
+				staged = append(staged, stagingPath)
+			}
+			store := newFakeStore(staged...)
+			store.failAfter = tt.failAfter
+			pipeline := &uploadPipeline{
+				hash:    NewFileService(0).GenerateHash,
+				scanner: tt.scanner,
+				store:   store,
+				limit:   tt.limit,
+			}
+
+			var src io.Reader = bytes.NewReader(tt.src)
+			if tt.srcErr != nil {
+				src = &failingReader{data: tt.src, err: tt.srcErr}
+			}
+			counted := &countingReader{r: src}
+			var local bytes.Buffer
+			file := UploadFile{Filename: "report.pdf", ContentType: mimePDF, Size: int64(len(tt.src))}
+
+			hash, written, err := pipeline.stage(counted, file, stagingPath, !tt.alreadyStaged, nopWriteCloser{&local})
+
+			if tt.alreadyStaged && store.uploads != 0 {
+				t.Errorf("uploaded an object that was staged already")
+			}
+			if tt.readsAll != (counted.n == int64(len(tt.src))) {
+				t.Errorf("read %d of %d bytes from the source", counted.n, len(tt.src))
+			}
+
+			if tt.wantCode == "" {
+				if err != nil {
+					t.Fatalf("stage() unexpected error: %v", err)
+				}
+				if hash != sha256Hex(tt.src) || written != int64(len(tt.src)) {
+					t.Errorf("stage() = %s, %d; want %s, %d", hash, written, sha256Hex(tt.src), len(tt.src))
+				}
+				if !bytes.Equal(store.objects[stagingPath], tt.src) {
+					t.Errorf("staged object holds %d bytes, want %d", len(store.objects[stagingPath]), len(tt.src))
+				}
+				if !bytes.Equal(local.Bytes(), tt.src) {
+					t.Errorf("local copy holds %d bytes, want %d", local.Len(), len(tt.src))
+				}
+				if len(store.deleted) != 0 {
+					t.Errorf("deleted %v after a successful upload", store.deleted)
+				}
+				return
+			}
+
+			var appErr *appErrors.AppError
+			if !errors.As(err, &appErr) {
+				t.Fatalf("stage() error = %v (%T), want an AppError", err, err)
+			}
+			if appErr.Code != tt.wantCode || !strings.HasPrefix(appErr.Message, tt.wantMessage) {
+				t.Errorf("stage() error = %s %q, want %s %q", appErr.Code, appErr.Message, tt.wantCode, tt.wantMessage)
+			}
+			if tt.wantCause != nil && !errors.Is(err, tt.wantCause) {
+				t.Errorf("stage() error = %v, want it caused by %v", err, tt.wantCause)
+			}
+			if hash != "" {
+				t.Errorf("stage() returned hash %s for a failed upload", hash)
+			}
+			if _, ok := store.objects[stagingPath]; ok {
+				t.Errorf("staged object %s was left behind", stagingPath)
+			}
+			if len(store.deleted) != 1 || store.deleted[0] != stagingPath {
+				t.Errorf("deleted %v, want [%s]", store.deleted, stagingPath)
+			}
+		})
+	}
+}
+
+func TestUploadPipelineWithoutStorage(t *testing.T) {
+	pipeline := &uploadPipeline{hash: NewFileService(0).GenerateHash, scanner: &fakeScanner{}}
+	data := []byte("%PDF-1.7 " + eicar)
+
+	_, _, err := pipeline.stage(bytes.NewReader(data), UploadFile{Filename: "a.pdf"}, "", true, nil)
+	var appErr *appErrors.AppError
+	if !errors.As(err, &appErr) || appErr.Code != appErrors.ErrCodeValidation {
+		t.Errorf("stage() error = %v, want the virus scan failure", err)
+	}
+}
+
+func TestLimitedReader(t *testing.T) {
+	tests := []struct {
+		name    string
+		size    int
+		limit   int64
+		wantErr bool
+	}{
+		{"under", 10, 11, false},
+		{"exact", 10, 10, false},
+		{"over by one", 11, 10, true},
+		{"far over", 1 << 20, 10, true},
+		{"empty", 0, 1, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := io.ReadAll(&limitedReader{r: bytes.NewReader(make([]byte, tt.size)), remaining: tt.limit})
+			if tt.wantErr {
+				if !errors.Is(err, errUploadTooLarge) {
+					t.Errorf("ReadAll() error = %v, want errUploadTooLarge", err)
+				}
+				if int64(len(data)) != tt.limit {
+					t.Errorf("read %d bytes before failing, want %d", len(data), tt.limit)
+				}
+				return
+			}
+			if err != nil || len(data) != tt.size {
+				t.Errorf("ReadAll() = %d bytes, %v; want %d bytes", len(data), err, tt.size)
+			}
+		})
+	}
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
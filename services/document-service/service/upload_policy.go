@@ -0,0 +1,159 @@
+package service
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	appErrors "github.com/Kyei-Ernest/libsystem/shared/errors"
+	"github.com/Kyei-Ernest/libsystem/shared/metadata"
+	"github.com/Kyei-Ernest/libsystem/shared/models"
+)
+
+// checkFilePolicy validates a file's type and size against a collection's upload policy.
+// AllowedFileTypes entries may name the file type ("pdf"), the extension (".pdf") or the
+// MIME type ("application/pdf").
+func checkFilePolicy(settings models.CollectionSettings, file UploadFile, ext string) error {
+	if len(settings.AllowedFileTypes) > 0 && !acceptsFileType(settings.AllowedFileTypes, file.ContentType, ext) {
+		return appErrors.NewValidationError(
+			fmt.Sprintf("This collection does not accept '%s' files. Accepted types: %s",
+				strings.TrimPrefix(ext, "."), strings.Join(settings.AllowedFileTypes, ", ")),
+			nil,
+		)
+	}
+	return checkFileSizePolicy(settings, file.Size)
+}
+
+// checkFileSizePolicy validates a file size against a collection's size cap
+func checkFileSizePolicy(settings models.CollectionSettings, size int64) error {
+	if settings.MaxFileSize > 0 && size > settings.MaxFileSize {
+		return appErrors.NewValidationError(
+			fmt.Sprintf("File is %s; this collection accepts files up to %s",
+				formatSize(size), formatSize(settings.MaxFileSize)),
+			nil,
+		)
+	}
+	return nil
+}
+
+// checkPagePolicy validates a document's page count against a collection's page limit
+func checkPagePolicy(settings models.CollectionSettings, pages int) error {
+	if settings.MaxPageCount > 0 && pages > settings.MaxPageCount {
+		return appErrors.NewValidationError(
+			fmt.Sprintf("Document has %d pages; this collection accepts documents of up to %d pages",
+				pages, settings.MaxPageCount),
+			nil,
+		)
+	}
+	return nil
+}
+
+// pdfPageCount reads the page count of a local PDF the way the derivative worker does.
+// ok is false when the file can't be parsed; such files are served as uploaded.
+func pdfPageCount(path string) (pages int, ok bool) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, false
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return 0, false
+	}
+	result, err := metadata.DefaultRegistry().Extract("PDF", f, info.Size())
+	if err != nil || result == nil || result.PageCount <= 0 {
+		return 0, false
+	}
+	return result.PageCount, true
+}
+
+// checkMetadataPolicy validates that an upload supplies every metadata field the collection
+// requires. Names that are not standard fields are looked up in the custom fields.
+func checkMetadataPolicy(settings models.CollectionSettings, metadata UploadMetadata) error {
+	var missing []string
+	for _, field := range settings.RequiredMetadata {
+		if !hasMetadataField(metadata, field) {
+			missing = append(missing, field)
+		}
+	}
+	if len(missing) > 0 {
+		return appErrors.NewValidationError(
+			fmt.Sprintf("Missing metadata required by this collection: %s (required: %s)",
+				strings.Join(missing, ", "), strings.Join(settings.RequiredMetadata, ", ")),
+			nil,
+		)
+	}
+	return nil
+}
+
+func acceptsFileType(allowed []string, mimeType, ext string) bool {
+	fileType := getFileType(ext)
+	for _, entry := range allowed {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		switch {
+		case entry == "":
+			continue
+		case strings.Contains(entry, "/"):
+			if entry == baseMediaType(mimeType) {
+				return true
+			}
+		case strings.HasPrefix(entry, "."):
+			if entry == ext {
+				return true
+			}
+		default:
+			if entry == strings.TrimPrefix(ext, ".") || strings.EqualFold(entry, fileType) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func hasMetadataField(metadata UploadMetadata, field string) bool {
+	switch field {
+	case "title":
+		return strings.TrimSpace(metadata.Title) != ""
+	case "description":
+		return strings.TrimSpace(metadata.Description) != ""
+	}
+
+	m := metadata.Metadata
+	if m == nil {
+		return false
+	}
+	switch field {
+	case "author":
+		return strings.TrimSpace(m.Author) != ""
+	case "publisher":
+		return strings.TrimSpace(m.Publisher) != ""
+	case "publish_date":
+		return strings.TrimSpace(m.PublishDate) != ""
+	case "isbn":
+		return strings.TrimSpace(m.ISBN) != ""
+	case "tags":
+		return len(m.Tags) > 0
+	}
+
+	value, ok := m.CustomFields[field]
+	if !ok || value == nil {
+		return false
+	}
+	if s, isString := value.(string); isString {
+		return strings.TrimSpace(s) != ""
+	}
+	return true
+}
+
+// formatSize renders a byte count for error messages
+func formatSize(size int64) string {
+	switch {
+	case size >= 1024*1024:
+		return fmt.Sprintf("%.1f MB", float64(size)/(1024*1024))
+	case size >= 1024:
+		return fmt.Sprintf("%.1f KB", float64(size)/1024)
+	default:
+		return fmt.Sprintf("%d bytes", size)
+	}
+}
@@ -0,0 +1,335 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/Kyei-Ernest/libsystem/services/document-service/repository"
+	appErrors "github.com/Kyei-Ernest/libsystem/shared/errors"
+	"github.com/Kyei-Ernest/libsystem/shared/models"
+	"github.com/Kyei-Ernest/libsystem/shared/validator"
+	"github.com/google/uuid"
+)
+
+// UploadService manages resumable (tus 1.0) uploads. Received bytes are staged on local
+// disk and, once complete, handed off to DocumentService.IngestDocument.
+type UploadService interface {
+	CreateUpload(length int64, file UploadFile, metadata UploadMetadata) (*models.UploadSession, error)
+	GetUpload(id, userID uuid.UUID) (*models.UploadSession, error)
+	WriteChunk(id, userID uuid.UUID, offset int64, chunk io.Reader) (*models.UploadSession, error)
+	TerminateUpload(id, userID uuid.UUID) error
+	PurgeExpired() (int, error)
+	MaxUploadSize() int64
+}
+
+// uploadService implements UploadService
+type uploadService struct {
+	uploadRepo      repository.UploadRepository
+	documentService DocumentService
+	fileService     FileService
+	stagingDir      string
+	expiry          time.Duration
+}
+
+// NewUploadService creates a new resumable upload service
+// stagingDir must be shared between replicas that serve the same uploads; requests for an
+// upload are serialised by its session's row lock
+func NewUploadService(uploadRepo repository.UploadRepository, documentService DocumentService, fileService FileService, stagingDir string, expiry time.Duration) UploadService {
+	return &uploadService{
+		uploadRepo:      uploadRepo,
+		documentService: documentService,
+		fileService:     fileService,
+		stagingDir:      stagingDir,
+		expiry:          expiry,
+	}
+}
+
+// CreateUpload validates the declared file and opens a new upload session
+func (s *uploadService) CreateUpload(length int64, file UploadFile, metadata UploadMetadata) (*models.UploadSession, error) {
+	if err := validator.ValidateRequired(metadata.Title, "title"); err != nil {
+		return nil, appErrors.NewValidationError(err.Error(), err)
+	}
+	if err := validator.ValidateRequired(file.Filename, "filename"); err != nil {
+		return nil, appErrors.NewValidationError(err.Error(), err)
+	}
+
+	// Fail fast on files that ingestion would reject anyway
+	if err := s.fileService.ValidateFileSize(length); err != nil {
+		return nil, err
+	}
+	// Without a declared type (no filetype metadata) the type is detected on ingestion
+	if !undeclaredType(file.ContentType) {
+		if err := s.fileService.ValidateFileType(file.ContentType); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.documentService.CheckUploadPolicy(file, metadata); err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(s.stagingDir, 0o750); err != nil {
+		return nil, appErrors.NewInternalError("Failed to prepare upload staging area", err)
+	}
+
+	id := uuid.New()
+	stagingPath := filepath.Join(s.stagingDir, id.String())
+	staged, err := os.Create(stagingPath)
+	if err != nil {
+		return nil, appErrors.NewInternalError("Failed to create upload staging file", err)
+	}
+	staged.Close()
+
+	session := &models.UploadSession{
+		BaseModel:    models.BaseModel{ID: id},
+		UploaderID:   metadata.UploaderID,
+		CollectionID: metadata.CollectionID,
+		Title:        metadata.Title,
+		Description:  metadata.Description,
+		Metadata:     uploadDocumentMetadata(metadata),
+		Filename:     file.Filename,
+		ContentType:  file.ContentType,
+		Length:       length,
+		StagingPath:  stagingPath,
+		Status:       models.UploadStatusInProgress,
+		ExpiresAt:    time.Now().Add(s.expiry),
+
+		AvailableFrom:       metadata.Availability.AvailableFrom,
+		AvailableUntil:      metadata.Availability.AvailableUntil,
+		EmbargoShowMetadata: metadata.Availability.EmbargoShowMetadata,
+	}
+
+	if err := s.uploadRepo.Create(session); err != nil {
+		os.Remove(stagingPath)
+		return nil, appErrors.NewInternalError("Failed to create upload session", err)
+	}
+
+	return session, nil
+}
+
+// GetUpload retrieves an upload session owned by the user
+func (s *uploadService) GetUpload(id, userID uuid.UUID) (*models.UploadSession, error) {
+	session, err := s.uploadRepo.FindByID(id)
+	if err != nil {
+		return nil, appErrors.NewNotFoundError("Upload", err)
+	}
+	if err := checkUploadAccess(session, userID); err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// checkUploadAccess lets the uploader reach an upload session until it expires
+func checkUploadAccess(session *models.UploadSession, userID uuid.UUID) error {
+	if session.UploaderID != userID {
+		return appErrors.NewForbiddenError("Only the uploader can access this upload", nil)
+	}
+	if session.Status == models.UploadStatusInProgress && time.Now().After(session.ExpiresAt) {
+		return appErrors.NewNotFoundError("Upload", fmt.Errorf("upload expired at %s", session.ExpiresAt))
+	}
+	return nil
+}
+
+// WriteChunk appends a chunk at the given offset. When the last byte has been received the
+// file is ingested as a document and the session is completed (or failed). The session
+// stays locked throughout, so a concurrent PATCH waits and then finds the offset moved on.
+func (s *uploadService) WriteChunk(id, userID uuid.UUID, offset int64, chunk io.Reader) (*models.UploadSession, error) {
+	var result *models.UploadSession
+	var resultErr error
+	err := s.uploadRepo.Lock(id, func(session *models.UploadSession) error {
+		if err := checkUploadAccess(session, userID); err != nil {
+			return err
+		}
+		result = session
+		resultErr = s.writeChunk(session, offset, chunk)
+		return nil
+	})
+	if errors.Is(err, repository.ErrUploadNotFound) {
+		return nil, appErrors.NewNotFoundError("Upload", err)
+	}
+	var appErr *appErrors.AppError
+	if errors.As(err, &appErr) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, appErrors.NewInternalError("Failed to record upload progress", err)
+	}
+	return result, resultErr
+}
+
+// writeChunk writes a chunk into a locked session, which is saved afterwards whatever
+// the outcome
+func (s *uploadService) writeChunk(session *models.UploadSession, offset int64, chunk io.Reader) error {
+	if session.Status != models.UploadStatusInProgress {
+		return &appErrors.AppError{
+			Code:       appErrors.ErrCodeConflict,
+			Message:    fmt.Sprintf("Upload is already %s", session.Status),
+			HTTPStatus: http.StatusConflict,
+		}
+	}
+
+	if offset != session.Offset {
+		return &appErrors.AppError{
+			Code:       appErrors.ErrCodeConflict,
+			Message:    fmt.Sprintf("Upload-Offset %d does not match current offset %d", offset, session.Offset),
+			HTTPStatus: http.StatusConflict,
+		}
+	}
+
+	// The staging file is never recreated: without it the bytes received are lost
+	staged, err := os.OpenFile(session.StagingPath, os.O_WRONLY, 0)
+	if os.IsNotExist(err) {
+		session.Status = models.UploadStatusFailed
+		session.Error = "staged data was lost"
+		return &appErrors.AppError{
+			Code:       appErrors.ErrCodeConflict,
+			Message:    "Upload data was lost, start a new upload",
+			HTTPStatus: http.StatusConflict,
+		}
+	}
+	if err != nil {
+		return appErrors.NewInternalError("Failed to open upload staging file", err)
+	}
+
+	// Discard bytes written after the last recorded offset (e.g. a crash mid-chunk);
+	// the client resends them from the offset we report
+	if err := staged.Truncate(session.Offset); err != nil {
+		staged.Close()
+		return appErrors.NewInternalError("Failed to prepare upload staging file", err)
+	}
+	if _, err := staged.Seek(session.Offset, io.SeekStart); err != nil {
+		staged.Close()
+		return appErrors.NewInternalError("Failed to prepare upload staging file", err)
+	}
+
+	// Keep whatever arrived even if the connection dropped part-way through
+	written, copyErr := io.Copy(staged, io.LimitReader(chunk, session.Length-session.Offset))
+	if err := staged.Close(); err != nil && copyErr == nil {
+		copyErr = err
+	}
+
+	session.Offset += written
+	session.ExpiresAt = time.Now().Add(s.expiry)
+
+	if copyErr != nil {
+		return appErrors.NewBadRequestError("Upload interrupted, resume from Upload-Offset", copyErr)
+	}
+
+	if session.Offset == session.Length {
+		return s.complete(session)
+	}
+
+	return nil
+}
+
+// complete hands a fully received upload off to document ingestion, recording the
+// outcome on the session
+func (s *uploadService) complete(session *models.UploadSession) error {
+	staged, err := os.Open(session.StagingPath)
+	if err != nil {
+		return appErrors.NewInternalError("Failed to open upload staging file", err)
+	}
+
+	document, ingestErr := s.documentService.IngestDocument(staged, UploadFile{
+		Filename:    session.Filename,
+		ContentType: session.ContentType,
+		Size:        session.Length,
+	}, UploadMetadata{
+		CollectionID: session.CollectionID,
+		UploaderID:   session.UploaderID,
+		Title:        session.Title,
+		Description:  session.Description,
+		Metadata:     &session.Metadata,
+		Availability: Availability{
+			AvailableFrom:       session.AvailableFrom,
+			AvailableUntil:      session.AvailableUntil,
+			EmbargoShowMetadata: session.EmbargoShowMetadata,
+		},
+	})
+	staged.Close()
+	os.Remove(session.StagingPath)
+
+	if ingestErr != nil {
+		session.Status = models.UploadStatusFailed
+		session.Error = ingestErr.Error()
+	} else {
+		session.Status = models.UploadStatusCompleted
+		session.DocumentID = &document.ID
+	}
+
+	return ingestErr
+}
+
+// TerminateUpload aborts an upload and discards received data (tus termination extension)
+func (s *uploadService) TerminateUpload(id, userID uuid.UUID) error {
+	_, err := s.uploadRepo.DeleteLocked(id, func(session *models.UploadSession) (bool, error) {
+		if session.UploaderID != userID {
+			return false, appErrors.NewForbiddenError("Only the uploader can terminate this upload", nil)
+		}
+		removeStaged(session)
+		return true, nil
+	})
+	if errors.Is(err, repository.ErrUploadNotFound) {
+		return appErrors.NewNotFoundError("Upload", err)
+	}
+	var appErr *appErrors.AppError
+	if err != nil && !errors.As(err, &appErr) {
+		return appErrors.NewInternalError("Failed to terminate upload", err)
+	}
+	return err
+}
+
+// PurgeExpired removes expired upload sessions and their staged data
+// Returns the number of sessions removed. Each session is checked again under its lock:
+// a PATCH in flight when it was listed has since extended its expiry.
+func (s *uploadService) PurgeExpired() (int, error) {
+	now := time.Now()
+	sessions, err := s.uploadRepo.ListExpired(now)
+	if err != nil {
+		return 0, err
+	}
+
+	purged := 0
+	for i := range sessions {
+		deleted, err := s.uploadRepo.DeleteLocked(sessions[i].ID, func(session *models.UploadSession) (bool, error) {
+			if !session.ExpiresAt.Before(now) {
+				return false, nil
+			}
+			removeStaged(session)
+			return true, nil
+		})
+		if err != nil && !errors.Is(err, repository.ErrUploadNotFound) {
+			fmt.Printf("DEBUG: Failed to delete upload session %s: %v\n", sessions[i].ID, err)
+			continue
+		}
+		if deleted {
+			purged++
+		}
+	}
+	return purged, nil
+}
+
+// MaxUploadSize returns the largest upload accepted (Tus-Max-Size)
+func (s *uploadService) MaxUploadSize() int64 {
+	return s.fileService.MaxFileSize()
+}
+
+// removeStaged removes an upload's staged data (best effort)
+func removeStaged(session *models.UploadSession) {
+	if err := os.Remove(session.StagingPath); err != nil && !os.IsNotExist(err) {
+		fmt.Printf("DEBUG: Failed to remove staged upload %s: %v\n", session.StagingPath, err)
+	}
+}
+
+// uploadDocumentMetadata returns the document metadata to record on an upload session or
+// intent until the file is ingested
+func uploadDocumentMetadata(metadata UploadMetadata) models.DocumentMetadata {
+	if metadata.Metadata == nil {
+		return models.DocumentMetadata{}
+	}
+	return *metadata.Metadata
+}
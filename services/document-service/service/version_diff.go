@@ -0,0 +1,348 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/Kyei-Ernest/libsystem/shared/models"
+	"github.com/google/uuid"
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+// maxWordDiffWords bounds the size of a changed block that is refined into a word-level
+// diff; larger blocks are reported at line level only
+const maxWordDiffWords = 5000
+
+// maxLineDiffLines bounds the combined line count of two texts that are diffed line by
+// line; larger texts only get summary counts of the lines outside their common prefix
+// and suffix
+const maxLineDiffLines = 20000
+
+// VersionComparison is the result of comparing two versions of a document
+type VersionComparison struct {
+	DocumentID uuid.UUID        `json:"document_id"`
+	From       VersionSummary   `json:"from"`
+	To         VersionSummary   `json:"to"`
+	Text       TextDiff         `json:"text"`
+	Metadata   []MetadataChange `json:"metadata"`
+}
+
+// VersionSummary identifies a compared version
+type VersionSummary struct {
+	ID            uuid.UUID `json:"id"`
+	VersionNumber int       `json:"version_number"`
+	Hash          string    `json:"hash"`
+	TextAvailable bool      `json:"text_available"` // false until the indexer has extracted it
+}
+
+// TextDiff is a diff of the extracted text of two versions
+type TextDiff struct {
+	Unified      string       `json:"unified"`
+	Changes      []TextChange `json:"changes"`
+	LinesAdded   int          `json:"lines_added"`
+	LinesRemoved int          `json:"lines_removed"`
+	WordsAdded   int          `json:"words_added"`
+	WordsRemoved int          `json:"words_removed"`
+	Truncated    bool         `json:"truncated"` // too large to diff; only the counts are set
+}
+
+// TextChange is a block of changed lines. Line numbers are 1-based; a pure insertion has
+// no old lines and a pure deletion no new lines.
+type TextChange struct {
+	Op       string       `json:"op"` // insert, delete or replace
+	OldStart int          `json:"old_start"`
+	OldLines []string     `json:"old_lines,omitempty"`
+	NewStart int          `json:"new_start"`
+	NewLines []string     `json:"new_lines,omitempty"`
+	Words    []WordChange `json:"words,omitempty"` // word-level refinement of a replace
+}
+
+// WordChange is a run of words within a replaced block
+type WordChange struct {
+	Op   string `json:"op"` // equal, insert or delete
+	Text string `json:"text"`
+}
+
+// MetadataChange is a field whose value differs between two versions
+type MetadataChange struct {
+	Field string      `json:"field"`
+	From  interface{} `json:"from"`
+	To    interface{} `json:"to"`
+}
+
+// diffOps maps difflib opcode tags to change operations
+var diffOps = map[byte]string{'r': "replace", 'd': "delete", 'i': "insert", 'e': "equal"}
+
+// compareVersions builds the text and metadata diff between two versions
+// Text is only diffed once it has been extracted for both versions.
+func compareVersions(from, to *models.DocumentVersion, context int) (*VersionComparison, error) {
+	text := &TextDiff{Changes: []TextChange{}}
+	if from.ExtractedText != "" && to.ExtractedText != "" {
+		var err error
+		if text, err = diffText(from, to, context); err != nil {
+			return nil, err
+		}
+	}
+
+	return &VersionComparison{
+		DocumentID: from.DocumentID,
+		From:       summarizeVersion(from),
+		To:         summarizeVersion(to),
+		Text:       *text,
+		Metadata:   diffMetadata(from, to),
+	}, nil
+}
+
+func summarizeVersion(v *models.DocumentVersion) VersionSummary {
+	return VersionSummary{
+		ID:            v.ID,
+		VersionNumber: v.VersionNumber,
+		Hash:          v.Hash,
+		TextAvailable: v.ExtractedText != "",
+	}
+}
+
+// diffText diffs the extracted text line by line and refines replaced lines word by word
+func diffText(from, to *models.DocumentVersion, context int) (*TextDiff, error) {
+	oldLines := difflib.SplitLines(from.ExtractedText)
+	newLines := difflib.SplitLines(to.ExtractedText)
+
+	if len(oldLines)+len(newLines) > maxLineDiffLines {
+		return summarizeText(oldLines, newLines), nil
+	}
+
+	// Autojunk would treat frequent lines (blank lines, headers) as noise and skew the diff
+	matcher := difflib.NewMatcherWithJunk(oldLines, newLines, false, nil)
+
+	var unified strings.Builder
+	if err := writeUnified(&unified, matcher, oldLines, newLines,
+		fmt.Sprintf("version %d", from.VersionNumber), fmt.Sprintf("version %d", to.VersionNumber), context); err != nil {
+		return nil, fmt.Errorf("failed to build unified diff: %w", err)
+	}
+
+	result := &TextDiff{Unified: unified.String(), Changes: []TextChange{}}
+
+	for _, op := range matcher.GetOpCodes() {
+		if op.Tag == 'e' {
+			continue
+		}
+
+		change := TextChange{
+			Op:       diffOps[op.Tag],
+			OldStart: op.I1 + 1,
+			OldLines: trimLines(oldLines[op.I1:op.I2]),
+			NewStart: op.J1 + 1,
+			NewLines: trimLines(newLines[op.J1:op.J2]),
+		}
+		result.LinesRemoved += op.I2 - op.I1
+		result.LinesAdded += op.J2 - op.J1
+
+		oldWords := strings.Fields(strings.Join(change.OldLines, "\n"))
+		newWords := strings.Fields(strings.Join(change.NewLines, "\n"))
+		switch {
+		case op.Tag != 'r':
+			result.WordsRemoved += len(oldWords)
+			result.WordsAdded += len(newWords)
+		case len(oldWords)+len(newWords) <= maxWordDiffWords:
+			change.Words = diffWords(oldWords, newWords, result)
+		default:
+			result.WordsRemoved += len(oldWords)
+			result.WordsAdded += len(newWords)
+		}
+
+		result.Changes = append(result.Changes, change)
+	}
+
+	return result, nil
+}
+
+// writeUnified writes the matcher's opcodes as a unified diff with context lines around
+// each hunk (the format of difflib.WriteUnifiedDiff, without running a second matcher)
+func writeUnified(w io.Writer, matcher *difflib.SequenceMatcher, oldLines, newLines []string, fromFile, toFile string, context int) error {
+	started := false
+	for _, group := range matcher.GetGroupedOpCodes(context) {
+		if !started {
+			started = true
+			if _, err := fmt.Fprintf(w, "--- %s\n+++ %s\n", fromFile, toFile); err != nil {
+				return err
+			}
+		}
+		first, last := group[0], group[len(group)-1]
+		if _, err := fmt.Fprintf(w, "@@ -%s +%s @@\n", unifiedRange(first.I1, last.I2), unifiedRange(first.J1, last.J2)); err != nil {
+			return err
+		}
+		for _, op := range group {
+			if op.Tag == 'e' {
+				if err := writePrefixed(w, " ", oldLines[op.I1:op.I2]); err != nil {
+					return err
+				}
+				continue
+			}
+			if err := writePrefixed(w, "-", oldLines[op.I1:op.I2]); err != nil {
+				return err
+			}
+			if err := writePrefixed(w, "+", newLines[op.J1:op.J2]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func writePrefixed(w io.Writer, prefix string, lines []string) error {
+	for _, line := range lines {
+		if _, err := io.WriteString(w, prefix+line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// unifiedRange formats a hunk range; an empty range starts at the line before it
+func unifiedRange(start, stop int) string {
+	length := stop - start
+	switch length {
+	case 0:
+		return fmt.Sprintf("%d,0", start)
+	case 1:
+		return fmt.Sprintf("%d", start+1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, length)
+}
+
+// summarizeText counts the lines and words outside the common prefix and suffix of two
+// texts too large to diff. The counts are an upper bound of what a full diff would report.
+func summarizeText(oldLines, newLines []string) *TextDiff {
+	prefix := 0
+	for prefix < len(oldLines) && prefix < len(newLines) && oldLines[prefix] == newLines[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(oldLines)-prefix && suffix < len(newLines)-prefix &&
+		oldLines[len(oldLines)-1-suffix] == newLines[len(newLines)-1-suffix] {
+		suffix++
+	}
+
+	removed := oldLines[prefix : len(oldLines)-suffix]
+	added := newLines[prefix : len(newLines)-suffix]
+	return &TextDiff{
+		Changes:      []TextChange{},
+		LinesRemoved: len(removed),
+		LinesAdded:   len(added),
+		WordsRemoved: len(strings.Fields(strings.Join(removed, ""))),
+		WordsAdded:   len(strings.Fields(strings.Join(added, ""))),
+		Truncated:    true,
+	}
+}
+
+// diffWords diffs two word sequences, merging consecutive words with the same operation
+// and counting added/removed words into stats
+func diffWords(oldWords, newWords []string, stats *TextDiff) []WordChange {
+	var changes []WordChange
+	add := func(op string, words []string) {
+		if len(words) == 0 {
+			return
+		}
+		text := strings.Join(words, " ")
+		if n := len(changes); n > 0 && changes[n-1].Op == op {
+			changes[n-1].Text += " " + text
+			return
+		}
+		changes = append(changes, WordChange{Op: op, Text: text})
+	}
+
+	matcher := difflib.NewMatcherWithJunk(oldWords, newWords, false, nil)
+	for _, op := range matcher.GetOpCodes() {
+		switch op.Tag {
+		case 'e':
+			add("equal", oldWords[op.I1:op.I2])
+		default:
+			add("delete", oldWords[op.I1:op.I2])
+			add("insert", newWords[op.J1:op.J2])
+			stats.WordsRemoved += op.I2 - op.I1
+			stats.WordsAdded += op.J2 - op.J1
+		}
+	}
+	return changes
+}
+
+// trimLines strips the line terminators SplitLines keeps
+func trimLines(lines []string) []string {
+	if len(lines) == 0 {
+		return nil
+	}
+	trimmed := make([]string, len(lines))
+	for i, line := range lines {
+		trimmed[i] = strings.TrimRight(line, "\r\n")
+	}
+	return trimmed
+}
+
+// diffMetadata lists the version and document fields that differ. Nested document
+// metadata is compared per field, custom fields per key.
+func diffMetadata(from, to *models.DocumentVersion) []MetadataChange {
+	changes := []MetadataChange{}
+	compare := func(field string, a, b interface{}) {
+		if !reflect.DeepEqual(a, b) {
+			changes = append(changes, MetadataChange{Field: field, From: a, To: b})
+		}
+	}
+
+	compare("title", from.Title, to.Title)
+	compare("description", from.Description, to.Description)
+	compare("original_filename", from.OriginalFilename, to.OriginalFilename)
+	compare("file_type", from.FileType, to.FileType)
+	compare("mime_type", from.MimeType, to.MimeType)
+	compare("file_size", from.FileSize, to.FileSize)
+	compare("hash", from.Hash, to.Hash)
+
+	compare("metadata.author", from.Metadata.Author, to.Metadata.Author)
+	compare("metadata.publisher", from.Metadata.Publisher, to.Metadata.Publisher)
+	compare("metadata.publish_date", from.Metadata.PublishDate, to.Metadata.PublishDate)
+	compare("metadata.isbn", from.Metadata.ISBN, to.Metadata.ISBN)
+	compare("metadata.tags", normalizeJSON(from.Metadata.Tags), normalizeJSON(to.Metadata.Tags))
+
+	keys := make(map[string]bool)
+	for k := range from.Metadata.CustomFields {
+		keys[k] = true
+	}
+	for k := range to.Metadata.CustomFields {
+		keys[k] = true
+	}
+	for _, k := range sortedKeys(keys) {
+		compare("metadata.custom_fields."+k,
+			normalizeJSON(from.Metadata.CustomFields[k]), normalizeJSON(to.Metadata.CustomFields[k]))
+	}
+
+	return changes
+}
+
+// normalizeJSON round-trips a value through JSON so that values read from the database
+// and values built in memory compare equal (e.g. nil vs empty slices, int vs float64)
+func normalizeJSON(v interface{}) interface{} {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return v
+	}
+	var out interface{}
+	if err := json.Unmarshal(data, &out); err != nil {
+		return v
+	}
+	if arr, ok := out.([]interface{}); ok && len(arr) == 0 {
+		return nil
+	}
+	return out
+}
+
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for k := range set {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
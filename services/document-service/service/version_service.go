@@ -2,120 +2,422 @@ package service
 
 import (
 	"fmt"
-	"time"
+	"io"
 
 	"github.com/Kyei-Ernest/libsystem/services/document-service/repository"
+	appErrors "github.com/Kyei-Ernest/libsystem/shared/errors"
 	"github.com/Kyei-Ernest/libsystem/shared/models"
 	"github.com/Kyei-Ernest/libsystem/shared/storage"
 	"github.com/google/uuid"
 )
 
 // VersionService handles document version operations
+//
+// Every version owns an immutable storage object; the document's file fields point at the
+// current version. Uploading a new file or restoring an old version adds a version and
+// moves the pointer, so history is never rewritten.
 type VersionService interface {
 	CreateVersion(documentID, createdBy uuid.UUID, changeSummary string) (*models.DocumentVersion, error)
-	GetVersions(documentID uuid.UUID) ([]models.DocumentVersion, error)
-	GetVersion(versionID uuid.UUID) (*models.DocumentVersion, error)
-	RestoreVersion(versionID, restoredBy uuid.UUID) error
-	DeleteVersion(versionID uuid.UUID) error
+	UploadVersion(documentID, uploadedBy uuid.UUID, src io.Reader, file UploadFile, changeSummary string) (*models.DocumentVersion, error)
+	GetVersions(documentID uuid.UUID, userID *uuid.UUID) ([]models.DocumentVersion, error)
+	GetVersion(documentID, versionID uuid.UUID, userID *uuid.UUID) (*models.DocumentVersion, error)
+	GetVersionStream(documentID, versionID uuid.UUID, userID *uuid.UUID) (io.ReadSeekCloser, *models.Document, error)
+	RestoreVersion(documentID, versionID, restoredBy uuid.UUID) (*models.DocumentVersion, error)
+	DeleteVersion(documentID, versionID uuid.UUID) error
+	CompareVersions(documentID, fromID, toID uuid.UUID, context int, userID *uuid.UUID) (*VersionComparison, error)
 }
 
 type versionService struct {
-	versionRepo  repository.VersionRepository
-	documentRepo repository.DocumentRepository
-	storage      *storage.MinIOClient
+	versionRepo     repository.VersionRepository
+	documentRepo    repository.DocumentRepository
+	documentService DocumentService
+	previews        PreviewService
+	storage         *storage.MinIOClient
 }
 
 // NewVersionService creates a new version service
-func NewVersionService(versionRepo repository.VersionRepository, documentRepo repository.DocumentRepository, storage *storage.MinIOClient) VersionService {
+func NewVersionService(versionRepo repository.VersionRepository, documentRepo repository.DocumentRepository, documentService DocumentService, previews PreviewService, storage *storage.MinIOClient) VersionService {
 	return &versionService{
-		versionRepo:  versionRepo,
-		documentRepo: documentRepo,
-		storage:      storage,
+		versionRepo:     versionRepo,
+		documentRepo:    documentRepo,
+		documentService: documentService,
+		previews:        previews,
+		storage:         storage,
 	}
 }
 
-// CreateVersion creates a new version of a document
+// CreateVersion records the document's current file as a version
 func (s *versionService) CreateVersion(documentID, createdBy uuid.UUID, changeSummary string) (*models.DocumentVersion, error) {
-	// Get the current document
 	doc, err := s.documentRepo.FindByID(documentID)
 	if err != nil {
-		return nil, fmt.Errorf("document not found: %w", err)
+		return nil, appErrors.NewNotFoundError("Document", err)
 	}
 
-	// Get latest version number
-	versions, err := s.versionRepo.GetByDocumentID(documentID)
+	current, err := s.currentVersion(doc)
 	if err != nil {
 		return nil, err
 	}
+	if current != nil {
+		return nil, appErrors.NewConflictError("Version",
+			fmt.Errorf("the current file is already version %d", current.VersionNumber))
+	}
 
-	versionNumber := 1
-	if len(versions) > 0 {
-		versionNumber = versions[0].VersionNumber + 1
+	return s.snapshot(doc, createdBy, changeSummary)
+}
+
+// UploadVersion stores a revised file as a new version and makes it current
+func (s *versionService) UploadVersion(documentID, uploadedBy uuid.UUID, src io.Reader, file UploadFile, changeSummary string) (*models.DocumentVersion, error) {
+	doc, err := s.documentRepo.FindByID(documentID)
+	if err != nil {
+		return nil, appErrors.NewNotFoundError("Document", err)
 	}
 
-	// Create new version
-	version := &models.DocumentVersion{
-		BaseModel:     models.BaseModel{ID: uuid.New(), CreatedAt: time.Now(), UpdatedAt: time.Now()},
-		DocumentID:    documentID,
-		VersionNumber: versionNumber,
-		StoragePath:   doc.StoragePath, // Store current file path
-		FileSize:      doc.FileSize,
-		Hash:          doc.Hash,
-		CreatedBy:     createdBy,
-		ChangeLog:     changeSummary,
+	// Keep the file being replaced in the history
+	if err := s.ensureBaseline(doc, uploadedBy); err != nil {
+		return nil, err
 	}
 
-	if err := s.versionRepo.Create(version); err != nil {
+	stored, err := s.documentService.StoreFile(src, file, doc.CollectionID)
+	if err != nil {
 		return nil, err
 	}
 
+	version, err := s.addVersion(doc, stored, uploadedBy, changeSummary, "")
+	if err != nil {
+		s.documentService.DiscardStoredFile(stored)
+		return nil, err
+	}
 	return version, nil
 }
 
-// GetVersions retrieves all versions of a document
-func (s *versionService) GetVersions(documentID uuid.UUID) ([]models.DocumentVersion, error) {
-	return s.versionRepo.GetByDocumentID(documentID)
+// GetVersions retrieves all versions of a document visible to the user
+func (s *versionService) GetVersions(documentID uuid.UUID, userID *uuid.UUID) ([]models.DocumentVersion, error) {
+	// reuse GetDocument for permission checks
+	if _, err := s.documentService.GetDocument(documentID, userID); err != nil {
+		return nil, err
+	}
+
+	versions, err := s.versionRepo.GetByDocumentID(documentID)
+	if err != nil {
+		return nil, appErrors.NewInternalError("Failed to list versions", err)
+	}
+	return versions, nil
+}
+
+// GetVersion retrieves a specific version of a document visible to the user
+func (s *versionService) GetVersion(documentID, versionID uuid.UUID, userID *uuid.UUID) (*models.DocumentVersion, error) {
+	if _, err := s.documentService.GetDocument(documentID, userID); err != nil {
+		return nil, err
+	}
+	return s.findVersion(documentID, versionID)
+}
+
+// GetVersionStream opens the file of a specific version, described as the document it was
+// (the document with the version's file). Office versions in a collection that watermarks
+// deliveries are opened as their PDF rendition, which can be stamped, as the current file
+// is.
+func (s *versionService) GetVersionStream(documentID, versionID uuid.UUID, userID *uuid.UUID) (io.ReadSeekCloser, *models.Document, error) {
+	document, err := s.documentService.GetContentDocument(documentID, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := s.documentService.CheckLoan(document, userID); err != nil {
+		return nil, nil, err
+	}
+	version, err := s.findVersion(documentID, versionID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if s.storage == nil {
+		return nil, nil, appErrors.NewInternalError("Storage service not available", nil)
+	}
+
+	versionDoc := *document
+	versionDoc.OriginalFilename = version.OriginalFilename
+	versionDoc.FileType = version.FileType
+	versionDoc.MimeType = version.MimeType
+	versionDoc.FileSize = version.FileSize
+	versionDoc.Hash = version.Hash
+	versionDoc.StoragePath = version.StoragePath
+	versionDoc.UpdatedAt = version.CreatedAt
+
+	exists, err := s.storage.FileExists(version.StoragePath)
+	if err != nil {
+		return nil, nil, appErrors.NewInternalError("Failed to check file existence", err)
+	}
+	if !exists {
+		return nil, nil, appErrors.NewNotFoundError("Version file in storage", fmt.Errorf("path: %s", version.StoragePath))
+	}
+
+	key := version.StoragePath
+	if document.Collection.Settings.WatermarkDownloads && s.previews != nil && s.previews.NeedsPreview(version.MimeType) {
+		if key, err = s.previews.Preview(version.Hash, version.StoragePath); err != nil {
+			return nil, nil, err
+		}
+		info, err := s.storage.GetFileInfo(key)
+		if err != nil {
+			return nil, nil, appErrors.NewInternalError("Failed to get preview", err)
+		}
+		versionDoc.MimeType = mimePDF
+		versionDoc.FileSize = info.Size
+		// Cache validators (ETag) must distinguish the rendition from the original file
+		versionDoc.Hash = version.Hash + "-pdf"
+	}
+
+	stream, err := s.storage.OpenFile(key)
+	if err != nil {
+		return nil, nil, appErrors.NewInternalError("Failed to get version stream", err)
+	}
+	return stream, &versionDoc, nil
+}
+
+// RestoreVersion makes an earlier version current again by adding a copy of it as the
+// newest version
+func (s *versionService) RestoreVersion(documentID, versionID, restoredBy uuid.UUID) (*models.DocumentVersion, error) {
+	version, err := s.findVersion(documentID, versionID)
+	if err != nil {
+		return nil, err
+	}
+
+	doc, err := s.documentRepo.FindByID(documentID)
+	if err != nil {
+		return nil, appErrors.NewNotFoundError("Document", err)
+	}
+
+	if doc.Hash == version.Hash {
+		return nil, appErrors.NewConflictError("Version",
+			fmt.Errorf("version %d is already the current content", version.VersionNumber))
+	}
+
+	// Content is unique per collection; another document may have taken it since
+	if existing, err := s.documentRepo.FindByHashInCollection(version.Hash, doc.CollectionID); err != nil {
+		return nil, appErrors.NewInternalError("Failed to check for duplicates", err)
+	} else if existing != nil {
+		return nil, duplicateError(existing)
+	}
+
+	if err := s.ensureBaseline(doc, restoredBy); err != nil {
+		return nil, err
+	}
+
+	// The restored version shares the old version's content
+	if err := s.documentService.RetainContent(version.Hash); err != nil {
+		return nil, err
+	}
+	stored := &StoredFile{
+		StoragePath:   version.StoragePath,
+		ThumbnailPath: version.ThumbnailPath,
+		Hash:          version.Hash,
+		Size:          version.FileSize,
+		Filename:      version.OriginalFilename,
+		FileType:      version.FileType,
+		MimeType:      version.MimeType,
+	}
+
+	restored, err := s.addVersion(doc, stored, restoredBy, fmt.Sprintf("Restored from version %d", version.VersionNumber), version.ExtractedText)
+	if err != nil {
+		s.documentService.DiscardStoredFile(stored)
+		return nil, err
+	}
+	return restored, nil
+}
+
+// DeleteVersion permanently deletes a version that is not current, along with its file
+func (s *versionService) DeleteVersion(documentID, versionID uuid.UUID) error {
+	version, err := s.findVersion(documentID, versionID)
+	if err != nil {
+		return err
+	}
+
+	doc, err := s.documentRepo.FindByID(documentID)
+	if err != nil {
+		return appErrors.NewNotFoundError("Document", err)
+	}
+
+	current, err := s.currentVersion(doc)
+	if err != nil {
+		return err
+	}
+	if current != nil && current.ID == version.ID {
+		return appErrors.NewConflictError("Version",
+			fmt.Errorf("version %d is current; restore another version first", version.VersionNumber))
+	}
+
+	if err := s.versionRepo.Delete(version.ID); err != nil {
+		return appErrors.NewInternalError("Failed to delete version", err)
+	}
+
+	s.documentService.ReleaseContent(version.Hash)
+	return nil
 }
 
-// GetVersion retrieves a specific version
-func (s *versionService) GetVersion(versionID uuid.UUID) (*models.DocumentVersion, error) {
-	return s.versionRepo.GetByID(versionID)
+// CompareVersions diffs the extracted text and metadata of two versions of a document
+// context is the number of unchanged lines around each hunk of the unified diff
+func (s *versionService) CompareVersions(documentID, fromID, toID uuid.UUID, context int, userID *uuid.UUID) (*VersionComparison, error) {
+	// Comparisons show content, which is withheld during an embargo and from readers
+	// without a loan of a lent document
+	document, err := s.documentService.GetContentDocument(documentID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.documentService.CheckLoan(document, userID); err != nil {
+		return nil, err
+	}
+	from, err := s.findVersion(documentID, fromID)
+	if err != nil {
+		return nil, err
+	}
+	to, err := s.findVersion(documentID, toID)
+	if err != nil {
+		return nil, err
+	}
+
+	comparison, err := compareVersions(from, to, context)
+	if err != nil {
+		return nil, appErrors.NewInternalError("Failed to compare versions", err)
+	}
+	return comparison, nil
 }
 
-// RestoreVersion restores a document to a previous version
-func (s *versionService) RestoreVersion(versionID, restoredBy uuid.UUID) error {
-	// Get the version to restore
+// findVersion retrieves a version and checks it belongs to the document
+func (s *versionService) findVersion(documentID, versionID uuid.UUID) (*models.DocumentVersion, error) {
 	version, err := s.versionRepo.GetByID(versionID)
 	if err != nil {
-		return fmt.Errorf("version not found: %w", err)
+		return nil, appErrors.NewNotFoundError("Version", err)
+	}
+	if version.DocumentID != documentID {
+		return nil, appErrors.NewNotFoundError("Version", fmt.Errorf("version %s does not belong to document %s", versionID, documentID))
 	}
+	return version, nil
+}
 
-	// Get the document
-	doc, err := s.documentRepo.FindByID(version.DocumentID)
+// currentVersion returns the version holding the document's current file, if recorded
+func (s *versionService) currentVersion(doc *models.Document) (*models.DocumentVersion, error) {
+	versions, err := s.versionRepo.GetByDocumentID(doc.ID)
 	if err != nil {
-		return fmt.Errorf("document not found: %w", err)
+		return nil, appErrors.NewInternalError("Failed to list versions", err)
 	}
+	for i := range versions {
+		if versions[i].StoragePath == doc.StoragePath {
+			return &versions[i], nil
+		}
+	}
+	return nil, nil
+}
 
-	// Create a new version with current state before restoring
-	if _, err := s.CreateVersion(doc.ID, restoredBy, "Auto-save before restore"); err != nil {
-		return fmt.Errorf("failed to create backup version: %w", err)
+// ensureBaseline records the document's current file as a version before it is replaced
+func (s *versionService) ensureBaseline(doc *models.Document, createdBy uuid.UUID) error {
+	current, err := s.currentVersion(doc)
+	if err != nil {
+		return err
+	}
+	if current != nil {
+		return nil
+	}
+	_, err = s.snapshot(doc, createdBy, "Original file")
+	return err
+}
+
+// snapshot records the document's current file as a new version
+func (s *versionService) snapshot(doc *models.Document, createdBy uuid.UUID, changeSummary string) (*models.DocumentVersion, error) {
+	versionNumber, err := s.versionRepo.NextVersionNumber(doc.ID)
+	if err != nil {
+		return nil, appErrors.NewInternalError("Failed to number version", err)
+	}
+
+	// The version shares the document's content
+	if err := s.documentService.RetainContent(doc.Hash); err != nil {
+		return nil, err
 	}
 
-	// Update document to point to the version's file
-	// In a real implementation, you might copy the file to a new location
+	version := &models.DocumentVersion{
+		DocumentID:       doc.ID,
+		VersionNumber:    versionNumber,
+		StoragePath:      doc.StoragePath,
+		FileSize:         doc.FileSize,
+		Hash:             doc.Hash,
+		CreatedBy:        createdBy,
+		ChangeLog:        changeSummary,
+		OriginalFilename: doc.OriginalFilename,
+		FileType:         doc.FileType,
+		MimeType:         doc.MimeType,
+		ThumbnailPath:    doc.ThumbnailPath,
+		Title:            doc.Title,
+		Description:      doc.Description,
+		Metadata:         doc.Metadata,
+		ExtractedText:    doc.ExtractedText,
+	}
+
+	if err := s.versionRepo.Create(version); err != nil {
+		s.documentService.ReleaseContent(doc.Hash)
+		return nil, appErrors.NewInternalError("Failed to create version", err)
+	}
+	return version, nil
+}
+
+// addVersion records a stored file as the newest version, points the document at it and
+// requests reindexing. The version takes over the stored file's reference to its content.
+// extractedText is the file's text when already known (restores); otherwise the indexer
+// reports it. The caller discards the stored file on error.
+func (s *versionService) addVersion(doc *models.Document, stored *StoredFile, createdBy uuid.UUID, changeSummary, extractedText string) (*models.DocumentVersion, error) {
+	versionNumber, err := s.versionRepo.NextVersionNumber(doc.ID)
+	if err != nil {
+		return nil, appErrors.NewInternalError("Failed to number version", err)
+	}
+
+	version := &models.DocumentVersion{
+		DocumentID:       doc.ID,
+		VersionNumber:    versionNumber,
+		StoragePath:      stored.StoragePath,
+		FileSize:         stored.Size,
+		Hash:             stored.Hash,
+		CreatedBy:        createdBy,
+		ChangeLog:        changeSummary,
+		OriginalFilename: stored.Filename,
+		FileType:         stored.FileType,
+		MimeType:         stored.MimeType,
+		ThumbnailPath:    stored.ThumbnailPath,
+		Title:            doc.Title,
+		Description:      doc.Description,
+		Metadata:         doc.Metadata,
+		ExtractedText:    extractedText,
+	}
+
+	if err := s.versionRepo.Create(version); err != nil {
+		return nil, appErrors.NewInternalError("Failed to create version", err)
+	}
+
+	// The document references the new content too, and stops referencing the old
+	if err := s.documentService.RetainContent(version.Hash); err != nil {
+		s.versionRepo.Delete(version.ID)
+		return nil, err
+	}
+	previousHash := doc.Hash
+
+	// Point the document at the new version. Extracted content is refreshed by the indexer.
 	doc.StoragePath = version.StoragePath
 	doc.FileSize = version.FileSize
 	doc.Hash = version.Hash
-	doc.UpdatedAt = time.Now()
+	doc.OriginalFilename = version.OriginalFilename
+	doc.FileType = version.FileType
+	doc.MimeType = version.MimeType
+	doc.ThumbnailPath = version.ThumbnailPath
+	doc.ExtractedText = extractedText
+	doc.PageCount = 0
+	doc.IsIndexed = false
+	doc.IndexedAt = nil
 
 	if err := s.documentRepo.Update(doc); err != nil {
-		return fmt.Errorf("failed to restore document: %w", err)
+		s.versionRepo.Delete(version.ID)
+		s.documentService.ReleaseContent(version.Hash)
+		return nil, appErrors.NewInternalError("Failed to update document", err)
 	}
+	s.documentService.ReleaseContent(previousHash)
 
-	return nil
-}
+	if err := s.documentService.RequestReindex(doc.ID); err != nil {
+		fmt.Printf("DEBUG: Failed to request reindex of document %s: %v\n", doc.ID, err)
+	}
 
-// DeleteVersion deletes a version (soft delete by marking it)
-func (s *versionService) DeleteVersion(versionID uuid.UUID) error {
-	return s.versionRepo.Delete(versionID)
+	return version, nil
 }
@@ -0,0 +1,186 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	appErrors "github.com/Kyei-Ernest/libsystem/shared/errors"
+	"github.com/Kyei-Ernest/libsystem/shared/models"
+	"github.com/google/uuid"
+)
+
+// Watermark identifies whom a delivered copy was made for
+type Watermark struct {
+	UserID    uuid.UUID
+	UserName  string // Username or email, printed next to the ID
+	RequestID string
+	IssuedAt  time.Time
+}
+
+// WatermarkService stamps PDFs delivered from collections whose policy asks for it with a
+// visible footer naming the reader, and the same details as document metadata, so that a
+// leaked copy can be traced. Every delivery is stamped afresh, so the footer names the
+// request that got it; stamped copies are never stored.
+type WatermarkService interface {
+	Applies(document *models.Document, userID *uuid.UUID) bool
+	Stamp(content io.ReadSeekCloser, document *models.Document, mark Watermark) (io.ReadSeekCloser, *models.Document, error)
+}
+
+type watermarkService struct {
+	slots   chan struct{} // Free Ghostscript slots
+	timeout time.Duration
+}
+
+// NewWatermarkService creates a watermark service running up to workers Ghostscript
+// processes at once, each killed after timeout
+func NewWatermarkService(workers int, timeout time.Duration) WatermarkService {
+	slots := make(chan struct{}, workers)
+	for i := 0; i < workers; i++ {
+		slots <- struct{}{}
+	}
+	return &watermarkService{
+		slots:   slots,
+		timeout: timeout,
+	}
+}
+
+// Applies reports whether a document is delivered watermarked: a PDF (or a PDF rendition)
+// in a collection with the watermark policy. The uploader and the collection owner get
+// the original.
+func (s *watermarkService) Applies(document *models.Document, userID *uuid.UUID) bool {
+	if !document.Collection.Settings.WatermarkDownloads || document.MimeType != mimePDF {
+		return false
+	}
+	if userID != nil && (*userID == document.UploaderID || *userID == document.Collection.OwnerID) {
+		return false
+	}
+	return true
+}
+
+// Stamp returns a copy of a PDF stamped for the reader and request of mark. content is
+// consumed and closed either way; the copy is a temp file removed when it is closed.
+func (s *watermarkService) Stamp(content io.ReadSeekCloser, document *models.Document, mark Watermark) (io.ReadSeekCloser, *models.Document, error) {
+	defer content.Close()
+
+	if mark.UserID == uuid.Nil {
+		return nil, nil, appErrors.NewUnauthorizedError("Sign in to read documents from this collection", nil)
+	}
+
+	source, err := os.CreateTemp("", "watermark_src_*.pdf")
+	if err != nil {
+		return nil, nil, appErrors.NewInternalError("Failed to create temp file", err)
+	}
+	defer os.Remove(source.Name())
+	defer source.Close()
+	if _, err := io.Copy(source, content); err != nil {
+		return nil, nil, appErrors.NewInternalError("Failed to read document for watermarking", err)
+	}
+
+	outPath := source.Name() + ".stamped.pdf"
+	if err := s.stamp(source.Name(), outPath, document, mark); err != nil {
+		os.Remove(outPath)
+		return nil, nil, err
+	}
+
+	f, err := os.Open(outPath)
+	if err != nil {
+		os.Remove(outPath)
+		return nil, nil, appErrors.NewInternalError("Failed to read watermarked PDF", err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		os.Remove(outPath)
+		return nil, nil, appErrors.NewInternalError("Failed to read watermarked PDF", err)
+	}
+
+	stamped := *document
+	stamped.FileSize = info.Size()
+	// Each delivery is distinct content
+	stamped.Hash = ""
+	stamped.UpdatedAt = mark.IssuedAt
+	return &tempFile{File: f}, &stamped, nil
+}
+
+// tempFile is a temp file removed when it is closed
+type tempFile struct {
+	*os.File
+}
+
+func (f *tempFile) Close() error {
+	err := f.File.Close()
+	os.Remove(f.Name())
+	return err
+}
+
+// stamp runs Ghostscript in a free slot, waiting for one for at most the timeout. Every
+// page gets the footer when it is emitted, and the details go into the document info.
+// The text reaches PostScript as -s string definitions, never as code.
+func (s *watermarkService) stamp(sourcePath, outPath string, document *models.Document, mark Watermark) error {
+	wait := time.NewTimer(s.timeout)
+	defer wait.Stop()
+
+	select {
+	case <-s.slots:
+	case <-wait.C:
+		return appErrors.NewInternalError("Watermarking is busy; try again later", nil)
+	}
+	defer func() { s.slots <- struct{}{} }()
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+
+	issued := mark.IssuedAt.UTC().Format(time.RFC3339)
+	reader := mark.UserID.String()
+	if mark.UserName != "" {
+		reader = fmt.Sprintf("%s (%s)", mark.UserName, mark.UserID)
+	}
+	footer := fmt.Sprintf("Delivered to %s on %s - request %s", reader, issued, mark.RequestID)
+
+	cmd := exec.CommandContext(ctx, "gs",
+		"-dSAFER", "-dBATCH", "-dNOPAUSE", "-dQUIET",
+		"-sDEVICE=pdfwrite",
+		"-sOutputFile="+outPath,
+		"-sWatermarkFooter="+postScriptText(footer),
+		"-sWatermarkUser="+mark.UserID.String(),
+		"-sWatermarkRequest="+postScriptText(mark.RequestID),
+		"-sWatermarkIssued="+issued,
+		"-sWatermarkDocument="+document.ID.String(),
+		"-c", "<< /EndPage { exch pop 2 eq { false } { gsave "+
+			"/Helvetica findfont 7 scalefont setfont 0.45 setgray "+
+			"18 10 moveto WatermarkFooter show grestore true } ifelse } bind >> setpagedevice",
+		"-f", sourcePath,
+		"-c", "[ /LibsystemUser WatermarkUser /LibsystemRequest WatermarkRequest "+
+			"/LibsystemIssued WatermarkIssued /LibsystemDocument WatermarkDocument /DOCINFO pdfmark",
+	)
+	killProcessGroupOnCancel(cmd)
+
+	output, err := cmd.CombinedOutput()
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return appErrors.NewInternalError("Watermarking timed out", ctx.Err())
+	}
+	if err != nil {
+		fmt.Printf("DEBUG: Watermarking failed: %s\n", string(output))
+		return appErrors.NewInternalError("Watermarking failed", err)
+	}
+	if _, err := os.Stat(outPath); err != nil {
+		return appErrors.NewInternalError("Failed to read watermarked PDF", err)
+	}
+	return nil
+}
+
+// postScriptText keeps the printable ASCII of s, which the standard fonts can show
+func postScriptText(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r < 0x20 || r > 0x7e {
+			return '?'
+		}
+		return r
+	}, s)
+}
@@ -154,8 +154,10 @@ func (p *Processor) indexAndUpdateStatus(ctx context.Context, event map[string]i
 		return err // Retryable
 	}
 
-	// Update document status in database
-	if err := p.updateDocumentStatus(ctx, docID, true); err != nil {
+	// Update document status in database, reporting the extracted text for the content
+	// identified by the event's hash
+	hash, _ := event["hash"].(string)
+	if err := p.updateDocumentStatus(ctx, docID, true, hash, content); err != nil {
 		log.Printf("Warning: Failed to update document status: %v", err)
 		// Don't fail the entire process if status update fails
 	}
@@ -215,12 +217,17 @@ func (p *Processor) Delete(ctx context.Context, msg []byte) error {
 }
 
 // updateDocumentStatus calls the document service API to update is_indexed flag
-func (p *Processor) updateDocumentStatus(ctx context.Context, docID string, indexed bool) error {
+// Extracted text is only reported when the hash of the processed content is known
+func (p *Processor) updateDocumentStatus(ctx context.Context, docID string, indexed bool, hash, text string) error {
 	url := fmt.Sprintf("%s/api/v1/documents/%s/status", p.documentAPIURL, docID)
 
 	payload := map[string]interface{}{
 		"is_indexed": indexed,
 	}
+	if hash != "" {
+		payload["hash"] = hash
+		payload["extracted_text"] = text
+	}
 
 	body, err := json.Marshal(payload)
 	if err != nil {
@@ -0,0 +1,28 @@
+-- Resumable (tus) upload sessions
+CREATE TABLE IF NOT EXISTS upload_sessions (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    uploader_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    collection_id UUID,
+    title VARCHAR(500) NOT NULL,
+    description TEXT,
+    filename VARCHAR(500) NOT NULL,
+    content_type VARCHAR(100) NOT NULL,
+    length BIGINT NOT NULL,
+    "offset" BIGINT NOT NULL DEFAULT 0,
+    staging_path VARCHAR(500) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'in_progress' CHECK (status IN ('in_progress', 'completed', 'failed')),
+    document_id UUID REFERENCES documents(id) ON DELETE SET NULL,
+    error TEXT,
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    deleted_at TIMESTAMP
+);
+
+CREATE INDEX idx_upload_sessions_uploader_id ON upload_sessions(uploader_id) WHERE deleted_at IS NULL;
+CREATE INDEX idx_upload_sessions_expires_at ON upload_sessions(expires_at) WHERE status = 'in_progress';
+
+CREATE TRIGGER update_upload_sessions_updated_at BEFORE UPDATE ON upload_sessions
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+COMMENT ON TABLE upload_sessions IS 'Resumable upload sessions (tus 1.0 protocol)';
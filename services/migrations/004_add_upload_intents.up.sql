@@ -0,0 +1,27 @@
+-- Direct-to-storage (presigned) upload intents
+CREATE TABLE IF NOT EXISTS upload_intents (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    uploader_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    collection_id UUID,
+    title VARCHAR(500) NOT NULL,
+    description TEXT,
+    filename VARCHAR(500) NOT NULL,
+    content_type VARCHAR(100) NOT NULL,
+    size BIGINT NOT NULL,
+    object_key VARCHAR(500) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'in_progress' CHECK (status IN ('in_progress', 'completed', 'failed')),
+    document_id UUID REFERENCES documents(id) ON DELETE SET NULL,
+    error TEXT,
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    deleted_at TIMESTAMP
+);
+
+CREATE INDEX idx_upload_intents_uploader_id ON upload_intents(uploader_id) WHERE deleted_at IS NULL;
+CREATE INDEX idx_upload_intents_expires_at ON upload_intents(expires_at);
+
+CREATE TRIGGER update_upload_intents_updated_at BEFORE UPDATE ON upload_intents
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+COMMENT ON TABLE upload_intents IS 'Presigned direct-to-storage uploads awaiting finalization';
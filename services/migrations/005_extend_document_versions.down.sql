@@ -0,0 +1,5 @@
+ALTER TABLE document_versions
+    DROP COLUMN IF EXISTS original_filename,
+    DROP COLUMN IF EXISTS file_type,
+    DROP COLUMN IF EXISTS mime_type,
+    DROP COLUMN IF EXISTS thumbnail_path;
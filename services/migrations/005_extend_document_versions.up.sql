@@ -0,0 +1,14 @@
+-- File information per version so new uploads and restores carry their own file details
+ALTER TABLE document_versions
+    ADD COLUMN IF NOT EXISTS original_filename VARCHAR(500),
+    ADD COLUMN IF NOT EXISTS file_type VARCHAR(50),
+    ADD COLUMN IF NOT EXISTS mime_type VARCHAR(100),
+    ADD COLUMN IF NOT EXISTS thumbnail_path VARCHAR(500);
+
+-- Backfill from the documents the versions belong to
+UPDATE document_versions v
+SET original_filename = d.original_filename,
+    file_type = d.file_type,
+    mime_type = d.mime_type
+FROM documents d
+WHERE v.document_id = d.id AND v.original_filename IS NULL;
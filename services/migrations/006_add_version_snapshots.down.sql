@@ -0,0 +1,5 @@
+ALTER TABLE document_versions
+    DROP COLUMN IF EXISTS title,
+    DROP COLUMN IF EXISTS description,
+    DROP COLUMN IF EXISTS metadata,
+    DROP COLUMN IF EXISTS extracted_text;
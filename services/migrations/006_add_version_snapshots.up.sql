@@ -0,0 +1,16 @@
+-- Per-version snapshot of document details and extracted text, so historical versions
+-- can be compared after the document has been edited or reindexed
+ALTER TABLE document_versions
+    ADD COLUMN IF NOT EXISTS title VARCHAR(500),
+    ADD COLUMN IF NOT EXISTS description TEXT,
+    ADD COLUMN IF NOT EXISTS metadata JSONB DEFAULT '{}',
+    ADD COLUMN IF NOT EXISTS extracted_text TEXT;
+
+-- Backfill from the documents; text only where the version is the current content
+UPDATE document_versions v
+SET title = d.title,
+    description = d.description,
+    metadata = d.metadata,
+    extracted_text = CASE WHEN v.hash = d.hash THEN d.extracted_text END
+FROM documents d
+WHERE v.document_id = d.id AND v.title IS NULL;
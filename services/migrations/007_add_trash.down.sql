@@ -0,0 +1,9 @@
+DROP INDEX IF EXISTS idx_collections_trash;
+DROP INDEX IF EXISTS idx_documents_trash;
+
+ALTER TABLE collections
+    DROP COLUMN IF EXISTS deleted_by;
+
+ALTER TABLE documents
+    DROP COLUMN IF EXISTS deleted_by,
+    DROP COLUMN IF EXISTS trashed_with_collection;
@@ -0,0 +1,11 @@
+-- Deleted documents and collections stay in the trash (soft-deleted) until purged
+ALTER TABLE documents
+    ADD COLUMN IF NOT EXISTS deleted_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    ADD COLUMN IF NOT EXISTS trashed_with_collection BOOLEAN NOT NULL DEFAULT FALSE;
+
+ALTER TABLE collections
+    ADD COLUMN IF NOT EXISTS deleted_by UUID REFERENCES users(id) ON DELETE SET NULL;
+
+-- Trash listings and the purge job only look at deleted rows
+CREATE INDEX IF NOT EXISTS idx_documents_trash ON documents(deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_collections_trash ON collections(deleted_at) WHERE deleted_at IS NOT NULL;
@@ -0,0 +1,12 @@
+DROP INDEX IF EXISTS idx_documents_review_queue;
+
+ALTER TABLE documents
+    DROP COLUMN IF EXISTS reviewed_by,
+    DROP COLUMN IF EXISTS reviewed_at,
+    DROP COLUMN IF EXISTS review_note;
+
+-- Pending submissions go back to the upload state
+UPDATE documents SET status = 'pending' WHERE status = 'submitted';
+ALTER TABLE documents DROP CONSTRAINT IF EXISTS documents_status_check;
+ALTER TABLE documents ADD CONSTRAINT documents_status_check
+    CHECK (status IN ('pending', 'processing', 'active', 'rejected', 'archived'));
@@ -0,0 +1,12 @@
+-- Submissions to collections that require approval wait in the review queue
+ALTER TABLE documents DROP CONSTRAINT IF EXISTS documents_status_check;
+ALTER TABLE documents ADD CONSTRAINT documents_status_check
+    CHECK (status IN ('pending', 'processing', 'active', 'rejected', 'archived', 'submitted'));
+
+ALTER TABLE documents
+    ADD COLUMN IF NOT EXISTS reviewed_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    ADD COLUMN IF NOT EXISTS reviewed_at TIMESTAMP,
+    ADD COLUMN IF NOT EXISTS review_note TEXT;
+
+CREATE INDEX IF NOT EXISTS idx_documents_review_queue ON documents(collection_id, created_at)
+    WHERE status = 'submitted' AND deleted_at IS NULL;
@@ -0,0 +1,2 @@
+ALTER TABLE upload_intents DROP COLUMN IF EXISTS metadata;
+ALTER TABLE upload_sessions DROP COLUMN IF EXISTS metadata;
@@ -0,0 +1,4 @@
+-- Document metadata supplied when a resumable or direct upload is created, so collection
+-- upload policies can require it before the file arrives
+ALTER TABLE upload_sessions ADD COLUMN IF NOT EXISTS metadata JSONB NOT NULL DEFAULT '{}';
+ALTER TABLE upload_intents ADD COLUMN IF NOT EXISTS metadata JSONB NOT NULL DEFAULT '{}';
@@ -0,0 +1,6 @@
+-- Fails if the same content was uploaded to more than one collection
+DROP INDEX IF EXISTS idx_documents_collection_hash;
+ALTER TABLE documents ADD CONSTRAINT documents_hash_key UNIQUE (hash);
+
+DROP TRIGGER IF EXISTS update_blobs_updated_at ON blobs;
+DROP TABLE IF EXISTS blobs;
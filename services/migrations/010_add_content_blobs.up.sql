@@ -0,0 +1,51 @@
+-- Content-addressed storage: one blob per distinct file content, shared by every document
+-- and version row with that hash and deleted with its objects when the last of them goes
+CREATE TABLE IF NOT EXISTS blobs (
+    hash VARCHAR(64) PRIMARY KEY,
+    storage_path VARCHAR(500) NOT NULL,
+    thumbnail_path VARCHAR(500),
+    size BIGINT NOT NULL,
+    mime_type VARCHAR(100) NOT NULL,
+    ref_count INTEGER NOT NULL DEFAULT 0 CHECK (ref_count >= 0),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE TRIGGER update_blobs_updated_at BEFORE UPDATE ON blobs
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+COMMENT ON TABLE blobs IS 'Stored file content shared by documents and versions with the same hash';
+
+-- The same content may appear in several collections, but only once in each
+ALTER TABLE documents DROP CONSTRAINT IF EXISTS documents_hash_key;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_documents_collection_hash ON documents(collection_id, hash);
+
+-- Adopt existing files: each hash keeps the object its document (else its earliest
+-- version) uses. Copies made by earlier version restores are left in storage unreferenced.
+INSERT INTO blobs (hash, storage_path, thumbnail_path, size, mime_type)
+SELECT DISTINCT ON (hash) hash, storage_path, NULLIF(thumbnail_path, ''), file_size, mime_type
+FROM (
+    SELECT hash, storage_path, thumbnail_path, file_size, mime_type, 0 AS source, created_at
+    FROM documents
+    UNION ALL
+    SELECT hash, storage_path, thumbnail_path, file_size, COALESCE(mime_type, 'application/octet-stream'), 1, created_at
+    FROM document_versions
+) files
+ORDER BY hash, source, created_at
+ON CONFLICT (hash) DO NOTHING;
+
+UPDATE documents d
+SET storage_path = b.storage_path,
+    thumbnail_path = COALESCE(b.thumbnail_path, d.thumbnail_path)
+FROM blobs b
+WHERE d.hash = b.hash AND d.storage_path <> b.storage_path;
+
+UPDATE document_versions v
+SET storage_path = b.storage_path,
+    thumbnail_path = COALESCE(b.thumbnail_path, v.thumbnail_path)
+FROM blobs b
+WHERE v.hash = b.hash AND v.storage_path <> b.storage_path;
+
+UPDATE blobs b
+SET ref_count = (SELECT COUNT(*) FROM documents d WHERE d.hash = b.hash)
+              + (SELECT COUNT(*) FROM document_versions v WHERE v.hash = b.hash);
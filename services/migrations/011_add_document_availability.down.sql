@@ -0,0 +1,14 @@
+DROP INDEX IF EXISTS idx_documents_available_until;
+DROP INDEX IF EXISTS idx_documents_available_from;
+
+ALTER TABLE documents DROP CONSTRAINT IF EXISTS documents_availability_check;
+ALTER TABLE documents
+    DROP COLUMN IF EXISTS available_from,
+    DROP COLUMN IF EXISTS available_until,
+    DROP COLUMN IF EXISTS embargo_show_metadata;
+
+-- Documents outside their window go back to being visible
+UPDATE documents SET status = 'active' WHERE status IN ('embargoed', 'expired');
+ALTER TABLE documents DROP CONSTRAINT IF EXISTS documents_status_check;
+ALTER TABLE documents ADD CONSTRAINT documents_status_check
+    CHECK (status IN ('pending', 'processing', 'active', 'rejected', 'archived', 'submitted'));
@@ -0,0 +1,18 @@
+-- Documents may be embargoed until a date and expire at another
+ALTER TABLE documents DROP CONSTRAINT IF EXISTS documents_status_check;
+ALTER TABLE documents ADD CONSTRAINT documents_status_check
+    CHECK (status IN ('pending', 'processing', 'active', 'rejected', 'archived', 'submitted', 'embargoed', 'expired'));
+
+ALTER TABLE documents
+    ADD COLUMN IF NOT EXISTS available_from TIMESTAMP,
+    ADD COLUMN IF NOT EXISTS available_until TIMESTAMP,
+    ADD COLUMN IF NOT EXISTS embargo_show_metadata BOOLEAN NOT NULL DEFAULT FALSE;
+
+ALTER TABLE documents ADD CONSTRAINT documents_availability_check
+    CHECK (available_from IS NULL OR available_until IS NULL OR available_from < available_until);
+
+-- The availability scheduler looks up documents reaching either boundary
+CREATE INDEX IF NOT EXISTS idx_documents_available_from ON documents(available_from)
+    WHERE status = 'embargoed' AND deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_documents_available_until ON documents(available_until)
+    WHERE status = 'active' AND deleted_at IS NULL;
@@ -0,0 +1,2 @@
+DROP TRIGGER IF EXISTS update_document_relations_updated_at ON document_relations;
+DROP TABLE IF EXISTS document_relations;
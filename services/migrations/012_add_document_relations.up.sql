@@ -0,0 +1,27 @@
+-- Typed links between documents: parts of multi-volume works and journal issues, series
+-- membership, editions, translations and related works. Inverse types (has_part, ...) are
+-- stored as their forward type with subject and object swapped.
+CREATE TABLE IF NOT EXISTS document_relations (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    subject_id UUID NOT NULL REFERENCES documents(id) ON DELETE CASCADE,
+    object_id UUID NOT NULL REFERENCES documents(id) ON DELETE CASCADE,
+    type VARCHAR(20) NOT NULL CHECK (type IN ('part_of', 'series_member', 'supersedes', 'translation_of', 'related')),
+    position INTEGER CHECK (position IS NULL OR type IN ('part_of', 'series_member')),
+    created_by UUID NOT NULL REFERENCES users(id),
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    CHECK (subject_id <> object_id),
+    UNIQUE (subject_id, object_id, type)
+);
+
+CREATE INDEX IF NOT EXISTS idx_document_relations_subject ON document_relations(subject_id, type);
+CREATE INDEX IF NOT EXISTS idx_document_relations_object ON document_relations(object_id, type, position);
+
+-- A document is part of at most one work
+CREATE UNIQUE INDEX IF NOT EXISTS idx_document_relations_single_parent ON document_relations(subject_id)
+    WHERE type = 'part_of';
+
+CREATE TRIGGER update_document_relations_updated_at BEFORE UPDATE ON document_relations
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+COMMENT ON TABLE document_relations IS 'Typed relationships between documents (parts, series, editions, translations)';
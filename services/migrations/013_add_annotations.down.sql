@@ -0,0 +1,2 @@
+DROP TRIGGER IF EXISTS update_annotations_updated_at ON annotations;
+DROP TABLE IF EXISTS annotations;
@@ -0,0 +1,21 @@
+-- Highlights and notes anchored to passages of a document's content (W3C Web Annotation
+-- selectors in target). Annotations belong to the content hash they were made on.
+CREATE TABLE IF NOT EXISTS annotations (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    document_id UUID NOT NULL REFERENCES documents(id) ON DELETE CASCADE,
+    hash VARCHAR(64) NOT NULL,
+    creator_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    visibility VARCHAR(20) NOT NULL DEFAULT 'private' CHECK (visibility IN ('private', 'collection', 'public')),
+    target JSONB NOT NULL,
+    body TEXT,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_annotations_document ON annotations(document_id, hash);
+CREATE INDEX IF NOT EXISTS idx_annotations_creator ON annotations(creator_id, created_at DESC);
+
+CREATE TRIGGER update_annotations_updated_at BEFORE UPDATE ON annotations
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+COMMENT ON TABLE annotations IS 'User highlights and notes on document passages';
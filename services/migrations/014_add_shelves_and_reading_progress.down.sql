@@ -0,0 +1,4 @@
+DROP TRIGGER IF EXISTS update_shelves_updated_at ON shelves;
+DROP TABLE IF EXISTS reading_progress;
+DROP TABLE IF EXISTS shelf_items;
+DROP TABLE IF EXISTS shelves;
@@ -0,0 +1,46 @@
+-- Personal shelves: each user's favorites plus named, ordered reading lists
+CREATE TABLE IF NOT EXISTS shelves (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    owner_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    kind VARCHAR(20) NOT NULL DEFAULT 'list' CHECK (kind IN ('favorites', 'list')),
+    name VARCHAR(255) NOT NULL,
+    description TEXT,
+    is_public BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- One favorites shelf per user, and shelf names are unique per user
+CREATE UNIQUE INDEX IF NOT EXISTS idx_shelves_favorites ON shelves(owner_id) WHERE kind = 'favorites';
+CREATE UNIQUE INDEX IF NOT EXISTS idx_shelves_owner_name ON shelves(owner_id, LOWER(name));
+
+CREATE TABLE IF NOT EXISTS shelf_items (
+    shelf_id UUID NOT NULL REFERENCES shelves(id) ON DELETE CASCADE,
+    document_id UUID NOT NULL REFERENCES documents(id) ON DELETE CASCADE,
+    position INTEGER NOT NULL CHECK (position >= 1),
+    note TEXT,
+    added_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (shelf_id, document_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_shelf_items_position ON shelf_items(shelf_id, position);
+CREATE INDEX IF NOT EXISTS idx_shelf_items_document ON shelf_items(document_id);
+
+-- Where each user is in each document they have opened
+CREATE TABLE IF NOT EXISTS reading_progress (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    document_id UUID NOT NULL REFERENCES documents(id) ON DELETE CASCADE,
+    page INTEGER CHECK (page >= 1),
+    percentage NUMERIC(5,2) CHECK (percentage >= 0 AND percentage <= 100),
+    position_updated_at TIMESTAMP,
+    last_read_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, document_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_reading_progress_recent ON reading_progress(user_id, last_read_at DESC);
+
+CREATE TRIGGER update_shelves_updated_at BEFORE UPDATE ON shelves
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+COMMENT ON TABLE shelves IS 'User favorites and reading lists';
+COMMENT ON TABLE reading_progress IS 'Reading positions and recently read documents per user';
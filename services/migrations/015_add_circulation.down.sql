@@ -0,0 +1,6 @@
+DROP TRIGGER IF EXISTS update_loan_policies_updated_at ON loan_policies;
+DROP TRIGGER IF EXISTS update_items_updated_at ON items;
+DROP TABLE IF EXISTS holds;
+DROP TABLE IF EXISTS loans;
+DROP TABLE IF EXISTS loan_policies;
+DROP TABLE IF EXISTS items;
@@ -0,0 +1,78 @@
+-- Physical copies of catalogue records and their circulation: loans, holds and the
+-- lending policy of each role
+CREATE TABLE IF NOT EXISTS items (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    document_id UUID REFERENCES documents(id) ON DELETE SET NULL,
+    barcode VARCHAR(64) NOT NULL UNIQUE,
+    call_number VARCHAR(100),
+    location VARCHAR(255),
+    condition VARCHAR(20) NOT NULL DEFAULT 'good' CHECK (condition IN ('new', 'good', 'fair', 'poor', 'damaged')),
+    status VARCHAR(20) NOT NULL DEFAULT 'available' CHECK (status IN ('available', 'on_loan', 'on_hold_shelf', 'in_repair', 'lost', 'withdrawn')),
+    notes TEXT,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_items_document ON items(document_id, status);
+
+CREATE TABLE IF NOT EXISTS loan_policies (
+    role VARCHAR(20) PRIMARY KEY CHECK (role IN ('admin', 'librarian', 'patron')),
+    loan_period_days INTEGER NOT NULL CHECK (loan_period_days > 0),
+    max_loans INTEGER NOT NULL CHECK (max_loans >= 0),
+    max_renewals INTEGER NOT NULL CHECK (max_renewals >= 0),
+    max_holds INTEGER NOT NULL CHECK (max_holds >= 0),
+    hold_pickup_days INTEGER NOT NULL CHECK (hold_pickup_days > 0),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+INSERT INTO loan_policies (role, loan_period_days, max_loans, max_renewals, max_holds, hold_pickup_days) VALUES
+    ('patron', 21, 10, 2, 5, 7),
+    ('librarian', 42, 30, 5, 15, 7),
+    ('admin', 42, 30, 5, 15, 7)
+ON CONFLICT (role) DO NOTHING;
+
+CREATE TABLE IF NOT EXISTS loans (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    item_id UUID NOT NULL REFERENCES items(id),
+    user_id UUID NOT NULL REFERENCES users(id),
+    checked_out_by UUID NOT NULL REFERENCES users(id),
+    checked_out_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    due_at TIMESTAMP NOT NULL,
+    renewals INTEGER NOT NULL DEFAULT 0,
+    returned_at TIMESTAMP,
+    checked_in_by UUID REFERENCES users(id),
+    overdue_notified_at TIMESTAMP
+);
+
+-- An item is on at most one open loan
+CREATE UNIQUE INDEX IF NOT EXISTS idx_loans_open_item ON loans(item_id) WHERE returned_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_loans_user ON loans(user_id, checked_out_at DESC);
+CREATE INDEX IF NOT EXISTS idx_loans_due ON loans(due_at) WHERE returned_at IS NULL;
+
+CREATE TABLE IF NOT EXISTS holds (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    document_id UUID NOT NULL REFERENCES documents(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    status VARCHAR(20) NOT NULL DEFAULT 'waiting' CHECK (status IN ('waiting', 'ready', 'fulfilled', 'cancelled', 'expired')),
+    item_id UUID REFERENCES items(id),
+    placed_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    ready_at TIMESTAMP,
+    expires_at TIMESTAMP,
+    closed_at TIMESTAMP
+);
+
+-- A user holds a record at most once at a time, and a copy waits for one hold
+CREATE UNIQUE INDEX IF NOT EXISTS idx_holds_active_user ON holds(document_id, user_id) WHERE status IN ('waiting', 'ready');
+CREATE UNIQUE INDEX IF NOT EXISTS idx_holds_ready_item ON holds(item_id) WHERE status = 'ready';
+CREATE INDEX IF NOT EXISTS idx_holds_queue ON holds(document_id, placed_at) WHERE status = 'waiting';
+CREATE INDEX IF NOT EXISTS idx_holds_user ON holds(user_id, placed_at DESC);
+
+CREATE TRIGGER update_items_updated_at BEFORE UPDATE ON items
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+CREATE TRIGGER update_loan_policies_updated_at BEFORE UPDATE ON loan_policies
+    FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
+COMMENT ON TABLE items IS 'Physical copies of catalogue records';
+COMMENT ON TABLE loans IS 'Checkouts of items, open until checked in';
+COMMENT ON TABLE holds IS 'Queue of users waiting for a copy of a record';
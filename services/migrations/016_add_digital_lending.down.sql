@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS digital_loans;
+
+ALTER TABLE documents DROP CONSTRAINT IF EXISTS documents_lending_check;
+ALTER TABLE documents
+    DROP COLUMN IF EXISTS lending_licenses,
+    DROP COLUMN IF EXISTS lending_period_hours;
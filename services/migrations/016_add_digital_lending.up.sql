@@ -0,0 +1,35 @@
+-- Controlled digital lending: a lent document may be read by at most lending_licenses
+-- patrons at once, each under a loan of lending_period_hours
+ALTER TABLE documents
+    ADD COLUMN IF NOT EXISTS lending_licenses INTEGER CHECK (lending_licenses >= 1),
+    ADD COLUMN IF NOT EXISTS lending_period_hours INTEGER NOT NULL DEFAULT 0 CHECK (lending_period_hours >= 0);
+
+ALTER TABLE documents ADD CONSTRAINT documents_lending_check
+    CHECK (lending_licenses IS NULL OR lending_period_hours >= 1);
+
+-- Loans of lent documents, and the waitlist for them
+CREATE TABLE IF NOT EXISTS digital_loans (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    document_id UUID NOT NULL REFERENCES documents(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    status VARCHAR(20) NOT NULL DEFAULT 'waiting'
+        CHECK (status IN ('waiting', 'active', 'returned', 'expired', 'cancelled')),
+    requested_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    started_at TIMESTAMP,
+    expires_at TIMESTAMP,
+    closed_at TIMESTAMP,
+    CHECK (status = 'waiting' OR started_at IS NOT NULL OR status = 'cancelled'),
+    CHECK (status <> 'active' OR expires_at IS NOT NULL)
+);
+
+-- A patron has at most one open loan or place in the waitlist per document
+CREATE UNIQUE INDEX IF NOT EXISTS idx_digital_loans_open ON digital_loans(document_id, user_id)
+    WHERE status IN ('waiting', 'active');
+CREATE INDEX IF NOT EXISTS idx_digital_loans_waitlist ON digital_loans(document_id, requested_at)
+    WHERE status = 'waiting';
+-- The lending sweeper looks up loans past their end
+CREATE INDEX IF NOT EXISTS idx_digital_loans_expires ON digital_loans(expires_at)
+    WHERE status = 'active';
+CREATE INDEX IF NOT EXISTS idx_digital_loans_user ON digital_loans(user_id, requested_at DESC);
+
+COMMENT ON TABLE digital_loans IS 'Time-limited loans of licensed documents and their waitlists';
@@ -0,0 +1,52 @@
+-- Background jobs, shared by every replica: workers claim queued jobs and hold them under
+-- a lease they renew while running; a job whose lease lapses is claimed again
+CREATE TABLE IF NOT EXISTS jobs (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    type VARCHAR(50) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending'
+        CHECK (status IN ('pending', 'running', 'completed', 'failed', 'cancelled')),
+    payload JSONB NOT NULL DEFAULT '{}',
+    total INTEGER NOT NULL DEFAULT 0,
+    completed INTEGER NOT NULL DEFAULT 0,
+    failed INTEGER NOT NULL DEFAULT 0,
+    errors JSONB NOT NULL DEFAULT '[]',
+    result JSONB NOT NULL DEFAULT '{}',
+    created_by UUID NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    cancel_requested BOOLEAN NOT NULL DEFAULT FALSE,
+    lease_owner VARCHAR(100),
+    lease_expires_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    started_at TIMESTAMP,
+    completed_at TIMESTAMP,
+    CHECK (status <> 'running' OR lease_expires_at IS NOT NULL)
+);
+
+-- Workers look for queued jobs, and running ones whose lease lapsed, oldest first
+CREATE INDEX IF NOT EXISTS idx_jobs_claimable ON jobs(created_at)
+    WHERE status IN ('pending', 'running');
+CREATE INDEX IF NOT EXISTS idx_jobs_created_by ON jobs(created_by, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_jobs_finished ON jobs(type, completed_at)
+    WHERE completed_at IS NOT NULL;
+
+-- The units of work of a job (files, documents, manifest rows). Items that are done are
+-- skipped when a job is claimed again; failed ones can be retried.
+CREATE TABLE IF NOT EXISTS job_items (
+    job_id UUID NOT NULL REFERENCES jobs(id) ON DELETE CASCADE,
+    seq INTEGER NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending'
+        CHECK (status IN ('pending', 'done', 'failed')),
+    payload JSONB NOT NULL DEFAULT '{}',
+    result JSONB NOT NULL DEFAULT '{}',
+    error TEXT,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (job_id, seq)
+);
+
+CREATE INDEX IF NOT EXISTS idx_job_items_pending ON job_items(job_id, seq)
+    WHERE status = 'pending';
+
+COMMENT ON TABLE jobs IS 'Durable background jobs claimed by worker leases';
+COMMENT ON TABLE job_items IS 'Per-item progress of background jobs, for resuming and retrying';
@@ -0,0 +1,8 @@
+ALTER TABLE upload_intents
+    DROP COLUMN IF EXISTS available_from,
+    DROP COLUMN IF EXISTS available_until,
+    DROP COLUMN IF EXISTS embargo_show_metadata;
+ALTER TABLE upload_sessions
+    DROP COLUMN IF EXISTS available_from,
+    DROP COLUMN IF EXISTS available_until,
+    DROP COLUMN IF EXISTS embargo_show_metadata;
@@ -0,0 +1,10 @@
+-- Availability window supplied when a resumable or direct upload is created, applied to
+-- the document once the file is ingested
+ALTER TABLE upload_sessions
+    ADD COLUMN IF NOT EXISTS available_from TIMESTAMP,
+    ADD COLUMN IF NOT EXISTS available_until TIMESTAMP,
+    ADD COLUMN IF NOT EXISTS embargo_show_metadata BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE upload_intents
+    ADD COLUMN IF NOT EXISTS available_from TIMESTAMP,
+    ADD COLUMN IF NOT EXISTS available_until TIMESTAMP,
+    ADD COLUMN IF NOT EXISTS embargo_show_metadata BOOLEAN NOT NULL DEFAULT FALSE;
@@ -26,6 +26,7 @@ func NewSearchHandler(s service.SearchService) *SearchHandler {
 // @Param        q          query     string  false "Query string"
 // @Param        page       query     int     false "Page number" default(1)
 // @Param        page_size  query     int     false "Page size" default(10)
+// @Param        collapse   query     string  false "\"works\" shows one hit per series or multi-part work"
 // @Success      200  {object}  service.SearchResult "Search results"
 // @Failure      500  {object}  map[string]string "Internal server error"
 // @Router       / [get]
@@ -45,7 +46,7 @@ func (h *SearchHandler) Search(c *gin.Context) {
 	}
 
 	log.Printf("DEBUG: Search Handler called with query: '%s'", query)
-	result, err := h.searchService.Search(query, page, pageSize)
+	result, err := h.searchService.Search(query, page, pageSize, c.Query("collapse") == "works")
 	if err != nil {
 		log.Printf("DEBUG: Search Service failed: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -11,7 +11,7 @@ import (
 )
 
 type SearchService interface {
-	Search(query string, page, pageSize int) (*SearchResult, error)
+	Search(query string, page, pageSize int, collapseWorks bool) (*SearchResult, error)
 }
 
 type searchService struct {
@@ -30,30 +30,21 @@ func NewSearchService(client *elasticsearch.TypedClient) SearchService {
 	return &searchService{client: client}
 }
 
-func (s *searchService) Search(query string, page, pageSize int) (*SearchResult, error) {
+// Search finds available documents matching query. With collapseWorks, the volumes of a
+// series and the parts of a work are collapsed into their best-matching hit.
+func (s *searchService) Search(query string, page, pageSize int, collapseWorks bool) (*SearchResult, error) {
 	from := (page - 1) * pageSize
 
-	// Default query if empty: match all
-	var q *types.Query
-	if query == "" {
-		q = &types.Query{
-			MatchAll: &types.MatchAllQuery{},
-		}
-	} else {
-		// Multi-match query on title, description, and content
-		q = &types.Query{
-			MultiMatch: &types.MultiMatchQuery{
-				Query:     query,
-				Fields:    []string{"title^2", "description", "content"}, // Boost title
-				Fuzziness: "AUTO",
-			},
-		}
+	request := s.client.Search().
+		Index("documents").
+		Query(availableQuery(query))
+	if collapseWorks {
+		// group_id is the series or work a document belongs to, or the document itself
+		request = request.Collapse(&types.FieldCollapse{Field: "group_id.keyword"})
 	}
 
 	// Execute Search with Aggregations
-	res, err := s.client.Search().
-		Index("documents").
-		Query(q).
+	res, err := request.
 		From(from).
 		Size(pageSize).
 		Aggregations(map[string]types.Aggregations{
@@ -72,6 +63,11 @@ func (s *searchService) Search(query string, page, pageSize int) (*SearchResult,
 					Field: some("collection_id.keyword"),
 				},
 			},
+			"series": {
+				Terms: &types.TermsAggregation{
+					Field: some("series_id.keyword"),
+				},
+			},
 		}).
 		Do(context.Background())
 
@@ -98,6 +94,7 @@ func (s *searchService) Search(query string, page, pageSize int) (*SearchResult,
 		facets["file_types"] = parseTermsAgg(res.Aggregations["file_types"])
 		facets["statuses"] = parseTermsAgg(res.Aggregations["statuses"])
 		facets["collections"] = parseTermsAgg(res.Aggregations["collections"])
+		facets["series"] = parseTermsAgg(res.Aggregations["series"])
 	}
 
 	return &SearchResult{
@@ -109,6 +106,51 @@ func (s *searchService) Search(query string, page, pageSize int) (*SearchResult,
 	}, nil
 }
 
+// textQuery matches query in fields, or matches everything when query is empty
+func textQuery(query string, fields ...string) types.Query {
+	if query == "" {
+		return types.Query{MatchAll: &types.MatchAllQuery{}}
+	}
+	return types.Query{
+		MultiMatch: &types.MultiMatchQuery{
+			Query:     query,
+			Fields:    fields,
+			Fuzziness: "AUTO",
+		},
+	}
+}
+
+// availableQuery restricts a search to documents within their availability window.
+// Documents under an embargo that shows metadata are found by title and description
+// only, so their content does not leak through matches.
+func availableQuery(query string) *types.Query {
+	notStarted := types.Query{Range: map[string]types.RangeQuery{
+		"available_from": types.DateRangeQuery{Gt: some("now")},
+	}}
+	ended := types.Query{Range: map[string]types.RangeQuery{
+		"available_until": types.DateRangeQuery{Lte: some("now")},
+	}}
+
+	return &types.Query{
+		Bool: &types.BoolQuery{
+			MustNot: []types.Query{ended},
+			Should: []types.Query{
+				{Bool: &types.BoolQuery{
+					Must:    []types.Query{textQuery(query, "title^2", "description", "content")}, // Boost title
+					MustNot: []types.Query{notStarted},
+				}},
+				{Bool: &types.BoolQuery{
+					Must: []types.Query{textQuery(query, "title^2", "description")},
+					Filter: []types.Query{notStarted, {Term: map[string]types.TermQuery{
+						"embargo_show_metadata": {Value: true},
+					}}},
+				}},
+			},
+			MinimumShouldMatch: 1,
+		},
+	}
+}
+
 func some(s string) *string {
 	return &s
 }
@@ -0,0 +1,132 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"sync"
+
+	sharedRedis "github.com/Kyei-Ernest/libsystem/shared/redis"
+	"github.com/google/uuid"
+)
+
+// updatesChannel is the Redis channel job updates are published on
+const updatesChannel = "jobs:updates"
+
+// watcherBuffer is how many updates a watcher can fall behind by before older ones are dropped
+const watcherBuffer = 8
+
+// Broker fans job updates out to the watchers of a job on every replica. Updates are
+// published through Redis pub/sub when a client is given, otherwise only to watchers on
+// this replica. Each update is the job's full state, so a watcher that falls behind only
+// misses intermediate states.
+type Broker struct {
+	redis *sharedRedis.Client
+
+	mu       sync.Mutex
+	watchers map[uuid.UUID]map[chan *Job]struct{}
+}
+
+// NewBroker creates a job update broker; client may be nil
+func NewBroker(client *sharedRedis.Client) *Broker {
+	return &Broker{
+		redis:    client,
+		watchers: make(map[uuid.UUID]map[chan *Job]struct{}),
+	}
+}
+
+// Publish sends a job's state to its watchers on every replica
+func (b *Broker) Publish(job *Job) {
+	if b.redis == nil {
+		b.deliver(job)
+		return
+	}
+
+	data, err := json.Marshal(job)
+	if err != nil {
+		log.Printf("Failed to encode update of job %s: %v", job.ID, err)
+		return
+	}
+	if err := b.redis.Publish(updatesChannel, data); err != nil {
+		// Watchers elsewhere catch up when they next poll the job
+		log.Printf("Failed to publish update of job %s: %v", job.ID, err)
+		b.deliver(job)
+	}
+}
+
+// Run relays updates published by any replica to the watchers on this one until ctx is
+// cancelled. The subscription reconnects by itself if Redis goes away.
+func (b *Broker) Run(ctx context.Context) {
+	if b.redis == nil {
+		return
+	}
+
+	subscription := b.redis.Subscribe(ctx, updatesChannel)
+	defer subscription.Close()
+
+	messages := subscription.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case message, ok := <-messages:
+			if !ok {
+				return
+			}
+			var job Job
+			if err := json.Unmarshal([]byte(message.Payload), &job); err != nil {
+				log.Printf("Failed to decode job update: %v", err)
+				continue
+			}
+			b.deliver(&job)
+		}
+	}
+}
+
+// Watch returns the updates of a job until stop is called
+func (b *Broker) Watch(jobID uuid.UUID) (<-chan *Job, func()) {
+	updates := make(chan *Job, watcherBuffer)
+
+	b.mu.Lock()
+	if b.watchers[jobID] == nil {
+		b.watchers[jobID] = make(map[chan *Job]struct{})
+	}
+	b.watchers[jobID][updates] = struct{}{}
+	b.mu.Unlock()
+
+	var once sync.Once
+	stop := func() {
+		once.Do(func() {
+			b.mu.Lock()
+			defer b.mu.Unlock()
+			delete(b.watchers[jobID], updates)
+			if len(b.watchers[jobID]) == 0 {
+				delete(b.watchers, jobID)
+			}
+			close(updates)
+		})
+	}
+	return updates, stop
+}
+
+// deliver hands an update to the job's watchers on this replica, dropping the oldest
+// update of a watcher that is full
+func (b *Broker) deliver(job *Job) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for updates := range b.watchers[job.ID] {
+		for {
+			select {
+			case updates <- job:
+			default:
+				select {
+				case <-updates:
+				default:
+				}
+				continue
+			}
+			break
+		}
+	}
+}
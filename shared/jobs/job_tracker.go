@@ -1,10 +1,16 @@
 package jobs
 
 import (
-	"sync"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
 	"time"
 
 	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // JobStatus represents the current status of a job
@@ -25,186 +31,482 @@ const (
 	JobTypeBulkUpload         JobType = "bulk_upload"
 	JobTypeBulkMetadataUpdate JobType = "bulk_metadata_update"
 	JobTypeBulkDelete         JobType = "bulk_delete"
+	JobTypeBulkImport         JobType = "bulk_import"
+	JobTypeCollectionExport   JobType = "collection_export"
 )
 
-// Job represents a background job with progress tracking
-type Job struct {
-	ID          uuid.UUID              `json:"id"`
-	Type        JobType                `json:"type"`
-	Status      JobStatus              `json:"status"`
-	Total       int                    `json:"total"`
-	Completed   int                    `json:"completed"`
-	Failed      int                    `json:"failed"`
-	Errors      []string               `json:"errors,omitempty"`
-	Result      map[string]interface{} `json:"result,omitempty"`
-	CreatedBy   uuid.UUID              `json:"created_by"`
-	CreatedAt   time.Time              `json:"created_at"`
-	StartedAt   *time.Time             `json:"started_at,omitempty"`
-	CompletedAt *time.Time             `json:"completed_at,omitempty"`
-}
-
-// JobTracker manages background jobs
-type JobTracker struct {
-	jobs map[uuid.UUID]*Job
-	mu   sync.RWMutex
+// ItemStatus represents the status of a job item
+type ItemStatus string
+
+const (
+	ItemStatusPending ItemStatus = "pending"
+	ItemStatusDone    ItemStatus = "done"
+	ItemStatusFailed  ItemStatus = "failed"
+)
+
+// maxJobErrors caps the errors kept on a job; item errors stay on the items
+const maxJobErrors = 100
+
+// JobData is a JSON object stored as JSONB
+type JobData map[string]interface{}
+
+// Scan implements sql.Scanner for JSONB
+func (d *JobData) Scan(value interface{}) error {
+	if value == nil {
+		*d = JobData{}
+		return nil
+	}
+
+	bytes, ok := value.([]byte)
+	if !ok {
+		return fmt.Errorf("failed to unmarshal JSONB value: %v", value)
+	}
+
+	return json.Unmarshal(bytes, d)
 }
 
-// NewJobTracker creates a new job tracker
-func NewJobTracker() *JobTracker {
-	return &JobTracker{
-		jobs: make(map[uuid.UUID]*Job),
+// Value implements driver.Valuer for JSONB
+func (d JobData) Value() (driver.Value, error) {
+	if d == nil {
+		return []byte("{}"), nil
 	}
+	return json.Marshal(d)
 }
 
-// CreateJob creates a new job
-func (jt *JobTracker) CreateJob(jobType JobType, total int, createdBy uuid.UUID) *Job {
-	job := &Job{
-		ID:        uuid.New(),
-		Type:      jobType,
-		Status:    JobStatusPending,
-		Total:     total,
-		Completed: 0,
-		Failed:    0,
-		Errors:    []string{},
-		Result:    make(map[string]interface{}),
-		CreatedBy: createdBy,
-		CreatedAt: time.Now(),
+// Decode unmarshals the data into v
+func (d JobData) Decode(v interface{}) error {
+	bytes, err := json.Marshal(d)
+	if err != nil {
+		return err
 	}
+	return json.Unmarshal(bytes, v)
+}
 
-	jt.mu.Lock()
-	jt.jobs[job.ID] = job
-	jt.mu.Unlock()
+// JobErrors is a list of error messages stored as JSONB
+type JobErrors []string
 
-	return job
-}
+// Scan implements sql.Scanner for JSONB
+func (e *JobErrors) Scan(value interface{}) error {
+	if value == nil {
+		*e = nil
+		return nil
+	}
 
-// StartJob marks a job as started
-func (jt *JobTracker) StartJob(jobID uuid.UUID) error {
-	jt.mu.Lock()
-	defer jt.mu.Unlock()
+	bytes, ok := value.([]byte)
+	if !ok {
+		return fmt.Errorf("failed to unmarshal JSONB value: %v", value)
+	}
+
+	return json.Unmarshal(bytes, e)
+}
 
-	job, exists := jt.jobs[jobID]
-	if !exists {
-		return ErrJobNotFound
+// Value implements driver.Valuer for JSONB
+func (e JobErrors) Value() (driver.Value, error) {
+	if e == nil {
+		return []byte("[]"), nil
 	}
+	return json.Marshal(e)
+}
 
-	now := time.Now()
-	job.Status = JobStatusRunning
-	job.StartedAt = &now
+// Job represents a background job with progress tracking
+type Job struct {
+	ID              uuid.UUID  `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	Type            JobType    `gorm:"type:varchar(50);not null" json:"type"`
+	Status          JobStatus  `gorm:"type:varchar(20);not null;default:'pending'" json:"status"`
+	Payload         JobData    `gorm:"type:jsonb" json:"-"`
+	Total           int        `gorm:"not null;default:0" json:"total"`
+	Completed       int        `gorm:"not null;default:0" json:"completed"`
+	Failed          int        `gorm:"not null;default:0" json:"failed"`
+	Errors          JobErrors  `gorm:"type:jsonb" json:"errors,omitempty"`
+	Result          JobData    `gorm:"type:jsonb" json:"result,omitempty"`
+	CreatedBy       uuid.UUID  `gorm:"type:uuid;not null" json:"created_by"`
+	Attempts        int        `gorm:"not null;default:0" json:"attempts"` // Times a worker claimed the job
+	CancelRequested bool       `gorm:"not null;default:false" json:"cancel_requested"`
+	LeaseOwner      *string    `gorm:"type:varchar(100)" json:"-"`
+	LeaseExpiresAt  *time.Time `json:"-"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+	StartedAt       *time.Time `json:"started_at,omitempty"`
+	CompletedAt     *time.Time `json:"completed_at,omitempty"`
+}
 
-	return nil
+// TableName specifies the table name
+func (Job) TableName() string {
+	return "jobs"
 }
 
-// UpdateProgress updates job progress
-func (jt *JobTracker) UpdateProgress(jobID uuid.UUID, completed, failed int, errorMsg string) error {
-	jt.mu.Lock()
-	defer jt.mu.Unlock()
+// Finished reports whether the job has ended
+func (j *Job) Finished() bool {
+	return j.Status == JobStatusCompleted || j.Status == JobStatusFailed || j.Status == JobStatusCancelled
+}
 
-	job, exists := jt.jobs[jobID]
-	if !exists {
-		return ErrJobNotFound
-	}
+// Item is a unit of work of a job, such as a file or a document
+type Item struct {
+	JobID     uuid.UUID  `gorm:"type:uuid;primaryKey" json:"job_id"`
+	Seq       int        `gorm:"primaryKey;autoIncrement:false" json:"seq"`
+	Status    ItemStatus `gorm:"type:varchar(20);not null;default:'pending'" json:"status"`
+	Payload   JobData    `gorm:"type:jsonb" json:"payload"`
+	Result    JobData    `gorm:"type:jsonb" json:"result,omitempty"`
+	Error     string     `gorm:"type:text" json:"error,omitempty"`
+	Attempts  int        `gorm:"not null;default:0" json:"attempts"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
 
-	job.Completed = completed
-	job.Failed = failed
+// TableName specifies the table name
+func (Item) TableName() string {
+	return "job_items"
+}
 
-	if errorMsg != "" {
-		job.Errors = append(job.Errors, errorMsg)
-	}
+// JobSpec describes a job to enqueue. A job with items counts progress per item and has
+// as many as its total; other jobs report their progress against Total themselves.
+type JobSpec struct {
+	Type      JobType
+	CreatedBy uuid.UUID
+	Payload   interface{}   // Encoded as a JSON object
+	Items     []interface{} // Each encoded as a JSON object
+	Total     int           // For jobs without items
+	Result    JobData       // Initial result
+}
 
-	return nil
+// JobFilter represents filters for listing jobs
+type JobFilter struct {
+	CreatedBy      *uuid.UUID
+	Type           JobType
+	Status         JobStatus
+	FinishedBefore *time.Time
 }
 
-// CompleteJob marks a job as completed
-func (jt *JobTracker) CompleteJob(jobID uuid.UUID) error {
-	jt.mu.Lock()
-	defer jt.mu.Unlock()
+// JobTracker keeps background jobs in Postgres, so that their status survives restarts
+// and is the same on every replica, and runs them on workers that claim them (see Run)
+type JobTracker struct {
+	db       *gorm.DB
+	workerID string
+	lease    time.Duration
+	handlers map[JobType]Handler
+	wake     chan struct{}
+	broker   *Broker
+}
 
-	job, exists := jt.jobs[jobID]
-	if !exists {
-		return ErrJobNotFound
+// NewJobTracker creates a job tracker. Its workers identify as workerID and hold the jobs
+// they run under leases of the given length, renewed while they run.
+func NewJobTracker(db *gorm.DB, workerID string, lease time.Duration) *JobTracker {
+	return &JobTracker{
+		db:       db,
+		workerID: workerID,
+		lease:    lease,
+		handlers: make(map[JobType]Handler),
+		wake:     make(chan struct{}, 1),
 	}
+}
 
-	now := time.Now()
-	job.Status = JobStatusCompleted
-	job.CompletedAt = &now
+// SetBroker publishes every change to a job through the broker, for watchers to follow
+func (jt *JobTracker) SetBroker(broker *Broker) {
+	jt.broker = broker
+}
 
-	return nil
+// changed publishes a job's current state, if anyone may be watching
+func (jt *JobTracker) changed(jobID uuid.UUID) {
+	if jt.broker == nil {
+		return
+	}
+	job, err := jt.GetJob(jobID)
+	if err != nil {
+		log.Printf("Failed to load job %s to publish: %v", jobID, err)
+		return
+	}
+	jt.broker.Publish(job)
 }
 
-// FailJob marks a job as failed
-func (jt *JobTracker) FailJob(jobID uuid.UUID, errorMsg string) error {
-	jt.mu.Lock()
-	defer jt.mu.Unlock()
+// Enqueue creates a pending job and its items
+func (jt *JobTracker) Enqueue(spec JobSpec) (*Job, error) {
+	payload, err := toJobData(spec.Payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode job payload: %w", err)
+	}
 
-	job, exists := jt.jobs[jobID]
-	if !exists {
-		return ErrJobNotFound
+	job := &Job{
+		ID:        uuid.New(),
+		Type:      spec.Type,
+		Status:    JobStatusPending,
+		Payload:   payload,
+		Total:     spec.Total,
+		Errors:    JobErrors{},
+		Result:    spec.Result,
+		CreatedBy: spec.CreatedBy,
+	}
+	if job.Result == nil {
+		job.Result = JobData{}
 	}
 
-	now := time.Now()
-	job.Status = JobStatusFailed
-	job.CompletedAt = &now
+	items := make([]Item, len(spec.Items))
+	for i, itemPayload := range spec.Items {
+		data, err := toJobData(itemPayload)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode job item: %w", err)
+		}
+		items[i] = Item{JobID: job.ID, Seq: i + 1, Status: ItemStatusPending, Payload: data, Result: JobData{}}
+	}
+	if len(items) > 0 {
+		job.Total = len(items)
+	}
 
-	if errorMsg != "" {
-		job.Errors = append(job.Errors, errorMsg)
+	err = jt.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(job).Error; err != nil {
+			return err
+		}
+		if len(items) > 0 {
+			return tx.CreateInBatches(items, 500).Error
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	return nil
+	jt.notify()
+	jt.changed(job.ID)
+	return job, nil
 }
 
 // GetJob retrieves a job by ID
 func (jt *JobTracker) GetJob(jobID uuid.UUID) (*Job, error) {
-	jt.mu.RLock()
-	defer jt.mu.RUnlock()
+	var job Job
+	if err := jt.db.First(&job, "id = ?", jobID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrJobNotFound
+		}
+		return nil, err
+	}
+	return &job, nil
+}
 
-	job, exists := jt.jobs[jobID]
-	if !exists {
-		return nil, ErrJobNotFound
+// ListJobs lists jobs matching the filter, newest first
+func (jt *JobTracker) ListJobs(filter JobFilter, offset, limit int) ([]Job, int64, error) {
+	query := jt.db.Model(&Job{})
+	if filter.CreatedBy != nil {
+		query = query.Where("created_by = ?", *filter.CreatedBy)
+	}
+	if filter.Type != "" {
+		query = query.Where("type = ?", filter.Type)
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.FinishedBefore != nil {
+		query = query.Where("completed_at < ?", *filter.FinishedBefore)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
 	}
 
-	// Return a copy to prevent external modifications
-	jobCopy := *job
-	return &jobCopy, nil
+	var jobs []Job
+	if err := query.Order("created_at DESC").Offset(offset).Limit(limit).Find(&jobs).Error; err != nil {
+		return nil, 0, err
+	}
+	return jobs, total, nil
 }
 
-// ListJobs lists all jobs for a user
-func (jt *JobTracker) ListJobs(userID uuid.UUID) []*Job {
-	jt.mu.RLock()
-	defer jt.mu.RUnlock()
+// ListItems lists a job's items in order, optionally only those with a status
+func (jt *JobTracker) ListItems(jobID uuid.UUID, status ItemStatus, offset, limit int) ([]Item, int64, error) {
+	query := jt.db.Model(&Item{}).Where("job_id = ?", jobID)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
 
-	var jobs []*Job
-	for _, job := range jt.jobs {
-		if job.CreatedBy == userID {
-			jobCopy := *job
-			jobs = append(jobs, &jobCopy)
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var items []Item
+	if err := query.Order("seq").Offset(offset).Limit(limit).Find(&items).Error; err != nil {
+		return nil, 0, err
+	}
+	return items, total, nil
+}
+
+// SetResult merges values into a job's result
+func (jt *JobTracker) SetResult(jobID uuid.UUID, values JobData) error {
+	if err := setResult(jt.db.Where("id = ?", jobID), values); err != nil {
+		return err
+	}
+	jt.changed(jobID)
+	return nil
+}
+
+// CancelJob cancels a job. A pending job is cancelled at once; a running job is stopped by
+// its worker, which notices at its next heartbeat.
+func (jt *JobTracker) CancelJob(jobID uuid.UUID) (*Job, error) {
+	var job Job
+	err := jt.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&job, "id = ?", jobID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrJobNotFound
+			}
+			return err
 		}
+
+		now := time.Now()
+		switch job.Status {
+		case JobStatusPending:
+			job.Status = JobStatusCancelled
+			job.CompletedAt = &now
+		case JobStatusRunning:
+			job.CancelRequested = true
+		default:
+			return ErrJobFinished
+		}
+		return tx.Model(&job).Updates(map[string]interface{}{
+			"status":           job.Status,
+			"cancel_requested": job.CancelRequested,
+			"completed_at":     job.CompletedAt,
+			"updated_at":       now,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	return jobs
+	jt.changed(job.ID)
+	return &job, nil
 }
 
-// CleanupOldJobs removes completed jobs older than the specified duration
-func (jt *JobTracker) CleanupOldJobs(maxAge time.Duration) int {
-	jt.mu.Lock()
-	defer jt.mu.Unlock()
+// RetryJob queues a failed or cancelled job again. Its failed items are retried and the
+// items already done are skipped; a job without items starts over.
+func (jt *JobTracker) RetryJob(jobID uuid.UUID) (*Job, error) {
+	var job Job
+	err := jt.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&job, "id = ?", jobID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrJobNotFound
+			}
+			return err
+		}
+		if job.Status != JobStatusFailed && job.Status != JobStatusCancelled {
+			return ErrJobNotRetryable
+		}
+
+		retried := tx.Model(&Item{}).
+			Where("job_id = ? AND status = ?", job.ID, ItemStatusFailed).
+			Updates(map[string]interface{}{"status": ItemStatusPending, "error": "", "updated_at": time.Now()})
+		if retried.Error != nil {
+			return retried.Error
+		}
+		var items int64
+		if err := tx.Model(&Item{}).Where("job_id = ?", job.ID).Count(&items).Error; err != nil {
+			return err
+		}
+
+		job.Status = JobStatusPending
+		job.Failed = 0
+		if items == 0 {
+			job.Completed = 0
+		}
+		job.Errors = JobErrors{}
+		job.Attempts = 0
+		job.CancelRequested = false
+		job.CompletedAt = nil
+		return tx.Model(&job).Updates(map[string]interface{}{
+			"status":           job.Status,
+			"failed":           job.Failed,
+			"completed":        job.Completed,
+			"errors":           job.Errors,
+			"attempts":         job.Attempts,
+			"cancel_requested": false,
+			"completed_at":     nil,
+			"updated_at":       time.Now(),
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
 
+	jt.notify()
+	jt.changed(job.ID)
+	return &job, nil
+}
+
+// CleanupOldJobs deletes jobs that finished before the cutoff, with their items, letting
+// their handlers release what they hold first
+func (jt *JobTracker) CleanupOldJobs(maxAge time.Duration) (int, error) {
 	cutoff := time.Now().Add(-maxAge)
 	removed := 0
 
-	for id, job := range jt.jobs {
-		if job.CompletedAt != nil && job.CompletedAt.Before(cutoff) {
-			delete(jt.jobs, id)
+	for {
+		var jobs []Job
+		if err := jt.db.Where("completed_at < ?", cutoff).Order("completed_at").Limit(100).Find(&jobs).Error; err != nil {
+			return removed, err
+		}
+
+		for i := range jobs {
+			job := &jobs[i]
+			if handler, ok := jt.handlers[job.Type]; ok && handler.Cleanup != nil {
+				var items []Item
+				if err := jt.db.Where("job_id = ?", job.ID).Order("seq").Find(&items).Error; err != nil {
+					return removed, err
+				}
+				handler.Cleanup(job, items)
+			}
+			if err := jt.db.Delete(&Job{}, "id = ?", job.ID).Error; err != nil {
+				return removed, err
+			}
 			removed++
 		}
+
+		if len(jobs) < 100 {
+			return removed, nil
+		}
+	}
+}
+
+// notify wakes an idle local worker
+func (jt *JobTracker) notify() {
+	select {
+	case jt.wake <- struct{}{}:
+	default:
+	}
+}
+
+// setResult merges values into the result of the jobs query selects
+func setResult(query *gorm.DB, values JobData) error {
+	encoded, err := json.Marshal(values)
+	if err != nil {
+		return err
+	}
+	return query.Model(&Job{}).Updates(map[string]interface{}{
+		"result":     gorm.Expr("result || ?::jsonb", string(encoded)),
+		"updated_at": time.Now(),
+	}).Error
+}
+
+// toJobData encodes v as a JSON object
+func toJobData(v interface{}) (JobData, error) {
+	if v == nil {
+		return JobData{}, nil
+	}
+	if data, ok := v.(JobData); ok {
+		return data, nil
 	}
 
-	return removed
+	bytes, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var data JobData
+	if err := json.Unmarshal(bytes, &data); err != nil {
+		return nil, err
+	}
+	return data, nil
 }
 
 // Error types
 var (
-	ErrJobNotFound = &JobError{Message: "job not found"}
+	ErrJobNotFound     = &JobError{Message: "job not found"}
+	ErrJobFinished     = &JobError{Message: "job has already finished"}
+	ErrJobNotRetryable = &JobError{Message: "only failed or cancelled jobs can be retried"}
+	ErrLeaseLost       = &JobError{Message: "job lease lost"}
 )
 
 // JobError represents a job-related error
@@ -0,0 +1,483 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// maxJobAttempts caps how many times a job whose worker vanished is claimed again
+const maxJobAttempts = 3
+
+// pollInterval is how often idle workers look for claimable jobs
+const pollInterval = 2 * time.Second
+
+// Handler runs the jobs of a type
+type Handler struct {
+	// Run does the job's work. It should return ctx.Err() when ctx is cancelled before
+	// the work is done; jobs whose handler returns nil are completed.
+	Run func(ctx context.Context, run *Run) error
+	// Cleanup, if set, releases what a finished job holds before the job is deleted
+	Cleanup func(job *Job, items []Item)
+}
+
+// Register sets the handler for a job type. Workers only claim jobs of registered types.
+func (jt *JobTracker) Register(jobType JobType, handler Handler) {
+	jt.handlers[jobType] = handler
+}
+
+// Run starts workers that claim and run jobs until ctx is cancelled. Jobs still running
+// then are released, to be claimed again by this or another replica.
+func (jt *JobTracker) Run(ctx context.Context, workers int) {
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			jt.work(ctx)
+		}()
+	}
+	wg.Wait()
+}
+
+// work claims and runs jobs one at a time
+func (jt *JobTracker) work(ctx context.Context) {
+	for ctx.Err() == nil {
+		job, err := jt.claim()
+		if err != nil {
+			log.Printf("Failed to claim job: %v", err)
+		}
+		if job != nil {
+			jt.execute(ctx, job)
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+		case <-jt.wake:
+		case <-time.After(pollInterval):
+		}
+	}
+}
+
+// claim takes the oldest pending job, or a running job whose lease expired, and leases
+// it to this worker. Abandoned jobs that were asked to stop, or were claimed too often,
+// are finished instead.
+func (jt *JobTracker) claim() (*Job, error) {
+	types := make([]JobType, 0, len(jt.handlers))
+	for jobType := range jt.handlers {
+		types = append(types, jobType)
+	}
+	if len(types) == 0 {
+		return nil, nil
+	}
+
+	for {
+		var job Job
+		claimed := false
+		err := jt.db.Transaction(func(tx *gorm.DB) error {
+			now := time.Now()
+			err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+				Where("type IN ?", types).
+				Where("status = ? OR (status = ? AND lease_expires_at < ?)", JobStatusPending, JobStatusRunning, now).
+				Order("created_at").
+				First(&job).Error
+			if err != nil {
+				return err
+			}
+
+			switch {
+			case job.Status == JobStatusRunning && job.CancelRequested:
+				return tx.Model(&job).Updates(finishUpdates(JobStatusCancelled, "")).Error
+			case job.Status == JobStatusRunning && job.Attempts >= maxJobAttempts:
+				message := fmt.Sprintf("Abandoned after %d attempts", job.Attempts)
+				return tx.Model(&job).Updates(finishUpdates(JobStatusFailed, message)).Error
+			}
+
+			lease := now.Add(jt.lease)
+			job.Status = JobStatusRunning
+			job.LeaseOwner = &jt.workerID
+			job.LeaseExpiresAt = &lease
+			job.Attempts++
+			if job.StartedAt == nil {
+				job.StartedAt = &now
+			}
+			claimed = true
+			return tx.Model(&job).Updates(map[string]interface{}{
+				"status":           job.Status,
+				"lease_owner":      jt.workerID,
+				"lease_expires_at": lease,
+				"attempts":         job.Attempts,
+				"started_at":       job.StartedAt,
+				"updated_at":       now,
+			}).Error
+		})
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		jt.changed(job.ID)
+		if claimed {
+			return &job, nil
+		}
+	}
+}
+
+// execute runs a claimed job, renewing its lease until the handler returns
+func (jt *JobTracker) execute(parent context.Context, job *Job) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	run := &Run{Job: job, tracker: jt}
+
+	// Why the handler was stopped, if it was
+	var mu sync.Mutex
+	var stopped JobStatus
+	stop := func(reason JobStatus) {
+		mu.Lock()
+		if stopped == "" {
+			stopped = reason
+		}
+		mu.Unlock()
+		cancel()
+	}
+
+	done := make(chan struct{})
+	heartbeatDone := make(chan struct{})
+	go func() {
+		defer close(heartbeatDone)
+		ticker := time.NewTicker(jt.lease / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-parent.Done():
+				stop(JobStatusPending)
+				return
+			case <-ticker.C:
+				cancelRequested, err := jt.renew(job)
+				switch {
+				case errors.Is(err, ErrLeaseLost):
+					log.Printf("Lost the lease on job %s", job.ID)
+					stop(JobStatusRunning)
+					return
+				case err != nil:
+					log.Printf("Failed to renew the lease on job %s: %v", job.ID, err)
+				case cancelRequested:
+					stop(JobStatusCancelled)
+				}
+			}
+		}
+	}()
+
+	err := jt.invoke(ctx, run)
+	close(done)
+	<-heartbeatDone
+
+	mu.Lock()
+	reason := stopped
+	mu.Unlock()
+
+	var finishErr error
+	switch {
+	case reason == JobStatusRunning:
+		// Another worker has the job now
+		return
+	case err == nil:
+		// A job whose items failed can be retried
+		failed, countErr := run.failedItems()
+		switch {
+		case countErr != nil:
+			finishErr = countErr
+		case failed > 0:
+			finishErr = jt.finish(job, JobStatusFailed, fmt.Sprintf("%d items failed", failed))
+		default:
+			finishErr = jt.finish(job, JobStatusCompleted, "")
+		}
+	case reason == JobStatusPending:
+		finishErr = jt.release(job)
+	case reason == JobStatusCancelled:
+		finishErr = jt.finish(job, JobStatusCancelled, "")
+	default:
+		finishErr = jt.finish(job, JobStatusFailed, err.Error())
+	}
+	if finishErr != nil && !errors.Is(finishErr, ErrLeaseLost) {
+		log.Printf("Failed to record the outcome of job %s: %v", job.ID, finishErr)
+	}
+}
+
+// invoke calls the job's handler, turning a panic into an error
+func (jt *JobTracker) invoke(ctx context.Context, run *Run) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("Job %s panicked: %v\n%s", run.Job.ID, r, debug.Stack())
+			err = fmt.Errorf("job panicked: %v", r)
+		}
+	}()
+
+	handler, ok := jt.handlers[run.Job.Type]
+	if !ok {
+		return fmt.Errorf("no handler for job type %s", run.Job.Type)
+	}
+	return handler.Run(ctx, run)
+}
+
+// renew extends the lease on a running job and reports whether it was asked to stop
+func (jt *JobTracker) renew(job *Job) (bool, error) {
+	now := time.Now()
+	result := jt.leased(jt.db, job).Updates(map[string]interface{}{
+		"lease_expires_at": now.Add(jt.lease),
+		"updated_at":       now,
+	})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return false, ErrLeaseLost
+	}
+
+	var current Job
+	if err := jt.db.Select("cancel_requested").First(&current, "id = ?", job.ID).Error; err != nil {
+		return false, err
+	}
+	return current.CancelRequested, nil
+}
+
+// finish ends a job this worker holds, recording a final error if there is one
+func (jt *JobTracker) finish(job *Job, status JobStatus, message string) error {
+	result := jt.leased(jt.db, job).Updates(finishUpdates(status, message))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrLeaseLost
+	}
+	jt.changed(job.ID)
+	return nil
+}
+
+// release gives a job back to the queue without counting the attempt
+func (jt *JobTracker) release(job *Job) error {
+	err := jt.leased(jt.db, job).Updates(map[string]interface{}{
+		"status":           JobStatusPending,
+		"lease_owner":      nil,
+		"lease_expires_at": nil,
+		"attempts":         gorm.Expr("GREATEST(attempts - 1, 0)"),
+		"updated_at":       time.Now(),
+	}).Error
+	if err != nil {
+		return err
+	}
+	jt.changed(job.ID)
+	return nil
+}
+
+// leased selects a job while this worker holds its lease
+func (jt *JobTracker) leased(db *gorm.DB, job *Job) *gorm.DB {
+	return db.Model(&Job{}).Where("id = ? AND status = ? AND lease_owner = ?", job.ID, JobStatusRunning, jt.workerID)
+}
+
+// finishUpdates are the column updates that end a job
+func finishUpdates(status JobStatus, message string) map[string]interface{} {
+	now := time.Now()
+	updates := map[string]interface{}{
+		"status":           status,
+		"lease_owner":      nil,
+		"lease_expires_at": nil,
+		"completed_at":     now,
+		"updated_at":       now,
+	}
+	if message != "" {
+		updates["errors"] = appendError(message)
+	}
+	return updates
+}
+
+// appendError appends a message to a job's errors until they reach maxJobErrors
+func appendError(message string) clause.Expr {
+	return gorm.Expr("CASE WHEN jsonb_array_length(errors) < ? THEN errors || to_jsonb(?::text) ELSE errors END", maxJobErrors, message)
+}
+
+// Run is a job being run by a worker. Its updates only apply while the worker holds the
+// job's lease; once it is lost they return ErrLeaseLost.
+type Run struct {
+	Job     *Job
+	tracker *JobTracker
+}
+
+// Items returns the job's items still to do, in order
+func (r *Run) Items() ([]Item, error) {
+	var items []Item
+	err := r.tracker.db.Where("job_id = ? AND status = ?", r.Job.ID, ItemStatusPending).Order("seq").Find(&items).Error
+	return items, err
+}
+
+// ItemDone records an item as done, with what it produced
+func (r *Run) ItemDone(seq int, result JobData) error {
+	if result == nil {
+		result = JobData{}
+	}
+	return r.finishItem(seq, map[string]interface{}{"status": ItemStatusDone, "result": result, "error": ""},
+		map[string]interface{}{"completed": gorm.Expr("completed + 1")})
+}
+
+// ItemFailed records an item as failed; retrying the job tries it again. The message
+// should name the item.
+func (r *Run) ItemFailed(seq int, message string) error {
+	return r.finishItem(seq, map[string]interface{}{"status": ItemStatusFailed, "error": message},
+		map[string]interface{}{"failed": gorm.Expr("failed + 1"), "errors": appendError(message)})
+}
+
+// finishItem updates a pending item and the job's counters together
+func (r *Run) finishItem(seq int, itemUpdates, jobUpdates map[string]interface{}) error {
+	now := time.Now()
+	itemUpdates["attempts"] = gorm.Expr("attempts + 1")
+	itemUpdates["updated_at"] = now
+	jobUpdates["updated_at"] = now
+
+	err := r.tracker.db.Transaction(func(tx *gorm.DB) error {
+		result := r.tracker.leased(tx, r.Job).Updates(jobUpdates)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrLeaseLost
+		}
+
+		result = tx.Model(&Item{}).
+			Where("job_id = ? AND seq = ? AND status = ?", r.Job.ID, seq, ItemStatusPending).
+			Updates(itemUpdates)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return fmt.Errorf("item %d of job %s is not pending", seq, r.Job.ID)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	r.tracker.changed(r.Job.ID)
+	return nil
+}
+
+// Progress sets the counters of a job without items, recording an error if there is one
+func (r *Run) Progress(completed, failed int, message string) error {
+	updates := map[string]interface{}{
+		"completed":  completed,
+		"failed":     failed,
+		"updated_at": time.Now(),
+	}
+	if message != "" {
+		updates["errors"] = appendError(message)
+	}
+
+	result := r.tracker.leased(r.tracker.db, r.Job).Updates(updates)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrLeaseLost
+	}
+	r.tracker.changed(r.Job.ID)
+	return nil
+}
+
+// SetResult merges values into the job's result
+func (r *Run) SetResult(values JobData) error {
+	if err := setResult(r.tracker.leased(r.tracker.db, r.Job), values); err != nil {
+		return err
+	}
+	r.tracker.changed(r.Job.ID)
+	return nil
+}
+
+// failedItems counts the job's failed items
+func (r *Run) failedItems() (int64, error) {
+	var count int64
+	err := r.tracker.db.Model(&Item{}).Where("job_id = ? AND status = ?", r.Job.ID, ItemStatusFailed).Count(&count).Error
+	return count, err
+}
+
+// ForEach processes the job's pending items, up to concurrency at a time, recording each
+// as done with the result fn returns or as failed with its error. It stops when ctx is
+// cancelled, leaving the items it did not finish pending, or when the lease is lost.
+func (r *Run) ForEach(ctx context.Context, concurrency int, fn func(ctx context.Context, item Item) (JobData, error)) error {
+	items, err := r.Items()
+	if err != nil {
+		return err
+	}
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	var mu sync.Mutex
+	var firstErr error
+	stopped := func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return firstErr != nil
+	}
+
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for _, item := range items {
+		sem <- struct{}{}
+		if ctx.Err() != nil || stopped() {
+			<-sem
+			break
+		}
+
+		wg.Add(1)
+		go func(item Item) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			result, err := runItem(ctx, fn, item)
+			if err != nil && ctx.Err() != nil {
+				return // Interrupted; the item is done again when the job resumes
+			}
+			if err != nil {
+				err = r.ItemFailed(item.Seq, err.Error())
+			} else {
+				err = r.ItemDone(item.Seq, result)
+			}
+			if err != nil {
+				mu.Lock()
+				if firstErr == nil {
+					firstErr = err
+				}
+				mu.Unlock()
+			}
+		}(item)
+	}
+	wg.Wait()
+
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return firstErr
+}
+
+// runItem calls fn on an item, turning a panic into the item's error so that one bad item
+// fails alone instead of taking the process down
+func runItem(ctx context.Context, fn func(ctx context.Context, item Item) (JobData, error), item Item) (result JobData, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("Item %d of job %s panicked: %v\n%s", item.Seq, item.JobID, r, debug.Stack())
+			result, err = nil, fmt.Errorf("item %d panicked: %v", item.Seq, r)
+		}
+	}()
+	return fn(ctx, item)
+}
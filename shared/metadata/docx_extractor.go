@@ -0,0 +1,76 @@
+package metadata
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"io"
+
+	"github.com/Kyei-Ernest/libsystem/shared/models"
+)
+
+// DOCXExtractor extracts metadata from the package properties of DOCX files
+type DOCXExtractor struct{}
+
+// coreProperties is docProps/core.xml, the Dublin Core properties of an OOXML package
+type coreProperties struct {
+	Title       string `xml:"http://purl.org/dc/elements/1.1/ title"`
+	Subject     string `xml:"http://purl.org/dc/elements/1.1/ subject"`
+	Creator     string `xml:"http://purl.org/dc/elements/1.1/ creator"`
+	Description string `xml:"http://purl.org/dc/elements/1.1/ description"`
+	Identifier  string `xml:"http://purl.org/dc/elements/1.1/ identifier"`
+	Keywords    string `xml:"http://schemas.openxmlformats.org/package/2006/metadata/core-properties keywords"`
+	Category    string `xml:"http://schemas.openxmlformats.org/package/2006/metadata/core-properties category"`
+	Created     string `xml:"http://purl.org/dc/terms/ created"`
+}
+
+// appProperties is docProps/app.xml, the properties recorded by the producing application
+type appProperties struct {
+	Pages       int    `xml:"Pages"`
+	Application string `xml:"Application"`
+}
+
+// Extract extracts metadata from a DOCX file
+func (e *DOCXExtractor) Extract(r io.ReaderAt, size int64) (*models.DocumentMetadata, error) {
+	archive, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open DOCX: %w", err)
+	}
+
+	metadata := &models.DocumentMetadata{
+		CustomFields: make(map[string]interface{}),
+	}
+
+	var core coreProperties
+	if err := decodeZipXML(archive, "docProps/core.xml", &core); err == nil {
+		metadata.Author = firstNonEmpty(core.Creator)
+		metadata.PublishDate = normalizeDate(core.Created)
+		metadata.ISBN = firstISBN(core.Identifier)
+		metadata.Tags = splitKeywords(core.Keywords)
+		setCustomField(metadata, "title", core.Title)
+		setCustomField(metadata, "subject", core.Subject)
+		setCustomField(metadata, "category", core.Category)
+	}
+
+	// Word records the page count; documents saved by other tools may not carry it
+	var app appProperties
+	if err := decodeZipXML(archive, "docProps/app.xml", &app); err == nil {
+		if app.Pages > 0 {
+			metadata.CustomFields[PageCountField] = app.Pages
+		}
+		setCustomField(metadata, "producer", app.Application)
+	}
+
+	return metadata, nil
+}
+
+// decodeZipXML decodes an XML entry of a ZIP archive
+func decodeZipXML(archive *zip.Reader, name string, v interface{}) error {
+	entry, err := archive.Open(name)
+	if err != nil {
+		return err
+	}
+	defer entry.Close()
+
+	return xml.NewDecoder(entry).Decode(v)
+}
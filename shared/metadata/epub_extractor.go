@@ -0,0 +1,107 @@
+package metadata
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/Kyei-Ernest/libsystem/shared/models"
+)
+
+// EPUBExtractor extracts the Dublin Core metadata of the package document (OPF) of EPUB files
+type EPUBExtractor struct{}
+
+// epubContainer is META-INF/container.xml, which locates the package document
+type epubContainer struct {
+	Rootfiles []struct {
+		FullPath  string `xml:"full-path,attr"`
+		MediaType string `xml:"media-type,attr"`
+	} `xml:"rootfiles>rootfile"`
+}
+
+// opfPackage is the part of the package document holding the publication's metadata
+type opfPackage struct {
+	Metadata struct {
+		Titles       []string        `xml:"http://purl.org/dc/elements/1.1/ title"`
+		Creators     []string        `xml:"http://purl.org/dc/elements/1.1/ creator"`
+		Publishers   []string        `xml:"http://purl.org/dc/elements/1.1/ publisher"`
+		Dates        []string        `xml:"http://purl.org/dc/elements/1.1/ date"`
+		Subjects     []string        `xml:"http://purl.org/dc/elements/1.1/ subject"`
+		Descriptions []string        `xml:"http://purl.org/dc/elements/1.1/ description"`
+		Languages    []string        `xml:"http://purl.org/dc/elements/1.1/ language"`
+		Identifiers  []opfIdentifier `xml:"http://purl.org/dc/elements/1.1/ identifier"`
+	} `xml:"metadata"`
+}
+
+// opfIdentifier is a dc:identifier; EPUB 2 names its scheme in an attribute
+type opfIdentifier struct {
+	Scheme string `xml:"scheme,attr"`
+	Value  string `xml:",chardata"`
+}
+
+// Extract extracts metadata from an EPUB file
+func (e *EPUBExtractor) Extract(r io.ReaderAt, size int64) (*models.DocumentMetadata, error) {
+	archive, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open EPUB: %w", err)
+	}
+
+	var container epubContainer
+	if err := decodeZipXML(archive, "META-INF/container.xml", &container); err != nil {
+		return nil, fmt.Errorf("failed to read EPUB container: %w", err)
+	}
+
+	var packagePath string
+	for _, rootfile := range container.Rootfiles {
+		if rootfile.MediaType == "" || rootfile.MediaType == "application/oebps-package+xml" {
+			packagePath = rootfile.FullPath
+			break
+		}
+	}
+	if packagePath == "" {
+		return nil, fmt.Errorf("EPUB container names no package document")
+	}
+
+	var pkg opfPackage
+	if err := decodeZipXML(archive, packagePath, &pkg); err != nil {
+		return nil, fmt.Errorf("failed to read EPUB package document: %w", err)
+	}
+	dc := pkg.Metadata
+
+	metadata := &models.DocumentMetadata{
+		CustomFields: make(map[string]interface{}),
+	}
+	metadata.Author = strings.Join(nonEmpty(dc.Creators), "; ")
+	metadata.Publisher = firstNonEmpty(dc.Publishers...)
+	metadata.PublishDate = normalizeDate(firstNonEmpty(dc.Dates...))
+	metadata.Tags = splitKeywords(dc.Subjects...)
+
+	// Identifiers may be a UUID or URI as well as an ISBN; prefer ones declared as ISBNs
+	var declared, others []string
+	for _, identifier := range dc.Identifiers {
+		if strings.EqualFold(identifier.Scheme, "isbn") {
+			declared = append(declared, identifier.Value)
+		} else {
+			others = append(others, identifier.Value)
+		}
+	}
+	metadata.ISBN = firstISBN(append(declared, others...)...)
+
+	setCustomField(metadata, "title", firstNonEmpty(dc.Titles...))
+	setCustomField(metadata, "subject", firstNonEmpty(dc.Descriptions...))
+	setCustomField(metadata, "language", firstNonEmpty(dc.Languages...))
+
+	return metadata, nil
+}
+
+// nonEmpty returns the trimmed values that are not blank
+func nonEmpty(values []string) []string {
+	var result []string
+	for _, value := range values {
+		if value = strings.TrimSpace(value); value != "" {
+			result = append(result, value)
+		}
+	}
+	return result
+}
@@ -0,0 +1,192 @@
+package metadata
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Kyei-Ernest/libsystem/shared/models"
+)
+
+// PageCountField is the custom field extractors report a document's page count in
+const PageCountField = "page_count"
+
+// Extractor defines the interface for embedded metadata extraction
+type Extractor interface {
+	Extract(r io.ReaderAt, size int64) (*models.DocumentMetadata, error)
+}
+
+// Result is the metadata extracted from a document
+type Result struct {
+	Metadata  models.DocumentMetadata
+	PageCount int    // 0 when the format has no fixed pages or the count is unknown
+	Source    string // Format the metadata was read from, recorded as its provenance
+}
+
+// Registry maps document file types ("PDF", "DOCX", "EPUB") to their extractors
+type Registry struct {
+	mu         sync.RWMutex
+	extractors map[string]Extractor
+}
+
+// NewRegistry creates an empty registry
+func NewRegistry() *Registry {
+	return &Registry{extractors: make(map[string]Extractor)}
+}
+
+// DefaultRegistry returns a registry with the built-in PDF, DOCX and EPUB extractors
+func DefaultRegistry() *Registry {
+	registry := NewRegistry()
+	registry.Register("PDF", &PDFExtractor{})
+	registry.Register("DOCX", &DOCXExtractor{})
+	registry.Register("EPUB", &EPUBExtractor{})
+	return registry
+}
+
+// Register sets the extractor for a file type, replacing any registered before
+func (r *Registry) Register(fileType string, extractor Extractor) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.extractors[strings.ToUpper(fileType)] = extractor
+}
+
+// Extract reads the embedded metadata of a document. It returns nil without error when no
+// extractor is registered for the file type.
+func (r *Registry) Extract(fileType string, src io.ReaderAt, size int64) (result *Result, err error) {
+	r.mu.RLock()
+	extractor, ok := r.extractors[strings.ToUpper(fileType)]
+	r.mu.RUnlock()
+	if !ok {
+		return nil, nil
+	}
+
+	// Parsers of untrusted files may panic on malformed input
+	defer func() {
+		if p := recover(); p != nil {
+			result, err = nil, fmt.Errorf("%s metadata extraction failed: %v", fileType, p)
+		}
+	}()
+
+	extracted, err := extractor.Extract(src, size)
+	if err != nil {
+		return nil, err
+	}
+
+	result = &Result{Metadata: *extracted, Source: strings.ToLower(fileType)}
+	if pages, ok := extracted.CustomFields[PageCountField].(int); ok {
+		result.PageCount = pages
+		delete(result.Metadata.CustomFields, PageCountField)
+	}
+	return result, nil
+}
+
+// splitKeywords splits a keyword list on commas and semicolons, dropping duplicates
+func splitKeywords(keywords ...string) []string {
+	var tags []string
+	seen := make(map[string]bool)
+	for _, list := range keywords {
+		for _, keyword := range strings.FieldsFunc(list, func(r rune) bool { return r == ',' || r == ';' }) {
+			keyword = strings.TrimSpace(keyword)
+			if keyword == "" || seen[strings.ToLower(keyword)] {
+				continue
+			}
+			seen[strings.ToLower(keyword)] = true
+			tags = append(tags, keyword)
+		}
+	}
+	return tags
+}
+
+var partialDate = regexp.MustCompile(`^\d{4}(-\d{2})?$`)
+
+// normalizeDate converts an ISO 8601 date or timestamp to YYYY-MM-DD, keeping dates that
+// only give a year or month as they are. Anything else is dropped.
+func normalizeDate(value string) string {
+	value = strings.TrimSpace(value)
+	if len(value) >= 10 {
+		if date, err := time.Parse("2006-01-02", value[:10]); err == nil {
+			return date.Format("2006-01-02")
+		}
+	}
+	if partialDate.MatchString(value) {
+		return value
+	}
+	return ""
+}
+
+// normalizeISBN returns an ISBN-10 or ISBN-13 without separators or URN prefix, or "" if
+// value is not one (the check digit must be valid)
+func normalizeISBN(value string) string {
+	value = strings.TrimSpace(strings.ToUpper(value))
+	for _, prefix := range []string{"URN:ISBN:", "ISBN:", "ISBN"} {
+		value = strings.TrimPrefix(value, prefix)
+	}
+	value = strings.NewReplacer("-", "", " ", "").Replace(strings.TrimSpace(value))
+
+	switch len(value) {
+	case 10:
+		sum := 0
+		for i, c := range value {
+			digit := int(c - '0')
+			if c == 'X' && i == 9 {
+				digit = 10
+			} else if c < '0' || c > '9' {
+				return ""
+			}
+			sum += (10 - i) * digit
+		}
+		if sum%11 == 0 {
+			return value
+		}
+	case 13:
+		sum := 0
+		for i, c := range value {
+			if c < '0' || c > '9' {
+				return ""
+			}
+			weight := 1
+			if i%2 == 1 {
+				weight = 3
+			}
+			sum += weight * int(c-'0')
+		}
+		if sum%10 == 0 {
+			return value
+		}
+	}
+	return ""
+}
+
+// firstISBN returns the first of the given identifiers that is an ISBN
+func firstISBN(identifiers ...string) string {
+	for _, identifier := range identifiers {
+		if isbn := normalizeISBN(identifier); isbn != "" {
+			return isbn
+		}
+	}
+	return ""
+}
+
+func setCustomField(metadata *models.DocumentMetadata, key, value string) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return
+	}
+	if metadata.CustomFields == nil {
+		metadata.CustomFields = make(map[string]interface{})
+	}
+	metadata.CustomFields[key] = value
+}
+
+// firstNonEmpty returns the first value that is not blank, trimmed
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value = strings.TrimSpace(value); value != "" {
+			return value
+		}
+	}
+	return ""
+}
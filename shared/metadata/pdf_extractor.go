@@ -1,8 +1,10 @@
 package metadata
 
 import (
+	"encoding/xml"
 	"fmt"
 	"io"
+	"strings"
 	"time"
 
 	"github.com/Kyei-Ernest/libsystem/shared/models"
@@ -12,7 +14,8 @@ import (
 // PDFExtractor extracts metadata from PDF files
 type PDFExtractor struct{}
 
-// Extract extracts metadata from a PDF file
+// Extract extracts metadata from the document information dictionary of a PDF file and
+// from its XMP metadata stream, which takes precedence where both are present
 func (e *PDFExtractor) Extract(r io.ReaderAt, size int64) (*models.DocumentMetadata, error) {
 	reader, err := pdf.NewReader(r, size)
 	if err != nil {
@@ -24,67 +27,46 @@ func (e *PDFExtractor) Extract(r io.ReaderAt, size int64) (*models.DocumentMetad
 	}
 
 	// Extract PDF info dictionary
-	trailer := reader.Trailer()
-	if !trailer.IsNull() {
-		info := trailer.Key("Info")
-		if !info.IsNull() {
-			// Extract title
-			title := info.Key("Title")
-			if !title.IsNull() {
-				if titleStr := title.String(); titleStr != "" {
-					metadata.CustomFields["title"] = titleStr
-				}
-			}
+	info := reader.Trailer().Key("Info")
+	text := func(key string) string {
+		return strings.TrimSpace(info.Key(key).Text())
+	}
 
-			// Extract author
-			author := info.Key("Author")
-			if !author.IsNull() {
-				if authorStr := author.String(); authorStr != "" {
-					metadata.Author = authorStr
-				}
-			}
+	// Extract XMP packet, if any
+	xmp := map[string][]string{}
+	if stream := reader.Trailer().Key("Root").Key("Metadata"); stream.Kind() == pdf.Stream {
+		rc := stream.Reader()
+		xmp = parseXMP(rc)
+		rc.Close()
+	}
 
-			// Extract subject
-			subject := info.Key("Subject")
-			if !subject.IsNull() {
-				if subjectStr := subject.String(); subjectStr != "" {
-					metadata.CustomFields["subject"] = subjectStr
-				}
-			}
+	metadata.Author = firstNonEmpty(strings.Join(xmp["dc:creator"], "; "), text("Author"))
+	metadata.Publisher = firstNonEmpty(strings.Join(xmp["dc:publisher"], "; "))
+	metadata.ISBN = firstISBN(append(append([]string{}, xmp["prism:isbn"]...), xmp["dc:identifier"]...)...)
 
-			// Extract keywords (convert to tags)
-			keywords := info.Key("Keywords")
-			if !keywords.IsNull() {
-				if keywordsStr := keywords.String(); keywordsStr != "" {
-					// Simple split by commas/semicolons
-					// Could be improved with better parsing
-					metadata.CustomFields["keywords"] = keywordsStr
-				}
-			}
-
-			// Extract creation date
-			creationDate := info.Key("CreationDate")
-			if !creationDate.IsNull() {
-				if dateStr := creationDate.String(); dateStr != "" {
-					// PDF dates are in format: D:YYYYMMDDHHmmSS
-					if parsedDate, err := parsePDFDate(dateStr); err == nil {
-						metadata.PublishDate = parsedDate.Format("2006-01-02")
-					}
-				}
-			}
+	// Keywords become tags; dc:subject holds them as a list
+	if subjects := xmp["dc:subject"]; len(subjects) > 0 {
+		metadata.Tags = splitKeywords(subjects...)
+	} else {
+		metadata.Tags = splitKeywords(firstNonEmpty(strings.Join(xmp["pdf:Keywords"], ","), text("Keywords")))
+	}
 
-			// Extract producer/creator
-			producer := info.Key("Producer")
-			if !producer.IsNull() {
-				if prodStr := producer.String(); prodStr != "" {
-					metadata.CustomFields["producer"] = prodStr
-				}
-			}
+	// Extract creation date
+	if created := firstNonEmpty(xmp["xmp:CreateDate"]...); created != "" {
+		metadata.PublishDate = normalizeDate(created)
+	} else if created := text("CreationDate"); created != "" {
+		// PDF dates are in format: D:YYYYMMDDHHmmSS
+		if parsedDate, err := parsePDFDate(created); err == nil {
+			metadata.PublishDate = parsedDate.Format("2006-01-02")
 		}
 	}
 
+	setCustomField(metadata, "title", firstNonEmpty(firstNonEmpty(xmp["dc:title"]...), text("Title")))
+	setCustomField(metadata, "subject", firstNonEmpty(firstNonEmpty(xmp["dc:description"]...), text("Subject")))
+	setCustomField(metadata, "producer", firstNonEmpty(firstNonEmpty(xmp["pdf:Producer"]...), text("Producer")))
+
 	// Extract page count
-	metadata.CustomFields["page_count"] = reader.NumPage()
+	metadata.CustomFields[PageCountField] = reader.NumPage()
 
 	return metadata, nil
 }
@@ -106,3 +88,79 @@ func parsePDFDate(dateStr string) (time.Time, error) {
 
 	return time.Time{}, fmt.Errorf("invalid PDF date format")
 }
+
+const rdfNamespace = "http://www.w3.org/1999/02/22-rdf-syntax-ns#"
+
+// xmpPrefixes names the XMP schemas whose properties are read
+var xmpPrefixes = map[string]string{
+	"http://purl.org/dc/elements/1.1/":               "dc",
+	"http://ns.adobe.com/xap/1.0/":                   "xmp",
+	"http://ns.adobe.com/pdf/1.3/":                   "pdf",
+	"http://prismstandard.org/namespaces/basic/2.0/": "prism",
+	"http://prismstandard.org/namespaces/basic/3.0/": "prism",
+}
+
+// parseXMP collects the values of the XMP properties of known schemas, keyed by prefixed
+// name ("dc:creator"). Array properties give one value per item; properties may also be
+// written as attributes of rdf:Description.
+func parseXMP(r io.Reader) map[string][]string {
+	properties := make(map[string][]string)
+	decoder := xml.NewDecoder(r)
+	decoder.Strict = false
+
+	var property string // Property being read, "" outside properties
+	var depth int       // Nesting below the property element
+	var text strings.Builder
+
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			// Keep what was read before any malformed markup
+			return properties
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			if property != "" {
+				depth++
+				text.Reset()
+				continue
+			}
+			if t.Name.Space == rdfNamespace && t.Name.Local == "Description" {
+				for _, attr := range t.Attr {
+					if prefix, ok := xmpPrefixes[attr.Name.Space]; ok && strings.TrimSpace(attr.Value) != "" {
+						key := prefix + ":" + attr.Name.Local
+						properties[key] = append(properties[key], strings.TrimSpace(attr.Value))
+					}
+				}
+				continue
+			}
+			if prefix, ok := xmpPrefixes[t.Name.Space]; ok {
+				property = prefix + ":" + t.Name.Local
+				depth = 0
+				text.Reset()
+			}
+		case xml.CharData:
+			if property != "" {
+				text.Write(t)
+			}
+		case xml.EndElement:
+			if property == "" {
+				continue
+			}
+			value := strings.TrimSpace(text.String())
+			text.Reset()
+			if depth > 0 {
+				if t.Name.Space == rdfNamespace && t.Name.Local == "li" && value != "" {
+					properties[property] = append(properties[property], value)
+				}
+				depth--
+				continue
+			}
+			if value != "" {
+				properties[property] = append(properties[property], value)
+			}
+			property = ""
+		}
+	}
+}
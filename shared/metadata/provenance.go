@@ -0,0 +1,114 @@
+package metadata
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/Kyei-Ernest/libsystem/shared/models"
+)
+
+const customFieldPrefix = "custom_fields."
+
+// Merge applies extracted metadata to a document's metadata. Fields that are empty or were
+// themselves extracted take the extracted value and record source as their provenance;
+// fields supplied by users are left alone. Previously extracted fields the new extraction
+// no longer yields (e.g. after the content is replaced) are cleared.
+func Merge(dst *models.DocumentMetadata, extracted *models.DocumentMetadata, source string) {
+	mergeString(dst, "author", &dst.Author, extracted.Author, source)
+	mergeString(dst, "publisher", &dst.Publisher, extracted.Publisher, source)
+	mergeString(dst, "publish_date", &dst.PublishDate, extracted.PublishDate, source)
+	mergeString(dst, "isbn", &dst.ISBN, extracted.ISBN, source)
+
+	if len(dst.Tags) == 0 || isExtracted(dst, "tags") {
+		dst.Tags = extracted.Tags
+		setProvenance(dst, "tags", source, len(extracted.Tags) > 0)
+	}
+
+	for key, value := range extracted.CustomFields {
+		if _, supplied := dst.CustomFields[key]; supplied && !isExtracted(dst, customFieldPrefix+key) {
+			continue
+		}
+		if dst.CustomFields == nil {
+			dst.CustomFields = make(map[string]interface{})
+		}
+		dst.CustomFields[key] = value
+		setProvenance(dst, customFieldPrefix+key, source, true)
+	}
+	for field := range dst.Provenance {
+		key, ok := strings.CutPrefix(field, customFieldPrefix)
+		if !ok {
+			continue
+		}
+		if _, still := extracted.CustomFields[key]; !still {
+			delete(dst.CustomFields, key)
+			delete(dst.Provenance, field)
+		}
+	}
+}
+
+// ClaimEdits settles the provenance of metadata edited by a user: fields whose value
+// changed now belong to the user, unchanged ones keep their previous provenance.
+// Provenance sent by the client is ignored.
+func ClaimEdits(previous models.DocumentMetadata, edited *models.DocumentMetadata) {
+	edited.Provenance = nil
+	for field, source := range previous.Provenance {
+		before, _ := fieldValue(&previous, field)
+		after, present := fieldValue(edited, field)
+		if present && reflect.DeepEqual(before, after) {
+			setProvenance(edited, field, source, true)
+		}
+	}
+}
+
+// ClearProvenance drops any provenance a client supplied with new metadata
+func ClearProvenance(m *models.DocumentMetadata) {
+	if m != nil {
+		m.Provenance = nil
+	}
+}
+
+func mergeString(dst *models.DocumentMetadata, field string, value *string, extracted, source string) {
+	if strings.TrimSpace(*value) != "" && !isExtracted(dst, field) {
+		return
+	}
+	*value = extracted
+	setProvenance(dst, field, source, extracted != "")
+}
+
+func isExtracted(m *models.DocumentMetadata, field string) bool {
+	_, ok := m.Provenance[field]
+	return ok
+}
+
+// setProvenance records source for field, or removes the record when the field is unset
+func setProvenance(m *models.DocumentMetadata, field, source string, set bool) {
+	if !set {
+		delete(m.Provenance, field)
+		return
+	}
+	if m.Provenance == nil {
+		m.Provenance = make(map[string]string)
+	}
+	m.Provenance[field] = source
+}
+
+// fieldValue returns the value of a field named as in Provenance, and whether it is set
+func fieldValue(m *models.DocumentMetadata, field string) (interface{}, bool) {
+	switch field {
+	case "author":
+		return m.Author, m.Author != ""
+	case "publisher":
+		return m.Publisher, m.Publisher != ""
+	case "publish_date":
+		return m.PublishDate, m.PublishDate != ""
+	case "isbn":
+		return m.ISBN, m.ISBN != ""
+	case "tags":
+		return m.Tags, len(m.Tags) > 0
+	}
+	if key, ok := strings.CutPrefix(field, customFieldPrefix); ok {
+		value, present := m.CustomFields[key]
+		return value, present
+	}
+	return nil, false
+}
@@ -0,0 +1,89 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// AnnotationVisibility defines who can see an annotation
+type AnnotationVisibility string
+
+const (
+	AnnotationPrivate    AnnotationVisibility = "private"    // Its creator only
+	AnnotationCollection AnnotationVisibility = "collection" // Members of the document's collection
+	AnnotationPublic     AnnotationVisibility = "public"     // Everyone who can see the document
+)
+
+// Annotation is a highlight or note anchored to a passage of a document's content, after
+// the W3C Web Annotation model. It belongs to the content it was made on (Hash), so it
+// stays with that version when the document is revised.
+type Annotation struct {
+	ID         uuid.UUID            `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	DocumentID uuid.UUID            `gorm:"type:uuid;not null;index" json:"document_id"`
+	Hash       string               `gorm:"type:varchar(64);not null" json:"hash"` // Content the anchors refer to
+	VersionID  *uuid.UUID           `gorm:"-" json:"version_id,omitempty"`         // Version with that content, if any; not stored
+	CreatorID  uuid.UUID            `gorm:"type:uuid;not null;index" json:"creator_id"`
+	Visibility AnnotationVisibility `gorm:"type:varchar(20);not null;default:'private'" json:"visibility"`
+	Target     AnnotationTarget     `gorm:"type:jsonb;not null" json:"target"`
+	Body       string               `gorm:"type:text" json:"body,omitempty"` // Note text; empty for a plain highlight
+	CreatedAt  time.Time            `gorm:"not null;default:NOW()" json:"created_at"`
+	UpdatedAt  time.Time            `gorm:"not null;default:NOW()" json:"updated_at"`
+}
+
+// TableName specifies the table name for Annotation
+func (Annotation) TableName() string {
+	return "annotations"
+}
+
+// Motivation returns the W3C motivation of the annotation
+func (a *Annotation) Motivation() string {
+	if a.Body == "" {
+		return "highlighting"
+	}
+	return "commenting"
+}
+
+// AnnotationTarget anchors an annotation: an optional page (for paged formats such as
+// PDF) plus a text quote, a text position in the extracted text, or both
+type AnnotationTarget struct {
+	Page         *int                  `json:"page,omitempty"`
+	TextQuote    *TextQuoteSelector    `json:"text_quote,omitempty"`
+	TextPosition *TextPositionSelector `json:"text_position,omitempty"`
+}
+
+// TextQuoteSelector selects the passage Exact, disambiguated by the text around it
+type TextQuoteSelector struct {
+	Exact  string `json:"exact"`
+	Prefix string `json:"prefix,omitempty"`
+	Suffix string `json:"suffix,omitempty"`
+}
+
+// TextPositionSelector selects the characters from Start up to (not including) End
+type TextPositionSelector struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
+}
+
+// Scan implements sql.Scanner for JSONB
+func (t *AnnotationTarget) Scan(value interface{}) error {
+	if value == nil {
+		*t = AnnotationTarget{}
+		return nil
+	}
+
+	bytes, ok := value.([]byte)
+	if !ok {
+		return fmt.Errorf("failed to unmarshal JSONB value: %v", value)
+	}
+
+	return json.Unmarshal(bytes, t)
+}
+
+// Value implements driver.Valuer for JSONB
+func (t AnnotationTarget) Value() (driver.Value, error) {
+	return json.Marshal(t)
+}
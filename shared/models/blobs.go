@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// Blob is stored file content, keyed by its SHA-256 hash. Every document and document
+// version row with that hash references it; it is deleted, objects included, when the
+// last reference goes.
+type Blob struct {
+	Hash          string    `gorm:"primaryKey;type:varchar(64)" json:"hash"`
+	StoragePath   string    `gorm:"not null" json:"storage_path"`
+	ThumbnailPath string    `gorm:"type:varchar(500)" json:"thumbnail_path,omitempty"`
+	Size          int64     `gorm:"not null" json:"size"`
+	MimeType      string    `gorm:"not null" json:"mime_type"`
+	RefCount      int       `gorm:"not null;default:0" json:"ref_count"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// TableName specifies the table name for Blob
+func (Blob) TableName() string {
+	return "blobs"
+}
@@ -0,0 +1,128 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ItemStatus defines the circulation status of a physical copy
+type ItemStatus string
+
+const (
+	ItemAvailable   ItemStatus = "available"     // On the shelf
+	ItemOnLoan      ItemStatus = "on_loan"       // Checked out
+	ItemOnHoldShelf ItemStatus = "on_hold_shelf" // Set aside for a patron's hold
+	ItemInRepair    ItemStatus = "in_repair"
+	ItemLost        ItemStatus = "lost"
+	ItemWithdrawn   ItemStatus = "withdrawn"
+)
+
+// ItemCondition records the physical condition of a copy
+type ItemCondition string
+
+const (
+	ConditionNew     ItemCondition = "new"
+	ConditionGood    ItemCondition = "good"
+	ConditionFair    ItemCondition = "fair"
+	ConditionPoor    ItemCondition = "poor"
+	ConditionDamaged ItemCondition = "damaged"
+)
+
+// Item is a physical copy of a catalogue record (a Document) that can be lent. Copies of
+// a record that is purged stay in circulation, detached from it.
+type Item struct {
+	ID         uuid.UUID     `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	DocumentID *uuid.UUID    `gorm:"type:uuid;index" json:"document_id,omitempty"`
+	Barcode    string        `gorm:"type:varchar(64);uniqueIndex;not null" json:"barcode"`
+	CallNumber string        `gorm:"type:varchar(100)" json:"call_number,omitempty"`
+	Location   string        `gorm:"type:varchar(255)" json:"location,omitempty"`
+	Condition  ItemCondition `gorm:"type:varchar(20);not null;default:'good'" json:"condition"`
+	Status     ItemStatus    `gorm:"type:varchar(20);not null;default:'available'" json:"status"`
+	Notes      string        `gorm:"type:text" json:"notes,omitempty"`
+	CreatedAt  time.Time     `gorm:"not null;default:NOW()" json:"created_at"`
+	UpdatedAt  time.Time     `gorm:"not null;default:NOW()" json:"updated_at"`
+}
+
+// TableName specifies the table name for Item
+func (Item) TableName() string {
+	return "items"
+}
+
+// LoanPolicy sets the lending rules for a user role
+type LoanPolicy struct {
+	Role           UserRole  `gorm:"type:varchar(20);primaryKey" json:"role"`
+	LoanPeriodDays int       `gorm:"not null" json:"loan_period_days"`
+	MaxLoans       int       `gorm:"not null" json:"max_loans"`
+	MaxRenewals    int       `gorm:"not null" json:"max_renewals"`
+	MaxHolds       int       `gorm:"not null" json:"max_holds"`
+	HoldPickupDays int       `gorm:"not null" json:"hold_pickup_days"` // Days a held copy waits on the hold shelf
+	UpdatedAt      time.Time `gorm:"not null;default:NOW()" json:"updated_at"`
+}
+
+// TableName specifies the table name for LoanPolicy
+func (LoanPolicy) TableName() string {
+	return "loan_policies"
+}
+
+// Loan is the lending of an item to a user, open until the item is checked in
+type Loan struct {
+	ID                uuid.UUID  `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	ItemID            uuid.UUID  `gorm:"type:uuid;not null;index" json:"item_id"`
+	Item              *Item      `gorm:"foreignKey:ItemID" json:"item,omitempty"`
+	UserID            uuid.UUID  `gorm:"type:uuid;not null;index" json:"user_id"`
+	CheckedOutBy      uuid.UUID  `gorm:"type:uuid;not null" json:"checked_out_by"`
+	CheckedOutAt      time.Time  `gorm:"not null;default:NOW()" json:"checked_out_at"`
+	DueAt             time.Time  `gorm:"not null" json:"due_at"`
+	Renewals          int        `gorm:"not null;default:0" json:"renewals"`
+	ReturnedAt        *time.Time `json:"returned_at,omitempty"`
+	CheckedInBy       *uuid.UUID `gorm:"type:uuid" json:"checked_in_by,omitempty"`
+	OverdueNotifiedAt *time.Time `json:"-"`
+	Overdue           bool       `gorm:"-" json:"overdue"`
+}
+
+// TableName specifies the table name for Loan
+func (Loan) TableName() string {
+	return "loans"
+}
+
+// IsOverdue reports whether the loan is still open after its due date
+func (l *Loan) IsOverdue(now time.Time) bool {
+	return l.ReturnedAt == nil && now.After(l.DueAt)
+}
+
+// HoldStatus defines the state of a hold
+type HoldStatus string
+
+const (
+	HoldWaiting   HoldStatus = "waiting"   // In the queue for the next copy
+	HoldReady     HoldStatus = "ready"     // A copy is on the hold shelf for pickup
+	HoldFulfilled HoldStatus = "fulfilled" // The copy was checked out to the holder
+	HoldCancelled HoldStatus = "cancelled"
+	HoldExpired   HoldStatus = "expired" // Not picked up in time
+)
+
+// Hold is a user's place in the queue for any copy of a catalogue record
+type Hold struct {
+	ID         uuid.UUID  `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	DocumentID uuid.UUID  `gorm:"type:uuid;not null;index" json:"document_id"`
+	UserID     uuid.UUID  `gorm:"type:uuid;not null;index" json:"user_id"`
+	Status     HoldStatus `gorm:"type:varchar(20);not null;default:'waiting'" json:"status"`
+	ItemID     *uuid.UUID `gorm:"type:uuid" json:"item_id,omitempty"` // Copy set aside once ready
+	Item       *Item      `gorm:"foreignKey:ItemID" json:"item,omitempty"`
+	PlacedAt   time.Time  `gorm:"not null;default:NOW()" json:"placed_at"`
+	ReadyAt    *time.Time `json:"ready_at,omitempty"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"` // Pickup deadline once ready
+	ClosedAt   *time.Time `json:"closed_at,omitempty"`
+	Position   int        `gorm:"-" json:"position,omitempty"` // Place in the queue while waiting
+}
+
+// TableName specifies the table name for Hold
+func (Hold) TableName() string {
+	return "holds"
+}
+
+// Active reports whether the hold is still waiting or ready
+func (h *Hold) Active() bool {
+	return h.Status == HoldWaiting || h.Status == HoldReady
+}
@@ -0,0 +1,44 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DigitalLoanStatus defines the state of a loan of a lent document
+type DigitalLoanStatus string
+
+const (
+	DigitalLoanWaiting   DigitalLoanStatus = "waiting"   // On the waitlist for a free licence
+	DigitalLoanActive    DigitalLoanStatus = "active"    // The patron may read the document until ExpiresAt
+	DigitalLoanReturned  DigitalLoanStatus = "returned"  // Returned early by the patron
+	DigitalLoanExpired   DigitalLoanStatus = "expired"   // Returned automatically at ExpiresAt
+	DigitalLoanCancelled DigitalLoanStatus = "cancelled" // Left the waitlist, or lending ended
+)
+
+// DigitalLoan is a patron's time-limited loan of a document under controlled digital
+// lending, or their place on its waitlist. Waiting loans start in order of RequestedAt
+// as licences come free.
+type DigitalLoan struct {
+	ID          uuid.UUID         `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	DocumentID  uuid.UUID         `gorm:"type:uuid;not null;index" json:"document_id"`
+	Document    *Document         `gorm:"foreignKey:DocumentID" json:"document,omitempty"`
+	UserID      uuid.UUID         `gorm:"type:uuid;not null;index" json:"user_id"`
+	Status      DigitalLoanStatus `gorm:"type:varchar(20);not null;default:'waiting'" json:"status"`
+	RequestedAt time.Time         `gorm:"not null;default:NOW()" json:"requested_at"`
+	StartedAt   *time.Time        `json:"started_at,omitempty"`
+	ExpiresAt   *time.Time        `json:"expires_at,omitempty"`
+	ClosedAt    *time.Time        `json:"closed_at,omitempty"`
+	Position    int               `gorm:"-" json:"position,omitempty"` // Place on the waitlist, from 1
+}
+
+// TableName specifies the table name for DigitalLoan
+func (DigitalLoan) TableName() string {
+	return "digital_loans"
+}
+
+// IsActive reports whether the loan lets the patron read the document at now
+func (l *DigitalLoan) IsActive(now time.Time) bool {
+	return l.Status == DigitalLoanActive && l.ExpiresAt != nil && now.Before(*l.ExpiresAt)
+}
@@ -54,7 +54,8 @@ type Collection struct {
 	Owner       User               `gorm:"foreignKey:OwnerID" json:"owner,omitempty"`
 	Metadata    MetadataSchema     `gorm:"type:jsonb" json:"metadata"`
 	Settings    CollectionSettings `gorm:"type:jsonb" json:"settings"`
-	Stats       CollectionStats    `gorm:"-" json:"stats,omitempty"` // Not stored in DB, computed
+	Stats       CollectionStats    `gorm:"-" json:"stats,omitempty"`              // Not stored in DB, computed
+	DeletedBy   *uuid.UUID         `gorm:"type:uuid" json:"deleted_by,omitempty"` // Set while in the trash
 
 	// Relationships
 	Documents []Document `gorm:"foreignKey:CollectionID" json:"documents,omitempty"`
@@ -72,8 +73,11 @@ type MetadataSchema map[string]interface{}
 type CollectionSettings struct {
 	AllowPublicSubmissions bool     `json:"allow_public_submissions"`
 	RequireApproval        bool     `json:"require_approval"`
-	AllowedFileTypes       []string `json:"allowed_file_types,omitempty"`
-	MaxFileSize            int64    `json:"max_file_size,omitempty"`
+	AllowedFileTypes       []string `json:"allowed_file_types,omitempty"`  // File type names ("pdf"), extensions (".docx") or MIME types
+	MaxFileSize            int64    `json:"max_file_size,omitempty"`       // Bytes; capped by the service-wide limit
+	MaxPageCount           int      `json:"max_page_count,omitempty"`      // Applies to paged formats (PDF, DOCX)
+	RequiredMetadata       []string `json:"required_metadata,omitempty"`   // Metadata fields (or custom field names) uploads must supply
+	WatermarkDownloads     bool     `json:"watermark_downloads,omitempty"` // Stamp delivered PDFs with the reader's identity
 }
 
 // Scan implements sql.Scanner for JSONB
@@ -114,7 +118,7 @@ type Document struct {
 	FileSize         int64  `gorm:"not null" json:"file_size"`
 	StoragePath      string `gorm:"not null" json:"storage_path"`            // S3 key or path
 	ThumbnailPath    string `gorm:"type:varchar(500)" json:"thumbnail_path"` // Path to generated thumbnail
-	Hash             string `gorm:"uniqueIndex;not null" json:"hash"`        // SHA-256 for deduplication
+	Hash             string `gorm:"not null;index" json:"hash"`              // SHA-256; keys the shared Blob, unique per collection
 
 	// Extracted content
 	ExtractedText string `gorm:"type:text" json:"-"` // Full text for indexing
@@ -133,8 +137,29 @@ type Document struct {
 	ViewCount     int64 `gorm:"default:0" json:"view_count"`
 	DownloadCount int64 `gorm:"default:0" json:"download_count"`
 
+	// Availability window; outside it only the uploader and the collection owner see the
+	// document, except that EmbargoShowMetadata lists it (without content) before AvailableFrom
+	AvailableFrom       *time.Time `json:"available_from,omitempty"`
+	AvailableUntil      *time.Time `json:"available_until,omitempty"`
+	EmbargoShowMetadata bool       `gorm:"not null;default:false" json:"embargo_show_metadata"`
+
+	// Controlled digital lending; when LendingLicenses is set, reading the content needs a
+	// loan of LendingPeriodHours and at most LendingLicenses patrons hold one at a time
+	LendingLicenses    *int `json:"lending_licenses,omitempty"`
+	LendingPeriodHours int  `gorm:"not null;default:0" json:"lending_period_hours,omitempty"`
+
+	// Moderation
+	ReviewedBy *uuid.UUID `gorm:"type:uuid" json:"reviewed_by,omitempty"`
+	ReviewedAt *time.Time `json:"reviewed_at,omitempty"`
+	ReviewNote string     `gorm:"type:text" json:"review_note,omitempty"` // Reason given for a rejection
+
+	// Trash
+	DeletedBy             *uuid.UUID `gorm:"type:uuid" json:"deleted_by,omitempty"`
+	TrashedWithCollection bool       `gorm:"not null;default:false" json:"trashed_with_collection,omitempty"` // Restored together with its collection
+
 	// Relationships
-	Versions []DocumentVersion `gorm:"foreignKey:DocumentID" json:"versions,omitempty"`
+	Versions   []DocumentVersion   `gorm:"foreignKey:DocumentID" json:"versions,omitempty"`
+	Navigation *DocumentNavigation `gorm:"-" json:"navigation,omitempty"` // Not stored in DB, set on single-document responses
 }
 
 type DocumentStatus string
@@ -145,8 +170,34 @@ const (
 	StatusActive     DocumentStatus = "active"
 	StatusRejected   DocumentStatus = "rejected"
 	StatusArchived   DocumentStatus = "archived"
+	StatusSubmitted  DocumentStatus = "submitted" // Awaiting review by the collection's moderators
+	StatusEmbargoed  DocumentStatus = "embargoed" // Processed, waiting for its AvailableFrom
+	StatusExpired    DocumentStatus = "expired"   // Past its AvailableUntil
 )
 
+// IsEmbargoed reports whether the document is not yet available at t
+func (d *Document) IsEmbargoed(t time.Time) bool {
+	return d.AvailableFrom != nil && t.Before(*d.AvailableFrom)
+}
+
+// IsExpired reports whether the document is no longer available at t
+func (d *Document) IsExpired(t time.Time) bool {
+	return d.AvailableUntil != nil && !t.Before(*d.AvailableUntil)
+}
+
+// AvailabilityStatus returns the status a processed document has at t: embargoed or
+// expired outside its availability window, active within it
+func (d *Document) AvailabilityStatus(t time.Time) DocumentStatus {
+	switch {
+	case d.IsExpired(t):
+		return StatusExpired
+	case d.IsEmbargoed(t):
+		return StatusEmbargoed
+	default:
+		return StatusActive
+	}
+}
+
 // DocumentMetadata stores document-specific metadata
 type DocumentMetadata struct {
 	Author       string                 `json:"author,omitempty"`
@@ -155,6 +206,10 @@ type DocumentMetadata struct {
 	ISBN         string                 `json:"isbn,omitempty"`
 	Tags         []string               `json:"tags,omitempty"`
 	CustomFields map[string]interface{} `json:"custom_fields,omitempty"`
+	// Provenance names the source of each field filled from the document's embedded
+	// metadata ("pdf", "docx", "epub"), keyed by field name or "custom_fields.<key>".
+	// Fields without an entry were supplied by users and are never overwritten.
+	Provenance map[string]string `json:"provenance,omitempty"`
 }
 
 // Scan implements sql.Scanner for JSONB
@@ -183,11 +238,23 @@ type DocumentVersion struct {
 	DocumentID    uuid.UUID `gorm:"type:uuid;not null;index" json:"document_id"`
 	Document      Document  `gorm:"foreignKey:DocumentID" json:"-"`
 	VersionNumber int       `gorm:"not null" json:"version_number"`
-	StoragePath   string    `gorm:"not null" json:"storage_path"`
+	StoragePath   string    `gorm:"not null" json:"storage_path"` // Object of the version's Blob
 	FileSize      int64     `gorm:"not null" json:"file_size"`
 	Hash          string    `gorm:"not null" json:"hash"`
 	ChangeLog     string    `gorm:"type:text" json:"change_log,omitempty"`
 	CreatedBy     uuid.UUID `gorm:"type:uuid;not null" json:"created_by"`
+
+	// File information as uploaded, restored onto the document with the version
+	OriginalFilename string `json:"original_filename"`
+	FileType         string `json:"file_type"`
+	MimeType         string `json:"mime_type"`
+	ThumbnailPath    string `gorm:"type:varchar(500)" json:"thumbnail_path,omitempty"`
+
+	// Snapshot of the document when the version was created, kept for comparisons
+	Title         string           `json:"title"`
+	Description   string           `gorm:"type:text" json:"description,omitempty"`
+	Metadata      DocumentMetadata `gorm:"type:jsonb" json:"metadata"`
+	ExtractedText string           `gorm:"type:text" json:"-"`
 }
 
 // SearchQuery represents a saved or logged search query
@@ -0,0 +1,98 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// RelationType names how a document (the subject) relates to another (the object)
+type RelationType string
+
+const (
+	RelationPartOf         RelationType = "part_of"         // A chapter or issue of the object
+	RelationHasPart        RelationType = "has_part"        // Inverse of part_of; stored as part_of
+	RelationSeriesMember   RelationType = "series_member"   // A volume of the series the object describes
+	RelationHasMember      RelationType = "has_member"      // Inverse of series_member; stored as series_member
+	RelationSupersedes     RelationType = "supersedes"      // A newer edition replacing the object
+	RelationSupersededBy   RelationType = "superseded_by"   // Inverse of supersedes; stored as supersedes
+	RelationTranslationOf  RelationType = "translation_of"  // A translation of the object
+	RelationHasTranslation RelationType = "has_translation" // Inverse of translation_of; stored as translation_of
+	RelationRelated        RelationType = "related"         // Symmetric
+)
+
+// relationInverses maps each relation type to its type as seen from its object
+var relationInverses = map[RelationType]RelationType{
+	RelationPartOf:         RelationHasPart,
+	RelationHasPart:        RelationPartOf,
+	RelationSeriesMember:   RelationHasMember,
+	RelationHasMember:      RelationSeriesMember,
+	RelationSupersedes:     RelationSupersededBy,
+	RelationSupersededBy:   RelationSupersedes,
+	RelationTranslationOf:  RelationHasTranslation,
+	RelationHasTranslation: RelationTranslationOf,
+	RelationRelated:        RelationRelated,
+}
+
+// Valid reports whether t is a known relation type
+func (t RelationType) Valid() bool {
+	_, ok := relationInverses[t]
+	return ok
+}
+
+// Inverse returns the type of the relation seen from its object
+func (t RelationType) Inverse() RelationType {
+	return relationInverses[t]
+}
+
+// Stored reports whether relations of this type are stored as given; inverse types are
+// stored as their inverse with subject and object swapped
+func (t RelationType) Stored() bool {
+	switch t {
+	case RelationPartOf, RelationSeriesMember, RelationSupersedes, RelationTranslationOf, RelationRelated:
+		return true
+	}
+	return false
+}
+
+// Ordered reports whether relations of this type carry a position among the object's
+// parts or volumes
+func (t RelationType) Ordered() bool {
+	return t == RelationPartOf || t == RelationSeriesMember
+}
+
+// DocumentRelation is a typed, directed link between two documents
+type DocumentRelation struct {
+	ID        uuid.UUID    `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	SubjectID uuid.UUID    `gorm:"type:uuid;not null;index" json:"subject_id"`
+	Subject   Document     `gorm:"foreignKey:SubjectID" json:"-"`
+	ObjectID  uuid.UUID    `gorm:"type:uuid;not null;index" json:"object_id"`
+	Object    Document     `gorm:"foreignKey:ObjectID" json:"-"`
+	Type      RelationType `gorm:"type:varchar(20);not null" json:"type"`
+	Position  *int         `json:"position,omitempty"` // Order among the object's parts or volumes
+	CreatedBy uuid.UUID    `gorm:"type:uuid;not null" json:"created_by"`
+	CreatedAt time.Time    `gorm:"not null;default:NOW()" json:"created_at"`
+	UpdatedAt time.Time    `gorm:"not null;default:NOW()" json:"updated_at"`
+}
+
+// TableName specifies the table name for DocumentRelation
+func (DocumentRelation) TableName() string {
+	return "document_relations"
+}
+
+// DocumentLink identifies a related document
+type DocumentLink struct {
+	ID       uuid.UUID `json:"id"`
+	Title    string    `json:"title"`
+	Position *int      `json:"position,omitempty"`
+}
+
+// DocumentNavigation places a document among the works it belongs to: the work it is a
+// part of, its series, and the neighbouring parts or volumes
+type DocumentNavigation struct {
+	PartOf   *DocumentLink  `json:"part_of,omitempty"`
+	Series   *DocumentLink  `json:"series,omitempty"`
+	Previous *DocumentLink  `json:"previous,omitempty"`
+	Next     *DocumentLink  `json:"next,omitempty"`
+	Parts    []DocumentLink `json:"parts,omitempty"` // Parts or volumes of this document, in order
+}
@@ -0,0 +1,66 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ShelfKind defines the kind of a personal shelf
+type ShelfKind string
+
+const (
+	ShelfFavorites ShelfKind = "favorites" // Each user's favorites; created on first use
+	ShelfList      ShelfKind = "list"      // A named reading list
+)
+
+// Shelf is a user's ordered list of documents. Public shelves can be shared by link.
+type Shelf struct {
+	ID          uuid.UUID   `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	OwnerID     uuid.UUID   `gorm:"type:uuid;not null;index" json:"owner_id"`
+	Kind        ShelfKind   `gorm:"type:varchar(20);not null;default:'list'" json:"kind"`
+	Name        string      `gorm:"type:varchar(255);not null" json:"name"`
+	Description string      `gorm:"type:text" json:"description,omitempty"`
+	IsPublic    bool        `gorm:"not null;default:false" json:"is_public"`
+	ItemCount   int64       `gorm:"-" json:"item_count"`
+	Items       []ShelfItem `gorm:"foreignKey:ShelfID" json:"items,omitempty"`
+	CreatedAt   time.Time   `gorm:"not null;default:NOW()" json:"created_at"`
+	UpdatedAt   time.Time   `gorm:"not null;default:NOW()" json:"updated_at"`
+}
+
+// TableName specifies the table name for Shelf
+func (Shelf) TableName() string {
+	return "shelves"
+}
+
+// ShelfItem is a document on a shelf, at a position from 1, with the owner's note
+type ShelfItem struct {
+	ShelfID    uuid.UUID `gorm:"type:uuid;primaryKey" json:"-"`
+	DocumentID uuid.UUID `gorm:"type:uuid;primaryKey" json:"document_id"`
+	Document   *Document `gorm:"foreignKey:DocumentID" json:"document,omitempty"`
+	Position   int       `gorm:"not null" json:"position"`
+	Note       string    `gorm:"type:text" json:"note,omitempty"`
+	AddedAt    time.Time `gorm:"not null;default:NOW()" json:"added_at"`
+}
+
+// TableName specifies the table name for ShelfItem
+func (ShelfItem) TableName() string {
+	return "shelf_items"
+}
+
+// ReadingProgress is where a user is in a document. The position (page or percentage) is
+// set by the viewer; LastReadAt also advances when the user views or downloads it.
+type ReadingProgress struct {
+	UserID            uuid.UUID  `gorm:"type:uuid;primaryKey" json:"-"`
+	DocumentID        uuid.UUID  `gorm:"type:uuid;primaryKey" json:"document_id"`
+	Document          *Document  `gorm:"foreignKey:DocumentID" json:"document,omitempty"`
+	Page              *int       `json:"page,omitempty"`
+	Percentage        *float64   `gorm:"type:numeric(5,2)" json:"percentage,omitempty"`
+	PositionUpdatedAt *time.Time `json:"position_updated_at,omitempty"` // When the position was recorded on the reader's device
+	LastReadAt        time.Time  `gorm:"not null;default:NOW()" json:"last_read_at"`
+}
+
+// TableName specifies the table name for ReadingProgress
+func (ReadingProgress) TableName() string {
+	return "reading_progress"
+}
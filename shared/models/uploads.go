@@ -0,0 +1,74 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// UploadStatus represents the lifecycle state of a resumable upload
+type UploadStatus string
+
+const (
+	UploadStatusInProgress UploadStatus = "in_progress"
+	UploadStatusCompleted  UploadStatus = "completed"
+	UploadStatusFailed     UploadStatus = "failed"
+)
+
+// UploadSession tracks a resumable (tus) upload until it is handed off for ingestion
+type UploadSession struct {
+	BaseModel
+	UploaderID   uuid.UUID        `gorm:"type:uuid;not null;index" json:"uploader_id"`
+	CollectionID uuid.UUID        `gorm:"type:uuid" json:"collection_id"`
+	Title        string           `gorm:"not null" json:"title"`
+	Description  string           `gorm:"type:text" json:"description"`
+	Metadata     DocumentMetadata `gorm:"type:jsonb" json:"metadata"`
+	Filename     string           `gorm:"not null" json:"filename"`
+	ContentType  string           `gorm:"not null" json:"content_type"`
+	Length       int64            `gorm:"not null" json:"length"`           // Declared total size (Upload-Length)
+	Offset       int64            `gorm:"not null;default:0" json:"offset"` // Bytes received so far (Upload-Offset)
+	StagingPath  string           `gorm:"not null" json:"-"`                // Local file holding received bytes
+	Status       UploadStatus     `gorm:"type:varchar(20);not null;default:'in_progress'" json:"status"`
+	DocumentID   *uuid.UUID       `gorm:"type:uuid" json:"document_id,omitempty"`
+	Error        string           `gorm:"type:text" json:"error,omitempty"`
+	ExpiresAt    time.Time        `gorm:"not null;index" json:"expires_at"`
+
+	// Availability window of the document the upload becomes (see Document.AvailableFrom)
+	AvailableFrom       *time.Time `json:"available_from,omitempty"`
+	AvailableUntil      *time.Time `json:"available_until,omitempty"`
+	EmbargoShowMetadata bool       `gorm:"not null;default:false" json:"embargo_show_metadata"`
+}
+
+// TableName specifies the table name for UploadSession
+func (UploadSession) TableName() string {
+	return "upload_sessions"
+}
+
+// UploadIntent tracks a direct-to-storage upload: the client PUTs/POSTs the file to a
+// presigned staging key, then asks the service to finalize it into a document
+type UploadIntent struct {
+	BaseModel
+	UploaderID   uuid.UUID        `gorm:"type:uuid;not null;index" json:"uploader_id"`
+	CollectionID uuid.UUID        `gorm:"type:uuid" json:"collection_id"`
+	Title        string           `gorm:"not null" json:"title"`
+	Description  string           `gorm:"type:text" json:"description"`
+	Metadata     DocumentMetadata `gorm:"type:jsonb" json:"metadata"`
+	Filename     string           `gorm:"not null" json:"filename"`
+	ContentType  string           `gorm:"not null" json:"content_type"`
+	Size         int64            `gorm:"not null" json:"size"` // Declared size; the staged object must match
+	ObjectKey    string           `gorm:"not null" json:"-"`    // Staging key the client uploads to
+	Status       UploadStatus     `gorm:"type:varchar(20);not null;default:'in_progress'" json:"status"`
+	DocumentID   *uuid.UUID       `gorm:"type:uuid" json:"document_id,omitempty"`
+	Error        string           `gorm:"type:text" json:"error,omitempty"`
+	ExpiresAt    time.Time        `gorm:"not null;index" json:"expires_at"`
+
+	// Availability window of the document the upload becomes (see Document.AvailableFrom)
+	AvailableFrom       *time.Time `json:"available_from,omitempty"`
+	AvailableUntil      *time.Time `json:"available_until,omitempty"`
+	EmbargoShowMetadata bool       `gorm:"not null;default:false" json:"embargo_show_metadata"`
+}
+
+// TableName specifies the table name for UploadIntent
+func (UploadIntent) TableName() string {
+	return "upload_intents"
+}
@@ -89,6 +89,16 @@ func (c *Client) Expire(key string, expiration time.Duration) error {
 	return c.client.Expire(c.ctx, key, expiration).Err()
 }
 
+// Publish sends a message to a pub/sub channel
+func (c *Client) Publish(channel string, message interface{}) error {
+	return c.client.Publish(c.ctx, channel, message).Err()
+}
+
+// Subscribe subscribes to pub/sub channels until the subscription is closed
+func (c *Client) Subscribe(ctx context.Context, channels ...string) *redis.PubSub {
+	return c.client.Subscribe(ctx, channels...)
+}
+
 // GetClient returns the underlying redis client for advanced operations
 func (c *Client) GetClient() *redis.Client {
 	return c.client
@@ -4,6 +4,8 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/minio/minio-go/v7"
@@ -18,11 +20,15 @@ type MinIOConfig struct {
 	UseSSL          bool
 	BucketName      string
 	Region          string
+	// PublicEndpoint is the host clients use to reach MinIO directly (e.g. behind a proxy).
+	// Presigned URLs are signed for this host; defaults to Endpoint.
+	PublicEndpoint string
 }
 
 // MinIOClient wraps minio.Client with helper methods
 type MinIOClient struct {
 	client     *minio.Client
+	presigner  *minio.Client // signs URLs for the public endpoint
 	bucketName string
 	ctx        context.Context
 }
@@ -57,8 +63,22 @@ func NewMinIOClient(config *MinIOConfig) (*MinIOClient, error) {
 		}
 	}
 
+	// Signing is done offline, so the public endpoint does not need to be reachable from here
+	presigner := client
+	if config.PublicEndpoint != "" && config.PublicEndpoint != config.Endpoint {
+		presigner, err = minio.New(config.PublicEndpoint, &minio.Options{
+			Creds:  credentials.NewStaticV4(config.AccessKeyID, config.SecretAccessKey, ""),
+			Secure: config.UseSSL,
+			Region: config.Region,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create MinIO presign client: %w", err)
+		}
+	}
+
 	return &MinIOClient{
 		client:     client,
+		presigner:  presigner,
 		bucketName: config.BucketName,
 		ctx:        ctx,
 	}, nil
@@ -82,6 +102,51 @@ func (m *MinIOClient) UploadFile(objectName string, reader io.Reader, size int64
 	return nil
 }
 
+// streamPartSize is the multipart chunk size used for uploads of unknown length.
+// minio-go buffers one part at a time, so this bounds memory per upload.
+const streamPartSize = 16 * 1024 * 1024 // 16MB
+
+// UploadStream uploads a stream of unknown length to MinIO using a multipart upload
+// Returns the number of bytes written
+func (m *MinIOClient) UploadStream(objectName string, reader io.Reader, contentType string) (int64, error) {
+	info, err := m.client.PutObject(
+		m.ctx,
+		m.bucketName,
+		objectName,
+		reader,
+		-1,
+		minio.PutObjectOptions{
+			ContentType: contentType,
+			PartSize:    streamPartSize,
+		},
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to upload stream: %w", err)
+	}
+	return info.Size, nil
+}
+
+// CopyFile performs a server-side copy of an object within the bucket
+func (m *MinIOClient) CopyFile(srcObject, dstObject string) error {
+	_, err := m.client.ComposeObject(
+		m.ctx,
+		minio.CopyDestOptions{Bucket: m.bucketName, Object: dstObject},
+		minio.CopySrcOptions{Bucket: m.bucketName, Object: srcObject},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to copy file: %w", err)
+	}
+	return nil
+}
+
+// MoveFile promotes an object to a new key (server-side copy, then delete of the source)
+func (m *MinIOClient) MoveFile(srcObject, dstObject string) error {
+	if err := m.CopyFile(srcObject, dstObject); err != nil {
+		return err
+	}
+	return m.DeleteFile(srcObject)
+}
+
 // DownloadFile retrieves a file from MinIO
 func (m *MinIOClient) DownloadFile(objectName string) (io.ReadCloser, error) {
 	object, err := m.client.GetObject(m.ctx, m.bucketName, objectName, minio.GetObjectOptions{})
@@ -91,6 +156,17 @@ func (m *MinIOClient) DownloadFile(objectName string) (io.ReadCloser, error) {
 	return object, nil
 }
 
+// OpenFile opens an object for random access. Nothing is fetched until the first Read;
+// each Seek followed by a Read issues a ranged GET, so only the requested bytes are
+// transferred (suitable for http.ServeContent).
+func (m *MinIOClient) OpenFile(objectName string) (io.ReadSeekCloser, error) {
+	object, err := m.client.GetObject(m.ctx, m.bucketName, objectName, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get object: %w", err)
+	}
+	return object, nil
+}
+
 // DeleteFile removes a file from MinIO
 func (m *MinIOClient) DeleteFile(objectName string) error {
 	err := m.client.RemoveObject(m.ctx, m.bucketName, objectName, minio.RemoveObjectOptions{})
@@ -102,13 +178,61 @@ func (m *MinIOClient) DeleteFile(objectName string) error {
 
 // GetPresignedURL generates a pre-signed URL for temporary access
 func (m *MinIOClient) GetPresignedURL(objectName string, expiry time.Duration) (string, error) {
-	url, err := m.client.PresignedGetObject(m.ctx, m.bucketName, objectName, expiry, nil)
+	url, err := m.presigner.PresignedGetObject(m.ctx, m.bucketName, objectName, expiry, nil)
 	if err != nil {
 		return "", fmt.Errorf("failed to generate presigned URL: %w", err)
 	}
 	return url.String(), nil
 }
 
+// GetPresignedPutURL generates a pre-signed URL for uploading an object with a single PUT.
+// Content-Length and, if given, Content-Type are signed into the URL, so MinIO rejects a
+// PUT of any other size or type; the request must send both headers with exactly these
+// values.
+func (m *MinIOClient) GetPresignedPutURL(objectName, contentType string, size int64, expiry time.Duration) (string, error) {
+	headers := http.Header{}
+	headers.Set("Content-Length", strconv.FormatInt(size, 10))
+	if contentType != "" {
+		headers.Set("Content-Type", contentType)
+	}
+	url, err := m.presigner.PresignHeader(m.ctx, http.MethodPut, m.bucketName, objectName, expiry, nil, headers)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate presigned upload URL: %w", err)
+	}
+	return url.String(), nil
+}
+
+// GetPresignedPostPolicy generates a pre-signed browser form upload (POST policy) for a
+// single object key. MinIO rejects uploads whose content type differs or whose size falls
+// outside [1, maxSize].
+// Returns the form action URL and the form fields to submit alongside the file.
+func (m *MinIOClient) GetPresignedPostPolicy(objectName, contentType string, maxSize int64, expiry time.Duration) (string, map[string]string, error) {
+	policy := minio.NewPostPolicy()
+	if err := policy.SetBucket(m.bucketName); err != nil {
+		return "", nil, fmt.Errorf("failed to build upload policy: %w", err)
+	}
+	if err := policy.SetKey(objectName); err != nil {
+		return "", nil, fmt.Errorf("failed to build upload policy: %w", err)
+	}
+	if err := policy.SetExpires(time.Now().UTC().Add(expiry)); err != nil {
+		return "", nil, fmt.Errorf("failed to build upload policy: %w", err)
+	}
+	if err := policy.SetContentLengthRange(1, maxSize); err != nil {
+		return "", nil, fmt.Errorf("failed to build upload policy: %w", err)
+	}
+	if contentType != "" {
+		if err := policy.SetContentType(contentType); err != nil {
+			return "", nil, fmt.Errorf("failed to build upload policy: %w", err)
+		}
+	}
+
+	url, formData, err := m.presigner.PresignedPostPolicy(m.ctx, policy)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to generate presigned upload policy: %w", err)
+	}
+	return url.String(), formData, nil
+}
+
 // FileExists checks if a file exists in MinIO
 func (m *MinIOClient) FileExists(objectName string) (bool, error) {
 	_, err := m.client.StatObject(m.ctx, m.bucketName, objectName, minio.StatObjectOptions{})